@@ -0,0 +1,134 @@
+// Copyright 2015 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/Unknwon/com"
+	"github.com/codegangsta/cli"
+
+	"github.com/gogits/gogs/models"
+	"github.com/gogits/gogs/modules/git"
+	"github.com/gogits/gogs/modules/log"
+	"github.com/gogits/gogs/modules/process"
+	"github.com/gogits/gogs/modules/setting"
+)
+
+// _MAX_HOOK_OUTPUT_SIZE is the maximum size of hook output kept for admin notice.
+const _MAX_HOOK_OUTPUT_SIZE = 4096
+
+var CmdHook = cli.Command{
+	Name:        "hook",
+	Usage:       "This command should only be called by Git hooks",
+	Description: `Run custom Git hook of repository with time limit`,
+	Action:      runHook,
+	Flags: []cli.Flag{
+		stringFlag("config, c", "custom/conf/app.ini", "Custom configuration file path"),
+	},
+}
+
+// hookOutput keeps beginning of hook output up to _MAX_HOOK_OUTPUT_SIZE.
+type hookOutput struct {
+	bytes.Buffer
+}
+
+func (o *hookOutput) Write(p []byte) (int, error) {
+	if remain := _MAX_HOOK_OUTPUT_SIZE - o.Len(); remain > 0 {
+		if len(p) > remain {
+			o.Buffer.Write(p[:remain])
+		} else {
+			o.Buffer.Write(p)
+		}
+	}
+	return len(p), nil
+}
+
+func runHook(c *cli.Context) {
+	if c.IsSet("config") {
+		setting.CustomConf = c.String("config")
+	}
+	args := c.Args()
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "Gogs: hook name is not given")
+		os.Exit(1)
+	}
+	name := args[0]
+
+	// Git runs hooks in repository directory, get it before
+	// working directory may be changed by database setup.
+	repoPath, err := os.Getwd()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Gogs: fail to get repository path:", err)
+		os.Exit(1)
+	}
+
+	setupDatabase("hooks.log")
+
+	script := filepath.Join(repoPath, git.CUSTOM_HOOKS_DIR, name)
+	if !com.IsFile(script) {
+		return
+	}
+
+	// Output is sent to Git client as it is.
+	output := new(hookOutput)
+	cmd := exec.Command(script, args[1:]...)
+	cmd.Dir = repoPath
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = io.MultiWriter(os.Stdout, output)
+	cmd.Stderr = io.MultiWriter(os.Stderr, output)
+	process.SetNewGroup(cmd)
+	if err = cmd.Start(); err != nil {
+		reportHookFailure(name, repoPath, err, output)
+		fail(fmt.Sprintf("Hook '%s' cannot be started: %v", name, err), "")
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	var timeout <-chan time.Time
+	if setting.Git.HookTimeout > 0 {
+		timeout = time.After(time.Duration(setting.Git.HookTimeout) * time.Second)
+	}
+
+	select {
+	case err = <-done:
+		if err != nil {
+			reportHookFailure(name, repoPath, err, output)
+			fail(fmt.Sprintf("Hook '%s' failed: %v", name, err), "")
+		}
+	case <-timeout:
+		// Kill whole group so that no process started by hook is left behind.
+		if err = process.KillGroup(cmd); err != nil {
+			log.GitLogger.Error(2, "KillGroup[%s]: %v", script, err)
+		}
+		<-done
+
+		err = fmt.Errorf("timed out after %d seconds", setting.Git.HookTimeout)
+		reportHookFailure(name, repoPath, err, output)
+		fail(fmt.Sprintf("Hook '%s' %v and has been killed, push is rejected.", name, err), "")
+	}
+}
+
+// reportHookFailure logs failure of hook and creates an admin notice with its output.
+func reportHookFailure(name, repoPath string, err error, output *hookOutput) {
+	log.GitLogger.Error(2, "Hook '%s' of repository '%s' failed: %v", name, repoPath, err)
+
+	desc := fmt.Sprintf("Hook '%s' of repository '%s' failed: %v", name, repoPath, err)
+	if output.Len() > 0 {
+		desc += "\n\n" + output.String()
+	}
+	if err = models.CreateRepositoryNotice(desc); err != nil {
+		log.GitLogger.Error(2, "CreateRepositoryNotice: %v", err)
+	}
+}
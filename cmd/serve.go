@@ -8,7 +8,6 @@ import (
 	"crypto/tls"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 	"time"
@@ -17,6 +16,8 @@ import (
 	"github.com/codegangsta/cli"
 
 	"github.com/gogits/gogs/models"
+	"github.com/gogits/gogs/modules/base"
+	"github.com/gogits/gogs/modules/git"
 	"github.com/gogits/gogs/modules/httplib"
 	"github.com/gogits/gogs/modules/log"
 	"github.com/gogits/gogs/modules/setting"
@@ -38,13 +39,19 @@ var CmdServ = cli.Command{
 }
 
 func setup(logPath string) {
-	setting.NewContext()
-	log.NewGitLogger(filepath.Join(setting.LogRootPath, logPath))
+	setupDatabase(logPath)
 
 	if setting.DisableSSH {
 		println("Gogs: SSH has been disabled")
 		os.Exit(1)
 	}
+}
+
+// setupDatabase loads configuration and connects to database,
+// it does not check SSH availability so can be used by hooks of pushes over HTTP.
+func setupDatabase(logPath string) {
+	setting.NewContext()
+	log.NewGitLogger(filepath.Join(setting.LogRootPath, logPath))
 
 	models.LoadConfigs()
 
@@ -158,13 +165,18 @@ func runServ(c *cli.Context) {
 		reponame = reponame[:len(reponame)-5]
 	}
 
-	repoUser, err := models.GetUserByName(username)
+	repoUser, err := models.GetUserByNameOrRedirect(username)
 	if err != nil {
 		if models.IsErrUserNotExist(err) {
 			fail("Repository owner does not exist", "Unregistered owner: %s", username)
 		}
 		fail("Internal error", "Failed to get repository owner(%s): %v", username, err)
 	}
+	// Repositories are stored under current name of owner.
+	if repoUser.LowerName != username {
+		username = repoUser.LowerName
+		repoPath = username + "/" + rr[1]
+	}
 
 	repo, err := models.GetRepositoryByName(repoUser.Id, reponame)
 	if err != nil {
@@ -183,6 +195,18 @@ func runServ(c *cli.Context) {
 	if requestedMode > models.ACCESS_MODE_READ && repo.IsMirror {
 		fail("mirror repository is read-only", "")
 	}
+	if requestedMode > models.ACCESS_MODE_READ && repo.IsArchived {
+		fail("archived repository is read-only", "")
+	}
+	if requestedMode > models.ACCESS_MODE_READ && !isWiki {
+		exceeded, err := repoUser.IsStorageQuotaExceeded()
+		if err != nil {
+			fail("Internal error", "Failed to check storage quota: %v", err)
+		} else if exceeded {
+			fail(fmt.Sprintf("storage quota of %s (%s) has been reached",
+				repoUser.Name, base.FileSize(repoUser.StorageQuotaSize())), "")
+		}
+	}
 
 	// Allow anonymous clone for public repositories.
 	var (
@@ -203,20 +227,19 @@ func runServ(c *cli.Context) {
 
 		// Check deploy key or user key.
 		if key.Type == models.KEY_TYPE_DEPLOY {
-			if key.Mode < requestedMode {
-				fail("Key permission denied", "Cannot push with deployment key: %d", key.ID)
-			}
 			// Check if this deploy key belongs to current repository.
 			if !models.HasDeployKey(key.ID, repo.ID) {
 				fail("Key access denied", "Key access denied: %d-%d", key.ID, repo.ID)
 			}
 
-			// Update deploy key activity.
 			deployKey, err := models.GetDeployKeyByRepo(key.ID, repo.ID)
 			if err != nil {
 				fail("Internal error", "GetDeployKey: %v", err)
+			} else if deployKey.IsReadOnly() && requestedMode > models.ACCESS_MODE_READ {
+				fail("Key permission denied", "Cannot push with read-only deployment key: %d", key.ID)
 			}
 
+			// Update deploy key activity.
 			deployKey.Updated = time.Now()
 			if err = models.UpdateDeployKey(deployKey); err != nil {
 				fail("Internal error", "UpdateDeployKey: %v", err)
@@ -240,24 +263,22 @@ func runServ(c *cli.Context) {
 					user.Name, requestedMode, repoPath)
 			}
 		}
+
+		if requestedMode > models.ACCESS_MODE_READ && setting.MaintenanceMode && (user == nil || !user.IsAdmin) {
+			fail("site is in maintenance mode and read-only", "")
+		}
 	}
 
 	uuid := uuid.NewV4().String()
 	os.Setenv("uuid", uuid)
-
-	// Special handle for Windows.
-	if setting.IsWindows {
-		verb = strings.Replace(verb, "-", " ", 1)
+	if requestedMode == models.ACCESS_MODE_WRITE && !isWiki {
+		os.Setenv(models.ENV_REPO_ID, com.ToStr(repo.ID))
+		if user != nil {
+			os.Setenv(models.ENV_AUTH_USER_ID, com.ToStr(user.Id))
+		}
 	}
 
-	var gitcmd *exec.Cmd
-	verbs := strings.Split(verb, " ")
-	if len(verbs) == 2 {
-		gitcmd = exec.Command(verbs[0], verbs[1], repoPath)
-	} else {
-		gitcmd = exec.Command(verb, repoPath)
-	}
-	gitcmd.Dir = setting.RepoRootPath
+	gitcmd := git.Command(setting.RepoRootPath, strings.TrimPrefix(verb, "git-"), repoPath)
 	gitcmd.Stdout = os.Stdout
 	gitcmd.Stdin = os.Stdin
 	gitcmd.Stderr = os.Stderr
@@ -266,7 +287,12 @@ func runServ(c *cli.Context) {
 	}
 
 	if requestedMode == models.ACCESS_MODE_WRITE {
-		handleUpdateTask(uuid, user, username, reponame, isWiki)
+		// Changes pushed by deploy key are recorded as pushed by repository owner.
+		pusher := user
+		if pusher == nil {
+			pusher = repoUser
+		}
+		handleUpdateTask(uuid, pusher, username, reponame, isWiki)
 	}
 
 	// Update user key activity.
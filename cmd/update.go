@@ -5,11 +5,16 @@
 package cmd
 
 import (
+	"fmt"
 	"os"
+	"strings"
 
+	"github.com/Unknwon/com"
 	"github.com/codegangsta/cli"
 
 	"github.com/gogits/gogs/models"
+	"github.com/gogits/gogs/modules/base"
+	"github.com/gogits/gogs/modules/git"
 	"github.com/gogits/gogs/modules/log"
 	"github.com/gogits/gogs/modules/setting"
 )
@@ -29,11 +34,17 @@ func runUpdate(c *cli.Context) {
 		setting.CustomConf = c.String("config")
 	}
 	cmd := os.Getenv("SSH_ORIGINAL_COMMAND")
-	if cmd == "" {
+	repoID := com.StrTo(os.Getenv(models.ENV_REPO_ID)).MustInt64()
+	if cmd == "" && repoID == 0 {
 		return
 	}
 
-	setup("update.log")
+	// Pushes over HTTP also run this hook to enforce protected tags.
+	if cmd == "" {
+		setupDatabase("update.log")
+	} else {
+		setup("update.log")
+	}
 
 	args := c.Args()
 	if len(args) != 3 {
@@ -42,6 +53,20 @@ func runUpdate(c *cli.Context) {
 		log.GitLogger.Fatal(2, "refName is empty, shouldn't use")
 	}
 
+	if repoID > 0 && strings.HasPrefix(args[0], "refs/tags/") {
+		checkProtectedTag(repoID, strings.TrimPrefix(args[0], "refs/tags/"), args[1])
+	}
+	if repoID > 0 && strings.HasPrefix(args[0], "refs/heads/") {
+		checkSignedCommits(repoID, strings.TrimPrefix(args[0], "refs/heads/"), args[2])
+	}
+	if repoID > 0 {
+		checkStorageQuota(repoID)
+	}
+
+	if cmd == "" {
+		return
+	}
+
 	task := models.UpdateTask{
 		UUID:        os.Getenv("uuid"),
 		RefName:     args[0],
@@ -53,3 +78,87 @@ func runUpdate(c *cli.Context) {
 		log.GitLogger.Fatal(2, "AddUpdateTask: %v", err)
 	}
 }
+
+// checkStorageQuota rejects push if objects received would make repositories of owner
+// exceed the storage quota. Hook runs after objects are received but before refs are
+// updated, so a single push cannot overshoot the quota.
+func checkStorageQuota(repoID int64) {
+	repo, err := models.GetRepositoryByID(repoID)
+	if err != nil {
+		fail("Internal error", "GetRepositoryByID: %v", err)
+	} else if err = repo.GetOwner(); err != nil {
+		fail("Internal error", "GetOwner: %v", err)
+	} else if repo.Owner.StorageQuotaSize() <= 0 {
+		return
+	}
+
+	// Git 2.11+ keeps received objects in quarantine directory which is the object directory
+	// of hook, otherwise they are already in repository whose recorded size is before push.
+	size, err := git.GetRepoSize(repo.RepoPath())
+	if err != nil {
+		fail("Internal error", "GetRepoSize: %v", err)
+	}
+	if len(os.Getenv("GIT_QUARANTINE_PATH")) == 0 {
+		size -= repo.Size
+	}
+
+	exceeded, err := repo.Owner.WouldExceedStorageQuota(size)
+	if err != nil {
+		fail("Internal error", "WouldExceedStorageQuota: %v", err)
+	} else if exceeded {
+		fail(fmt.Sprintf("push of %s exceeds storage quota of %s (%s)", base.FileSize(size),
+			repo.Owner.Name, base.FileSize(repo.Owner.StorageQuotaSize())), "")
+	}
+}
+
+// checkProtectedTag rejects update or deletion of existing tag
+// if it is protected against the pusher.
+func checkProtectedTag(repoID int64, tagName, oldCommitID string) {
+	// Creating a new tag is always allowed.
+	if strings.Trim(oldCommitID, "0") == "" {
+		return
+	}
+
+	repo, err := models.GetRepositoryByID(repoID)
+	if err != nil {
+		fail("Internal error", "GetRepositoryByID: %v", err)
+	}
+
+	var doer *models.User
+	if uid := com.StrTo(os.Getenv(models.ENV_AUTH_USER_ID)).MustInt64(); uid > 0 {
+		if doer, err = models.GetUserByID(uid); err != nil {
+			fail("Internal error", "GetUserByID: %v", err)
+		}
+	}
+
+	t, err := models.GetBlockingProtectedTag(repo, doer, tagName)
+	if err != nil {
+		fail("Internal error", "GetBlockingProtectedTag: %v", err)
+	} else if t != nil {
+		fail(fmt.Sprintf("tag '%s' is protected by pattern '%s' and cannot be updated or deleted", tagName, t.Pattern), "")
+	}
+}
+
+// checkSignedCommits rejects push to branch if its new commits are not signed
+// by GPG keys registered by users, as required by repository.
+func checkSignedCommits(repoID int64, branchName, newCommitID string) {
+	// Deleting a branch adds no commit.
+	if strings.Trim(newCommitID, "0") == "" {
+		return
+	}
+
+	repo, err := models.GetRepositoryByID(repoID)
+	if err != nil {
+		fail("Internal error", "GetRepositoryByID: %v", err)
+	} else if repo.SignedCommitMode == models.SIGNED_COMMIT_NONE {
+		return
+	}
+
+	unsigned, err := repo.UnsignedCommits(newCommitID)
+	if err != nil {
+		fail("Internal error", "UnsignedCommits: %v", err)
+	} else if len(unsigned) > 0 {
+		fail(fmt.Sprintf("branch '%s' only accepts commits signed by GPG keys registered on Gogs, following commits are not:\n%s",
+			branchName, strings.Join(unsigned, "\n")), "")
+	}
+}
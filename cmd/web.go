@@ -104,12 +104,20 @@ func newMacaron() *macaron.Macaron {
 		m.Use(macaron.Logger())
 	}
 	m.Use(macaron.Recovery())
+	m.Use(middleware.SecurityHeaders())
 	if setting.EnableGzip {
 		m.Use(gzip.Gziper())
 	}
 	if setting.Protocol == setting.FCGI {
 		m.SetURLPrefix(setting.AppSubUrl)
 	}
+	// Custom assets take precedence over bundled ones.
+	m.Use(macaron.Static(
+		path.Join(setting.CustomPath, "public"),
+		macaron.StaticOptions{
+			SkipLogging: setting.DisableRouterLog,
+		},
+	))
 	m.Use(macaron.Static(
 		path.Join(setting.StaticRootPath, "public"),
 		macaron.StaticOptions{
@@ -160,6 +168,13 @@ func newMacaron() *macaron.Macaron {
 		Header:     "X-Csrf-Token",
 		CookiePath: setting.AppSubUrl,
 	}))
+	// Instance still serves reads in maintenance mode, so it is reported
+	// by health check as a header instead of a failure.
+	m.Use(func(ctx *macaron.Context) {
+		if setting.MaintenanceMode && ctx.Req.URL.Path == "/healthcheck" {
+			ctx.Resp.Header().Set("X-Gogs-Maintenance-Mode", "enabled")
+		}
+	})
 	m.Use(toolbox.Toolboxer(m, toolbox.Options{
 		HealthCheckFuncs: []*toolbox.HealthCheckFuncDesc{
 			&toolbox.HealthCheckFuncDesc{
@@ -197,12 +212,25 @@ func runWeb(ctx *cli.Context) {
 	m.Get("/^:type(issues|pulls)$", reqSignIn, user.Issues)
 
 	// ***** START: API *****
+	// Endpoints about account rather than repositories require explicit scope of access token.
+	reqTokenUser := middleware.ApiReqTokenScope(models.ACCESS_TOKEN_SCOPE_USER)
+
 	// FIXME: custom form error response.
 	m.Group("/api", func() {
 		m.Group("/v1", func() {
 			// Miscellaneous.
 			m.Post("/markdown", bindIgnErr(apiv1.MarkdownForm{}), v1.Markdown)
 			m.Post("/markdown/raw", v1.MarkdownRaw)
+			m.Get("/version", v1.Version)
+			m.Get("/rate_limit", v1.GetRateLimit)
+			m.Combo("/csrf").Get(v1.GetCsrfToken).
+				Post(v1.RenewCsrfToken)
+
+			// Explore.
+			m.Group("/explore", func() {
+				m.Get("/users", v1.ExploreUsers)
+				m.Get("/orgs", v1.ExploreOrgs)
+			})
 
 			// Users.
 			m.Group("/users", func() {
@@ -210,38 +238,142 @@ func runWeb(ctx *cli.Context) {
 
 				m.Group("/:username", func() {
 					m.Get("", v1.GetUserInfo)
+					m.Get("/orgs", v1.ListUserOrgs)
+					m.Get("/followers", v1.ListFollowers)
+					m.Get("/following", v1.ListFollowing)
 
 					m.Group("/tokens", func() {
 						m.Combo("").Get(v1.ListAccessTokens).
 							Post(bind(v1.CreateAccessTokenForm{}), v1.CreateAccessToken)
 					}, middleware.ApiReqBasicAuth())
+
+					m.Group("/exports", func() {
+						m.Post("", v1.CreateUserExport)
+						m.Combo("/:id:int").Get(v1.GetUserExport).
+							Delete(v1.DeleteUserExport)
+						m.Get("/:id:int/archive", v1.GetUserExportArchive)
+					}, reqTokenUser)
 				})
 			})
 
+			m.Get("/user", middleware.ApiReqToken(), v1.GetAuthenticatedUser)
+			m.Group("/user/sessions", func() {
+				m.Combo("").Get(v1.ListMySessions).
+					Delete(v1.RevokeMyOtherSessions)
+				m.Delete("/:id:int", v1.RevokeMySession)
+			}, reqTokenUser)
+			m.Combo("/user/following/:username", reqTokenUser).Get(v1.CheckMyFollowing).
+				Put(v1.Follow).Delete(v1.Unfollow)
+			m.Group("/user/oauth2/authorizations", func() {
+				m.Get("", v1.ListMyOAuth2Authorizations)
+				m.Delete("/:id:int", v1.RevokeMyOAuth2Authorization)
+			}, reqTokenUser)
+
+			m.Group("/notifications", func() {
+				m.Combo("").Get(v1.ListNotifications).
+					Patch(v1.MarkNotificationsRead)
+				m.Patch("/:id:int", v1.MarkNotificationRead)
+			}, reqTokenUser)
+
 			// Repositories.
 			m.Combo("/user/repos", middleware.ApiReqToken()).Get(v1.ListMyRepos).
-				Post(bind(api.CreateRepoOption{}), v1.CreateRepo)
-			m.Post("/org/:org/repos", middleware.ApiReqToken(), bind(api.CreateRepoOption{}), v1.CreateOrgRepo)
+				Post(bind(v1.CreateRepoOption{}), v1.CreateRepo)
+			m.Post("/org/:org/repos", middleware.ApiReqToken(), bind(v1.CreateRepoOption{}), v1.CreateOrgRepo)
+
+			// Administration.
+			m.Combo("/admin/rebuild", middleware.ApiReqAdmin()).Get(v1.GetRebuildStatus).
+				Post(v1.RebuildDerivedData)
+			m.Get("/admin/stats", middleware.ApiReqAdmin(), v1.GetServerStats)
+
+			// Organizations.
+			m.Get("/user/orgs", middleware.ApiReqToken(), v1.ListMyOrgs)
+			m.Get("/orgs/:org/members", v1.ListOrgMembers)
+			m.Get("/orgs/:org/members/:username", v1.GetOrgMember)
+			m.Group("/orgs/:org", func() {
+				m.Combo("/avatar").Post(v1.UpdateOrgAvatar).
+					Delete(v1.DeleteOrgAvatar)
+				m.Combo("/members/:username").Put(v1.AddOrgMember).
+					Delete(v1.RemoveOrgMember)
+				m.Combo("/teams").Get(v1.ListOrgTeams).
+					Post(bind(v1.CreateTeamOption{}), v1.CreateTeam)
+			}, reqTokenUser)
+			m.Group("/teams/:teamid", func() {
+				m.Combo("").Get(v1.GetTeam).
+					Patch(bind(v1.EditTeamOption{}), v1.EditTeam).
+					Delete(v1.DeleteTeam)
+				m.Get("/members", v1.ListTeamMembers)
+				m.Combo("/members/:username").Put(v1.AddTeamMember).
+					Delete(v1.RemoveTeamMember)
+				m.Get("/repos", v1.ListTeamRepos)
+				m.Combo("/repos/:reponame").Put(v1.AddTeamRepo).
+					Delete(v1.RemoveTeamRepo)
+			}, reqTokenUser)
 
 			m.Group("/repos", func() {
 				m.Get("/search", v1.SearchRepos)
 			})
 
+			// Issues.
+			m.Get("/issues/search", middleware.ApiReqToken(), v1.SearchIssues)
+
 			m.Group("/repos", func() {
 				m.Post("/migrate", bindIgnErr(auth.MigrateRepoForm{}), v1.MigrateRepo)
 				m.Combo("/:username/:reponame").Get(v1.GetRepo).
+					Patch(bind(v1.EditRepoOption{}), v1.EditRepo).
 					Delete(v1.DeleteRepo)
 
 				m.Group("/:username/:reponame", func() {
 					m.Combo("/hooks").Get(v1.ListRepoHooks).
 						Post(bind(api.CreateHookOption{}), v1.CreateRepoHook)
 					m.Patch("/hooks/:id:int", bind(api.EditHookOption{}), v1.EditRepoHook)
+					m.Combo("/hooks/:id:int/headers").Get(v1.GetRepoHookHeaders).
+						Put(v1.SetRepoHookHeaders)
 					m.Get("/raw/*", middleware.RepoRef(), v1.GetRepoRawFile)
+					m.Get("/readme", v1.GetRepoReadme)
+					m.Get("/blame/*", v1.GetRepoBlame)
+					m.Get("/languages", v1.GetRepoLanguages)
+					m.Get("/tags", v1.ListRepoTags)
+					m.Get("/commits", v1.ListRepoCommits)
+					m.Get("/commits/:sha", v1.GetRepoCommit)
+					m.Get("/merge-base", v1.GetMergeBase)
+					m.Get("/git/resolve/*", v1.ResolveRepoRef)
+					m.Get("/git/refs", v1.ListRepoRefs)
+					m.Get("/git/refs/*", v1.ListRepoRefs)
 					m.Get("/archive/*", v1.GetRepoArchive)
+					m.Post("/issues/batch", middleware.ApiRequireRepoUnit(models.UNIT_ISSUES), middleware.ApiRequireRepoNotArchived(),
+						bind(v1.BatchIssuesOption{}), v1.BatchUpdateIssues)
+					m.Patch("/issues/:index", middleware.ApiRequireRepoUnit(models.UNIT_ISSUES), middleware.ApiRequireRepoNotArchived(),
+						bind(v1.EditIssueOption{}), v1.EditIssue)
+					m.Combo("/issues/:index/lock", middleware.ApiRequireRepoNotArchived()).Put(bind(v1.LockIssueOption{}), v1.LockIssue).
+						Delete(v1.UnlockIssue)
+					m.Combo("/issues/:index/pin", middleware.ApiRequireRepoNotArchived()).Put(v1.PinIssue).Delete(v1.UnpinIssue)
+					m.Get("/issues/:index/timeline", v1.ListIssueTimeline)
+					m.Group("/issues/:index/comments", func() {
+						m.Combo("").Get(v1.ListIssueComments).
+							Post(middleware.ApiRequireRepoNotArchived(), bind(v1.CreateIssueCommentOption{}), v1.CreateIssueComment)
+						m.Combo("/:id:int", middleware.ApiRequireRepoNotArchived()).Patch(bind(v1.EditIssueCommentOption{}), v1.EditIssueComment).
+							Delete(v1.DeleteIssueComment)
+					})
+					m.Post("/forks", bind(v1.CreateForkOption{}), v1.CreateFork)
+					m.Post("/generate", bind(v1.GenerateRepoOption{}), v1.GenerateRepo)
+					m.Post("/mirror-sync", middleware.ApiRequireRepoNotArchived(), v1.MirrorSync)
+					m.Post("/cache/flush", middleware.ApiReqAdmin(), v1.FlushRepoCache)
+					m.Combo("/default-reviewers").Get(v1.GetDefaultReviewers).
+						Put(bind(v1.EditDefaultReviewersOption{}), v1.EditDefaultReviewers)
+
+					m.Group("/pulls", func() {
+						m.Combo("").Get(v1.ListPullRequests).
+							Post(middleware.ApiRequireRepoNotArchived(), bind(v1.CreatePullRequestOption{}), v1.CreatePullRequest)
+						m.Get("/:index", v1.GetPullRequest)
+						m.Get("/:index([0-9]+)\\.:ext(diff|patch)", v1.GetPullRequestPatch)
+						m.Post("/:index/merge", middleware.ApiRequireRepoNotArchived(), bind(v1.MergePullRequestOption{}), v1.MergePullRequest)
+						m.Combo("/:index/reviews").Get(v1.ListPullReviews).
+							Post(middleware.ApiRequireRepoNotArchived(), bind(v1.CreateReviewOption{}), v1.CreatePullReview)
+					}, middleware.ApiRequireRepoUnit(models.UNIT_PULLS))
 
 					m.Group("/keys", func() {
 						m.Combo("").Get(v1.ListRepoDeployKeys).
-							Post(bind(api.CreateDeployKeyOption{}), v1.CreateRepoDeployKey)
+							Post(bind(v1.CreateDeployKeyOption{}), v1.CreateRepoDeployKey)
 						m.Combo("/:id").Get(v1.GetRepoDeployKey).
 							Delete(v1.DeleteRepoDeploykey)
 					})
@@ -251,7 +383,7 @@ func runWeb(ctx *cli.Context) {
 			m.Any("/*", func(ctx *middleware.Context) {
 				ctx.Error(404)
 			})
-		})
+		}, middleware.ApiRateLimiter("/api/v1/rate_limit"))
 	}, ignSignIn)
 	// ***** END: API *****
 
@@ -259,12 +391,19 @@ func runWeb(ctx *cli.Context) {
 	m.Group("/user", func() {
 		m.Get("/login", user.SignIn)
 		m.Post("/login", bindIgnErr(auth.SignInForm{}), user.SignInPost)
+		m.Combo("/login/security_key").Get(user.SignInSecurityKey).
+			Post(bindIgnErr(auth.SecurityKeySignInForm{}), user.SignInSecurityKeyPost)
 		m.Get("/sign_up", user.SignUp)
 		m.Post("/sign_up", bindIgnErr(auth.RegisterForm{}), user.SignUpPost)
 		m.Get("/reset_password", user.ResetPasswd)
 		m.Post("/reset_password", user.ResetPasswdPost)
 	}, reqSignOut)
 
+	m.Group("/user/notifications", func() {
+		m.Get("", user.Notifications)
+		m.Post("/read", user.MarkNotificationsRead)
+	}, reqSignIn)
+
 	m.Group("/user/settings", func() {
 		m.Get("", user.Settings)
 		m.Post("", bindIgnErr(auth.UpdateProfileForm{}), user.SettingsPost)
@@ -272,22 +411,45 @@ func runWeb(ctx *cli.Context) {
 		m.Combo("/email").Get(user.SettingsEmails).
 			Post(bindIgnErr(auth.AddEmailForm{}), user.SettingsEmailPost)
 		m.Post("/email/delete", user.DeleteEmail)
+		m.Combo("/notifications").Get(user.SettingsNotification).
+			Post(bindIgnErr(auth.UpdateNotificationForm{}), user.SettingsNotificationPost)
 		m.Get("/password", user.SettingsPassword)
 		m.Post("/password", bindIgnErr(auth.ChangePasswordForm{}), user.SettingsPasswordPost)
 		m.Combo("/ssh").Get(user.SettingsSSHKeys).
 			Post(bindIgnErr(auth.AddSSHKeyForm{}), user.SettingsSSHKeysPost)
+		m.Post("/ssh/import", bindIgnErr(auth.ImportSSHKeysForm{}), user.SettingsSSHKeysImportPost)
 		m.Post("/ssh/delete", user.DeleteSSHKey)
+		m.Combo("/security_keys").Get(user.SettingsSecurityKeys).
+			Post(bindIgnErr(auth.AddSecurityKeyForm{}), user.SettingsSecurityKeysPost)
+		m.Post("/security_keys/delete", user.DeleteSecurityKey)
+		m.Combo("/gpg_keys").Get(user.SettingsGPGKeys).
+			Post(bindIgnErr(auth.AddGPGKeyForm{}), user.SettingsGPGKeysPost)
+		m.Post("/gpg_keys/delete", user.DeleteGPGKey)
+		m.Get("/sessions", user.SettingsSessions)
+		m.Post("/sessions/revoke", user.RevokeSession)
+		m.Post("/sessions/revoke_others", user.RevokeOtherSessions)
 		m.Combo("/applications").Get(user.SettingsApplications).
 			Post(bindIgnErr(auth.NewAccessTokenForm{}), user.SettingsApplicationsPost)
 		m.Post("/applications/delete", user.SettingsDeleteApplication)
+		m.Post("/applications/oauth2", bindIgnErr(auth.NewOAuth2ApplicationForm{}), user.SettingsOAuth2ApplicationsPost)
+		m.Post("/applications/oauth2/delete", user.SettingsDeleteOAuth2Application)
+		m.Post("/applications/grants/revoke", user.SettingsRevokeOAuth2Grant)
 		m.Route("/delete", "GET,POST", user.SettingsDelete)
 	}, reqSignIn, func(ctx *middleware.Context) {
 		ctx.Data["PageIsUserSettings"] = true
 	})
 
+	// OAuth2 provider.
+	m.Group("/login/oauth", func() {
+		m.Combo("/authorize", reqSignIn).Get(user.OAuth2Authorize).
+			Post(user.OAuth2AuthorizePost)
+		m.Post("/access_token", ignSignInAndCsrf, user.OAuth2AccessToken)
+	})
+
 	m.Group("/user", func() {
 		// r.Get("/feeds", binding.Bind(auth.FeedsForm{}), user.Feeds)
 		m.Any("/activate", user.Activate)
+		m.Post("/activate/resend", user.Activate)
 		m.Any("/activate_email", user.ActivateEmail)
 		m.Get("/email2user", user.Email2User)
 		m.Get("/forget_password", user.ForgotPasswd)
@@ -315,6 +477,7 @@ func runWeb(ctx *cli.Context) {
 			m.Post("/new", bindIgnErr(auth.AdminCrateUserForm{}), admin.NewUserPost)
 			m.Get("/:userid", admin.EditUser)
 			m.Post("/:userid", bindIgnErr(auth.AdminEditUserForm{}), admin.EditUserPost)
+			m.Post("/:userid/approve", admin.ApproveUser)
 			m.Post("/:userid/delete", admin.DeleteUser)
 		})
 
@@ -324,6 +487,7 @@ func runWeb(ctx *cli.Context) {
 
 		m.Group("/repos", func() {
 			m.Get("", admin.Repositories)
+			m.Post("/:id/gc", admin.GitGcRepo)
 		})
 
 		m.Group("/auths", func() {
@@ -379,6 +543,11 @@ func runWeb(ctx *cli.Context) {
 
 	reqRepoAdmin := middleware.RequireRepoAdmin()
 	reqRepoPusher := middleware.RequireRepoPusher()
+	reqRepoNotArchived := middleware.RequireRepoNotArchived()
+	reqIssuesUnit := middleware.RequireRepoUnit(models.UNIT_ISSUES)
+	reqPullsUnit := middleware.RequireRepoUnit(models.UNIT_PULLS)
+	reqWikiUnit := middleware.RequireRepoUnit(models.UNIT_WIKI)
+	reqReleasesUnit := middleware.RequireRepoUnit(models.UNIT_RELEASES)
 
 	// ***** START: Organization *****
 	m.Group("/org", func() {
@@ -409,6 +578,10 @@ func runWeb(ctx *cli.Context) {
 				m.Combo("").Get(org.Settings).
 					Post(bindIgnErr(auth.UpdateOrgSettingForm{}), org.SettingsPost)
 				m.Post("/avatar", binding.MultipartForm(auth.UploadAvatarForm{}), org.SettingsAvatar)
+				m.Combo("/repo_defaults").Get(org.RepoDefaults).
+					Post(bindIgnErr(auth.OrgRepoDefaultsForm{}), org.RepoDefaultsPost)
+				m.Combo("/member_defaults").Get(org.MemberDefaults).
+					Post(bindIgnErr(auth.OrgMemberDefaultsForm{}), org.MemberDefaultsPost)
 
 				m.Group("/hooks", func() {
 					m.Get("", org.Webhooks)
@@ -437,6 +610,8 @@ func runWeb(ctx *cli.Context) {
 		m.Post("/migrate", bindIgnErr(auth.MigrateRepoForm{}), repo.MigratePost)
 		m.Combo("/fork/:repoid").Get(repo.Fork).
 			Post(bindIgnErr(auth.CreateRepoForm{}), repo.ForkPost)
+		m.Combo("/generate/:repoid").Get(repo.Generate).
+			Post(bindIgnErr(auth.GenerateRepoForm{}), repo.GeneratePost)
 	}, reqSignIn)
 
 	m.Group("/:username/:reponame", func() {
@@ -452,6 +627,7 @@ func runWeb(ctx *cli.Context) {
 				m.Post("/gogs/new", bindIgnErr(auth.NewWebhookForm{}), repo.WebHooksNewPost)
 				m.Post("/slack/new", bindIgnErr(auth.NewSlackHookForm{}), repo.SlackHooksNewPost)
 				m.Get("/:id", repo.WebHooksEdit)
+				m.Post("/:id/test", repo.TestWebhook)
 				m.Post("/gogs/:id", bindIgnErr(auth.NewWebhookForm{}), repo.WebHooksEditPost)
 				m.Post("/slack/:id", bindIgnErr(auth.NewSlackHookForm{}), repo.SlackHooksEditPost)
 
@@ -464,20 +640,27 @@ func runWeb(ctx *cli.Context) {
 
 			m.Group("/keys", func() {
 				m.Combo("").Get(repo.DeployKeys).
-					Post(bindIgnErr(auth.AddSSHKeyForm{}), repo.DeployKeysPost)
+					Post(bindIgnErr(auth.AddDeployKeyForm{}), repo.DeployKeysPost)
 				m.Post("/delete", repo.DeleteDeployKey)
 			})
 
+			m.Group("/tags", func() {
+				m.Combo("").Get(repo.ProtectedTags).
+					Post(bindIgnErr(auth.ProtectedTagForm{}), repo.ProtectedTagsPost)
+				m.Post("/delete", repo.DeleteProtectedTag)
+			})
+
 		}, func(ctx *middleware.Context) {
 			ctx.Data["PageIsSettings"] = true
 		})
 	}, reqSignIn, middleware.RepoAssignment(), reqRepoAdmin, middleware.RepoRef())
 
-	m.Group("/:username/:reponame", func() {
-		m.Get("/action/:action", repo.Action)
+	m.Get("/:username/:reponame/action/:action", reqSignIn, middleware.RepoAssignment(), repo.Action)
 
+	// Archived repository is read-only, nothing in this group is allowed to change it.
+	m.Group("/:username/:reponame", func() {
 		m.Group("/issues", func() {
-			m.Combo("/new").Get(repo.NewIssue).
+			m.Combo("/new", reqIssuesUnit).Get(repo.NewIssue).
 				Post(bindIgnErr(auth.CreateIssueForm{}), repo.NewIssuePost)
 
 			m.Combo("/:index/comments").Post(bindIgnErr(auth.CreateCommentForm{}), repo.NewComment)
@@ -485,6 +668,10 @@ func runWeb(ctx *cli.Context) {
 				m.Post("/label", repo.UpdateIssueLabel)
 				m.Post("/milestone", repo.UpdateIssueMilestone)
 				m.Post("/assignee", repo.UpdateIssueAssignee)
+				m.Post("/lock", bindIgnErr(auth.LockIssueForm{}), repo.LockIssue)
+				m.Post("/unlock", repo.UnlockIssue)
+				m.Post("/pin", repo.PinIssue)
+				m.Post("/unpin", repo.UnpinIssue)
 			}, reqRepoAdmin)
 
 			m.Group("/:index", func() {
@@ -493,6 +680,8 @@ func runWeb(ctx *cli.Context) {
 			})
 		})
 		m.Post("/comments/:id", repo.UpdateCommentContent)
+		m.Post("/pulls/:index/files/comment", reqPullsUnit, bindIgnErr(auth.CodeCommentForm{}), repo.NewCodeComment)
+		m.Post("/pulls/:index/review", reqPullsUnit, bindIgnErr(auth.SubmitReviewForm{}), repo.SubmitReview)
 		m.Group("/labels", func() {
 			m.Post("/new", bindIgnErr(auth.CreateLabelForm{}), repo.NewLabel)
 			m.Post("/edit", bindIgnErr(auth.CreateLabelForm{}), repo.UpdateLabel)
@@ -513,16 +702,16 @@ func runWeb(ctx *cli.Context) {
 			m.Get("/edit/:tagname", repo.EditRelease)
 			m.Post("/edit/:tagname", bindIgnErr(auth.EditReleaseForm{}), repo.EditReleasePost)
 			m.Post("/delete", repo.DeleteRelease)
-		}, reqRepoAdmin, middleware.RepoRef())
+		}, reqReleasesUnit, reqRepoAdmin, middleware.RepoRef())
 
-		m.Combo("/compare/*").Get(repo.CompareAndPullRequest).
+		m.Combo("/compare/*", reqPullsUnit).Get(repo.CompareAndPullRequest).
 			Post(bindIgnErr(auth.CreateIssueForm{}), repo.CompareAndPullRequestPost)
-	}, reqSignIn, middleware.RepoAssignment())
+	}, reqSignIn, middleware.RepoAssignment(), reqRepoNotArchived)
 
 	m.Group("/:username/:reponame", func() {
 		m.Group("", func() {
-			m.Get("/releases", repo.Releases)
-			m.Get("/^:type(issues|pulls)$", repo.RetrieveLabels, repo.Issues)
+			m.Get("/releases", reqReleasesUnit, repo.Releases)
+			m.Get("/^:type(issues|pulls)$", middleware.RequireRepoIssueUnit(), repo.RetrieveLabels, repo.Issues)
 			m.Get("/labels/", repo.RetrieveLabels, repo.Labels)
 			m.Get("/milestones", repo.Milestones)
 		}, middleware.RepoRef(),
@@ -542,16 +731,17 @@ func runWeb(ctx *cli.Context) {
 					Post(bindIgnErr(auth.NewWikiForm{}), repo.NewWikiPost)
 				m.Combo("/:page/_edit").Get(repo.EditWiki).
 					Post(bindIgnErr(auth.NewWikiForm{}), repo.EditWikiPost)
-			}, reqSignIn, reqRepoPusher)
-		}, middleware.RepoRef())
+				m.Post("/:page/_delete", repo.DeleteWikiPagePost)
+			}, reqSignIn, reqRepoPusher, reqRepoNotArchived)
+		}, reqWikiUnit, middleware.RepoRef())
 
 		m.Get("/archive/*", repo.Download)
 
 		m.Group("/pulls/:index", func() {
 			m.Get("/commits", repo.ViewPullCommits)
 			m.Get("/files", repo.ViewPullFiles)
-			m.Post("/merge", reqRepoAdmin, repo.MergePullRequest)
-		})
+			m.Post("/merge", reqRepoAdmin, reqRepoNotArchived, repo.MergePullRequest)
+		}, reqPullsUnit)
 
 		m.Group("", func() {
 			m.Get("/src/*", repo.Home)
@@ -6,6 +6,7 @@ package models
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/gogits/gogs/modules/log"
 )
@@ -113,6 +114,32 @@ func (u *User) GetAccessibleRepositories() ([]*Repository, error) {
 	return repos, x.Where("owner_id != ?", u.Id).In("id", repoIDs).Desc("updated").Find(&repos)
 }
 
+// GetOwnedAndAccessibleRepositories returns repositories owned by or accessible to user
+// in given order, along with access modes of repositories that are not owned by user.
+func (u *User) GetOwnedAndAccessibleRepositories(sort, order string) ([]*Repository, map[int64]AccessMode, error) {
+	accesses := make([]*Access, 0, 10)
+	if err := x.Find(&accesses, &Access{UserID: u.Id}); err != nil {
+		return nil, nil, err
+	}
+
+	modes := make(map[int64]AccessMode, len(accesses))
+	cond := "owner_id=?"
+	args := []interface{}{u.Id}
+	if len(accesses) > 0 {
+		cond += " OR id IN (?" + strings.Repeat(",?", len(accesses)-1) + ")"
+		for _, access := range accesses {
+			modes[access.RepoID] = access.Mode
+			args = append(args, access.RepoID)
+		}
+	}
+
+	repos := make([]*Repository, 0, 10+len(accesses))
+	if err := x.Where(cond, args...).OrderBy(repoOrderBy(sort, order)).Find(&repos); err != nil {
+		return nil, nil, err
+	}
+	return repos, modes, nil
+}
+
 func maxAccessMode(modes ...AccessMode) AccessMode {
 	max := ACCESS_MODE_NONE
 	for _, mode := range modes {
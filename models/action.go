@@ -530,12 +530,18 @@ func CommitRepoAction(
 		}
 
 	case PUSH_TAG: // Create
-		return PrepareWebhooks(repo, HOOK_EVENT_CREATE, &api.CreatePayload{
+		tagAction := HOOK_TAG_CREATED
+		if !strings.HasPrefix(oldCommitID, "0000000") {
+			tagAction = HOOK_TAG_UPDATED
+		} else if err = PrepareWebhooks(repo, HOOK_EVENT_CREATE, &api.CreatePayload{
 			Ref:     refName,
 			RefType: "tag",
 			Repo:    payloadRepo,
 			Sender:  payloadSender,
-		})
+		}); err != nil {
+			return fmt.Errorf("PrepareWebhooks: %v", err)
+		}
+		return PreparePushTagWebhooks(repo, u, tagAction, refFullName, oldCommitID, newCommitID)
 	}
 
 	return nil
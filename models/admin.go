@@ -14,6 +14,7 @@ type NoticeType int
 
 const (
 	NOTICE_REPOSITORY NoticeType = iota + 1
+	NOTICE_USER
 )
 
 // Notice represents a system notice for admin.
@@ -44,6 +45,11 @@ func CreateRepositoryNotice(desc string) error {
 	return CreateNotice(NOTICE_REPOSITORY, desc)
 }
 
+// CreateUserNotice creates new system notice with type NOTICE_USER.
+func CreateUserNotice(desc string) error {
+	return CreateNotice(NOTICE_USER, desc)
+}
+
 // CountNotices returns number of notices.
 func CountNotices() int64 {
 	count, _ := x.Count(new(Notice))
@@ -0,0 +1,170 @@
+// Copyright 2015 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gogits/gogs/modules/log"
+)
+
+// RebuildStatus represents progress of rebuilding derived data.
+type RebuildStatus struct {
+	IsRunning bool      `json:"is_running"`
+	Stage     string    `json:"stage"`
+	Done      int       `json:"done"`
+	Total     int       `json:"total"`
+	Started   time.Time `json:"started_at"`
+	Finished  time.Time `json:"finished_at"`
+	Summary   []string  `json:"summary"`
+}
+
+var (
+	rebuildLock   sync.RWMutex
+	rebuildStatus RebuildStatus
+)
+
+// GetRebuildStatus returns a copy of current or last rebuild progress.
+func GetRebuildStatus() RebuildStatus {
+	rebuildLock.RLock()
+	defer rebuildLock.RUnlock()
+
+	status := rebuildStatus
+	status.Summary = append([]string{}, rebuildStatus.Summary...)
+	return status
+}
+
+func updateRebuildStatus(f func(status *RebuildStatus)) {
+	rebuildLock.Lock()
+	f(&rebuildStatus)
+	rebuildLock.Unlock()
+}
+
+// IsRebuildRunning returns true if derived data is being rebuilt.
+func IsRebuildRunning() bool {
+	return taskStatusPool.IsRunning(_REBUILD_DERIVED)
+}
+
+// checkRepoIssueCounts corrects issue and pull request counters of all repositories,
+// and returns number of corrected repositories.
+func checkRepoIssueCounts() int {
+	count := func(repoID int64, isPull, isClosed bool) (int, error) {
+		sess := x.Where("repo_id=?", repoID).And("is_pull=?", isPull)
+		if isClosed {
+			sess.And("is_closed=?", true)
+		}
+		num, err := sess.Count(new(Issue))
+		return int(num), err
+	}
+
+	corrected := 0
+	repos := make([]*Repository, 0, 10)
+	if err := x.Where("id > 0").Find(&repos); err != nil {
+		log.Error(4, "find repositories: %v", err)
+		return 0
+	}
+	for _, repo := range repos {
+		var nums [4]int
+		var err error
+		for i, opt := range [][2]bool{{false, false}, {false, true}, {true, false}, {true, true}} {
+			if nums[i], err = count(repo.ID, opt[0], opt[1]); err != nil {
+				break
+			}
+		}
+		if err != nil {
+			log.Error(4, "count issues[%d]: %v", repo.ID, err)
+			continue
+		}
+
+		if repo.NumIssues == nums[0] && repo.NumClosedIssues == nums[1] &&
+			repo.NumPulls == nums[2] && repo.NumClosedPulls == nums[3] {
+			continue
+		}
+
+		log.Trace("Updating repository issue counts: %d", repo.ID)
+		if _, err = x.Exec("UPDATE `repository` SET num_issues=?, num_closed_issues=?, num_pulls=?, num_closed_pulls=? WHERE id=?",
+			nums[0], nums[1], nums[2], nums[3], repo.ID); err != nil {
+			log.Error(4, "update repository issue counts[%d]: %v", repo.ID, err)
+			continue
+		}
+		corrected++
+	}
+	return corrected
+}
+
+// updateAllRepoSizes updates size of all repositories and returns number of updated repositories.
+func updateAllRepoSizes() int {
+	updated := 0
+	if err := x.Where("id > 0").Iterate(new(Repository),
+		func(idx int, bean interface{}) error {
+			repo := bean.(*Repository)
+			if err := repo.UpdateSize(); err != nil {
+				log.Error(4, "UpdateSize[%d]: %v", repo.ID, err)
+			} else {
+				updated++
+			}
+			return nil
+		}); err != nil {
+		log.Error(4, "updateAllRepoSizes: %v", err)
+	}
+	return updated
+}
+
+// RebuildDerivedData recomputes all aggregated data stored in database,
+// e.g. counters and repository sizes. Progress can be checked by GetRebuildStatus.
+func RebuildDerivedData() {
+	if taskStatusPool.IsRunning(_REBUILD_DERIVED) {
+		return
+	}
+	taskStatusPool.Start(_REBUILD_DERIVED)
+	defer taskStatusPool.Stop(_REBUILD_DERIVED)
+
+	log.Trace("Doing: RebuildDerivedData")
+
+	type step struct {
+		desc string
+		run  func() int
+	}
+	steps := make([]step, 0, len(repoStatsCheckers)+2)
+	for i := range repoStatsCheckers {
+		checker := repoStatsCheckers[i]
+		steps = append(steps, step{checker.desc, func() int {
+			return repoStatsCheck(checker)
+		}})
+	}
+	steps = append(steps,
+		step{"repository issue counts", checkRepoIssueCounts},
+		step{"repository sizes", updateAllRepoSizes})
+
+	updateRebuildStatus(func(status *RebuildStatus) {
+		*status = RebuildStatus{
+			IsRunning: true,
+			Total:     len(steps),
+			Started:   time.Now(),
+		}
+	})
+
+	for _, s := range steps {
+		updateRebuildStatus(func(status *RebuildStatus) {
+			status.Stage = s.desc
+		})
+		num := s.run()
+		updateRebuildStatus(func(status *RebuildStatus) {
+			status.Done++
+			status.Summary = append(status.Summary, fmt.Sprintf("%s: %d record(s) updated", s.desc, num))
+		})
+	}
+
+	status := GetRebuildStatus()
+	updateRebuildStatus(func(status *RebuildStatus) {
+		status.IsRunning = false
+		status.Stage = ""
+		status.Finished = time.Now()
+	})
+	log.Info("Derived data rebuilt in %s:\n%s", time.Since(status.Started), strings.Join(status.Summary, "\n"))
+}
@@ -0,0 +1,73 @@
+// Copyright 2015 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/Unknwon/com"
+)
+
+// Git operations served since start of process, only operations over HTTP
+// and builtin SSH server are counted because others run in separate processes.
+var (
+	gitStatsStarted = time.Now()
+	numGitClones    int64
+	numGitPushes    int64
+)
+
+// IncreaseGitClones counts a clone or fetch of repository.
+func IncreaseGitClones() {
+	atomic.AddInt64(&numGitClones, 1)
+}
+
+// IncreaseGitPushes counts a push to repository.
+func IncreaseGitPushes() {
+	atomic.AddInt64(&numGitPushes, 1)
+}
+
+// ServerStats represents a point-in-time snapshot of server usage.
+type ServerStats struct {
+	Users    int64 `json:"users"`
+	Orgs     int64 `json:"orgs"`
+	Repos    int64 `json:"repos"`
+	Issues   int64 `json:"issues"`
+	Pulls    int64 `json:"pulls"`
+	RepoSize int64 `json:"repo_size"` // In bytes, computed from stored sizes of repositories.
+
+	GitClones int64     `json:"git_clones"`
+	GitPushes int64     `json:"git_pushes"`
+	Since     time.Time `json:"since"` // When git operations started to be counted.
+}
+
+// GetServerStats returns current server usage, sizes of repositories are not recalculated.
+func GetServerStats() (*ServerStats, error) {
+	stats := &ServerStats{
+		Users:     CountUsers(),
+		Orgs:      CountOrganizations(),
+		Repos:     CountRepositories(),
+		GitClones: atomic.LoadInt64(&numGitClones),
+		GitPushes: atomic.LoadInt64(&numGitPushes),
+		Since:     gitStatsStarted,
+	}
+
+	var err error
+	if stats.Issues, err = x.Where("is_pull=?", false).Count(new(Issue)); err != nil {
+		return nil, fmt.Errorf("count issues: %v", err)
+	}
+	if stats.Pulls, err = x.Where("is_pull=?", true).Count(new(Issue)); err != nil {
+		return nil, fmt.Errorf("count pull requests: %v", err)
+	}
+
+	results, err := x.Query("SELECT SUM(size) AS total FROM `repository`")
+	if err != nil {
+		return nil, fmt.Errorf("sum repository sizes: %v", err)
+	} else if len(results) > 0 {
+		stats.RepoSize = com.StrTo(results[0]["total"]).MustInt64()
+	}
+	return stats, nil
+}
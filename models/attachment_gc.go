@@ -0,0 +1,138 @@
+// Copyright 2015 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gogits/gogs/modules/base"
+	"github.com/gogits/gogs/modules/log"
+	"github.com/gogits/gogs/modules/setting"
+)
+
+// orphanedAttachmentsCond matches attachments that are not referenced by any
+// existing issue, comment or release, including uploads that were never submitted.
+const orphanedAttachmentsCond = "(issue_id=0 AND comment_id=0 AND release_id=0)" +
+	" OR (issue_id>0 AND issue_id NOT IN (SELECT id FROM `issue`))" +
+	" OR (comment_id>0 AND comment_id NOT IN (SELECT id FROM `comment`))" +
+	" OR (release_id>0 AND release_id NOT IN (SELECT id FROM `release`))"
+
+// removeAttachmentFile removes file at given path and returns its size.
+func removeAttachmentFile(localPath string) (int64, error) {
+	fi, err := os.Stat(localPath)
+	if os.IsNotExist(err) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	return fi.Size(), os.Remove(localPath)
+}
+
+// DeleteOrphanedAttachments deletes orphaned attachment records and files in attachment directory
+// without records, which are older than the grace period. Size of reclaimed space is logged.
+func DeleteOrphanedAttachments() error {
+	if taskStatusPool.IsRunning(_DELETE_ATTACHMENTS) {
+		return nil
+	}
+	taskStatusPool.Start(_DELETE_ATTACHMENTS)
+	defer taskStatusPool.Stop(_DELETE_ATTACHMENTS)
+
+	log.Trace("Doing: DeleteOrphanedAttachments")
+
+	// Grace period cannot be disabled, otherwise uploads not yet submitted are deleted.
+	hours := setting.Cron.DeleteOrphanedAttachments.GracePeriodHours
+	if hours <= 0 {
+		hours = 24
+	}
+	deadline := time.Now().Add(-time.Duration(hours) * time.Hour)
+
+	var (
+		numRecords int
+		numFiles   int
+		reclaimed  int64
+	)
+	attachments := make([]*Attachment, 0, 10)
+	if err := x.Where("created<?", deadline).And(orphanedAttachmentsCond).Find(&attachments); err != nil {
+		return fmt.Errorf("find orphaned attachments: %v", err)
+	}
+	for _, a := range attachments {
+		size, err := removeAttachmentFile(a.LocalPath())
+		if err != nil {
+			return fmt.Errorf("remove attachment file[%s]: %v", a.UUID, err)
+		}
+		if _, err = x.Id(a.ID).Delete(new(Attachment)); err != nil {
+			return fmt.Errorf("delete attachment[%d]: %v", a.ID, err)
+		}
+		numRecords++
+		reclaimed += size
+	}
+
+	// Files may be left behind without records when attachments are deleted
+	// by other ways, e.g. records of deleted issues.
+	// Only files of attachment layout (<x>/<y>/<uuid>) are considered, other
+	// data like user exports is stored under the same directory.
+	exportsPath := filepath.Join(setting.AttachmentPath, "exports")
+	if err := filepath.Walk(setting.AttachmentPath, func(fpath string, fi os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		} else if fi.IsDir() {
+			if fpath == exportsPath {
+				return filepath.SkipDir
+			}
+			return nil
+		} else if fi.ModTime().After(deadline) {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(setting.AttachmentPath, fpath)
+		if err != nil {
+			return nil
+		}
+		parts := strings.Split(filepath.ToSlash(relPath), "/")
+		name := fi.Name()
+		if len(parts) != 3 || len(name) < 2 || parts[0] != name[0:1] || parts[1] != name[1:2] {
+			return nil
+		}
+
+		has, err := x.Where("uuid=?", fi.Name()).Get(new(Attachment))
+		if err != nil {
+			return fmt.Errorf("get attachment[%s]: %v", fi.Name(), err)
+		} else if has {
+			return nil
+		}
+
+		if err = os.Remove(fpath); err != nil {
+			return fmt.Errorf("remove file[%s]: %v", fpath, err)
+		}
+		numFiles++
+		reclaimed += fi.Size()
+		return nil
+	}); err != nil {
+		return fmt.Errorf("walk attachment directory: %v", err)
+	}
+
+	log.Info("Deleted %d orphaned attachments and %d files without records, %s reclaimed",
+		numRecords, numFiles, base.FileSize(reclaimed))
+	return nil
+}
+
+// IsDeletingOrphanedAttachments returns true if orphaned attachments are being deleted.
+func IsDeletingOrphanedAttachments() bool {
+	return taskStatusPool.IsRunning(_DELETE_ATTACHMENTS)
+}
+
+// DeleteOrphanedAttachmentsTask runs DeleteOrphanedAttachments as a cron task.
+func DeleteOrphanedAttachmentsTask() {
+	if err := DeleteOrphanedAttachments(); err != nil {
+		log.Error(4, "DeleteOrphanedAttachments: %v", err)
+	}
+}
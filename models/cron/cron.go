@@ -50,6 +50,46 @@ func NewContext() {
 			go models.CheckRepoStats()
 		}
 	}
+	if setting.Cron.GitGcRepos.Enabled {
+		entry, err = c.AddFunc("Repository garbage collection", setting.Cron.GitGcRepos.Schedule, models.GitGcReposTask)
+		if err != nil {
+			log.Fatal(4, "Cron[Repository garbage collection]: %v", err)
+		}
+		if setting.Cron.GitGcRepos.RunAtStart {
+			entry.Prev = time.Now()
+			go models.GitGcReposTask()
+		}
+	}
+	if setting.Cron.UpdateRepoSizes.Enabled {
+		entry, err = c.AddFunc("Update repository sizes", setting.Cron.UpdateRepoSizes.Schedule, models.UpdateRepoSizes)
+		if err != nil {
+			log.Fatal(4, "Cron[Update repository sizes]: %v", err)
+		}
+		if setting.Cron.UpdateRepoSizes.RunAtStart {
+			entry.Prev = time.Now()
+			go models.UpdateRepoSizes()
+		}
+	}
+	if setting.Cron.DeleteUserExports.Enabled {
+		entry, err = c.AddFunc("Delete expired user exports", setting.Cron.DeleteUserExports.Schedule, models.DeleteExpiredUserExports)
+		if err != nil {
+			log.Fatal(4, "Cron[Delete expired user exports]: %v", err)
+		}
+		if setting.Cron.DeleteUserExports.RunAtStart {
+			entry.Prev = time.Now()
+			go models.DeleteExpiredUserExports()
+		}
+	}
+	if setting.Cron.DeleteOrphanedAttachments.Enabled {
+		entry, err = c.AddFunc("Delete orphaned attachments", setting.Cron.DeleteOrphanedAttachments.Schedule, models.DeleteOrphanedAttachmentsTask)
+		if err != nil {
+			log.Fatal(4, "Cron[Delete orphaned attachments]: %v", err)
+		}
+		if setting.Cron.DeleteOrphanedAttachments.RunAtStart {
+			entry.Prev = time.Now()
+			go models.DeleteOrphanedAttachmentsTask()
+		}
+	}
 	c.Start()
 }
 
@@ -34,6 +34,19 @@ func (err ErrNamePatternNotAllowed) Error() string {
 	return fmt.Sprintf("name pattern is not allowed [pattern: %s]", err.Pattern)
 }
 
+type ErrNameCharsNotAllowed struct {
+	Name string
+}
+
+func IsErrNameCharsNotAllowed(err error) bool {
+	_, ok := err.(ErrNameCharsNotAllowed)
+	return ok
+}
+
+func (err ErrNameCharsNotAllowed) Error() string {
+	return fmt.Sprintf("name contains characters that are not allowed [name: %s]", err.Name)
+}
+
 //  ____ ___
 // |    |   \______ ___________
 // |    |   /  ___// __ \_  __ \
@@ -127,6 +140,19 @@ func (err ErrWikiAlreadyExist) Error() string {
 	return fmt.Sprintf("wiki page already exists [title: %s]", err.Title)
 }
 
+type ErrWikiNotExist struct {
+	Title string
+}
+
+func IsErrWikiNotExist(err error) bool {
+	_, ok := err.(ErrWikiNotExist)
+	return ok
+}
+
+func (err ErrWikiNotExist) Error() string {
+	return fmt.Sprintf("wiki page does not exist [title: %s]", err.Title)
+}
+
 // __________     ___.   .__  .__          ____  __.
 // \______   \__ _\_ |__ |  | |__| ____   |    |/ _|____ ___.__.
 //  |     ___/  |  \ __ \|  | |  |/ ___\  |      <_/ __ <   |  |
@@ -251,6 +277,71 @@ func (err ErrAccessTokenNotExist) Error() string {
 	return fmt.Sprintf("access token does not exist [sha: %s]", err.SHA)
 }
 
+type ErrAvatarInvalid struct {
+	Reason string
+}
+
+func IsErrAvatarInvalid(err error) bool {
+	_, ok := err.(ErrAvatarInvalid)
+	return ok
+}
+
+func (err ErrAvatarInvalid) Error() string {
+	return fmt.Sprintf("invalid avatar: %s", err.Reason)
+}
+
+type ErrUserExportNotExist struct {
+	ID int64
+}
+
+func IsErrUserExportNotExist(err error) bool {
+	_, ok := err.(ErrUserExportNotExist)
+	return ok
+}
+
+func (err ErrUserExportNotExist) Error() string {
+	return fmt.Sprintf("user export does not exist [id: %d]", err.ID)
+}
+
+type ErrUserSessionNotExist struct {
+	ID int64
+}
+
+func IsErrUserSessionNotExist(err error) bool {
+	_, ok := err.(ErrUserSessionNotExist)
+	return ok
+}
+
+func (err ErrUserSessionNotExist) Error() string {
+	return fmt.Sprintf("user session does not exist [id: %d]", err.ID)
+}
+
+type ErrNotificationNotExist struct {
+	ID int64
+}
+
+func IsErrNotificationNotExist(err error) bool {
+	_, ok := err.(ErrNotificationNotExist)
+	return ok
+}
+
+func (err ErrNotificationNotExist) Error() string {
+	return fmt.Sprintf("notification does not exist [id: %d]", err.ID)
+}
+
+type ErrAccessTokenInvalidScope struct {
+	Scope string
+}
+
+func IsErrAccessTokenInvalidScope(err error) bool {
+	_, ok := err.(ErrAccessTokenInvalidScope)
+	return ok
+}
+
+func (err ErrAccessTokenInvalidScope) Error() string {
+	return fmt.Sprintf("invalid access token scope [scope: %s]", err.Scope)
+}
+
 // ________                            .__                __  .__
 // \_____  \_______  _________    ____ |__|____________ _/  |_|__| ____   ____
 //  /   |   \_  __ \/ ___\__  \  /    \|  \___   /\__  \\   __\  |/  _ \ /    \
@@ -271,6 +362,125 @@ func (err ErrLastOrgOwner) Error() string {
 	return fmt.Sprintf("user is the last member of owner team [uid: %d]", err.UID)
 }
 
+type ErrSecurityKeyNotExist struct {
+	ID           int64
+	CredentialID string
+}
+
+func IsErrSecurityKeyNotExist(err error) bool {
+	_, ok := err.(ErrSecurityKeyNotExist)
+	return ok
+}
+
+func (err ErrSecurityKeyNotExist) Error() string {
+	return fmt.Sprintf("security key does not exist [id: %d, credential_id: %s]", err.ID, err.CredentialID)
+}
+
+type ErrSecurityKeyAlreadyExist struct {
+	Name string
+}
+
+func IsErrSecurityKeyAlreadyExist(err error) bool {
+	_, ok := err.(ErrSecurityKeyAlreadyExist)
+	return ok
+}
+
+func (err ErrSecurityKeyAlreadyExist) Error() string {
+	return fmt.Sprintf("security key already exists [name: %s]", err.Name)
+}
+
+type ErrGPGKeyNotExist struct {
+	ID int64
+}
+
+func IsErrGPGKeyNotExist(err error) bool {
+	_, ok := err.(ErrGPGKeyNotExist)
+	return ok
+}
+
+func (err ErrGPGKeyNotExist) Error() string {
+	return fmt.Sprintf("GPG key does not exist [id: %d]", err.ID)
+}
+
+type ErrGPGKeyAlreadyExist struct {
+	Fingerprint string
+}
+
+func IsErrGPGKeyAlreadyExist(err error) bool {
+	_, ok := err.(ErrGPGKeyAlreadyExist)
+	return ok
+}
+
+func (err ErrGPGKeyAlreadyExist) Error() string {
+	return fmt.Sprintf("GPG key already exists [fingerprint: %s]", err.Fingerprint)
+}
+
+type ErrGPGKeyInvalid struct {
+	Reason string
+}
+
+func IsErrGPGKeyInvalid(err error) bool {
+	_, ok := err.(ErrGPGKeyInvalid)
+	return ok
+}
+
+func (err ErrGPGKeyInvalid) Error() string {
+	return fmt.Sprintf("GPG key is invalid: %s", err.Reason)
+}
+
+type ErrOAuth2ApplicationNotExist struct {
+	ID       int64
+	ClientID string
+}
+
+func IsErrOAuth2ApplicationNotExist(err error) bool {
+	_, ok := err.(ErrOAuth2ApplicationNotExist)
+	return ok
+}
+
+func (err ErrOAuth2ApplicationNotExist) Error() string {
+	return fmt.Sprintf("OAuth2 application does not exist [id: %d, client_id: %s]", err.ID, err.ClientID)
+}
+
+type ErrOAuth2InvalidRedirectURI struct {
+	URI string
+}
+
+func IsErrOAuth2InvalidRedirectURI(err error) bool {
+	_, ok := err.(ErrOAuth2InvalidRedirectURI)
+	return ok
+}
+
+func (err ErrOAuth2InvalidRedirectURI) Error() string {
+	return fmt.Sprintf("invalid OAuth2 redirect URI [uri: %s]", err.URI)
+}
+
+type ErrOAuth2GrantNotExist struct {
+	ID int64
+}
+
+func IsErrOAuth2GrantNotExist(err error) bool {
+	_, ok := err.(ErrOAuth2GrantNotExist)
+	return ok
+}
+
+func (err ErrOAuth2GrantNotExist) Error() string {
+	return fmt.Sprintf("OAuth2 authorization does not exist [id: %d]", err.ID)
+}
+
+type ErrOAuth2InvalidCode struct {
+	Code string
+}
+
+func IsErrOAuth2InvalidCode(err error) bool {
+	_, ok := err.(ErrOAuth2InvalidCode)
+	return ok
+}
+
+func (err ErrOAuth2InvalidCode) Error() string {
+	return fmt.Sprintf("invalid or expired OAuth2 authorization code [code: %s]", err.Code)
+}
+
 // __________                           .__  __
 // \______   \ ____ ______   ____  _____|__|/  |_  ___________ ___.__.
 //  |       _// __ \\____ \ /  _ \/  ___/  \   __\/  _ \_  __ <   |  |
@@ -307,6 +517,85 @@ func (err ErrRepoAlreadyExist) Error() string {
 	return fmt.Sprintf("repository already exists [uname: %s, name: %s]", err.Uname, err.Name)
 }
 
+type ErrReachLimitOfRepo struct {
+	Limit int
+}
+
+func IsErrReachLimitOfRepo(err error) bool {
+	_, ok := err.(ErrReachLimitOfRepo)
+	return ok
+}
+
+func (err ErrReachLimitOfRepo) Error() string {
+	return fmt.Sprintf("user has reached maximum limit of repositories [limit: %d]", err.Limit)
+}
+
+type ErrRepoNotTemplate struct {
+	ID int64
+}
+
+func IsErrRepoNotTemplate(err error) bool {
+	_, ok := err.(ErrRepoNotTemplate)
+	return ok
+}
+
+func (err ErrRepoNotTemplate) Error() string {
+	return fmt.Sprintf("repository is not a template [id: %d]", err.ID)
+}
+
+type ErrInvalidDefaultBranch struct {
+	Name string
+}
+
+func IsErrInvalidDefaultBranch(err error) bool {
+	_, ok := err.(ErrInvalidDefaultBranch)
+	return ok
+}
+
+func (err ErrInvalidDefaultBranch) Error() string {
+	return fmt.Sprintf("invalid default branch name [name: %s]", err.Name)
+}
+
+type ErrRepoInitFileNotExist struct {
+	Type string
+	Name string
+}
+
+func IsErrRepoInitFileNotExist(err error) bool {
+	_, ok := err.(ErrRepoInitFileNotExist)
+	return ok
+}
+
+func (err ErrRepoInitFileNotExist) Error() string {
+	return fmt.Sprintf("repository init file does not exist [type: %s, name: %s]", err.Type, err.Name)
+}
+
+type ErrInvalidDefaultLabel struct {
+	Line string
+}
+
+func IsErrInvalidDefaultLabel(err error) bool {
+	_, ok := err.(ErrInvalidDefaultLabel)
+	return ok
+}
+
+func (err ErrInvalidDefaultLabel) Error() string {
+	return fmt.Sprintf("invalid default label [line: %s]", err.Line)
+}
+
+type ErrRepoLocked struct {
+	ID int64
+}
+
+func IsErrRepoLocked(err error) bool {
+	_, ok := err.(ErrRepoLocked)
+	return ok
+}
+
+func (err ErrRepoLocked) Error() string {
+	return fmt.Sprintf("repository is being written by another process [id: %d]", err.ID)
+}
+
 type ErrInvalidCloneAddr struct {
 	IsURLError         bool
 	IsInvalidPath      bool
@@ -363,6 +652,32 @@ func (err ErrReleaseNotExist) Error() string {
 	return fmt.Sprintf("Release tag does not exist [id: %d, tag_name: %s]", err.ID, err.TagName)
 }
 
+type ErrInvalidTagPattern struct {
+	Pattern string
+}
+
+func IsErrInvalidTagPattern(err error) bool {
+	_, ok := err.(ErrInvalidTagPattern)
+	return ok
+}
+
+func (err ErrInvalidTagPattern) Error() string {
+	return fmt.Sprintf("invalid tag pattern [pattern: %s]", err.Pattern)
+}
+
+type ErrProtectedTagAlreadyExist struct {
+	Pattern string
+}
+
+func IsErrProtectedTagAlreadyExist(err error) bool {
+	_, ok := err.(ErrProtectedTagAlreadyExist)
+	return ok
+}
+
+func (err ErrProtectedTagAlreadyExist) Error() string {
+	return fmt.Sprintf("protected tag already exists [pattern: %s]", err.Pattern)
+}
+
 //  __      __      ___.   .__                   __
 // /  \    /  \ ____\_ |__ |  |__   ____   ____ |  | __
 // \   \/\/   // __ \| __ \|  |  \ /  _ \ /  _ \|  |/ /
@@ -383,6 +698,20 @@ func (err ErrWebhookNotExist) Error() string {
 	return fmt.Sprintf("webhook does not exist [id: %d]", err.ID)
 }
 
+type ErrInvalidHookHeader struct {
+	Name   string
+	Reason string
+}
+
+func IsErrInvalidHookHeader(err error) bool {
+	_, ok := err.(ErrInvalidHookHeader)
+	return ok
+}
+
+func (err ErrInvalidHookHeader) Error() string {
+	return fmt.Sprintf("invalid webhook header '%s': %s", err.Name, err.Reason)
+}
+
 // .___
 // |   | ______ ________ __   ____
 // |   |/  ___//  ___/  |  \_/ __ \
@@ -405,6 +734,73 @@ func (err ErrIssueNotExist) Error() string {
 	return fmt.Sprintf("issue does not exist [id: %d, repo_id: %d, index: %d]", err.ID, err.RepoID, err.Index)
 }
 
+type ErrInvalidReviewer struct {
+	Name string
+}
+
+func IsErrInvalidReviewer(err error) bool {
+	_, ok := err.(ErrInvalidReviewer)
+	return ok
+}
+
+func (err ErrInvalidReviewer) Error() string {
+	return fmt.Sprintf("user or team cannot review pull requests of repository [name: %s]", err.Name)
+}
+
+type ErrInvalidAutoAssignee struct {
+	Name string
+}
+
+func IsErrInvalidAutoAssignee(err error) bool {
+	_, ok := err.(ErrInvalidAutoAssignee)
+	return ok
+}
+
+func (err ErrInvalidAutoAssignee) Error() string {
+	return fmt.Sprintf("user or team cannot be assigned to issues of repository [name: %s]", err.Name)
+}
+
+type ErrPullRequestNotApproved struct {
+	Required  int
+	Approvals int
+}
+
+func IsErrPullRequestNotApproved(err error) bool {
+	_, ok := err.(ErrPullRequestNotApproved)
+	return ok
+}
+
+func (err ErrPullRequestNotApproved) Error() string {
+	return fmt.Sprintf("pull request does not have enough approvals [required: %d, approvals: %d]", err.Required, err.Approvals)
+}
+
+type ErrPullRequestChangesRequested struct {
+	Reviewer string
+}
+
+func IsErrPullRequestChangesRequested(err error) bool {
+	_, ok := err.(ErrPullRequestChangesRequested)
+	return ok
+}
+
+func (err ErrPullRequestChangesRequested) Error() string {
+	return fmt.Sprintf("reviewer requested changes to pull request [reviewer: %s]", err.Reviewer)
+}
+
+type ErrIssuePinnedLimit struct {
+	RepoID int64
+	Limit  int
+}
+
+func IsErrIssuePinnedLimit(err error) bool {
+	_, ok := err.(ErrIssuePinnedLimit)
+	return ok
+}
+
+func (err ErrIssuePinnedLimit) Error() string {
+	return fmt.Sprintf("repository has reached limit of pinned issues [repo_id: %d, limit: %d]", err.RepoID, err.Limit)
+}
+
 // __________      .__  .__ __________                                     __
 // \______   \__ __|  | |  |\______   \ ____  ________ __   ____   _______/  |_
 //  |     ___/  |  \  | |  | |       _// __ \/ ____/  |  \_/ __ \ /  ___/\   __\
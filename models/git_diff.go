@@ -21,8 +21,10 @@ import (
 
 	"github.com/gogits/gogs/modules/base"
 	"github.com/gogits/gogs/modules/git"
+	"github.com/gogits/gogs/modules/highlight"
 	"github.com/gogits/gogs/modules/log"
 	"github.com/gogits/gogs/modules/process"
+	"github.com/gogits/gogs/modules/setting"
 )
 
 // Diff line types.
@@ -40,6 +42,26 @@ const (
 	DIFF_FILE_RENAME
 )
 
+// DiffWhitespace represents how whitespace changes are treated in diff.
+type DiffWhitespace int
+
+const (
+	DIFF_WHITESPACE_SHOW DiffWhitespace = iota
+	DIFF_WHITESPACE_IGNORE_ALL
+	DIFF_WHITESPACE_IGNORE_EOL
+)
+
+// gitArgs returns corresponding arguments of git diff.
+func (w DiffWhitespace) gitArgs() []string {
+	switch w {
+	case DIFF_WHITESPACE_IGNORE_ALL:
+		return []string{"-w"}
+	case DIFF_WHITESPACE_IGNORE_EOL:
+		return []string{"--ignore-space-at-eol"}
+	}
+	return nil
+}
+
 type DiffLine struct {
 	LeftIdx  int
 	RightIdx int
@@ -51,6 +73,15 @@ func (d DiffLine) GetType() int {
 	return d.Type
 }
 
+// CommentLine returns line number that code comments on this line refer to,
+// which is negative for deleted line of old file and 0 for section header.
+func (d DiffLine) CommentLine() int64 {
+	if d.RightIdx > 0 {
+		return int64(d.RightIdx)
+	}
+	return -int64(d.LeftIdx)
+}
+
 type DiffSection struct {
 	Name  string
 	Lines []*DiffLine
@@ -66,12 +97,21 @@ type DiffFile struct {
 	IsDeleted          bool
 	IsBin              bool
 	IsRenamed          bool
-	Sections           []*DiffSection
+	// IsIncomplete indicates content of file is too large to be displayed.
+	IsIncomplete bool
+	Sections     []*DiffSection
+}
+
+// HighlightClass returns class name used by highlight.js for the file.
+func (diffFile *DiffFile) HighlightClass() string {
+	return highlight.FileNameToHighlightClass(diffFile.Name)
 }
 
 type Diff struct {
 	TotalAddition, TotalDeletion int
 	Files                        []*DiffFile
+	// IsIncomplete indicates diff has been truncated and not all files are listed.
+	IsIncomplete bool
 }
 
 func (diff *Diff) NumFiles() int {
@@ -80,7 +120,28 @@ func (diff *Diff) NumFiles() int {
 
 const DIFF_HEAD = "diff --git "
 
-func ParsePatch(pid int64, maxlines int, cmd *exec.Cmd, reader io.Reader) (*Diff, error) {
+// DiffLimits represents limits of size of diff to be parsed, 0 means no limit.
+type DiffLimits struct {
+	MaxLines        int   // Maximum number of lines of whole diff.
+	MaxFiles        int   // Maximum number of files.
+	MaxLinesPerFile int   // Maximum number of lines of a single file.
+	MaxBytes        int64 // Maximum size of whole diff in bytes.
+}
+
+// DefaultDiffLimits returns limits of diff set in configuration.
+func DefaultDiffLimits() DiffLimits {
+	return DiffLimits{
+		MaxLines:        setting.Git.MaxGitDiffLines,
+		MaxFiles:        setting.Git.MaxGitDiffFiles,
+		MaxLinesPerFile: setting.Git.MaxGitDiffLinesPerFile,
+		MaxBytes:        setting.Git.MaxGitDiffBytes,
+	}
+}
+
+// ParsePatch parses diff from reader and stops reading as soon as any limit is reached.
+// The diff is marked as incomplete once it is truncated, and content of file
+// exceeding limit of lines is dropped but additions and deletions are still counted.
+func ParsePatch(pid int64, limits DiffLimits, cmd *exec.Cmd, reader io.Reader) (*Diff, error) {
 	scanner := bufio.NewScanner(reader)
 	var (
 		curFile    *DiffFile
@@ -94,10 +155,21 @@ func ParsePatch(pid int64, maxlines int, cmd *exec.Cmd, reader io.Reader) (*Diff
 	)
 
 	diff := &Diff{Files: make([]*DiffFile, 0)}
-	var i int
+	var (
+		i          int
+		fileLines  int
+		totalBytes int64
+	)
 	for scanner.Scan() {
 		line := scanner.Text()
 
+		totalBytes += int64(len(line)) + 1
+		if limits.MaxBytes > 0 && totalBytes > limits.MaxBytes {
+			log.Warn("Diff data too large: exceeds %d bytes", limits.MaxBytes)
+			diff.IsIncomplete = true
+			break
+		}
+
 		if strings.HasPrefix(line, "+++ ") || strings.HasPrefix(line, "--- ") {
 			continue
 		}
@@ -109,10 +181,33 @@ func ParsePatch(pid int64, maxlines int, cmd *exec.Cmd, reader io.Reader) (*Diff
 		i = i + 1
 
 		// Diff data too large, we only show the first about maxlines lines
-		if i >= maxlines {
-			log.Warn("Diff data too large")
-			diff.Files = nil
-			return diff, nil
+		if limits.MaxLines > 0 && i >= limits.MaxLines {
+			log.Warn("Diff data too large: exceeds %d lines", limits.MaxLines)
+			diff.IsIncomplete = true
+			break
+		}
+
+		// Content of file is too large, only count changes until next file.
+		if curFile != nil && !strings.HasPrefix(line, DIFF_HEAD) {
+			switch line[0] {
+			case ' ', '+', '-':
+				fileLines++
+				if limits.MaxLinesPerFile > 0 && fileLines > limits.MaxLinesPerFile {
+					curFile.IsIncomplete = true
+					curFile.Sections = nil
+				}
+			}
+			if curFile.IsIncomplete {
+				switch line[0] {
+				case '+':
+					curFile.Addition++
+					diff.TotalAddition++
+				case '-':
+					curFile.Deletion++
+					diff.TotalDeletion++
+				}
+				continue
+			}
 		}
 
 		switch {
@@ -161,6 +256,13 @@ func ParsePatch(pid int64, maxlines int, cmd *exec.Cmd, reader io.Reader) (*Diff
 
 		// Get new file.
 		if strings.HasPrefix(line, DIFF_HEAD) {
+			if limits.MaxFiles > 0 && len(diff.Files) >= limits.MaxFiles {
+				log.Warn("Diff data too large: exceeds %d files", limits.MaxFiles)
+				diff.IsIncomplete = true
+				break
+			}
+			fileLines = 0
+
 			middle := -1
 
 			// Note: In case file name is surrounded by double quotes (it happens only in git-shell).
@@ -238,7 +340,14 @@ func ParsePatch(pid int64, maxlines int, cmd *exec.Cmd, reader io.Reader) (*Diff
 	return diff, nil
 }
 
-func GetDiffRange(repoPath, beforeCommitId string, afterCommitId string, maxlines int) (*Diff, error) {
+func GetDiffRange(repoPath, beforeCommitId string, afterCommitId string, limits DiffLimits) (*Diff, error) {
+	return GetDiffRangeWithWhitespace(repoPath, beforeCommitId, afterCommitId, limits, DIFF_WHITESPACE_SHOW)
+}
+
+// GetDiffRangeWithWhitespace returns diff between given commits with whitespace changes treated as requested.
+// Line numbers are always taken from hunk headers, so they refer to actual lines of files
+// even when whitespace changes are ignored.
+func GetDiffRangeWithWhitespace(repoPath, beforeCommitId string, afterCommitId string, limits DiffLimits, whitespace DiffWhitespace) (*Diff, error) {
 	repo, err := git.OpenRepository(repoPath)
 	if err != nil {
 		return nil, err
@@ -250,20 +359,22 @@ func GetDiffRange(repoPath, beforeCommitId string, afterCommitId string, maxline
 	}
 
 	rd, wr := io.Pipe()
-	var cmd *exec.Cmd
+	args := []string{"diff", "-M"}
 	// if "after" commit given
 	if beforeCommitId == "" {
 		// First commit of repository.
 		if commit.ParentCount() == 0 {
-			cmd = exec.Command("git", "show", afterCommitId)
+			args = []string{"show"}
 		} else {
 			c, _ := commit.Parent(0)
-			cmd = exec.Command("git", "diff", "-M", c.ID.String(), afterCommitId)
+			beforeCommitId = c.ID.String()
 		}
-	} else {
-		cmd = exec.Command("git", "diff", "-M", beforeCommitId, afterCommitId)
 	}
-	cmd.Dir = repoPath
+	args = append(args, whitespace.gitArgs()...)
+	if len(beforeCommitId) > 0 {
+		args = append(args, beforeCommitId)
+	}
+	cmd := git.Command(repoPath, append(args, afterCommitId)...)
 	cmd.Stdout = wr
 	cmd.Stdin = os.Stdin
 	cmd.Stderr = os.Stderr
@@ -292,9 +403,29 @@ func GetDiffRange(repoPath, beforeCommitId string, afterCommitId string, maxline
 		}
 	}()
 
-	return ParsePatch(pid, maxlines, cmd, rd)
+	diff, err := ParsePatch(pid, limits, cmd, rd)
+	if err != nil || whitespace == DIFF_WHITESPACE_SHOW {
+		return diff, err
+	}
+
+	// Files that only have whitespace changes are still listed without any hunk.
+	files := diff.Files[:0]
+	for _, f := range diff.Files {
+		if f.Type == DIFF_FILE_CHANGE && !f.IsBin && !f.IsIncomplete && len(f.Sections) == 0 {
+			continue
+		}
+		f.Index = len(files) + 1
+		files = append(files, f)
+	}
+	diff.Files = files
+	return diff, nil
+}
+
+func GetDiffCommit(repoPath, commitId string, limits DiffLimits) (*Diff, error) {
+	return GetDiffRange(repoPath, "", commitId, limits)
 }
 
-func GetDiffCommit(repoPath, commitId string, maxlines int) (*Diff, error) {
-	return GetDiffRange(repoPath, "", commitId, maxlines)
+// GetDiffCommitWithWhitespace returns diff of given commit with whitespace changes treated as requested.
+func GetDiffCommitWithWhitespace(repoPath, commitId string, limits DiffLimits, whitespace DiffWhitespace) (*Diff, error) {
+	return GetDiffRangeWithWhitespace(repoPath, "", commitId, limits, whitespace)
 }
@@ -0,0 +1,235 @@
+// Copyright 2015 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/Unknwon/com"
+
+	oldgit "github.com/gogits/gogs/modules/git"
+	"github.com/gogits/gogs/modules/process"
+	"github.com/gogits/gogs/modules/setting"
+)
+
+// GPGKey represents a public GPG key registered by a user,
+// commits signed by it are accepted by repositories requiring signed commits.
+type GPGKey struct {
+	ID          int64     `xorm:"pk autoincr"`
+	OwnerID     int64     `xorm:"INDEX NOT NULL"`
+	KeyID       string    `xorm:"INDEX NOT NULL"`
+	Fingerprint string    `xorm:"UNIQUE NOT NULL"` // Of primary key.
+	Content     string    `xorm:"TEXT NOT NULL"`
+	Created     time.Time `xorm:"CREATED"`
+}
+
+// gpgExec runs GnuPG with keyring of registered keys and given input.
+func gpgExec(desc, input string, args ...string) (string, string, error) {
+	if err := prepareGPGHome(); err != nil {
+		return "", "", fmt.Errorf("prepareGPGHome: %v", err)
+	}
+
+	cmd := exec.Command(setting.Git.GpgPath, append([]string{"--homedir", setting.Git.GpgHomePath, "--batch"}, args...)...)
+	cmd.Stdin = strings.NewReader(input)
+	return process.ExecCmd(-1, desc, cmd)
+}
+
+// prepareGPGHome creates home directory of keyring if it does not exist.
+// Keys are only imported after verification of owner, so all of them are trusted.
+func prepareGPGHome() error {
+	if com.IsExist(setting.Git.GpgHomePath) {
+		return nil
+	}
+	if err := os.MkdirAll(setting.Git.GpgHomePath, 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path.Join(setting.Git.GpgHomePath, "gpg.conf"), []byte("trust-model always\n"), 0600)
+}
+
+// importGPGKey imports armored public key into keyring and returns fingerprint of its primary key.
+func importGPGKey(content string) (string, error) {
+	if strings.Contains(content, "PRIVATE KEY BLOCK") {
+		return "", ErrGPGKeyInvalid{"private key must not be uploaded"}
+	} else if !strings.Contains(content, "-----BEGIN PGP PUBLIC KEY BLOCK-----") {
+		return "", ErrGPGKeyInvalid{"not an armored public key"}
+	}
+
+	stdout, stderr, err := gpgExec("importGPGKey", content, "--status-fd", "1", "--import")
+	if err != nil {
+		return "", ErrGPGKeyInvalid{strings.TrimSpace(stderr)}
+	}
+
+	// Status line has format "[GNUPG:] IMPORT_OK <reason> <fingerprint>".
+	fingerprints := make(map[string]bool)
+	var fingerprint string
+	for _, line := range strings.Split(stdout, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 4 || fields[1] != "IMPORT_OK" {
+			continue
+		}
+		fingerprint = strings.ToUpper(fields[3])
+		fingerprints[fingerprint] = true
+	}
+	switch len(fingerprints) {
+	case 0:
+		return "", ErrGPGKeyInvalid{"no public key found"}
+	case 1:
+		return fingerprint, nil
+	}
+	return "", ErrGPGKeyInvalid{"only one key can be added at a time"}
+}
+
+// AddGPGKey adds a new public GPG key for user.
+func AddGPGKey(ownerID int64, content string) (*GPGKey, error) {
+	content = strings.TrimSpace(content)
+	fingerprint, err := importGPGKey(content)
+	if err != nil {
+		return nil, err
+	}
+
+	has, err := x.Get(&GPGKey{Fingerprint: fingerprint})
+	if err != nil {
+		return nil, err
+	} else if has {
+		return nil, ErrGPGKeyAlreadyExist{fingerprint}
+	}
+
+	key := &GPGKey{
+		OwnerID:     ownerID,
+		KeyID:       fingerprint[len(fingerprint)-16:],
+		Fingerprint: fingerprint,
+		Content:     content,
+	}
+	if _, err = x.Insert(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// ListGPGKeys returns all public GPG keys of given user.
+func ListGPGKeys(ownerID int64) ([]*GPGKey, error) {
+	keys := make([]*GPGKey, 0, 2)
+	return keys, x.Where("owner_id=?", ownerID).Asc("id").Find(&keys)
+}
+
+// GetGPGKeyByID returns public GPG key of given user by ID.
+func GetGPGKeyByID(ownerID, id int64) (*GPGKey, error) {
+	key := &GPGKey{
+		ID:      id,
+		OwnerID: ownerID,
+	}
+	has, err := x.Get(key)
+	if err != nil {
+		return nil, err
+	} else if !has {
+		return nil, ErrGPGKeyNotExist{id}
+	}
+	return key, nil
+}
+
+// DeleteGPGKey deletes public GPG key of given user by ID,
+// commits signed by it are no longer accepted by repositories requiring signed commits.
+func DeleteGPGKey(ownerID, id int64) error {
+	key, err := GetGPGKeyByID(ownerID, id)
+	if err != nil {
+		return err
+	}
+	if _, err = x.Id(key.ID).Delete(new(GPGKey)); err != nil {
+		return err
+	}
+
+	// Key is rejected by missing record even if it is left in keyring.
+	if _, stderr, err := gpgExec("DeleteGPGKey", "", "--yes", "--delete-keys", key.Fingerprint); err != nil {
+		return fmt.Errorf("delete key from keyring: %v - %s", err, stderr)
+	}
+	return nil
+}
+
+// SignedCommitMode represents which new commits of a push must be signed by registered GPG keys.
+type SignedCommitMode int
+
+const (
+	SIGNED_COMMIT_NONE SignedCommitMode = iota
+	SIGNED_COMMIT_TIP                   // Only the newest commit of each pushed branch.
+	SIGNED_COMMIT_ALL                   // Every commit that is new to repository.
+)
+
+var signedCommitModes = map[string]SignedCommitMode{
+	"none": SIGNED_COMMIT_NONE,
+	"tip":  SIGNED_COMMIT_TIP,
+	"all":  SIGNED_COMMIT_ALL,
+}
+
+// ToSignedCommitMode returns SignedCommitMode by given name.
+func ToSignedCommitMode(name string) (SignedCommitMode, bool) {
+	mode, ok := signedCommitModes[name]
+	return mode, ok
+}
+
+func (mode SignedCommitMode) Name() string {
+	switch mode {
+	case SIGNED_COMMIT_TIP:
+		return "tip"
+	case SIGNED_COMMIT_ALL:
+		return "all"
+	}
+	return "none"
+}
+
+// UnsignedCommits returns IDs of commits up to given new commit that are not yet
+// in repository and not signed by any registered GPG key, as required by repository.
+// It is called by Git hook before references are updated.
+func (repo *Repository) UnsignedCommits(newCommitID string) ([]string, error) {
+	if repo.SignedCommitMode == SIGNED_COMMIT_NONE {
+		return nil, nil
+	}
+	if err := prepareGPGHome(); err != nil {
+		return nil, fmt.Errorf("prepareGPGHome: %v", err)
+	}
+
+	args := []string{newCommitID, "--not", "--all"}
+	if repo.SignedCommitMode == SIGNED_COMMIT_TIP {
+		args = append([]string{"--max-count=1"}, args...)
+	}
+	gitRepo, err := oldgit.OpenRepository(repo.RepoPath())
+	if err != nil {
+		return nil, fmt.Errorf("OpenRepository: %v", err)
+	}
+	sigs, err := gitRepo.GetCommitSignatures(setting.Git.GpgPath, setting.Git.GpgHomePath, args...)
+	if err != nil {
+		return nil, fmt.Errorf("GetCommitSignatures: %v", err)
+	}
+
+	fingerprints := make([]string, 0, len(sigs)*2)
+	for _, sig := range sigs {
+		if sig.IsGood() {
+			fingerprints = append(fingerprints, sig.Fingerprint, sig.PrimaryFingerprint)
+		}
+	}
+	keys := make([]*GPGKey, 0, len(fingerprints))
+	if len(fingerprints) > 0 {
+		if err = x.In("fingerprint", fingerprints).Find(&keys); err != nil {
+			return nil, err
+		}
+	}
+	registered := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		registered[key.Fingerprint] = true
+	}
+
+	var unsigned []string
+	for _, sig := range sigs {
+		if !sig.IsGood() || !(registered[sig.Fingerprint] || registered[sig.PrimaryFingerprint]) {
+			unsigned = append(unsigned, sig.CommitID)
+		}
+	}
+	return unsigned, nil
+}
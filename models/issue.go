@@ -56,6 +56,15 @@ type Issue struct {
 	Created         time.Time `xorm:"CREATED"`
 	Updated         time.Time `xorm:"UPDATED"`
 
+	// IsLocked indicates conversation is limited to users with write access.
+	IsLocked   bool `xorm:"NOT NULL DEFAULT false"`
+	LockerID   int64
+	LockReason string
+
+	// Pinned issues are listed before others in order of PinOrder.
+	IsPinned bool `xorm:"NOT NULL DEFAULT false"`
+	PinOrder int  `xorm:"NOT NULL DEFAULT 0"`
+
 	Attachments []*Attachment `xorm:"-"`
 	Comments    []*Comment    `xorm:"-"`
 }
@@ -131,7 +140,7 @@ func (i *Issue) addLabel(e *xorm.Session, label *Label) error {
 }
 
 // AddLabel adds new label to issue by given ID.
-func (i *Issue) AddLabel(label *Label) (err error) {
+func (i *Issue) AddLabel(doer *User, label *Label) (err error) {
 	sess := x.NewSession()
 	defer sessionRelease(sess)
 	if err = sess.Begin(); err != nil {
@@ -140,6 +149,11 @@ func (i *Issue) AddLabel(label *Label) (err error) {
 
 	if err = i.addLabel(sess, label); err != nil {
 		return err
+	} else if err = createEventComment(sess, doer, i, &Comment{
+		Type:    COMMENT_TYPE_LABEL,
+		LabelID: label.ID,
+	}); err != nil {
+		return err
 	}
 
 	return sess.Commit()
@@ -167,7 +181,7 @@ func (i *Issue) removeLabel(e *xorm.Session, label *Label) error {
 }
 
 // RemoveLabel removes a label from issue by given ID.
-func (i *Issue) RemoveLabel(label *Label) (err error) {
+func (i *Issue) RemoveLabel(doer *User, label *Label) (err error) {
 	sess := x.NewSession()
 	defer sessionRelease(sess)
 	if err = sess.Begin(); err != nil {
@@ -176,12 +190,17 @@ func (i *Issue) RemoveLabel(label *Label) (err error) {
 
 	if err = i.removeLabel(sess, label); err != nil {
 		return err
+	} else if err = createEventComment(sess, doer, i, &Comment{
+		Type:    COMMENT_TYPE_UNLABEL,
+		LabelID: label.ID,
+	}); err != nil {
+		return err
 	}
 
 	return sess.Commit()
 }
 
-func (i *Issue) ClearLabels() (err error) {
+func (i *Issue) ClearLabels(doer *User) (err error) {
 	sess := x.NewSession()
 	defer sessionRelease(sess)
 	if err = sess.Begin(); err != nil {
@@ -195,6 +214,11 @@ func (i *Issue) ClearLabels() (err error) {
 	for idx := range i.Labels {
 		if err = i.removeLabel(sess, i.Labels[idx]); err != nil {
 			return err
+		} else if err = createEventComment(sess, doer, i, &Comment{
+			Type:    COMMENT_TYPE_UNLABEL,
+			LabelID: i.Labels[idx].ID,
+		}); err != nil {
+			return err
 		}
 	}
 
@@ -213,9 +237,12 @@ func (i *Issue) GetAssignee() (err error) {
 	return err
 }
 
-// ReadBy sets issue to be read by given user.
+// ReadBy sets issue and its notification to be read by given user.
 func (i *Issue) ReadBy(uid int64) error {
-	return UpdateIssueUserByRead(uid, i.ID)
+	if err := UpdateIssueUserByRead(uid, i.ID); err != nil {
+		return err
+	}
+	return markIssueNotificationRead(uid, i.ID)
 }
 
 func (i *Issue) changeStatus(e *xorm.Session, doer *User, isClosed bool) (err error) {
@@ -260,6 +287,10 @@ func (i *Issue) changeStatus(e *xorm.Session, doer *User, isClosed bool) (err er
 
 // ChangeStatus changes issue status to open/closed.
 func (i *Issue) ChangeStatus(doer *User, isClosed bool) (err error) {
+	if i.IsClosed == isClosed {
+		return nil
+	}
+
 	sess := x.NewSession()
 	defer sessionRelease(sess)
 	if err = sess.Begin(); err != nil {
@@ -270,6 +301,100 @@ func (i *Issue) ChangeStatus(doer *User, isClosed bool) (err error) {
 		return err
 	}
 
+	if err = sess.Commit(); err != nil {
+		return err
+	}
+
+	if i.IsPull {
+		if err = i.GetPullRequest(); err != nil {
+			log.Error(4, "GetPullRequest: %v", err)
+			return nil
+		}
+
+		action := HOOK_ACTION_REOPENED
+		if isClosed {
+			action = HOOK_ACTION_CLOSED
+		}
+		i.PullRequest.Issue = i
+		if err = i.PullRequest.PrepareWebhooks(doer, action, ""); err != nil {
+			log.Error(4, "PrepareWebhooks: %v", err)
+		}
+	}
+	return nil
+}
+
+// CanComment returns true if given user is allowed to comment on issue,
+// only users with write access can comment on a locked conversation.
+func (i *Issue) CanComment(u *User, isPusher bool) bool {
+	return !i.IsLocked || isPusher || (u != nil && u.IsAdmin)
+}
+
+// ChangeLockStatus locks or unlocks conversation of issue with optional reason.
+func (i *Issue) ChangeLockStatus(doer *User, repo *Repository, isLocked bool, reason string) (err error) {
+	if i.IsLocked == isLocked {
+		return nil
+	}
+
+	i.IsLocked = isLocked
+	cmtType := COMMENT_TYPE_LOCK
+	if isLocked {
+		i.LockerID = doer.Id
+		i.LockReason = reason
+	} else {
+		cmtType = COMMENT_TYPE_UNLOCK
+		i.LockerID = 0
+		i.LockReason = ""
+		reason = ""
+	}
+
+	sess := x.NewSession()
+	defer sessionRelease(sess)
+	if err = sess.Begin(); err != nil {
+		return err
+	}
+
+	if _, err = sess.Id(i.ID).Cols("is_locked", "locker_id", "lock_reason").Update(i); err != nil {
+		return fmt.Errorf("update: %v", err)
+	} else if _, err = createComment(sess, doer, repo, i, 0, 0, cmtType, reason, "", nil); err != nil {
+		return fmt.Errorf("createComment: %v", err)
+	}
+	return sess.Commit()
+}
+
+// ChangePinStatus pins or unpins issue, newly pinned issue is placed after existing ones.
+// It returns ErrIssuePinnedLimit when repository already has maximum number of pinned issues.
+func (i *Issue) ChangePinStatus(isPinned bool) (err error) {
+	if i.IsPinned == isPinned {
+		return nil
+	}
+
+	sess := x.NewSession()
+	defer sessionRelease(sess)
+	if err = sess.Begin(); err != nil {
+		return err
+	}
+
+	if isPinned {
+		count, err := sess.Where("repo_id=?", i.RepoID).And("is_pinned=?", true).Count(new(Issue))
+		if err != nil {
+			return fmt.Errorf("count pinned issues: %v", err)
+		} else if count >= int64(setting.Repository.MaxPinnedIssues) {
+			return ErrIssuePinnedLimit{i.RepoID, setting.Repository.MaxPinnedIssues}
+		}
+
+		last := new(Issue)
+		if _, err = sess.Where("repo_id=?", i.RepoID).And("is_pinned=?", true).Desc("pin_order").Get(last); err != nil {
+			return fmt.Errorf("get last pinned issue: %v", err)
+		}
+		i.PinOrder = last.PinOrder + 1
+	} else {
+		i.PinOrder = 0
+	}
+	i.IsPinned = isPinned
+
+	if _, err = sess.Id(i.ID).Cols("is_pinned", "pin_order").Update(i); err != nil {
+		return fmt.Errorf("update: %v", err)
+	}
 	return sess.Commit()
 }
 
@@ -323,6 +448,14 @@ func newIssue(e *xorm.Session, repo *Repository, issue *Issue, labelIDs []int64,
 		return err
 	}
 
+	if err = notifyIssueWatchers(e, issue.PosterID, issue); err != nil {
+		return fmt.Errorf("notifyIssueWatchers: %v", err)
+	} else if issue.AssigneeID > 0 {
+		if err = notifyUsers(e, issue.PosterID, issue, []int64{issue.AssigneeID}, NOTIFICATION_REASON_ASSIGN); err != nil {
+			return fmt.Errorf("notifyUsers: %v", err)
+		}
+	}
+
 	// Check attachments.
 	attachments := make([]*Attachment, 0, len(uuids))
 	for _, uuid := range uuids {
@@ -445,6 +578,17 @@ type IssuesOptions struct {
 	IsPull      bool
 	Labels      string
 	SortType    string
+
+	// LabelNames filters issues that have all given labels, by name.
+	LabelNames []string
+	// Keyword filters issues whose title or content contains it.
+	Keyword string
+	// VisibleToUserID restricts issues to repositories that the user can read,
+	// i.e. public ones, owned ones and those the user has access to.
+	VisibleToUserID int64
+	// OnlyEnabledUnit restricts issues to repositories that have enabled
+	// unit of issues or pull requests.
+	OnlyEnabledUnit bool
 }
 
 // Issues returns a list of issues by given conditions.
@@ -465,7 +609,8 @@ func Issues(opts *IssuesOptions) ([]*Issue, error) {
 
 	if opts.AssigneeID > 0 {
 		sess.And("issue.assignee_id=?", opts.AssigneeID)
-	} else if opts.PosterID > 0 {
+	}
+	if opts.PosterID > 0 {
 		sess.And("issue.poster_id=?", opts.PosterID)
 	}
 
@@ -475,6 +620,34 @@ func Issues(opts *IssuesOptions) ([]*Issue, error) {
 
 	sess.And("issue.is_pull=?", opts.IsPull)
 
+	if opts.VisibleToUserID > 0 {
+		sess.And(`issue.repo_id IN (SELECT id FROM repository WHERE is_private=? OR owner_id=?
+			OR id IN (SELECT repo_id FROM access WHERE user_id=? AND mode>=?))`,
+			false, opts.VisibleToUserID, opts.VisibleToUserID, ACCESS_MODE_READ)
+	}
+	if opts.OnlyEnabledUnit {
+		if opts.IsPull {
+			sess.And("issue.repo_id IN (SELECT id FROM repository WHERE enable_pulls=?)", true)
+		} else {
+			sess.And("issue.repo_id IN (SELECT id FROM repository WHERE enable_issues=?)", true)
+		}
+	}
+
+	if len(opts.Keyword) > 0 {
+		keyword := "%" + opts.Keyword + "%"
+		sess.And("(issue.name LIKE ? OR issue.content LIKE ?)", keyword, keyword)
+	}
+
+	for _, name := range opts.LabelNames {
+		sess.And(`issue.id IN (SELECT issue_label.issue_id FROM issue_label
+			INNER JOIN label ON label.id=issue_label.label_id WHERE label.name=?)`, name)
+	}
+
+	// Pinned issues come first in list of open issues of a repository.
+	if opts.RepoID > 0 && !opts.IsClosed {
+		sess.Desc("is_pinned").Asc("pin_order")
+	}
+
 	switch opts.SortType {
 	case "oldest":
 		sess.Asc("created")
@@ -623,6 +796,19 @@ func GetIssueUsers(rid, uid int64, isClosed bool) ([]*IssueUser, error) {
 	return ius, err
 }
 
+// IsParticipantOfIssue returns true if user has created, been assigned to,
+// been mentioned in or commented on given issue.
+func IsParticipantOfIssue(uid, issueID int64) (bool, error) {
+	has, err := x.Where("uid=? AND issue_id=?", uid, issueID).
+		And("is_poster=? OR is_assigned=? OR is_mentioned=?", true, true, true).Get(new(IssueUser))
+	if err != nil {
+		return false, err
+	} else if has {
+		return true, nil
+	}
+	return x.Where("poster_id=? AND issue_id=?", uid, issueID).Get(new(Comment))
+}
+
 // GetIssueUserPairsByRepoIds returns issue-user pairs by given repository IDs.
 func GetIssueUserPairsByRepoIds(rids []int64, isClosed bool, page int) ([]*IssueUser, error) {
 	if len(rids) == 0 {
@@ -835,7 +1021,18 @@ func UpdateIssueUsersByStatus(issueID int64, isClosed bool) error {
 	return updateIssueUsersByStatus(x, issueID, isClosed)
 }
 
-func updateIssueUserByAssignee(e *xorm.Session, issue *Issue) (err error) {
+func updateIssueUserByAssignee(e *xorm.Session, doer *User, issue *Issue) (err error) {
+	oldIssue := new(Issue)
+	if _, err = e.Id(issue.ID).Cols("assignee_id").Get(oldIssue); err != nil {
+		return err
+	} else if err = createEventComment(e, doer, issue, &Comment{
+		Type:          COMMENT_TYPE_ASSIGNEE,
+		OldAssigneeID: oldIssue.AssigneeID,
+		AssigneeID:    issue.AssigneeID,
+	}); err != nil {
+		return err
+	}
+
 	if _, err = e.Exec("UPDATE `issue_user` SET is_assigned=? WHERE issue_id=?", false, issue.ID); err != nil {
 		return err
 	}
@@ -844,21 +1041,24 @@ func updateIssueUserByAssignee(e *xorm.Session, issue *Issue) (err error) {
 	if issue.AssigneeID > 0 {
 		if _, err = e.Exec("UPDATE `issue_user` SET is_assigned=? WHERE uid=? AND issue_id=?", true, issue.AssigneeID, issue.ID); err != nil {
 			return err
+		} else if err = notifyUsers(e, doer.Id, issue, []int64{issue.AssigneeID}, NOTIFICATION_REASON_ASSIGN); err != nil {
+			return fmt.Errorf("notifyUsers: %v", err)
 		}
 	}
 
 	return updateIssue(e, issue)
 }
 
-// UpdateIssueUserByAssignee updates issue-user relation for assignee.
-func UpdateIssueUserByAssignee(issue *Issue) (err error) {
+// UpdateIssueUserByAssignee updates issue-user relation for assignee,
+// and notifies new assignee unless doer assigns oneself.
+func UpdateIssueUserByAssignee(doer *User, issue *Issue) (err error) {
 	sess := x.NewSession()
 	defer sessionRelease(sess)
 	if err = sess.Begin(); err != nil {
 		return err
 	}
 
-	if err = updateIssueUserByAssignee(sess, issue); err != nil {
+	if err = updateIssueUserByAssignee(sess, doer, issue); err != nil {
 		return err
 	}
 
@@ -1357,7 +1557,7 @@ func changeMilestoneAssign(e *xorm.Session, oldMid int64, issue *Issue) error {
 }
 
 // ChangeMilestoneAssign changes assignment of milestone for issue.
-func ChangeMilestoneAssign(oldMid int64, issue *Issue) (err error) {
+func ChangeMilestoneAssign(doer *User, oldMid int64, issue *Issue) (err error) {
 	sess := x.NewSession()
 	defer sess.Close()
 	if err = sess.Begin(); err != nil {
@@ -1366,6 +1566,12 @@ func ChangeMilestoneAssign(oldMid int64, issue *Issue) (err error) {
 
 	if err = changeMilestoneAssign(sess, oldMid, issue); err != nil {
 		return err
+	} else if err = createEventComment(sess, doer, issue, &Comment{
+		Type:           COMMENT_TYPE_MILESTONE,
+		OldMilestoneID: oldMid,
+		MilestoneID:    issue.MilestoneID,
+	}); err != nil {
+		return err
 	}
 	return sess.Commit()
 }
@@ -1433,6 +1639,21 @@ const (
 	COMMENT_TYPE_COMMENT_REF
 	// Reference from a pull request
 	COMMENT_TYPE_PULL_REF
+
+	// Comment on a line of file in pull request diff (TreePath != "" and Line != 0)
+	COMMENT_TYPE_CODE
+
+	// Conversation is locked (Content is the reason) or unlocked.
+	COMMENT_TYPE_LOCK
+	COMMENT_TYPE_UNLOCK
+
+	// Label (LabelID) is added to or removed from issue.
+	COMMENT_TYPE_LABEL
+	COMMENT_TYPE_UNLABEL
+	// Milestone is changed from OldMilestoneID to MilestoneID, 0 means none.
+	COMMENT_TYPE_MILESTONE
+	// Assignee is changed from OldAssigneeID to AssigneeID, 0 means none.
+	COMMENT_TYPE_ASSIGNEE
 )
 
 type CommentTag int
@@ -1452,14 +1673,24 @@ type Comment struct {
 	Poster          *User `xorm:"-"`
 	IssueID         int64 `xorm:"INDEX"`
 	CommitID        int64
-	Line            int64
+	Line            int64 // Negative for code comment on line of old file.
+	TreePath        string
 	Content         string    `xorm:"TEXT"`
 	RenderedContent string    `xorm:"-"`
 	Created         time.Time `xorm:"CREATED"`
+	Updated         time.Time `xorm:"UPDATED"`
+	IsEdited        bool
 
 	// Reference issue in commit message
 	CommitSHA string `xorm:"VARCHAR(40)"`
 
+	// For events of changing labels, milestone and assignee.
+	LabelID        int64
+	OldMilestoneID int64
+	MilestoneID    int64
+	OldAssigneeID  int64
+	AssigneeID     int64
+
 	Attachments []*Attachment `xorm:"-"`
 
 	// For view issue page.
@@ -1487,6 +1718,8 @@ func (c *Comment) AfterSet(colName string, _ xorm.Cell) {
 		}
 	case "created":
 		c.Created = regulateTimeZone(c.Created)
+	case "updated":
+		c.Updated = regulateTimeZone(c.Updated)
 	}
 }
 
@@ -1503,18 +1736,36 @@ func (c *Comment) HashTag() string {
 	return "issuecomment-" + com.ToStr(c.ID)
 }
 
+// IsOldLine returns true if code comment is made on line of old file.
+func (c *Comment) IsOldLine() bool {
+	return c.Line < 0
+}
+
+// LineNum returns line number in file of code comment.
+func (c *Comment) LineNum() int64 {
+	if c.Line < 0 {
+		return -c.Line
+	}
+	return c.Line
+}
+
 // EventTag returns unique event hash tag for comment.
 func (c *Comment) EventTag() string {
 	return "event-" + com.ToStr(c.ID)
 }
 
 func createComment(e *xorm.Session, u *User, repo *Repository, issue *Issue, commitID, line int64, cmtType CommentType, content, commitSHA string, uuids []string) (_ *Comment, err error) {
+	return createCommentWithTreePath(e, u, repo, issue, commitID, line, cmtType, content, commitSHA, "", uuids)
+}
+
+func createCommentWithTreePath(e *xorm.Session, u *User, repo *Repository, issue *Issue, commitID, line int64, cmtType CommentType, content, commitSHA, treePath string, uuids []string) (_ *Comment, err error) {
 	comment := &Comment{
 		PosterID:  u.Id,
 		Type:      cmtType,
 		IssueID:   issue.ID,
 		CommitID:  commitID,
 		Line:      line,
+		TreePath:  treePath,
 		Content:   content,
 		CommitSHA: commitSHA,
 	}
@@ -1524,7 +1775,7 @@ func createComment(e *xorm.Session, u *User, repo *Repository, issue *Issue, com
 
 	// Check comment type.
 	switch cmtType {
-	case COMMENT_TYPE_COMMENT:
+	case COMMENT_TYPE_COMMENT, COMMENT_TYPE_CODE:
 		if _, err = e.Exec("UPDATE `issue` SET num_comments=num_comments+1 WHERE id=?", issue.ID); err != nil {
 			return nil, err
 		}
@@ -1565,6 +1816,8 @@ func createComment(e *xorm.Session, u *User, repo *Repository, issue *Issue, com
 		}
 		if err = notifyWatchers(e, act); err != nil {
 			return nil, err
+		} else if err = notifyIssueWatchers(e, u.Id, issue); err != nil {
+			return nil, fmt.Errorf("notifyIssueWatchers: %v", err)
 		}
 
 	case COMMENT_TYPE_REOPEN:
@@ -1590,6 +1843,17 @@ func createComment(e *xorm.Session, u *User, repo *Repository, issue *Issue, com
 	return comment, nil
 }
 
+// createEventComment creates a comment that records an event of issue,
+// type and details of event must be set by caller.
+func createEventComment(e *xorm.Session, doer *User, issue *Issue, c *Comment) error {
+	c.PosterID = doer.Id
+	c.IssueID = issue.ID
+	if _, err := e.Insert(c); err != nil {
+		return fmt.Errorf("insert event comment: %v", err)
+	}
+	return nil
+}
+
 func createStatusComment(e *xorm.Session, doer *User, repo *Repository, issue *Issue) (*Comment, error) {
 	cmtType := COMMENT_TYPE_CLOSE
 	if !issue.IsClosed {
@@ -1619,6 +1883,56 @@ func CreateIssueComment(doer *User, repo *Repository, issue *Issue, content stri
 	return CreateComment(doer, repo, issue, 0, 0, COMMENT_TYPE_COMMENT, content, "", attachments)
 }
 
+// CreateCodeComment creates a comment on given line of file in diff of pull request,
+// line is negative when it refers to line of old file.
+func CreateCodeComment(doer *User, repo *Repository, issue *Issue, treePath string, line int64, commitSHA, content string) (_ *Comment, err error) {
+	if !issue.IsPull {
+		return nil, fmt.Errorf("issue is not a pull request: %d", issue.ID)
+	} else if len(treePath) == 0 || line == 0 {
+		return nil, fmt.Errorf("invalid position of code comment: %s:%d", treePath, line)
+	}
+
+	sess := x.NewSession()
+	defer sessionRelease(sess)
+	if err = sess.Begin(); err != nil {
+		return nil, err
+	}
+
+	comment, err := createCommentWithTreePath(sess, doer, repo, issue, 0, line, COMMENT_TYPE_CODE, content, commitSHA, treePath, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return comment, sess.Commit()
+}
+
+// CodeComments represents comments on lines of files grouped by file path and line,
+// comments on the same line form a thread in the order of creation.
+type CodeComments map[string]map[int64][]*Comment
+
+// Thread returns comments on given line of file in diff.
+func (cc CodeComments) Thread(treePath string, line int64) []*Comment {
+	return cc[treePath][line]
+}
+
+// GetCodeComments returns all code comments of pull request grouped into threads.
+func GetCodeComments(issueID int64) (CodeComments, error) {
+	comments := make([]*Comment, 0, 10)
+	if err := x.Where("issue_id=? AND type=?", issueID, COMMENT_TYPE_CODE).
+		Asc("created").Find(&comments); err != nil {
+		return nil, err
+	}
+
+	cc := make(CodeComments)
+	for _, c := range comments {
+		if cc[c.TreePath] == nil {
+			cc[c.TreePath] = make(map[int64][]*Comment)
+		}
+		cc[c.TreePath][c.Line] = append(cc[c.TreePath][c.Line], c)
+	}
+	return cc, nil
+}
+
 // CreateRefComment creates a commit reference comment to issue.
 func CreateRefComment(doer *User, repo *Repository, issue *Issue, content, commitSHA string) error {
 	if len(commitSHA) == 0 {
@@ -1659,12 +1973,45 @@ func GetCommentsByIssueID(issueID int64) ([]*Comment, error) {
 	return comments, x.Where("issue_id=?", issueID).Asc("created").Find(&comments)
 }
 
+// GetTimelineComments returns comments of all types of issue in given page,
+// which form the timeline of issue in chronological order.
+func GetTimelineComments(issueID int64, page, pageSize int) ([]*Comment, error) {
+	comments := make([]*Comment, 0, pageSize)
+	return comments, x.Where("issue_id=?", issueID).Asc("created").Asc("id").
+		Limit(pageSize, (page-1)*pageSize).Find(&comments)
+}
+
+// CountTimelineComments returns number of comments of all types of issue.
+func CountTimelineComments(issueID int64) (int64, error) {
+	return x.Where("issue_id=?", issueID).Count(new(Comment))
+}
+
 // UpdateComment updates information of comment.
 func UpdateComment(c *Comment) error {
 	_, err := x.Id(c.ID).AllCols().Update(c)
 	return err
 }
 
+// DeleteComment deletes comment and its attachments.
+func DeleteComment(c *Comment) (err error) {
+	sess := x.NewSession()
+	defer sessionRelease(sess)
+	if err = sess.Begin(); err != nil {
+		return err
+	}
+
+	// Bean must carry ID for deleting attachments in AfterDelete.
+	if _, err = sess.Delete(&Comment{ID: c.ID}); err != nil {
+		return err
+	}
+	if c.Type == COMMENT_TYPE_COMMENT || c.Type == COMMENT_TYPE_CODE {
+		if _, err = sess.Exec("UPDATE `issue` SET num_comments=num_comments-1 WHERE id=?", c.IssueID); err != nil {
+			return err
+		}
+	}
+	return sess.Commit()
+}
+
 // Attachment represent a attachment of issue/comment/release.
 type Attachment struct {
 	ID        int64  `xorm:"pk autoincr"`
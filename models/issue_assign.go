@@ -0,0 +1,224 @@
+// Copyright 2015 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"fmt"
+
+	"github.com/gogits/gogs/modules/base"
+)
+
+// AutoAssignMode represents how new issues and pull requests without assignee are assigned.
+type AutoAssignMode int
+
+const (
+	AUTO_ASSIGN_NONE        AutoAssignMode = iota
+	AUTO_ASSIGN_AUTHOR                     // Assign author of issue.
+	AUTO_ASSIGN_ROUND_ROBIN                // Assign configured users and team members in turn.
+)
+
+var autoAssignModes = map[string]AutoAssignMode{
+	"none":        AUTO_ASSIGN_NONE,
+	"author":      AUTO_ASSIGN_AUTHOR,
+	"round_robin": AUTO_ASSIGN_ROUND_ROBIN,
+}
+
+// ToAutoAssignMode returns AutoAssignMode by given name.
+func ToAutoAssignMode(name string) (AutoAssignMode, bool) {
+	mode, ok := autoAssignModes[name]
+	return mode, ok
+}
+
+func (mode AutoAssignMode) Name() string {
+	switch mode {
+	case AUTO_ASSIGN_AUTHOR:
+		return "author"
+	case AUTO_ASSIGN_ROUND_ROBIN:
+		return "round_robin"
+	}
+	return "none"
+}
+
+// GetAutoAssignees returns users assigned in turn in round-robin mode.
+// Users that no longer exist are skipped.
+func (repo *Repository) GetAutoAssignees() ([]*User, error) {
+	users := make([]*User, 0, 5)
+	for _, id := range splitIDs(repo.AutoAssigneeIDs) {
+		u, err := GetUserByID(id)
+		if err != nil {
+			if IsErrUserNotExist(err) {
+				continue
+			}
+			return nil, fmt.Errorf("GetUserByID[%d]: %v", id, err)
+		}
+		users = append(users, u)
+	}
+	return users, nil
+}
+
+// GetAutoAssignTeams returns teams whose members are assigned in turn in round-robin mode.
+// Teams that no longer exist are skipped.
+func (repo *Repository) GetAutoAssignTeams() ([]*Team, error) {
+	teams := make([]*Team, 0, 5)
+	for _, id := range splitIDs(repo.AutoAssignTeamIDs) {
+		t, err := GetTeamById(id)
+		if err != nil {
+			if err == ErrTeamNotExist {
+				continue
+			}
+			return nil, fmt.Errorf("GetTeamById[%d]: %v", id, err)
+		}
+		teams = append(teams, t)
+	}
+	return teams, nil
+}
+
+// SetAutoAssignment changes auto assignment of repository, users and teams are given by name
+// and only used in round-robin mode. Changes are saved by UpdateRepository.
+func (repo *Repository) SetAutoAssignment(mode AutoAssignMode, userNames, teamNames []string) error {
+	userIDs := make([]int64, 0, len(userNames))
+	teamIDs := make([]int64, 0, len(teamNames))
+	if mode == AUTO_ASSIGN_ROUND_ROBIN {
+		if len(userNames) == 0 && len(teamNames) == 0 {
+			return ErrInvalidAutoAssignee{""}
+		}
+
+		for _, name := range userNames {
+			u, err := GetUserByName(name)
+			if err != nil {
+				if IsErrUserNotExist(err) {
+					return ErrInvalidAutoAssignee{name}
+				}
+				return fmt.Errorf("GetUserByName: %v", err)
+			} else if u.IsOrganization() {
+				return ErrInvalidAutoAssignee{name}
+			} else if has, err := HasAccess(u, repo, ACCESS_MODE_WRITE); err != nil {
+				return fmt.Errorf("HasAccess: %v", err)
+			} else if !has {
+				return ErrInvalidAutoAssignee{name}
+			}
+			if !base.Int64sToMap(userIDs)[u.Id] {
+				userIDs = append(userIDs, u.Id)
+			}
+		}
+
+		if len(teamNames) > 0 {
+			if err := repo.GetOwner(); err != nil {
+				return fmt.Errorf("GetOwner: %v", err)
+			}
+		}
+		for _, name := range teamNames {
+			if !repo.Owner.IsOrganization() {
+				return ErrInvalidAutoAssignee{name}
+			}
+			t, err := repo.Owner.GetTeam(name)
+			if err != nil {
+				if err == ErrTeamNotExist {
+					return ErrInvalidAutoAssignee{name}
+				}
+				return fmt.Errorf("GetTeam: %v", err)
+			} else if !t.IsOwnerTeam() && (t.Authorize < ACCESS_MODE_WRITE || !t.HasRepository(repo.ID)) {
+				return ErrInvalidAutoAssignee{name}
+			}
+			if !base.Int64sToMap(teamIDs)[t.ID] {
+				teamIDs = append(teamIDs, t.ID)
+			}
+		}
+	}
+
+	repo.AutoAssignMode = mode
+	repo.AutoAssigneeIDs = joinIDs(userIDs)
+	repo.AutoAssignTeamIDs = joinIDs(teamIDs)
+	return nil
+}
+
+// autoAssignCandidates returns users assigned in turn in round-robin mode in fixed order,
+// configured users come first and are followed by members of configured teams.
+func (repo *Repository) autoAssignCandidates() ([]*User, error) {
+	users, err := repo.GetAutoAssignees()
+	if err != nil {
+		return nil, err
+	}
+	teams, err := repo.GetAutoAssignTeams()
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[int64]bool, len(users))
+	candidates := make([]*User, 0, len(users))
+	for _, u := range users {
+		seen[u.Id] = true
+		candidates = append(candidates, u)
+	}
+	for _, t := range teams {
+		if err = t.GetMembers(); err != nil {
+			return nil, fmt.Errorf("GetMembers[%d]: %v", t.ID, err)
+		}
+		for _, u := range t.Members {
+			if !seen[u.Id] {
+				seen[u.Id] = true
+				candidates = append(candidates, u)
+			}
+		}
+	}
+	return candidates, nil
+}
+
+// AutoAssignee returns ID of user to be assigned to a new issue or pull request posted
+// by given user, or 0 if nobody should be assigned. Only users who still have write
+// access to repository are assigned. In round-robin mode every call moves on to the
+// next candidate.
+func (repo *Repository) AutoAssignee(poster *User) (int64, error) {
+	switch repo.AutoAssignMode {
+	case AUTO_ASSIGN_AUTHOR:
+		has, err := HasAccess(poster, repo, ACCESS_MODE_WRITE)
+		if err != nil {
+			return 0, fmt.Errorf("HasAccess: %v", err)
+		} else if !has {
+			return 0, nil
+		}
+		return poster.Id, nil
+
+	case AUTO_ASSIGN_ROUND_ROBIN:
+		candidates, err := repo.autoAssignCandidates()
+		if err != nil {
+			return 0, err
+		}
+
+		assignees := make([]*User, 0, len(candidates))
+		for _, u := range candidates {
+			if has, err := HasAccess(u, repo, ACCESS_MODE_WRITE); err != nil {
+				return 0, fmt.Errorf("HasAccess: %v", err)
+			} else if has {
+				assignees = append(assignees, u)
+			}
+		}
+		if len(assignees) == 0 {
+			return 0, nil
+		}
+
+		// Cursor is kept in database so rotation continues after restarts.
+		// It is increased and read in one transaction, so that concurrent
+		// creations wait for the row lock and never pick the same position.
+		sess := x.NewSession()
+		defer sessionRelease(sess)
+		if err = sess.Begin(); err != nil {
+			return 0, err
+		}
+		if _, err = sess.Exec("UPDATE `repository` SET auto_assign_cursor=auto_assign_cursor+1 WHERE id=?", repo.ID); err != nil {
+			return 0, fmt.Errorf("increase cursor: %v", err)
+		}
+		cursor := new(Repository)
+		if _, err = sess.Id(repo.ID).Cols("auto_assign_cursor").Get(cursor); err != nil {
+			return 0, fmt.Errorf("get cursor: %v", err)
+		}
+		if err = sess.Commit(); err != nil {
+			return 0, err
+		}
+		repo.AutoAssignCursor = cursor.AutoAssignCursor
+		return assignees[(repo.AutoAssignCursor-1)%int64(len(assignees))].Id, nil
+	}
+	return 0, nil
+}
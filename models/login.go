@@ -504,5 +504,8 @@ func UserSignIn(uname, passwd string) (*User, error) {
 		log.Warn("Failed to login '%s' via '%s': %v", uname, source.Name, err)
 	}
 
+	// Spend about the same time as validating password of an existing user,
+	// so response time does not tell whether the user exists.
+	u.ValidatePassword(passwd)
 	return nil, ErrUserNotExist{u.Id, u.Name}
 }
@@ -19,6 +19,7 @@ import (
 	"github.com/go-xorm/xorm"
 	"gopkg.in/ini.v1"
 
+	"github.com/gogits/gogs/modules/git"
 	"github.com/gogits/gogs/modules/log"
 	"github.com/gogits/gogs/modules/setting"
 	gouuid "github.com/gogits/gogs/modules/uuid"
@@ -68,6 +69,7 @@ var migrations = []Migration{
 	NewMigration("refactor attachment table", attachmentRefactor),                // V7 -> V8:v0.6.4
 	NewMigration("rename pull request fields", renamePullRequestFields),          // V8 -> V9:v0.6.16
 	NewMigration("clean up migrate repo info", cleanUpMigrateRepoInfo),           // V9 -> V10:v0.6.20
+	NewMigration("wrap custom Git hooks with time limit", wrapCustomGitHooks),    // V10 -> V11:v0.7.24
 }
 
 // Migrate database to current version
@@ -707,3 +709,55 @@ func cleanUpMigrateRepoInfo(x *xorm.Engine) (err error) {
 
 	return nil
 }
+
+// wrapCustomGitHooks moves content of custom Git hooks saved directly in hooks directory
+// to custom hooks directory and installs wrappers, so they run with time limit.
+func wrapCustomGitHooks(x *xorm.Engine) (err error) {
+	type (
+		User struct {
+			ID        int64 `xorm:"pk autoincr"`
+			LowerName string
+		}
+		Repository struct {
+			ID        int64 `xorm:"pk autoincr"`
+			OwnerID   int64
+			LowerName string
+		}
+	)
+
+	repos := make([]*Repository, 0, 25)
+	if err = x.Find(&repos); err != nil {
+		return fmt.Errorf("select all repositories: %v", err)
+	}
+	var user *User
+	for _, repo := range repos {
+		user = &User{ID: repo.OwnerID}
+		has, err := x.Get(user)
+		if err != nil {
+			return fmt.Errorf("get owner of repository[%d - %d]: %v", repo.ID, repo.OwnerID, err)
+		} else if !has {
+			continue
+		}
+
+		repoPath := filepath.Join(setting.RepoRootPath, user.LowerName, repo.LowerName+".git")
+		if !com.IsDir(filepath.Join(repoPath, "hooks")) {
+			continue
+		}
+
+		hooks, err := git.ListHooks(repoPath)
+		if err != nil {
+			return fmt.Errorf("list hooks of repository[%d]: %v", repo.ID, err)
+		}
+		for _, h := range hooks {
+			if !h.IsActive || h.IsWrapped() {
+				continue
+			}
+			wrapper := fmt.Sprintf("#!/usr/bin/env %s\n%s hook --config='%s' %s \"$@\"\n",
+				setting.ScriptType, "\""+setting.AppPath+"\"", setting.CustomConf, h.Name())
+			if err = h.Update(wrapper); err != nil {
+				return fmt.Errorf("wrap hook '%s' of repository[%d]: %v", h.Name(), repo.ID, err)
+			}
+		}
+	}
+	return nil
+}
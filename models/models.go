@@ -88,7 +88,10 @@ func init() {
 		new(Mirror), new(Release), new(LoginSource), new(Webhook),
 		new(UpdateTask), new(HookTask),
 		new(Team), new(OrgUser), new(TeamUser), new(TeamRepo),
-		new(Notice), new(EmailAddress))
+		new(Notice), new(EmailAddress), new(UserExport), new(SecurityKey),
+		new(UserSession), new(ProtectedTag), new(OrgRepoDefaults), new(GPGKey),
+		new(OAuth2Application), new(OAuth2Grant), new(OAuth2Code),
+		new(ReviewRequest), new(Review), new(UserRedirect), new(Notification))
 
 	gonicNames := []string{"SSL"}
 	for _, name := range gonicNames {
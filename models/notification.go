@@ -0,0 +1,274 @@
+// Copyright 2015 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-xorm/xorm"
+)
+
+// NotificationReason represents why user receives a notification.
+type NotificationReason int
+
+const (
+	NOTIFICATION_REASON_WATCHING NotificationReason = iota + 1 // Activity of watched repository.
+	NOTIFICATION_REASON_MENTION                                // User is mentioned.
+	NOTIFICATION_REASON_ASSIGN                                 // User is assigned.
+)
+
+func (r NotificationReason) String() string {
+	switch r {
+	case NOTIFICATION_REASON_MENTION:
+		return "mention"
+	case NOTIFICATION_REASON_ASSIGN:
+		return "assign"
+	}
+	return "watching"
+}
+
+// Notification represents a notification of activity of an issue or pull request to user,
+// user has at most one notification for each issue which is updated by new activities.
+type Notification struct {
+	ID      int64 `xorm:"pk autoincr"`
+	UID     int64 `xorm:"UNIQUE(s) INDEX NOT NULL"`
+	IssueID int64 `xorm:"UNIQUE(s) NOT NULL"`
+	RepoID  int64 `xorm:"INDEX NOT NULL"`
+	Reason  NotificationReason
+	IsRead  bool      `xorm:"INDEX NOT NULL"`
+	Created time.Time `xorm:"CREATED"`
+	// Updated is the time of latest activity, it is not changed when notification is read.
+	Updated time.Time `xorm:"INDEX"`
+
+	Repo  *Repository `xorm:"-"`
+	Issue *Issue      `xorm:"-"`
+}
+
+func (n *Notification) AfterSet(colName string, _ xorm.Cell) {
+	switch colName {
+	case "created":
+		n.Created = regulateTimeZone(n.Created)
+	case "updated":
+		n.Updated = regulateTimeZone(n.Updated)
+	}
+}
+
+// LoadAttributes loads repository and issue of notification.
+func (n *Notification) LoadAttributes() (err error) {
+	if n.Repo == nil {
+		n.Repo, err = getRepositoryByID(x, n.RepoID)
+		if err != nil {
+			return fmt.Errorf("getRepositoryByID[%d]: %v", n.RepoID, err)
+		} else if err = n.Repo.GetOwner(); err != nil {
+			return fmt.Errorf("GetOwner: %v", err)
+		}
+	}
+	if n.Issue == nil {
+		n.Issue, err = GetIssueByID(n.IssueID)
+		if err != nil {
+			return fmt.Errorf("GetIssueByID[%d]: %v", n.IssueID, err)
+		}
+	}
+	return nil
+}
+
+// Link returns relative link to the issue or pull request of notification.
+func (n *Notification) Link() string {
+	if n.Issue.IsPull {
+		return fmt.Sprintf("%s/pulls/%d", n.Repo.RepoLink(), n.Issue.Index)
+	}
+	return fmt.Sprintf("%s/issues/%d", n.Repo.RepoLink(), n.Issue.Index)
+}
+
+// notifyUser creates or updates notification of issue for user and marks it as unread.
+// Reason of an existing notification is only replaced by more specific ones.
+func notifyUser(e Engine, uid int64, issue *Issue, reason NotificationReason) error {
+	n := &Notification{
+		UID:     uid,
+		IssueID: issue.ID,
+	}
+	has, err := e.Get(n)
+	if err != nil {
+		return err
+	}
+
+	n.RepoID = issue.RepoID
+	n.IsRead = false
+	n.Updated = time.Now()
+	if !has || reason != NOTIFICATION_REASON_WATCHING {
+		n.Reason = reason
+	}
+	if has {
+		_, err = e.Id(n.ID).AllCols().Update(n)
+	} else {
+		_, err = e.Insert(n)
+	}
+	return err
+}
+
+// notifyUsers notifies given users about activity of issue except the doer,
+// users who cannot read the repository are skipped.
+func notifyUsers(e Engine, doerID int64, issue *Issue, uids []int64, reason NotificationReason) error {
+	repo, err := getRepositoryByID(e, issue.RepoID)
+	if err != nil {
+		return fmt.Errorf("getRepositoryByID: %v", err)
+	}
+
+	notified := make(map[int64]bool, len(uids))
+	for _, uid := range uids {
+		if uid == doerID || notified[uid] {
+			continue
+		}
+		notified[uid] = true
+
+		u, err := getUserByID(e, uid)
+		if err != nil {
+			if IsErrUserNotExist(err) {
+				continue
+			}
+			return fmt.Errorf("getUserByID[%d]: %v", uid, err)
+		}
+		if has, err := hasAccess(e, u, repo, ACCESS_MODE_READ); err != nil {
+			return fmt.Errorf("hasAccess: %v", err)
+		} else if !has {
+			continue
+		}
+
+		if err := notifyUser(e, uid, issue, reason); err != nil {
+			return fmt.Errorf("notifyUser[%d]: %v", uid, err)
+		}
+	}
+	return nil
+}
+
+// notifyIssueWatchers notifies watchers of repository about activity of issue except the doer.
+func notifyIssueWatchers(e Engine, doerID int64, issue *Issue) error {
+	watches, err := getWatchers(e, issue.RepoID)
+	if err != nil {
+		return fmt.Errorf("getWatchers: %v", err)
+	}
+
+	uids := make([]int64, len(watches))
+	for i := range watches {
+		uids[i] = watches[i].UserID
+	}
+	return notifyUsers(e, doerID, issue, uids, NOTIFICATION_REASON_WATCHING)
+}
+
+// NotificationsOptions represents options of listing notifications of user.
+type NotificationsOptions struct {
+	UID        int64
+	RepoID     int64
+	OnlyUnread bool
+	Page       int
+	PageSize   int
+}
+
+func (opts *NotificationsOptions) session() *xorm.Session {
+	sess := x.Where("uid=?", opts.UID)
+	if opts.RepoID > 0 {
+		sess.And("repo_id=?", opts.RepoID)
+	}
+	if opts.OnlyUnread {
+		sess.And("is_read=?", false)
+	}
+	return sess
+}
+
+// GetNotifications returns notifications of user with given options with attributes loaded,
+// in the order of latest activity first. Notifications of repositories that user can
+// no longer read are deleted instead of being returned.
+func GetNotifications(opts *NotificationsOptions) ([]*Notification, error) {
+	if opts.Page <= 0 {
+		opts.Page = 1
+	}
+	if opts.PageSize <= 0 {
+		opts.PageSize = ItemsPerPage
+	}
+
+	notifications := make([]*Notification, 0, opts.PageSize)
+	if err := opts.session().Desc("updated").
+		Limit(opts.PageSize, (opts.Page-1)*opts.PageSize).Find(&notifications); err != nil {
+		return nil, err
+	}
+
+	u, err := GetUserByID(opts.UID)
+	if err != nil {
+		return nil, fmt.Errorf("GetUserByID: %v", err)
+	}
+
+	canRead := make(map[int64]bool)
+	accessible := make([]*Notification, 0, len(notifications))
+	for _, n := range notifications {
+		if err = n.LoadAttributes(); err != nil {
+			return nil, err
+		}
+
+		has, ok := canRead[n.RepoID]
+		if !ok {
+			if has, err = HasAccess(u, n.Repo, ACCESS_MODE_READ); err != nil {
+				return nil, fmt.Errorf("HasAccess: %v", err)
+			}
+			canRead[n.RepoID] = has
+		}
+		if !has {
+			if _, err = x.Id(n.ID).Delete(new(Notification)); err != nil {
+				return nil, fmt.Errorf("delete notification[%d]: %v", n.ID, err)
+			}
+			continue
+		}
+		accessible = append(accessible, n)
+	}
+	return accessible, nil
+}
+
+// CountNotifications returns number of notifications of user with given options.
+func CountNotifications(opts *NotificationsOptions) (int64, error) {
+	return opts.session().Count(new(Notification))
+}
+
+// CountUnreadNotifications returns number of unread notifications of user.
+func CountUnreadNotifications(uid int64) (int64, error) {
+	return CountNotifications(&NotificationsOptions{
+		UID:        uid,
+		OnlyUnread: true,
+	})
+}
+
+// MarkNotificationRead marks notification of user with given ID as read.
+func MarkNotificationRead(uid, id int64) error {
+	n := &Notification{
+		ID:  id,
+		UID: uid,
+	}
+	has, err := x.Get(n)
+	if err != nil {
+		return err
+	} else if !has {
+		return ErrNotificationNotExist{id}
+	}
+
+	n.IsRead = true
+	_, err = x.Id(n.ID).Cols("is_read").Update(n)
+	return err
+}
+
+// MarkNotificationsRead marks all notifications of user as read,
+// only notifications of given repository are marked if repoID is greater than 0.
+func MarkNotificationsRead(uid, repoID int64) error {
+	sess := x.Where("uid=?", uid).And("is_read=?", false)
+	if repoID > 0 {
+		sess.And("repo_id=?", repoID)
+	}
+	_, err := sess.Cols("is_read").Update(&Notification{IsRead: true})
+	return err
+}
+
+// markIssueNotificationRead marks notification of issue as read for user.
+func markIssueNotificationRead(uid, issueID int64) error {
+	_, err := x.Where("uid=?", uid).And("issue_id=?", issueID).Cols("is_read").Update(&Notification{IsRead: true})
+	return err
+}
@@ -0,0 +1,293 @@
+// Copyright 2015 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"crypto/subtle"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gogits/gogs/modules/base"
+	"github.com/gogits/gogs/modules/uuid"
+)
+
+// OAUTH2_CODE_LIFETIME is the time an authorization code can be exchanged for access token.
+const OAUTH2_CODE_LIFETIME = 10 * time.Minute
+
+// OAuth2Application represents an OAuth2 client application registered by a user,
+// it can only redirect users back to its registered redirect URIs.
+type OAuth2Application struct {
+	ID           int64 `xorm:"pk autoincr"`
+	UID          int64 `xorm:"INDEX"`
+	Name         string
+	ClientID     string    `xorm:"UNIQUE VARCHAR(40)"`
+	ClientSecret string    `xorm:"VARCHAR(40)"` // SHA1 of secret, which is only shown once.
+	RedirectURIs string    `xorm:"TEXT"`        // Newline separated list.
+	Created      time.Time `xorm:"CREATED"`
+	Updated      time.Time `xorm:"UPDATED"`
+}
+
+// RedirectURIList returns list of registered redirect URIs.
+func (app *OAuth2Application) RedirectURIList() []string {
+	if len(app.RedirectURIs) == 0 {
+		return nil
+	}
+	return strings.Split(app.RedirectURIs, "\n")
+}
+
+// IsValidRedirectURI returns true if given URI exactly matches one of registered redirect URIs,
+// prefix or partial matches are not allowed to avoid open redirects.
+func (app *OAuth2Application) IsValidRedirectURI(uri string) bool {
+	for _, u := range app.RedirectURIList() {
+		if u == uri {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateClientSecret returns true if given secret is the secret of application.
+func (app *OAuth2Application) ValidateClientSecret(secret string) bool {
+	return subtle.ConstantTimeCompare([]byte(base.EncodeSha1(secret)), []byte(app.ClientSecret)) == 1
+}
+
+// SetRedirectURIs validates and sets redirect URIs from newline separated list,
+// each of them must be an absolute URL without fragment.
+func (app *OAuth2Application) SetRedirectURIs(list string) error {
+	uris := make([]string, 0, 2)
+	for _, uri := range strings.Split(list, "\n") {
+		uri = strings.TrimSpace(uri)
+		if len(uri) == 0 {
+			continue
+		}
+
+		u, err := url.Parse(uri)
+		if err != nil || !u.IsAbs() || len(u.Host) == 0 || len(u.Fragment) > 0 ||
+			(u.Scheme != "http" && u.Scheme != "https") {
+			return ErrOAuth2InvalidRedirectURI{uri}
+		}
+		uris = append(uris, uri)
+	}
+	if len(uris) == 0 {
+		return ErrOAuth2InvalidRedirectURI{}
+	}
+	app.RedirectURIs = strings.Join(uris, "\n")
+	return nil
+}
+
+// NewOAuth2Application registers a new OAuth2 application and returns its client secret.
+// Caller should set redirect URIs by SetRedirectURIs in advance.
+func NewOAuth2Application(app *OAuth2Application) (string, error) {
+	secret := base.EncodeSha1(uuid.NewV4().String())
+	app.ClientID = base.EncodeSha1(uuid.NewV4().String())[:20]
+	app.ClientSecret = base.EncodeSha1(secret)
+	_, err := x.Insert(app)
+	return secret, err
+}
+
+// GetOAuth2ApplicationByID returns OAuth2 application of given user by ID.
+func GetOAuth2ApplicationByID(uid, id int64) (*OAuth2Application, error) {
+	app := &OAuth2Application{ID: id, UID: uid}
+	has, err := x.Get(app)
+	if err != nil {
+		return nil, err
+	} else if !has {
+		return nil, ErrOAuth2ApplicationNotExist{id, ""}
+	}
+	return app, nil
+}
+
+// GetOAuth2ApplicationByClientID returns OAuth2 application by given client ID.
+func GetOAuth2ApplicationByClientID(clientID string) (*OAuth2Application, error) {
+	if len(clientID) == 0 {
+		return nil, ErrOAuth2ApplicationNotExist{0, clientID}
+	}
+
+	app := &OAuth2Application{ClientID: clientID}
+	has, err := x.Get(app)
+	if err != nil {
+		return nil, err
+	} else if !has {
+		return nil, ErrOAuth2ApplicationNotExist{0, clientID}
+	}
+	return app, nil
+}
+
+// ListOAuth2Applications returns OAuth2 applications registered by given user.
+func ListOAuth2Applications(uid int64) ([]*OAuth2Application, error) {
+	apps := make([]*OAuth2Application, 0, 5)
+	return apps, x.Where("uid=?", uid).Desc("id").Find(&apps)
+}
+
+// DeleteOAuth2Application deletes OAuth2 application of given user,
+// and revokes all authorizations and access tokens issued to it.
+func DeleteOAuth2Application(uid, id int64) error {
+	if _, err := GetOAuth2ApplicationByID(uid, id); err != nil {
+		return err
+	}
+
+	grants := make([]*OAuth2Grant, 0, 10)
+	if err := x.Where("app_id=?", id).Find(&grants); err != nil {
+		return err
+	}
+
+	sess := x.NewSession()
+	defer sessionRelease(sess)
+	if err := sess.Begin(); err != nil {
+		return err
+	}
+
+	for _, g := range grants {
+		if err := deleteOAuth2Grant(sess, g.ID); err != nil {
+			return err
+		}
+	}
+	if _, err := sess.Id(id).Delete(new(OAuth2Application)); err != nil {
+		return err
+	}
+	return sess.Commit()
+}
+
+// OAuth2Grant represents authorization of a user to an OAuth2 application.
+type OAuth2Grant struct {
+	ID      int64              `xorm:"pk autoincr"`
+	UID     int64              `xorm:"UNIQUE(s)"`
+	AppID   int64              `xorm:"UNIQUE(s)"`
+	App     *OAuth2Application `xorm:"-"`
+	Scopes  string             // Comma separated list, empty means full access.
+	Created time.Time          `xorm:"CREATED"`
+	Updated time.Time          `xorm:"UPDATED"`
+}
+
+// ListOAuth2Grants returns all authorizations of given user with their applications.
+func ListOAuth2Grants(uid int64) ([]*OAuth2Grant, error) {
+	grants := make([]*OAuth2Grant, 0, 5)
+	if err := x.Where("uid=?", uid).Desc("updated").Find(&grants); err != nil {
+		return nil, err
+	}
+
+	for _, g := range grants {
+		g.App = new(OAuth2Application)
+		if has, err := x.Id(g.AppID).Get(g.App); err != nil {
+			return nil, err
+		} else if !has {
+			g.App = &OAuth2Application{ID: g.AppID}
+		}
+	}
+	return grants, nil
+}
+
+func deleteOAuth2Grant(e Engine, id int64) error {
+	if _, err := e.Where("grant_id=?", id).Delete(new(AccessToken)); err != nil {
+		return err
+	} else if _, err = e.Where("grant_id=?", id).Delete(new(OAuth2Code)); err != nil {
+		return err
+	}
+	_, err := e.Id(id).Delete(new(OAuth2Grant))
+	return err
+}
+
+// RevokeOAuth2Grant revokes authorization of given user by ID,
+// and deletes all access tokens issued through it.
+func RevokeOAuth2Grant(uid, id int64) error {
+	g := &OAuth2Grant{ID: id, UID: uid}
+	has, err := x.Get(g)
+	if err != nil {
+		return err
+	} else if !has {
+		return ErrOAuth2GrantNotExist{id}
+	}
+
+	sess := x.NewSession()
+	defer sessionRelease(sess)
+	if err = sess.Begin(); err != nil {
+		return err
+	}
+	if err = deleteOAuth2Grant(sess, id); err != nil {
+		return err
+	}
+	return sess.Commit()
+}
+
+// OAuth2Code represents an authorization code waiting to be exchanged for access token.
+type OAuth2Code struct {
+	ID          int64  `xorm:"pk autoincr"`
+	GrantID     int64  `xorm:"INDEX"`
+	Code        string `xorm:"UNIQUE VARCHAR(40)"` // SHA1 of code.
+	RedirectURI string `xorm:"TEXT"`
+	Expires     int64
+}
+
+// NewOAuth2Code authorizes application on behalf of user with given scopes,
+// and returns a new authorization code bound to given redirect URI.
+func NewOAuth2Code(uid int64, app *OAuth2Application, redirectURI string, scopes []string) (string, error) {
+	// Reuse token validation of scopes.
+	t := new(AccessToken)
+	if err := t.SetScopes(scopes); err != nil {
+		return "", err
+	}
+
+	g := &OAuth2Grant{UID: uid, AppID: app.ID}
+	has, err := x.Get(g)
+	if err != nil {
+		return "", err
+	}
+	g.Scopes = t.Scopes
+	if has {
+		_, err = x.Id(g.ID).AllCols().Update(g)
+	} else {
+		_, err = x.Insert(g)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	code := base.EncodeSha1(uuid.NewV4().String())
+	_, err = x.Insert(&OAuth2Code{
+		GrantID:     g.ID,
+		Code:        base.EncodeSha1(code),
+		RedirectURI: redirectURI,
+		Expires:     time.Now().Add(OAUTH2_CODE_LIFETIME).Unix(),
+	})
+	return code, err
+}
+
+// ExchangeOAuth2Code exchanges authorization code issued to given application
+// for a new access token. Code can only be used once, and redirect URI must
+// be the same as the one used to request the code.
+func ExchangeOAuth2Code(app *OAuth2Application, code, redirectURI string) (*AccessToken, error) {
+	c := &OAuth2Code{Code: base.EncodeSha1(code)}
+	has, err := x.Get(c)
+	if err != nil {
+		return nil, err
+	} else if !has {
+		return nil, ErrOAuth2InvalidCode{code}
+	}
+
+	// Code is consumed no matter exchange succeeds or not.
+	if _, err = x.Id(c.ID).Delete(new(OAuth2Code)); err != nil {
+		return nil, err
+	}
+
+	g := &OAuth2Grant{ID: c.GrantID}
+	if has, err = x.Get(g); err != nil {
+		return nil, err
+	} else if !has || g.AppID != app.ID || c.RedirectURI != redirectURI ||
+		time.Now().Unix() > c.Expires {
+		return nil, ErrOAuth2InvalidCode{code}
+	}
+
+	t := &AccessToken{
+		UID:     g.UID,
+		Name:    app.Name,
+		Scopes:  g.Scopes,
+		GrantID: g.ID,
+	}
+	if err = NewAccessToken(t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
@@ -25,6 +25,20 @@ func (org *User) IsOwnedBy(uid int64) bool {
 	return IsOrganizationOwner(org.Id, uid)
 }
 
+// CanCreateOrgRepo returns true if given user is allowed to create repositories in organization.
+func (org *User) CanCreateOrgRepo(uid int64) bool {
+	return org.IsOwnedBy(uid) || (org.MembersCanCreateRepo && org.IsOrgMember(uid))
+}
+
+// GrantRepoCreatorAccess gives member who created repository in organization
+// access to it as collaborator, owners already have full access to all repositories.
+func (org *User) GrantRepoCreatorAccess(repo *Repository, u *User) error {
+	if !org.IsOrganization() || org.IsOwnedBy(u.Id) {
+		return nil
+	}
+	return repo.addCollaborator(u, true)
+}
+
 // IsOrgMember returns true if given user is member of organization.
 func (org *User) IsOrgMember(uid int64) bool {
 	return org.IsOrganization() && IsOrganizationMember(org.Id, uid)
@@ -106,6 +120,13 @@ func CreateOrganization(org, owner *User) (err error) {
 		return ErrUserAlreadyExist{org.Name}
 	}
 
+	isExist, err = isNameRedirectedByOthers(0, org.Name)
+	if err != nil {
+		return err
+	} else if isExist {
+		return ErrUserAlreadyExist{org.Name}
+	}
+
 	org.LowerName = strings.ToLower(org.Name)
 	org.FullName = org.Name
 	org.UseCustomAvatar = true
@@ -206,6 +227,7 @@ func DeleteOrganization(org *User) (err error) {
 		&Team{OrgID: org.Id},
 		&OrgUser{OrgID: org.Id},
 		&TeamUser{OrgID: org.Id},
+		&OrgRepoDefaults{OrgID: org.Id},
 	); err != nil {
 		return fmt.Errorf("deleteBeans: %v", err)
 	}
@@ -271,6 +293,15 @@ func GetOwnedOrgsByUserIDDesc(userID int64, desc string) ([]*User, error) {
 	return getOwnedOrgsByUserID(sess.Desc(desc), userID)
 }
 
+// GetOrgsCanCreateRepoByUserID returns a list of organizations that given user
+// is allowed to create repositories in, ordered by recently updated.
+func GetOrgsCanCreateRepoByUserID(userID int64) ([]*User, error) {
+	orgs := make([]*User, 0, 10)
+	return orgs, x.Where("`org_user`.uid=?", userID).
+		And("`org_user`.is_owner=? OR `user`.members_can_create_repo=?", true, true).
+		Join("INNER", "`org_user`", "`org_user`.org_id=`user`.id").Desc("`user`.updated").Find(&orgs)
+}
+
 // GetOrgUsersByUserId returns all organization-user relations by user ID.
 func GetOrgUsersByUserId(uid int64) ([]*OrgUser, error) {
 	ous := make([]*OrgUser, 0, 10)
@@ -285,6 +316,19 @@ func GetOrgUsersByOrgId(orgId int64) ([]*OrgUser, error) {
 	return ous, err
 }
 
+// GetOrgUser returns organization-user relation of given organization and user,
+// it returns nil if user is not a member of organization.
+func GetOrgUser(orgID, uid int64) (*OrgUser, error) {
+	ou := new(OrgUser)
+	has, err := x.Where("uid=?", uid).And("org_id=?", orgID).Get(ou)
+	if err != nil {
+		return nil, err
+	} else if !has {
+		return nil, nil
+	}
+	return ou, nil
+}
+
 // ChangeOrgUserStatus changes public or private membership status.
 func ChangeOrgUserStatus(orgId, uid int64, public bool) error {
 	ou := new(OrgUser)
@@ -300,12 +344,18 @@ func ChangeOrgUserStatus(orgId, uid int64, public bool) error {
 	return err
 }
 
-// AddOrgUser adds new user to given organization.
+// AddOrgUser adds new user to given organization,
+// default membership visibility and team of organization are applied.
 func AddOrgUser(orgId, uid int64) error {
 	if IsOrganizationMember(orgId, uid) {
 		return nil
 	}
 
+	org, err := GetUserByID(orgId)
+	if err != nil {
+		return fmt.Errorf("get organization: %v", err)
+	}
+
 	sess := x.NewSession()
 	defer sess.Close()
 	if err := sess.Begin(); err != nil {
@@ -313,8 +363,9 @@ func AddOrgUser(orgId, uid int64) error {
 	}
 
 	ou := &OrgUser{
-		Uid:   uid,
-		OrgID: orgId,
+		Uid:      uid,
+		OrgID:    orgId,
+		IsPublic: org.DefaultMemberPublic,
 	}
 
 	if _, err := sess.Insert(ou); err != nil {
@@ -325,7 +376,17 @@ func AddOrgUser(orgId, uid int64) error {
 		return err
 	}
 
-	return sess.Commit()
+	if err = sess.Commit(); err != nil {
+		return err
+	}
+
+	if org.DefaultTeamID > 0 {
+		// Default team may have been deleted after it was set.
+		if err = AddTeamMember(orgId, org.DefaultTeamID, uid); err != nil && err != ErrTeamNotExist {
+			return fmt.Errorf("add to default team: %v", err)
+		}
+	}
+	return nil
 }
 
 // RemoveOrgUser removes user from given organization.
@@ -373,6 +434,17 @@ func RemoveOrgUser(orgId, uid int64) error {
 		return err
 	}
 
+	// Revoke collaborations granted for creating repositories.
+	if len(org.Repos) > 0 {
+		repoIDs := make([]int64, len(org.Repos))
+		for i := range org.Repos {
+			repoIDs[i] = org.Repos[i].ID
+		}
+		if _, err = sess.Where("user_id=? AND is_creator=?", u.Id, true).In("repo_id", repoIDs).Delete(new(Collaboration)); err != nil {
+			return fmt.Errorf("delete creator collaborations: %v", err)
+		}
+	}
+
 	// Delete all repository accesses.
 	access := &Access{UserID: u.Id}
 	for _, repo := range org.Repos {
@@ -825,6 +897,11 @@ func AddTeamMember(orgId, teamId, uid int64) error {
 		return err
 	}
 
+	// User may have been added to the team as default team of organization.
+	if IsTeamMember(orgId, teamId, uid) {
+		return nil
+	}
+
 	// Get team and its repositories.
 	t, err := GetTeamById(teamId)
 	if err != nil {
@@ -0,0 +1,104 @@
+// Copyright 2015 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+var defaultLabelPattern = regexp.MustCompile(`^(#[0-9a-fA-F]{6})\s+(.+)$`)
+
+// OrgRepoDefaults represents default settings applied to repositories
+// newly created in an organization.
+type OrgRepoDefaults struct {
+	ID            int64 `xorm:"pk autoincr"`
+	OrgID         int64 `xorm:"UNIQUE"`
+	DefaultBranch string
+	IsPrivate     bool
+	EnableIssues  bool `xorm:"NOT NULL DEFAULT true"`
+	EnableWiki    bool `xorm:"NOT NULL DEFAULT true"`
+	// Labels is the list of labels created in new repositories,
+	// one label per line in format "#color name".
+	Labels  string    `xorm:"TEXT"`
+	Updated time.Time `xorm:"UPDATED"`
+}
+
+// ParseDefaultLabels parses labels from text in format of one "#color name" per line,
+// empty lines are ignored.
+func ParseDefaultLabels(text string) ([]*Label, error) {
+	lines := strings.Split(text, "\n")
+	labels := make([]*Label, 0, len(lines))
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+
+		infos := defaultLabelPattern.FindStringSubmatch(line)
+		if len(infos) != 3 {
+			return nil, ErrInvalidDefaultLabel{line}
+		}
+		labels = append(labels, &Label{
+			Name:  strings.TrimSpace(infos[2]),
+			Color: strings.ToLower(infos[1]),
+		})
+	}
+	return labels, nil
+}
+
+// Apply sets default values of organization to options of creating repository,
+// values given by creator are not changed. Visibility and units are only set
+// when corresponding value is not given (nil).
+func (d *OrgRepoDefaults) Apply(opts *CreateRepoOptions, isPrivate, enableIssues, enableWiki *bool, withLabels bool) {
+	if len(opts.DefaultBranch) == 0 {
+		opts.DefaultBranch = d.DefaultBranch
+	}
+	if isPrivate == nil {
+		opts.IsPrivate = d.IsPrivate
+	}
+	if enableIssues == nil {
+		opts.DisableIssues = !d.EnableIssues
+	}
+	if enableWiki == nil {
+		opts.DisableWiki = !d.EnableWiki
+	}
+	if withLabels {
+		// Labels have been validated when saved.
+		opts.Labels, _ = ParseDefaultLabels(d.Labels)
+	}
+}
+
+// GetOrgRepoDefaults returns default repository settings of given organization,
+// an empty set is returned if organization has not configured any.
+func GetOrgRepoDefaults(orgID int64) (*OrgRepoDefaults, error) {
+	d := new(OrgRepoDefaults)
+	has, err := x.Where("org_id=?", orgID).Get(d)
+	if err != nil {
+		return nil, err
+	} else if !has {
+		return &OrgRepoDefaults{
+			OrgID:        orgID,
+			EnableIssues: true,
+			EnableWiki:   true,
+		}, nil
+	}
+	return d, nil
+}
+
+// UpdateOrgRepoDefaults saves default repository settings of organization.
+func UpdateOrgRepoDefaults(d *OrgRepoDefaults) (err error) {
+	if _, err = ParseDefaultLabels(d.Labels); err != nil {
+		return err
+	}
+
+	if d.ID == 0 {
+		_, err = x.Insert(d)
+	} else {
+		_, err = x.Id(d.ID).AllCols().Update(d)
+	}
+	return err
+}
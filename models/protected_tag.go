@@ -0,0 +1,114 @@
+// Copyright 2015 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"path"
+	"time"
+)
+
+const (
+	// ENV_AUTH_USER_ID is the environment variable passed to Git hooks with ID of pusher.
+	ENV_AUTH_USER_ID = "GOGS_AUTH_USER_ID"
+	// ENV_REPO_ID is the environment variable passed to Git hooks with ID of repository being pushed to.
+	ENV_REPO_ID = "GOGS_REPO_ID"
+)
+
+// ProtectedTag represents a rule that prevents existing tags matching pattern
+// from being updated or deleted by push.
+type ProtectedTag struct {
+	ID      int64  `xorm:"pk autoincr"`
+	RepoID  int64  `xorm:"INDEX"`
+	Pattern string `xorm:"NOT NULL"`
+	// OverrideMode is the minimum access mode of pusher to bypass the rule,
+	// nobody is allowed when it is ACCESS_MODE_NONE.
+	OverrideMode AccessMode
+	Created      time.Time `xorm:"CREATED"`
+}
+
+// Match returns true if given tag name matches pattern of rule.
+func (t *ProtectedTag) Match(tagName string) bool {
+	matched, _ := path.Match(t.Pattern, tagName)
+	return matched
+}
+
+// CanOverride returns true if given access mode is allowed to bypass the rule.
+func (t *ProtectedTag) CanOverride(mode AccessMode) bool {
+	return t.OverrideMode > ACCESS_MODE_NONE && mode >= t.OverrideMode
+}
+
+// OverrideModeName returns name of minimum access mode to bypass the rule.
+func (t *ProtectedTag) OverrideModeName() string {
+	switch {
+	case t.OverrideMode >= ACCESS_MODE_OWNER:
+		return "owner"
+	case t.OverrideMode >= ACCESS_MODE_ADMIN:
+		return "admin"
+	}
+	return "none"
+}
+
+// NewProtectedTag creates a new tag protection rule of repository.
+func NewProtectedTag(repoID int64, pattern string, overrideMode AccessMode) (*ProtectedTag, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, ErrInvalidTagPattern{pattern}
+	}
+
+	has, err := x.Where("repo_id=? AND pattern=?", repoID, pattern).Get(new(ProtectedTag))
+	if err != nil {
+		return nil, err
+	} else if has {
+		return nil, ErrProtectedTagAlreadyExist{pattern}
+	}
+
+	t := &ProtectedTag{
+		RepoID:       repoID,
+		Pattern:      pattern,
+		OverrideMode: overrideMode,
+	}
+	if _, err = x.Insert(t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// GetProtectedTags returns all tag protection rules of repository.
+func GetProtectedTags(repoID int64) ([]*ProtectedTag, error) {
+	tags := make([]*ProtectedTag, 0, 5)
+	return tags, x.Where("repo_id=?", repoID).Asc("id").Find(&tags)
+}
+
+// DeleteProtectedTag deletes a tag protection rule of repository by ID.
+func DeleteProtectedTag(repoID, id int64) error {
+	_, err := x.Where("id=? AND repo_id=?", id, repoID).Delete(new(ProtectedTag))
+	return err
+}
+
+// GetBlockingProtectedTag returns the rule that prevents doer from updating or deleting
+// given tag of repository, or nil if doer is allowed to do so.
+// Doer can be nil, e.g. push via deploy key.
+func GetBlockingProtectedTag(repo *Repository, doer *User, tagName string) (*ProtectedTag, error) {
+	tags, err := GetProtectedTags(repo.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var mode AccessMode
+	for _, t := range tags {
+		if !t.Match(tagName) {
+			continue
+		}
+
+		if doer != nil && mode == ACCESS_MODE_NONE {
+			if mode, err = AccessLevel(doer, repo); err != nil {
+				return nil, err
+			}
+		}
+		if !t.CanOverride(mode) {
+			return t, nil
+		}
+	}
+	return nil, nil
+}
@@ -519,11 +519,17 @@ type DeployKey struct {
 	RepoID            int64 `xorm:"UNIQUE(s) INDEX"`
 	Name              string
 	Fingerprint       string
-	Content           string    `xorm:"-"`
-	Created           time.Time `xorm:"CREATED"`
-	Updated           time.Time // Note: Updated must below Created for AfterSet.
-	HasRecentActivity bool      `xorm:"-"`
-	HasUsed           bool      `xorm:"-"`
+	Content           string     `xorm:"-"`
+	Mode              AccessMode `xorm:"NOT NULL DEFAULT 1"`
+	Created           time.Time  `xorm:"CREATED"`
+	Updated           time.Time  // Note: Updated must below Created for AfterSet.
+	HasRecentActivity bool       `xorm:"-"`
+	HasUsed           bool       `xorm:"-"`
+}
+
+// IsReadOnly returns true if deploy key can only be used to clone and pull.
+func (k *DeployKey) IsReadOnly() bool {
+	return k.Mode < ACCESS_MODE_WRITE
 }
 
 func (k *DeployKey) AfterSet(colName string, _ xorm.Cell) {
@@ -564,7 +570,7 @@ func checkDeployKey(e Engine, keyID, repoID int64, name string) error {
 }
 
 // addDeployKey adds new key-repo relation.
-func addDeployKey(e *xorm.Session, keyID, repoID int64, name, fingerprint string) (*DeployKey, error) {
+func addDeployKey(e *xorm.Session, keyID, repoID int64, name, fingerprint string, mode AccessMode) (*DeployKey, error) {
 	if err := checkDeployKey(e, keyID, repoID, name); err != nil {
 		return nil, err
 	}
@@ -574,6 +580,7 @@ func addDeployKey(e *xorm.Session, keyID, repoID int64, name, fingerprint string
 		RepoID:      repoID,
 		Name:        name,
 		Fingerprint: fingerprint,
+		Mode:        mode,
 	}
 	_, err := e.Insert(key)
 	return key, err
@@ -585,15 +592,17 @@ func HasDeployKey(keyID, repoID int64) bool {
 	return has
 }
 
-// AddDeployKey add new deploy key to database and authorized_keys file.
-func AddDeployKey(repoID int64, name, content string) (*DeployKey, error) {
+// AddDeployKey add new deploy key to database and authorized_keys file,
+// key can be used to push to the repository unless it is read-only.
+func AddDeployKey(repoID int64, name, content string, readOnly bool) (*DeployKey, error) {
 	if err := checkKeyContent(content); err != nil {
 		return nil, err
 	}
 
+	// Same public key can be used by multiple repositories with different access modes,
+	// so access mode of each repository is stored in deploy key.
 	pkey := &PublicKey{
 		Content: content,
-		Mode:    ACCESS_MODE_READ,
 		Type:    KEY_TYPE_DEPLOY,
 	}
 	has, err := x.Get(pkey)
@@ -609,12 +618,17 @@ func AddDeployKey(repoID int64, name, content string) (*DeployKey, error) {
 
 	// First time use this deploy key.
 	if !has {
+		pkey.Mode = ACCESS_MODE_READ
 		if err = addKey(sess, pkey); err != nil {
 			return nil, fmt.Errorf("addKey: %v", err)
 		}
 	}
 
-	key, err := addDeployKey(sess, pkey.ID, repoID, name, pkey.Fingerprint)
+	mode := ACCESS_MODE_WRITE
+	if readOnly {
+		mode = ACCESS_MODE_READ
+	}
+	key, err := addDeployKey(sess, pkey.ID, repoID, name, pkey.Fingerprint, mode)
 	if err != nil {
 		return nil, fmt.Errorf("addDeployKey: %v", err)
 	}
@@ -0,0 +1,173 @@
+// Copyright 2015 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gogits/gogs/modules/base"
+	"github.com/gogits/gogs/modules/setting"
+)
+
+const (
+	// _KEY_IMPORT_MAX_SIZE is the maximum size of response of remote key list.
+	_KEY_IMPORT_MAX_SIZE = 64 << 10
+	// _KEY_IMPORT_TIMEOUT is the time limit of fetching remote key list.
+	_KEY_IMPORT_TIMEOUT = 10 * time.Second
+)
+
+// keyImportUserPattern matches usernames that are safe to be put in URL path.
+var keyImportUserPattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]{0,99}$`)
+
+// IsValidKeyImportSource returns true if given host is allowed to import public keys from.
+func IsValidKeyImportSource(source string) bool {
+	for _, s := range setting.Service.SSHKeyImportSources {
+		if s == source {
+			return true
+		}
+	}
+	return false
+}
+
+var privateIPNets []*net.IPNet
+
+func init() {
+	for _, cidr := range []string{
+		"0.0.0.0/8", "10.0.0.0/8", "100.64.0.0/10", "127.0.0.0/8", "169.254.0.0/16",
+		"172.16.0.0/12", "192.168.0.0/16", "::1/128", "fc00::/7", "fe80::/10",
+	} {
+		_, ipNet, _ := net.ParseCIDR(cidr)
+		privateIPNets = append(privateIPNets, ipNet)
+	}
+}
+
+// isPublicIP returns false if given IP address is in loopback, private,
+// link-local or other non-routable network.
+func isPublicIP(ip net.IP) bool {
+	if ip.IsUnspecified() || ip.IsMulticast() {
+		return false
+	}
+	for _, ipNet := range privateIPNets {
+		if ipNet.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// dialPublicAddr only connects to public IP addresses, so that configured host
+// cannot be used to reach internal services through DNS.
+func dialPublicAddr(network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	ips, err := net.LookupIP(host)
+	if err != nil {
+		return nil, err
+	}
+
+	dialer := &net.Dialer{Timeout: _KEY_IMPORT_TIMEOUT}
+	for _, ip := range ips {
+		if !isPublicIP(ip) {
+			continue
+		}
+		// Connect to the checked address, not the name, to avoid resolving again.
+		return dialer.Dial(network, net.JoinHostPort(ip.String(), port))
+	}
+	return nil, fmt.Errorf("no public address found for host: %s", host)
+}
+
+var keyImportClient = &http.Client{
+	Timeout: _KEY_IMPORT_TIMEOUT,
+	Transport: &http.Transport{
+		Dial: dialPublicAddr,
+	},
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return errors.New("redirect is not allowed")
+	},
+}
+
+// FetchRemotePublicKeys returns public keys of given user on given host,
+// which are listed at "https://<host>/<username>.keys".
+func FetchRemotePublicKeys(source, username string) ([]string, error) {
+	if !IsValidKeyImportSource(source) {
+		return nil, fmt.Errorf("source is not allowed: %s", source)
+	} else if !keyImportUserPattern.MatchString(username) {
+		return nil, fmt.Errorf("invalid username: %s", username)
+	}
+
+	u := &url.URL{
+		Scheme: "https",
+		Host:   source,
+		Path:   "/" + username + ".keys",
+	}
+	resp, err := keyImportClient.Get(u.String())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+
+	keys := make([]string, 0, 5)
+	scanner := bufio.NewScanner(io.LimitReader(resp.Body, _KEY_IMPORT_MAX_SIZE))
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); len(line) > 0 {
+			keys = append(keys, line)
+		}
+	}
+	return keys, scanner.Err()
+}
+
+// ImportedPublicKey represents result of importing a public key.
+type ImportedPublicKey struct {
+	Name   string
+	Reason string // Reason why key is skipped.
+}
+
+// ImportPublicKeys validates and adds given public keys to user,
+// keys that are invalid or already in use are skipped.
+func ImportPublicKeys(ownerID int64, source, username string, contents []string) (added, skipped []*ImportedPublicKey, err error) {
+	for _, raw := range contents {
+		content, err := CheckPublicKeyString(raw)
+		if err != nil && !IsErrKeyUnableVerify(err) {
+			name := raw
+			if len(name) > 30 {
+				name = name[:27] + "..."
+			}
+			skipped = append(skipped, &ImportedPublicKey{
+				Name:   name,
+				Reason: err.Error(),
+			})
+			continue
+		}
+
+		// Key is named after its content so imported names never collide.
+		key := &ImportedPublicKey{
+			Name: fmt.Sprintf("%s/%s %s", source, username, base.EncodeMD5(content)[:8]),
+		}
+		if err = AddPublicKey(ownerID, key.Name, content); err != nil {
+			if IsErrKeyAlreadyExist(err) || IsErrKeyNameAlreadyUsed(err) {
+				key.Reason = "already in use"
+				skipped = append(skipped, key)
+				continue
+			}
+			return added, skipped, fmt.Errorf("AddPublicKey: %v", err)
+		}
+		added = append(added, key)
+	}
+	return added, skipped, nil
+}
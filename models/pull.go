@@ -16,7 +16,6 @@ import (
 
 	"github.com/gogits/gogs/modules/git"
 	"github.com/gogits/gogs/modules/log"
-	"github.com/gogits/gogs/modules/process"
 	"github.com/gogits/gogs/modules/setting"
 )
 
@@ -35,6 +34,23 @@ const (
 	PULL_REQUEST_STATUS_MERGEABLE
 )
 
+// MergeStyle represents the way of merging head branch into base branch.
+type MergeStyle string
+
+const (
+	MERGE_STYLE_MERGE  MergeStyle = "merge"  // Create a merge commit.
+	MERGE_STYLE_SQUASH MergeStyle = "squash" // Squash all commits into a single one.
+)
+
+// IsValidMergeStyle returns true if given name is a supported merge style.
+func IsValidMergeStyle(name string) bool {
+	switch MergeStyle(name) {
+	case MERGE_STYLE_MERGE, MERGE_STYLE_SQUASH:
+		return true
+	}
+	return false
+}
+
 // PullRequest represents relation between pull request and repositories.
 type PullRequest struct {
 	ID     int64 `xorm:"pk autoincr"`
@@ -56,9 +72,12 @@ type PullRequest struct {
 
 	HasMerged      bool
 	MergedCommitID string `xorm:"VARCHAR(40)"`
-	Merged         time.Time
-	MergerID       int64
-	Merger         *User `xorm:"-"`
+	// MergeCommitID is the commit created on base branch by merging,
+	// while MergedCommitID is the head commit that has been merged.
+	MergeCommitID string `xorm:"VARCHAR(40)"`
+	Merged        time.Time
+	MergerID      int64
+	Merger        *User `xorm:"-"`
 }
 
 // Note: don't try to get Pull because will end up recursive querying.
@@ -112,6 +131,61 @@ func (pr *PullRequest) GetMerger() (err error) {
 	return nil
 }
 
+// PrepareWebhooks creates webhook tasks of pull request event with given action,
+// merge commit ID is only used by merged action.
+func (pr *PullRequest) PrepareWebhooks(doer *User, action HookPullRequestAction, mergeCommitID string) (err error) {
+	if pr.Issue == nil {
+		if pr.Issue, err = GetIssueByID(pr.IssueID); err != nil {
+			return fmt.Errorf("GetIssueByID: %v", err)
+		}
+	}
+	if err = pr.Issue.GetPoster(); err != nil {
+		return fmt.Errorf("GetPoster: %v", err)
+	} else if err = pr.GetBaseRepo(); err != nil {
+		return err
+	} else if err = pr.BaseRepo.GetOwner(); err != nil {
+		return fmt.Errorf("GetOwner: %v", err)
+	} else if err = pr.GetHeadRepo(); err != nil {
+		return err
+	}
+
+	headRepo := pr.HeadUserName
+	if pr.HeadRepo != nil {
+		headRepo += "/" + pr.HeadRepo.Name
+	}
+	state := "open"
+	if pr.Issue.IsClosed {
+		state = "closed"
+	}
+
+	p := &PullRequestPayload{
+		Action: action,
+		Index:  pr.Index,
+		PullRequest: &PayloadPullRequest{
+			ID:            pr.Issue.ID,
+			Index:         pr.Index,
+			Title:         pr.Issue.Name,
+			Body:          pr.Issue.Content,
+			URL:           fmt.Sprintf("%s%s/%s/pulls/%d", setting.AppUrl, pr.BaseRepo.Owner.Name, pr.BaseRepo.Name, pr.Index),
+			State:         state,
+			Poster:        composePayloadUser(pr.Issue.Poster),
+			HeadRepo:      headRepo,
+			HeadBranch:    pr.HeadBranch,
+			BaseBranch:    pr.BaseBranch,
+			Merged:        pr.HasMerged,
+			MergeCommitID: mergeCommitID,
+		},
+		Repo:   composePayloadRepo(pr.BaseRepo),
+		Sender: composePayloadUser(doer),
+	}
+	if err = PrepareWebhooks(pr.BaseRepo, HOOK_EVENT_PULL_REQUEST, p); err != nil {
+		return fmt.Errorf("PrepareWebhooks: %v", err)
+	}
+
+	go HookQueue.Add(pr.BaseRepo.ID)
+	return nil
+}
+
 // IsChecking returns true if this pull request is still checking conflict.
 func (pr *PullRequest) IsChecking() bool {
 	return pr.Status == PULL_REQUEST_STATUS_CHECKING
@@ -122,41 +196,49 @@ func (pr *PullRequest) CanAutoMerge() bool {
 	return pr.Status == PULL_REQUEST_STATUS_MERGEABLE
 }
 
-// Merge merges pull request to base repository.
-func (pr *PullRequest) Merge(doer *User, baseGitRepo *git.Repository) (err error) {
+// DefaultMergeMessage returns commit message used when merging without a custom one,
+// head repository must be loaded.
+func (pr *PullRequest) DefaultMergeMessage() string {
+	return fmt.Sprintf("Merge branch '%s' of %s/%s into %s", pr.HeadBranch, pr.HeadUserName, pr.HeadRepo.Name, pr.BaseBranch)
+}
+
+// Merge merges pull request to base repository with given style,
+// and returns ID of the commit created on base branch.
+// Default message is used when given commit message is empty.
+func (pr *PullRequest) Merge(doer *User, baseGitRepo *git.Repository, style MergeStyle, message string) (_ string, err error) {
 	sess := x.NewSession()
 	defer sessionRelease(sess)
 	if err = sess.Begin(); err != nil {
-		return err
+		return "", err
 	}
 
 	if err = pr.Issue.changeStatus(sess, doer, true); err != nil {
-		return fmt.Errorf("Issue.changeStatus: %v", err)
+		return "", fmt.Errorf("Issue.changeStatus: %v", err)
 	}
 
 	if err = pr.getHeadRepo(sess); err != nil {
-		return fmt.Errorf("getHeadRepo: %v", err)
+		return "", fmt.Errorf("getHeadRepo: %v", err)
 	}
 
 	headRepoPath := RepoPath(pr.HeadUserName, pr.HeadRepo.Name)
 	headGitRepo, err := git.OpenRepository(headRepoPath)
 	if err != nil {
-		return fmt.Errorf("OpenRepository: %v", err)
+		return "", fmt.Errorf("OpenRepository: %v", err)
 	}
 	pr.MergedCommitID, err = headGitRepo.GetCommitIdOfBranch(pr.HeadBranch)
 	if err != nil {
-		return fmt.Errorf("GetCommitIdOfBranch: %v", err)
+		return "", fmt.Errorf("GetCommitIdOfBranch: %v", err)
 	}
 
 	if err = mergePullRequestAction(sess, doer, pr.Issue.Repo, pr.Issue); err != nil {
-		return fmt.Errorf("mergePullRequestAction: %v", err)
+		return "", fmt.Errorf("mergePullRequestAction: %v", err)
 	}
 
 	pr.HasMerged = true
 	pr.Merged = time.Now()
 	pr.MergerID = doer.Id
 	if _, err = sess.Id(pr.ID).AllCols().Update(pr); err != nil {
-		return fmt.Errorf("update pull request: %v", err)
+		return "", fmt.Errorf("update pull request: %v", err)
 	}
 
 	// Clone base repo.
@@ -165,55 +247,80 @@ func (pr *PullRequest) Merge(doer *User, baseGitRepo *git.Repository) (err error
 	defer os.RemoveAll(path.Dir(tmpBasePath))
 
 	var stderr string
-	if _, stderr, err = process.ExecTimeout(5*time.Minute,
+	if _, stderr, err = git.ExecTimeout(5*time.Minute,
 		fmt.Sprintf("PullRequest.Merge (git clone): %s", tmpBasePath),
-		"git", "clone", baseGitRepo.Path, tmpBasePath); err != nil {
-		return fmt.Errorf("git clone: %s", stderr)
+		"clone", baseGitRepo.Path, tmpBasePath); err != nil {
+		return "", fmt.Errorf("git clone: %s", stderr)
 	}
 
 	// Check out base branch.
-	if _, stderr, err = process.ExecDir(-1, tmpBasePath,
+	if _, stderr, err = git.ExecDir(-1, tmpBasePath,
 		fmt.Sprintf("PullRequest.Merge (git checkout): %s", tmpBasePath),
-		"git", "checkout", pr.BaseBranch); err != nil {
-		return fmt.Errorf("git checkout: %s", stderr)
+		"checkout", pr.BaseBranch); err != nil {
+		return "", fmt.Errorf("git checkout: %s", stderr)
 	}
 
 	// Add head repo remote.
-	if _, stderr, err = process.ExecDir(-1, tmpBasePath,
+	if _, stderr, err = git.ExecDir(-1, tmpBasePath,
 		fmt.Sprintf("PullRequest.Merge (git remote add): %s", tmpBasePath),
-		"git", "remote", "add", "head_repo", headRepoPath); err != nil {
-		return fmt.Errorf("git remote add [%s -> %s]: %s", headRepoPath, tmpBasePath, stderr)
+		"remote", "add", "head_repo", headRepoPath); err != nil {
+		return "", fmt.Errorf("git remote add [%s -> %s]: %s", headRepoPath, tmpBasePath, stderr)
 	}
 
 	// Merge commits.
-	if _, stderr, err = process.ExecDir(-1, tmpBasePath,
+	if _, stderr, err = git.ExecDir(-1, tmpBasePath,
 		fmt.Sprintf("PullRequest.Merge (git fetch): %s", tmpBasePath),
-		"git", "fetch", "head_repo"); err != nil {
-		return fmt.Errorf("git fetch [%s -> %s]: %s", headRepoPath, tmpBasePath, stderr)
+		"fetch", "head_repo"); err != nil {
+		return "", fmt.Errorf("git fetch [%s -> %s]: %s", headRepoPath, tmpBasePath, stderr)
 	}
 
-	if _, stderr, err = process.ExecDir(-1, tmpBasePath,
-		fmt.Sprintf("PullRequest.Merge (git merge --no-ff --no-commit): %s", tmpBasePath),
-		"git", "merge", "--no-ff", "--no-commit", "head_repo/"+pr.HeadBranch); err != nil {
-		return fmt.Errorf("git merge --no-ff --no-commit [%s]: %v - %s", tmpBasePath, err, stderr)
+	mergeArgs := []string{"merge", "--no-ff", "--no-commit", "head_repo/" + pr.HeadBranch}
+	if style == MERGE_STYLE_SQUASH {
+		mergeArgs = []string{"merge", "--squash", "head_repo/" + pr.HeadBranch}
+	}
+	if _, stderr, err = git.ExecDir(-1, tmpBasePath,
+		fmt.Sprintf("PullRequest.Merge (git %s): %s", strings.Join(mergeArgs[:2], " "), tmpBasePath),
+		mergeArgs...); err != nil {
+		return "", fmt.Errorf("git %s [%s]: %v - %s", strings.Join(mergeArgs[:2], " "), tmpBasePath, err, stderr)
 	}
 
+	if len(message) == 0 {
+		message = pr.DefaultMergeMessage()
+	}
 	sig := doer.NewGitSig()
-	if _, stderr, err = process.ExecDir(-1, tmpBasePath,
-		fmt.Sprintf("PullRequest.Merge (git merge): %s", tmpBasePath),
-		"git", "commit", fmt.Sprintf("--author='%s <%s>'", sig.Name, sig.Email),
-		"-m", fmt.Sprintf("Merge branch '%s' of %s/%s into %s", pr.HeadBranch, pr.HeadUserName, pr.HeadRepo.Name, pr.BaseBranch)); err != nil {
-		return fmt.Errorf("git commit [%s]: %v - %s", tmpBasePath, err, stderr)
+	if _, stderr, err = git.ExecDir(-1, tmpBasePath,
+		fmt.Sprintf("PullRequest.Merge (git commit): %s", tmpBasePath),
+		"commit", fmt.Sprintf("--author='%s <%s>'", sig.Name, sig.Email),
+		"-m", message); err != nil {
+		return "", fmt.Errorf("git commit [%s]: %v - %s", tmpBasePath, err, stderr)
+	}
+
+	stdout, stderr, err := git.ExecDir(-1, tmpBasePath,
+		fmt.Sprintf("PullRequest.Merge (git rev-parse): %s", tmpBasePath),
+		"rev-parse", "HEAD")
+	if err != nil {
+		return "", fmt.Errorf("git rev-parse [%s]: %v - %s", tmpBasePath, err, stderr)
+	}
+	pr.MergeCommitID = strings.TrimSpace(stdout)
+	if _, err = sess.Id(pr.ID).Cols("merge_commit_id").Update(pr); err != nil {
+		return "", fmt.Errorf("update merge commit: %v", err)
 	}
 
 	// Push back to upstream.
-	if _, stderr, err = process.ExecDir(-1, tmpBasePath,
+	if _, stderr, err = git.ExecDir(-1, tmpBasePath,
 		fmt.Sprintf("PullRequest.Merge (git push): %s", tmpBasePath),
-		"git", "push", baseGitRepo.Path, pr.BaseBranch); err != nil {
-		return fmt.Errorf("git push: %s", stderr)
+		"push", baseGitRepo.Path, pr.BaseBranch); err != nil {
+		return "", fmt.Errorf("git push: %s", stderr)
+	}
+
+	if err = sess.Commit(); err != nil {
+		return "", err
 	}
 
-	return sess.Commit()
+	if err = pr.PrepareWebhooks(doer, HOOK_ACTION_MERGED, pr.MergeCommitID); err != nil {
+		log.Error(4, "PrepareWebhooks: %v", err)
+	}
+	return pr.MergeCommitID, nil
 }
 
 // patchConflicts is a list of conflit description from Git.
@@ -251,17 +358,17 @@ func (pr *PullRequest) testPatch() (err error) {
 	}
 
 	// Checkout base branch.
-	_, stderr, err := process.ExecDir(-1, pr.BaseRepo.LocalCopyPath(),
+	_, stderr, err := git.ExecDir(-1, pr.BaseRepo.LocalCopyPath(),
 		fmt.Sprintf("PullRequest.Merge(git checkout): %v", pr.BaseRepo.ID),
-		"git", "checkout", pr.BaseBranch)
+		"checkout", pr.BaseBranch)
 	if err != nil {
 		return fmt.Errorf("git checkout: %s", stderr)
 	}
 
 	pr.Status = PULL_REQUEST_STATUS_CHECKING
-	_, stderr, err = process.ExecDir(-1, pr.BaseRepo.LocalCopyPath(),
+	_, stderr, err = git.ExecDir(-1, pr.BaseRepo.LocalCopyPath(),
 		fmt.Sprintf("testPatch(git apply --check): %d", pr.BaseRepo.ID),
-		"git", "apply", "--check", patchPath)
+		"apply", "--check", patchPath)
 	if err != nil {
 		for i := range patchConflicts {
 			if strings.Contains(stderr, patchConflicts[i]) {
@@ -321,9 +428,19 @@ func NewPullRequest(repo *Repository, pull *Issue, labelIDs []int64, uuids []str
 	pr.IssueID = pull.ID
 	if _, err = sess.Insert(pr); err != nil {
 		return fmt.Errorf("insert pull repo: %v", err)
+	} else if err = requestDefaultReviews(sess, repo, pr, pull.PosterID); err != nil {
+		return fmt.Errorf("requestDefaultReviews: %v", err)
+	}
+
+	if err = sess.Commit(); err != nil {
+		return err
 	}
 
-	return sess.Commit()
+	pr.Issue = pull
+	if err = pr.PrepareWebhooks(pull.Poster, HOOK_ACTION_OPENED, ""); err != nil {
+		log.Error(4, "PrepareWebhooks: %v", err)
+	}
+	return nil
 }
 
 // GetUnmergedPullRequest returnss a pull request that is open and has not been merged
@@ -460,6 +577,9 @@ func (pr *PullRequest) AddToTaskQueue() {
 
 func addHeadRepoTasks(prs []*PullRequest) {
 	for _, pr := range prs {
+		// Deliver synchronized webhooks that could be created by another process.
+		go HookQueue.Add(pr.BaseRepoID)
+
 		log.Trace("addHeadRepoTasks[%d]: composing new test task", pr.ID)
 		if err := pr.UpdatePatch(); err != nil {
 			log.Error(4, "UpdatePatch: %v", err)
@@ -470,6 +590,22 @@ func addHeadRepoTasks(prs []*PullRequest) {
 	}
 }
 
+// PrepareSynchronizedWebhooks creates webhook tasks for open pull requests
+// whose head branch has received new commits pushed by doer.
+func PrepareSynchronizedWebhooks(doer *User, repoID int64, branch string) error {
+	prs, err := GetUnmergedPullRequestsByHeadInfo(repoID, branch)
+	if err != nil {
+		return fmt.Errorf("GetUnmergedPullRequestsByHeadInfo: %v", err)
+	}
+
+	for _, pr := range prs {
+		if err = pr.PrepareWebhooks(doer, HOOK_ACTION_SYNCHRONIZED, ""); err != nil {
+			return fmt.Errorf("PrepareWebhooks[%d]: %v", pr.ID, err)
+		}
+	}
+	return nil
+}
+
 // AddTestPullRequestTask adds new test tasks by given head/base repository and head/base branch,
 // and generate new patch for testing as needed.
 func AddTestPullRequestTask(repoID int64, branch string) {
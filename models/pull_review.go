@@ -0,0 +1,344 @@
+// Copyright 2015 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gogits/gogs/modules/base"
+	"github.com/gogits/gogs/modules/git"
+)
+
+// ReviewRequest represents a request for a user or a team to review a pull request,
+// exactly one of ReviewerID and TeamID is set.
+type ReviewRequest struct {
+	ID         int64     `xorm:"pk autoincr"`
+	PullID     int64     `xorm:"INDEX UNIQUE(s)"`
+	ReviewerID int64     `xorm:"UNIQUE(s)"`
+	TeamID     int64     `xorm:"UNIQUE(s)"`
+	Created    time.Time `xorm:"CREATED"`
+}
+
+// GetReviewRequests returns all review requests of given pull request.
+func GetReviewRequests(pullID int64) ([]*ReviewRequest, error) {
+	requests := make([]*ReviewRequest, 0, 5)
+	return requests, x.Where("pull_id=?", pullID).Asc("id").Find(&requests)
+}
+
+// splitIDs parses comma-separated IDs and ignores invalid ones.
+func splitIDs(str string) []int64 {
+	ids := make([]int64, 0, 5)
+	for _, id := range base.StringsToInt64s(strings.Split(str, ",")) {
+		if id > 0 {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func joinIDs(ids []int64) string {
+	return strings.Join(base.Int64sToStrings(ids), ",")
+}
+
+// GetDefaultReviewers returns users whose reviews are requested for new pull requests.
+// Users that no longer exist are skipped.
+func (repo *Repository) GetDefaultReviewers() ([]*User, error) {
+	users := make([]*User, 0, 5)
+	for _, id := range splitIDs(repo.DefaultReviewerIDs) {
+		u, err := GetUserByID(id)
+		if err != nil {
+			if IsErrUserNotExist(err) {
+				continue
+			}
+			return nil, fmt.Errorf("GetUserByID[%d]: %v", id, err)
+		}
+		users = append(users, u)
+	}
+	return users, nil
+}
+
+// GetDefaultReviewTeams returns teams whose reviews are requested for new pull requests.
+// Teams that no longer exist are skipped.
+func (repo *Repository) GetDefaultReviewTeams() ([]*Team, error) {
+	teams := make([]*Team, 0, 5)
+	for _, id := range splitIDs(repo.DefaultReviewTeamIDs) {
+		t, err := GetTeamById(id)
+		if err != nil {
+			if err == ErrTeamNotExist {
+				continue
+			}
+			return nil, fmt.Errorf("GetTeamById[%d]: %v", id, err)
+		}
+		teams = append(teams, t)
+	}
+	return teams, nil
+}
+
+// UpdateDefaultReviewers sets users and teams whose reviews are requested for new pull requests.
+// Every user must be able to read repository, and every team must have access to it.
+func (repo *Repository) UpdateDefaultReviewers(users []*User, teams []*Team) error {
+	userIDs := make([]int64, 0, len(users))
+	for _, u := range users {
+		if u.IsOrganization() {
+			return ErrInvalidReviewer{u.Name}
+		} else if has, err := HasAccess(u, repo, ACCESS_MODE_READ); err != nil {
+			return fmt.Errorf("HasAccess: %v", err)
+		} else if !has {
+			return ErrInvalidReviewer{u.Name}
+		}
+		if !base.Int64sToMap(userIDs)[u.Id] {
+			userIDs = append(userIDs, u.Id)
+		}
+	}
+
+	teamIDs := make([]int64, 0, len(teams))
+	for _, t := range teams {
+		if t.OrgID != repo.OwnerID || (!t.IsOwnerTeam() && !t.HasRepository(repo.ID)) {
+			return ErrInvalidReviewer{t.Name}
+		}
+		if !base.Int64sToMap(teamIDs)[t.ID] {
+			teamIDs = append(teamIDs, t.ID)
+		}
+	}
+
+	repo.DefaultReviewerIDs = joinIDs(userIDs)
+	repo.DefaultReviewTeamIDs = joinIDs(teamIDs)
+	_, err := x.Id(repo.ID).Cols("default_reviewer_ids", "default_review_team_ids").Update(repo)
+	return err
+}
+
+// requestDefaultReviews creates review requests of default reviewers of repository
+// for given pull request, poster of pull request is never requested.
+func requestDefaultReviews(e Engine, repo *Repository, pr *PullRequest, posterID int64) error {
+	for _, id := range splitIDs(repo.DefaultReviewerIDs) {
+		if id == posterID {
+			continue
+		}
+		if _, err := e.Insert(&ReviewRequest{PullID: pr.ID, ReviewerID: id}); err != nil {
+			return err
+		}
+	}
+	for _, id := range splitIDs(repo.DefaultReviewTeamIDs) {
+		if _, err := e.Insert(&ReviewRequest{PullID: pr.ID, TeamID: id}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReviewState represents the conclusion of a review.
+type ReviewState int
+
+const (
+	REVIEW_STATE_COMMENT ReviewState = iota + 1
+	REVIEW_STATE_APPROVED
+	REVIEW_STATE_CHANGES_REQUESTED
+)
+
+// Review represents the latest review of a user on a pull request.
+type Review struct {
+	ID         int64       `xorm:"pk autoincr"`
+	PullID     int64       `xorm:"INDEX UNIQUE(s)"`
+	ReviewerID int64       `xorm:"UNIQUE(s)"`
+	Reviewer   *User       `xorm:"-"`
+	State      ReviewState `xorm:"NOT NULL DEFAULT 1"`
+	Content    string      `xorm:"TEXT"`
+	// CommitID is the head commit reviewed by approval or change request,
+	// approval no longer counts once new commits are pushed.
+	CommitID string    `xorm:"VARCHAR(40)"`
+	Created  time.Time `xorm:"CREATED"`
+	Updated  time.Time `xorm:"UPDATED"`
+}
+
+// IsDismissed returns true if review is an approval of an outdated head commit.
+func (r *Review) IsDismissed(headCommitID string) bool {
+	return r.State == REVIEW_STATE_APPROVED && r.CommitID != headCommitID
+}
+
+// loadReviewers loads reviewers of all given reviews by one query.
+func loadReviewers(reviews []*Review) error {
+	if len(reviews) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, 0, len(reviews))
+	for _, r := range reviews {
+		ids = append(ids, r.ReviewerID)
+	}
+	users := make([]*User, 0, len(ids))
+	if err := x.In("id", ids).Find(&users); err != nil {
+		return fmt.Errorf("find reviewers: %v", err)
+	}
+
+	userMap := make(map[int64]*User, len(users))
+	for _, u := range users {
+		userMap[u.Id] = u
+	}
+	for _, r := range reviews {
+		if r.Reviewer = userMap[r.ReviewerID]; r.Reviewer == nil {
+			r.Reviewer = NewFakeUser()
+		}
+	}
+	return nil
+}
+
+// GetReviews returns latest reviews of all reviewers of given pull request.
+func GetReviews(pullID int64) ([]*Review, error) {
+	reviews := make([]*Review, 0, 5)
+	if err := x.Where("pull_id=?", pullID).Asc("id").Find(&reviews); err != nil {
+		return nil, err
+	}
+	return reviews, loadReviewers(reviews)
+}
+
+// HeadCommitID returns latest commit ID of head branch of pull request,
+// or an empty string if head repository or branch no longer exists.
+func (pr *PullRequest) HeadCommitID() (string, error) {
+	if err := pr.GetHeadRepo(); err != nil {
+		return "", err
+	} else if pr.HeadRepo == nil {
+		return "", nil
+	}
+
+	gitRepo, err := git.OpenRepository(pr.HeadRepo.RepoPath())
+	if err != nil {
+		return "", fmt.Errorf("OpenRepository: %v", err)
+	}
+	if !gitRepo.IsBranchExist(pr.HeadBranch) {
+		return "", nil
+	}
+	return gitRepo.GetCommitIdOfBranch(pr.HeadBranch)
+}
+
+// SubmitReview records review of user on pull request, it replaces previous review
+// of the same user. A comment does not change previous approval or change request.
+func SubmitReview(pr *PullRequest, reviewer *User, state ReviewState, content string) (*Review, error) {
+	headCommitID, err := pr.HeadCommitID()
+	if err != nil {
+		return nil, fmt.Errorf("HeadCommitID: %v", err)
+	}
+
+	r := &Review{
+		PullID:     pr.ID,
+		ReviewerID: reviewer.Id,
+	}
+	has, err := x.Get(r)
+	if err != nil {
+		return nil, err
+	}
+
+	r.Content = content
+	if !has {
+		r.State = state
+		r.CommitID = headCommitID
+		_, err = x.Insert(r)
+		return r, err
+	}
+
+	if state != REVIEW_STATE_COMMENT {
+		r.State = state
+		r.CommitID = headCommitID
+	}
+	_, err = x.Id(r.ID).Cols("state", "content", "commit_id").Update(r)
+	return r, err
+}
+
+// checkReviews returns an error if given reviews do not allow pull request to be merged,
+// access of reviewers is checked through cache shared by pull requests of same repository.
+func checkReviews(repo *Repository, reviews []*Review, headCommitID string, hasWrite map[int64]bool) error {
+	approvals := 0
+	for _, r := range reviews {
+		if r.State == REVIEW_STATE_COMMENT || r.IsDismissed(headCommitID) {
+			continue
+		}
+
+		has, ok := hasWrite[r.ReviewerID]
+		if !ok {
+			var err error
+			if has, err = HasAccess(r.Reviewer, repo, ACCESS_MODE_WRITE); err != nil {
+				return fmt.Errorf("HasAccess: %v", err)
+			}
+			hasWrite[r.ReviewerID] = has
+		}
+		if !has {
+			continue
+		}
+
+		if r.State == REVIEW_STATE_CHANGES_REQUESTED {
+			return ErrPullRequestChangesRequested{r.Reviewer.Name}
+		}
+		approvals++
+	}
+
+	if approvals < repo.RequiredApprovals {
+		return ErrPullRequestNotApproved{repo.RequiredApprovals, approvals}
+	}
+	return nil
+}
+
+// CheckReviews returns an error if reviews do not allow pull request to be merged,
+// only reviews of users who have write access to base repository are counted,
+// and approvals only count for the current head commit.
+// It returns ErrPullRequestChangesRequested if any of them requested changes,
+// or ErrPullRequestNotApproved if approvals are fewer than required by repository.
+func (pr *PullRequest) CheckReviews() error {
+	if err := pr.GetBaseRepo(); err != nil {
+		return err
+	}
+
+	reviews, err := GetReviews(pr.ID)
+	if err != nil {
+		return fmt.Errorf("GetReviews: %v", err)
+	}
+	headCommitID, err := pr.HeadCommitID()
+	if err != nil {
+		return fmt.Errorf("HeadCommitID: %v", err)
+	}
+	return checkReviews(pr.BaseRepo, reviews, headCommitID, make(map[int64]bool))
+}
+
+// CheckReviewsOfPullRequests works like CheckReviews for pull requests of given base repository,
+// but loads reviews of all of them at once. It returns results mapped by pull request ID,
+// errors other than ErrPullRequestChangesRequested and ErrPullRequestNotApproved are returned directly.
+func CheckReviewsOfPullRequests(repo *Repository, prs []*PullRequest) (map[int64]error, error) {
+	results := make(map[int64]error, len(prs))
+	if len(prs) == 0 {
+		return results, nil
+	}
+
+	ids := make([]int64, len(prs))
+	for i := range prs {
+		ids[i] = prs[i].ID
+	}
+	reviews := make([]*Review, 0, len(prs))
+	if err := x.In("pull_id", ids).Asc("id").Find(&reviews); err != nil {
+		return nil, fmt.Errorf("find reviews: %v", err)
+	} else if err = loadReviewers(reviews); err != nil {
+		return nil, err
+	}
+
+	pullReviews := make(map[int64][]*Review, len(prs))
+	for _, r := range reviews {
+		pullReviews[r.PullID] = append(pullReviews[r.PullID], r)
+	}
+
+	hasWrite := make(map[int64]bool)
+	for _, pr := range prs {
+		pr.BaseRepo = repo
+		headCommitID, err := pr.HeadCommitID()
+		if err != nil {
+			return nil, fmt.Errorf("HeadCommitID[%d]: %v", pr.ID, err)
+		}
+
+		err = checkReviews(repo, pullReviews[pr.ID], headCommitID, hasWrite)
+		if err != nil && !IsErrPullRequestChangesRequested(err) && !IsErrPullRequestNotApproved(err) {
+			return nil, err
+		}
+		results[pr.ID] = err
+	}
+	return results, nil
+}
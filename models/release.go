@@ -13,7 +13,7 @@ import (
 	"github.com/go-xorm/xorm"
 
 	"github.com/gogits/gogs/modules/git"
-	"github.com/gogits/gogs/modules/process"
+	"github.com/gogits/gogs/modules/setting"
 )
 
 // Release represents a release of repository.
@@ -167,6 +167,70 @@ func UpdateRelease(gitRepo *git.Repository, rel *Release) (err error) {
 	return err
 }
 
+// GetAttachments returns files attached to release.
+func (r *Release) GetAttachments() ([]*Attachment, error) {
+	attachments := make([]*Attachment, 0, 5)
+	return attachments, x.Where("release_id=?", r.ID).Asc("id").Find(&attachments)
+}
+
+// PrepareWebhooks adds release webhooks of given action to task queue.
+// Drafts are not visible to others thus never trigger any webhook.
+func (r *Release) PrepareWebhooks(doer *User, action HookReleaseAction) error {
+	if r.IsDraft {
+		return nil
+	}
+
+	repo, err := GetRepositoryByID(r.RepoID)
+	if err != nil {
+		return fmt.Errorf("GetRepositoryByID: %v", err)
+	} else if err = repo.GetOwner(); err != nil {
+		return fmt.Errorf("GetOwner: %v", err)
+	}
+
+	publisher, err := GetUserByID(r.PublisherID)
+	if err != nil {
+		return fmt.Errorf("GetUserByID: %v", err)
+	}
+
+	attachments, err := r.GetAttachments()
+	if err != nil {
+		return fmt.Errorf("GetAttachments: %v", err)
+	}
+	assets := make([]*PayloadReleaseAsset, len(attachments))
+	for i := range attachments {
+		assets[i] = &PayloadReleaseAsset{
+			UUID:        attachments[i].UUID,
+			Name:        attachments[i].Name,
+			DownloadURL: setting.AppUrl + "attachments/" + attachments[i].UUID,
+		}
+	}
+
+	p := &ReleasePayload{
+		Action: action,
+		Release: &PayloadRelease{
+			ID:           r.ID,
+			TagName:      r.TagName,
+			Target:       r.Target,
+			Title:        r.Title,
+			Body:         r.Note,
+			URL:          fmt.Sprintf("%s%s/%s/releases", setting.AppUrl, repo.Owner.Name, repo.Name),
+			IsDraft:      r.IsDraft,
+			IsPrerelease: r.IsPrerelease,
+			Publisher:    composePayloadUser(publisher),
+			Assets:       assets,
+			Created:      r.Created,
+		},
+		Repo:   composePayloadRepo(repo),
+		Sender: composePayloadUser(doer),
+	}
+	if err = PrepareWebhooks(repo, HOOK_EVENT_RELEASE, p); err != nil {
+		return fmt.Errorf("PrepareWebhooks: %v", err)
+	}
+
+	go HookQueue.Add(repo.ID)
+	return nil
+}
+
 // DeleteReleaseByID deletes a release and corresponding Git tag by given ID.
 func DeleteReleaseByID(id int64) error {
 	rel, err := GetReleaseByID(id)
@@ -179,9 +243,9 @@ func DeleteReleaseByID(id int64) error {
 		return fmt.Errorf("GetRepositoryByID: %v", err)
 	}
 
-	_, stderr, err := process.ExecDir(-1, repo.RepoPath(),
+	_, stderr, err := git.ExecDir(-1, repo.RepoPath(),
 		fmt.Sprintf("DeleteReleaseByID (git tag -d): %d", rel.ID),
-		"git", "tag", "-d", rel.TagName)
+		"tag", "-d", rel.TagName)
 	if err != nil && !strings.Contains(stderr, "not found") {
 		return fmt.Errorf("git tag -d: %v - %s", err, stderr)
 	}
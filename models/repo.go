@@ -31,13 +31,14 @@ import (
 
 	"github.com/gogits/gogs/modules/base"
 	"github.com/gogits/gogs/modules/bindata"
+	oldgit "github.com/gogits/gogs/modules/git"
 	"github.com/gogits/gogs/modules/log"
-	"github.com/gogits/gogs/modules/process"
 	"github.com/gogits/gogs/modules/setting"
 )
 
 const (
 	_TPL_UPDATE_HOOK = "#!/usr/bin/env %s\n%s update $1 $2 $3 --config='%s'\n"
+	_TPL_CUSTOM_HOOK = "#!/usr/bin/env %s\n%s hook --config='%s' %s \"$@\"\n"
 )
 
 var (
@@ -92,27 +93,28 @@ func NewRepoContext() {
 	zip.Verbose = false
 
 	// Check Git installation.
-	if _, err := exec.LookPath("git"); err != nil {
+	gitPath, err := exec.LookPath(oldgit.BinPath())
+	if err != nil {
 		log.Fatal(4, "Fail to test 'git' command: %v (forgotten install?)", err)
 	}
 
 	// Check Git version.
-	gitVer, err := git.Version()
+	gitVer, err := oldgit.GetVersion()
 	if err != nil {
 		log.Fatal(4, "Fail to get Git version: %v", err)
 	}
 
-	log.Info("Git Version: %s", gitVer)
-	if version.Compare("1.7.1", gitVer, ">") {
+	log.Info("Git Version: %s (%s)", gitVer, gitPath)
+	if version.Compare("1.7.1", gitVer.String(), ">") {
 		log.Fatal(4, "Gogs requires Git version greater or equal to 1.7.1")
 	}
 
 	// Git requires setting user.name and user.email in order to commit changes.
 	for configKey, defaultValue := range map[string]string{"user.name": "Gogs", "user.email": "gogs@fake.local"} {
-		if stdout, stderr, err := process.Exec("NewRepoContext(get setting)", "git", "config", "--get", configKey); err != nil || strings.TrimSpace(stdout) == "" {
+		if stdout, stderr, err := oldgit.Exec("NewRepoContext(get setting)", "config", "--get", configKey); err != nil || strings.TrimSpace(stdout) == "" {
 			// ExitError indicates this config is not set
 			if _, ok := err.(*exec.ExitError); ok || strings.TrimSpace(stdout) == "" {
-				if _, stderr, gerr := process.Exec("NewRepoContext(set "+configKey+")", "git", "config", "--global", configKey, defaultValue); gerr != nil {
+				if _, stderr, gerr := oldgit.Exec("NewRepoContext(set "+configKey+")", "config", "--global", configKey, defaultValue); gerr != nil {
 					log.Fatal(4, "Fail to set git %s(%s): %s", configKey, gerr, stderr)
 				}
 				log.Info("Git config %s set to %s", configKey, defaultValue)
@@ -123,8 +125,8 @@ func NewRepoContext() {
 	}
 
 	// Set git some configurations.
-	if _, stderr, err := process.Exec("NewRepoContext(git config --global core.quotepath false)",
-		"git", "config", "--global", "core.quotepath", "false"); err != nil {
+	if _, stderr, err := oldgit.Exec("NewRepoContext(git config --global core.quotepath false)",
+		"config", "--global", "core.quotepath", "false"); err != nil {
 		log.Fatal(4, "Fail to execute 'git config --global core.quotepath false': %s", stderr)
 	}
 
@@ -142,7 +144,7 @@ type Repository struct {
 	DefaultBranch string
 
 	NumWatches          int
-	NumStars            int
+	NumStars            int `xorm:"INDEX"`
 	NumForks            int
 	NumIssues           int
 	NumClosedIssues     int
@@ -165,8 +167,48 @@ type Repository struct {
 	ForkID   int64
 	BaseRepo *Repository `xorm:"-"`
 
-	Created time.Time `xorm:"CREATED"`
-	Updated time.Time `xorm:"UPDATED"`
+	// IsTemplate indicates the repository can be used to generate new repositories.
+	IsTemplate bool `xorm:"NOT NULL DEFAULT false"`
+	// TemplateID is ID of template repository this repository is generated from.
+	TemplateID int64
+
+	// IsArchived indicates the repository is read-only:
+	// it can still be browsed and cloned but does not accept any changes.
+	IsArchived bool `xorm:"NOT NULL DEFAULT false"`
+
+	// Units of repository, a disabled unit is hidden and its pages are not accessible.
+	EnableIssues   bool `xorm:"NOT NULL DEFAULT true"`
+	EnablePulls    bool `xorm:"NOT NULL DEFAULT true"`
+	EnableWiki     bool `xorm:"NOT NULL DEFAULT true"`
+	EnableReleases bool `xorm:"NOT NULL DEFAULT true"`
+
+	// Size is disk usage of repository in bytes.
+	Size int64 `xorm:"INDEX NOT NULL DEFAULT 0"`
+
+	// Result of last garbage collection, sizes are in bytes.
+	LastGcTime   time.Time
+	SizeBeforeGc int64
+	SizeAfterGc  int64
+
+	// Users and teams whose reviews are requested for new pull requests, as comma-separated IDs.
+	DefaultReviewerIDs   string `xorm:"TEXT"`
+	DefaultReviewTeamIDs string `xorm:"TEXT"`
+	// Auto assignment of new issues and pull requests, users and teams are comma-separated IDs
+	// of candidates in round-robin mode, and cursor counts assignments made in turn.
+	AutoAssignMode    AutoAssignMode `xorm:"NOT NULL DEFAULT 0"`
+	AutoAssigneeIDs   string         `xorm:"TEXT"`
+	AutoAssignTeamIDs string         `xorm:"TEXT"`
+	AutoAssignCursor  int64          `xorm:"NOT NULL DEFAULT 0"`
+	// RequiredApprovals is the number of approving reviews required to merge a pull request.
+	RequiredApprovals int `xorm:"NOT NULL DEFAULT 0"`
+	// SignedCommitMode is which new commits of a push must be signed by registered GPG keys.
+	SignedCommitMode SignedCommitMode `xorm:"NOT NULL DEFAULT 0"`
+	// MaxPushSize is maximum size of a single push over HTTP in MB,
+	// 0 means to use site default and -1 means unlimited.
+	MaxPushSize int64 `xorm:"NOT NULL DEFAULT 0"`
+
+	Created time.Time `xorm:"INDEX CREATED"`
+	Updated time.Time `xorm:"INDEX UPDATED"`
 }
 
 func (repo *Repository) AfterSet(colName string, _ xorm.Cell) {
@@ -278,6 +320,19 @@ func (repo *Repository) RepoPath() string {
 	return repo.repoPath(x)
 }
 
+// MaxPushBodySize returns maximum size of request body of a single push
+// over HTTP in bytes, 0 means unlimited.
+func (repo *Repository) MaxPushBodySize() int64 {
+	size := repo.MaxPushSize
+	if size == 0 {
+		size = setting.Git.MaxPushSize
+	}
+	if size < 0 {
+		return 0
+	}
+	return size * 1024 * 1024
+}
+
 func (repo *Repository) RepoLink() string {
 	return setting.AppSubUrl + "/" + repo.MustOwner().Name + "/" + repo.Name
 }
@@ -384,14 +439,20 @@ func (repo *Repository) cloneLink(isWiki bool) *CloneLink {
 		repoName += ".wiki"
 	}
 
+	// IPv6 address must be enclosed in brackets.
+	sshDomain := setting.SSHDomain
+	if strings.Contains(sshDomain, ":") && !strings.HasPrefix(sshDomain, "[") {
+		sshDomain = "[" + sshDomain + "]"
+	}
+
 	repo.Owner = repo.MustOwner()
 	cl := new(CloneLink)
 	if setting.SSHPort != 22 {
-		cl.SSH = fmt.Sprintf("ssh://%s@%s:%d/%s/%s.git", setting.RunUser, setting.SSHDomain, setting.SSHPort, repo.Owner.Name, repoName)
+		cl.SSH = fmt.Sprintf("ssh://%s@%s:%d/%s/%s.git", setting.SSHUser, sshDomain, setting.SSHPort, repo.Owner.Name, repoName)
 	} else {
-		cl.SSH = fmt.Sprintf("%s@%s:%s/%s.git", setting.RunUser, setting.SSHDomain, repo.Owner.Name, repoName)
+		cl.SSH = fmt.Sprintf("%s@%s:%s/%s.git", setting.SSHUser, sshDomain, repo.Owner.Name, repoName)
 	}
-	cl.HTTPS = fmt.Sprintf("%s%s/%s.git", setting.AppUrl, repo.Owner.Name, repoName)
+	cl.HTTPS = fmt.Sprintf("%s%s/%s.git", setting.CloneRootUrl, repo.Owner.Name, repoName)
 	return cl
 }
 
@@ -428,6 +489,45 @@ func IsUsableName(name string) error {
 	return nil
 }
 
+var (
+	// reservedRepoNames contains names that have special meaning in paths
+	// under an owner, in addition to configured ones.
+	reservedRepoNames = []string{".", "..", "settings", "repositories", "followers", "following", "stars", "keys"}
+
+	// validRepoNamePattern requires name to start with an alphanumeric character or underscore,
+	// and to only contain alphanumeric characters, dashes, underscores and dots.
+	validRepoNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_][a-zA-Z0-9_\-\.]*$`)
+)
+
+// IsUsableRepoName checks if name can be used as a repository name.
+// In addition to rules of IsUsableName, it rejects names that conflict with routes
+// or are configured as reserved, and names with problematic characters.
+func IsUsableRepoName(name string) error {
+	if err := IsUsableName(name); err != nil {
+		return err
+	}
+
+	name = strings.TrimSpace(name)
+	if !validRepoNamePattern.MatchString(name) || strings.Contains(name, "..") {
+		return ErrNameCharsNotAllowed{name}
+	}
+
+	name = strings.ToLower(name)
+	for _, reserved := range append(reservedRepoNames, setting.Repository.ReservedNames...) {
+		if name == reserved {
+			return ErrNameReserved{name}
+		}
+	}
+
+	for _, pat := range setting.Repository.ReservedPatterns {
+		if matched, _ := path.Match(pat, name); matched {
+			return ErrNamePatternNotAllowed{pat}
+		}
+	}
+
+	return nil
+}
+
 // Mirror represents a mirror information of repository.
 type Mirror struct {
 	ID         int64 `xorm:"pk autoincr"`
@@ -436,6 +536,8 @@ type Mirror struct {
 	Interval   int         // Hour.
 	Updated    time.Time   `xorm:"UPDATED"`
 	NextUpdate time.Time
+	LastSync   time.Time
+	LastError  string `xorm:"TEXT"`
 }
 
 func (m *Mirror) AfterSet(colName string, _ xorm.Cell) {
@@ -466,7 +568,7 @@ func GetMirror(repoId int64) (*Mirror, error) {
 }
 
 func updateMirror(e Engine, m *Mirror) error {
-	_, err := e.Id(m.ID).Update(m)
+	_, err := e.Id(m.ID).AllCols().Update(m)
 	return err
 }
 
@@ -474,16 +576,81 @@ func UpdateMirror(m *Mirror) error {
 	return updateMirror(x, m)
 }
 
+var urlCredentialsPattern = regexp.MustCompile(`://[^/@\s]+@`)
+
+// sanitizeURLCredentials hides credentials of URLs contained in given string.
+func sanitizeURLCredentials(s string) string {
+	return urlCredentialsPattern.ReplaceAllString(s, "://<credentials>@")
+}
+
+// runSync fetches updates from remote of mirror and records result of synchronization.
+// Failed mirror is retried by next run of mirror update task.
+func (m *Mirror) runSync() {
+	repoPath := m.Repo.RepoPath()
+	m.LastSync = time.Now()
+	if _, stderr, err := oldgit.ExecDir(10*time.Minute,
+		repoPath, fmt.Sprintf("MirrorUpdate: %s", repoPath),
+		"remote", "update", "--prune"); err != nil {
+		m.LastError = sanitizeURLCredentials(stderr)
+		desc := fmt.Sprintf("Fail to update mirror repository(%s): %s", repoPath, m.LastError)
+		log.Error(4, desc)
+		if err = CreateRepositoryNotice(desc); err != nil {
+			log.Error(4, "CreateRepositoryNotice: %v", err)
+		}
+		return
+	}
+
+	m.LastError = ""
+	m.NextUpdate = m.LastSync.Add(time.Duration(m.Interval) * time.Hour)
+	if err := m.Repo.UpdateSize(); err != nil {
+		log.Error(4, "UpdateSize[%d]: %v", m.RepoID, err)
+	}
+}
+
+// ScheduleSync makes mirror to be synchronized by next run of mirror update task,
+// and starts the task in background immediately.
+func (m *Mirror) ScheduleSync() error {
+	m.NextUpdate = time.Now()
+	if err := UpdateMirror(m); err != nil {
+		return err
+	}
+	go MirrorUpdate()
+	return nil
+}
+
 func createUpdateHook(repoPath string) error {
 	return git.SetUpdateHook(repoPath,
 		fmt.Sprintf(_TPL_UPDATE_HOOK, setting.ScriptType, "\""+setting.AppPath+"\"", setting.CustomConf))
 }
 
+// CustomHookWrapper returns content of hook that runs custom hook of given name.
+func CustomHookWrapper(name string) string {
+	return fmt.Sprintf(_TPL_CUSTOM_HOOK, setting.ScriptType, "\""+setting.AppPath+"\"", setting.CustomConf, name)
+}
+
+// rewriteCustomHookWrappers installs wrappers again for all custom hooks of repository,
+// hooks saved directly in hooks directory are moved to custom hooks directory.
+func rewriteCustomHookWrappers(repoPath string) error {
+	hooks, err := oldgit.ListHooks(repoPath)
+	if err != nil {
+		return fmt.Errorf("ListHooks: %v", err)
+	}
+	for _, h := range hooks {
+		if !h.IsActive {
+			continue
+		}
+		if err = h.Update(CustomHookWrapper(h.Name())); err != nil {
+			return fmt.Errorf("update hook '%s': %v", h.Name(), err)
+		}
+	}
+	return nil
+}
+
 // MirrorRepository creates a mirror repository from source.
 func MirrorRepository(repoId int64, userName, repoName, repoPath, url string) error {
-	_, stderr, err := process.ExecTimeout(10*time.Minute,
+	_, stderr, err := oldgit.ExecTimeout(10*time.Minute,
 		fmt.Sprintf("MirrorRepository: %s/%s", userName, repoName),
-		"git", "clone", "--mirror", url, repoPath)
+		"clone", "--mirror", url, repoPath)
 	if err != nil {
 		return errors.New("git clone --mirror: " + stderr)
 	}
@@ -546,9 +713,9 @@ func MigrateRepository(u *User, opts MigrateRepoOptions) (*Repository, error) {
 	}
 
 	// FIXME: this command could for both migrate and mirror
-	_, stderr, err := process.ExecTimeout(10*time.Minute,
+	_, stderr, err := oldgit.ExecTimeout(10*time.Minute,
 		fmt.Sprintf("MigrateRepository: %s", repoPath),
-		"git", "clone", "--mirror", "--bare", "--quiet", opts.RemoteAddr, repoPath)
+		"clone", "--mirror", "--bare", "--quiet", opts.RemoteAddr, repoPath)
 	if err != nil {
 		return repo, fmt.Errorf("git clone --mirror --bare --quiet: %v", stderr)
 	} else if err = createUpdateHook(repoPath); err != nil {
@@ -567,7 +734,9 @@ func MigrateRepository(u *User, opts MigrateRepoOptions) (*Repository, error) {
 	}
 
 	// Check if repository is empty.
-	_, stderr, err = com.ExecCmdDir(repoPath, "git", "log", "-1")
+	_, stderr, err = oldgit.ExecDir(-1, repoPath,
+		fmt.Sprintf("MigrateRepository(git log): %s", repoPath),
+		"log", "-1")
 	if err != nil {
 		if strings.Contains(stderr, "fatal: bad default revision 'HEAD'") {
 			repo.IsBare = true
@@ -595,24 +764,30 @@ func MigrateRepository(u *User, opts MigrateRepoOptions) (*Repository, error) {
 }
 
 // initRepoCommit temporarily changes with work directory.
-func initRepoCommit(tmpPath string, sig *git.Signature) (err error) {
+func initRepoCommit(tmpPath, branch string, sig *git.Signature) (err error) {
 	var stderr string
-	if _, stderr, err = process.ExecDir(-1,
+	if _, stderr, err = oldgit.ExecDir(-1,
+		tmpPath, fmt.Sprintf("initRepoCommit (git checkout): %s", tmpPath),
+		"checkout", "-b", branch); err != nil {
+		return fmt.Errorf("git checkout: %s", stderr)
+	}
+
+	if _, stderr, err = oldgit.ExecDir(-1,
 		tmpPath, fmt.Sprintf("initRepoCommit (git add): %s", tmpPath),
-		"git", "add", "--all"); err != nil {
+		"add", "--all"); err != nil {
 		return fmt.Errorf("git add: %s", stderr)
 	}
 
-	if _, stderr, err = process.ExecDir(-1,
+	if _, stderr, err = oldgit.ExecDir(-1,
 		tmpPath, fmt.Sprintf("initRepoCommit (git commit): %s", tmpPath),
-		"git", "commit", fmt.Sprintf("--author='%s <%s>'", sig.Name, sig.Email),
+		"commit", fmt.Sprintf("--author='%s <%s>'", sig.Name, sig.Email),
 		"-m", "initial commit"); err != nil {
 		return fmt.Errorf("git commit: %s", stderr)
 	}
 
-	if _, stderr, err = process.ExecDir(-1,
+	if _, stderr, err = oldgit.ExecDir(-1,
 		tmpPath, fmt.Sprintf("initRepoCommit (git push): %s", tmpPath),
-		"git", "push", "origin", "master"); err != nil {
+		"push", "origin", branch); err != nil {
 		return fmt.Errorf("git push: %s", stderr)
 	}
 	return nil
@@ -627,6 +802,18 @@ type CreateRepoOptions struct {
 	IsPrivate   bool
 	IsMirror    bool
 	AutoInit    bool
+
+	// DefaultBranch is the name of initial branch,
+	// setting.Repository.DefaultBranch is used when empty.
+	DefaultBranch string
+
+	// Labels are created in new repository.
+	Labels []*Label
+
+	// DisableIssues and DisableWiki turn off units of new repository,
+	// all units are enabled by default.
+	DisableIssues bool
+	DisableWiki   bool
 }
 
 func getRepoInitFile(tp, name string) ([]byte, error) {
@@ -640,10 +827,29 @@ func getRepoInitFile(tp, name string) ([]byte, error) {
 	return bindata.Asset(relPath)
 }
 
+// checkRepoInitFiles validates names of README, .gitignore and license templates.
+// Only known templates are allowed because names are used as file paths.
+func checkRepoInitFiles(opts CreateRepoOptions) error {
+	if !com.IsSliceContainsStr(Readmes, opts.Readme) {
+		return ErrRepoInitFileNotExist{"readme", opts.Readme}
+	}
+	if len(opts.Gitignores) > 0 {
+		for _, name := range strings.Split(opts.Gitignores, ",") {
+			if !com.IsSliceContainsStr(Gitignores, name) {
+				return ErrRepoInitFileNotExist{"gitignore", name}
+			}
+		}
+	}
+	if len(opts.License) > 0 && !com.IsSliceContainsStr(Licenses, opts.License) {
+		return ErrRepoInitFileNotExist{"license", opts.License}
+	}
+	return nil
+}
+
 func prepareRepoCommit(repo *Repository, tmpDir, repoPath string, opts CreateRepoOptions) error {
 	// Clone to temprory path and do the init commit.
-	_, stderr, err := process.Exec(
-		fmt.Sprintf("initRepository(git clone): %s", repoPath), "git", "clone", repoPath, tmpDir)
+	_, stderr, err := oldgit.Exec(
+		fmt.Sprintf("initRepository(git clone): %s", repoPath), "clone", repoPath, tmpDir)
 	if err != nil {
 		return fmt.Errorf("git clone: %v - %s", err, stderr)
 	}
@@ -716,6 +922,14 @@ func initRepository(e Engine, repoPath string, u *User, repo *Repository, opts C
 		return fmt.Errorf("createUpdateHook: %v", err)
 	}
 
+	// Point HEAD to default branch so clones check it out even before first push.
+	var stderr string
+	if _, stderr, err = oldgit.ExecDir(-1,
+		repoPath, fmt.Sprintf("initRepository (git symbolic-ref): %s", repoPath),
+		"symbolic-ref", "HEAD", "refs/heads/"+opts.DefaultBranch); err != nil {
+		return fmt.Errorf("git symbolic-ref: %s", stderr)
+	}
+
 	tmpDir := filepath.Join(os.TempDir(), "gogs-"+repo.Name+"-"+com.ToStr(time.Now().Nanosecond()))
 
 	// Initialize repository according to user's choice.
@@ -728,7 +942,7 @@ func initRepository(e Engine, repoPath string, u *User, repo *Repository, opts C
 		}
 
 		// Apply changes and commit.
-		if err = initRepoCommit(tmpDir, u.NewGitSig()); err != nil {
+		if err = initRepoCommit(tmpDir, opts.DefaultBranch, u.NewGitSig()); err != nil {
 			return fmt.Errorf("initRepoCommit: %v", err)
 		}
 	}
@@ -743,7 +957,7 @@ func initRepository(e Engine, repoPath string, u *User, repo *Repository, opts C
 		repo.IsBare = true
 	}
 
-	repo.DefaultBranch = "master"
+	repo.DefaultBranch = opts.DefaultBranch
 	if err = updateRepository(e, repo, false); err != nil {
 		return fmt.Errorf("updateRepository: %v", err)
 	}
@@ -752,10 +966,17 @@ func initRepository(e Engine, repoPath string, u *User, repo *Repository, opts C
 }
 
 func createRepository(e *xorm.Session, u *User, repo *Repository) (err error) {
-	if err = IsUsableName(repo.Name); err != nil {
+	if err = IsUsableRepoName(repo.Name); err != nil {
 		return err
 	}
 
+	canCreate, err := u.canCreateRepo(e, repo.IsFork)
+	if err != nil {
+		return fmt.Errorf("canCreateRepo: %v", err)
+	} else if !canCreate {
+		return ErrReachLimitOfRepo{u.MaxCreationLimit()}
+	}
+
 	has, err := isRepositoryExist(e, u, repo.Name)
 	if err != nil {
 		return fmt.Errorf("IsRepositoryExist: %v", err)
@@ -763,6 +984,11 @@ func createRepository(e *xorm.Session, u *User, repo *Repository) (err error) {
 		return ErrRepoAlreadyExist{u.Name, repo.Name}
 	}
 
+	// All units are enabled for new repository.
+	repo.EnableIssues = true
+	repo.EnablePulls = true
+	repo.EnableWiki = true
+	repo.EnableReleases = true
 	if _, err = e.Insert(repo); err != nil {
 		return err
 	}
@@ -800,6 +1026,20 @@ func createRepository(e *xorm.Session, u *User, repo *Repository) (err error) {
 
 // CreateRepository creates a repository for given user or organization.
 func CreateRepository(u *User, opts CreateRepoOptions) (_ *Repository, err error) {
+	if !opts.IsMirror {
+		if len(opts.DefaultBranch) == 0 {
+			opts.DefaultBranch = setting.Repository.DefaultBranch
+		}
+		if !git.IsValidBranchName(opts.DefaultBranch) {
+			return nil, ErrInvalidDefaultBranch{opts.DefaultBranch}
+		}
+	}
+	if opts.AutoInit {
+		if err = checkRepoInitFiles(opts); err != nil {
+			return nil, err
+		}
+	}
+
 	repo := &Repository{
 		OwnerID:     u.Id,
 		Owner:       u,
@@ -819,6 +1059,21 @@ func CreateRepository(u *User, opts CreateRepoOptions) (_ *Repository, err error
 		return nil, err
 	}
 
+	if opts.DisableIssues || opts.DisableWiki {
+		repo.EnableIssues = !opts.DisableIssues
+		repo.EnableWiki = !opts.DisableWiki
+		if _, err = sess.Id(repo.ID).Cols("enable_issues", "enable_wiki").Update(repo); err != nil {
+			return nil, fmt.Errorf("update units: %v", err)
+		}
+	}
+
+	for i := range opts.Labels {
+		opts.Labels[i].RepoID = repo.ID
+		if _, err = sess.Insert(opts.Labels[i]); err != nil {
+			return nil, fmt.Errorf("insert label: %v", err)
+		}
+	}
+
 	// No need for init mirror.
 	if !opts.IsMirror {
 		repoPath := RepoPath(u.Name, repo.Name)
@@ -831,9 +1086,9 @@ func CreateRepository(u *User, opts CreateRepoOptions) (_ *Repository, err error
 			return nil, fmt.Errorf("initRepository: %v", err)
 		}
 
-		_, stderr, err := process.ExecDir(-1,
+		_, stderr, err := oldgit.ExecDir(-1,
 			repoPath, fmt.Sprintf("CreateRepository(git update-server-info): %s", repoPath),
-			"git", "update-server-info")
+			"update-server-info")
 		if err != nil {
 			return nil, errors.New("CreateRepository(git update-server-info): " + stderr)
 		}
@@ -996,7 +1251,7 @@ func TransferOwnership(u *User, newOwnerName string, repo *Repository) error {
 func ChangeRepositoryName(u *User, oldRepoName, newRepoName string) (err error) {
 	oldRepoName = strings.ToLower(oldRepoName)
 	newRepoName = strings.ToLower(newRepoName)
-	if err = IsUsableName(newRepoName); err != nil {
+	if err = IsUsableRepoName(newRepoName); err != nil {
 		return err
 	}
 
@@ -1115,6 +1370,13 @@ func DeleteRepository(uid, repoID int64) error {
 		}
 	}
 
+	// Reviews and review requests refer to pull requests, thus they have to be deleted first.
+	for _, table := range []string{"review_request", "review"} {
+		if _, err = sess.Exec("DELETE FROM `"+table+"` WHERE pull_id IN (SELECT id FROM `pull_request` WHERE base_repo_id=?)", repoID); err != nil {
+			return fmt.Errorf("delete %s: %v", table, err)
+		}
+	}
+
 	if err = deleteBeans(sess,
 		&Repository{ID: repoID},
 		&Access{RepoID: repo.ID},
@@ -1123,10 +1385,12 @@ func DeleteRepository(uid, repoID int64) error {
 		&Star{RepoID: repoID},
 		&Mirror{RepoID: repoID},
 		&IssueUser{RepoID: repoID},
+		&Notification{RepoID: repoID},
 		&Milestone{RepoID: repoID},
 		&Release{RepoID: repoID},
 		&Collaboration{RepoID: repoID},
 		&PullRequest{BaseRepoID: repoID},
+		&ProtectedTag{RepoID: repoID},
 	); err != nil {
 		return fmt.Errorf("deleteBeans: %v", err)
 	}
@@ -1295,10 +1559,53 @@ func GetRepositoryCount(u *User) (int64, error) {
 }
 
 type SearchOption struct {
-	Keyword string
-	Uid     int64
-	Limit   int
-	Private bool
+	Keyword  string
+	Uid      int64
+	Limit    int
+	Private  bool
+	Archived bool // Whether to include archived repositories.
+	Sort     string
+	Order    string
+}
+
+// repoSortColumns maps allowed sort keys of repository listing to indexed columns.
+var repoSortColumns = map[string]string{
+	"updated": "updated",
+	"created": "created",
+	"name":    "lower_name",
+	"size":    "size",
+	"stars":   "num_stars",
+}
+
+// RepoSortKeys returns allowed sort keys of repository listing.
+func RepoSortKeys() []string {
+	return []string{"updated", "created", "name", "size", "stars"}
+}
+
+// IsValidRepoSort returns true if given sort key and order are allowed,
+// empty values mean default sort.
+func IsValidRepoSort(sort, order string) bool {
+	if _, ok := repoSortColumns[sort]; !ok && len(sort) > 0 {
+		return false
+	}
+	return len(order) == 0 || order == "asc" || order == "desc"
+}
+
+// repoOrderBy returns ORDER BY clause of given sort key and order,
+// it defaults to most recently updated first.
+func repoOrderBy(sort, order string) string {
+	col, ok := repoSortColumns[sort]
+	if !ok {
+		col = "updated"
+	}
+	if order != "asc" && order != "desc" {
+		// Names are naturally read ascending, others are more useful descending.
+		order = "desc"
+		if col == "lower_name" {
+			order = "asc"
+		}
+	}
+	return col + " " + order + ", id " + order
 }
 
 // SearchRepositoryByName returns given number of repositories whose name contains keyword.
@@ -1318,10 +1625,29 @@ func SearchRepositoryByName(opt SearchOption) (repos []*Repository, err error) {
 	if !opt.Private {
 		sess.And("is_private=?", false)
 	}
-	sess.And("lower_name like ?", "%"+opt.Keyword+"%").Find(&repos)
+	if !opt.Archived {
+		sess.And("is_archived=?", false)
+	}
+	err = sess.And("lower_name like ?", "%"+opt.Keyword+"%").OrderBy(repoOrderBy(opt.Sort, opt.Order)).Find(&repos)
 	return repos, err
 }
 
+// DeleteArchives deletes all cached archives of repository,
+// and returns the number of archive files that were deleted.
+func (repo *Repository) DeleteArchives() (int, error) {
+	archivePath := filepath.Join(repo.RepoPath(), "archives")
+	count := 0
+	if err := filepath.Walk(archivePath, func(path string, fi os.FileInfo, err error) error {
+		if err == nil && !fi.IsDir() {
+			count++
+		}
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+	return count, os.RemoveAll(archivePath)
+}
+
 // DeleteRepositoryArchives deletes all repositories' archives.
 func DeleteRepositoryArchives() error {
 	return x.Where("id > 0").Iterate(new(Repository),
@@ -1368,7 +1694,10 @@ func RewriteRepositoryUpdateHook() error {
 	return x.Where("id > 0").Iterate(new(Repository),
 		func(idx int, bean interface{}) error {
 			repo := bean.(*Repository)
-			return createUpdateHook(repo.RepoPath())
+			if err := createUpdateHook(repo.RepoPath()); err != nil {
+				return err
+			}
+			return rewriteCustomHookWrappers(repo.RepoPath())
 		})
 }
 
@@ -1408,9 +1737,14 @@ var taskStatusPool = &statusPool{
 }
 
 const (
-	_MIRROR_UPDATE = "mirror_update"
-	_GIT_FSCK      = "git_fsck"
-	_CHECK_REPOs   = "check_repos"
+	_MIRROR_UPDATE       = "mirror_update"
+	_GIT_FSCK            = "git_fsck"
+	_GIT_GC_REPOS        = "git_gc_repos"
+	_UPDATE_SIZES        = "update_repo_sizes"
+	_DELETE_USER_EXPORTS = "delete_user_exports"
+	_DELETE_ATTACHMENTS  = "delete_orphaned_attachments"
+	_REBUILD_DERIVED     = "rebuild_derived_data"
+	_CHECK_REPOs         = "check_repos"
 )
 
 // MirrorUpdate checks and updates mirror repositories.
@@ -1435,19 +1769,6 @@ func MirrorUpdate() {
 			return nil
 		}
 
-		repoPath := m.Repo.RepoPath()
-		if _, stderr, err := process.ExecDir(10*time.Minute,
-			repoPath, fmt.Sprintf("MirrorUpdate: %s", repoPath),
-			"git", "remote", "update", "--prune"); err != nil {
-			desc := fmt.Sprintf("Fail to update mirror repository(%s): %s", repoPath, stderr)
-			log.Error(4, desc)
-			if err = CreateRepositoryNotice(desc); err != nil {
-				log.Error(4, "CreateRepositoryNotice: %v", err)
-			}
-			return nil
-		}
-
-		m.NextUpdate = time.Now().Add(time.Duration(m.Interval) * time.Hour)
 		mirrors = append(mirrors, m)
 		return nil
 	}); err != nil {
@@ -1455,6 +1776,7 @@ func MirrorUpdate() {
 	}
 
 	for i := range mirrors {
+		mirrors[i].runSync()
 		if err := UpdateMirror(mirrors[i]); err != nil {
 			log.Error(4, "UpdateMirror[%d]: %v", mirrors[i].ID, err)
 		}
@@ -1476,7 +1798,7 @@ func GitFsck() {
 		func(idx int, bean interface{}) error {
 			repo := bean.(*Repository)
 			repoPath := repo.RepoPath()
-			_, _, err := process.ExecDir(-1, repoPath, "Repository health check", "git", args...)
+			_, _, err := oldgit.ExecDir(-1, repoPath, "Repository health check", args...)
 			if err != nil {
 				desc := fmt.Sprintf("Fail to health check repository(%s)", repoPath)
 				log.Warn(desc)
@@ -1490,41 +1812,174 @@ func GitFsck() {
 	}
 }
 
-func GitGcRepos() error {
+// GitGC runs garbage collection on repository and records its size before and after.
+// It refuses to run when repository is being written by another process.
+func (repo *Repository) GitGC() (err error) {
+	repoPath := repo.RepoPath()
+	if git.IsRepoLocked(repoPath) {
+		return ErrRepoLocked{repo.ID}
+	}
+
+	sizeBefore, err := git.GetRepoSize(repoPath)
+	if err != nil {
+		return fmt.Errorf("GetRepoSize: %v", err)
+	}
+
 	args := append([]string{"gc"}, setting.Git.GcArgs...)
+	if _, stderr, err := oldgit.ExecDir(-1,
+		repoPath, fmt.Sprintf("GitGC: %s", repoPath),
+		args...); err != nil {
+		return fmt.Errorf("git gc: %v - %s", err, stderr)
+	}
+
+	sizeAfter, err := git.GetRepoSize(repoPath)
+	if err != nil {
+		return fmt.Errorf("GetRepoSize: %v", err)
+	}
+
+	repo.LastGcTime = time.Now()
+	repo.SizeBeforeGc = sizeBefore
+	repo.SizeAfterGc = sizeAfter
+	repo.Size = sizeAfter
+	// Use raw SQL to not change updated time of repository.
+	_, err = x.Exec("UPDATE `repository` SET last_gc_time=?, size_before_gc=?, size_after_gc=?, size=? WHERE id=?",
+		repo.LastGcTime, repo.SizeBeforeGc, repo.SizeAfterGc, repo.Size, repo.ID)
+	return err
+}
+
+// GitGcRepos runs garbage collection on all repositories,
+// repositories that are being written are skipped.
+func GitGcRepos() error {
+	if taskStatusPool.IsRunning(_GIT_GC_REPOS) {
+		return nil
+	}
+	taskStatusPool.Start(_GIT_GC_REPOS)
+	defer taskStatusPool.Stop(_GIT_GC_REPOS)
+
+	log.Trace("Doing: GitGcRepos")
+
 	return x.Where("id > 0").Iterate(new(Repository),
 		func(idx int, bean interface{}) error {
 			repo := bean.(*Repository)
 			if err := repo.GetOwner(); err != nil {
 				return err
 			}
-			_, stderr, err := process.ExecDir(-1, RepoPath(repo.Owner.Name, repo.Name), "Repository garbage collection", "git", args...)
-			if err != nil {
-				return fmt.Errorf("%v: %v", err, stderr)
+			if err := repo.GitGC(); err != nil {
+				if IsErrRepoLocked(err) {
+					log.Trace("Skip garbage collection of locked repository: %s/%s", repo.Owner.Name, repo.Name)
+					return nil
+				}
+
+				desc := fmt.Sprintf("Fail to do garbage collection on repository(%s/%s): %v", repo.Owner.Name, repo.Name, err)
+				log.Warn(desc)
+				if err = CreateRepositoryNotice(desc); err != nil {
+					log.Error(4, "CreateRepositoryNotice: %v", err)
+				}
 			}
 			return nil
 		})
 }
 
+// UpdateSize updates disk usage of repository.
+func (repo *Repository) UpdateSize() error {
+	size, err := git.GetRepoSize(repo.RepoPath())
+	if err != nil {
+		return fmt.Errorf("GetRepoSize: %v", err)
+	}
+
+	repo.Size = size
+	// Use raw SQL to not change updated time of repository.
+	_, err = x.Exec("UPDATE `repository` SET size=? WHERE id=?", repo.Size, repo.ID)
+	return err
+}
+
+// UpdateRepoSizes recalculates disk usage of all repositories.
+func UpdateRepoSizes() {
+	if taskStatusPool.IsRunning(_UPDATE_SIZES) {
+		return
+	}
+	taskStatusPool.Start(_UPDATE_SIZES)
+	defer taskStatusPool.Stop(_UPDATE_SIZES)
+
+	log.Trace("Doing: UpdateRepoSizes")
+
+	if err := x.Where("id > 0").Iterate(new(Repository),
+		func(idx int, bean interface{}) error {
+			repo := bean.(*Repository)
+			if err := repo.UpdateSize(); err != nil {
+				log.Error(4, "UpdateSize[%d]: %v", repo.ID, err)
+			}
+			return nil
+		}); err != nil {
+		log.Error(4, "UpdateRepoSizes: %v", err)
+	}
+}
+
+// GitGcReposTask runs garbage collection on all repositories as cron task.
+func GitGcReposTask() {
+	if err := GitGcRepos(); err != nil {
+		log.Error(4, "GitGcRepos: %v", err)
+	}
+}
+
 type repoChecker struct {
 	querySQL, correctSQL string
 	desc                 string
 }
 
-func repoStatsCheck(checker *repoChecker) {
+// repoStatsCheck corrects records found by checker and returns number of corrected records.
+func repoStatsCheck(checker *repoChecker) int {
 	results, err := x.Query(checker.querySQL)
 	if err != nil {
 		log.Error(4, "Select %s: %v", checker.desc, err)
-		return
+		return 0
 	}
+	corrected := 0
 	for _, result := range results {
 		id := com.StrTo(result["id"]).MustInt64()
 		log.Trace("Updating %s: %d", checker.desc, id)
 		_, err = x.Exec(checker.correctSQL, id, id)
 		if err != nil {
 			log.Error(4, "Update %s[%d]: %v", checker.desc, id, err)
+			continue
 		}
-	}
+		corrected++
+	}
+	return corrected
+}
+
+// repoStatsCheckers contains all checkers of counters stored in database.
+var repoStatsCheckers = []*repoChecker{
+	// Repository.NumWatches
+	{
+		"SELECT repo.id FROM `repository` repo WHERE repo.num_watches!=(SELECT COUNT(*) FROM `watch` WHERE repo_id=repo.id)",
+		"UPDATE `repository` SET num_watches=(SELECT COUNT(*) FROM `watch` WHERE repo_id=?) WHERE id=?",
+		"repository count 'num_watches'",
+	},
+	// Repository.NumStars
+	{
+		"SELECT repo.id FROM `repository` repo WHERE repo.num_stars!=(SELECT COUNT(*) FROM `star` WHERE repo_id=repo.id)",
+		"UPDATE `repository` SET num_stars=(SELECT COUNT(*) FROM `star` WHERE repo_id=?) WHERE id=?",
+		"repository count 'num_stars'",
+	},
+	// Label.NumIssues
+	{
+		"SELECT label.id FROM `label` WHERE label.num_issues!=(SELECT COUNT(*) FROM `issue_label` WHERE label_id=label.id)",
+		"UPDATE `label` SET num_issues=(SELECT COUNT(*) FROM `issue_label` WHERE label_id=?) WHERE id=?",
+		"label count 'num_issues'",
+	},
+	// User.NumRepos
+	{
+		"SELECT `user`.id FROM `user` WHERE `user`.num_repos!=(SELECT COUNT(*) FROM `repository` WHERE owner_id=`user`.id)",
+		"UPDATE `user` SET num_repos=(SELECT COUNT(*) FROM `repository` WHERE owner_id=?) WHERE id=?",
+		"user count 'num_repos'",
+	},
+	// Issue.NumComments
+	{
+		"SELECT `issue`.id FROM `issue` WHERE `issue`.num_comments!=(SELECT COUNT(*) FROM `comment` WHERE issue_id=`issue`.id AND type=0)",
+		"UPDATE `issue` SET num_comments=(SELECT COUNT(*) FROM `comment` WHERE issue_id=? AND type=0) WHERE id=?",
+		"issue count 'num_comments'",
+	},
 }
 
 func CheckRepoStats() {
@@ -1536,40 +1991,8 @@ func CheckRepoStats() {
 
 	log.Trace("Doing: CheckRepoStats")
 
-	checkers := []*repoChecker{
-		// Repository.NumWatches
-		{
-			"SELECT repo.id FROM `repository` repo WHERE repo.num_watches!=(SELECT COUNT(*) FROM `watch` WHERE repo_id=repo.id)",
-			"UPDATE `repository` SET num_watches=(SELECT COUNT(*) FROM `watch` WHERE repo_id=?) WHERE id=?",
-			"repository count 'num_watches'",
-		},
-		// Repository.NumStars
-		{
-			"SELECT repo.id FROM `repository` repo WHERE repo.num_stars!=(SELECT COUNT(*) FROM `star` WHERE repo_id=repo.id)",
-			"UPDATE `repository` SET num_stars=(SELECT COUNT(*) FROM `star` WHERE repo_id=?) WHERE id=?",
-			"repository count 'num_stars'",
-		},
-		// Label.NumIssues
-		{
-			"SELECT label.id FROM `label` WHERE label.num_issues!=(SELECT COUNT(*) FROM `issue_label` WHERE label_id=label.id)",
-			"UPDATE `label` SET num_issues=(SELECT COUNT(*) FROM `issue_label` WHERE label_id=?) WHERE id=?",
-			"label count 'num_issues'",
-		},
-		// User.NumRepos
-		{
-			"SELECT `user`.id FROM `user` WHERE `user`.num_repos!=(SELECT COUNT(*) FROM `repository` WHERE owner_id=`user`.id)",
-			"UPDATE `user` SET num_repos=(SELECT COUNT(*) FROM `repository` WHERE owner_id=?) WHERE id=?",
-			"user count 'num_repos'",
-		},
-		// Issue.NumComments
-		{
-			"SELECT `issue`.id FROM `issue` WHERE `issue`.num_comments!=(SELECT COUNT(*) FROM `comment` WHERE issue_id=`issue`.id AND type=0)",
-			"UPDATE `issue` SET num_comments=(SELECT COUNT(*) FROM `comment` WHERE issue_id=? AND type=0) WHERE id=?",
-			"issue count 'num_comments'",
-		},
-	}
-	for i := range checkers {
-		repoStatsCheck(checkers[i])
+	for i := range repoStatsCheckers {
+		repoStatsCheck(repoStatsCheckers[i])
 	}
 
 	// FIXME: use checker when v0.9, stop supporting old fork repo format.
@@ -1613,14 +2036,21 @@ func CheckRepoStats() {
 
 // A Collaboration is a relation between an individual and a repository
 type Collaboration struct {
-	ID      int64     `xorm:"pk autoincr"`
-	RepoID  int64     `xorm:"UNIQUE(s) INDEX NOT NULL"`
-	UserID  int64     `xorm:"UNIQUE(s) INDEX NOT NULL"`
-	Created time.Time `xorm:"CREATED"`
+	ID     int64 `xorm:"pk autoincr"`
+	RepoID int64 `xorm:"UNIQUE(s) INDEX NOT NULL"`
+	UserID int64 `xorm:"UNIQUE(s) INDEX NOT NULL"`
+	// IsCreator indicates collaboration is granted to member who created
+	// repository in organization, it is revoked when member leaves.
+	IsCreator bool      `xorm:"NOT NULL DEFAULT false"`
+	Created   time.Time `xorm:"CREATED"`
 }
 
 // Add collaborator and accompanying access
 func (repo *Repository) AddCollaborator(u *User) error {
+	return repo.addCollaborator(u, false)
+}
+
+func (repo *Repository) addCollaborator(u *User, isCreator bool) error {
 	collaboration := &Collaboration{
 		RepoID: repo.ID,
 		UserID: u.Id,
@@ -1643,6 +2073,7 @@ func (repo *Repository) AddCollaborator(u *User) error {
 		return err
 	}
 
+	collaboration.IsCreator = isCreator
 	if _, err = sess.InsertOne(collaboration); err != nil {
 		return err
 	}
@@ -1794,6 +2225,31 @@ func notifyWatchers(e Engine, act *Action) error {
 			return fmt.Errorf("insert new action: %v", err)
 		}
 	}
+
+	// Add feeds for followers of actioner who are not watching the repository,
+	// only public activity is visible to followers.
+	if act.IsPrivate {
+		return nil
+	}
+	followerIDs, err := getFollowerIDs(e, act.ActUserID)
+	if err != nil {
+		return fmt.Errorf("get followers: %v", err)
+	}
+	notified := make(map[int64]bool, len(watches))
+	for i := range watches {
+		notified[watches[i].UserID] = true
+	}
+	for _, uid := range followerIDs {
+		if uid == act.ActUserID || notified[uid] {
+			continue
+		}
+
+		act.ID = 0
+		act.UserID = uid
+		if _, err = e.InsertOne(act); err != nil {
+			return fmt.Errorf("insert new action: %v", err)
+		}
+	}
 	return nil
 }
 
@@ -1895,16 +2351,16 @@ func ForkRepository(u *User, oldRepo *Repository, name, desc string) (_ *Reposit
 	}
 
 	repoPath := RepoPath(u.Name, repo.Name)
-	_, stderr, err := process.ExecTimeout(10*time.Minute,
+	_, stderr, err := oldgit.ExecTimeout(10*time.Minute,
 		fmt.Sprintf("ForkRepository(git clone): %s/%s", u.Name, repo.Name),
-		"git", "clone", "--bare", oldRepo.RepoPath(), repoPath)
+		"clone", "--bare", oldRepo.RepoPath(), repoPath)
 	if err != nil {
 		return nil, fmt.Errorf("git clone: %v", stderr)
 	}
 
-	_, stderr, err = process.ExecDir(-1,
+	_, stderr, err = oldgit.ExecDir(-1,
 		repoPath, fmt.Sprintf("ForkRepository(git update-server-info): %s", repoPath),
-		"git", "update-server-info")
+		"update-server-info")
 	if err != nil {
 		return nil, fmt.Errorf("git update-server-info: %v", err)
 	}
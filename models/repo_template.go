@@ -0,0 +1,290 @@
+// Copyright 2015 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Unknwon/com"
+
+	"github.com/gogits/gogs/modules/base"
+	"github.com/gogits/gogs/modules/git"
+	"github.com/gogits/gogs/modules/log"
+	"github.com/gogits/gogs/modules/setting"
+)
+
+// GenerateRepoOptions contains options of generating a repository from template.
+type GenerateRepoOptions struct {
+	Name        string
+	Description string
+	IsPrivate   bool
+
+	// FlattenHistory puts files of template into a single initial commit,
+	// otherwise history of default branch of template is kept.
+	FlattenHistory bool
+}
+
+// templateReplacer returns replacer of placeholders in files of template
+// with information of generated repository.
+func templateReplacer(tmpl, repo *Repository) *strings.Replacer {
+	cloneLink := repo.CloneLink()
+	return strings.NewReplacer(
+		"${REPO_NAME}", repo.Name,
+		"${REPO_OWNER}", repo.Owner.Name,
+		"${REPO_DESCRIPTION}", repo.Description,
+		"${REPO_LINK}", setting.AppUrl+repo.Owner.Name+"/"+repo.Name,
+		"${CLONE_URL_SSH}", cloneLink.SSH,
+		"${CLONE_URL_HTTPS}", cloneLink.HTTPS,
+		"${TEMPLATE_NAME}", tmpl.Name,
+		"${TEMPLATE_OWNER}", tmpl.Owner.Name,
+	)
+}
+
+// extractRepoArchive extracts files of given revision of repository into a directory.
+func extractRepoArchive(repoPath, revision, dstDir string) error {
+	archivePath := dstDir + ".tar"
+	defer os.Remove(archivePath)
+
+	_, stderr, err := git.ExecDir(10*time.Minute,
+		repoPath, fmt.Sprintf("extractRepoArchive(git archive): %s", repoPath),
+		"archive", "--format=tar", "-o", archivePath, revision)
+	if err != nil {
+		return fmt.Errorf("git archive: %s", stderr)
+	}
+
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	tr := tar.NewReader(f)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("read archive: %v", err)
+		}
+
+		target := filepath.Join(dstDir, hdr.Name)
+		if !strings.HasPrefix(target, filepath.Clean(dstDir)+string(filepath.Separator)) {
+			return fmt.Errorf("invalid path in archive: %s", hdr.Name)
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(target, os.ModePerm)
+		case tar.TypeSymlink:
+			err = os.Symlink(hdr.Linkname, target)
+		case tar.TypeReg, tar.TypeRegA:
+			if err = os.MkdirAll(filepath.Dir(target), os.ModePerm); err != nil {
+				break
+			}
+			var fw *os.File
+			if fw, err = os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(hdr.Mode)); err != nil {
+				break
+			}
+			_, err = io.Copy(fw, tr)
+			fw.Close()
+		}
+		if err != nil {
+			return fmt.Errorf("extract %s: %v", hdr.Name, err)
+		}
+	}
+	return nil
+}
+
+// applyTemplateReplacer substitutes placeholders in all text files of work directory,
+// and returns true if any file is changed.
+func applyTemplateReplacer(workDir string, r *strings.Replacer) (bool, error) {
+	changed := false
+	err := filepath.Walk(workDir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		} else if fi.IsDir() {
+			if fi.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		} else if !fi.Mode().IsRegular() {
+			return nil
+		}
+
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		} else if _, isText := base.IsTextFile(data); !isText {
+			return nil
+		}
+
+		content := r.Replace(string(data))
+		if content == string(data) {
+			return nil
+		}
+		changed = true
+		return ioutil.WriteFile(path, []byte(content), fi.Mode())
+	})
+	return changed, err
+}
+
+// commitTemplateFiles commits all changes in work directory and pushes to origin.
+func commitTemplateFiles(tmpPath, branch, msg string, sig *git.Signature) (err error) {
+	var stderr string
+	if _, stderr, err = git.ExecDir(-1,
+		tmpPath, fmt.Sprintf("commitTemplateFiles (git add): %s", tmpPath),
+		"add", "--all"); err != nil {
+		return fmt.Errorf("git add: %s", stderr)
+	}
+
+	if _, stderr, err = git.ExecDir(-1,
+		tmpPath, fmt.Sprintf("commitTemplateFiles (git commit): %s", tmpPath),
+		"commit", fmt.Sprintf("--author='%s <%s>'", sig.Name, sig.Email),
+		"-m", msg); err != nil {
+		return fmt.Errorf("git commit: %s", stderr)
+	}
+
+	if _, stderr, err = git.ExecDir(-1,
+		tmpPath, fmt.Sprintf("commitTemplateFiles (git push): %s", tmpPath),
+		"push", "origin", branch); err != nil {
+		return fmt.Errorf("git push: %s", stderr)
+	}
+	return nil
+}
+
+func generateRepository(repoPath string, u *User, tmpl, repo *Repository, opts GenerateRepoOptions) (err error) {
+	if com.IsExist(repoPath) {
+		return fmt.Errorf("path already exists: %s", repoPath)
+	}
+
+	var stderr string
+	if tmpl.IsBare || opts.FlattenHistory {
+		if err = git.InitRepository(repoPath, true); err != nil {
+			return fmt.Errorf("InitRepository: %v", err)
+		}
+		if _, stderr, err = git.ExecDir(-1,
+			repoPath, fmt.Sprintf("generateRepository (git symbolic-ref): %s", repoPath),
+			"symbolic-ref", "HEAD", "refs/heads/"+repo.DefaultBranch); err != nil {
+			return fmt.Errorf("git symbolic-ref: %s", stderr)
+		}
+	} else {
+		if _, stderr, err = git.ExecTimeout(10*time.Minute,
+			fmt.Sprintf("generateRepository (git clone): %s/%s", u.Name, repo.Name),
+			"clone", "--bare", "--single-branch", "--branch", tmpl.DefaultBranch,
+			tmpl.RepoPath(), repoPath); err != nil {
+			return fmt.Errorf("git clone: %s", stderr)
+		}
+		// Template is not supposed to be tracked as remote.
+		if _, stderr, err = git.ExecDir(-1,
+			repoPath, fmt.Sprintf("generateRepository (git remote rm): %s", repoPath),
+			"remote", "rm", "origin"); err != nil {
+			return fmt.Errorf("git remote rm: %s", stderr)
+		}
+	}
+	if err = createUpdateHook(repoPath); err != nil {
+		return fmt.Errorf("createUpdateHook: %v", err)
+	}
+
+	// Nothing to copy from template.
+	if tmpl.IsBare {
+		return nil
+	}
+
+	tmpDir := filepath.Join(os.TempDir(), "gogs-generate-"+repo.Name+"-"+com.ToStr(time.Now().Nanosecond()))
+	defer os.RemoveAll(tmpDir)
+
+	if _, stderr, err = git.Exec(
+		fmt.Sprintf("generateRepository (git clone): %s", repoPath),
+		"clone", repoPath, tmpDir); err != nil {
+		return fmt.Errorf("git clone: %s", stderr)
+	}
+
+	if opts.FlattenHistory {
+		if _, stderr, err = git.ExecDir(-1,
+			tmpDir, fmt.Sprintf("generateRepository (git checkout): %s", tmpDir),
+			"checkout", "-b", repo.DefaultBranch); err != nil {
+			return fmt.Errorf("git checkout: %s", stderr)
+		}
+		if err = extractRepoArchive(tmpl.RepoPath(), tmpl.DefaultBranch, tmpDir); err != nil {
+			return fmt.Errorf("extractRepoArchive: %v", err)
+		}
+	}
+
+	changed, err := applyTemplateReplacer(tmpDir, templateReplacer(tmpl, repo))
+	if err != nil {
+		return fmt.Errorf("applyTemplateReplacer: %v", err)
+	}
+
+	sig := u.NewGitSig()
+	if opts.FlattenHistory {
+		err = commitTemplateFiles(tmpDir, repo.DefaultBranch, "initial commit", sig)
+	} else if changed {
+		err = commitTemplateFiles(tmpDir, repo.DefaultBranch,
+			fmt.Sprintf("Generate from template %s/%s", tmpl.Owner.Name, tmpl.Name), sig)
+	}
+	return err
+}
+
+// GenerateRepository creates a repository for given user or organization
+// with files copied from template repository.
+func GenerateRepository(u *User, tmpl *Repository, opts GenerateRepoOptions) (_ *Repository, err error) {
+	if !tmpl.IsTemplate {
+		return nil, ErrRepoNotTemplate{tmpl.ID}
+	} else if err = tmpl.GetOwner(); err != nil {
+		return nil, fmt.Errorf("GetOwner: %v", err)
+	}
+
+	repo := &Repository{
+		OwnerID:       u.Id,
+		Owner:         u,
+		Name:          opts.Name,
+		LowerName:     strings.ToLower(opts.Name),
+		Description:   opts.Description,
+		DefaultBranch: tmpl.DefaultBranch,
+		IsPrivate:     opts.IsPrivate,
+		IsBare:        tmpl.IsBare,
+		TemplateID:    tmpl.ID,
+	}
+	if len(repo.DefaultBranch) == 0 {
+		repo.DefaultBranch = setting.Repository.DefaultBranch
+	}
+
+	sess := x.NewSession()
+	defer sessionRelease(sess)
+	if err = sess.Begin(); err != nil {
+		return nil, err
+	}
+
+	if err = createRepository(sess, u, repo); err != nil {
+		return nil, err
+	}
+
+	repoPath := RepoPath(u.Name, repo.Name)
+	if err = generateRepository(repoPath, u, tmpl, repo, opts); err != nil {
+		if err2 := os.RemoveAll(repoPath); err2 != nil {
+			log.Error(4, "generateRepository: %v", err)
+			return nil, fmt.Errorf(
+				"delete repo directory %s/%s failed(2): %v", u.Name, repo.Name, err2)
+		}
+		return nil, fmt.Errorf("generateRepository: %v", err)
+	}
+
+	_, stderr, err := git.ExecDir(-1,
+		repoPath, fmt.Sprintf("GenerateRepository(git update-server-info): %s", repoPath),
+		"update-server-info")
+	if err != nil {
+		return nil, errors.New("GenerateRepository(git update-server-info): " + stderr)
+	}
+
+	return repo, sess.Commit()
+}
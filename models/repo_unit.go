@@ -0,0 +1,38 @@
+// Copyright 2015 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+// RepoUnit represents a feature of repository that can be enabled or disabled.
+type RepoUnit int
+
+const (
+	UNIT_ISSUES RepoUnit = iota + 1
+	UNIT_PULLS
+	UNIT_WIKI
+	UNIT_RELEASES
+)
+
+// IsUnitEnabled returns true if given unit is enabled in repository.
+func (repo *Repository) IsUnitEnabled(unit RepoUnit) bool {
+	switch unit {
+	case UNIT_ISSUES:
+		return repo.EnableIssues
+	case UNIT_PULLS:
+		return repo.EnablePulls
+	case UNIT_WIKI:
+		return repo.EnableWiki
+	case UNIT_RELEASES:
+		return repo.EnableReleases
+	}
+	return false
+}
+
+// Unit returns the repository unit that issue belongs to.
+func (i *Issue) Unit() RepoUnit {
+	if i.IsPull {
+		return UNIT_PULLS
+	}
+	return UNIT_ISSUES
+}
@@ -0,0 +1,117 @@
+// Copyright 2015 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gogits/gogs/modules/webauthn"
+)
+
+// SecurityKey represents a WebAuthn credential registered by a user
+// as second factor of sign in.
+type SecurityKey struct {
+	ID           int64  `xorm:"pk autoincr"`
+	UID          int64  `xorm:"INDEX"`
+	Name         string `xorm:"NOT NULL"`
+	CredentialID string `xorm:"UNIQUE NOT NULL"` // Encoded by webauthn.Encoding.
+	PublicKey    string `xorm:"TEXT NOT NULL"`   // Encoded by webauthn.Encoding.
+	SignCount    int64
+	Created      time.Time `xorm:"CREATED"`
+	LastUsed     time.Time
+}
+
+// Credential returns WebAuthn credential of security key.
+func (k *SecurityKey) Credential() (*webauthn.Credential, error) {
+	id, err := webauthn.Encoding.DecodeString(k.CredentialID)
+	if err != nil {
+		return nil, err
+	}
+	publicKey, err := webauthn.Encoding.DecodeString(k.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	return &webauthn.Credential{
+		ID:        id,
+		PublicKey: publicKey,
+		SignCount: uint32(k.SignCount),
+	}, nil
+}
+
+// AddSecurityKey adds a new security key with given credential for user.
+func AddSecurityKey(uid int64, name string, cred *webauthn.Credential) (*SecurityKey, error) {
+	name = strings.TrimSpace(name)
+	has, err := x.Where("uid=? AND name=?", uid, name).Get(new(SecurityKey))
+	if err != nil {
+		return nil, err
+	} else if has {
+		return nil, ErrSecurityKeyAlreadyExist{name}
+	}
+
+	key := &SecurityKey{
+		UID:          uid,
+		Name:         name,
+		CredentialID: webauthn.Encoding.EncodeToString(cred.ID),
+		PublicKey:    webauthn.Encoding.EncodeToString(cred.PublicKey),
+		SignCount:    int64(cred.SignCount),
+	}
+	has, err = x.Get(&SecurityKey{CredentialID: key.CredentialID})
+	if err != nil {
+		return nil, err
+	} else if has {
+		return nil, ErrSecurityKeyAlreadyExist{name}
+	}
+
+	if _, err = x.Insert(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// ListSecurityKeys returns all security keys of given user.
+func ListSecurityKeys(uid int64) ([]*SecurityKey, error) {
+	keys := make([]*SecurityKey, 0, 2)
+	return keys, x.Where("uid=?", uid).Asc("id").Find(&keys)
+}
+
+// HasSecurityKeys returns true if given user has registered any security key.
+func HasSecurityKeys(uid int64) (bool, error) {
+	return x.Get(&SecurityKey{UID: uid})
+}
+
+// GetSecurityKeyByCredentialID returns security key of given user by credential ID.
+func GetSecurityKeyByCredentialID(uid int64, credID string) (*SecurityKey, error) {
+	key := &SecurityKey{
+		UID:          uid,
+		CredentialID: credID,
+	}
+	has, err := x.Get(key)
+	if err != nil {
+		return nil, err
+	} else if !has {
+		return nil, ErrSecurityKeyNotExist{0, credID}
+	}
+	return key, nil
+}
+
+// UpdateSecurityKeyUsage records a successful sign in with security key.
+func UpdateSecurityKeyUsage(key *SecurityKey, signCount uint32) error {
+	key.SignCount = int64(signCount)
+	key.LastUsed = time.Now()
+	_, err := x.Id(key.ID).Cols("sign_count", "last_used").Update(key)
+	return err
+}
+
+// DeleteSecurityKey deletes security key of given user by ID.
+func DeleteSecurityKey(uid, id int64) error {
+	affected, err := x.Delete(&SecurityKey{ID: id, UID: uid})
+	if err != nil {
+		return err
+	} else if affected == 0 {
+		return ErrSecurityKeyNotExist{id, ""}
+	}
+	return nil
+}
@@ -5,22 +5,96 @@
 package models
 
 import (
+	"strings"
 	"time"
 
 	"github.com/gogits/gogs/modules/base"
 	"github.com/gogits/gogs/modules/uuid"
 )
 
+const (
+	ACCESS_TOKEN_SCOPE_REPO_READ  = "repo:read"
+	ACCESS_TOKEN_SCOPE_REPO_WRITE = "repo:write"
+	ACCESS_TOKEN_SCOPE_ADMIN_USER = "admin:user"
+	// ACCESS_TOKEN_SCOPE_USER allows to manage account of token owner, e.g. data exports,
+	// sessions, OAuth2 grants, notifications, following, organizations and teams.
+	ACCESS_TOKEN_SCOPE_USER = "user"
+)
+
+// AccessTokenScopes contains all valid scopes of access token.
+var AccessTokenScopes = []string{
+	ACCESS_TOKEN_SCOPE_REPO_READ,
+	ACCESS_TOKEN_SCOPE_REPO_WRITE,
+	ACCESS_TOKEN_SCOPE_ADMIN_USER,
+	ACCESS_TOKEN_SCOPE_USER,
+}
+
+// IsValidAccessTokenScope returns true if given scope is valid.
+func IsValidAccessTokenScope(scope string) bool {
+	for i := range AccessTokenScopes {
+		if AccessTokenScopes[i] == scope {
+			return true
+		}
+	}
+	return false
+}
+
 // AccessToken represents a personal access token.
 type AccessToken struct {
 	ID                int64 `xorm:"pk autoincr"`
 	UID               int64 `xorm:"INDEX"`
 	Name              string
 	Sha1              string    `xorm:"UNIQUE VARCHAR(40)"`
+	Scopes            string    // Comma separated list, empty means full access.
 	Created           time.Time `xorm:"CREATED"`
 	Updated           time.Time
-	HasRecentActivity bool `xorm:"-"`
-	HasUsed           bool `xorm:"-"`
+	LastUsed          time.Time
+	LastUsedIP        string `xorm:"VARCHAR(50)"`
+	GrantID           int64  `xorm:"INDEX"` // OAuth2 authorization that issued the token, if any.
+	HasRecentActivity bool   `xorm:"-"`
+	HasUsed           bool   `xorm:"-"`
+}
+
+// ScopeList returns list of scopes of access token,
+// it returns nil when token has full access.
+func (t *AccessToken) ScopeList() []string {
+	if len(t.Scopes) == 0 {
+		return nil
+	}
+	return strings.Split(t.Scopes, ",")
+}
+
+// HasScope returns true if access token is allowed to perform operations
+// that require given scope. Write access to repositories implies read access.
+func (t *AccessToken) HasScope(scope string) bool {
+	if len(t.Scopes) == 0 {
+		return true
+	}
+
+	for _, s := range t.ScopeList() {
+		if s == scope ||
+			(s == ACCESS_TOKEN_SCOPE_REPO_WRITE && scope == ACCESS_TOKEN_SCOPE_REPO_READ) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetScopes validates and sets scopes of access token,
+// empty list grants full access.
+func (t *AccessToken) SetScopes(scopes []string) error {
+	valid := make([]string, 0, len(scopes))
+	for _, s := range scopes {
+		s = strings.TrimSpace(s)
+		if len(s) == 0 {
+			continue
+		} else if !IsValidAccessTokenScope(s) {
+			return ErrAccessTokenInvalidScope{s}
+		}
+		valid = append(valid, s)
+	}
+	t.Scopes = strings.Join(valid, ",")
+	return nil
 }
 
 // NewAccessToken creates new access token.
@@ -51,8 +125,12 @@ func ListAccessTokens(uid int64) ([]*AccessToken, error) {
 	}
 
 	for _, t := range tokens {
-		t.HasUsed = t.Updated.After(t.Created)
-		t.HasRecentActivity = t.Updated.Add(7 * 24 * time.Hour).After(time.Now())
+		// Tokens used before last used time was recorded.
+		if t.LastUsed.IsZero() && t.Updated.After(t.Created) {
+			t.LastUsed = t.Updated
+		}
+		t.HasUsed = !t.LastUsed.IsZero()
+		t.HasRecentActivity = t.LastUsed.Add(7 * 24 * time.Hour).After(time.Now())
 	}
 	return tokens, nil
 }
@@ -63,6 +141,25 @@ func UpdateAccessToekn(t *AccessToken) error {
 	return err
 }
 
+// ACCESS_TOKEN_USED_UPDATE_INTERVAL is the minimal interval between two updates
+// of last used information of same access token from same IP address.
+const ACCESS_TOKEN_USED_UPDATE_INTERVAL = time.Minute
+
+// UpdateLastUsed records access token is used from given IP address now.
+// To avoid writing database on every request, update is skipped when
+// token has been recently used from same IP address.
+func (t *AccessToken) UpdateLastUsed(ip string) error {
+	now := time.Now()
+	if t.LastUsedIP == ip && now.Sub(t.LastUsed) < ACCESS_TOKEN_USED_UPDATE_INTERVAL {
+		return nil
+	}
+
+	t.LastUsed = now
+	t.LastUsedIP = ip
+	_, err := x.Id(t.ID).Cols("last_used", "last_used_ip").Update(t)
+	return err
+}
+
 // DeleteAccessTokenByID deletes access token by given ID.
 func DeleteAccessTokenByID(id int64) error {
 	_, err := x.Id(id).Delete(new(AccessToken))
@@ -7,7 +7,6 @@ package models
 import (
 	"container/list"
 	"fmt"
-	"os/exec"
 	"strings"
 
 	"github.com/gogits/gogs/modules/git"
@@ -46,6 +45,28 @@ func DeleteUpdateTaskByUUID(uuid string) error {
 	return err
 }
 
+// deleteTagUpdate fires push tag webhooks for a tag deleted by push.
+func deleteTagUpdate(refName, oldCommitID, newCommitID, repoUserName, repoName string, userID int64) error {
+	pusher, err := GetUserByID(userID)
+	if err != nil {
+		return fmt.Errorf("GetUserByID: %v", err)
+	}
+	owner, err := GetUserByName(repoUserName)
+	if err != nil {
+		return fmt.Errorf("GetUserByName: %v", err)
+	}
+	repo, err := GetRepositoryByName(owner.Id, repoName)
+	if err != nil {
+		return fmt.Errorf("GetRepositoryByName: %v", err)
+	}
+	repo.Owner = owner
+
+	if err = PreparePushTagWebhooks(repo, pusher, HOOK_TAG_DELETED, refName, oldCommitID, newCommitID); err != nil {
+		return fmt.Errorf("PreparePushTagWebhooks: %v", err)
+	}
+	return nil
+}
+
 func Update(refName, oldCommitID, newCommitID, userName, repoUserName, repoName string, userID int64) error {
 	isNew := strings.HasPrefix(oldCommitID, "0000000")
 	if isNew &&
@@ -55,13 +76,14 @@ func Update(refName, oldCommitID, newCommitID, userName, repoUserName, repoName
 
 	f := RepoPath(repoUserName, repoName)
 
-	gitUpdate := exec.Command("git", "update-server-info")
-	gitUpdate.Dir = f
-	gitUpdate.Run()
+	git.Command(f, "update-server-info").Run()
 
 	isDel := strings.HasPrefix(newCommitID, "0000000")
 	if isDel {
 		log.GitLogger.Info("del rev", refName, "from", userName+"/"+repoName+".git", "by", userID)
+		if strings.HasPrefix(refName, "refs/tags/") {
+			return deleteTagUpdate(refName, oldCommitID, newCommitID, repoUserName, repoName, userID)
+		}
 		return nil
 	}
 
@@ -80,6 +102,10 @@ func Update(refName, oldCommitID, newCommitID, userName, repoUserName, repoName
 		return fmt.Errorf("runUpdate.GetRepositoryByName userId: %v", err)
 	}
 
+	if err = repo.UpdateSize(); err != nil {
+		log.GitLogger.Error(4, "runUpdate.UpdateSize: %v", err)
+	}
+
 	// Push tags.
 	if strings.HasPrefix(refName, "refs/tags/") {
 		tagName := git.RefEndName(refName)
@@ -151,5 +177,14 @@ func Update(refName, oldCommitID, newCommitID, userName, repoUserName, repoName
 		repo.ID, repoUserName, repoName, refName, &PushCommits{l.Len(), commits, "", nil}, oldCommitID, newCommitID); err != nil {
 		return fmt.Errorf("runUpdate.models.CommitRepoAction: %s/%s:%v", repoUserName, repoName, err)
 	}
+
+	if !isNew {
+		pusher, err := GetUserByID(userID)
+		if err != nil {
+			return fmt.Errorf("runUpdate.GetUserByID: %v", err)
+		} else if err = PrepareSynchronizedWebhooks(pusher, repo.ID, git.RefEndName(refName)); err != nil {
+			log.GitLogger.Error(4, "PrepareSynchronizedWebhooks: %v", err)
+		}
+	}
 	return nil
 }
@@ -41,6 +41,15 @@ const (
 	ORGANIZATION
 )
 
+// WatchNotifyMode represents which activities of watched repositories user is notified of by e-mail.
+type WatchNotifyMode int
+
+const (
+	WATCH_NOTIFY_ALL           WatchNotifyMode = iota // All activities, default for historic reason.
+	WATCH_NOTIFY_PARTICIPATING                        // Only issues and pull requests user participates in.
+	WATCH_NOTIFY_NONE
+)
+
 var (
 	ErrUserNotKeyOwner       = errors.New("User does not the owner of public key")
 	ErrEmailNotExist         = errors.New("E-mail does not exist")
@@ -73,16 +82,33 @@ type User struct {
 	Salt        string    `xorm:"VARCHAR(10)"`
 	Created     time.Time `xorm:"CREATED"`
 	Updated     time.Time `xorm:"UPDATED"`
+	// NameChanged is the last time that user changed name.
+	NameChanged time.Time
+	// RememberSalt is changed to invalidate remembered sign in without affecting other codes.
+	RememberSalt string `xorm:"VARCHAR(10)"`
 
 	// Remember visibility choice for convenience, true for private
 	LastRepoVisibility bool
 
+	// WatchNotifyMode is which activities of watched repositories to be notified by e-mail.
+	WatchNotifyMode WatchNotifyMode `xorm:"NOT NULL DEFAULT 0"`
+
 	// Permissions.
 	IsActive         bool
 	IsAdmin          bool
 	AllowGitHook     bool
 	AllowImportLocal bool // Allow migrate repository by local path
 
+	// NeedApproval indicates self-registered account is waiting for approval of administrator.
+	NeedApproval bool `xorm:"INDEX NOT NULL DEFAULT false"`
+
+	// StorageQuota is maximum total size of owned repositories in MB,
+	// 0 means unlimited and -1 means to use site default.
+	StorageQuota int64 `xorm:"NOT NULL DEFAULT -1"`
+	// MaxRepoCreation is maximum number of repositories user can own,
+	// -1 means to use site default.
+	MaxRepoCreation int `xorm:"NOT NULL DEFAULT -1"`
+
 	// Avatar.
 	Avatar          string `xorm:"VARCHAR(2048) NOT NULL"`
 	AvatarEmail     string `xorm:"NOT NULL"`
@@ -100,6 +126,11 @@ type User struct {
 	NumMembers  int
 	Teams       []*Team `xorm:"-"`
 	Members     []*User `xorm:"-"`
+
+	// Settings of organization applied to members.
+	DefaultMemberPublic  bool  // Whether membership of new members is public.
+	DefaultTeamID        int64 // Team that new members are added to, 0 means none.
+	MembersCanCreateRepo bool  // Whether members who are not owners can create repositories.
 }
 
 func (u *User) AfterSet(colName string, _ xorm.Cell) {
@@ -122,6 +153,90 @@ func (u *User) CanEditGitHook() bool {
 	return u.IsAdmin || u.AllowGitHook
 }
 
+// StorageQuotaSize returns maximum total size of repositories
+// user is allowed to own in bytes, 0 means unlimited.
+func (u *User) StorageQuotaSize() int64 {
+	quota := u.StorageQuota
+	if quota < 0 {
+		quota = setting.Repository.StorageQuota
+	}
+	return quota * 1024 * 1024
+}
+
+// StorageUsage returns total size of repositories owned by user in bytes.
+func (u *User) StorageUsage() (int64, error) {
+	results, err := x.Query("SELECT SUM(size) AS total FROM `repository` WHERE owner_id=?", u.Id)
+	if err != nil {
+		return 0, err
+	} else if len(results) == 0 {
+		return 0, nil
+	}
+	return com.StrTo(results[0]["total"]).MustInt64(), nil
+}
+
+// IsStorageQuotaExceeded returns true if repositories owned by user
+// have reached the storage quota.
+func (u *User) IsStorageQuotaExceeded() (bool, error) {
+	quota := u.StorageQuotaSize()
+	if quota <= 0 {
+		return false, nil
+	}
+
+	usage, err := u.StorageUsage()
+	if err != nil {
+		return false, fmt.Errorf("StorageUsage: %v", err)
+	}
+	return usage >= quota, nil
+}
+
+// WouldExceedStorageQuota returns true if repositories owned by user
+// would exceed the storage quota after adding given size.
+func (u *User) WouldExceedStorageQuota(size int64) (bool, error) {
+	quota := u.StorageQuotaSize()
+	if quota <= 0 {
+		return false, nil
+	}
+
+	usage, err := u.StorageUsage()
+	if err != nil {
+		return false, fmt.Errorf("StorageUsage: %v", err)
+	}
+	return usage+size > quota, nil
+}
+
+// MaxCreationLimit returns maximum number of repositories
+// user is allowed to own, -1 means unlimited.
+func (u *User) MaxCreationLimit() int {
+	if u.MaxRepoCreation <= -1 {
+		return setting.Repository.MaxCreationLimit
+	}
+	return u.MaxRepoCreation
+}
+
+func (u *User) canCreateRepo(e Engine, isFork bool) (bool, error) {
+	limit := u.MaxCreationLimit()
+	if u.IsAdmin || limit <= -1 {
+		return true, nil
+	}
+
+	if !setting.Repository.MaxCreationExcludeForks {
+		return u.NumRepos < limit, nil
+	} else if isFork {
+		return true, nil
+	}
+	count, err := e.Where("owner_id=? AND is_fork=?", u.Id, false).Count(new(Repository))
+	if err != nil {
+		return false, err
+	}
+	return int(count) < limit, nil
+}
+
+// CanCreateRepo returns true if user has not reached the limit
+// of number of repositories to own.
+func (u *User) CanCreateRepo() (bool, error) {
+	return u.canCreateRepo(x, false)
+}
+
 // CanImportLocal returns true if user can migrate repository by local path.
 func (u *User) CanImportLocal() bool {
 	return u.IsAdmin || u.AllowImportLocal
@@ -150,6 +265,16 @@ func (u *User) HomeLink() string {
 	return setting.AppSubUrl + "/" + u.Name
 }
 
+// RememberCookieSecret returns secret to sign remember cookie of user,
+// which changes when sessions of user are revoked.
+func (u *User) RememberCookieSecret() string {
+	// Cookies issued before the salt was introduced are still accepted.
+	if len(u.RememberSalt) == 0 {
+		return base.EncodeMD5(u.Rands + u.Passwd)
+	}
+	return base.EncodeMD5(u.RememberSalt + u.Passwd)
+}
+
 // GenerateEmailActivateCode generates an activate code based on user information and given e-mail.
 func (u *User) GenerateEmailActivateCode(email string) string {
 	code := base.CreateTimeLimitCode(
@@ -256,6 +381,21 @@ func (u *User) ValidatePassword(passwd string) bool {
 	return u.Passwd == newUser.Passwd
 }
 
+// CheckAvatarImage returns error if given data is not an acceptable avatar image.
+func CheckAvatarImage(data []byte) error {
+	if int64(len(data)) > setting.AvatarMaxSize {
+		return ErrAvatarInvalid{fmt.Sprintf("file size exceeds %d bytes", setting.AvatarMaxSize)}
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return ErrAvatarInvalid{"not a supported image"}
+	} else if cfg.Width > setting.AvatarMaxWidth || cfg.Height > setting.AvatarMaxHeight {
+		return ErrAvatarInvalid{fmt.Sprintf("dimensions exceed %dx%d", setting.AvatarMaxWidth, setting.AvatarMaxHeight)}
+	}
+	return nil
+}
+
 // UploadAvatar saves custom avatar for user.
 // FIXME: split uploads to different subdirs in case we have massive users.
 func (u *User) UploadAvatar(data []byte) error {
@@ -291,6 +431,26 @@ func (u *User) UploadAvatar(data []byte) error {
 	return sess.Commit()
 }
 
+// DeleteAvatar deletes custom avatar of user and switches back to generated one.
+func (u *User) DeleteAvatar() error {
+	log.Trace("DeleteAvatar[%d]: %s", u.Id, u.CustomAvatarPath())
+	if err := os.Remove(u.CustomAvatarPath()); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("Remove: %v", err)
+	}
+
+	u.UseCustomAvatar = false
+	if err := UpdateUser(u); err != nil {
+		return fmt.Errorf("UpdateUser: %v", err)
+	}
+
+	if setting.DisableGravatar || setting.OfflineMode {
+		if err := u.GenerateRandomAvatar(); err != nil {
+			return fmt.Errorf("GenerateRandomAvatar: %v", err)
+		}
+	}
+	return nil
+}
+
 // IsAdminOfRepo returns true if user has admin or higher access of repository.
 func (u *User) IsAdminOfRepo(repo *Repository) bool {
 	if err := repo.GetOwner(); err != nil {
@@ -432,6 +592,13 @@ func CreateUser(u *User) (err error) {
 		return ErrUserAlreadyExist{u.Name}
 	}
 
+	isExist, err = isNameRedirectedByOthers(0, u.Name)
+	if err != nil {
+		return err
+	} else if isExist {
+		return ErrUserAlreadyExist{u.Name}
+	}
+
 	u.Email = strings.ToLower(u.Email)
 	isExist, err = IsEmailUsed(u.Email)
 	if err != nil {
@@ -480,6 +647,65 @@ func Users(page, pageSize int) ([]*User, error) {
 	return users, x.Limit(pageSize, (page-1)*pageSize).Where("type=0").Asc("id").Find(&users)
 }
 
+// CountPendingApprovalUsers returns number of users waiting for approval of administrator.
+func CountPendingApprovalUsers() int64 {
+	count, _ := x.Where("type=0").And("need_approval=?", true).Count(new(User))
+	return count
+}
+
+// PendingApprovalUsers returns users waiting for approval of administrator in given page.
+func PendingApprovalUsers(page, pageSize int) ([]*User, error) {
+	users := make([]*User, 0, pageSize)
+	return users, x.Limit(pageSize, (page-1)*pageSize).Where("type=0").And("need_approval=?", true).Asc("id").Find(&users)
+}
+
+// GetAdminUsers returns all site administrators.
+func GetAdminUsers() ([]*User, error) {
+	admins := make([]*User, 0, 5)
+	return admins, x.Where("type=0").And("is_admin=?", true).Find(&admins)
+}
+
+// ApproveUser approves account waiting for approval of administrator,
+// it is activated unless e-mail address needs to be confirmed.
+func ApproveUser(u *User) error {
+	u.NeedApproval = false
+	u.IsActive = !setting.Service.RegisterEmailConfirm
+	u.Rands = GetUserSalt()
+	_, err := x.Id(u.Id).Cols("need_approval", "is_active", "rands").Update(u)
+	return err
+}
+
+// ExploreUserOptions represents options of listing public users or organizations.
+type ExploreUserOptions struct {
+	Type     UserType
+	OrderBy  string
+	Page     int
+	PageSize int
+}
+
+// ExploreUsers returns users or organizations which are visible to the public in given page,
+// and total number of them. Individual users are listed only when they have been activated.
+func ExploreUsers(opts *ExploreUserOptions) ([]*User, int64, error) {
+	cond := "type=?"
+	args := []interface{}{opts.Type}
+	if opts.Type == INDIVIDUAL {
+		cond += " AND is_active=?"
+		args = append(args, true)
+	}
+
+	total, err := x.Where(cond, args...).Count(new(User))
+	if err != nil {
+		return nil, 0, fmt.Errorf("Count: %v", err)
+	}
+
+	users := make([]*User, 0, opts.PageSize)
+	sess := x.Limit(opts.PageSize, (opts.Page-1)*opts.PageSize).Where(cond, args...)
+	if len(opts.OrderBy) > 0 {
+		sess.OrderBy(opts.OrderBy)
+	}
+	return users, total, sess.Find(&users)
+}
+
 // get user by erify code
 func getVerifyUser(code string) (user *User) {
 	if len(code) <= base.TimeLimitCodeLength {
@@ -546,7 +772,21 @@ func ChangeUserName(u *User, newUserName string) (err error) {
 		return ErrUserAlreadyExist{newUserName}
 	}
 
-	return os.Rename(UserPath(u.LowerName), UserPath(newUserName))
+	// Former names of other accounts are kept so their old links do not break.
+	isExist, err = isNameRedirectedByOthers(u.Id, newUserName)
+	if err != nil {
+		return err
+	} else if isExist {
+		return ErrUserAlreadyExist{newUserName}
+	}
+
+	if err = os.Rename(UserPath(u.LowerName), UserPath(newUserName)); err != nil {
+		return err
+	} else if err = newUserRedirect(u.Id, u.Name, newUserName); err != nil {
+		return fmt.Errorf("newUserRedirect: %v", err)
+	}
+	u.NameChanged = time.Now()
+	return nil
 }
 
 func updateUser(e Engine, u *User) error {
@@ -644,13 +884,23 @@ func deleteUser(e *xorm.Session, u *User) error {
 	// ***** END: Star *****
 
 	// ***** START: Follow *****
+	followings := make([]*Follow, 0, 10)
+	if err = e.Find(&followings, &Follow{UserID: u.Id}); err != nil {
+		return fmt.Errorf("get all followings: %v", err)
+	}
+	for i := range followings {
+		if _, err = e.Exec("UPDATE `user` SET num_followers=num_followers-1 WHERE id=?", followings[i].FollowID); err != nil {
+			return fmt.Errorf("decrease user follower number[%d]: %v", followings[i].FollowID, err)
+		}
+	}
+
 	followers := make([]*Follow, 0, 10)
-	if err = e.Find(&followers, &Follow{UserID: u.Id}); err != nil {
+	if err = e.Find(&followers, &Follow{FollowID: u.Id}); err != nil {
 		return fmt.Errorf("get all followers: %v", err)
 	}
 	for i := range followers {
-		if _, err = e.Exec("UPDATE `user` SET num_followers=num_followers-1 WHERE id=?", followers[i].UserID); err != nil {
-			return fmt.Errorf("decrease user follower number[%d]: %v", followers[i].UserID, err)
+		if _, err = e.Exec("UPDATE `user` SET num_followings=num_followings-1 WHERE id=?", followers[i].UserID); err != nil {
+			return fmt.Errorf("decrease user following number[%d]: %v", followers[i].UserID, err)
 		}
 	}
 	// ***** END: Follow *****
@@ -661,14 +911,45 @@ func deleteUser(e *xorm.Session, u *User) error {
 		&Access{UserID: u.Id},
 		&Watch{UserID: u.Id},
 		&Star{UID: u.Id},
+		&Follow{UserID: u.Id},
 		&Follow{FollowID: u.Id},
 		&Action{UserID: u.Id},
 		&IssueUser{UID: u.Id},
 		&EmailAddress{UID: u.Id},
+		&SecurityKey{UID: u.Id},
+		&GPGKey{OwnerID: u.Id},
+		&UserSession{UID: u.Id},
+		&UserRedirect{RedirectUserID: u.Id},
+		&Notification{UID: u.Id},
 	); err != nil {
 		return fmt.Errorf("deleteBeans: %v", err)
 	}
 
+	// ***** START: OAuth2 *****
+	// Authorizations of user and authorizations to applications of user.
+	apps := make([]*OAuth2Application, 0, 5)
+	if err = e.Find(&apps, &OAuth2Application{UID: u.Id}); err != nil {
+		return fmt.Errorf("get OAuth2 applications: %v", err)
+	}
+	grants := make([]*OAuth2Grant, 0, 10)
+	if err = e.Find(&grants, &OAuth2Grant{UID: u.Id}); err != nil {
+		return fmt.Errorf("get OAuth2 grants: %v", err)
+	}
+	for _, app := range apps {
+		if err = e.Find(&grants, &OAuth2Grant{AppID: app.ID}); err != nil {
+			return fmt.Errorf("get OAuth2 grants of application[%d]: %v", app.ID, err)
+		}
+	}
+	for _, g := range grants {
+		if err = deleteOAuth2Grant(e, g.ID); err != nil {
+			return fmt.Errorf("deleteOAuth2Grant[%d]: %v", g.ID, err)
+		}
+	}
+	if _, err = e.Delete(&OAuth2Application{UID: u.Id}); err != nil {
+		return fmt.Errorf("delete OAuth2 applications: %v", err)
+	}
+	// ***** END: OAuth2 *****
+
 	// ***** START: PublicKey *****
 	keys := make([]*PublicKey, 0, 10)
 	if err = e.Find(&keys, &PublicKey{OwnerID: u.Id}); err != nil {
@@ -1028,57 +1309,94 @@ type Follow struct {
 	FollowID int64 `xorm:"UNIQUE(follow)"`
 }
 
+// IsFollowing returns true if user is following followID.
+func IsFollowing(userID, followID int64) bool {
+	has, _ := x.Get(&Follow{UserID: userID, FollowID: followID})
+	return has
+}
+
 // FollowUser marks someone be another's follower.
-func FollowUser(userId int64, followId int64) (err error) {
+func FollowUser(userID, followID int64) (err error) {
+	if userID == followID || IsFollowing(userID, followID) {
+		return nil
+	}
+
 	sess := x.NewSession()
-	defer sess.Close()
-	sess.Begin()
+	defer sessionRelease(sess)
+	if err = sess.Begin(); err != nil {
+		return err
+	}
 
-	if _, err = sess.Insert(&Follow{UserID: userId, FollowID: followId}); err != nil {
-		sess.Rollback()
+	if _, err = sess.Insert(&Follow{UserID: userID, FollowID: followID}); err != nil {
 		return err
 	}
 
-	rawSql := "UPDATE `user` SET num_followers = num_followers + 1 WHERE id = ?"
-	if _, err = sess.Exec(rawSql, followId); err != nil {
-		sess.Rollback()
+	if _, err = sess.Exec("UPDATE `user` SET num_followers = num_followers + 1 WHERE id = ?", followID); err != nil {
 		return err
 	}
 
-	rawSql = "UPDATE `user` SET num_followings = num_followings + 1 WHERE id = ?"
-	if _, err = sess.Exec(rawSql, userId); err != nil {
-		sess.Rollback()
+	if _, err = sess.Exec("UPDATE `user` SET num_followings = num_followings + 1 WHERE id = ?", userID); err != nil {
 		return err
 	}
 	return sess.Commit()
 }
 
 // UnFollowUser unmarks someone be another's follower.
-func UnFollowUser(userId int64, unFollowId int64) (err error) {
-	session := x.NewSession()
-	defer session.Close()
-	session.Begin()
+func UnFollowUser(userID, followID int64) (err error) {
+	if userID == followID || !IsFollowing(userID, followID) {
+		return nil
+	}
 
-	if _, err = session.Delete(&Follow{UserID: userId, FollowID: unFollowId}); err != nil {
-		session.Rollback()
+	sess := x.NewSession()
+	defer sessionRelease(sess)
+	if err = sess.Begin(); err != nil {
 		return err
 	}
 
-	rawSql := "UPDATE `user` SET num_followers = num_followers - 1 WHERE id = ?"
-	if _, err = session.Exec(rawSql, unFollowId); err != nil {
-		session.Rollback()
+	if _, err = sess.Delete(&Follow{UserID: userID, FollowID: followID}); err != nil {
 		return err
 	}
 
-	rawSql = "UPDATE `user` SET num_followings = num_followings - 1 WHERE id = ?"
-	if _, err = session.Exec(rawSql, userId); err != nil {
-		session.Rollback()
+	if _, err = sess.Exec("UPDATE `user` SET num_followers = num_followers - 1 WHERE id = ?", followID); err != nil {
 		return err
 	}
-	return session.Commit()
+
+	if _, err = sess.Exec("UPDATE `user` SET num_followings = num_followings - 1 WHERE id = ?", userID); err != nil {
+		return err
+	}
+	return sess.Commit()
+}
+
+// GetFollowers returns range of users who are following given user.
+func (u *User) GetFollowers(page int) ([]*User, error) {
+	users := make([]*User, 0, ItemsPerPage)
+	return users, x.Limit(ItemsPerPage, (page-1)*ItemsPerPage).
+		Where("follow.follow_id=?", u.Id).Join("LEFT", "follow", "user.id=follow.user_id").Find(&users)
+}
+
+// GetFollowing returns range of users who are followed by given user.
+func (u *User) GetFollowing(page int) ([]*User, error) {
+	users := make([]*User, 0, ItemsPerPage)
+	return users, x.Limit(ItemsPerPage, (page-1)*ItemsPerPage).
+		Where("follow.user_id=?", u.Id).Join("LEFT", "follow", "user.id=follow.follow_id").Find(&users)
+}
+
+func getFollowerIDs(e Engine, uid int64) ([]int64, error) {
+	follows := make([]*Follow, 0, 10)
+	if err := e.Find(&follows, &Follow{FollowID: uid}); err != nil {
+		return nil, err
+	}
+
+	ids := make([]int64, len(follows))
+	for i := range follows {
+		ids[i] = follows[i].UserID
+	}
+	return ids, nil
 }
 
-func UpdateMentions(userNames []string, issueId int64) error {
+// UpdateMentions marks users of given names as mentioned in issue and notifies them,
+// members of mentioned organizations are mentioned as well.
+func UpdateMentions(doer *User, userNames []string, issueId int64) error {
 	for i := range userNames {
 		userNames[i] = strings.ToLower(userNames[i])
 	}
@@ -1116,5 +1434,24 @@ func UpdateMentions(userNames []string, issueId int64) error {
 		return err
 	}
 
-	return nil
+	issue, err := GetIssueByID(issueId)
+	if err != nil {
+		return fmt.Errorf("GetIssueByID: %v", err)
+	}
+	repo, err := getRepositoryByID(x, issue.RepoID)
+	if err != nil {
+		return fmt.Errorf("getRepositoryByID: %v", err)
+	}
+
+	// Users who cannot read the repository are not notified.
+	uids := make([]int64, 0, len(ids))
+	for _, uid := range ids {
+		has, err := HasAccess(&User{Id: uid}, repo, ACCESS_MODE_READ)
+		if err != nil {
+			return fmt.Errorf("HasAccess: %v", err)
+		} else if has {
+			uids = append(uids, uid)
+		}
+	}
+	return notifyUsers(x, doer.Id, issue, uids, NOTIFICATION_REASON_MENTION)
 }
@@ -0,0 +1,310 @@
+// Copyright 2015 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"time"
+
+	"github.com/gogits/gogs/modules/log"
+	"github.com/gogits/gogs/modules/setting"
+	"github.com/gogits/gogs/modules/uuid"
+)
+
+// USER_EXPORT_LIFETIME is the duration that an archive of user data export is kept.
+const USER_EXPORT_LIFETIME = 24 * time.Hour
+
+type UserExportStatus int
+
+const (
+	USER_EXPORT_PENDING UserExportStatus = iota
+	USER_EXPORT_READY
+	USER_EXPORT_FAILED
+)
+
+func (s UserExportStatus) Name() string {
+	switch s {
+	case USER_EXPORT_PENDING:
+		return "pending"
+	case USER_EXPORT_READY:
+		return "ready"
+	case USER_EXPORT_FAILED:
+		return "failed"
+	}
+	return ""
+}
+
+// UserExport represents an export job of personal data of a user.
+type UserExport struct {
+	ID      int64  `xorm:"pk autoincr"`
+	UUID    string `xorm:"uuid UNIQUE"`
+	UID     int64  `xorm:"INDEX"`
+	DoerID  int64
+	Status  UserExportStatus
+	Error   string    `xorm:"TEXT"`
+	Created time.Time `xorm:"CREATED"`
+	Expires time.Time
+}
+
+// LocalPath returns where archive of export is stored.
+func (e *UserExport) LocalPath() string {
+	return path.Join(setting.AttachmentPath, "exports", e.UUID+".zip")
+}
+
+// IsExpired returns true if archive of export is no longer available.
+func (e *UserExport) IsExpired() bool {
+	return time.Now().After(e.Expires)
+}
+
+// NewUserExport creates a pending export job of given user's data requested by doer.
+func NewUserExport(u, doer *User) (*UserExport, error) {
+	e := &UserExport{
+		UUID:    uuid.NewV4().String(),
+		UID:     u.Id,
+		DoerID:  doer.Id,
+		Status:  USER_EXPORT_PENDING,
+		Expires: time.Now().Add(USER_EXPORT_LIFETIME),
+	}
+	if _, err := x.Insert(e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// GetUserExportByID returns export job of given user by ID.
+func GetUserExportByID(uid, id int64) (*UserExport, error) {
+	e := &UserExport{ID: id, UID: uid}
+	has, err := x.Get(e)
+	if err != nil {
+		return nil, err
+	} else if !has {
+		return nil, ErrUserExportNotExist{id}
+	}
+	return e, nil
+}
+
+func updateUserExport(e *UserExport) error {
+	_, err := x.Id(e.ID).AllCols().Update(e)
+	return err
+}
+
+// DeleteUserExport deletes export job and its archive.
+func DeleteUserExport(e *UserExport) error {
+	if _, err := x.Id(e.ID).Delete(new(UserExport)); err != nil {
+		return err
+	}
+	if err := os.Remove(e.LocalPath()); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("remove archive: %v", err)
+	}
+	return nil
+}
+
+type exportProfile struct {
+	ID           int64     `json:"id"`
+	Name         string    `json:"username"`
+	FullName     string    `json:"full_name"`
+	Email        string    `json:"email"`
+	Emails       []string  `json:"emails"`
+	Website      string    `json:"website"`
+	Location     string    `json:"location"`
+	Created      time.Time `json:"created_at"`
+	PublicKeys   []string  `json:"public_keys"`
+	NumFollowers int       `json:"followers"`
+	NumFollowing int       `json:"following"`
+}
+
+type exportRepository struct {
+	ID          int64     `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Website     string    `json:"website"`
+	Private     bool      `json:"private"`
+	Fork        bool      `json:"fork"`
+	Mirror      bool      `json:"mirror"`
+	Created     time.Time `json:"created_at"`
+	Updated     time.Time `json:"updated_at"`
+}
+
+type exportIssue struct {
+	ID      int64     `json:"id"`
+	RepoID  int64     `json:"repo_id"`
+	Index   int64     `json:"number"`
+	Title   string    `json:"title"`
+	Body    string    `json:"body"`
+	IsPull  bool      `json:"is_pull"`
+	Closed  bool      `json:"closed"`
+	Created time.Time `json:"created_at"`
+}
+
+type exportComment struct {
+	ID      int64     `json:"id"`
+	IssueID int64     `json:"issue_id"`
+	Body    string    `json:"body"`
+	Created time.Time `json:"created_at"`
+}
+
+func collectUserExportData(u *User) (map[string]interface{}, error) {
+	profile := &exportProfile{
+		ID:           u.Id,
+		Name:         u.Name,
+		FullName:     u.FullName,
+		Email:        u.Email,
+		Website:      u.Website,
+		Location:     u.Location,
+		Created:      u.Created,
+		NumFollowers: u.NumFollowers,
+		NumFollowing: u.NumFollowings,
+	}
+	emails, err := GetEmailAddresses(u.Id)
+	if err != nil {
+		return nil, fmt.Errorf("GetEmailAddresses: %v", err)
+	}
+	for i := range emails {
+		profile.Emails = append(profile.Emails, emails[i].Email)
+	}
+	keys, err := ListPublicKeys(u.Id)
+	if err != nil {
+		return nil, fmt.Errorf("ListPublicKeys: %v", err)
+	}
+	for i := range keys {
+		profile.PublicKeys = append(profile.PublicKeys, keys[i].Content)
+	}
+
+	repos, err := GetRepositories(u.Id, true)
+	if err != nil {
+		return nil, fmt.Errorf("GetRepositories: %v", err)
+	}
+	exportRepos := make([]*exportRepository, len(repos))
+	for i, repo := range repos {
+		exportRepos[i] = &exportRepository{
+			ID:          repo.ID,
+			Name:        repo.Name,
+			Description: repo.Description,
+			Website:     repo.Website,
+			Private:     repo.IsPrivate,
+			Fork:        repo.IsFork,
+			Mirror:      repo.IsMirror,
+			Created:     repo.Created,
+			Updated:     repo.Updated,
+		}
+	}
+
+	issues := make([]*Issue, 0, 10)
+	if err = x.Where("poster_id=?", u.Id).Asc("id").Find(&issues); err != nil {
+		return nil, fmt.Errorf("find issues: %v", err)
+	}
+	exportIssues := make([]*exportIssue, len(issues))
+	for i, issue := range issues {
+		exportIssues[i] = &exportIssue{
+			ID:      issue.ID,
+			RepoID:  issue.RepoID,
+			Index:   issue.Index,
+			Title:   issue.Name,
+			Body:    issue.Content,
+			IsPull:  issue.IsPull,
+			Closed:  issue.IsClosed,
+			Created: issue.Created,
+		}
+	}
+
+	comments := make([]*Comment, 0, 10)
+	if err = x.Where("poster_id=? AND type=?", u.Id, COMMENT_TYPE_COMMENT).Asc("id").Find(&comments); err != nil {
+		return nil, fmt.Errorf("find comments: %v", err)
+	}
+	exportComments := make([]*exportComment, len(comments))
+	for i, comment := range comments {
+		exportComments[i] = &exportComment{
+			ID:      comment.ID,
+			IssueID: comment.IssueID,
+			Body:    comment.Content,
+			Created: comment.Created,
+		}
+	}
+
+	return map[string]interface{}{
+		"profile.json":      profile,
+		"repositories.json": exportRepos,
+		"issues.json":       exportIssues,
+		"comments.json":     exportComments,
+	}, nil
+}
+
+func writeUserExportArchive(e *UserExport, u *User) error {
+	data, err := collectUserExportData(u)
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(path.Dir(e.LocalPath()), os.ModePerm); err != nil {
+		return fmt.Errorf("MkdirAll: %v", err)
+	}
+	fw, err := os.Create(e.LocalPath())
+	if err != nil {
+		return fmt.Errorf("Create: %v", err)
+	}
+	defer fw.Close()
+
+	zw := zip.NewWriter(fw)
+	for name, v := range data {
+		w, err := zw.Create(name)
+		if err != nil {
+			return fmt.Errorf("create %s: %v", name, err)
+		}
+		enc := json.NewEncoder(w)
+		if err = enc.Encode(v); err != nil {
+			return fmt.Errorf("encode %s: %v", name, err)
+		}
+	}
+	return zw.Close()
+}
+
+// ExportUserData assembles personal data of user into an archive,
+// and records result of export job.
+func ExportUserData(e *UserExport) error {
+	u, err := GetUserByID(e.UID)
+	if err != nil {
+		return fmt.Errorf("GetUserByID: %v", err)
+	}
+
+	if err = writeUserExportArchive(e, u); err != nil {
+		log.Error(4, "writeUserExportArchive[%d]: %v", e.ID, err)
+		os.Remove(e.LocalPath())
+		e.Status = USER_EXPORT_FAILED
+		e.Error = err.Error()
+	} else {
+		e.Status = USER_EXPORT_READY
+		e.Expires = time.Now().Add(USER_EXPORT_LIFETIME)
+	}
+	if err = updateUserExport(e); err != nil {
+		return fmt.Errorf("updateUserExport: %v", err)
+	}
+	return nil
+}
+
+// DeleteExpiredUserExports deletes all expired export jobs and their archives.
+func DeleteExpiredUserExports() {
+	if taskStatusPool.IsRunning(_DELETE_USER_EXPORTS) {
+		return
+	}
+	taskStatusPool.Start(_DELETE_USER_EXPORTS)
+	defer taskStatusPool.Stop(_DELETE_USER_EXPORTS)
+
+	log.Trace("Doing: DeleteExpiredUserExports")
+
+	exports := make([]*UserExport, 0, 10)
+	if err := x.Where("expires<?", time.Now()).Find(&exports); err != nil {
+		log.Error(4, "find expired exports: %v", err)
+		return
+	}
+	for _, e := range exports {
+		if err := DeleteUserExport(e); err != nil {
+			log.Error(4, "DeleteUserExport[%d]: %v", e.ID, err)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright 2015 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gogits/gogs/modules/setting"
+)
+
+// UserRedirect represents a former name of user or organization,
+// which redirects to the account that used to own it.
+type UserRedirect struct {
+	ID             int64     `xorm:"pk autoincr"`
+	LowerName      string    `xorm:"UNIQUE NOT NULL"`
+	RedirectUserID int64     `xorm:"INDEX"`
+	Created        time.Time `xorm:"CREATED"`
+}
+
+// LookupUserRedirect returns the user that given former name redirects to.
+func LookupUserRedirect(name string) (*User, error) {
+	redirect := &UserRedirect{LowerName: strings.ToLower(name)}
+	has, err := x.Get(redirect)
+	if err != nil {
+		return nil, err
+	} else if !has {
+		return nil, ErrUserNotExist{0, name}
+	}
+	return GetUserByID(redirect.RedirectUserID)
+}
+
+// GetUserByNameOrRedirect returns the user with given name,
+// or the user that given name redirects to if it is a former name.
+func GetUserByNameOrRedirect(name string) (*User, error) {
+	u, err := GetUserByName(name)
+	if IsErrUserNotExist(err) {
+		return LookupUserRedirect(name)
+	}
+	return u, err
+}
+
+// isNameRedirectedByOthers returns true if given name is a former name
+// of an account other than the user with given ID.
+func isNameRedirectedByOthers(uid int64, name string) (bool, error) {
+	return x.Where("lower_name=?", strings.ToLower(name)).And("redirect_user_id!=?", uid).Get(new(UserRedirect))
+}
+
+// newUserRedirect records old name of user as a redirect to the user,
+// and removes the redirect of new name in case user takes back a former name.
+func newUserRedirect(uid int64, oldName, newName string) (err error) {
+	sess := x.NewSession()
+	defer sessionRelease(sess)
+	if err = sess.Begin(); err != nil {
+		return err
+	}
+
+	if _, err = sess.Where("lower_name=?", strings.ToLower(newName)).Delete(new(UserRedirect)); err != nil {
+		return err
+	} else if _, err = sess.Where("lower_name=?", strings.ToLower(oldName)).Delete(new(UserRedirect)); err != nil {
+		return err
+	} else if _, err = sess.Insert(&UserRedirect{
+		LowerName:      strings.ToLower(oldName),
+		RedirectUserID: uid,
+	}); err != nil {
+		return err
+	}
+
+	return sess.Commit()
+}
+
+// NextNameChangeTime returns the earliest time that user is allowed to change name again.
+func (u *User) NextNameChangeTime() time.Time {
+	if u.NameChanged.IsZero() {
+		return u.NameChanged
+	}
+	return u.NameChanged.Add(setting.Service.UsernameChangeCooldown)
+}
+
+// CanChangeName returns true if user is allowed to change name by oneself.
+func (u *User) CanChangeName() bool {
+	return setting.Service.EnableUsernameChange && time.Now().After(u.NextNameChangeTime())
+}
@@ -0,0 +1,159 @@
+// Copyright 2015 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"time"
+
+	"github.com/gogits/gogs/modules/setting"
+)
+
+// USER_SESSION_TOUCH_INTERVAL is the minimum interval between updates of last seen time of a session.
+const USER_SESSION_TOUCH_INTERVAL = time.Minute
+
+// UserSession represents metadata of a signed in session of user,
+// session is considered as revoked once its record is deleted.
+type UserSession struct {
+	ID        int64  `xorm:"pk autoincr"`
+	UID       int64  `xorm:"INDEX"`
+	SID       string `xorm:"UNIQUE"`
+	IP        string
+	UserAgent string    `xorm:"TEXT"`
+	Created   time.Time `xorm:"CREATED"`
+	LastSeen  time.Time
+}
+
+// IsExpired returns true if session has been idle longer than session life time.
+func (s *UserSession) IsExpired() bool {
+	return time.Since(s.LastSeen) > time.Duration(setting.SessionConfig.Maxlifetime)*time.Second
+}
+
+// IsTimedOut returns true if session has been idle longer than idle timeout,
+// or lived longer than absolute timeout since sign in.
+func (s *UserSession) IsTimedOut() bool {
+	if setting.SessionIdleTimeout > 0 && time.Since(s.LastSeen) > setting.SessionIdleTimeout {
+		return true
+	}
+	return setting.SessionAbsoluteTimeout > 0 && time.Since(s.Created) > setting.SessionAbsoluteTimeout
+}
+
+// NewUserSession creates a record for session of given user.
+func NewUserSession(uid int64, sid, ip, userAgent string) (*UserSession, error) {
+	s := &UserSession{
+		UID:       uid,
+		SID:       sid,
+		IP:        ip,
+		UserAgent: userAgent,
+		LastSeen:  time.Now(),
+	}
+
+	sess := x.NewSession()
+	defer sessionRelease(sess)
+	if err := sess.Begin(); err != nil {
+		return nil, err
+	}
+
+	// Session ID may be reused by another user after sign out.
+	if _, err := sess.Where("sid=?", sid).Delete(new(UserSession)); err != nil {
+		return nil, err
+	} else if _, err = sess.Insert(s); err != nil {
+		return nil, err
+	}
+	return s, sess.Commit()
+}
+
+// GetUserSessionByID returns session record of given user by ID.
+func GetUserSessionByID(uid, id int64) (*UserSession, error) {
+	s := &UserSession{ID: id, UID: uid}
+	has, err := x.Get(s)
+	if err != nil {
+		return nil, err
+	} else if !has {
+		return nil, ErrUserSessionNotExist{id}
+	}
+	return s, nil
+}
+
+// GetUserSessionBySID returns record of session with given session ID.
+func GetUserSessionBySID(sid string) (*UserSession, error) {
+	s := new(UserSession)
+	has, err := x.Where("sid=?", sid).Get(s)
+	if err != nil {
+		return nil, err
+	} else if !has {
+		return nil, ErrUserSessionNotExist{}
+	}
+	return s, nil
+}
+
+// TouchUserSession updates last seen time and IP of session,
+// it only writes to database when previous update is old enough.
+func TouchUserSession(s *UserSession, ip string) error {
+	if time.Since(s.LastSeen) < USER_SESSION_TOUCH_INTERVAL && s.IP == ip {
+		return nil
+	}
+	s.LastSeen = time.Now()
+	s.IP = ip
+	_, err := x.Id(s.ID).Cols("last_seen", "ip").Update(s)
+	return err
+}
+
+// ListUserSessions returns all active sessions of given user,
+// and deletes records of expired ones.
+func ListUserSessions(uid int64) ([]*UserSession, error) {
+	sessions := make([]*UserSession, 0, 5)
+	if err := x.Where("uid=?", uid).Desc("last_seen").Find(&sessions); err != nil {
+		return nil, err
+	}
+
+	actives := make([]*UserSession, 0, len(sessions))
+	for _, s := range sessions {
+		if !s.IsExpired() && !s.IsTimedOut() {
+			actives = append(actives, s)
+			continue
+		}
+		if _, err := x.Id(s.ID).Delete(new(UserSession)); err != nil {
+			return nil, err
+		}
+	}
+	return actives, nil
+}
+
+// revokeUserSessions deletes session records matching given condition,
+// and invalidates remembered sign in of user so that revoked sessions
+// cannot be recovered by auto sign in.
+func revokeUserSessions(uid int64, cond string, args ...interface{}) error {
+	sess := x.NewSession()
+	defer sessionRelease(sess)
+	if err := sess.Begin(); err != nil {
+		return err
+	}
+
+	if _, err := sess.Where(cond, args...).Delete(new(UserSession)); err != nil {
+		return err
+	} else if _, err = sess.Id(uid).Cols("remember_salt").Update(&User{RememberSalt: GetUserSalt()}); err != nil {
+		return err
+	}
+	return sess.Commit()
+}
+
+// RevokeUserSession revokes session of given user by ID.
+func RevokeUserSession(uid, id int64) error {
+	if _, err := GetUserSessionByID(uid, id); err != nil {
+		return err
+	}
+	return revokeUserSessions(uid, "id=? AND uid=?", id, uid)
+}
+
+// RevokeOtherUserSessions revokes all sessions of given user except the one with given session ID.
+func RevokeOtherUserSessions(uid int64, sid string) error {
+	return revokeUserSessions(uid, "uid=? AND sid!=?", uid, sid)
+}
+
+// DeleteUserSessionBySID deletes record of session with given session ID, e.g. when user signs out.
+func DeleteUserSessionBySID(sid string) error {
+	_, err := x.Where("sid=?", sid).Delete(new(UserSession))
+	return err
+}
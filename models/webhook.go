@@ -18,6 +18,7 @@ import (
 
 	api "github.com/gogits/go-gogs-client"
 
+	"github.com/gogits/gogs/modules/git"
 	"github.com/gogits/gogs/modules/httplib"
 	"github.com/gogits/gogs/modules/log"
 	"github.com/gogits/gogs/modules/setting"
@@ -58,8 +59,12 @@ func IsValidHookContentType(name string) bool {
 }
 
 type HookEvents struct {
-	Create bool `json:"create"`
-	Push   bool `json:"push"`
+	Create      bool `json:"create"`
+	Push        bool `json:"push"`
+	PushTag     bool `json:"push_tag"`
+	PullRequest bool `json:"pull_request"`
+	Release     bool `json:"release"`
+	Wiki        bool `json:"wiki"`
 }
 
 // HookEvent represents events that will delivery hook.
@@ -96,6 +101,12 @@ type Webhook struct {
 	LastStatus   HookStatus // Last delivery status
 	Created      time.Time  `xorm:"CREATED"`
 	Updated      time.Time  `xorm:"UPDATED"`
+
+	// Custom payload template rendered with text/template,
+	// standard payload is sent when it is empty.
+	PayloadTemplate string `xorm:"TEXT"`
+	// Headers is JSON-encoded custom headers added to every delivery.
+	Headers string `xorm:"TEXT"`
 }
 
 func (w *Webhook) AfterSet(colName string, _ xorm.Cell) {
@@ -143,14 +154,50 @@ func (w *Webhook) HasPushEvent() bool {
 		(w.ChooseEvents && w.HookEvents.Push)
 }
 
+// HasPushTagEvent returns true if hook enabled push tag event.
+func (w *Webhook) HasPushTagEvent() bool {
+	return w.SendEverything ||
+		(w.ChooseEvents && w.HookEvents.PushTag)
+}
+
+// HasPullRequestEvent returns true if hook enabled pull request event.
+func (w *Webhook) HasPullRequestEvent() bool {
+	return w.SendEverything ||
+		(w.ChooseEvents && w.HookEvents.PullRequest)
+}
+
+// HasReleaseEvent returns true if hook enabled release event.
+func (w *Webhook) HasReleaseEvent() bool {
+	return w.SendEverything ||
+		(w.ChooseEvents && w.HookEvents.Release)
+}
+
+// HasWikiEvent returns true if hook enabled wiki event.
+func (w *Webhook) HasWikiEvent() bool {
+	return w.SendEverything ||
+		(w.ChooseEvents && w.HookEvents.Wiki)
+}
+
 func (w *Webhook) EventsArray() []string {
-	events := make([]string, 0, 2)
+	events := make([]string, 0, 4)
 	if w.HasCreateEvent() {
 		events = append(events, "create")
 	}
 	if w.HasPushEvent() {
 		events = append(events, "push")
 	}
+	if w.HasPushTagEvent() {
+		events = append(events, "push_tag")
+	}
+	if w.HasPullRequestEvent() {
+		events = append(events, "pull_request")
+	}
+	if w.HasReleaseEvent() {
+		events = append(events, "release")
+	}
+	if w.HasWikiEvent() {
+		events = append(events, "wiki")
+	}
 	return events
 }
 
@@ -262,10 +309,249 @@ func IsValidHookTaskType(name string) bool {
 type HookEventType string
 
 const (
-	HOOK_EVENT_CREATE HookEventType = "create"
-	HOOK_EVENT_PUSH   HookEventType = "push"
+	HOOK_EVENT_CREATE       HookEventType = "create"
+	HOOK_EVENT_PUSH         HookEventType = "push"
+	HOOK_EVENT_PUSH_TAG     HookEventType = "push_tag"
+	HOOK_EVENT_PULL_REQUEST HookEventType = "pull_request"
+	HOOK_EVENT_RELEASE      HookEventType = "release"
+	HOOK_EVENT_WIKI         HookEventType = "wiki"
+	HOOK_EVENT_PING         HookEventType = "ping"
+)
+
+// composePayloadRepo converts repository to webhook payload format,
+// owner of repository must be loaded.
+func composePayloadRepo(repo *Repository) *api.PayloadRepo {
+	return &api.PayloadRepo{
+		ID:          repo.ID,
+		Name:        repo.LowerName,
+		URL:         setting.AppUrl + repo.Owner.Name + "/" + repo.Name,
+		Description: repo.Description,
+		Website:     repo.Website,
+		Watchers:    repo.NumWatches,
+		Owner: &api.PayloadAuthor{
+			Name:     repo.Owner.DisplayName(),
+			Email:    repo.Owner.Email,
+			UserName: repo.Owner.Name,
+		},
+		Private: repo.IsPrivate,
+	}
+}
+
+// composePayloadUser converts user to webhook payload format.
+func composePayloadUser(u *User) *api.PayloadUser {
+	return &api.PayloadUser{
+		UserName:  u.Name,
+		ID:        u.Id,
+		AvatarUrl: setting.AppUrl + u.RelAvatarLink(),
+	}
+}
+
+// PingPayload represents the payload sent to test delivery of a webhook.
+type PingPayload struct {
+	Secret string           `json:"secret"`
+	HookID int64            `json:"hook_id"`
+	Repo   *api.PayloadRepo `json:"repository,omitempty"`
+	Sender *api.PayloadUser `json:"sender"`
+}
+
+func (p *PingPayload) SetSecret(secret string) {
+	p.Secret = secret
+}
+
+func (p *PingPayload) JSONPayload() ([]byte, error) {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return []byte{}, err
+	}
+	return data, nil
+}
+
+type HookTagAction string
+
+const (
+	HOOK_TAG_CREATED HookTagAction = "created"
+	HOOK_TAG_UPDATED HookTagAction = "updated"
+	HOOK_TAG_DELETED HookTagAction = "deleted"
+)
+
+// PushTagPayload represents the payload of push tag events,
+// which are only fired when tags are created or deleted by pushes.
+type PushTagPayload struct {
+	Secret  string           `json:"secret"`
+	Action  HookTagAction    `json:"action"`
+	Ref     string           `json:"ref"`
+	RefType string           `json:"ref_type"`
+	RefName string           `json:"ref_name"`
+	Before  string           `json:"before"`
+	After   string           `json:"after"`
+	Repo    *api.PayloadRepo `json:"repository"`
+	Sender  *api.PayloadUser `json:"sender"`
+}
+
+func (p *PushTagPayload) SetSecret(secret string) {
+	p.Secret = secret
+}
+
+func (p *PushTagPayload) JSONPayload() ([]byte, error) {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return []byte{}, err
+	}
+	return data, nil
+}
+
+// PreparePushTagWebhooks adds new webhooks to task queue for a tag created or deleted by push,
+// owner of repository must be loaded.
+func PreparePushTagWebhooks(repo *Repository, pusher *User, action HookTagAction, refFullName, oldCommitID, newCommitID string) error {
+	return PrepareWebhooks(repo, HOOK_EVENT_PUSH_TAG, &PushTagPayload{
+		Action:  action,
+		Ref:     refFullName,
+		RefType: "tag",
+		RefName: git.RefEndName(refFullName),
+		Before:  oldCommitID,
+		After:   newCommitID,
+		Repo:    composePayloadRepo(repo),
+		Sender:  composePayloadUser(pusher),
+	})
+}
+
+type HookPullRequestAction string
+
+const (
+	HOOK_ACTION_OPENED       HookPullRequestAction = "opened"
+	HOOK_ACTION_CLOSED       HookPullRequestAction = "closed"
+	HOOK_ACTION_REOPENED     HookPullRequestAction = "reopened"
+	HOOK_ACTION_SYNCHRONIZED HookPullRequestAction = "synchronized"
+	HOOK_ACTION_MERGED       HookPullRequestAction = "merged"
+)
+
+// PayloadPullRequest represents a pull request in webhook payload.
+type PayloadPullRequest struct {
+	ID         int64            `json:"id"`
+	Index      int64            `json:"number"`
+	Title      string           `json:"title"`
+	Body       string           `json:"body"`
+	URL        string           `json:"html_url"`
+	State      string           `json:"state"`
+	Poster     *api.PayloadUser `json:"user"`
+	HeadRepo   string           `json:"head_repo"`
+	HeadBranch string           `json:"head_branch"`
+	BaseBranch string           `json:"base_branch"`
+	Merged     bool             `json:"merged"`
+	// MergeCommitID is only presented in merged action.
+	MergeCommitID string `json:"merge_commit_sha,omitempty"`
+}
+
+// PullRequestPayload represents the payload of pull request events.
+type PullRequestPayload struct {
+	Secret      string                `json:"secret"`
+	Action      HookPullRequestAction `json:"action"`
+	Index       int64                 `json:"number"`
+	PullRequest *PayloadPullRequest   `json:"pull_request"`
+	Repo        *api.PayloadRepo      `json:"repository"`
+	Sender      *api.PayloadUser      `json:"sender"`
+}
+
+func (p *PullRequestPayload) SetSecret(secret string) {
+	p.Secret = secret
+}
+
+func (p *PullRequestPayload) JSONPayload() ([]byte, error) {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return []byte{}, err
+	}
+	return data, nil
+}
+
+type HookReleaseAction string
+
+const (
+	HOOK_ACTION_PUBLISHED HookReleaseAction = "published"
+	HOOK_ACTION_EDITED    HookReleaseAction = "edited"
+	HOOK_ACTION_DELETED   HookReleaseAction = "deleted"
+)
+
+// PayloadReleaseAsset represents a file attached to release in webhook payload.
+type PayloadReleaseAsset struct {
+	UUID        string `json:"uuid"`
+	Name        string `json:"name"`
+	DownloadURL string `json:"browser_download_url"`
+}
+
+// PayloadRelease represents a release in webhook payload.
+type PayloadRelease struct {
+	ID           int64                  `json:"id"`
+	TagName      string                 `json:"tag_name"`
+	Target       string                 `json:"target_commitish"`
+	Title        string                 `json:"name"`
+	Body         string                 `json:"body"`
+	URL          string                 `json:"html_url"`
+	IsDraft      bool                   `json:"draft"`
+	IsPrerelease bool                   `json:"prerelease"`
+	Publisher    *api.PayloadUser       `json:"author"`
+	Assets       []*PayloadReleaseAsset `json:"assets"`
+	Created      time.Time              `json:"created_at"`
+}
+
+// ReleasePayload represents the payload of release events.
+type ReleasePayload struct {
+	Secret  string            `json:"secret"`
+	Action  HookReleaseAction `json:"action"`
+	Release *PayloadRelease   `json:"release"`
+	Repo    *api.PayloadRepo  `json:"repository"`
+	Sender  *api.PayloadUser  `json:"sender"`
+}
+
+func (p *ReleasePayload) SetSecret(secret string) {
+	p.Secret = secret
+}
+
+func (p *ReleasePayload) JSONPayload() ([]byte, error) {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return []byte{}, err
+	}
+	return data, nil
+}
+
+type HookWikiAction string
+
+const (
+	HOOK_WIKI_CREATED HookWikiAction = "created"
+	HOOK_WIKI_EDITED  HookWikiAction = "edited"
+	HOOK_WIKI_DELETED HookWikiAction = "deleted"
 )
 
+// PayloadWikiPage represents a wiki page in webhook payload.
+type PayloadWikiPage struct {
+	Name     string `json:"page_name"`
+	URL      string `json:"html_url"`
+	Message  string `json:"message"`
+	CommitID string `json:"sha"`
+}
+
+// WikiPayload represents the payload of wiki events.
+type WikiPayload struct {
+	Secret string           `json:"secret"`
+	Action HookWikiAction   `json:"action"`
+	Page   *PayloadWikiPage `json:"page"`
+	Repo   *api.PayloadRepo `json:"repository"`
+	Sender *api.PayloadUser `json:"sender"`
+}
+
+func (p *WikiPayload) SetSecret(secret string) {
+	p.Secret = secret
+}
+
+func (p *WikiPayload) JSONPayload() ([]byte, error) {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return []byte{}, err
+	}
+	return data, nil
+}
+
 // HookRequest represents hook task request information.
 type HookRequest struct {
 	Headers map[string]string `json:"headers"`
@@ -295,6 +581,11 @@ type HookTask struct {
 	Delivered       int64
 	DeliveredString string `xorm:"-"`
 
+	// Retry info, attempt starts from 1 and each retry is a new task
+	// with same UUID, which is not delivered before next attempt time.
+	Attempt     int
+	NextAttempt int64 `xorm:"INDEX"`
+
 	// History info.
 	IsSucceed       bool
 	RequestContent  string        `xorm:"TEXT"`
@@ -363,6 +654,7 @@ func CreateHookTask(t *HookTask) error {
 	}
 	t.UUID = uuid.NewV4().String()
 	t.PayloadContent = string(data)
+	t.Attempt = 1
 	_, err = x.Insert(t)
 	return err
 }
@@ -408,16 +700,37 @@ func PrepareWebhooks(repo *Repository, event HookEventType, p api.Payloader) err
 			if !w.HasPushEvent() {
 				continue
 			}
+		case HOOK_EVENT_PUSH_TAG:
+			if !w.HasPushTagEvent() {
+				continue
+			}
+		case HOOK_EVENT_PULL_REQUEST:
+			if !w.HasPullRequestEvent() {
+				continue
+			}
+		case HOOK_EVENT_RELEASE:
+			if !w.HasReleaseEvent() {
+				continue
+			}
+		case HOOK_EVENT_WIKI:
+			if !w.HasWikiEvent() {
+				continue
+			}
 		}
 
+		// Keep original payload intact for other webhooks.
+		payloader := p
 		switch w.HookTaskType {
 		case SLACK:
-			p, err = GetSlackPayload(p, event, w.Meta)
+			payloader, err = GetSlackPayload(p, event, w.Meta)
 			if err != nil {
 				return fmt.Errorf("GetSlackPayload: %v", err)
 			}
 		default:
 			p.SetSecret(w.Secret)
+			if len(w.PayloadTemplate) > 0 {
+				payloader = &TemplatePayload{p, w.PayloadTemplate, event}
+			}
 		}
 
 		if err = CreateHookTask(&HookTask{
@@ -425,9 +738,9 @@ func PrepareWebhooks(repo *Repository, event HookEventType, p api.Payloader) err
 			HookID:      w.ID,
 			Type:        w.HookTaskType,
 			URL:         w.URL,
-			Payloader:   p,
+			Payloader:   payloader,
 			ContentType: w.ContentType,
-			EventType:   HOOK_EVENT_PUSH,
+			EventType:   event,
 			IsSSL:       w.IsSSL,
 		}); err != nil {
 			return fmt.Errorf("CreateHookTask: %v", err)
@@ -497,9 +810,24 @@ var HookQueue = NewUniqueQueue(setting.Webhook.QueueLength)
 func (t *HookTask) deliver() {
 	t.IsDelivered = true
 
+	// Webhook is loaded once for custom headers and last delivery status.
+	w, err := GetWebhookByID(t.HookID)
+	if err != nil {
+		log.Error(5, "GetWebhookByID: %v", err)
+	}
+
+	// Custom headers are set first so they never override the standard ones.
+	var headers map[string]string
+	if w != nil {
+		headers = w.GetHeaders()
+	}
+
 	timeout := time.Duration(setting.Webhook.DeliverTimeout) * time.Second
-	req := httplib.Post(t.URL).SetTimeout(timeout, timeout).
-		Header("X-Gogs-Delivery", t.UUID).
+	req := httplib.Post(t.URL).SetTimeout(timeout, timeout)
+	for name, value := range headers {
+		req.Header(name, value)
+	}
+	req = req.Header("X-Gogs-Delivery", t.UUID).
 		Header("X-Gogs-Event", string(t.EventType)).
 		SetTLSClientConfig(&tls.Config{InsecureSkipVerify: setting.Webhook.SkipTLSVerify})
 
@@ -507,7 +835,7 @@ func (t *HookTask) deliver() {
 	case JSON:
 		req = req.Header("Content-Type", "application/json").Body(t.PayloadContent)
 	case FORM:
-		req.Param("payload", t.PayloadContent)
+		req.Header("Content-Type", "application/x-www-form-urlencoded").Param("payload", t.PayloadContent)
 	}
 
 	// Record delivery information.
@@ -516,6 +844,9 @@ func (t *HookTask) deliver() {
 	}
 	for k, vals := range req.Headers() {
 		t.RequestInfo.Headers[k] = strings.Join(vals, ",")
+		if _, ok := headers[k]; ok && IsSecretHookHeader(k) {
+			t.RequestInfo.Headers[k] = HOOK_HEADER_MASK
+		}
 	}
 
 	t.ResponseInfo = &HookResponse{
@@ -528,10 +859,9 @@ func (t *HookTask) deliver() {
 			log.Trace("Hook delivered: %s", t.UUID)
 		}
 
-		// Update webhook last delivery status.
-		w, err := GetWebhookByID(t.HookID)
-		if err != nil {
-			log.Error(5, "GetWebhookByID: %v", err)
+		// Update webhook last delivery status, other columns may have been
+		// changed during delivery and must not be overwritten.
+		if w == nil {
 			return
 		}
 		if t.IsSucceed {
@@ -539,8 +869,8 @@ func (t *HookTask) deliver() {
 		} else {
 			w.LastStatus = HOOK_STATUS_FAILED
 		}
-		if err = UpdateWebhook(w); err != nil {
-			log.Error(5, "UpdateWebhook: %v", err)
+		if _, err := x.Id(w.ID).Cols("last_status").Update(w); err != nil {
+			log.Error(5, "Update webhook last status: %v", err)
 			return
 		}
 	}()
@@ -575,23 +905,152 @@ func (t *HookTask) deliver() {
 	}
 }
 
+// IsRetryable returns true if failed delivery may succeed by trying again,
+// which is when no response is received or server responds with error.
+// Client errors (4xx) are seen as permanent failures.
+func (t *HookTask) IsRetryable() bool {
+	if t.IsSucceed || t.ResponseInfo == nil {
+		return false
+	}
+	return t.ResponseInfo.Status == 0 || t.ResponseInfo.Status/100 == 5
+}
+
+// scheduleHookTask puts repository of hook task into queue at its next attempt time.
+func scheduleHookTask(t *HookTask) {
+	delay := time.Unix(t.NextAttempt, 0).Sub(time.Now())
+	if delay < 0 {
+		delay = 0
+	}
+	time.AfterFunc(delay, func() {
+		HookQueue.Add(t.RepoID)
+	})
+}
+
+// retryHookTask creates a new task to deliver payload of failed hook task again
+// with exponential backoff, until maximum attempts is reached.
+func retryHookTask(t *HookTask) {
+	if !t.IsRetryable() {
+		if !t.IsSucceed {
+			log.Warn("Hook delivery failed permanently[%s]: attempt %d", t.UUID, t.Attempt)
+		}
+		return
+	}
+
+	attempt := t.Attempt
+	if attempt == 0 {
+		attempt = 1
+	}
+	if attempt >= setting.Webhook.MaxAttempts {
+		log.Warn("Hook delivery failed after %d attempts[%s]", attempt, t.UUID)
+		return
+	}
+
+	backoff := time.Duration(setting.Webhook.RetryBackoff) * time.Second << uint(attempt-1)
+	// Shifting too many times overflows to non-positive value.
+	if max := time.Duration(setting.Webhook.MaxRetryBackoff) * time.Second; backoff > max || backoff <= 0 {
+		backoff = max
+	}
+	rt := &HookTask{
+		RepoID:         t.RepoID,
+		HookID:         t.HookID,
+		UUID:           t.UUID,
+		Type:           t.Type,
+		URL:            t.URL,
+		PayloadContent: t.PayloadContent,
+		ContentType:    t.ContentType,
+		EventType:      t.EventType,
+		IsSSL:          t.IsSSL,
+		Attempt:        attempt + 1,
+		NextAttempt:    time.Now().Add(backoff).Unix(),
+	}
+	if _, err := x.Insert(rt); err != nil {
+		log.Error(4, "Insert retry of hook task[%d]: %v", t.ID, err)
+		return
+	}
+	log.Trace("Hook delivery will be retried in %s[%s]: attempt %d", backoff, rt.UUID, rt.Attempt)
+	scheduleHookTask(rt)
+}
+
+// deliverHookTask delivers hook task, saves its result and retries on failure.
+func deliverHookTask(t *HookTask) {
+	t.deliver()
+	if err := UpdateHookTask(t); err != nil {
+		log.Error(4, "UpdateHookTask[%d]: %v", t.ID, err)
+		return
+	}
+	retryHookTask(t)
+}
+
+// TestDelivery sends a ping event with a sample payload to the webhook
+// immediately, and returns the delivered hook task with response information.
+func (w *Webhook) TestDelivery(doer *User, repo *Repository) (*HookTask, error) {
+	p := &PingPayload{
+		HookID: w.ID,
+		Sender: composePayloadUser(doer),
+	}
+	if repo != nil {
+		if err := repo.GetOwner(); err != nil {
+			return nil, fmt.Errorf("GetOwner: %v", err)
+		}
+		p.Repo = composePayloadRepo(repo)
+	}
+
+	var (
+		payloader api.Payloader = p
+		err       error
+	)
+	switch w.HookTaskType {
+	case SLACK:
+		payloader, err = GetSlackPayload(p, HOOK_EVENT_PING, w.Meta)
+		if err != nil {
+			return nil, fmt.Errorf("GetSlackPayload: %v", err)
+		}
+	default:
+		p.SetSecret(w.Secret)
+		if len(w.PayloadTemplate) > 0 {
+			payloader = &TemplatePayload{p, w.PayloadTemplate, HOOK_EVENT_PING}
+		}
+	}
+
+	t := &HookTask{
+		RepoID:      w.RepoID,
+		HookID:      w.ID,
+		Type:        w.HookTaskType,
+		URL:         w.URL,
+		Payloader:   payloader,
+		ContentType: w.ContentType,
+		EventType:   HOOK_EVENT_PING,
+		IsSSL:       w.IsSSL,
+	}
+	if err = CreateHookTask(t); err != nil {
+		return nil, fmt.Errorf("CreateHookTask: %v", err)
+	}
+
+	t.deliver()
+	if err = UpdateHookTask(t); err != nil {
+		return nil, fmt.Errorf("UpdateHookTask: %v", err)
+	}
+	return t, nil
+}
+
 // DeliverHooks checks and delivers undelivered hooks.
 // TODO: shoot more hooks at same time.
 func DeliverHooks() {
 	tasks := make([]*HookTask, 0, 10)
 	x.Where("is_delivered=?", false).Iterate(new(HookTask),
 		func(idx int, bean interface{}) error {
-			t := bean.(*HookTask)
-			t.deliver()
-			tasks = append(tasks, t)
+			tasks = append(tasks, bean.(*HookTask))
 			return nil
 		})
 
-	// Update hook task status.
+	// Deliver due tasks and schedule retries left from last run.
+	now := time.Now().Unix()
 	for _, t := range tasks {
-		if err := UpdateHookTask(t); err != nil {
-			log.Error(4, "UpdateHookTask(%d): %v", t.ID, err)
+		if t.NextAttempt > now {
+			scheduleHookTask(t)
+			continue
 		}
+		deliverHookTask(t)
 	}
 
 	// Start listening on new hook requests.
@@ -600,16 +1059,12 @@ func DeliverHooks() {
 		HookQueue.Remove(repoID)
 
 		tasks = make([]*HookTask, 0, 5)
-		if err := x.Where("repo_id=? AND is_delivered=?", repoID, false).Find(&tasks); err != nil {
+		if err := x.Where("repo_id=? AND is_delivered=? AND next_attempt<=?", repoID, false, time.Now().Unix()).Find(&tasks); err != nil {
 			log.Error(4, "Get repository(%d) hook tasks: %v", repoID, err)
 			continue
 		}
 		for _, t := range tasks {
-			t.deliver()
-			if err := UpdateHookTask(t); err != nil {
-				log.Error(4, "UpdateHookTask[%d]: %v", t.ID, err)
-				continue
-			}
+			deliverHookTask(t)
 		}
 	}
 }
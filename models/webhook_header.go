@@ -0,0 +1,159 @@
+// Copyright 2015 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gogits/gogs/modules/log"
+)
+
+const (
+	// HOOK_HEADER_MASK replaces values of secret-looking custom headers in read responses,
+	// submitting it back keeps the stored value unchanged.
+	HOOK_HEADER_MASK = "********"
+
+	_MAX_HOOK_HEADERS         = 20
+	_MAX_HOOK_HEADER_VAL_SIZE = 1024
+)
+
+var (
+	hookHeaderNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9-]*$`)
+
+	// reservedHookHeaders are set by Gogs for every delivery and cannot be overridden.
+	reservedHookHeaders = map[string]bool{
+		"Content-Type":      true,
+		"Content-Length":    true,
+		"Content-Encoding":  true,
+		"Host":              true,
+		"Transfer-Encoding": true,
+		"Connection":        true,
+	}
+
+	// secretHookHeaderWords are parts of header names whose values are seen as secrets.
+	secretHookHeaderWords = []string{"auth", "token", "key", "secret", "pass", "cookie", "session", "signature", "credential"}
+)
+
+// IsSecretHookHeader returns true if value of header with given name looks like a secret.
+func IsSecretHookHeader(name string) bool {
+	name = strings.ToLower(name)
+	for _, word := range secretHookHeaderWords {
+		if strings.Contains(name, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateHookHeader checks if custom header is allowed to be sent with deliveries.
+func validateHookHeader(name, value string) error {
+	switch {
+	case !hookHeaderNamePattern.MatchString(name):
+		return ErrInvalidHookHeader{name, "name can only contain letters, digits and dashes"}
+	case reservedHookHeaders[name] || strings.HasPrefix(name, "X-Gogs-"):
+		return ErrInvalidHookHeader{name, "header is reserved"}
+	case strings.ContainsAny(value, "\r\n"):
+		return ErrInvalidHookHeader{name, "value cannot contain line breaks"}
+	case len(value) > _MAX_HOOK_HEADER_VAL_SIZE:
+		return ErrInvalidHookHeader{name, "value is too long"}
+	}
+	return nil
+}
+
+// GetHeaders returns custom headers of webhook.
+func (w *Webhook) GetHeaders() map[string]string {
+	headers := make(map[string]string)
+	if len(w.Headers) == 0 {
+		return headers
+	}
+	if err := json.Unmarshal([]byte(w.Headers), &headers); err != nil {
+		log.Error(4, "webhook.GetHeaders(%d): %v", w.ID, err)
+	}
+	return headers
+}
+
+// MaskedHeaders returns custom headers of webhook with values of secret-looking ones masked.
+func (w *Webhook) MaskedHeaders() map[string]string {
+	headers := w.GetHeaders()
+	for name := range headers {
+		if IsSecretHookHeader(name) {
+			headers[name] = HOOK_HEADER_MASK
+		}
+	}
+	return headers
+}
+
+// MaskedHeadersText returns masked custom headers of webhook
+// in the form of "Name: Value" per line, sorted by name.
+func (w *Webhook) MaskedHeadersText() string {
+	headers := w.MaskedHeaders()
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	lines := make([]string, len(names))
+	for i, name := range names {
+		lines[i] = name + ": " + headers[name]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// SetHeaders validates and replaces custom headers of webhook,
+// stored value is kept for a header whose value is submitted as the mask.
+func (w *Webhook) SetHeaders(headers map[string]string) error {
+	if len(headers) > _MAX_HOOK_HEADERS {
+		return ErrInvalidHookHeader{"", "too many headers"}
+	}
+
+	old := w.GetHeaders()
+	newHeaders := make(map[string]string, len(headers))
+	for name, value := range headers {
+		name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+		value = strings.TrimSpace(value)
+		if value == HOOK_HEADER_MASK {
+			if oldValue, ok := old[name]; ok {
+				value = oldValue
+			}
+		}
+		if err := validateHookHeader(name, value); err != nil {
+			return err
+		}
+		newHeaders[name] = value
+	}
+
+	if len(newHeaders) == 0 {
+		w.Headers = ""
+		return nil
+	}
+	data, err := json.Marshal(newHeaders)
+	if err != nil {
+		return err
+	}
+	w.Headers = string(data)
+	return nil
+}
+
+// ParseHookHeaders parses custom headers in the form of "Name: Value" per line.
+func ParseHookHeaders(text string) (map[string]string, error) {
+	headers := make(map[string]string)
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		idx := strings.Index(line, ":")
+		if idx == -1 {
+			return nil, ErrInvalidHookHeader{line, "header must be in the form of 'Name: Value'"}
+		}
+		headers[strings.TrimSpace(line[:idx])] = strings.TrimSpace(line[idx+1:])
+	}
+	return headers, nil
+}
@@ -122,6 +122,114 @@ func getSlackPushPayload(p *api.PushPayload, slack *SlackMeta) (*SlackPayload, e
 	}, nil
 }
 
+func getSlackPushTagPayload(p *PushTagPayload, slack *SlackMeta) (*SlackPayload, error) {
+	repoLink := SlackLinkFormatter(p.Repo.URL, p.Repo.Name)
+	tagLink := SlackLinkFormatter(p.Repo.URL+"/src/"+p.RefName, p.RefName)
+	if p.Action == HOOK_TAG_DELETED {
+		tagLink = p.RefName
+	}
+	text := fmt.Sprintf("[%s] Tag %s: %s by %s", repoLink, p.Action, tagLink, p.Sender.UserName)
+
+	return &SlackPayload{
+		Channel:  slack.Channel,
+		Text:     text,
+		Username: slack.Username,
+		IconURL:  slack.IconURL,
+	}, nil
+}
+
+func getSlackPullRequestPayload(p *PullRequestPayload, slack *SlackMeta) (*SlackPayload, error) {
+	title := fmt.Sprintf("#%d %s", p.Index, p.PullRequest.Title)
+	titleLink := SlackLinkFormatter(p.PullRequest.URL, title)
+	repoLink := SlackLinkFormatter(p.Repo.URL, p.Repo.Name)
+
+	var text string
+	switch p.Action {
+	case HOOK_ACTION_SYNCHRONIZED:
+		text = fmt.Sprintf("[%s] Pull request synchronized: %s by %s", repoLink, titleLink, p.Sender.UserName)
+	case HOOK_ACTION_MERGED:
+		text = fmt.Sprintf("[%s] Pull request merged: %s by %s", repoLink, titleLink, p.Sender.UserName)
+		if len(p.PullRequest.MergeCommitID) >= 7 {
+			text += fmt.Sprintf(" (%s)", SlackLinkFormatter(p.Repo.URL+"/commit/"+p.PullRequest.MergeCommitID, p.PullRequest.MergeCommitID[:7]))
+		}
+	default:
+		text = fmt.Sprintf("[%s] Pull request %s: %s by %s", repoLink, p.Action, titleLink, p.Sender.UserName)
+	}
+
+	var attachments []SlackAttachment
+	if p.Action == HOOK_ACTION_OPENED && len(p.PullRequest.Body) > 0 {
+		attachments = []SlackAttachment{{Color: slack.Color, Text: SlackTextFormatter(p.PullRequest.Body)}}
+	}
+
+	return &SlackPayload{
+		Channel:     slack.Channel,
+		Text:        text,
+		Username:    slack.Username,
+		IconURL:     slack.IconURL,
+		Attachments: attachments,
+	}, nil
+}
+
+func getSlackReleasePayload(p *ReleasePayload, slack *SlackMeta) (*SlackPayload, error) {
+	repoLink := SlackLinkFormatter(p.Repo.URL, p.Repo.Name)
+	title := p.Release.TagName
+	if len(p.Release.Title) > 0 {
+		title = p.Release.Title
+	}
+
+	var text string
+	switch p.Action {
+	case HOOK_ACTION_DELETED:
+		text = fmt.Sprintf("[%s] Release deleted: %s by %s", repoLink, title, p.Sender.UserName)
+	default:
+		text = fmt.Sprintf("[%s] Release %s: %s by %s", repoLink, p.Action,
+			SlackLinkFormatter(p.Release.URL, title), p.Sender.UserName)
+	}
+
+	var attachments []SlackAttachment
+	if p.Action == HOOK_ACTION_PUBLISHED && len(p.Release.Body) > 0 {
+		attachments = []SlackAttachment{{Color: slack.Color, Text: SlackTextFormatter(p.Release.Body)}}
+	}
+
+	return &SlackPayload{
+		Channel:     slack.Channel,
+		Text:        text,
+		Username:    slack.Username,
+		IconURL:     slack.IconURL,
+		Attachments: attachments,
+	}, nil
+}
+
+func getSlackWikiPayload(p *WikiPayload, slack *SlackMeta) (*SlackPayload, error) {
+	repoLink := SlackLinkFormatter(p.Repo.URL, p.Repo.Name)
+	pageLink := SlackLinkFormatter(p.Page.URL, p.Page.Name)
+	if p.Action == HOOK_WIKI_DELETED {
+		pageLink = p.Page.Name
+	}
+	text := fmt.Sprintf("[%s] Wiki page %s: %s by %s", repoLink, p.Action, pageLink, p.Sender.UserName)
+
+	return &SlackPayload{
+		Channel:  slack.Channel,
+		Text:     text,
+		Username: slack.Username,
+		IconURL:  slack.IconURL,
+	}, nil
+}
+
+func getSlackPingPayload(p *PingPayload, slack *SlackMeta) (*SlackPayload, error) {
+	text := fmt.Sprintf("Test delivery triggered by %s", p.Sender.UserName)
+	if p.Repo != nil {
+		text = fmt.Sprintf("[%s] %s", SlackLinkFormatter(p.Repo.URL, p.Repo.Name), text)
+	}
+
+	return &SlackPayload{
+		Channel:  slack.Channel,
+		Text:     text,
+		Username: slack.Username,
+		IconURL:  slack.IconURL,
+	}, nil
+}
+
 func GetSlackPayload(p api.Payloader, event HookEventType, meta string) (*SlackPayload, error) {
 	s := new(SlackPayload)
 
@@ -135,6 +243,16 @@ func GetSlackPayload(p api.Payloader, event HookEventType, meta string) (*SlackP
 		return getSlackCreatePayload(p.(*api.CreatePayload), slack)
 	case HOOK_EVENT_PUSH:
 		return getSlackPushPayload(p.(*api.PushPayload), slack)
+	case HOOK_EVENT_PUSH_TAG:
+		return getSlackPushTagPayload(p.(*PushTagPayload), slack)
+	case HOOK_EVENT_PULL_REQUEST:
+		return getSlackPullRequestPayload(p.(*PullRequestPayload), slack)
+	case HOOK_EVENT_RELEASE:
+		return getSlackReleasePayload(p.(*ReleasePayload), slack)
+	case HOOK_EVENT_WIKI:
+		return getSlackWikiPayload(p.(*WikiPayload), slack)
+	case HOOK_EVENT_PING:
+		return getSlackPingPayload(p.(*PingPayload), slack)
 	}
 
 	return s, nil
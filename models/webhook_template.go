@@ -0,0 +1,104 @@
+// Copyright 2015 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+
+	api "github.com/gogits/go-gogs-client"
+
+	"github.com/gogits/gogs/modules/log"
+)
+
+// _PAYLOAD_TEMPLATE_MAX_SIZE is the maximum size of rendered custom payload.
+const _PAYLOAD_TEMPLATE_MAX_SIZE = 1 << 20
+
+// payloadTemplateFuncs is the only set of functions available to payload templates
+// besides builtin ones of text/template, none of them has access to filesystem or network.
+var payloadTemplateFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+	"lower":     strings.ToLower,
+	"upper":     strings.ToUpper,
+	"trim":      strings.TrimSpace,
+	"replace":   strings.Replace,
+	"contains":  strings.Contains,
+	"hasPrefix": strings.HasPrefix,
+	"hasSuffix": strings.HasSuffix,
+	"join":      strings.Join,
+}
+
+// PayloadTemplateData represents the data passed to payload templates.
+// Payload is converted to plain JSON values so templates use the same field names
+// as standard payload, and cannot call any method of internal types.
+type PayloadTemplateData struct {
+	Event   string
+	Payload map[string]interface{}
+}
+
+// ParsePayloadTemplate parses and validates custom payload template.
+func ParsePayloadTemplate(tpl string) (*template.Template, error) {
+	return template.New("payload").Funcs(payloadTemplateFuncs).Option("missingkey=zero").Parse(tpl)
+}
+
+// limitedBuffer is a buffer returns error when it is written more than limit.
+type limitedBuffer struct {
+	bytes.Buffer
+	limit int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.Len()+len(p) > b.limit {
+		return 0, errors.New("rendered payload is too large")
+	}
+	return b.Buffer.Write(p)
+}
+
+// RenderPayloadTemplate renders custom payload with given template and standard payload.
+func RenderPayloadTemplate(tpl string, event HookEventType, p api.Payloader) ([]byte, error) {
+	t, err := ParsePayloadTemplate(tpl)
+	if err != nil {
+		return nil, fmt.Errorf("ParsePayloadTemplate: %v", err)
+	}
+
+	data, err := p.JSONPayload()
+	if err != nil {
+		return nil, fmt.Errorf("JSONPayload: %v", err)
+	}
+	ctx := &PayloadTemplateData{Event: string(event)}
+	if err = json.Unmarshal(data, &ctx.Payload); err != nil {
+		return nil, fmt.Errorf("Unmarshal: %v", err)
+	}
+
+	buf := &limitedBuffer{limit: _PAYLOAD_TEMPLATE_MAX_SIZE}
+	if err = t.Execute(buf, ctx); err != nil {
+		return nil, fmt.Errorf("Execute: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// TemplatePayload represents a payload rendered by custom template of webhook,
+// it falls back to standard payload when template fails to render.
+type TemplatePayload struct {
+	api.Payloader
+	Template string
+	Event    HookEventType
+}
+
+func (p *TemplatePayload) JSONPayload() ([]byte, error) {
+	data, err := RenderPayloadTemplate(p.Template, p.Event, p.Payloader)
+	if err != nil {
+		log.Warn("Failed to render custom payload, fallback to standard payload: %v", err)
+		return p.Payloader.JSONPayload()
+	}
+	return data, nil
+}
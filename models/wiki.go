@@ -17,6 +17,7 @@ import (
 
 	"github.com/gogits/git-shell"
 
+	oldgit "github.com/gogits/gogs/modules/git"
 	"github.com/gogits/gogs/modules/setting"
 )
 
@@ -114,13 +115,11 @@ func (repo *Repository) UpdateLocalWiki() error {
 	return updateLocalCopy(repo.WikiPath(), repo.LocalWikiPath())
 }
 
-// updateWikiPage adds new page to repository wiki.
-func (repo *Repository) updateWikiPage(doer *User, oldTitle, title, content, message string, isNew bool) (err error) {
-	wikiWorkingPool.CheckIn(com.ToStr(repo.ID))
-	defer wikiWorkingPool.CheckOut(com.ToStr(repo.ID))
-
-	if err = repo.InitWiki(); err != nil {
-		return fmt.Errorf("InitWiki: %v", err)
+// prepareLocalWiki makes sure the local copy of repository wiki is
+// initialized and up-to-date with remote, and returns its path.
+func (repo *Repository) prepareLocalWiki() (string, error) {
+	if err := repo.InitWiki(); err != nil {
+		return "", fmt.Errorf("InitWiki: %v", err)
 	}
 
 	localPath := repo.LocalWikiPath()
@@ -129,14 +128,47 @@ func (repo *Repository) updateWikiPage(doer *User, oldTitle, title, content, mes
 	if com.IsExist(localPath) {
 		// No need to check if nothing in the repository.
 		if git.IsBranchExist(localPath, "master") {
-			if err = git.ResetHEAD(localPath, true, "origin/master"); err != nil {
-				return fmt.Errorf("Reset: %v", err)
+			if err := git.ResetHEAD(localPath, true, "origin/master"); err != nil {
+				return "", fmt.Errorf("Reset: %v", err)
 			}
 		}
 	}
 
-	if err = repo.UpdateLocalWiki(); err != nil {
-		return fmt.Errorf("UpdateLocalWiki: %v", err)
+	if err := repo.UpdateLocalWiki(); err != nil {
+		return "", fmt.Errorf("UpdateLocalWiki: %v", err)
+	}
+	return localPath, nil
+}
+
+// commitLocalWiki commits all changes in local copy of repository wiki,
+// pushes them to remote and returns ID of the new commit.
+func commitLocalWiki(doer *User, localPath, message string) (string, error) {
+	if err := git.AddChanges(localPath, true); err != nil {
+		return "", fmt.Errorf("AddChanges: %v", err)
+	} else if err = git.CommitChanges(localPath, message, doer.NewGitSig()); err != nil {
+		return "", fmt.Errorf("CommitChanges: %v", err)
+	} else if err = git.Push(localPath, "origin", "master"); err != nil {
+		return "", fmt.Errorf("Push: %v", err)
+	}
+
+	stdout, stderr, err := oldgit.ExecDir(-1, localPath,
+		fmt.Sprintf("commitLocalWiki(git rev-parse): %s", localPath),
+		"rev-parse", "HEAD")
+	if err != nil {
+		return "", fmt.Errorf("rev-parse: %s", stderr)
+	}
+	return strings.TrimSpace(stdout), nil
+}
+
+// updateWikiPage adds new page to repository wiki, and returns ID of
+// the commit that contains the change along with its commit message.
+func (repo *Repository) updateWikiPage(doer *User, oldTitle, title, content, message string, isNew bool) (commitID, commitMsg string, err error) {
+	wikiWorkingPool.CheckIn(com.ToStr(repo.ID))
+	defer wikiWorkingPool.CheckOut(com.ToStr(repo.ID))
+
+	localPath, err := repo.prepareLocalWiki()
+	if err != nil {
+		return "", "", err
 	}
 
 	title = ToWikiPageName(strings.Replace(title, "/", " ", -1))
@@ -145,34 +177,84 @@ func (repo *Repository) updateWikiPage(doer *User, oldTitle, title, content, mes
 	// If not a new file, show perform update not create.
 	if isNew {
 		if com.IsExist(filename) {
-			return ErrWikiAlreadyExist{filename}
+			return "", "", ErrWikiAlreadyExist{filename}
 		}
 	} else {
 		os.Remove(path.Join(localPath, oldTitle+".md"))
 	}
 
 	if err = ioutil.WriteFile(filename, []byte(content), 0666); err != nil {
-		return fmt.Errorf("WriteFile: %v", err)
+		return "", "", fmt.Errorf("WriteFile: %v", err)
 	}
 
 	if len(message) == 0 {
 		message = "Update page '" + title + "'"
 	}
-	if err = git.AddChanges(localPath, true); err != nil {
-		return fmt.Errorf("AddChanges: %v", err)
-	} else if err = git.CommitChanges(localPath, message, doer.NewGitSig()); err != nil {
-		return fmt.Errorf("CommitChanges: %v", err)
-	} else if err = git.Push(localPath, "origin", "master"); err != nil {
-		return fmt.Errorf("Push: %v", err)
+	commitID, err = commitLocalWiki(doer, localPath, message)
+	if err != nil {
+		return "", "", err
 	}
-
-	return nil
+	return commitID, message, nil
 }
 
-func (repo *Repository) AddWikiPage(doer *User, title, content, message string) error {
+func (repo *Repository) AddWikiPage(doer *User, title, content, message string) (string, string, error) {
 	return repo.updateWikiPage(doer, "", title, content, message, true)
 }
 
-func (repo *Repository) EditWikiPage(doer *User, oldTitle, title, content, message string) error {
+func (repo *Repository) EditWikiPage(doer *User, oldTitle, title, content, message string) (string, string, error) {
 	return repo.updateWikiPage(doer, oldTitle, title, content, message, false)
 }
+
+// DeleteWikiPage deletes given page from repository wiki, and returns ID of
+// the commit that contains the change along with its commit message.
+func (repo *Repository) DeleteWikiPage(doer *User, title string) (commitID, commitMsg string, err error) {
+	wikiWorkingPool.CheckIn(com.ToStr(repo.ID))
+	defer wikiWorkingPool.CheckOut(com.ToStr(repo.ID))
+
+	localPath, err := repo.prepareLocalWiki()
+	if err != nil {
+		return "", "", err
+	}
+
+	title = ToWikiPageName(strings.Replace(title, "/", " ", -1))
+	filename := path.Join(localPath, title+".md")
+	if !com.IsFile(filename) {
+		return "", "", ErrWikiNotExist{title}
+	}
+	if err = os.Remove(filename); err != nil {
+		return "", "", fmt.Errorf("Remove: %v", err)
+	}
+
+	commitMsg = "Delete page '" + title + "'"
+	commitID, err = commitLocalWiki(doer, localPath, commitMsg)
+	if err != nil {
+		return "", "", err
+	}
+	return commitID, commitMsg, nil
+}
+
+// PrepareWikiWebhooks adds wiki webhooks of given action on page to task queue.
+func (repo *Repository) PrepareWikiWebhooks(doer *User, action HookWikiAction, title, message, commitID string) error {
+	if err := repo.GetOwner(); err != nil {
+		return fmt.Errorf("GetOwner: %v", err)
+	}
+
+	title = ToWikiPageName(strings.Replace(title, "/", " ", -1))
+	p := &WikiPayload{
+		Action: action,
+		Page: &PayloadWikiPage{
+			Name:     title,
+			URL:      fmt.Sprintf("%s%s/%s/wiki/%s", setting.AppUrl, repo.Owner.Name, repo.Name, ToWikiPageURL(title)),
+			Message:  message,
+			CommitID: commitID,
+		},
+		Repo:   composePayloadRepo(repo),
+		Sender: composePayloadUser(doer),
+	}
+	if err := PrepareWebhooks(repo, HOOK_EVENT_WIKI, p); err != nil {
+		return fmt.Errorf("PrepareWebhooks: %v", err)
+	}
+
+	go HookQueue.Add(repo.ID)
+	return nil
+}
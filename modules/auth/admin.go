@@ -26,6 +26,7 @@ func (f *AdminCrateUserForm) Validate(ctx *macaron.Context, errs binding.Errors)
 type AdminEditUserForm struct {
 	LoginType        string `binding:"Required"`
 	LoginName        string
+	UserName         string `binding:"Required;AlphaDashDot;MaxSize(35)"`
 	FullName         string `binding:"MaxSize(100)"`
 	Email            string `binding:"Required;Email;MaxSize(254)"`
 	Password         string `binding:"MaxSize(255)"`
@@ -35,6 +36,8 @@ type AdminEditUserForm struct {
 	Admin            bool
 	AllowGitHook     bool
 	AllowImportLocal bool
+	StorageQuota     int64
+	MaxRepoCreation  int
 }
 
 func (f *AdminEditUserForm) Validate(ctx *macaron.Context, errs binding.Errors) binding.Errors {
@@ -7,7 +7,6 @@ package auth
 import (
 	"reflect"
 	"strings"
-	"time"
 
 	"github.com/Unknwon/com"
 	"github.com/go-macaron/binding"
@@ -25,8 +24,9 @@ func IsAPIPath(url string) bool {
 	return strings.HasPrefix(url, "/api/")
 }
 
-// SignedInID returns the id of signed in user.
-func SignedInID(ctx *macaron.Context, sess session.Store) int64 {
+// SignedInID returns the id of signed in user,
+// remoteIP is the client address recorded by session.
+func SignedInID(ctx *macaron.Context, sess session.Store, remoteIP string) int64 {
 	if !models.HasEngine {
 		return 0
 	}
@@ -39,7 +39,8 @@ func SignedInID(ctx *macaron.Context, sess session.Store) int64 {
 			auHead := ctx.Req.Header.Get("Authorization")
 			if len(auHead) > 0 {
 				auths := strings.Fields(auHead)
-				if len(auths) == 2 && auths[0] == "token" {
+				// Tokens issued through OAuth2 are usually sent as bearer tokens.
+				if len(auths) == 2 && (auths[0] == "token" || strings.ToLower(auths[0]) == "bearer") {
 					tokenSHA = auths[1]
 				}
 			}
@@ -54,10 +55,7 @@ func SignedInID(ctx *macaron.Context, sess session.Store) int64 {
 				}
 				return 0
 			}
-			t.Updated = time.Now()
-			if err = models.UpdateAccessToekn(t); err != nil {
-				log.Error(4, "UpdateAccessToekn: %v", err)
-			}
+			ctx.Data["AccessToken"] = t
 			return t.UID
 		}
 	}
@@ -73,19 +71,79 @@ func SignedInID(ctx *macaron.Context, sess session.Store) int64 {
 			}
 			return 0
 		}
+		if !isValidUserSession(ctx, sess, id, remoteIP) {
+			return 0
+		}
 		return id
 	}
 	return 0
 }
 
-// SignedInUser returns the user object of signed user.
-// It returns a bool value to indicate whether user uses basic auth or not.
-func SignedInUser(ctx *macaron.Context, sess session.Store) (*models.User, bool) {
+func signOutSession(sess session.Store) {
+	sess.Delete("uid")
+	sess.Delete("uname")
+	sess.Delete("sessionTracked")
+}
+
+// endUserSession signs out user of the session and deletes remember cookies,
+// so auto-login does not bring back a revoked or timed out session.
+func endUserSession(ctx *macaron.Context, sess session.Store) {
+	signOutSession(sess)
+	ctx.SetCookie(setting.CookieUserName, "", -1, setting.AppSubUrl)
+	ctx.SetCookie(setting.CookieRememberName, "", -1, setting.AppSubUrl)
+}
+
+// isValidUserSession returns false if signed in session has been revoked,
+// and signs out user of the session. Sessions signed in without a record
+// get one at their first request.
+func isValidUserSession(ctx *macaron.Context, sess session.Store, uid int64, remoteIP string) bool {
+	s, err := models.GetUserSessionBySID(sess.ID())
+	if err != nil {
+		if !models.IsErrUserSessionNotExist(err) {
+			log.Error(4, "GetUserSessionBySID: %v", err)
+			return false
+		}
+
+		// Session was tracked before, thus it has been revoked or expired.
+		if sess.Get("sessionTracked") != nil {
+			endUserSession(ctx, sess)
+			return false
+		}
+
+		if _, err = models.NewUserSession(uid, sess.ID(), remoteIP, ctx.Req.UserAgent()); err != nil {
+			log.Error(4, "NewUserSession: %v", err)
+			return false
+		}
+		sess.Set("sessionTracked", true)
+		return true
+	}
+
+	if s.UID != uid {
+		signOutSession(sess)
+		return false
+	} else if s.IsTimedOut() {
+		if err = models.DeleteUserSessionBySID(sess.ID()); err != nil {
+			log.Error(4, "DeleteUserSessionBySID: %v", err)
+		}
+		endUserSession(ctx, sess)
+		log.Trace("Session timed out[%d]: %d", s.ID, uid)
+		return false
+	}
+	if err = models.TouchUserSession(s, remoteIP); err != nil {
+		log.Error(4, "TouchUserSession: %v", err)
+	}
+	return true
+}
+
+// SignedInUser returns the user object of signed user, remoteIP should be resolved
+// through trusted reverse proxies. It returns a bool value to indicate whether
+// user uses basic auth or not.
+func SignedInUser(ctx *macaron.Context, sess session.Store, remoteIP string) (*models.User, bool) {
 	if !models.HasEngine {
 		return nil, false
 	}
 
-	uid := SignedInID(ctx, sess)
+	uid := SignedInID(ctx, sess, remoteIP)
 
 	if uid <= 0 {
 		if setting.Service.EnableReverseProxyAuth {
@@ -132,6 +190,16 @@ func SignedInUser(ctx *macaron.Context, sess session.Store) (*models.User, bool)
 						log.Error(4, "UserSignIn: %v", err)
 					}
 					return nil, false
+				} else if u.NeedApproval || (!u.IsActive && setting.Service.RequireEmailConfirmSignIn) {
+					return nil, false
+				}
+
+				// Password alone cannot pass second factor, such users must use access tokens.
+				if has, err := models.HasSecurityKeys(u.Id); err != nil {
+					log.Error(4, "HasSecurityKeys: %v", err)
+					return nil, false
+				} else if has {
+					return nil, false
 				}
 
 				return u, true
@@ -36,6 +36,28 @@ func (f *UpdateOrgSettingForm) Validate(ctx *macaron.Context, errs binding.Error
 	return validate(errs, ctx.Data, f, ctx.Locale)
 }
 
+type OrgRepoDefaultsForm struct {
+	DefaultBranch string `binding:"MaxSize(100)"`
+	Private       bool
+	EnableIssues  bool
+	EnableWiki    bool
+	Labels        string
+}
+
+func (f *OrgRepoDefaultsForm) Validate(ctx *macaron.Context, errs binding.Errors) binding.Errors {
+	return validate(errs, ctx.Data, f, ctx.Locale)
+}
+
+type OrgMemberDefaultsForm struct {
+	DefaultMemberPublic  bool
+	DefaultTeamID        int64 `form:"default_team_id"`
+	MembersCanCreateRepo bool
+}
+
+func (f *OrgMemberDefaultsForm) Validate(ctx *macaron.Context, errs binding.Errors) binding.Errors {
+	return validate(errs, ctx.Data, f, ctx.Locale)
+}
+
 // ___________
 // \__    ___/___ _____    _____
 //   |    |_/ __ \\__  \  /     \
@@ -31,12 +31,29 @@ type CreateRepoForm struct {
 	Gitignores  string
 	License     string
 	Readme      string
+
+	DefaultBranch string `binding:"MaxSize(100)"`
+	DefaultLabels bool
+	EnableIssues  bool
+	EnableWiki    bool
 }
 
 func (f *CreateRepoForm) Validate(ctx *macaron.Context, errs binding.Errors) binding.Errors {
 	return validate(errs, ctx.Data, f, ctx.Locale)
 }
 
+type GenerateRepoForm struct {
+	Uid            int64  `binding:"Required"`
+	RepoName       string `binding:"Required;AlphaDashDot;MaxSize(100)"`
+	Private        bool
+	Description    string `binding:"MaxSize(255)"`
+	FlattenHistory bool
+}
+
+func (f *GenerateRepoForm) Validate(ctx *macaron.Context, errs binding.Errors) binding.Errors {
+	return validate(errs, ctx.Data, f, ctx.Locale)
+}
+
 type MigrateRepoForm struct {
 	CloneAddr    string `json:"clone_addr" binding:"Required"`
 	AuthUsername string `json:"auth_username"`
@@ -87,12 +104,35 @@ type RepoSettingForm struct {
 	Branch      string
 	Interval    int
 	Private     bool
+	Template    bool
+
+	EnableIssues   bool
+	EnablePulls    bool
+	EnableWiki     bool
+	EnableReleases bool
+
+	RequiredApprovals int `binding:"Range(0,100)"`
+	MaxPushSize       int64
+	SignedCommits     string `binding:"OmitEmpty;In(none,tip,all)"`
+
+	AutoAssign      string `binding:"OmitEmpty;In(none,author,round_robin)"`
+	AutoAssignees   string
+	AutoAssignTeams string
 }
 
 func (f *RepoSettingForm) Validate(ctx *macaron.Context, errs binding.Errors) binding.Errors {
 	return validate(errs, ctx.Data, f, ctx.Locale)
 }
 
+type ProtectedTagForm struct {
+	Pattern      string `binding:"Required;MaxSize(255)"`
+	OverrideMode string `binding:"OmitEmpty;In(admin,owner)"`
+}
+
+func (f *ProtectedTagForm) Validate(ctx *macaron.Context, errs binding.Errors) binding.Errors {
+	return validate(errs, ctx.Data, f, ctx.Locale)
+}
+
 //  __      __      ___.   .__    .__            __
 // /  \    /  \ ____\_ |__ |  |__ |  |__   ____ |  | __
 // \   \/\/   // __ \| __ \|  |  \|  |  \ /  _ \|  |/ /
@@ -101,10 +141,14 @@ func (f *RepoSettingForm) Validate(ctx *macaron.Context, errs binding.Errors) bi
 //        \/       \/    \/     \/     \/            \/
 
 type WebhookForm struct {
-	Events string
-	Create bool
-	Push   bool
-	Active bool
+	Events      string
+	Create      bool
+	Push        bool
+	PushTag     bool
+	PullRequest bool
+	Release     bool
+	Wiki        bool
+	Active      bool
 }
 
 func (f WebhookForm) PushOnly() bool {
@@ -120,9 +164,11 @@ func (f WebhookForm) ChooseEvents() bool {
 }
 
 type NewWebhookForm struct {
-	PayloadURL  string `binding:"Required;Url"`
-	ContentType int    `binding:"Required"`
-	Secret      string
+	PayloadURL      string `binding:"Required;Url"`
+	ContentType     string `binding:"Required"`
+	Secret          string
+	PayloadTemplate string
+	Headers         string
 	WebhookForm
 }
 
@@ -143,6 +189,16 @@ func (f *NewSlackHookForm) Validate(ctx *macaron.Context, errs binding.Errors) b
 	return validate(errs, ctx.Data, f, ctx.Locale)
 }
 
+type AddDeployKeyForm struct {
+	Title      string `binding:"Required;MaxSize(50)"`
+	Content    string `binding:"Required"`
+	IsWritable bool
+}
+
+func (f *AddDeployKeyForm) Validate(ctx *macaron.Context, errs binding.Errors) binding.Errors {
+	return validate(errs, ctx.Data, f, ctx.Locale)
+}
+
 // .___
 // |   | ______ ________ __   ____
 // |   |/  ___//  ___/  |  \_/ __ \
@@ -173,6 +229,34 @@ func (f *CreateCommentForm) Validate(ctx *macaron.Context, errs binding.Errors)
 	return validate(errs, ctx.Data, f, ctx.Locale)
 }
 
+type LockIssueForm struct {
+	Reason string `binding:"MaxSize(255)"`
+}
+
+func (f *LockIssueForm) Validate(ctx *macaron.Context, errs binding.Errors) binding.Errors {
+	return validate(errs, ctx.Data, f, ctx.Locale)
+}
+
+type CodeCommentForm struct {
+	TreePath  string `binding:"Required"`
+	Line      int64
+	CommitSHA string `binding:"MaxSize(40)"`
+	Content   string `binding:"Required"`
+}
+
+func (f *CodeCommentForm) Validate(ctx *macaron.Context, errs binding.Errors) binding.Errors {
+	return validate(errs, ctx.Data, f, ctx.Locale)
+}
+
+type SubmitReviewForm struct {
+	State   int `binding:"Range(1,3)"`
+	Content string
+}
+
+func (f *SubmitReviewForm) Validate(ctx *macaron.Context, errs binding.Errors) binding.Errors {
+	return validate(errs, ctx.Data, f, ctx.Locale)
+}
+
 //    _____  .__.__                   __
 //   /     \ |__|  |   ____   _______/  |_  ____   ____   ____
 //  /  \ /  \|  |  | _/ __ \ /  ___/\   __\/  _ \ /    \_/ __ \
@@ -126,6 +126,14 @@ func (f *ChangePasswordForm) Validate(ctx *macaron.Context, errs binding.Errors)
 	return validate(errs, ctx.Data, f, ctx.Locale)
 }
 
+type UpdateNotificationForm struct {
+	WatchNotifyMode int `form:"watch_notify_mode" binding:"Range(0,2)"`
+}
+
+func (f *UpdateNotificationForm) Validate(ctx *macaron.Context, errs binding.Errors) binding.Errors {
+	return validate(errs, ctx.Data, f, ctx.Locale)
+}
+
 type AddSSHKeyForm struct {
 	Title   string `binding:"Required;MaxSize(50)"`
 	Content string `binding:"Required"`
@@ -135,10 +143,65 @@ func (f *AddSSHKeyForm) Validate(ctx *macaron.Context, errs binding.Errors) bind
 	return validate(errs, ctx.Data, f, ctx.Locale)
 }
 
+type ImportSSHKeysForm struct {
+	Source   string `binding:"Required"`
+	UserName string `binding:"Required;MaxSize(100)"`
+}
+
+func (f *ImportSSHKeysForm) Validate(ctx *macaron.Context, errs binding.Errors) binding.Errors {
+	return validate(errs, ctx.Data, f, ctx.Locale)
+}
+
 type NewAccessTokenForm struct {
-	Name string `binding:"Required"`
+	Name   string `binding:"Required"`
+	Scopes []string
 }
 
 func (f *NewAccessTokenForm) Validate(ctx *macaron.Context, errs binding.Errors) binding.Errors {
 	return validate(errs, ctx.Data, f, ctx.Locale)
 }
+
+// NewOAuth2ApplicationForm contains information to register an OAuth2 application,
+// redirect URIs are separated by newline.
+type NewOAuth2ApplicationForm struct {
+	AppName      string `form:"app_name" binding:"Required;MaxSize(255)"`
+	RedirectURIs string `form:"redirect_uris" binding:"Required"`
+}
+
+func (f *NewOAuth2ApplicationForm) Validate(ctx *macaron.Context, errs binding.Errors) binding.Errors {
+	return validate(errs, ctx.Data, f, ctx.Locale)
+}
+
+// AddGPGKeyForm contains armored public GPG key.
+type AddGPGKeyForm struct {
+	Content string `binding:"Required"`
+}
+
+func (f *AddGPGKeyForm) Validate(ctx *macaron.Context, errs binding.Errors) binding.Errors {
+	return validate(errs, ctx.Data, f, ctx.Locale)
+}
+
+// AddSecurityKeyForm contains response of WebAuthn registration,
+// binary values are encoded in unpadded base64url.
+type AddSecurityKeyForm struct {
+	Name              string `binding:"Required;MaxSize(50)"`
+	ClientData        string `binding:"Required"`
+	AttestationObject string `binding:"Required"`
+}
+
+func (f *AddSecurityKeyForm) Validate(ctx *macaron.Context, errs binding.Errors) binding.Errors {
+	return validate(errs, ctx.Data, f, ctx.Locale)
+}
+
+// SecurityKeySignInForm contains response of WebAuthn assertion,
+// binary values are encoded in unpadded base64url.
+type SecurityKeySignInForm struct {
+	CredentialID      string `binding:"Required"`
+	ClientData        string `binding:"Required"`
+	AuthenticatorData string `binding:"Required"`
+	Signature         string `binding:"Required"`
+}
+
+func (f *SecurityKeySignInForm) Validate(ctx *macaron.Context, errs binding.Errors) binding.Errors {
+	return validate(errs, ctx.Data, f, ctx.Locale)
+}
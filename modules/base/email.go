@@ -0,0 +1,96 @@
+// Copyright 2015 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package base
+
+import (
+	"strings"
+)
+
+// disposableEmailDomains is a list of well-known disposable e-mail services.
+var disposableEmailDomains = map[string]bool{
+	"0-mail.com":             true,
+	"10minutemail.com":       true,
+	"20minutemail.com":       true,
+	"33mail.com":             true,
+	"anonbox.net":            true,
+	"discard.email":          true,
+	"dispostable.com":        true,
+	"emailondeck.com":        true,
+	"fakeinbox.com":          true,
+	"getairmail.com":         true,
+	"getnada.com":            true,
+	"guerrillamail.biz":      true,
+	"guerrillamail.com":      true,
+	"guerrillamail.de":       true,
+	"guerrillamail.info":     true,
+	"guerrillamail.net":      true,
+	"guerrillamail.org":      true,
+	"guerrillamailblock.com": true,
+	"harakirimail.com":       true,
+	"incognitomail.org":      true,
+	"jetable.org":            true,
+	"mailcatch.com":          true,
+	"maildrop.cc":            true,
+	"mailexpire.com":         true,
+	"mailinator.com":         true,
+	"mailinator.net":         true,
+	"mailnesia.com":          true,
+	"mintemail.com":          true,
+	"mohmal.com":             true,
+	"mytemp.email":           true,
+	"mytrashmail.com":        true,
+	"nowmymail.com":          true,
+	"sharklasers.com":        true,
+	"spam4.me":               true,
+	"spambox.us":             true,
+	"spamgourmet.com":        true,
+	"spamex.com":             true,
+	"tempail.com":            true,
+	"tempinbox.com":          true,
+	"tempmail.net":           true,
+	"tempmailaddress.com":    true,
+	"temp-mail.org":          true,
+	"throwawaymail.com":      true,
+	"trash-mail.com":         true,
+	"trashmail.com":          true,
+	"trashmail.net":          true,
+	"yopmail.com":            true,
+	"yopmail.fr":             true,
+	"yopmail.net":            true,
+}
+
+// EmailDomain returns lower cased domain part of given e-mail address.
+func EmailDomain(email string) string {
+	i := strings.LastIndex(email, "@")
+	if i == -1 {
+		return ""
+	}
+	return strings.ToLower(email[i+1:])
+}
+
+// IsEmailDomainMatch returns true if domain is one of given domains or a subdomain of them.
+func IsEmailDomainMatch(domain string, domains []string) bool {
+	for _, d := range domains {
+		if domain == d || strings.HasSuffix(domain, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsDisposableEmailDomain returns true if given domain belongs to a known disposable e-mail service.
+func IsDisposableEmailDomain(domain string) bool {
+	for len(domain) > 0 {
+		if disposableEmailDomains[domain] {
+			return true
+		}
+		i := strings.Index(domain, ".")
+		if i == -1 {
+			break
+		}
+		domain = domain[i+1:]
+	}
+	return false
+}
@@ -0,0 +1,116 @@
+// Copyright 2015 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package base
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+
+	"github.com/gogits/gogs/modules/setting"
+)
+
+// EmojiPattern matches emoji shortcodes like ":smile:".
+var EmojiPattern = regexp.MustCompile(`:([a-z0-9_+\-]+):`)
+
+// emojis maps commonly used shortcodes to Unicode emoji.
+var emojis = map[string]string{
+	"+1":                    "👍",
+	"-1":                    "👎",
+	"100":                   "💯",
+	"angry":                 "😠",
+	"bangbang":              "‼️",
+	"beer":                  "🍺",
+	"bell":                  "🔔",
+	"blush":                 "😊",
+	"boom":                  "💥",
+	"broken_heart":          "💔",
+	"bug":                   "🐛",
+	"bulb":                  "💡",
+	"cake":                  "🍰",
+	"clap":                  "👏",
+	"coffee":                "☕",
+	"confused":              "😕",
+	"construction":          "🚧",
+	"cry":                   "😢",
+	"disappointed":          "😞",
+	"eyes":                  "👀",
+	"fire":                  "🔥",
+	"grin":                  "😁",
+	"grinning":              "😀",
+	"heart":                 "❤️",
+	"heart_eyes":            "😍",
+	"heavy_check_mark":      "✔️",
+	"hourglass":             "⌛",
+	"hushed":                "😯",
+	"innocent":              "😇",
+	"joy":                   "😂",
+	"kissing":               "😗",
+	"laughing":              "😆",
+	"lock":                  "🔒",
+	"mag":                   "🔍",
+	"memo":                  "📝",
+	"muscle":                "💪",
+	"neutral_face":          "😐",
+	"ok_hand":               "👌",
+	"open_mouth":            "😮",
+	"package":               "📦",
+	"pencil":                "📝",
+	"point_left":            "👈",
+	"point_right":           "👉",
+	"pray":                  "🙏",
+	"question":              "❓",
+	"rage":                  "😡",
+	"raised_hands":          "🙌",
+	"rocket":                "🚀",
+	"rofl":                  "🤣",
+	"scream":                "😱",
+	"see_no_evil":           "🙈",
+	"shipit":                "🐿️",
+	"skull":                 "💀",
+	"sleeping":              "😴",
+	"slightly_smiling_face": "🙂",
+	"smile":                 "😄",
+	"smiley":                "😃",
+	"smirk":                 "😏",
+	"sob":                   "😭",
+	"sparkles":              "✨",
+	"star":                  "⭐",
+	"stuck_out_tongue":      "😛",
+	"sunglasses":            "😎",
+	"sweat":                 "😓",
+	"sweat_smile":           "😅",
+	"tada":                  "🎉",
+	"thinking":              "🤔",
+	"thumbsdown":            "👎",
+	"thumbsup":              "👍",
+	"tired_face":            "😫",
+	"trophy":                "🏆",
+	"unamused":              "😒",
+	"warning":               "⚠️",
+	"wave":                  "👋",
+	"white_check_mark":      "✅",
+	"wink":                  "😉",
+	"worried":               "😟",
+	"wrench":                "🔧",
+	"x":                     "❌",
+	"yum":                   "😋",
+	"zap":                   "⚡",
+}
+
+// RenderEmoji replaces known emoji shortcodes in HTML text with Unicode emoji,
+// and custom ones with images. Custom emoji take precedence over builtin ones.
+func RenderEmoji(rawBytes []byte) []byte {
+	return EmojiPattern.ReplaceAllFunc(rawBytes, func(m []byte) []byte {
+		name := string(m[1 : len(m)-1])
+		if src, ok := setting.Markdown.CustomEmoji[name]; ok {
+			return []byte(fmt.Sprintf(`<img class="emoji" src="%s" alt=":%s:" title=":%s:">`,
+				html.EscapeString(src), name, name))
+		} else if emoji, ok := emojis[name]; ok {
+			return []byte(emoji)
+		}
+		return m
+	})
+}
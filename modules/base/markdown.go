@@ -264,7 +264,7 @@ OUTER_LOOP:
 		token := tokenizer.Token()
 		switch token.Type {
 		case html.TextToken:
-			buf.Write(RenderSpecialLink([]byte(token.String()), urlPrefix))
+			buf.Write(RenderEmoji(RenderSpecialLink([]byte(token.String()), urlPrefix)))
 
 		case html.StartTagToken:
 			buf.WriteString(token.String())
@@ -29,7 +29,8 @@ import (
 	"github.com/gogits/gogs/modules/setting"
 )
 
-var Sanitizer = bluemonday.UGCPolicy().AllowAttrs("class").Matching(regexp.MustCompile(`[\p{L}\p{N}\s\-_',:\[\]!\./\\\(\)&]*`)).OnElements("code")
+var Sanitizer = bluemonday.UGCPolicy().AllowAttrs("class").Matching(regexp.MustCompile(`[\p{L}\p{N}\s\-_',:\[\]!\./\\\(\)&]*`)).OnElements("code").
+	AllowAttrs("class").Matching(regexp.MustCompile(`^emoji$`)).OnElements("img")
 
 // EncodeMD5 encodes string to md5 hex value.
 func EncodeMD5(str string) string {
@@ -0,0 +1,113 @@
+// Copyright 2015 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package git
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BlameLine represents a line of file in blame result.
+type BlameLine struct {
+	Line         int // Line number in the final file.
+	OriginalLine int // Line number in the commit that introduced it.
+	Content      string
+}
+
+// BlameHunk represents consecutive lines introduced by the same commit.
+type BlameHunk struct {
+	CommitID string
+	Author   *Signature
+	Summary  string
+	Filename string // Path of file in the commit that introduced lines.
+	Lines    []*BlameLine
+}
+
+// Blame returns authorship of every line of given file at given commit,
+// grouped into hunks in order of lines.
+func (repo *Repository) Blame(commitID, treePath string) ([]*BlameHunk, error) {
+	stdout, stderr, err := execDirBytes(repo.Path, "blame", "--porcelain", commitID, "--", treePath)
+	if err != nil {
+		return nil, concatenateError(err, string(stderr))
+	}
+	return parseBlamePorcelain(string(stdout))
+}
+
+// parseBlamePorcelain parses output of "git blame --porcelain".
+// Commit information is only given for the first time a commit appears.
+func parseBlamePorcelain(output string) ([]*BlameHunk, error) {
+	var (
+		hunks   = make([]*BlameHunk, 0, 10)
+		commits = make(map[string]*BlameHunk)
+		hunk    *BlameHunk
+		line    *BlameLine
+	)
+
+	for _, text := range strings.Split(output, "\n") {
+		if len(text) == 0 {
+			continue
+		}
+
+		// Content of line is prefixed by a tab and ends current line.
+		if strings.HasPrefix(text, "\t") {
+			if line == nil {
+				return nil, fmt.Errorf("unexpected content line: %q", text)
+			}
+			line.Content = text[1:]
+			hunk.Lines = append(hunk.Lines, line)
+			line = nil
+			continue
+		}
+
+		// Header of line: <sha> <orig_line> <final_line> [<num_lines>],
+		// number of lines is only given for the first line of a group.
+		if line == nil {
+			fields := strings.Fields(text)
+			if len(fields) < 3 || len(fields[0]) != 40 {
+				return nil, fmt.Errorf("unexpected header line: %q", text)
+			}
+			line = &BlameLine{}
+			line.OriginalLine, _ = strconv.Atoi(fields[1])
+			line.Line, _ = strconv.Atoi(fields[2])
+
+			if len(fields) == 4 || hunk == nil || hunk.CommitID != fields[0] {
+				hunk = &BlameHunk{CommitID: fields[0]}
+				if info, ok := commits[fields[0]]; ok {
+					hunk.Author = info.Author
+					hunk.Summary = info.Summary
+					hunk.Filename = info.Filename
+				} else {
+					hunk.Author = new(Signature)
+					commits[fields[0]] = hunk
+				}
+				hunks = append(hunks, hunk)
+			}
+			continue
+		}
+
+		// Commit information.
+		infos := strings.SplitN(text, " ", 2)
+		if len(infos) != 2 {
+			continue
+		}
+		switch infos[0] {
+		case "author":
+			hunk.Author.Name = infos[1]
+		case "author-mail":
+			hunk.Author.Email = strings.Trim(infos[1], "<>")
+		case "author-time":
+			if sec, err := strconv.ParseInt(infos[1], 10, 64); err == nil {
+				hunk.Author.When = time.Unix(sec, 0)
+			}
+		case "summary":
+			hunk.Summary = infos[1]
+		case "filename":
+			hunk.Filename = infos[1]
+		}
+	}
+	return hunks, nil
+}
@@ -0,0 +1,69 @@
+// Copyright 2015 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package git
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const testBlamePorcelain = `1111111111111111111111111111111111111111 1 1 2
+author Alice
+author-mail <alice@example.com>
+author-time 1445412825
+author-tz +0200
+committer Alice
+committer-mail <alice@example.com>
+committer-time 1445412825
+committer-tz +0200
+summary Initial commit
+boundary
+filename README.md
+	# Title
+1111111111111111111111111111111111111111 2 2
+	
+2222222222222222222222222222222222222222 2 3 1
+author Bob
+author-mail <bob@example.com>
+author-time 1445512825
+author-tz +0000
+committer Bob
+committer-mail <bob@example.com>
+committer-time 1445512825
+committer-tz +0000
+summary Add description
+previous 1111111111111111111111111111111111111111 README.md
+filename README.md
+	Description.
+1111111111111111111111111111111111111111 4 4 1
+filename README.md
+	Footer
+`
+
+func Test_parseBlamePorcelain(t *testing.T) {
+	Convey("Parse output of git blame --porcelain", t, func() {
+		hunks, err := parseBlamePorcelain(testBlamePorcelain)
+		So(err, ShouldBeNil)
+		So(len(hunks), ShouldEqual, 3)
+
+		So(hunks[0].CommitID, ShouldEqual, "1111111111111111111111111111111111111111")
+		So(hunks[0].Author.Name, ShouldEqual, "Alice")
+		So(hunks[0].Author.Email, ShouldEqual, "alice@example.com")
+		So(hunks[0].Summary, ShouldEqual, "Initial commit")
+		So(len(hunks[0].Lines), ShouldEqual, 2)
+		So(hunks[0].Lines[0].Content, ShouldEqual, "# Title")
+		So(hunks[0].Lines[1].Content, ShouldEqual, "")
+
+		So(hunks[1].Author.Name, ShouldEqual, "Bob")
+		So(hunks[1].Lines[0].Line, ShouldEqual, 3)
+		So(hunks[1].Lines[0].OriginalLine, ShouldEqual, 2)
+
+		// Commit information is reused for later hunks of the same commit.
+		So(hunks[2].Author.Name, ShouldEqual, "Alice")
+		So(hunks[2].Summary, ShouldEqual, "Initial commit")
+		So(hunks[2].Lines[0].Line, ShouldEqual, 4)
+	})
+}
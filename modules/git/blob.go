@@ -8,8 +8,7 @@ import (
 	"bytes"
 	"errors"
 	"io"
-
-	"github.com/Unknwon/com"
+	"io/ioutil"
 )
 
 type Blob struct {
@@ -18,9 +17,27 @@ type Blob struct {
 }
 
 func (b *Blob) Data() (io.Reader, error) {
-	stdout, stderr, err := com.ExecCmdDirBytes(b.repo.Path, "git", "show", b.ID.String())
+	stdout, stderr, err := execDirBytes(b.repo.Path, "show", b.ID.String())
 	if err != nil {
 		return nil, errors.New(string(stderr))
 	}
 	return bytes.NewBuffer(stdout), nil
 }
+
+// DataHead returns at most n bytes from the beginning of blob content,
+// the rest of content is never read into memory.
+func (b *Blob) DataHead(n int64) ([]byte, error) {
+	cmd := Command(b.repo.Path, "cat-file", "blob", b.ID.String())
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	} else if err = cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	data, err := ioutil.ReadAll(io.LimitReader(stdout, n))
+	// Process has either exited or is no longer needed, thus errors are ignored.
+	cmd.Process.Kill()
+	cmd.Wait()
+	return data, err
+}
@@ -0,0 +1,127 @@
+// Copyright 2016 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package git
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/gogits/gogs/modules/process"
+	"github.com/gogits/gogs/modules/setting"
+)
+
+// BinPath returns path of configured Git binary.
+func BinPath() string {
+	if len(setting.Git.Path) == 0 {
+		return "git"
+	}
+	return setting.Git.Path
+}
+
+// Env returns environment variables of current process
+// with configured custom Git environment variables appended.
+func Env() []string {
+	return append(os.Environ(), setting.Git.Env...)
+}
+
+// Command returns a command to run configured Git binary
+// with given arguments in given directory.
+func Command(dir string, args ...string) *exec.Cmd {
+	cmd := exec.Command(BinPath(), args...)
+	cmd.Dir = dir
+	cmd.Env = Env()
+	return cmd
+}
+
+// ExecDir runs configured Git binary with given arguments in given directory,
+// it records its process and timeout.
+func ExecDir(timeout time.Duration, dir, desc string, args ...string) (string, string, error) {
+	return process.ExecCmd(timeout, desc, Command(dir, args...))
+}
+
+// ExecTimeout runs configured Git binary with given arguments,
+// it records its process and timeout.
+func ExecTimeout(timeout time.Duration, desc string, args ...string) (string, string, error) {
+	return ExecDir(timeout, "", desc, args...)
+}
+
+// Exec runs configured Git binary with given arguments,
+// it records its process and has default timeout.
+func Exec(desc string, args ...string) (string, string, error) {
+	return ExecDir(-1, "", desc, args...)
+}
+
+func execDirBytes(dir string, args ...string) ([]byte, []byte, error) {
+	bufOut := new(bytes.Buffer)
+	bufErr := new(bytes.Buffer)
+
+	cmd := Command(dir, args...)
+	cmd.Stdout = bufOut
+	cmd.Stderr = bufErr
+	err := cmd.Run()
+	return bufOut.Bytes(), bufErr.Bytes(), err
+}
+
+// execDirStdin runs Git command in given directory with given standard input.
+func execDirStdin(dir string, stdin io.Reader, args ...string) (string, string, error) {
+	bufOut := new(bytes.Buffer)
+	bufErr := new(bytes.Buffer)
+
+	cmd := Command(dir, args...)
+	cmd.Stdin = stdin
+	cmd.Stdout = bufOut
+	cmd.Stderr = bufErr
+	err := cmd.Run()
+	return bufOut.String(), bufErr.String(), err
+}
+
+func execDir(dir string, args ...string) (string, string, error) {
+	stdout, stderr, err := execDirBytes(dir, args...)
+	return string(stdout), string(stderr), err
+}
+
+// execDirLimit runs Git command in given directory and reads at most limit bytes of its output,
+// the command is killed and ErrPatchTooLarge is returned when output exceeds the limit.
+// Output is not limited when limit is not positive.
+func execDirLimit(dir string, limit int64, args ...string) ([]byte, error) {
+	if limit <= 0 {
+		stdout, stderr, err := execDirBytes(dir, args...)
+		if err != nil {
+			return nil, concatenateError(err, string(stderr))
+		}
+		return stdout, nil
+	}
+
+	bufErr := new(bytes.Buffer)
+	cmd := Command(dir, args...)
+	cmd.Stderr = bufErr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err = cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	// Read one more byte than the limit to know whether output exceeds it.
+	data, err := ioutil.ReadAll(io.LimitReader(stdout, limit+1))
+	if err != nil || int64(len(data)) > limit {
+		cmd.Process.Kill()
+		cmd.Wait()
+		if err != nil {
+			return nil, err
+		}
+		return nil, ErrPatchTooLarge{limit}
+	}
+
+	if err = cmd.Wait(); err != nil {
+		return nil, concatenateError(err, bufErr.String())
+	}
+	return data, nil
+}
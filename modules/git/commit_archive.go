@@ -5,9 +5,11 @@
 package git
 
 import (
+	"bytes"
 	"fmt"
-
-	"github.com/Unknwon/com"
+	"io"
+	"os"
+	"strings"
 )
 
 type ArchiveType int
@@ -15,22 +17,55 @@ type ArchiveType int
 const (
 	ZIP ArchiveType = iota + 1
 	TARGZ
+	TARBZ2
 )
 
-func (c *Commit) CreateArchive(path string, archiveType ArchiveType) error {
-	var format string
+// archiveArgs returns arguments of Git to create archive of given type.
+func archiveArgs(archiveType ArchiveType) ([]string, error) {
 	switch archiveType {
 	case ZIP:
-		format = "zip"
+		return []string{"archive", "--format=zip"}, nil
 	case TARGZ:
-		format = "tar.gz"
-	default:
-		return fmt.Errorf("unknown format: %v", archiveType)
+		return []string{"archive", "--format=tar.gz"}, nil
+	case TARBZ2:
+		// Git does not support bzip2 natively, so register it as an external filter.
+		return []string{"-c", "tar.tar.bz2.command=bzip2 -c", "archive", "--format=tar.bz2"}, nil
+	}
+	return nil, fmt.Errorf("unknown format: %v", archiveType)
+}
+
+// WriteArchive streams archive of given type to w, all files are put under
+// given prefix directory when it is not empty.
+func (c *Commit) WriteArchive(w io.Writer, archiveType ArchiveType, prefix string) error {
+	args, err := archiveArgs(archiveType)
+	if err != nil {
+		return err
+	}
+	if len(prefix) > 0 {
+		args = append(args, "--prefix="+strings.TrimSuffix(prefix, "/")+"/")
 	}
+	args = append(args, c.ID.String())
 
-	_, stderr, err := com.ExecCmdDir(c.repo.Path, "git", "archive", "--format="+format, "-o", path, c.ID.String())
+	stderr := new(bytes.Buffer)
+	cmd := Command(c.repo.Path, args...)
+	cmd.Stdout = w
+	cmd.Stderr = stderr
+	if err = cmd.Run(); err != nil {
+		return fmt.Errorf("%v: %s", err, stderr)
+	}
+	return nil
+}
+
+func (c *Commit) CreateArchive(path string, archiveType ArchiveType) error {
+	f, err := os.Create(path)
 	if err != nil {
-		return fmt.Errorf("%s", stderr)
+		return err
+	}
+	defer f.Close()
+
+	if err = c.WriteArchive(f, archiveType, ""); err != nil {
+		os.Remove(path)
+		return err
 	}
 	return nil
 }
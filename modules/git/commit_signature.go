@@ -0,0 +1,60 @@
+// Copyright 2015 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package git
+
+import (
+	"strings"
+
+	"github.com/gogits/gogs/modules/process"
+)
+
+// CommitSignature represents result of verifying GPG signature of a commit.
+type CommitSignature struct {
+	CommitID string
+	// Status is the signature status reported by Git, e.g. "G" for a good signature
+	// and "N" for no signature, see "%G?" of git-log(1).
+	Status string
+	// Fingerprint is of the key that made the signature, which may be a subkey.
+	Fingerprint        string
+	PrimaryFingerprint string
+}
+
+// IsGood returns true if commit has a good and valid signature.
+func (sig *CommitSignature) IsGood() bool {
+	return sig.Status == "G"
+}
+
+// parseCommitSignatures parses output of git log with format "%H %G? %GF %GP".
+func parseCommitSignatures(stdout string) []*CommitSignature {
+	// Fingerprints are empty for unsigned commits, so spaces must not be trimmed.
+	lines := strings.Split(strings.Trim(stdout, "\n"), "\n")
+	sigs := make([]*CommitSignature, 0, len(lines))
+	for _, line := range lines {
+		fields := strings.Split(line, " ")
+		if len(fields) != 4 || len(fields[0]) == 0 {
+			continue
+		}
+		sigs = append(sigs, &CommitSignature{
+			CommitID:           fields[0],
+			Status:             fields[1],
+			Fingerprint:        strings.ToUpper(fields[2]),
+			PrimaryFingerprint: strings.ToUpper(fields[3]),
+		})
+	}
+	return sigs
+}
+
+// GetCommitSignatures verifies signatures of commits selected by given revision arguments
+// with given GnuPG binary and home directory of keyring, newest commits come first.
+func (repo *Repository) GetCommitSignatures(gpgPath, gpgHome string, revArgs ...string) ([]*CommitSignature, error) {
+	args := append([]string{"-c", "gpg.program=" + gpgPath, "log", "--topo-order", "--format=%H %G? %GF %GP"}, revArgs...)
+	cmd := Command(repo.Path, args...)
+	cmd.Env = append(cmd.Env, "GNUPGHOME="+gpgHome)
+	stdout, stderr, err := process.ExecCmd(-1, "GetCommitSignatures: "+repo.Path, cmd)
+	if err != nil {
+		return nil, concatenateError(err, stderr)
+	}
+	return parseCommitSignatures(stdout), nil
+}
@@ -0,0 +1,33 @@
+// Copyright 2015 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package git
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func Test_parseCommitSignatures(t *testing.T) {
+	Convey("Parse signatures of commits", t, func() {
+		stdout := "1111111111111111111111111111111111111111 G abcdef0123 0123abcdef\n" +
+			"2222222222222222222222222222222222222222 N  \n"
+		sigs := parseCommitSignatures(stdout)
+		So(len(sigs), ShouldEqual, 2)
+
+		So(sigs[0].CommitID, ShouldEqual, "1111111111111111111111111111111111111111")
+		So(sigs[0].IsGood(), ShouldBeTrue)
+		So(sigs[0].Fingerprint, ShouldEqual, "ABCDEF0123")
+		So(sigs[0].PrimaryFingerprint, ShouldEqual, "0123ABCDEF")
+
+		So(sigs[1].CommitID, ShouldEqual, "2222222222222222222222222222222222222222")
+		So(sigs[1].IsGood(), ShouldBeFalse)
+		So(sigs[1].Fingerprint, ShouldBeEmpty)
+	})
+
+	Convey("Parse empty output", t, func() {
+		So(parseCommitSignatures(""), ShouldBeEmpty)
+	})
+}
@@ -20,3 +20,30 @@ func IsErrUnsupportedVersion(err error) bool {
 func (err ErrUnsupportedVersion) Error() string {
 	return fmt.Sprintf("Operation requires higher version [required: %s]", err.Required)
 }
+
+type ErrAmbiguousObject struct {
+	Prefix     string
+	Candidates []string
+}
+
+func IsErrAmbiguousObject(err error) bool {
+	_, ok := err.(ErrAmbiguousObject)
+	return ok
+}
+
+func (err ErrAmbiguousObject) Error() string {
+	return fmt.Sprintf("Short object ID is ambiguous [prefix: %s, candidates: %d]", err.Prefix, len(err.Candidates))
+}
+
+type ErrPatchTooLarge struct {
+	Limit int64
+}
+
+func IsErrPatchTooLarge(err error) bool {
+	_, ok := err.(ErrPatchTooLarge)
+	return ok
+}
+
+func (err ErrPatchTooLarge) Error() string {
+	return fmt.Sprintf("Patch exceeds size limit [limit: %d]", err.Limit)
+}
@@ -39,6 +39,10 @@ var (
 	ErrNotValidHook = errors.New("not a valid Git hook")
 )
 
+// CUSTOM_HOOKS_DIR is the directory in repository where content of custom hooks is saved,
+// hooks in "hooks" directory only run them through a wrapper.
+const CUSTOM_HOOKS_DIR = "custom_hooks"
+
 // IsValidHookName returns true if given name is a valid Git hook.
 func IsValidHookName(name string) bool {
 	for _, hn := range hookNames {
@@ -51,11 +55,12 @@ func IsValidHookName(name string) bool {
 
 // Hook represents a Git hook.
 type Hook struct {
-	name     string
-	IsActive bool   // Indicates whether repository has this hook.
-	Content  string // Content of hook if it's active.
-	Sample   string // Sample content from Git.
-	path     string // Hook file path.
+	name       string
+	IsActive   bool   // Indicates whether repository has this hook.
+	Content    string // Content of hook if it's active.
+	Sample     string // Sample content from Git.
+	path       string // Hook file path.
+	customPath string // Custom hook content file path.
 }
 
 // GetHook returns a Git hook by given name and repository.
@@ -64,10 +69,19 @@ func GetHook(repoPath, name string) (*Hook, error) {
 		return nil, ErrNotValidHook
 	}
 	h := &Hook{
-		name: name,
-		path: path.Join(repoPath, "hooks", name),
+		name:       name,
+		path:       path.Join(repoPath, "hooks", name),
+		customPath: path.Join(repoPath, CUSTOM_HOOKS_DIR, name),
 	}
-	if isFile(h.path) {
+	if isFile(h.customPath) {
+		data, err := ioutil.ReadFile(h.customPath)
+		if err != nil {
+			return nil, err
+		}
+		h.IsActive = true
+		h.Content = string(data)
+	} else if isFile(h.path) {
+		// Hook saved before custom hooks directory was introduced.
 		data, err := ioutil.ReadFile(h.path)
 		if err != nil {
 			return nil, err
@@ -88,15 +102,32 @@ func (h *Hook) Name() string {
 	return h.name
 }
 
-// Update updates hook settings.
-func (h *Hook) Update() error {
+// IsWrapped returns true if hook content is saved in custom hooks directory
+// and run through a wrapper.
+func (h *Hook) IsWrapped() bool {
+	return isFile(h.customPath)
+}
+
+// Update updates hook settings, content is saved in custom hooks directory
+// and given wrapper is installed as the hook to run it.
+func (h *Hook) Update(wrapper string) error {
 	if len(strings.TrimSpace(h.Content)) == 0 {
-		if com.IsExist(h.path) {
-			return os.Remove(h.path)
+		for _, p := range []string{h.customPath, h.path} {
+			if com.IsExist(p) {
+				if err := os.Remove(p); err != nil {
+					return err
+				}
+			}
 		}
 		return nil
 	}
-	return ioutil.WriteFile(h.path, []byte(strings.Replace(h.Content, "\r", "", -1)), os.ModePerm)
+
+	if err := os.MkdirAll(path.Dir(h.customPath), os.ModePerm); err != nil {
+		return err
+	} else if err = ioutil.WriteFile(h.customPath, []byte(strings.Replace(h.Content, "\r", "", -1)), os.ModePerm); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(h.path, []byte(wrapper), os.ModePerm)
 }
 
 // ListHooks returns a list of Git hooks of given repository.
@@ -0,0 +1,24 @@
+// Copyright 2015 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package git
+
+import (
+	"strings"
+)
+
+// ENV_GIT_PROTOCOL is the environment variable used to pass
+// protocol parameters requested by client to git-upload-pack and git-receive-pack.
+const ENV_GIT_PROTOCOL = "GIT_PROTOCOL"
+
+// IsProtocolV2 returns true if given value of Git-Protocol header or GIT_PROTOCOL
+// environment variable, which is a colon-separated list of parameters, asks for protocol version 2.
+func IsProtocolV2(params string) bool {
+	for _, param := range strings.Split(params, ":") {
+		if strings.TrimSpace(param) == "version=2" {
+			return true
+		}
+	}
+	return false
+}
@@ -6,7 +6,13 @@ package git
 
 import (
 	"errors"
+	"fmt"
+	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/Unknwon/com"
 )
 
 // Repository represents a Git repository.
@@ -28,3 +34,53 @@ func OpenRepository(repoPath string) (*Repository, error) {
 
 	return &Repository{Path: repoPath}, nil
 }
+
+// GetRepoSize returns disk usage of objects in repository in bytes.
+func GetRepoSize(repoPath string) (int64, error) {
+	stdout, stderr, err := execDir(repoPath, "count-objects", "-v")
+	if err != nil {
+		return 0, concatenateError(err, stderr)
+	}
+
+	var size int64
+	for _, line := range strings.Split(stdout, "\n") {
+		fields := strings.SplitN(line, ":", 2)
+		if len(fields) != 2 {
+			continue
+		}
+		switch fields[0] {
+		case "size", "size-pack", "size-garbage":
+			kb, err := strconv.ParseInt(strings.TrimSpace(fields[1]), 10, 64)
+			if err != nil {
+				return 0, fmt.Errorf("parse %s: %v", fields[0], err)
+			}
+			size += kb * 1024
+		}
+	}
+	return size, nil
+}
+
+// IsRepoLocked returns true if repository is being written by another process,
+// e.g. a push or a running garbage collection.
+func IsRepoLocked(repoPath string) bool {
+	if com.IsExist(filepath.Join(repoPath, "gc.pid")) {
+		return true
+	}
+	if matches, _ := filepath.Glob(filepath.Join(repoPath, "*.lock")); len(matches) > 0 {
+		return true
+	}
+	// Objects are received into quarantine directory before refs are updated.
+	if matches, _ := filepath.Glob(filepath.Join(repoPath, "objects", "incoming-*")); len(matches) > 0 {
+		return true
+	}
+
+	locked := false
+	filepath.Walk(filepath.Join(repoPath, "refs"), func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() && strings.HasSuffix(path, ".lock") {
+			locked = true
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	return locked
+}
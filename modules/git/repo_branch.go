@@ -6,12 +6,19 @@ package git
 
 import (
 	"strings"
-
-	"github.com/Unknwon/com"
 )
 
 func IsBranchExist(repoPath, branchName string) bool {
-	_, _, err := com.ExecCmdDir(repoPath, "git", "show-ref", "--verify", "refs/heads/"+branchName)
+	_, _, err := execDir(repoPath, "show-ref", "--verify", "refs/heads/"+branchName)
+	return err == nil
+}
+
+// IsValidBranchName returns true if given name can be used as a branch name.
+func IsValidBranchName(name string) bool {
+	if len(name) == 0 || strings.HasPrefix(name, "-") {
+		return false
+	}
+	_, _, err := execDir("", "check-ref-format", "refs/heads/"+name)
 	return err == nil
 }
 
@@ -20,7 +27,7 @@ func (repo *Repository) IsBranchExist(branchName string) bool {
 }
 
 func (repo *Repository) GetBranches() ([]string, error) {
-	stdout, stderr, err := com.ExecCmdDir(repo.Path, "git", "show-ref", "--heads")
+	stdout, stderr, err := execDir(repo.Path, "show-ref", "--heads")
 	if err != nil {
 		return nil, concatenateError(err, stderr)
 	}
@@ -42,7 +49,7 @@ func (repo *Repository) SetDefaultBranch(branchName string) error {
 		return ErrUnsupportedVersion{"1.7.10"}
 	}
 
-	_, stderr, err := com.ExecCmdDir(repo.Path, "git", "symbolic-ref", "HEAD", "refs/heads/"+branchName)
+	_, stderr, err := execDir(repo.Path, "symbolic-ref", "HEAD", "refs/heads/"+branchName)
 	if err != nil {
 		return concatenateError(err, stderr)
 	}
@@ -16,7 +16,7 @@ import (
 )
 
 func (repo *Repository) getCommitIdOfRef(refpath string) (string, error) {
-	stdout, stderr, err := com.ExecCmdDir(repo.Path, "git", "show-ref", "--verify", refpath)
+	stdout, stderr, err := execDir(repo.Path, "show-ref", "--verify", refpath)
 	if err != nil {
 		return "", errors.New(stderr)
 	}
@@ -111,7 +111,7 @@ func (repo *Repository) getCommit(id sha1) (*Commit, error) {
 		repo.commitCache = make(map[sha1]*Commit, 10)
 	}
 
-	data, stderr, err := com.ExecCmdDirBytes(repo.Path, "git", "cat-file", "-p", id.String())
+	data, stderr, err := execDirBytes(repo.Path, "cat-file", "-p", id.String())
 	if err != nil {
 		return nil, concatenateError(err, string(stderr))
 	}
@@ -137,9 +137,15 @@ func (repo *Repository) GetCommit(commitId string) (*Commit, error) {
 	return repo.getCommit(id)
 }
 
+// GetCommitPatch returns patch of changes introduced by given commit,
+// it returns ErrPatchTooLarge when patch exceeds limit bytes (unlimited if not positive).
+func (repo *Repository) GetCommitPatch(commitID string, limit int64) ([]byte, error) {
+	return execDirLimit(repo.Path, limit, "show", "--pretty=format:", "--patch", "--binary", commitID)
+}
+
 func (repo *Repository) commitsCount(id sha1) (int, error) {
 	if gitVer.LessThan(MustParseVersion("1.8.0")) {
-		stdout, stderr, err := com.ExecCmdDirBytes(repo.Path, "git", "log",
+		stdout, stderr, err := execDirBytes(repo.Path, "log",
 			"--pretty=format:''", id.String())
 		if err != nil {
 			return 0, errors.New(string(stderr))
@@ -147,7 +153,7 @@ func (repo *Repository) commitsCount(id sha1) (int, error) {
 		return len(bytes.Split(stdout, []byte("\n"))), nil
 	}
 
-	stdout, stderr, err := com.ExecCmdDir(repo.Path, "git", "rev-list", "--count", id.String())
+	stdout, stderr, err := execDir(repo.Path, "rev-list", "--count", id.String())
 	if err != nil {
 		return 0, errors.New(stderr)
 	}
@@ -164,7 +170,7 @@ func (repo *Repository) CommitsCount(commitId string) (int, error) {
 
 func (repo *Repository) commitsCountBetween(start, end sha1) (int, error) {
 	if gitVer.LessThan(MustParseVersion("1.8.0")) {
-		stdout, stderr, err := com.ExecCmdDirBytes(repo.Path, "git", "log",
+		stdout, stderr, err := execDirBytes(repo.Path, "log",
 			"--pretty=format:''", start.String()+"..."+end.String())
 		if err != nil {
 			return 0, errors.New(string(stderr))
@@ -172,7 +178,7 @@ func (repo *Repository) commitsCountBetween(start, end sha1) (int, error) {
 		return len(bytes.Split(stdout, []byte("\n"))), nil
 	}
 
-	stdout, stderr, err := com.ExecCmdDir(repo.Path, "git", "rev-list", "--count",
+	stdout, stderr, err := execDir(repo.Path, "rev-list", "--count",
 		start.String()+"..."+end.String())
 	if err != nil {
 		return 0, errors.New(stderr)
@@ -193,7 +199,7 @@ func (repo *Repository) CommitsCountBetween(startCommitID, endCommitID string) (
 }
 
 func (repo *Repository) FilesCountBetween(startCommitID, endCommitID string) (int, error) {
-	stdout, stderr, err := com.ExecCmdDir(repo.Path, "git", "diff", "--name-only",
+	stdout, stderr, err := execDir(repo.Path, "diff", "--name-only",
 		startCommitID+"..."+endCommitID)
 	if err != nil {
 		return 0, fmt.Errorf("list changed files: %v", concatenateError(err, stderr))
@@ -281,7 +287,7 @@ func (repo *Repository) commitsBefore(lock *sync.Mutex, l *list.List, parent *li
 }
 
 func (repo *Repository) FileCommitsCount(branch, file string) (int, error) {
-	stdout, stderr, err := com.ExecCmdDir(repo.Path, "git", "rev-list", "--count",
+	stdout, stderr, err := execDir(repo.Path, "rev-list", "--count",
 		branch, "--", file)
 	if err != nil {
 		return 0, errors.New(stderr)
@@ -290,7 +296,7 @@ func (repo *Repository) FileCommitsCount(branch, file string) (int, error) {
 }
 
 func (repo *Repository) CommitsByFileAndRange(branch, file string, page int) (*list.List, error) {
-	stdout, stderr, err := com.ExecCmdDirBytes(repo.Path, "git", "log", branch,
+	stdout, stderr, err := execDirBytes(repo.Path, "log", branch,
 		"--skip="+com.ToStr((page-1)*50), "--max-count=50", prettyLogFormat, "--", file)
 	if err != nil {
 		return nil, errors.New(string(stderr))
@@ -298,6 +304,20 @@ func (repo *Repository) CommitsByFileAndRange(branch, file string, page int) (*l
 	return parsePrettyFormatLog(repo, stdout)
 }
 
+// CommitsByRangeAndPath returns at most limit commits reachable from given commit after skipping
+// given number of them, only commits touching given path are returned if it is not empty.
+func (repo *Repository) CommitsByRangeAndPath(commitID, relPath string, skip, limit int) (*list.List, error) {
+	args := []string{"log", commitID, "--skip=" + com.ToStr(skip), "--max-count=" + com.ToStr(limit), prettyLogFormat}
+	if len(relPath) > 0 {
+		args = append(args, "--", relPath)
+	}
+	stdout, stderr, err := execDirBytes(repo.Path, args...)
+	if err != nil {
+		return nil, concatenateError(err, string(stderr))
+	}
+	return parsePrettyFormatLog(repo, bytes.TrimSpace(stdout))
+}
+
 func (repo *Repository) getCommitsBefore(id sha1) (*list.List, error) {
 	l := list.New()
 	lock := new(sync.Mutex)
@@ -305,7 +325,7 @@ func (repo *Repository) getCommitsBefore(id sha1) (*list.List, error) {
 }
 
 func (repo *Repository) searchCommits(id sha1, keyword string) (*list.List, error) {
-	stdout, stderr, err := com.ExecCmdDirBytes(repo.Path, "git", "log", id.String(), "-100",
+	stdout, stderr, err := execDirBytes(repo.Path, "log", id.String(), "-100",
 		"-i", "--grep="+keyword, prettyLogFormat)
 	if err != nil {
 		return nil, err
@@ -318,7 +338,7 @@ func (repo *Repository) searchCommits(id sha1, keyword string) (*list.List, erro
 var CommitsRangeSize = 50
 
 func (repo *Repository) commitsByRange(id sha1, page int) (*list.List, error) {
-	stdout, stderr, err := com.ExecCmdDirBytes(repo.Path, "git", "log", id.String(),
+	stdout, stderr, err := execDirBytes(repo.Path, "log", id.String(),
 		"--skip="+com.ToStr((page-1)*CommitsRangeSize), "--max-count="+com.ToStr(CommitsRangeSize), prettyLogFormat)
 	if err != nil {
 		return nil, errors.New(string(stderr))
@@ -327,7 +347,7 @@ func (repo *Repository) commitsByRange(id sha1, page int) (*list.List, error) {
 }
 
 func (repo *Repository) getCommitOfRelPath(id sha1, relPath string) (*Commit, error) {
-	stdout, _, err := com.ExecCmdDir(repo.Path, "git", "log", "-1", prettyLogFormat, id.String(), "--", relPath)
+	stdout, _, err := execDir(repo.Path, "log", "-1", prettyLogFormat, id.String(), "--", relPath)
 	if err != nil {
 		return nil, err
 	}
@@ -4,6 +4,11 @@
 
 package git
 
+import (
+	"regexp"
+	"strings"
+)
+
 type ObjectType string
 
 const (
@@ -12,3 +17,75 @@ const (
 	BLOB   ObjectType = "blob"
 	TAG    ObjectType = "tag"
 )
+
+// shortSHAPattern matches abbreviated object ID that Git is able to disambiguate.
+var shortSHAPattern = regexp.MustCompile(`^[0-9a-fA-F]{4,40}$`)
+
+// GetObjectType returns type of object with given full ID.
+func (repo *Repository) GetObjectType(id string) (ObjectType, error) {
+	stdout, stderr, err := execDir(repo.Path, "cat-file", "-t", id)
+	if err != nil {
+		return "", concatenateError(err, stderr)
+	}
+	return ObjectType(strings.TrimSpace(stdout)), nil
+}
+
+// findObjectsByPrefix returns full IDs of all objects that start with given prefix.
+func (repo *Repository) findObjectsByPrefix(prefix string) ([]string, error) {
+	// Command exits with error when no object matches.
+	stdout, _, err := execDir(repo.Path, "rev-parse", "--disambiguate="+strings.ToLower(prefix))
+	if err != nil {
+		return nil, nil
+	}
+	return strings.Fields(stdout), nil
+}
+
+// ResolveRef resolves branch, tag or (abbreviated) object ID to full object ID
+// and type of the object. Tags are resolved to tag object if they are annotated.
+// It returns ErrAmbiguousObject when abbreviated ID matches more than one object.
+func (repo *Repository) ResolveRef(ref string) (string, ObjectType, error) {
+	var (
+		id  string
+		err error
+	)
+	switch {
+	case repo.IsBranchExist(ref):
+		id, err = repo.GetCommitIdOfBranch(ref)
+	case repo.IsTagExist(ref):
+		id, err = repo.GetCommitIdOfTag(ref)
+	case shortSHAPattern.MatchString(ref):
+		var ids []string
+		if ids, err = repo.findObjectsByPrefix(ref); err != nil {
+			return "", "", err
+		}
+		switch len(ids) {
+		case 0:
+			return "", "", ErrNotExist
+		case 1:
+			id = ids[0]
+		default:
+			return "", "", ErrAmbiguousObject{ref, ids}
+		}
+	default:
+		return "", "", ErrNotExist
+	}
+	if err != nil {
+		return "", "", err
+	}
+
+	typ, err := repo.GetObjectType(id)
+	if err != nil {
+		return "", "", err
+	}
+	return id, typ, nil
+}
+
+// PeelToCommit returns ID of commit that given object eventually points to,
+// e.g. commit of an annotated tag.
+func (repo *Repository) PeelToCommit(id string) (string, error) {
+	stdout, stderr, err := execDir(repo.Path, "rev-parse", "--verify", id+"^{commit}")
+	if err != nil {
+		return "", concatenateError(err, stderr)
+	}
+	return strings.TrimSpace(stdout), nil
+}
@@ -6,8 +6,12 @@ package git
 
 import (
 	"container/list"
+	"errors"
 	"fmt"
+	"os/exec"
+	"regexp"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/Unknwon/com"
@@ -20,19 +24,137 @@ type PullRequestInfo struct {
 	NumFiles int
 }
 
+// ErrNoMergeBase indicates two commits do not have a common ancestor.
+var ErrNoMergeBase = errors.New("no merge base")
+
 // GetMergeBase checks and returns merge base of two branches.
 func (repo *Repository) GetMergeBase(remoteBranch, headBranch string) (string, error) {
 	// Get merge base commit.
-	stdout, stderr, err := com.ExecCmdDir(repo.Path, "git", "merge-base", remoteBranch, headBranch)
+	stdout, stderr, err := execDir(repo.Path, "merge-base", remoteBranch, headBranch)
 	if err != nil {
+		// Command exits with 1 without any message when there is no common ancestor.
+		if isExitStatus(err, 1) && len(strings.TrimSpace(stderr)) == 0 {
+			return "", ErrNoMergeBase
+		}
 		return "", fmt.Errorf("get merge base: %v", concatenateError(err, stderr))
 	}
 	return strings.TrimSpace(stdout), nil
 }
 
+// isExitStatus returns true if err is caused by command exiting with given status.
+func isExitStatus(err error, status int) bool {
+	exitErr, ok := err.(*exec.ExitError)
+	if !ok {
+		return false
+	}
+	ws, ok := exitErr.Sys().(syscall.WaitStatus)
+	return ok && ws.ExitStatus() == status
+}
+
+// parseMergeTreeWriteTree returns paths of files that have conflicts from output of
+// "git merge-tree --write-tree --name-only --no-messages <branch1> <branch2>",
+// which is the tree ID followed by conflict section of one path per line.
+func parseMergeTreeWriteTree(output string) []string {
+	var conflicts []string
+	lines := strings.Split(output, "\n")
+	for _, line := range lines[1:] {
+		if len(line) == 0 {
+			break
+		}
+		conflicts = append(conflicts, line)
+	}
+	return conflicts
+}
+
+// mergeTreeHeaderPattern matches header of block in output of "git merge-tree",
+// e.g. "  our    100644 <sha> <path>".
+var mergeTreeHeaderPattern = regexp.MustCompile(`^  (?:base|our|their|result) +[0-7]{6} [0-9a-f]{40} (.+)$`)
+
+// mergeTreeBinaryPattern matches warning of "git merge-tree" about binary files
+// that cannot be merged, which have no diff in output.
+var mergeTreeBinaryPattern = regexp.MustCompile(`^warning: Cannot merge binary files: (.+) \(\.our vs\. \.their\)$`)
+
+// parseMergeTreeConflicts returns paths of files that have conflicts from output
+// of legacy "git merge-tree <base-tree> <branch1> <branch2>". Content conflicts are
+// marked in diff, modify/delete conflicts are blocks of "removed in local|remote",
+// and binary conflicts are only reported as warnings in stderr.
+func parseMergeTreeConflicts(stdout, stderr string) []string {
+	var (
+		conflicts   []string
+		curPath     string
+		hasConflict bool
+	)
+	seen := make(map[string]bool)
+	addConflict := func(path string) {
+		if !seen[path] {
+			seen[path] = true
+			conflicts = append(conflicts, path)
+		}
+	}
+	endBlock := func() {
+		if hasConflict && len(curPath) > 0 {
+			addConflict(curPath)
+		}
+		curPath = ""
+		hasConflict = false
+	}
+
+	for _, line := range strings.Split(stdout, "\n") {
+		switch {
+		case len(line) == 0:
+		case mergeTreeHeaderPattern.MatchString(line):
+			curPath = mergeTreeHeaderPattern.FindStringSubmatch(line)[1]
+		case strings.HasPrefix(line, "+<<<<<<< "):
+			hasConflict = true
+		case line[0] == ' ', line[0] == '+', line[0] == '-', line[0] == '@':
+		default:
+			// Start of a new block, e.g. "changed in both".
+			endBlock()
+			// Path is removed on one side but changed on the other.
+			hasConflict = line == "removed in local" || line == "removed in remote"
+		}
+	}
+	endBlock()
+
+	for _, line := range strings.Split(stderr, "\n") {
+		if m := mergeTreeBinaryPattern.FindStringSubmatch(strings.TrimSpace(line)); m != nil {
+			addConflict(m[1])
+		}
+	}
+	return conflicts
+}
+
+// GetMergeConflicts returns paths of files that would conflict when merging
+// head commit into base commit, it does not change any reference or working tree.
+func (repo *Repository) GetMergeConflicts(mergeBase, baseCommitID, headCommitID string) ([]string, error) {
+	gitVer, err := GetVersion()
+	if err != nil {
+		return nil, fmt.Errorf("GetVersion: %v", err)
+	}
+
+	// Real merge with rename detection is available since Git 2.38,
+	// it exits with 1 when there are conflicts.
+	if gitVer.AtLeast(MustParseVersion("2.38.0")) {
+		stdout, stderr, err := execDir(repo.Path, "merge-tree", "--write-tree", "--name-only", "--no-messages", baseCommitID, headCommitID)
+		if err != nil {
+			if isExitStatus(err, 1) {
+				return parseMergeTreeWriteTree(stdout), nil
+			}
+			return nil, fmt.Errorf("merge-tree: %v", concatenateError(err, stderr))
+		}
+		return nil, nil
+	}
+
+	stdout, stderr, err := execDir(repo.Path, "merge-tree", mergeBase, baseCommitID, headCommitID)
+	if err != nil {
+		return nil, fmt.Errorf("merge-tree: %v", concatenateError(err, stderr))
+	}
+	return parseMergeTreeConflicts(stdout, stderr), nil
+}
+
 // AddRemote adds a remote to repository.
 func (repo *Repository) AddRemote(name, path string) error {
-	_, stderr, err := com.ExecCmdDir(repo.Path, "git", "remote", "add", "-f", name, path)
+	_, stderr, err := execDir(repo.Path, "remote", "add", "-f", name, path)
 	if err != nil {
 		return fmt.Errorf("add remote(%s - %s): %v", name, path, concatenateError(err, stderr))
 	}
@@ -41,7 +163,7 @@ func (repo *Repository) AddRemote(name, path string) error {
 
 // RemoveRemote removes a remote from repository.
 func (repo *Repository) RemoveRemote(name string) error {
-	_, stderr, err := com.ExecCmdDir(repo.Path, "git", "remote", "remove", name)
+	_, stderr, err := execDir(repo.Path, "remote", "remove", name)
 	if err != nil {
 		return fmt.Errorf("remove remote(%s): %v", name, concatenateError(err, stderr))
 	}
@@ -68,7 +190,7 @@ func (repo *Repository) GetPullRequestInfo(basePath, baseBranch, headBranch stri
 		return nil, fmt.Errorf("GetMergeBase: %v", err)
 	}
 
-	stdout, stderr, err := com.ExecCmdDir(repo.Path, "git", "log", prInfo.MergeBase+"..."+headBranch, prettyLogFormat)
+	stdout, stderr, err := execDir(repo.Path, "log", prInfo.MergeBase+"..."+headBranch, prettyLogFormat)
 	if err != nil {
 		return nil, fmt.Errorf("list diff logs: %v", concatenateError(err, stderr))
 	}
@@ -78,7 +200,7 @@ func (repo *Repository) GetPullRequestInfo(basePath, baseBranch, headBranch stri
 	}
 
 	// Count number of changed files.
-	stdout, stderr, err = com.ExecCmdDir(repo.Path, "git", "diff", "--name-only", remoteBranch+"..."+headBranch)
+	stdout, stderr, err = execDir(repo.Path, "diff", "--name-only", remoteBranch+"..."+headBranch)
 	if err != nil {
 		return nil, fmt.Errorf("list changed files: %v", concatenateError(err, stderr))
 	}
@@ -89,12 +211,19 @@ func (repo *Repository) GetPullRequestInfo(basePath, baseBranch, headBranch stri
 
 // GetPatch generates and returns patch data between given branches.
 func (repo *Repository) GetPatch(mergeBase, headBranch string) ([]byte, error) {
-	stdout, stderr, err := com.ExecCmdDirBytes(repo.Path, "git", "diff", "-p", "--binary", mergeBase, headBranch)
-	if err != nil {
-		return nil, concatenateError(err, string(stderr))
-	}
+	return repo.GetLimitedPatch(mergeBase, headBranch, 0)
+}
+
+// GetLimitedPatch generates and returns patch data between given branches,
+// it returns ErrPatchTooLarge when patch exceeds limit bytes (unlimited if not positive).
+func (repo *Repository) GetLimitedPatch(mergeBase, headBranch string, limit int64) ([]byte, error) {
+	return execDirLimit(repo.Path, limit, "diff", "-p", "--binary", mergeBase, headBranch)
+}
 
-	return stdout, nil
+// GetFormatPatch generates and returns patches in mailbox format of commits between given commits,
+// one patch per commit. It returns ErrPatchTooLarge when patches exceed limit bytes (unlimited if not positive).
+func (repo *Repository) GetFormatPatch(mergeBase, headCommitID string, limit int64) ([]byte, error) {
+	return execDirLimit(repo.Path, limit, "format-patch", "--binary", "--stdout", mergeBase+".."+headCommitID)
 }
 
 // Merge merges pull request from head repository and branch.
@@ -0,0 +1,77 @@
+// Copyright 2015 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package git
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const mergeTreeOutput = `changed in both
+  base   100644 de980441c3ab03a8c07dda1ad27b8a11f39deb1e f
+  our    100644 f4ea702d479ef1388dde60e3430791a9c6eb8d4f f
+  their  100644 3b6f40af131104cca3a84e7a760c3c3475377106 f
+@@ -1,3 +1,7 @@
+ a
++<<<<<<< .our
+ B1
++=======
++B2
++>>>>>>> .their
+ c
+changed in both
+  base   100644 587be6b4c3f93f93c489c0111bba5596147a26cb dir/file name
+  our    100644 0ff3bbb9c8bba2291654cd64067fa417ff54c508 dir/file name
+  their  100644 e8fb6fbbea2fbd4d5183b6c8e2c2e31f5dfbcc3c dir/file name
+@@ -1,2 +1,3 @@
+   our    100644 0000000000000000000000000000000000000000 indented content
+ x
++y
+added in both
+  our    100644 8ba3a16384aacc37d01564b28401755ce8053f51 new
+  their  100644 45b983be36b73c0788dc9cbcb76cbb80fc7bb057 new
+@@ -1 +1,5 @@
++<<<<<<< .our
+ n
++=======
++hi
++>>>>>>> .their
+`
+
+func Test_parseMergeTreeConflicts(t *testing.T) {
+	Convey("Parse conflicting files from output of merge-tree", t, func() {
+		So(parseMergeTreeConflicts(mergeTreeOutput, ""), ShouldResemble, []string{"f", "new"})
+		So(parseMergeTreeConflicts("", ""), ShouldBeEmpty)
+	})
+
+	Convey("Parse modify/delete and binary conflicts from output of merge-tree", t, func() {
+		stdout := `changed in both
+  base   100644 88768efdf77ec78c9a995f94881793be6a41752b b.bin
+  our    100644 0a23a00c843492115cd4bf693bc01048115892f5 b.bin
+  their  100644 e570710657f8a5ffab9d81371c2caf4dcaf1b658 b.bin
+removed in local
+  base   100644 2fa992c0b8b5c6acd2bdd4fa31de29d29799bdd5 d
+  their  100644 5ea2ed416fbd4a4cbe227b75fe255dd7fa6bd4d6 d
+removed in both
+  base   100644 2fa992c0b8b5c6acd2bdd4fa31de29d29799bdd5 gone
+merged
+  result 100644 215593961549c7ae7ced8a174490f64dbd8d3960 f
+  our    100644 de980441c3ab03a8c07dda1ad27b8a11f39deb1e f
+@@ -1,3 +1,4 @@
+ a
++x
+`
+		stderr := "warning: Cannot merge binary files: b.bin (.our vs. .their)\n"
+		So(parseMergeTreeConflicts(stdout, stderr), ShouldResemble, []string{"d", "b.bin"})
+	})
+}
+
+func Test_parseMergeTreeWriteTree(t *testing.T) {
+	Convey("Parse conflicting files from output of merge-tree --write-tree", t, func() {
+		So(parseMergeTreeWriteTree("8f174f86cdb42716e33b3f64d150ec17fbb9a59b\nb.bin\nd\n"), ShouldResemble, []string{"b.bin", "d"})
+		So(parseMergeTreeWriteTree("8f174f86cdb42716e33b3f64d150ec17fbb9a59b\n"), ShouldBeEmpty)
+	})
+}
@@ -0,0 +1,50 @@
+// Copyright 2015 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package git
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Reference represents a Git reference and the object it points to.
+type Reference struct {
+	Name     string // Full name, e.g. "refs/heads/master".
+	ObjectID string
+	Type     ObjectType
+}
+
+// GetRefs returns all references of repository that match given patterns
+// in order of names, or all references when no pattern is given.
+// Loose and packed references are read by a single command.
+func (repo *Repository) GetRefs(patterns ...string) ([]*Reference, error) {
+	args := append([]string{"for-each-ref", "--format=%(objectname) %(objecttype) %(refname)"}, patterns...)
+	stdout, stderr, err := execDir(repo.Path, args...)
+	if err != nil {
+		return nil, concatenateError(err, stderr)
+	}
+	return parseRefs(stdout)
+}
+
+// parseRefs parses output of "git for-each-ref" in format of
+// "<objectname> <objecttype> <refname>".
+func parseRefs(output string) ([]*Reference, error) {
+	refs := make([]*Reference, 0, 10)
+	for _, line := range strings.Split(output, "\n") {
+		if len(line) == 0 {
+			continue
+		}
+		fields := strings.SplitN(line, " ", 3)
+		if len(fields) != 3 || len(fields[0]) != 40 {
+			return nil, fmt.Errorf("unexpected reference line: %q", line)
+		}
+		refs = append(refs, &Reference{
+			Name:     fields[2],
+			ObjectID: fields[0],
+			Type:     ObjectType(fields[1]),
+		})
+	}
+	return refs, nil
+}
@@ -0,0 +1,38 @@
+// Copyright 2015 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package git
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func Test_parseRefs(t *testing.T) {
+	Convey("Parse output of for-each-ref", t, func() {
+		output := `3d2a1e5d2e1a2b1f3e9c3e0b3a9ce2b6f0d0a1b2 commit refs/heads/master
+8f4c9b2e1d0a3c5e7f9b1d3f5a7c9e1b3d5f7a9c tag refs/tags/v1.0
+`
+		refs, err := parseRefs(output)
+		So(err, ShouldBeNil)
+		So(len(refs), ShouldEqual, 2)
+		So(refs[0].Name, ShouldEqual, "refs/heads/master")
+		So(refs[0].ObjectID, ShouldEqual, "3d2a1e5d2e1a2b1f3e9c3e0b3a9ce2b6f0d0a1b2")
+		So(refs[0].Type, ShouldEqual, COMMIT)
+		So(refs[1].Name, ShouldEqual, "refs/tags/v1.0")
+		So(refs[1].Type, ShouldEqual, TAG)
+	})
+
+	Convey("Parse empty output", t, func() {
+		refs, err := parseRefs("")
+		So(err, ShouldBeNil)
+		So(len(refs), ShouldEqual, 0)
+	})
+
+	Convey("Reject malformed line", t, func() {
+		_, err := parseRefs("master\n")
+		So(err, ShouldNotBeNil)
+	})
+}
@@ -7,12 +7,10 @@ package git
 import (
 	"errors"
 	"strings"
-
-	"github.com/Unknwon/com"
 )
 
 func IsTagExist(repoPath, tagName string) bool {
-	_, _, err := com.ExecCmdDir(repoPath, "git", "show-ref", "--verify", "refs/tags/"+tagName)
+	_, _, err := execDir(repoPath, "show-ref", "--verify", "refs/tags/"+tagName)
 	return err == nil
 }
 
@@ -21,7 +19,7 @@ func (repo *Repository) IsTagExist(tagName string) bool {
 }
 
 func (repo *Repository) getTagsReversed() ([]string, error) {
-	stdout, stderr, err := com.ExecCmdDir(repo.Path, "git", "tag", "-l", "--sort=-v:refname")
+	stdout, stderr, err := execDir(repo.Path, "tag", "-l", "--sort=-v:refname")
 	if err != nil {
 		return nil, concatenateError(err, stderr)
 	}
@@ -34,7 +32,7 @@ func (repo *Repository) GetTags() ([]string, error) {
 	if gitVer.AtLeast(MustParseVersion("2.0.0")) {
 		return repo.getTagsReversed()
 	}
-	stdout, stderr, err := com.ExecCmdDir(repo.Path, "git", "tag", "-l")
+	stdout, stderr, err := execDir(repo.Path, "tag", "-l")
 	if err != nil {
 		return nil, concatenateError(err, stderr)
 	}
@@ -42,8 +40,25 @@ func (repo *Repository) GetTags() ([]string, error) {
 	return tags[:len(tags)-1], nil
 }
 
+// GetTagsByCreatorDate returns names of all tags of given repository,
+// most recently created ones come first.
+func (repo *Repository) GetTagsByCreatorDate() ([]string, error) {
+	stdout, stderr, err := execDir(repo.Path, "for-each-ref", "--sort=-creatordate", "--format=%(refname)", "refs/tags")
+	if err != nil {
+		return nil, concatenateError(err, stderr)
+	}
+
+	tags := make([]string, 0, 10)
+	for _, line := range strings.Split(stdout, "\n") {
+		if len(line) > 0 {
+			tags = append(tags, strings.TrimPrefix(line, "refs/tags/"))
+		}
+	}
+	return tags, nil
+}
+
 func (repo *Repository) CreateTag(tagName, idStr string) error {
-	_, stderr, err := com.ExecCmdDir(repo.Path, "git", "tag", tagName, idStr)
+	_, stderr, err := execDir(repo.Path, "tag", tagName, idStr)
 	if err != nil {
 		return errors.New(stderr)
 	}
@@ -60,7 +75,7 @@ func (repo *Repository) getTag(id sha1) (*Tag, error) {
 	}
 
 	// Get tag type.
-	tp, stderr, err := com.ExecCmdDir(repo.Path, "git", "cat-file", "-t", id.String())
+	tp, stderr, err := execDir(repo.Path, "cat-file", "-t", id.String())
 	if err != nil {
 		return nil, errors.New(stderr)
 	}
@@ -79,7 +94,7 @@ func (repo *Repository) getTag(id sha1) (*Tag, error) {
 	}
 
 	// Tag with message.
-	data, bytErr, err := com.ExecCmdDirBytes(repo.Path, "git", "cat-file", "-p", id.String())
+	data, bytErr, err := execDirBytes(repo.Path, "cat-file", "-p", id.String())
 	if err != nil {
 		return nil, errors.New(string(bytErr))
 	}
@@ -98,7 +113,7 @@ func (repo *Repository) getTag(id sha1) (*Tag, error) {
 
 // GetTag returns a Git tag by given name.
 func (repo *Repository) GetTag(tagName string) (*Tag, error) {
-	stdout, stderr, err := com.ExecCmdDir(repo.Path, "git", "show-ref", "--tags", tagName)
+	stdout, stderr, err := execDir(repo.Path, "show-ref", "--tags", tagName)
 	if err != nil {
 		return nil, errors.New(stderr)
 	}
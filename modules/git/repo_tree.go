@@ -22,7 +22,7 @@ func (repo *Repository) GetTree(idStr string) (*Tree, error) {
 func (repo *Repository) getTree(id sha1) (*Tree, error) {
 	treePath := filepathFromSHA1(repo.Path, id.String())
 	if !com.IsFile(treePath) {
-		_, _, err := com.ExecCmdDir(repo.Path, "git", "ls-tree", id.String())
+		_, _, err := execDir(repo.Path, "ls-tree", id.String())
 		if err != nil {
 			return nil, fmt.Errorf("repo.getTree: %v", ErrNotExist)
 		}
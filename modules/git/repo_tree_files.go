@@ -0,0 +1,65 @@
+// Copyright 2015 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package git
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// TreeFile represents a file in tree of a commit.
+type TreeFile struct {
+	Path string
+	Mode EntryMode
+	Size int64
+}
+
+// ListTreeFiles returns all files in tree of given commit recursively
+// with their sizes. Submodules are not included.
+func (repo *Repository) ListTreeFiles(commitID string) ([]*TreeFile, error) {
+	stdout, stderr, err := execDir(repo.Path, "ls-tree", "-r", "-l", "-z", commitID)
+	if err != nil {
+		return nil, concatenateError(err, stderr)
+	}
+	return parseTreeFiles(stdout)
+}
+
+// parseTreeFiles parses output of "git ls-tree -r -l -z" in format of
+// "<mode> <type> <object> <size>\t<path>", entries are separated by NUL.
+func parseTreeFiles(output string) ([]*TreeFile, error) {
+	files := make([]*TreeFile, 0, 10)
+	for _, entry := range strings.Split(output, "\x00") {
+		if len(entry) == 0 {
+			continue
+		}
+		tab := strings.IndexByte(entry, '\t')
+		if tab == -1 {
+			return nil, fmt.Errorf("unexpected tree entry: %q", entry)
+		}
+		fields := strings.Fields(entry[:tab])
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("unexpected tree entry: %q", entry)
+		}
+		if fields[1] != string(BLOB) {
+			continue
+		}
+
+		mode, err := strconv.ParseInt(fields[0], 8, 32)
+		if err != nil {
+			return nil, fmt.Errorf("parse mode of tree entry %q: %v", entry, err)
+		}
+		size, err := strconv.ParseInt(fields[3], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parse size of tree entry %q: %v", entry, err)
+		}
+		files = append(files, &TreeFile{
+			Path: entry[tab+1:],
+			Mode: EntryMode(mode),
+			Size: size,
+		})
+	}
+	return files, nil
+}
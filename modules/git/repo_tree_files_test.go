@@ -0,0 +1,39 @@
+// Copyright 2015 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package git
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func Test_parseTreeFiles(t *testing.T) {
+	Convey("Parse output of ls-tree", t, func() {
+		output := "100644 blob 3d2a1e5d2e1a2b1f3e9c3e0b3a9ce2b6f0d0a1b2    1024\tmain.go\x00" +
+			"100755 blob 8f4c9b2e1d0a3c5e7f9b1d3f5a7c9e1b3d5f7a9c      12\tscripts/build file.sh\x00" +
+			"160000 commit 1b2c3d4e5f60718293a4b5c6d7e8f90a1b2c3d4e       -\tvendor/lib\x00"
+		files, err := parseTreeFiles(output)
+		So(err, ShouldBeNil)
+		So(len(files), ShouldEqual, 2)
+		So(files[0].Path, ShouldEqual, "main.go")
+		So(files[0].Mode, ShouldEqual, ModeBlob)
+		So(files[0].Size, ShouldEqual, 1024)
+		So(files[1].Path, ShouldEqual, "scripts/build file.sh")
+		So(files[1].Mode, ShouldEqual, ModeExec)
+		So(files[1].Size, ShouldEqual, 12)
+	})
+
+	Convey("Parse empty output", t, func() {
+		files, err := parseTreeFiles("")
+		So(err, ShouldBeNil)
+		So(len(files), ShouldEqual, 0)
+	})
+
+	Convey("Reject malformed entry", t, func() {
+		_, err := parseTreeFiles("100644 blob main.go\x00")
+		So(err, ShouldNotBeNil)
+	})
+}
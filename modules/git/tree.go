@@ -8,8 +8,6 @@ import (
 	"bytes"
 	"errors"
 	"strings"
-
-	"github.com/Unknwon/com"
 )
 
 var (
@@ -137,8 +135,8 @@ func (t *Tree) ListEntries(relpath string) (Entries, error) {
 	}
 	t.entriesParsed = true
 
-	stdout, stderr, err := com.ExecCmdDirBytes(t.repo.Path,
-		"git", "ls-tree", t.ID.String())
+	stdout, stderr, err := execDirBytes(t.repo.Path,
+		"ls-tree", t.ID.String())
 	if err != nil {
 		if strings.Contains(err.Error(), "exit status 128") {
 			return nil, errors.New(strings.TrimSpace(string(stderr)))
@@ -51,7 +51,7 @@ func (te *TreeEntry) Size() int64 {
 		return te.size
 	}
 
-	stdout, _, err := com.ExecCmdDir(te.ptree.repo.Path, "git", "cat-file", "-s", te.ID.String())
+	stdout, _, err := execDir(te.ptree.repo.Path, "cat-file", "-s", te.ID.String())
 	if err != nil {
 		return 0
 	}
@@ -0,0 +1,173 @@
+// Copyright 2015 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package git
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// UploadPackRequest represents a negotiation request sent by client to git-upload-pack.
+type UploadPackRequest struct {
+	Wants    []string
+	Haves    []string
+	Shallows []string
+
+	Depth       int
+	DeepenSince string
+	DeepenNot   []string
+
+	// Done indicates client has finished negotiation and expects a pack file.
+	Done bool
+	// Command is the command requested in protocol version 2, e.g. "ls-refs" or "fetch",
+	// it is empty for earlier protocol versions.
+	Command string
+}
+
+// IsShallow returns true if client asks for a shallow or deepened history.
+func (req *UploadPackRequest) IsShallow() bool {
+	return req.Depth > 0 || len(req.DeepenSince) > 0 || len(req.DeepenNot) > 0
+}
+
+// isObjectID returns true if given string is a full hexadecimal SHA1 object ID.
+func isObjectID(s string) bool {
+	if len(s) != 40 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseUploadPackRequest parses pkt-line formatted request body of git-upload-pack,
+// arguments of fetch command of protocol version 2 are parsed in the same way.
+// Object IDs are validated because they are later passed to Git commands.
+func ParseUploadPackRequest(data []byte) (*UploadPackRequest, error) {
+	req := new(UploadPackRequest)
+	for len(data) > 0 {
+		if len(data) < 4 {
+			return nil, errors.New("truncated pkt-line header")
+		}
+		size, err := strconv.ParseUint(string(data[:4]), 16, 16)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pkt-line length: %v", err)
+		}
+
+		// Flush packet, or delimiter and response end packets of protocol version 2.
+		if size <= 2 {
+			data = data[4:]
+			continue
+		}
+		if size < 4 || int(size) > len(data) {
+			return nil, fmt.Errorf("invalid pkt-line length: %d", size)
+		}
+
+		line := string(bytes.TrimRight(data[4:size], "\n"))
+		data = data[size:]
+
+		// Capabilities may follow a NUL byte.
+		if idx := strings.IndexByte(line, '\000'); idx > -1 {
+			line = line[:idx]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		if strings.HasPrefix(fields[0], "command=") {
+			req.Command = strings.TrimPrefix(fields[0], "command=")
+			continue
+		}
+
+		switch fields[0] {
+		case "want":
+			if len(fields) < 2 {
+				return nil, errors.New("want line without object ID")
+			} else if !isObjectID(fields[1]) {
+				return nil, fmt.Errorf("invalid object ID of want line: %q", fields[1])
+			}
+			req.Wants = append(req.Wants, fields[1])
+		case "have":
+			if len(fields) < 2 {
+				return nil, errors.New("have line without object ID")
+			} else if !isObjectID(fields[1]) {
+				return nil, fmt.Errorf("invalid object ID of have line: %q", fields[1])
+			}
+			req.Haves = append(req.Haves, fields[1])
+		case "shallow":
+			if len(fields) < 2 {
+				return nil, errors.New("shallow line without object ID")
+			} else if !isObjectID(fields[1]) {
+				return nil, fmt.Errorf("invalid object ID of shallow line: %q", fields[1])
+			}
+			req.Shallows = append(req.Shallows, fields[1])
+		case "deepen":
+			if len(fields) < 2 {
+				return nil, errors.New("deepen line without depth")
+			}
+			req.Depth, err = strconv.Atoi(fields[1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid depth: %v", err)
+			}
+		case "deepen-since":
+			if len(fields) < 2 {
+				return nil, errors.New("deepen-since line without timestamp")
+			}
+			req.DeepenSince = fields[1]
+		case "deepen-not":
+			if len(fields) < 2 {
+				return nil, errors.New("deepen-not line without reference")
+			}
+			req.DeepenNot = append(req.DeepenNot, fields[1])
+		case "done":
+			req.Done = true
+		}
+	}
+	return req, nil
+}
+
+// CountFetchObjects returns number of objects that would be sent to client
+// who wants given objects and already has the other ones.
+func (repo *Repository) CountFetchObjects(wants, haves []string) (int64, error) {
+	if len(wants) == 0 {
+		return 0, nil
+	}
+
+	// Revisions are given through standard input so that none of them can be taken as an option.
+	revs := new(bytes.Buffer)
+	for _, want := range wants {
+		revs.WriteString(want + "\n")
+	}
+	for _, have := range haves {
+		revs.WriteString("^" + have + "\n")
+	}
+	stdout, stderr, err := execDirStdin(repo.Path, revs, "rev-list", "--objects", "--count", "--ignore-missing", "--stdin")
+	if err != nil {
+		return 0, concatenateError(err, stderr)
+	}
+	return strconv.ParseInt(strings.TrimSpace(stdout), 10, 64)
+}
+
+// CountTipObjects returns number of objects of given commits without their history,
+// which is the most that a fetch with depth of 1 can send.
+func (repo *Repository) CountTipObjects(wants []string) (int64, error) {
+	if len(wants) == 0 {
+		return 0, nil
+	}
+
+	revs := strings.NewReader(strings.Join(wants, "\n") + "\n")
+	stdout, stderr, err := execDirStdin(repo.Path, revs, "rev-list", "--objects", "--count", "--no-walk", "--ignore-missing", "--stdin")
+	if err != nil {
+		return 0, concatenateError(err, stderr)
+	}
+	return strconv.ParseInt(strings.TrimSpace(stdout), 10, 64)
+}
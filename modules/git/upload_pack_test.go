@@ -0,0 +1,218 @@
+// Copyright 2015 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func pktLine(s string) string {
+	return fmt.Sprintf("%04x%s", len(s)+4, s)
+}
+
+// newFixtureRepo creates a repository with given number of linear commits
+// and returns its path along with commit IDs from oldest to newest.
+func newFixtureRepo(t *testing.T, numCommits int) (string, []string) {
+	dir, err := ioutil.TempDir("", "gogs-upload-pack")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	run := func(args ...string) string {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=Gogs", "GIT_AUTHOR_EMAIL=gogs@fake.local",
+			"GIT_COMMITTER_NAME=Gogs", "GIT_COMMITTER_EMAIL=gogs@fake.local")
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %v - %s", args, err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+
+	run("init", "-q")
+	commits := make([]string, numCommits)
+	for i := 0; i < numCommits; i++ {
+		name := fmt.Sprintf("file%d", i)
+		if err = ioutil.WriteFile(filepath.Join(dir, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+		run("add", name)
+		run("commit", "-q", "-m", name)
+		commits[i] = run("rev-parse", "HEAD")
+	}
+	return dir, commits
+}
+
+func Test_ParseUploadPackRequest(t *testing.T) {
+	Convey("Parse shallow upload-pack request", t, func() {
+		body := pktLine("want 0123456789012345678901234567890123456789 multi_ack_detailed side-band-64k shallow\n") +
+			pktLine("shallow abcdefabcdefabcdefabcdefabcdefabcdefabcd\n") +
+			pktLine("deepen 1\n") +
+			"0000" +
+			pktLine("have 9876543210987654321098765432109876543210\n") +
+			pktLine("done\n")
+
+		req, err := ParseUploadPackRequest([]byte(body))
+		So(err, ShouldBeNil)
+		So(req.Wants, ShouldResemble, []string{"0123456789012345678901234567890123456789"})
+		So(req.Haves, ShouldResemble, []string{"9876543210987654321098765432109876543210"})
+		So(req.Shallows, ShouldResemble, []string{"abcdefabcdefabcdefabcdefabcdefabcdefabcd"})
+		So(req.Depth, ShouldEqual, 1)
+		So(req.IsShallow(), ShouldBeTrue)
+		So(req.Done, ShouldBeTrue)
+	})
+
+	Convey("Parse malformed upload-pack request", t, func() {
+		_, err := ParseUploadPackRequest([]byte("00zzwant"))
+		So(err, ShouldNotBeNil)
+
+		_, err = ParseUploadPackRequest([]byte("0032want 0123"))
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("Reject upload-pack request with invalid object IDs", t, func() {
+		_, err := ParseUploadPackRequest([]byte(pktLine("want --output=/tmp/gogs\n")))
+		So(err, ShouldNotBeNil)
+
+		_, err = ParseUploadPackRequest([]byte(pktLine("want --all\n")))
+		So(err, ShouldNotBeNil)
+
+		_, err = ParseUploadPackRequest([]byte(pktLine("have 0123\n")))
+		So(err, ShouldNotBeNil)
+
+		_, err = ParseUploadPackRequest([]byte(pktLine("shallow master\n")))
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func Test_ShallowFetch(t *testing.T) {
+	dir, commits := newFixtureRepo(t, 3)
+	defer os.RemoveAll(dir)
+
+	Convey("Serve shallow fetch from fixture repository", t, func() {
+		head := commits[len(commits)-1]
+		body := pktLine("want "+head+" multi_ack_detailed no-done shallow\n") +
+			pktLine("deepen 1\n") +
+			"0000" +
+			pktLine("done\n")
+
+		req, err := ParseUploadPackRequest([]byte(body))
+		So(err, ShouldBeNil)
+		So(req.IsShallow(), ShouldBeTrue)
+
+		cmd := exec.Command("git", "upload-pack", "--stateless-rpc", dir)
+		cmd.Stdin = strings.NewReader(body)
+		out, err := cmd.Output()
+		So(err, ShouldBeNil)
+		So(bytes.Contains(out, []byte("shallow "+head)), ShouldBeTrue)
+		So(bytes.Contains(out, []byte("PACK")), ShouldBeTrue)
+	})
+
+	Convey("Count objects of full and incremental fetch", t, func() {
+		repo, err := OpenRepository(dir)
+		So(err, ShouldBeNil)
+
+		head := commits[len(commits)-1]
+
+		// Each commit introduces a commit, a tree and a blob object.
+		count, err := repo.CountFetchObjects([]string{head}, nil)
+		So(err, ShouldBeNil)
+		So(count, ShouldEqual, 9)
+
+		count, err = repo.CountFetchObjects([]string{head}, []string{commits[0]})
+		So(err, ShouldBeNil)
+		So(count, ShouldEqual, 6)
+
+		// Objects client has but server does not know about are ignored.
+		count, err = repo.CountFetchObjects([]string{head}, []string{"0123456789012345678901234567890123456789"})
+		So(err, ShouldBeNil)
+		So(count, ShouldEqual, 9)
+	})
+
+	Convey("Count objects of fetch with depth of 1", t, func() {
+		repo, err := OpenRepository(dir)
+		So(err, ShouldBeNil)
+
+		// Tip commit, its tree and all three blobs.
+		count, err := repo.CountTipObjects([]string{commits[len(commits)-1]})
+		So(err, ShouldBeNil)
+		So(count, ShouldEqual, 5)
+	})
+}
+
+func Test_ProtocolV2(t *testing.T) {
+	dir, commits := newFixtureRepo(t, 1)
+	defer os.RemoveAll(dir)
+
+	// Create many refs so that advertisement of version 0 becomes large.
+	for i := 0; i < 200; i++ {
+		cmd := exec.Command("git", "update-ref", fmt.Sprintf("refs/tags/v%d", i), commits[0])
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("update-ref: %v - %s", err, out)
+		}
+	}
+
+	advertise := func(params string) []byte {
+		cmd := exec.Command("git", "upload-pack", "--stateless-rpc", "--advertise-refs", dir)
+		cmd.Env = os.Environ()
+		if len(params) > 0 {
+			cmd.Env = append(cmd.Env, ENV_GIT_PROTOCOL+"="+params)
+		}
+		out, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("upload-pack: %v", err)
+		}
+		return out
+	}
+
+	Convey("Detect protocol version 2 parameters", t, func() {
+		So(IsProtocolV2("version=2"), ShouldBeTrue)
+		So(IsProtocolV2("foo=bar:version=2"), ShouldBeTrue)
+		So(IsProtocolV2("version=1"), ShouldBeFalse)
+		So(IsProtocolV2(""), ShouldBeFalse)
+	})
+
+	Convey("Advertise capabilities instead of refs with protocol version 2", t, func() {
+		v0 := advertise("")
+		So(bytes.Contains(v0, []byte("refs/tags/v199")), ShouldBeTrue)
+
+		v2 := advertise("version=2")
+		So(bytes.Contains(v2, []byte("version 2")), ShouldBeTrue)
+		So(bytes.Contains(v2, []byte("ls-refs")), ShouldBeTrue)
+		So(bytes.Contains(v2, []byte("refs/tags/")), ShouldBeFalse)
+		So(len(v2), ShouldBeLessThan, len(v0)/10)
+	})
+
+	Convey("Parse fetch command of protocol version 2", t, func() {
+		body := pktLine("command=fetch\n") +
+			"0001" +
+			pktLine("want "+commits[0]+"\n") +
+			pktLine("done\n") +
+			"0000"
+
+		req, err := ParseUploadPackRequest([]byte(body))
+		So(err, ShouldBeNil)
+		So(req.Wants, ShouldResemble, []string{commits[0]})
+		So(req.Done, ShouldBeTrue)
+		So(req.Command, ShouldEqual, "fetch")
+
+		req, err = ParseUploadPackRequest([]byte(pktLine("command=ls-refs\n") + "0001" + pktLine("peel\n") + "0000"))
+		So(err, ShouldBeNil)
+		So(req.Command, ShouldEqual, "ls-refs")
+		So(req.Wants, ShouldBeEmpty)
+	})
+}
@@ -89,7 +89,7 @@ func GetVersion() (*Version, error) {
 		return gitVer, nil
 	}
 
-	stdout, stderr, err := com.ExecCmd("git", "version")
+	stdout, stderr, err := execDir("", "version")
 	if err != nil {
 		return nil, errors.New(stderr)
 	}
@@ -0,0 +1,44 @@
+// Copyright 2015 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package highlight
+
+import (
+	"path"
+	"strings"
+
+	"github.com/gogits/gogs/modules/setting"
+)
+
+// highlightMapping maps file extensions or names to languages,
+// which takes precedence over detection of highlight.js.
+var highlightMapping = map[string]string{}
+
+// NewContext loads custom mapping from section "highlight.mapping" of settings,
+// keys are file extensions (e.g. ".tf") or full file names (e.g. "Jenkinsfile").
+func NewContext() {
+	keys := setting.Cfg.Section("highlight.mapping").Keys()
+	for i := range keys {
+		highlightMapping[strings.ToLower(keys[i].Name())] = keys[i].Value()
+	}
+}
+
+// FileNameToHighlightClass returns class name used by highlight.js for given file name.
+// Files without extension are shown as plain text unless mapped by settings,
+// and highlight.js shows plain text itself for languages it does not support.
+func FileNameToHighlightClass(fname string) string {
+	fname = strings.ToLower(path.Base(fname))
+	if lang, ok := highlightMapping[fname]; ok {
+		return "lang-" + lang
+	}
+
+	ext := path.Ext(fname)
+	if len(ext) == 0 {
+		return "nohighlight"
+	}
+	if lang, ok := highlightMapping[ext]; ok {
+		return "lang-" + lang
+	}
+	return "lang-" + ext[1:]
+}
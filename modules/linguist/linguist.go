@@ -0,0 +1,210 @@
+// Copyright 2015 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package linguist detects programming languages of files in repositories.
+package linguist
+
+import (
+	"path"
+	"strings"
+)
+
+// fileNameLanguages maps lower cased full file names to languages.
+var fileNameLanguages = map[string]string{
+	"cmakelists.txt": "CMake",
+	"dockerfile":     "Dockerfile",
+	"gnumakefile":    "Makefile",
+	"makefile":       "Makefile",
+	"rakefile":       "Ruby",
+	"gemfile":        "Ruby",
+	"vagrantfile":    "Ruby",
+}
+
+// extLanguages maps lower cased file extensions to languages,
+// files of other extensions such as documents and data files are not counted.
+var extLanguages = map[string]string{
+	".c":        "C",
+	".h":        "C",
+	".cc":       "C++",
+	".cpp":      "C++",
+	".cxx":      "C++",
+	".hh":       "C++",
+	".hpp":      "C++",
+	".cs":       "C#",
+	".clj":      "Clojure",
+	".cljs":     "Clojure",
+	".coffee":   "CoffeeScript",
+	".css":      "CSS",
+	".d":        "D",
+	".dart":     "Dart",
+	".erl":      "Erlang",
+	".ex":       "Elixir",
+	".exs":      "Elixir",
+	".elm":      "Elm",
+	".f90":      "Fortran",
+	".fs":       "F#",
+	".go":       "Go",
+	".groovy":   "Groovy",
+	".hs":       "Haskell",
+	".html":     "HTML",
+	".htm":      "HTML",
+	".java":     "Java",
+	".js":       "JavaScript",
+	".jsx":      "JavaScript",
+	".jl":       "Julia",
+	".kt":       "Kotlin",
+	".less":     "Less",
+	".lua":      "Lua",
+	".m":        "Objective-C",
+	".mm":       "Objective-C++",
+	".ml":       "OCaml",
+	".php":      "PHP",
+	".pl":       "Perl",
+	".pm":       "Perl",
+	".ps1":      "PowerShell",
+	".py":       "Python",
+	".r":        "R",
+	".rb":       "Ruby",
+	".rs":       "Rust",
+	".scala":    "Scala",
+	".scss":     "SCSS",
+	".sass":     "Sass",
+	".sh":       "Shell",
+	".bash":     "Shell",
+	".sql":      "SQL",
+	".swift":    "Swift",
+	".tmpl":     "Go Template",
+	".ts":       "TypeScript",
+	".tsx":      "TypeScript",
+	".vb":       "Visual Basic",
+	".vim":      "Vim script",
+	".vue":      "Vue",
+	".xslt":     "XSLT",
+	".m4":       "M4",
+	".asm":      "Assembly",
+	".s":        "Assembly",
+	".proto":    "Protocol Buffer",
+	".tf":       "HCL",
+	".nim":      "Nim",
+	".zig":      "Zig",
+	".cmake":    "CMake",
+	".mk":       "Makefile",
+	".hcl":      "HCL",
+	".cr":       "Crystal",
+	".pas":      "Pascal",
+	".lisp":     "Common Lisp",
+	".el":       "Emacs Lisp",
+	".scm":      "Scheme",
+	".tcl":      "Tcl",
+	".v":        "Verilog",
+	".vhd":      "VHDL",
+	".vhdl":     "VHDL",
+	".bat":      "Batchfile",
+	".cmd":      "Batchfile",
+	".styl":     "Stylus",
+	".haml":     "Haml",
+	".jade":     "Jade",
+	".mustache": "Mustache",
+}
+
+// defaultVendoredPrefixes are paths treated as vendored unless
+// they are explicitly unset by ".gitattributes".
+var defaultVendoredPrefixes = []string{
+	"vendor/",
+	"node_modules/",
+	"bower_components/",
+	"Godeps/_workspace/",
+	"third_party/",
+}
+
+// DetectLanguage returns language of given file by its name or extension,
+// or an empty string if file is not in any known language.
+func DetectLanguage(fpath string) string {
+	name := strings.ToLower(path.Base(fpath))
+	if lang, ok := fileNameLanguages[name]; ok {
+		return lang
+	}
+	return extLanguages[path.Ext(name)]
+}
+
+// attrRule represents a line of ".gitattributes" that sets or unsets
+// "linguist-vendored" or "linguist-generated" attributes.
+type attrRule struct {
+	pattern  string
+	excluded bool
+}
+
+// Attributes contains rules of ".gitattributes" that decide
+// whether a path is excluded from language statistics.
+type Attributes struct {
+	rules []*attrRule
+}
+
+// ParseAttributes parses content of ".gitattributes", lines that do not
+// set or unset "linguist-vendored" or "linguist-generated" are ignored.
+func ParseAttributes(data string) *Attributes {
+	attrs := new(Attributes)
+	for _, line := range strings.Split(data, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		for _, attr := range fields[1:] {
+			var excluded bool
+			switch attr {
+			case "linguist-vendored", "linguist-generated",
+				"linguist-vendored=true", "linguist-generated=true":
+				excluded = true
+			case "-linguist-vendored", "-linguist-generated",
+				"linguist-vendored=false", "linguist-generated=false":
+				excluded = false
+			default:
+				continue
+			}
+			attrs.rules = append(attrs.rules, &attrRule{
+				pattern:  strings.TrimPrefix(fields[0], "/"),
+				excluded: excluded,
+			})
+		}
+	}
+	return attrs
+}
+
+// matchAttrPattern returns true if pattern matches given path or any of its parent directories.
+// Patterns without slash match a name at any level, others match from root of repository.
+func matchAttrPattern(pattern, fpath string) bool {
+	if !strings.Contains(pattern, "/") {
+		for _, name := range strings.Split(fpath, "/") {
+			if matched, _ := path.Match(pattern, name); matched {
+				return true
+			}
+		}
+		return false
+	}
+
+	pattern = strings.TrimSuffix(strings.TrimSuffix(pattern, "**"), "/")
+	for p := fpath; p != "." && p != "/"; p = path.Dir(p) {
+		if matched, _ := path.Match(pattern, p); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// IsExcluded returns true if given path is vendored or generated,
+// the last matching rule takes precedence over default vendored paths.
+func (attrs *Attributes) IsExcluded(fpath string) bool {
+	for i := len(attrs.rules) - 1; i >= 0; i-- {
+		if matchAttrPattern(attrs.rules[i].pattern, fpath) {
+			return attrs.rules[i].excluded
+		}
+	}
+
+	for _, prefix := range defaultVendoredPrefixes {
+		if strings.HasPrefix(fpath, prefix) || strings.Contains(fpath, "/"+prefix) {
+			return true
+		}
+	}
+	return false
+}
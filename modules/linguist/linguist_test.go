@@ -0,0 +1,44 @@
+// Copyright 2015 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package linguist
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func Test_DetectLanguage(t *testing.T) {
+	Convey("Detect language by extension or file name", t, func() {
+		So(DetectLanguage("cmd/web.go"), ShouldEqual, "Go")
+		So(DetectLanguage("public/js/App.JS"), ShouldEqual, "JavaScript")
+		So(DetectLanguage("Makefile"), ShouldEqual, "Makefile")
+		So(DetectLanguage("README.md"), ShouldEqual, "")
+		So(DetectLanguage("LICENSE"), ShouldEqual, "")
+	})
+}
+
+func Test_Attributes(t *testing.T) {
+	Convey("Exclude default vendored paths", t, func() {
+		attrs := ParseAttributes("")
+		So(attrs.IsExcluded("vendor/github.com/foo/bar.go"), ShouldBeTrue)
+		So(attrs.IsExcluded("public/node_modules/jquery.js"), ShouldBeTrue)
+		So(attrs.IsExcluded("models/repo.go"), ShouldBeFalse)
+	})
+
+	Convey("Exclude paths by rules of .gitattributes", t, func() {
+		attrs := ParseAttributes(`# Comment
+*.min.js linguist-vendored
+/public/assets/** linguist-generated=true
+vendor/** -linguist-vendored
+*.go text eol=lf
+`)
+		So(attrs.IsExcluded("public/js/jquery.min.js"), ShouldBeTrue)
+		So(attrs.IsExcluded("public/assets/css/app.css"), ShouldBeTrue)
+		So(attrs.IsExcluded("public/js/app.js"), ShouldBeFalse)
+		So(attrs.IsExcluded("vendor/github.com/foo/bar.go"), ShouldBeFalse)
+		So(attrs.IsExcluded("models/repo.go"), ShouldBeFalse)
+	})
+}
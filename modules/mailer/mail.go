@@ -5,25 +5,32 @@
 package mailer
 
 import (
+	"bytes"
 	"fmt"
+	"html/template"
 	"path"
 
+	"github.com/Unknwon/com"
 	"gopkg.in/macaron.v1"
 
 	"github.com/gogits/gogs/models"
 	"github.com/gogits/gogs/modules/base"
 	"github.com/gogits/gogs/modules/log"
 	"github.com/gogits/gogs/modules/setting"
+	gogstemplate "github.com/gogits/gogs/modules/template"
 )
 
 const (
-	AUTH_ACTIVATE        base.TplName = "mail/auth/activate"
-	AUTH_ACTIVATE_EMAIL  base.TplName = "mail/auth/activate_email"
-	AUTH_REGISTER_NOTIFY base.TplName = "mail/auth/register_notify"
-	AUTH_RESET_PASSWORD  base.TplName = "mail/auth/reset_passwd"
+	AUTH_ACTIVATE         base.TplName = "mail/auth/activate"
+	AUTH_ACTIVATE_EMAIL   base.TplName = "mail/auth/activate_email"
+	AUTH_APPROVAL_REQUEST base.TplName = "mail/auth/approval_request"
+	AUTH_REGISTER_NOTIFY  base.TplName = "mail/auth/register_notify"
+	AUTH_RESET_PASSWORD   base.TplName = "mail/auth/reset_passwd"
 
 	NOTIFY_COLLABORATOR base.TplName = "mail/notify/collaborator"
 	NOTIFY_MENTION      base.TplName = "mail/notify/mention"
+	NOTIFY_USER_EXPORT  base.TplName = "mail/notify/user_export"
+	NOTIFY_WATCH        base.TplName = "mail/notify/watch"
 )
 
 func ComposeTplData(u *models.User) map[interface{}]interface{} {
@@ -40,12 +47,32 @@ func ComposeTplData(u *models.User) map[interface{}]interface{} {
 	return data
 }
 
+// renderMail renders given mail template, a template with same name
+// in custom directory takes precedence over the built-in one.
+func renderMail(r macaron.Render, tpl base.TplName, data map[interface{}]interface{}) (string, error) {
+	customPath := path.Join(setting.CustomPath, "templates", string(tpl)+".tmpl")
+	if com.IsFile(customPath) {
+		t, err := template.New(path.Base(customPath)).Funcs(gogstemplate.Funcs).ParseFiles(customPath)
+		if err != nil {
+			log.Warn("Fail to parse custom mail template '%s', fallback to default: %v", customPath, err)
+		} else {
+			buf := new(bytes.Buffer)
+			if err = t.Execute(buf, data); err != nil {
+				log.Warn("Fail to render custom mail template '%s', fallback to default: %v", customPath, err)
+			} else {
+				return buf.String(), nil
+			}
+		}
+	}
+	return r.HTMLString(string(tpl), data)
+}
+
 func SendUserMail(c *macaron.Context, u *models.User, tpl base.TplName, code, subject, info string) {
 	data := ComposeTplData(u)
 	data["Code"] = code
-	body, err := c.HTMLString(string(tpl), data)
+	body, err := renderMail(c, tpl, data)
 	if err != nil {
-		log.Error(4, "HTMLString: %v", err)
+		log.Error(4, "renderMail: %v", err)
 		return
 	}
 
@@ -66,9 +93,9 @@ func SendResetPasswordMail(c *macaron.Context, u *models.User) {
 
 // SendRegisterNotifyMail triggers a notify e-mail by admin created a account.
 func SendRegisterNotifyMail(c *macaron.Context, u *models.User) {
-	body, err := c.HTMLString(string(AUTH_REGISTER_NOTIFY), ComposeTplData(u))
+	body, err := renderMail(c, AUTH_REGISTER_NOTIFY, ComposeTplData(u))
 	if err != nil {
-		log.Error(4, "HTMLString: %v", err)
+		log.Error(4, "renderMail: %v", err)
 		return
 	}
 
@@ -78,14 +105,45 @@ func SendRegisterNotifyMail(c *macaron.Context, u *models.User) {
 	SendAsync(msg)
 }
 
+// SendApprovalRequestMail notifies site administrators that
+// a self-registered account is waiting for their approval.
+func SendApprovalRequestMail(c *macaron.Context, u *models.User) {
+	admins, err := models.GetAdminUsers()
+	if err != nil {
+		log.Error(4, "GetAdminUsers: %v", err)
+		return
+	}
+
+	body, err := renderMail(c, AUTH_APPROVAL_REQUEST, ComposeTplData(u))
+	if err != nil {
+		log.Error(4, "renderMail: %v", err)
+		return
+	}
+
+	tos := make([]string, 0, len(admins))
+	for i := range admins {
+		if admins[i].IsActive {
+			tos = append(tos, admins[i].Email)
+		}
+	}
+	if len(tos) == 0 {
+		return
+	}
+
+	msg := NewMessage(tos, c.Tr("mail.approval_request", u.Name), body)
+	msg.Info = fmt.Sprintf("UID: %d, approval request", u.Id)
+
+	SendAsync(msg)
+}
+
 // SendActivateAccountMail sends confirmation e-mail.
 func SendActivateEmailMail(c *macaron.Context, u *models.User, email *models.EmailAddress) {
 	data := ComposeTplData(u)
 	data["Code"] = u.GenerateEmailActivateCode(email.Email)
 	data["Email"] = email.Email
-	body, err := c.HTMLString(string(AUTH_ACTIVATE_EMAIL), data)
+	body, err := renderMail(c, AUTH_ACTIVATE_EMAIL, data)
 	if err != nil {
-		log.Error(4, "HTMLString: %v", err)
+		log.Error(4, "renderMail: %v", err)
 		return
 	}
 
@@ -95,14 +153,24 @@ func SendActivateEmailMail(c *macaron.Context, u *models.User, email *models.Ema
 	SendAsync(msg)
 }
 
-// SendIssueNotifyMail sends mail notification of all watchers of repository.
-func SendIssueNotifyMail(u, owner *models.User, repo *models.Repository, issue *models.Issue) ([]string, error) {
+// SendIssueNotifyMail sends mail notification to watchers of repository about new activity of issue
+// according to their preferences, and returns lower names of users who are notified.
+// Every watcher receives a separate e-mail so that e-mail addresses are not disclosed to each other.
+func SendIssueNotifyMail(r macaron.Render, u, owner *models.User, repo *models.Repository, issue *models.Issue) ([]string, error) {
 	ws, err := models.GetWatchers(repo.ID)
 	if err != nil {
 		return nil, fmt.Errorf("GetWatchers[%d]: %v", repo.ID, err)
 	}
 
-	tos := make([]string, 0, len(ws))
+	subject := fmt.Sprintf("[%s] %s (#%d)", repo.Name, issue.Name, issue.Index)
+	repoLink := owner.Name + "/" + repo.Name
+	issueLink := fmt.Sprintf("%s/issues/%d", repoLink, issue.Index)
+	item := &watchNotifyItem{
+		ActUserName: u.DisplayName(),
+		Content:     string(base.RenderSpecialLink([]byte(issue.Content), repoLink)),
+	}
+
+	names := make([]string, 0, len(ws))
 	for i := range ws {
 		uid := ws[i].UserID
 		if u.Id == uid {
@@ -112,26 +180,26 @@ func SendIssueNotifyMail(u, owner *models.User, repo *models.Repository, issue *
 		if err != nil {
 			return nil, fmt.Errorf("GetUserByID: %v", err)
 		}
-		if to.IsOrganization() {
+		if to.IsOrganization() || !to.IsActive {
 			continue
 		}
 
-		tos = append(tos, to.Email)
-	}
+		switch to.WatchNotifyMode {
+		case models.WATCH_NOTIFY_NONE:
+			continue
+		case models.WATCH_NOTIFY_PARTICIPATING:
+			isParticipant, err := models.IsParticipantOfIssue(uid, issue.ID)
+			if err != nil {
+				return nil, fmt.Errorf("IsParticipantOfIssue: %v", err)
+			} else if !isParticipant {
+				continue
+			}
+		}
 
-	if len(tos) == 0 {
-		return tos, nil
+		queueWatchNotifyMail(r, to, subject, repoLink, issueLink, item)
+		names = append(names, to.LowerName)
 	}
-
-	subject := fmt.Sprintf("[%s] %s (#%d)", repo.Name, issue.Name, issue.Index)
-	content := fmt.Sprintf("%s<br>-<br> <a href=\"%s%s/%s/issues/%d\">View it on Gogs</a>.",
-		base.RenderSpecialLink([]byte(issue.Content), owner.Name+"/"+repo.Name),
-		setting.AppUrl, owner.Name, repo.Name, issue.Index)
-	msg := NewMessage(tos, subject, content)
-	msg.Info = fmt.Sprintf("Subject: %s, issue notify", subject)
-
-	SendAsync(msg)
-	return tos, nil
+	return names, nil
 }
 
 // SendIssueMentionMail sends mail notification for who are mentioned in issue.
@@ -150,9 +218,9 @@ func SendIssueMentionMail(r macaron.Render, u, owner *models.User,
 	data["ActUserName"] = u.DisplayName()
 	data["Content"] = string(base.RenderSpecialLink([]byte(issue.Content), owner.Name+"/"+repo.Name))
 
-	body, err := r.HTMLString(string(NOTIFY_MENTION), data)
+	body, err := renderMail(r, NOTIFY_MENTION, data)
 	if err != nil {
-		return fmt.Errorf("HTMLString: %v", err)
+		return fmt.Errorf("renderMail: %v", err)
 	}
 
 	msg := NewMessage(tos, subject, body)
@@ -170,9 +238,9 @@ func SendCollaboratorMail(r macaron.Render, u, doer *models.User, repo *models.R
 	data["RepoLink"] = path.Join(repo.Owner.Name, repo.Name)
 	data["Subject"] = subject
 
-	body, err := r.HTMLString(string(NOTIFY_COLLABORATOR), data)
+	body, err := renderMail(r, NOTIFY_COLLABORATOR, data)
 	if err != nil {
-		return fmt.Errorf("HTMLString: %v", err)
+		return fmt.Errorf("renderMail: %v", err)
 	}
 
 	msg := NewMessage([]string{u.Email}, subject, body)
@@ -181,3 +249,24 @@ func SendCollaboratorMail(r macaron.Render, u, doer *models.User, repo *models.R
 	SendAsync(msg)
 	return nil
 }
+
+// NewUserExportMail composes mail notification to user that data export is ready.
+// It is composed before export starts because rendering is only available while
+// handling request, and sent by caller once export is done.
+func NewUserExportMail(r macaron.Render, u *models.User, e *models.UserExport) (*Message, error) {
+	subject := "Your data export is ready"
+
+	data := ComposeTplData(u)
+	data["Subject"] = subject
+	data["Export"] = e
+	data["LifetimeHours"] = int(models.USER_EXPORT_LIFETIME.Hours())
+
+	body, err := renderMail(r, NOTIFY_USER_EXPORT, data)
+	if err != nil {
+		return nil, fmt.Errorf("renderMail: %v", err)
+	}
+
+	msg := NewMessage([]string{u.Email}, subject, body)
+	msg.Info = fmt.Sprintf("UID: %d, user export", u.Id)
+	return msg, nil
+}
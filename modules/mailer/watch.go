@@ -0,0 +1,86 @@
+// Copyright 2015 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package mailer
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"gopkg.in/macaron.v1"
+
+	"github.com/gogits/gogs/models"
+	"github.com/gogits/gogs/modules/log"
+	"github.com/gogits/gogs/modules/setting"
+)
+
+// watchNotifyItem represents a single activity of an issue.
+type watchNotifyItem struct {
+	ActUserName string
+	Content     string
+}
+
+// watchNotifyBatch represents activities of an issue collected for a watcher,
+// they are sent in a single e-mail to avoid flooding the watcher.
+type watchNotifyBatch struct {
+	To        *models.User
+	Subject   string
+	RepoLink  string
+	IssueLink string
+	Items     []*watchNotifyItem
+}
+
+var watchNotifyBatches = struct {
+	sync.Mutex
+	batches map[string]*watchNotifyBatch
+}{batches: make(map[string]*watchNotifyBatch)}
+
+func sendWatchNotifyMail(r macaron.Render, b *watchNotifyBatch) {
+	data := ComposeTplData(b.To)
+	data["Subject"] = b.Subject
+	data["RepoLink"] = b.RepoLink
+	data["IssueLink"] = b.IssueLink
+	data["Items"] = b.Items
+
+	body, err := renderMail(r, NOTIFY_WATCH, data)
+	if err != nil {
+		log.Error(4, "renderMail: %v", err)
+		return
+	}
+
+	msg := NewMessage([]string{b.To.Email}, b.Subject, body)
+	msg.Info = fmt.Sprintf("UID: %d, Subject: %s, watch notify", b.To.Id, b.Subject)
+
+	SendAsync(msg)
+}
+
+// queueWatchNotifyMail adds activity to the batch of watcher for the issue,
+// batch is sent after configured interval since its first activity.
+func queueWatchNotifyMail(r macaron.Render, to *models.User, subject, repoLink, issueLink string, item *watchNotifyItem) {
+	if setting.Service.WatchNotifyBatchInterval <= 0 {
+		sendWatchNotifyMail(r, &watchNotifyBatch{to, subject, repoLink, issueLink, []*watchNotifyItem{item}})
+		return
+	}
+
+	key := fmt.Sprintf("%d:%s", to.Id, issueLink)
+
+	watchNotifyBatches.Lock()
+	defer watchNotifyBatches.Unlock()
+
+	if b, ok := watchNotifyBatches.batches[key]; ok {
+		b.Items = append(b.Items, item)
+		return
+	}
+
+	b := &watchNotifyBatch{to, subject, repoLink, issueLink, []*watchNotifyItem{item}}
+	watchNotifyBatches.batches[key] = b
+	time.AfterFunc(setting.Service.WatchNotifyBatchInterval, func() {
+		watchNotifyBatches.Lock()
+		delete(watchNotifyBatches.batches, key)
+		watchNotifyBatches.Unlock()
+
+		sendWatchNotifyMail(r, b)
+	})
+}
@@ -13,7 +13,6 @@ import (
 
 	"github.com/gogits/gogs/models"
 	"github.com/gogits/gogs/modules/auth"
-	"github.com/gogits/gogs/modules/base"
 	"github.com/gogits/gogs/modules/log"
 	"github.com/gogits/gogs/modules/setting"
 )
@@ -54,7 +53,7 @@ func AutoSignIn(ctx *Context) (bool, error) {
 	}
 
 	if val, _ := ctx.GetSuperSecureCookie(
-		base.EncodeMD5(u.Rands+u.Passwd), setting.CookieRememberName); val != u.Name {
+		u.RememberCookieSecret(), setting.CookieRememberName); val != u.Name {
 		return false, nil
 	}
 
@@ -64,6 +63,16 @@ func AutoSignIn(ctx *Context) (bool, error) {
 	return true, nil
 }
 
+// isMutatingMethod returns true if requests of given method may change data,
+// thus CSRF token is required for them.
+func isMutatingMethod(method string) bool {
+	switch method {
+	case "POST", "PUT", "PATCH", "DELETE":
+		return true
+	}
+	return false
+}
+
 func Toggle(options *ToggleOptions) macaron.Handler {
 	return func(ctx *Context) {
 		// Cannot view any page before installation.
@@ -84,7 +93,7 @@ func Toggle(options *ToggleOptions) macaron.Handler {
 			return
 		}
 
-		if !options.SignOutRequire && !options.DisableCsrf && ctx.Req.Method == "POST" && !auth.IsAPIPath(ctx.Req.URL.Path) {
+		if !options.SignOutRequire && !options.DisableCsrf && isMutatingMethod(ctx.Req.Method) && !auth.IsAPIPath(ctx.Req.URL.Path) {
 			csrf.Validate(ctx.Context, ctx.csrf)
 			if ctx.Written() {
 				return
@@ -132,11 +141,61 @@ func Toggle(options *ToggleOptions) macaron.Handler {
 }
 
 // Contexter middleware already checks token for user sign in process.
+// Tokens with limited scopes are only allowed to read repositories
+// unless they have write scope.
 func ApiReqToken() macaron.Handler {
+	return apiReqToken("")
+}
+
+// ApiReqTokenScope is same as ApiReqToken but tokens with limited scopes
+// must have given scope regardless of request method,
+// it is used by endpoints that are not about repositories.
+func ApiReqTokenScope(scope string) macaron.Handler {
+	return apiReqToken(scope)
+}
+
+func apiReqToken(requiredScope string) macaron.Handler {
+	return func(ctx *Context) {
+		if !ctx.IsSigned {
+			ctx.APIError(401, "", "Access token is invalid or not provided.")
+			return
+		}
+
+		if ctx.AccessToken != nil {
+			scope := requiredScope
+			if len(scope) == 0 {
+				scope = models.ACCESS_TOKEN_SCOPE_REPO_WRITE
+				if ctx.Req.Method == "GET" || ctx.Req.Method == "HEAD" {
+					scope = models.ACCESS_TOKEN_SCOPE_REPO_READ
+				}
+			}
+			if !ctx.AccessToken.HasScope(scope) {
+				ctx.APIError(403, "", "Access token does not have required scope: "+scope)
+				return
+			}
+
+			if err := ctx.AccessToken.UpdateLastUsed(ctx.RemoteIP); err != nil {
+				log.Error(4, "UpdateLastUsed: %v", err)
+			}
+		}
+	}
+}
+
+// ApiReqAdmin requires signed in user to be a site administrator,
+// and access token to have admin scope if used.
+func ApiReqAdmin() macaron.Handler {
 	return func(ctx *Context) {
 		if !ctx.IsSigned {
 			ctx.Error(401)
 			return
+		} else if !ctx.User.IsAdmin {
+			ctx.Error(403)
+			return
+		}
+
+		if ctx.AccessToken != nil && !ctx.AccessToken.HasScope(models.ACCESS_TOKEN_SCOPE_ADMIN_USER) {
+			ctx.APIError(403, "", "Access token does not have required scope: "+models.ACCESS_TOKEN_SCOPE_ADMIN_USER)
+			return
 		}
 	}
 }
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"html/template"
 	"io"
+	"net"
 	"net/http"
 	"strings"
 	"time"
@@ -58,6 +59,11 @@ type Context struct {
 	User        *models.User
 	IsSigned    bool
 	IsBasicAuth bool
+	// AccessToken is the token used to sign in through API, if any.
+	AccessToken *models.AccessToken
+
+	// RemoteIP is the IP address of client, resolved through trusted reverse proxies.
+	RemoteIP string
 
 	Repo *RepoContext
 
@@ -151,6 +157,8 @@ func (ctx *Context) Handle(status int, title string, err error) {
 		ctx.Data["Title"] = "Page Not Found"
 	case 500:
 		ctx.Data["Title"] = "Internal Server Error"
+	case 503:
+		ctx.Data["Title"] = "Service Unavailable"
 	}
 	ctx.HTML(status, base.TplName(fmt.Sprintf("status/%d", status)))
 }
@@ -181,6 +189,42 @@ func (ctx *Context) APIError(status int, title string, obj interface{}) {
 	})
 }
 
+// CheckETag sets ETag header of response with given tag and returns true
+// when client already has the same version of resource via If-None-Match header.
+// In that case status 304 has been written and caller should not write any content.
+func (ctx *Context) CheckETag(tag string) bool {
+	etag := `"` + tag + `"`
+	ctx.Resp.Header().Set("ETag", etag)
+
+	for _, match := range strings.Split(ctx.Req.Header.Get("If-None-Match"), ",") {
+		match = strings.TrimPrefix(strings.TrimSpace(match), "W/")
+		if match == etag || match == "*" {
+			ctx.Status(304)
+			return true
+		}
+	}
+	return false
+}
+
+// CSRF returns CSRF protection of current request.
+func (ctx *Context) CSRF() csrf.CSRF {
+	return ctx.csrf
+}
+
+// RenewCsrfToken generates a new CSRF token for current session and sets it to cookie.
+// Tokens are not stored on server, so previous tokens remain valid until they expire.
+func (ctx *Context) RenewCsrfToken() string {
+	id := "0"
+	if uid := ctx.Session.Get("uid"); uid != nil {
+		id = fmt.Sprint(uid)
+	}
+	token := csrf.GenerateToken(setting.SecretKey, id, "POST")
+	ctx.SetCookie(ctx.csrf.GetCookieName(), token, 0, ctx.csrf.GetCookiePath())
+	ctx.Data["CsrfToken"] = token
+	ctx.Data["CsrfTokenHtml"] = template.HTML(`<input type="hidden" name="_csrf" value="` + token + `">`)
+	return token
+}
+
 func (ctx *Context) ServeContent(name string, r io.ReadSeeker, params ...interface{}) {
 	modtime := time.Now()
 	for _, p := range params {
@@ -199,6 +243,65 @@ func (ctx *Context) ServeContent(name string, r io.ReadSeeker, params ...interfa
 	http.ServeContent(ctx.Resp, ctx.Req.Request, name, modtime, r)
 }
 
+func isTrustedProxy(ip net.IP) bool {
+	for _, ipNet := range setting.ReverseProxyTrustedProxies {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveRemoteIP returns the IP address of client who sends the request.
+// Header set by reverse proxy is only respected when the immediate peer is a trusted proxy,
+// otherwise anyone could spoof the address by sending the header directly.
+func resolveRemoteIP(req *http.Request) string {
+	peer, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		peer = req.RemoteAddr
+	}
+
+	ip := net.ParseIP(peer)
+	if ip == nil || !isTrustedProxy(ip) {
+		return peer
+	}
+
+	header := req.Header.Get(setting.ReverseProxyRealIPHeader)
+	if len(header) == 0 {
+		return peer
+	}
+
+	// Walk through the chain from the nearest hop, and the first address
+	// that is not one of trusted proxies is the client.
+	addrs := strings.Split(header, ",")
+	for i := len(addrs) - 1; i >= 0; i-- {
+		ip = net.ParseIP(strings.TrimSpace(addrs[i]))
+		if ip == nil {
+			return peer
+		} else if !isTrustedProxy(ip) || i == 0 {
+			return ip.String()
+		}
+	}
+	return peer
+}
+
+// isAllowedInMaintenance returns true if request does not modify any data,
+// or it is sent by an administrator.
+func isAllowedInMaintenance(ctx *Context) bool {
+	switch ctx.Req.Method {
+	case "GET", "HEAD", "OPTIONS":
+		return true
+	}
+	if ctx.IsSigned && ctx.User.IsAdmin {
+		return true
+	}
+
+	// Administrators must be able to sign in, and fetches over HTTP are sent by POST.
+	urlPath := strings.TrimSuffix(ctx.Req.URL.Path, "/")
+	return strings.HasPrefix(urlPath, "/user/login") ||
+		strings.HasSuffix(urlPath, "/git-upload-pack")
+}
+
 // Contexter initializes a classic context for a request.
 func Contexter() macaron.Handler {
 	return func(c *macaron.Context, l i18n.Locale, cache cache.Cache, sess session.Store, f *session.Flash, x csrf.CSRF) {
@@ -209,13 +312,18 @@ func Contexter() macaron.Handler {
 			Flash:   f,
 			Session: sess,
 		}
+		ctx.RemoteIP = resolveRemoteIP(ctx.Req.Request)
+
 		// Compute current URL for real-time change language.
 		ctx.Data["Link"] = setting.AppSubUrl + strings.TrimSuffix(ctx.Req.URL.Path, "/")
 
 		ctx.Data["PageStartTime"] = time.Now()
 
 		// Get user from session if logined.
-		ctx.User, ctx.IsBasicAuth = auth.SignedInUser(ctx.Context, ctx.Session)
+		ctx.User, ctx.IsBasicAuth = auth.SignedInUser(ctx.Context, ctx.Session, ctx.RemoteIP)
+		if t, ok := ctx.Data["AccessToken"].(*models.AccessToken); ok && ctx.User != nil {
+			ctx.AccessToken = t
+		}
 
 		if ctx.User != nil {
 			ctx.IsSigned = true
@@ -240,6 +348,16 @@ func Contexter() macaron.Handler {
 		ctx.Data["CsrfToken"] = x.GetToken()
 		ctx.Data["CsrfTokenHtml"] = template.HTML(`<input type="hidden" name="_csrf" value="` + x.GetToken() + `">`)
 
+		ctx.Data["MaintenanceMode"] = setting.MaintenanceMode
+		if setting.MaintenanceMode && !isAllowedInMaintenance(ctx) {
+			if strings.HasPrefix(ctx.Req.URL.Path, "/api/") {
+				ctx.APIError(503, "", "Site is in maintenance mode and read-only.")
+			} else {
+				ctx.Handle(503, "", nil)
+			}
+			return
+		}
+
 		ctx.Data["ShowRegistrationButton"] = setting.Service.ShowRegistrationButton
 		ctx.Data["ShowFooterBranding"] = setting.ShowFooterBranding
 		ctx.Data["ShowFooterVersion"] = setting.ShowFooterVersion
@@ -0,0 +1,100 @@
+// Copyright 2015 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package middleware
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Unknwon/com"
+	"github.com/go-macaron/cache"
+	"gopkg.in/macaron.v1"
+
+	"github.com/gogits/gogs/modules/log"
+	"github.com/gogits/gogs/modules/setting"
+)
+
+// RateLimit represents API rate limit status of a client in current window.
+type RateLimit struct {
+	Limit     int   `json:"limit"`
+	Remaining int   `json:"remaining"`
+	Reset     int64 `json:"reset"` // Unix time when current window ends.
+
+	key string
+}
+
+// GetRateLimit returns API rate limit status of current client without consuming quota.
+// Signed in users are counted by user ID, others by IP address.
+func GetRateLimit(ctx *Context) *RateLimit {
+	window := int64(setting.API.RateLimitWindow)
+	start := time.Now().Unix() / window * window
+
+	rl := &RateLimit{
+		Reset: start + window,
+	}
+	if ctx.IsSigned {
+		rl.Limit = setting.API.RateLimit
+		rl.key = fmt.Sprintf("APIRateLimit_user_%d_%d", ctx.User.Id, start)
+	} else {
+		rl.Limit = setting.API.AnonymousRateLimit
+		rl.key = fmt.Sprintf("APIRateLimit_ip_%s_%d", ctx.RemoteIP, start)
+	}
+
+	rl.load(ctx.Cache)
+	return rl
+}
+
+// load updates remaining quota by number of requests counted in cache.
+func (rl *RateLimit) load(c cache.Cache) {
+	used := com.StrTo(com.ToStr(c.Get(rl.key))).MustInt()
+	rl.Remaining = rl.Limit - used
+	if rl.Remaining < 0 {
+		rl.Remaining = 0
+	}
+}
+
+// SetRateLimitHeaders sets headers of rate limit status to response.
+func (ctx *Context) SetRateLimitHeaders(rl *RateLimit) {
+	h := ctx.Resp.Header()
+	h.Set("X-RateLimit-Limit", com.ToStr(rl.Limit))
+	h.Set("X-RateLimit-Remaining", com.ToStr(rl.Remaining))
+	h.Set("X-RateLimit-Reset", com.ToStr(rl.Reset))
+}
+
+// ApiRateLimiter counts API requests of client in cache and rejects them
+// when quota of current window is used up. Requests to given paths are
+// not counted but still get rate limit headers.
+func ApiRateLimiter(exempts ...string) macaron.Handler {
+	return func(ctx *Context) {
+		if !setting.API.RateLimitEnabled {
+			return
+		}
+
+		rl := GetRateLimit(ctx)
+		for _, p := range exempts {
+			if ctx.Req.URL.Path == setting.AppSubUrl+p {
+				ctx.SetRateLimitHeaders(rl)
+				return
+			}
+		}
+
+		if rl.Remaining == 0 {
+			ctx.SetRateLimitHeaders(rl)
+			ctx.APIError(429, "", fmt.Sprintf("API rate limit exceeded, quota is reset at %s.",
+				time.Unix(rl.Reset, 0).UTC().Format(time.RFC1123)))
+			return
+		}
+
+		// Counter is increased atomically, it is created with lifetime
+		// of window by the first request, for which increasing fails.
+		if err := ctx.Cache.Incr(rl.key); err != nil {
+			if err = ctx.Cache.Put(rl.key, 1, int64(setting.API.RateLimitWindow)); err != nil {
+				log.Error(4, "Set cache(APIRateLimit) fail: %v", err)
+			}
+		}
+		rl.load(ctx.Cache)
+		ctx.SetRateLimitHeaders(rl)
+	}
+}
@@ -17,6 +17,31 @@ import (
 	"github.com/gogits/gogs/modules/setting"
 )
 
+// RedirectToUser permanently redirects request of a former name of user to the same path
+// under current name, so old links to profile and repositories keep working.
+func (ctx *Context) RedirectToUser(userName string) {
+	u, err := models.LookupUserRedirect(userName)
+	if err != nil {
+		if models.IsErrUserNotExist(err) {
+			ctx.Handle(404, "LookupUserRedirect", err)
+		} else {
+			ctx.Handle(500, "LookupUserRedirect", err)
+		}
+		return
+	}
+
+	link := strings.TrimPrefix(ctx.Req.URL.Path, setting.AppSubUrl+"/")
+	if !strings.HasPrefix(strings.ToLower(link), strings.ToLower(userName)) {
+		ctx.Handle(404, "RedirectToUser", nil)
+		return
+	}
+	link = setting.AppSubUrl + "/" + u.Name + link[len(userName):]
+	if len(ctx.Req.URL.RawQuery) > 0 {
+		link += "?" + ctx.Req.URL.RawQuery
+	}
+	ctx.Redirect(link, 301)
+}
+
 func ApiRepoAssignment() macaron.Handler {
 	return func(ctx *Context) {
 		userName := ctx.Params(":username")
@@ -31,7 +56,7 @@ func ApiRepoAssignment() macaron.Handler {
 		if ctx.IsSigned && ctx.User.LowerName == strings.ToLower(userName) {
 			owner = ctx.User
 		} else {
-			owner, err = models.GetUserByName(userName)
+			owner, err = models.GetUserByNameOrRedirect(userName)
 			if err != nil {
 				if models.IsErrUserNotExist(err) {
 					ctx.Error(404)
@@ -252,7 +277,7 @@ func RepoAssignment(args ...bool) macaron.Handler {
 			owner, err = models.GetUserByName(userName)
 			if err != nil {
 				if models.IsErrUserNotExist(err) {
-					ctx.Handle(404, "GetUserByName", err)
+					ctx.RedirectToUser(userName)
 				} else {
 					ctx.Handle(500, "GetUserByName", err)
 				}
@@ -300,6 +325,7 @@ func RepoAssignment(args ...bool) macaron.Handler {
 				ctx.Handle(500, "GetMirror", err)
 				return
 			}
+			ctx.Data["Mirror"] = ctx.Repo.Mirror
 			ctx.Data["MirrorInterval"] = ctx.Repo.Mirror.Interval
 		}
 
@@ -410,6 +436,62 @@ func RequireRepoPusher() macaron.Handler {
 	}
 }
 
+// RequireRepoUnit rejects requests to a unit that is disabled in repository.
+func RequireRepoUnit(unit models.RepoUnit) macaron.Handler {
+	return func(ctx *Context) {
+		if !ctx.Repo.Repository.IsUnitEnabled(unit) {
+			ctx.Handle(404, ctx.Req.RequestURI, nil)
+			return
+		}
+	}
+}
+
+// RequireRepoIssueUnit rejects requests to issues or pull requests by type in URL,
+// when the corresponding unit is disabled in repository.
+func RequireRepoIssueUnit() macaron.Handler {
+	return func(ctx *Context) {
+		unit := models.UNIT_ISSUES
+		if ctx.Params(":type") == "pulls" {
+			unit = models.UNIT_PULLS
+		}
+		if !ctx.Repo.Repository.IsUnitEnabled(unit) {
+			ctx.Handle(404, ctx.Req.RequestURI, nil)
+			return
+		}
+	}
+}
+
+// ApiRequireRepoUnit rejects API requests to a unit that is disabled in repository.
+func ApiRequireRepoUnit(unit models.RepoUnit) macaron.Handler {
+	return func(ctx *Context) {
+		if !ctx.Repo.Repository.IsUnitEnabled(unit) {
+			ctx.Error(404)
+			return
+		}
+	}
+}
+
+// ApiRequireRepoNotArchived rejects API requests that would modify an archived repository.
+func ApiRequireRepoNotArchived() macaron.Handler {
+	return func(ctx *Context) {
+		if ctx.Repo.Repository.IsArchived {
+			ctx.APIError(403, "", "Repository is archived.")
+			return
+		}
+	}
+}
+
+// RequireRepoNotArchived rejects requests that would modify an archived repository.
+func RequireRepoNotArchived() macaron.Handler {
+	return func(ctx *Context) {
+		if ctx.Repo.Repository.IsArchived {
+			ctx.Flash.Error(ctx.Tr("repo.archived_read_only"))
+			ctx.Redirect(ctx.Repo.RepoLink)
+			return
+		}
+	}
+}
+
 // GitHookService checks if repository Git hooks service has been enabled.
 func GitHookService() macaron.Handler {
 	return func(ctx *Context) {
@@ -0,0 +1,52 @@
+// Copyright 2015 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package middleware
+
+import (
+	"fmt"
+
+	"github.com/Unknwon/com"
+	"github.com/go-macaron/cache"
+)
+
+// _REPO_CACHE_GEN_TTL is the lifetime of generation of cache keys of a repository,
+// it must be longer than lifetime of any cached value.
+const _REPO_CACHE_GEN_TTL = 30 * 24 * 3600
+
+func repoCacheGenKey(repoID int64) string {
+	return fmt.Sprintf("Repo_%d_gen", repoID)
+}
+
+// repoCacheGen returns current generation of cache keys of repository.
+func repoCacheGen(c cache.Cache, repoID int64) int64 {
+	return com.StrTo(com.ToStr(c.Get(repoCacheGenKey(repoID)))).MustInt64()
+}
+
+// RepoCacheKey returns cache key of given name for repository in given generation.
+func RepoCacheKey(repoID, gen int64, name string) string {
+	return fmt.Sprintf("Repo_%d_%d_%s", repoID, gen, name)
+}
+
+// GetRepoCache returns cached value of given name for repository.
+func (ctx *Context) GetRepoCache(repoID int64, name string) interface{} {
+	return ctx.Cache.Get(RepoCacheKey(repoID, repoCacheGen(ctx.Cache, repoID), name))
+}
+
+// PutRepoCache caches value of given name for repository in current generation.
+func (ctx *Context) PutRepoCache(repoID int64, name string, val interface{}, ttl int64) error {
+	return ctx.Cache.Put(RepoCacheKey(repoID, repoCacheGen(ctx.Cache, repoID), name), val, ttl)
+}
+
+// FlushRepoCache evicts all cached values of repository by moving to next generation,
+// values of previous generations are no longer reachable and expire by themselves.
+func (ctx *Context) FlushRepoCache(repoID int64) error {
+	key := repoCacheGenKey(repoID)
+	if ctx.Cache.IsExist(key) {
+		if err := ctx.Cache.Incr(key); err == nil {
+			return nil
+		}
+	}
+	return ctx.Cache.Put(key, repoCacheGen(ctx.Cache, repoID)+1, _REPO_CACHE_GEN_TTL)
+}
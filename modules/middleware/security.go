@@ -0,0 +1,49 @@
+// Copyright 2015 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package middleware
+
+import (
+	"strings"
+
+	"gopkg.in/macaron.v1"
+
+	"github.com/gogits/gogs/modules/base"
+	"github.com/gogits/gogs/modules/setting"
+)
+
+// isHeaderEnabled returns true if value of security header is not disabled by settings.
+func isHeaderEnabled(val string) bool {
+	return len(val) > 0 && strings.ToLower(val) != "none"
+}
+
+// SecurityHeaders sets Content-Security-Policy and related security headers of response.
+// A random nonce is generated for every request and added to "script-src" of policy,
+// templates can use it for inline scripts via {{.CSPNonce}}.
+func SecurityHeaders() macaron.Handler {
+	return func(ctx *macaron.Context) {
+		h := ctx.Resp.Header()
+		h.Set("X-Content-Type-Options", "nosniff")
+		if isHeaderEnabled(setting.XFrameOptions) {
+			h.Set("X-Frame-Options", setting.XFrameOptions)
+		}
+		if isHeaderEnabled(setting.ReferrerPolicy) {
+			h.Set("Referrer-Policy", setting.ReferrerPolicy)
+		}
+
+		if !isHeaderEnabled(setting.ContentSecurityPolicy) {
+			return
+		}
+		nonce := base.GetRandomString(16)
+		ctx.Data["CSPNonce"] = nonce
+		policy := strings.Replace(setting.ContentSecurityPolicy,
+			"script-src", "script-src 'nonce-"+nonce+"'", 1)
+
+		if setting.CSPReportOnly {
+			h.Set("Content-Security-Policy-Report-Only", policy)
+		} else {
+			h.Set("Content-Security-Policy", policy)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+// Copyright 2015 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package process
+
+import (
+	"os/exec"
+	"strconv"
+	"syscall"
+)
+
+// SetNewGroup makes command to be started in a new process group.
+func SetNewGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP}
+}
+
+// KillGroup kills started command and all its descendants.
+func KillGroup(cmd *exec.Cmd) error {
+	return exec.Command("taskkill", "/F", "/T", "/PID", strconv.Itoa(cmd.Process.Pid)).Run()
+}
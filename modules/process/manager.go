@@ -53,6 +53,13 @@ func Add(desc string, cmd *exec.Cmd) int64 {
 
 // Exec starts executing a command in given path, it records its process and timeout.
 func ExecDir(timeout time.Duration, dir, desc, cmdName string, args ...string) (string, string, error) {
+	cmd := exec.Command(cmdName, args...)
+	cmd.Dir = dir
+	return ExecCmd(timeout, desc, cmd)
+}
+
+// ExecCmd starts executing a prepared command, it records its process and timeout.
+func ExecCmd(timeout time.Duration, desc string, cmd *exec.Cmd) (string, string, error) {
 	if timeout == -1 {
 		timeout = DEFAULT
 	}
@@ -60,8 +67,6 @@ func ExecDir(timeout time.Duration, dir, desc, cmdName string, args ...string) (
 	bufOut := new(bytes.Buffer)
 	bufErr := new(bytes.Buffer)
 
-	cmd := exec.Command(cmdName, args...)
-	cmd.Dir = dir
 	cmd.Stdout = bufOut
 	cmd.Stderr = bufErr
 	if err := cmd.Start(); err != nil {
@@ -0,0 +1,214 @@
+// Copyright 2015 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package setting
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gogits/gogs/modules/log"
+)
+
+// SessionStore represents connection options of networked session providers.
+var SessionStore struct {
+	ConnectTimeout time.Duration
+	TLSEnabled     bool
+	TLSSkipVerify  bool
+	TLSCAFile      string
+}
+
+// sessionTLSConfig returns TLS configuration for connecting to given session store node.
+func sessionTLSConfig(addr string) (*tls.Config, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &tls.Config{
+		ServerName:         host,
+		InsecureSkipVerify: SessionStore.TLSSkipVerify,
+	}
+	if len(SessionStore.TLSCAFile) > 0 {
+		data, err := ioutil.ReadFile(SessionStore.TLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read CA file: %v", err)
+		}
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no valid certificate found in CA file: %s", SessionStore.TLSCAFile)
+		}
+	}
+	return cfg, nil
+}
+
+// dialSessionStore connects to given session store node with TLS if enabled.
+func dialSessionStore(addr string) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: SessionStore.ConnectTimeout}
+	if !SessionStore.TLSEnabled {
+		return dialer.Dial("tcp", addr)
+	}
+
+	cfg, err := sessionTLSConfig(addr)
+	if err != nil {
+		return nil, err
+	}
+	return tls.DialWithDialer(dialer, "tcp", addr, cfg)
+}
+
+// tunnelSessionStore listens on a Unix socket and forwards all connections
+// to given node over TLS, because session providers only speak plain TCP.
+// The socket is created in a directory only accessible by current user,
+// so no other local user can reach the store through it.
+// It returns path of the socket to be used by session provider.
+func tunnelSessionStore(addr string) (string, error) {
+	dir, err := ioutil.TempDir("", "gogs-session")
+	if err != nil {
+		return "", err
+	}
+	ln, err := net.Listen("unix", filepath.Join(dir, "store.sock"))
+	if err != nil {
+		return "", err
+	}
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				log.Error(4, "Session store tunnel to %s: %v", addr, err)
+				return
+			}
+
+			go func(conn net.Conn) {
+				defer conn.Close()
+				remote, err := dialSessionStore(addr)
+				if err != nil {
+					log.Error(4, "Session store tunnel to %s: %v", addr, err)
+					return
+				}
+				defer remote.Close()
+
+				// Both connections are closed as soon as either side hangs up,
+				// which also ends copying of the other direction.
+				done := make(chan struct{}, 2)
+				go func() {
+					io.Copy(remote, conn)
+					done <- struct{}{}
+				}()
+				go func() {
+					io.Copy(conn, remote)
+					done <- struct{}{}
+				}()
+				<-done
+			}(conn)
+		}
+	}()
+	return ln.Addr().String(), nil
+}
+
+// checkSessionStore verifies given node is reachable.
+func checkSessionStore(addr string) error {
+	conn, err := dialSessionStore(addr)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// parseRedisSessionConfig parses provider config of redis, e.g. "network=tcp,addr=:6379".
+func parseRedisSessionConfig(config string) map[string]string {
+	opts := make(map[string]string)
+	for _, field := range strings.Split(config, ",") {
+		infos := strings.SplitN(field, "=", 2)
+		if len(infos) != 2 {
+			continue
+		}
+		opts[strings.TrimSpace(infos[0])] = strings.TrimSpace(infos[1])
+	}
+	return opts
+}
+
+func buildRedisSessionConfig(opts map[string]string) string {
+	fields := make([]string, 0, len(opts))
+	for k, v := range opts {
+		fields = append(fields, k+"="+v)
+	}
+	return strings.Join(fields, ",")
+}
+
+// prepareSessionStore validates connectivity of networked session store
+// and rewrites provider config to use reachable nodes. Redis provider only
+// connects to a single node, Redis cluster and failover are not supported.
+func prepareSessionStore() error {
+	var nodes []string
+	var redisOpts map[string]string
+	switch SessionConfig.Provider {
+	case "redis":
+		redisOpts = parseRedisSessionConfig(SessionConfig.ProviderConfig)
+		if redisOpts["network"] == "unix" {
+			return nil
+		}
+		addr := redisOpts["addr"]
+		if len(addr) == 0 {
+			addr = "127.0.0.1:6379"
+		} else if strings.HasPrefix(addr, ":") {
+			addr = "127.0.0.1" + addr
+		}
+		nodes = []string{addr}
+	case "memcache":
+		nodes = strings.Split(SessionConfig.ProviderConfig, ";")
+	default:
+		return nil
+	}
+
+	reachable := make([]string, 0, len(nodes))
+	var lastErr error
+	for _, addr := range nodes {
+		addr = strings.TrimSpace(addr)
+		if len(addr) == 0 {
+			continue
+		}
+		if err := checkSessionStore(addr); err != nil {
+			log.Warn("Session store node %s is unreachable: %v", addr, err)
+			lastErr = fmt.Errorf("%s: %v", addr, err)
+			continue
+		}
+
+		if SessionStore.TLSEnabled {
+			local, err := tunnelSessionStore(addr)
+			if err != nil {
+				return fmt.Errorf("tunnel to %s: %v", addr, err)
+			}
+			addr = local
+		}
+		reachable = append(reachable, addr)
+	}
+	if len(reachable) == 0 {
+		if lastErr == nil {
+			return fmt.Errorf("no node is configured")
+		}
+		return lastErr
+	}
+
+	switch SessionConfig.Provider {
+	case "redis":
+		redisOpts["addr"] = reachable[0]
+		if SessionStore.TLSEnabled {
+			redisOpts["network"] = "unix"
+		} else if len(redisOpts["network"]) == 0 {
+			redisOpts["network"] = "tcp"
+		}
+		SessionConfig.ProviderConfig = buildRedisSessionConfig(redisOpts)
+	case "memcache":
+		SessionConfig.ProviderConfig = strings.Join(reachable, ";")
+	}
+	return nil
+}
@@ -6,6 +6,7 @@ package setting
 
 import (
 	"fmt"
+	"net"
 	"net/url"
 	"os"
 	"os/exec"
@@ -19,6 +20,7 @@ import (
 	_ "github.com/go-macaron/cache/memcache"
 	_ "github.com/go-macaron/cache/redis"
 	"github.com/go-macaron/session"
+	_ "github.com/go-macaron/session/memcache"
 	_ "github.com/go-macaron/session/redis"
 	"gopkg.in/ini.v1"
 
@@ -63,20 +65,30 @@ var (
 	StartSSHServer     bool
 	SSHDomain          string
 	SSHPort            int
+	SSHListenPort      int
+	SSHUser            string
+	CloneRootUrl       string
 	OfflineMode        bool
 	DisableRouterLog   bool
+	MaintenanceMode    bool
 	CertFile, KeyFile  string
 	StaticRootPath     string
 	EnableGzip         bool
 	LandingPageUrl     LandingPage
 
 	// Security settings.
-	InstallLock          bool
-	SecretKey            string
-	LogInRememberDays    int
-	CookieUserName       string
-	CookieRememberName   string
-	ReverseProxyAuthUser string
+	InstallLock                bool
+	SecretKey                  string
+	LogInRememberDays          int
+	CookieUserName             string
+	CookieRememberName         string
+	ReverseProxyAuthUser       string
+	ReverseProxyTrustedProxies []*net.IPNet
+	ReverseProxyRealIPHeader   string
+	ContentSecurityPolicy      string
+	CSPReportOnly              bool
+	XFrameOptions              string
+	ReferrerPolicy             string
 
 	// Database settings.
 	UseSQLite3    bool
@@ -84,20 +96,39 @@ var (
 	UsePostgreSQL bool
 	UseTiDB       bool
 
+	// API settings.
+	API struct {
+		RateLimitEnabled   bool
+		RateLimit          int
+		AnonymousRateLimit int
+		RateLimitWindow    int
+		MaxBatchSize       int
+	}
+
 	// Webhook settings.
 	Webhook struct {
-		QueueLength    int
-		DeliverTimeout int
-		SkipTLSVerify  bool
-		Types          []string
-		PagingNum      int
+		QueueLength     int
+		DeliverTimeout  int
+		SkipTLSVerify   bool
+		Types           []string
+		PagingNum       int
+		MaxAttempts     int
+		RetryBackoff    int
+		MaxRetryBackoff int
 	}
 
 	// Repository settings.
 	Repository struct {
-		AnsiCharset            string
-		ForcePrivate           bool
-		PullRequestQueueLength int
+		AnsiCharset             string
+		ForcePrivate            bool
+		PullRequestQueueLength  int
+		DefaultBranch           string
+		StorageQuota            int64
+		MaxCreationLimit        int
+		MaxCreationExcludeForks bool
+		ReservedNames           []string
+		ReservedPatterns        []string
+		MaxPinnedIssues         int
 	}
 	RepoRootPath string
 	ScriptType   string
@@ -106,19 +137,28 @@ var (
 	ExplorePagingNum     int
 	IssuePagingNum       int
 	FeedMaxCommitNum     int
+	HighlightTheme       string
+	Logo                 string
+	Favicon              string
 	AdminUserPagingNum   int
 	AdminRepoPagingNum   int
 	AdminNoticePagingNum int
 	AdminOrgPagingNum    int
+	MaxDisplayFileSize   int64
+	MaxHighlightFileSize int64
 
 	// Markdown sttings.
 	Markdown struct {
 		EnableHardLineBreak bool
+		CustomEmoji         map[string]string `ini:"-"`
 	}
 
 	// Picture settings.
 	PictureService   string
 	AvatarUploadPath string
+	AvatarMaxWidth   int
+	AvatarMaxHeight  int
+	AvatarMaxSize    int64
 	GravatarSource   string
 	DisableGravatar  bool
 
@@ -143,12 +183,26 @@ var (
 	CacheConn     string
 
 	// Session settings.
-	SessionConfig session.Options
+	SessionConfig          session.Options
+	SessionIdleTimeout     time.Duration
+	SessionAbsoluteTimeout time.Duration
 
 	// Git settings.
 	Git struct {
-		MaxGitDiffLines int
-		GcArgs          []string `delim:" "`
+		MaxGitDiffLines        int
+		MaxGitDiffFiles        int
+		MaxGitDiffLinesPerFile int
+		MaxGitDiffBytes        int64
+		GcArgs                 []string `delim:" "`
+		MaxFetchObjects        int64
+		DisableProtocolV2      bool
+		HookTimeout            int
+		MaxBlameFileSize       int64
+		MaxPushSize            int64
+		Path                   string
+		Env                    []string `ini:"-"`
+		GpgPath                string
+		GpgHomePath            string
 	}
 
 	// Cron tasks.
@@ -169,6 +223,27 @@ var (
 			RunAtStart bool
 			Schedule   string
 		} `ini:"cron.check_repo_stats"`
+		GitGcRepos struct {
+			Enabled    bool
+			RunAtStart bool
+			Schedule   string
+		} `ini:"cron.git_gc_repos"`
+		UpdateRepoSizes struct {
+			Enabled    bool
+			RunAtStart bool
+			Schedule   string
+		} `ini:"cron.update_repo_sizes"`
+		DeleteUserExports struct {
+			Enabled    bool
+			RunAtStart bool
+			Schedule   string
+		} `ini:"cron.delete_user_exports"`
+		DeleteOrphanedAttachments struct {
+			Enabled          bool
+			RunAtStart       bool
+			Schedule         string
+			GracePeriodHours int
+		} `ini:"cron.delete_orphaned_attachments"`
 	}
 
 	// I18n settings.
@@ -305,7 +380,13 @@ func NewContext() {
 	}
 	SSHDomain = sec.Key("SSH_DOMAIN").MustString(Domain)
 	SSHPort = sec.Key("SSH_PORT").MustInt(22)
+	SSHListenPort = sec.Key("SSH_LISTEN_PORT").MustInt(SSHPort)
+	CloneRootUrl = sec.Key("CLONE_ROOT_URL").MustString(AppUrl)
+	if CloneRootUrl[len(CloneRootUrl)-1] != '/' {
+		CloneRootUrl += "/"
+	}
 	OfflineMode = sec.Key("OFFLINE_MODE").MustBool()
+	MaintenanceMode = sec.Key("MAINTENANCE_MODE").MustBool()
 	DisableRouterLog = sec.Key("DISABLE_ROUTER_LOG").MustBool()
 	StaticRootPath = sec.Key("STATIC_ROOT_PATH").MustString(workDir)
 	EnableGzip = sec.Key("ENABLE_GZIP").MustBool()
@@ -324,6 +405,29 @@ func NewContext() {
 	CookieUserName = sec.Key("COOKIE_USERNAME").String()
 	CookieRememberName = sec.Key("COOKIE_REMEMBER_NAME").String()
 	ReverseProxyAuthUser = sec.Key("REVERSE_PROXY_AUTHENTICATION_USER").MustString("X-WEBAUTH-USER")
+	ReverseProxyRealIPHeader = sec.Key("REVERSE_PROXY_REAL_IP_HEADER").MustString("X-Forwarded-For")
+	ReverseProxyTrustedProxies = make([]*net.IPNet, 0, 2)
+	for _, addr := range sec.Key("REVERSE_PROXY_TRUSTED_PROXIES").Strings(",") {
+		// Single IP address is treated as a network contains only itself.
+		if !strings.Contains(addr, "/") {
+			if strings.Contains(addr, ":") {
+				addr += "/128"
+			} else {
+				addr += "/32"
+			}
+		}
+		_, ipNet, err := net.ParseCIDR(addr)
+		if err != nil {
+			log.Fatal(4, "Fail to parse trusted proxy '%s': %v", addr, err)
+		}
+		ReverseProxyTrustedProxies = append(ReverseProxyTrustedProxies, ipNet)
+	}
+	ContentSecurityPolicy = sec.Key("CONTENT_SECURITY_POLICY").MustString(
+		"default-src 'self'; script-src 'self'; style-src 'self' 'unsafe-inline'; img-src * data:; " +
+			"font-src 'self' data:; object-src 'none'; frame-ancestors 'self'; base-uri 'self'; form-action 'self'")
+	CSPReportOnly = sec.Key("CSP_REPORT_ONLY").MustBool()
+	XFrameOptions = sec.Key("X_FRAME_OPTIONS").MustString("SAMEORIGIN")
+	ReferrerPolicy = sec.Key("REFERRER_POLICY").MustString("no-referrer")
 
 	sec = Cfg.Section("attachment")
 	AttachmentPath = sec.Key("PATH").MustString(path.Join(AppDataPath, "attachments"))
@@ -354,6 +458,7 @@ func NewContext() {
 	}[Cfg.Section("time").Key("FORMAT").MustString("RFC1123")]
 
 	RunUser = Cfg.Section("").Key("RUN_USER").String()
+	SSHUser = Cfg.Section("server").Key("SSH_USER").MustString(RunUser)
 	curUser := user.CurrentUsername()
 	// Does not check run user when the install lock is off.
 	if InstallLock && RunUser != curUser {
@@ -379,12 +484,28 @@ func NewContext() {
 	Repository.AnsiCharset = sec.Key("ANSI_CHARSET").String()
 	Repository.ForcePrivate = sec.Key("FORCE_PRIVATE").MustBool()
 	Repository.PullRequestQueueLength = sec.Key("PULL_REQUEST_QUEUE_LENGTH").MustInt(10000)
+	Repository.DefaultBranch = sec.Key("DEFAULT_BRANCH").MustString("master")
+	Repository.StorageQuota = sec.Key("STORAGE_QUOTA").MustInt64()
+	Repository.MaxCreationLimit = sec.Key("MAX_CREATION_LIMIT").MustInt(-1)
+	Repository.MaxCreationExcludeForks = sec.Key("MAX_CREATION_EXCLUDE_FORKS").MustBool()
+	Repository.MaxPinnedIssues = sec.Key("MAX_PINNED_ISSUES").MustInt(3)
+	for _, name := range sec.Key("RESERVED_NAMES").Strings(",") {
+		Repository.ReservedNames = append(Repository.ReservedNames, strings.ToLower(name))
+	}
+	for _, pat := range sec.Key("RESERVED_PATTERNS").Strings(",") {
+		Repository.ReservedPatterns = append(Repository.ReservedPatterns, strings.ToLower(pat))
+	}
 
 	// UI settings.
 	sec = Cfg.Section("ui")
 	ExplorePagingNum = sec.Key("EXPLORE_PAGING_NUM").MustInt(20)
 	IssuePagingNum = sec.Key("ISSUE_PAGING_NUM").MustInt(10)
 	FeedMaxCommitNum = sec.Key("FEED_MAX_COMMIT_NUM").MustInt(5)
+	HighlightTheme = sec.Key("HIGHLIGHT_THEME").MustString("github")
+	Logo = sec.Key("LOGO").MustString("img/gogs-lg.png")
+	Favicon = sec.Key("FAVICON").MustString("img/favicon.png")
+	MaxDisplayFileSize = sec.Key("MAX_DISPLAY_FILE_SIZE").MustInt64(8388608)
+	MaxHighlightFileSize = sec.Key("MAX_HIGHLIGHT_FILE_SIZE").MustInt64(1048576)
 
 	sec = Cfg.Section("ui.admin")
 	AdminUserPagingNum = sec.Key("USER_PAGING_NUM").MustInt(50)
@@ -399,6 +520,9 @@ func NewContext() {
 	if !filepath.IsAbs(AvatarUploadPath) {
 		AvatarUploadPath = path.Join(workDir, AvatarUploadPath)
 	}
+	AvatarMaxWidth = sec.Key("AVATAR_MAX_WIDTH").MustInt(4096)
+	AvatarMaxHeight = sec.Key("AVATAR_MAX_HEIGHT").MustInt(3072)
+	AvatarMaxSize = sec.Key("AVATAR_MAX_FILE_SIZE").MustInt64(1048576)
 	switch source := sec.Key("GRAVATAR_SOURCE").MustString("gravatar"); source {
 	case "duoshuo":
 		GravatarSource = "http://gravatar.duoshuo.com/avatar/"
@@ -419,6 +543,22 @@ func NewContext() {
 	} else if Cfg.Section("cron").MapTo(&Cron); err != nil {
 		log.Fatal(4, "Fail to map Cron settings: %v", err)
 	}
+	Markdown.CustomEmoji = make(map[string]string)
+	for _, key := range Cfg.Section("markdown.custom_emoji").Keys() {
+		Markdown.CustomEmoji[key.Name()] = key.Value()
+	}
+	if err = setupGitEnv(); err != nil {
+		log.Fatal(4, "Fail to set up Git environment: %v", err)
+	}
+	if len(Git.GpgPath) == 0 {
+		Git.GpgPath = "gpg"
+	}
+	if len(Git.GpgHomePath) == 0 {
+		Git.GpgHomePath = path.Join(AppDataPath, "gpg")
+	}
+	if !filepath.IsAbs(Git.GpgHomePath) {
+		Git.GpgHomePath = path.Join(workDir, Git.GpgHomePath)
+	}
 
 	Langs = Cfg.Section("i18n").Key("LANGS").Strings(",")
 	Names = Cfg.Section("i18n").Key("NAMES").Strings(",")
@@ -430,20 +570,55 @@ func NewContext() {
 	HasRobotsTxt = com.IsFile(path.Join(CustomPath, "robots.txt"))
 }
 
+// setupGitEnv resolves configured Git binary and collects environment variables
+// to be passed to every Git invocation, environment of current process is not changed.
+func setupGitEnv() error {
+	if len(Git.Path) == 0 {
+		Git.Path = "git"
+	}
+	if Git.Path != "git" {
+		gitPath, err := filepath.Abs(Git.Path)
+		if err != nil {
+			return err
+		} else if !com.IsFile(gitPath) {
+			return fmt.Errorf("Git binary does not exist: %s", gitPath)
+		}
+		Git.Path = gitPath
+	}
+
+	Git.Env = Git.Env[:0]
+	for _, key := range Cfg.Section("git.env").Keys() {
+		Git.Env = append(Git.Env, key.Name()+"="+key.Value())
+	}
+	return nil
+}
+
 var Service struct {
 	ActiveCodeLives                int
 	ResetPwdCodeLives              int
 	RegisterEmailConfirm           bool
+	RequireEmailConfirmSignIn      bool
 	DisableRegistration            bool
 	ShowRegistrationButton         bool
 	RequireSignInView              bool
 	EnableCacheAvatar              bool
 	EnableNotifyMail               bool
+	WatchNotifyBatchInterval       time.Duration
 	EnableReverseProxyAuth         bool
 	EnableReverseProxyAutoRegister bool
 	DisableMinimumKeySizeCheck     bool
 	MinimumKeySizes                map[string]int
 	EnableCaptcha                  bool
+	LoginMaxFailedAttempts         int
+	LoginMaxFailedAttemptsPerIP    int
+	LoginLockoutMinutes            int
+	SSHKeyImportSources            []string
+	EnableUsernameChange           bool
+	UsernameChangeCooldown         time.Duration
+	EmailDomainWhitelist           []string
+	EmailDomainBlacklist           []string
+	BlockDisposableEmail           bool
+	RegisterManualApproval         bool
 }
 
 func newService() {
@@ -458,6 +633,33 @@ func newService() {
 	Service.EnableReverseProxyAutoRegister = sec.Key("ENABLE_REVERSE_PROXY_AUTO_REGISTRATION").MustBool()
 	Service.DisableMinimumKeySizeCheck = sec.Key("DISABLE_MINIMUM_KEY_SIZE_CHECK").MustBool()
 	Service.EnableCaptcha = sec.Key("ENABLE_CAPTCHA").MustBool()
+	Service.LoginMaxFailedAttempts = sec.Key("LOGIN_MAX_FAILED_ATTEMPTS").MustInt()
+	Service.LoginMaxFailedAttemptsPerIP = sec.Key("LOGIN_MAX_FAILED_ATTEMPTS_PER_IP").MustInt()
+	Service.LoginLockoutMinutes = sec.Key("LOGIN_LOCKOUT_MINUTES").MustInt(15)
+	Service.WatchNotifyBatchInterval = time.Duration(sec.Key("WATCH_NOTIFY_BATCH_INTERVAL").MustInt(60)) * time.Second
+	Service.EnableUsernameChange = sec.Key("ENABLE_USERNAME_CHANGE").MustBool(true)
+	Service.UsernameChangeCooldown = time.Duration(sec.Key("USERNAME_CHANGE_COOLDOWN_DAYS").MustInt(7)) * 24 * time.Hour
+	for _, domain := range sec.Key("EMAIL_DOMAIN_WHITELIST").Strings(",") {
+		if len(domain) > 0 {
+			Service.EmailDomainWhitelist = append(Service.EmailDomainWhitelist, strings.ToLower(domain))
+		}
+	}
+	for _, domain := range sec.Key("EMAIL_DOMAIN_BLACKLIST").Strings(",") {
+		if len(domain) > 0 {
+			Service.EmailDomainBlacklist = append(Service.EmailDomainBlacklist, strings.ToLower(domain))
+		}
+	}
+	Service.BlockDisposableEmail = sec.Key("BLOCK_DISPOSABLE_EMAIL").MustBool()
+	Service.RegisterManualApproval = sec.Key("REGISTER_MANUAL_APPROVAL").MustBool()
+	// Explicitly empty value disables importing of SSH keys.
+	if !sec.HasKey("SSH_KEY_IMPORT_SOURCES") {
+		sec.Key("SSH_KEY_IMPORT_SOURCES").SetValue("github.com,gitlab.com")
+	}
+	for _, source := range sec.Key("SSH_KEY_IMPORT_SOURCES").Strings(",") {
+		if len(source) > 0 {
+			Service.SSHKeyImportSources = append(Service.SSHKeyImportSources, strings.ToLower(source))
+		}
+	}
 
 	minimumKeySizes := Cfg.Section("service.minimum_key_sizes").Keys()
 	Service.MinimumKeySizes = make(map[string]int)
@@ -558,7 +760,7 @@ func newCacheService() {
 
 func newSessionService() {
 	SessionConfig.Provider = Cfg.Section("session").Key("PROVIDER").In("memory",
-		[]string{"memory", "file", "redis", "mysql"})
+		[]string{"memory", "file", "redis", "memcache", "mysql"})
 	SessionConfig.ProviderConfig = strings.Trim(Cfg.Section("session").Key("PROVIDER_CONFIG").String(), "\" ")
 	SessionConfig.CookieName = Cfg.Section("session").Key("COOKIE_NAME").MustString("i_like_gogits")
 	SessionConfig.CookiePath = AppSubUrl
@@ -566,6 +768,17 @@ func newSessionService() {
 	SessionConfig.Gclifetime = Cfg.Section("session").Key("GC_INTERVAL_TIME").MustInt64(86400)
 	SessionConfig.Maxlifetime = Cfg.Section("session").Key("SESSION_LIFE_TIME").MustInt64(86400)
 
+	sec := Cfg.Section("session")
+	SessionStore.ConnectTimeout = time.Duration(sec.Key("CONNECT_TIMEOUT").MustInt(5)) * time.Second
+	SessionStore.TLSEnabled = sec.Key("TLS_ENABLED").MustBool()
+	SessionStore.TLSSkipVerify = sec.Key("TLS_SKIP_VERIFY").MustBool()
+	SessionStore.TLSCAFile = sec.Key("TLS_CA_FILE").String()
+	SessionIdleTimeout = time.Duration(sec.Key("IDLE_TIMEOUT").MustInt64()) * time.Second
+	SessionAbsoluteTimeout = time.Duration(sec.Key("ABSOLUTE_TIMEOUT").MustInt64()) * time.Second
+	if err := prepareSessionStore(); err != nil {
+		log.Fatal(4, "Session store of provider '%s' is unreachable: %v", SessionConfig.Provider, err)
+	}
+
 	log.Info("Session Service Enabled")
 }
 
@@ -619,6 +832,7 @@ func newRegisterMailService() {
 		return
 	}
 	Service.RegisterEmailConfirm = true
+	Service.RequireEmailConfirmSignIn = Cfg.Section("service").Key("REQUIRE_EMAIL_CONFIRM_SIGNIN").MustBool()
 	log.Info("Register Mail Service Enabled")
 }
 
@@ -640,6 +854,21 @@ func newWebhookService() {
 	Webhook.SkipTLSVerify = sec.Key("SKIP_TLS_VERIFY").MustBool()
 	Webhook.Types = []string{"gogs", "slack"}
 	Webhook.PagingNum = sec.Key("PAGING_NUM").MustInt(10)
+	Webhook.MaxAttempts = sec.Key("MAX_ATTEMPTS").MustInt(5)
+	Webhook.RetryBackoff = sec.Key("RETRY_BACKOFF").MustInt(10)
+	Webhook.MaxRetryBackoff = sec.Key("MAX_RETRY_BACKOFF").MustInt(3600)
+}
+
+func newAPIService() {
+	sec := Cfg.Section("api")
+	API.RateLimitEnabled = sec.Key("RATE_LIMIT_ENABLED").MustBool()
+	API.RateLimit = sec.Key("RATE_LIMIT").MustInt(5000)
+	API.AnonymousRateLimit = sec.Key("ANONYMOUS_RATE_LIMIT").MustInt(60)
+	API.RateLimitWindow = sec.Key("RATE_LIMIT_WINDOW").MustInt(3600)
+	if API.RateLimitWindow <= 0 {
+		API.RateLimitWindow = 3600
+	}
+	API.MaxBatchSize = sec.Key("MAX_BATCH_SIZE").MustInt(100)
 }
 
 func NewServices() {
@@ -651,4 +880,33 @@ func NewServices() {
 	newRegisterMailService()
 	newNotifyMailService()
 	newWebhookService()
+	newAPIService()
+}
+
+// SaveMaintenanceMode turns maintenance mode on or off and saves it to custom configuration,
+// so that it is also respected by other processes such as SSH commands.
+func SaveMaintenanceMode(enabled bool) error {
+	cfg := ini.Empty()
+	if com.IsFile(CustomConf) {
+		if err := cfg.Append(CustomConf); err != nil {
+			return fmt.Errorf("Append: %v", err)
+		}
+	}
+	cfg.Section("server").Key("MAINTENANCE_MODE").SetValue(com.ToStr(enabled))
+
+	os.MkdirAll(filepath.Dir(CustomConf), os.ModePerm)
+	if err := cfg.SaveTo(CustomConf); err != nil {
+		return fmt.Errorf("SaveTo: %v", err)
+	}
+	MaintenanceMode = enabled
+	return nil
+}
+
+// AssetLink returns link of static asset, which can be a path relative to
+// public directory or an absolute URL.
+func AssetLink(asset string) string {
+	if strings.Contains(asset, "://") {
+		return asset
+	}
+	return AppSubUrl + "/" + strings.TrimPrefix(asset, "/")
 }
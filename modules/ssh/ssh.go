@@ -101,6 +101,14 @@ func handleServerConn(keyID string, chans <-chan ssh.NewChannel) {
 						return
 					}
 
+					// Operation is only counted when it is accepted and completed.
+					switch {
+					case strings.HasPrefix(cmdName, "git-upload-pack"):
+						models.IncreaseGitClones()
+					case strings.HasPrefix(cmdName, "git-receive-pack"):
+						models.IncreaseGitPushes()
+					}
+
 					ch.SendRequest("exit-status", false, []byte{0, 0, 0, 0})
 					return
 				default:
@@ -40,6 +40,15 @@ var Funcs template.FuncMap = map[string]interface{}{
 	"AppDomain": func() string {
 		return setting.Domain
 	},
+	"AppLogo": func() string {
+		return setting.AssetLink(setting.Logo)
+	},
+	"AppFavicon": func() string {
+		return setting.AssetLink(setting.Favicon)
+	},
+	"HighlightTheme": func() string {
+		return setting.HighlightTheme
+	},
 	"DisableGravatar": func() bool {
 		return setting.DisableGravatar
 	},
@@ -0,0 +1,151 @@
+// Copyright 2015 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package webauthn
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Major types of CBOR data items (RFC 7049).
+const (
+	cborUint = iota
+	cborNegInt
+	cborBytes
+	cborText
+	cborArray
+	cborMap
+	cborTag
+	cborSimple
+)
+
+const cborMaxDepth = 16
+
+var errCBORTruncated = errors.New("truncated CBOR data")
+
+// decodeCBOR decodes the first data item of data, and returns it with remaining bytes.
+// It supports the subset of CBOR used by authenticators: integers, byte and text strings,
+// arrays, maps, booleans and null. Integers are decoded as int64, maps as map[interface{}]interface{}.
+func decodeCBOR(data []byte) (interface{}, []byte, error) {
+	return decodeCBORItem(data, 0)
+}
+
+func readCBORHead(data []byte) (major byte, arg uint64, rest []byte, err error) {
+	if len(data) < 1 {
+		return 0, 0, nil, errCBORTruncated
+	}
+	major = data[0] >> 5
+	info := data[0] & 0x1f
+	data = data[1:]
+
+	switch {
+	case info < 24:
+		arg = uint64(info)
+	case info == 24:
+		if len(data) < 1 {
+			return 0, 0, nil, errCBORTruncated
+		}
+		arg, data = uint64(data[0]), data[1:]
+	case info == 25:
+		if len(data) < 2 {
+			return 0, 0, nil, errCBORTruncated
+		}
+		arg, data = uint64(binary.BigEndian.Uint16(data)), data[2:]
+	case info == 26:
+		if len(data) < 4 {
+			return 0, 0, nil, errCBORTruncated
+		}
+		arg, data = uint64(binary.BigEndian.Uint32(data)), data[4:]
+	case info == 27:
+		if len(data) < 8 {
+			return 0, 0, nil, errCBORTruncated
+		}
+		arg, data = binary.BigEndian.Uint64(data), data[8:]
+	default:
+		return 0, 0, nil, fmt.Errorf("unsupported CBOR additional information: %d", info)
+	}
+	return major, arg, data, nil
+}
+
+func decodeCBORItem(data []byte, depth int) (interface{}, []byte, error) {
+	if depth > cborMaxDepth {
+		return nil, nil, errors.New("CBOR data is nested too deeply")
+	}
+
+	major, arg, data, err := readCBORHead(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch major {
+	case cborUint, cborNegInt:
+		if arg > 1<<63-1 {
+			return nil, nil, errors.New("CBOR integer overflows")
+		}
+		if major == cborNegInt {
+			return -1 - int64(arg), data, nil
+		}
+		return int64(arg), data, nil
+
+	case cborBytes, cborText:
+		if arg > uint64(len(data)) {
+			return nil, nil, errCBORTruncated
+		}
+		if major == cborText {
+			return string(data[:arg]), data[arg:], nil
+		}
+		return append([]byte{}, data[:arg]...), data[arg:], nil
+
+	case cborArray:
+		if arg > uint64(len(data)) {
+			return nil, nil, errCBORTruncated
+		}
+		items := make([]interface{}, arg)
+		for i := range items {
+			if items[i], data, err = decodeCBORItem(data, depth+1); err != nil {
+				return nil, nil, err
+			}
+		}
+		return items, data, nil
+
+	case cborMap:
+		if arg > uint64(len(data)) {
+			return nil, nil, errCBORTruncated
+		}
+		m := make(map[interface{}]interface{}, arg)
+		for i := uint64(0); i < arg; i++ {
+			var key, val interface{}
+			if key, data, err = decodeCBORItem(data, depth+1); err != nil {
+				return nil, nil, err
+			}
+			switch key.(type) {
+			case int64, string:
+			default:
+				return nil, nil, errors.New("unsupported CBOR map key type")
+			}
+			if val, data, err = decodeCBORItem(data, depth+1); err != nil {
+				return nil, nil, err
+			}
+			m[key] = val
+		}
+		return m, data, nil
+
+	case cborTag:
+		// Tags only give hints of semantics, use the tagged item as is.
+		return decodeCBORItem(data, depth+1)
+
+	case cborSimple:
+		switch arg {
+		case 20:
+			return false, data, nil
+		case 21:
+			return true, data, nil
+		case 22, 23:
+			return nil, data, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("unsupported CBOR data item: major type %d", major)
+}
@@ -0,0 +1,302 @@
+// Copyright 2015 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package webauthn implements server side verification of Web Authentication
+// registration and assertion ceremonies for security keys.
+// Attestation statements are not verified, so any authenticator is accepted.
+package webauthn
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"strings"
+)
+
+// COSE algorithm identifiers of supported public keys.
+const (
+	ALG_ES256 = -7
+	ALG_RS256 = -257
+)
+
+// Flags of authenticator data.
+const (
+	flagUserPresent  = 0x01
+	flagAttestedData = 0x40
+)
+
+var (
+	ErrInvalidClientData     = errors.New("invalid client data")
+	ErrChallengeMismatch     = errors.New("challenge does not match")
+	ErrOriginMismatch        = errors.New("origin does not match")
+	ErrRelyingPartyMismatch  = errors.New("relying party ID does not match")
+	ErrUserNotPresent        = errors.New("user presence is not confirmed")
+	ErrUnsupportedKey        = errors.New("unsupported public key")
+	ErrInvalidSignature      = errors.New("invalid signature")
+	ErrSignCountNotIncreased = errors.New("signature counter did not increase, authenticator may be cloned")
+)
+
+// Encoding is used for challenges and binary values exchanged with browsers.
+var Encoding = base64.RawURLEncoding
+
+// Credential represents a public key credential registered by an authenticator.
+type Credential struct {
+	ID        []byte
+	PublicKey []byte // COSE_Key format.
+	SignCount uint32
+}
+
+// RelyingParty represents the web application that credentials are scoped to.
+type RelyingParty struct {
+	ID     string // Effective domain, e.g. "try.gogs.io".
+	Origin string // e.g. "https://try.gogs.io".
+}
+
+// NewRelyingParty returns relying party of the application served at given URL.
+func NewRelyingParty(appURL string) (*RelyingParty, error) {
+	u, err := url.Parse(appURL)
+	if err != nil {
+		return nil, err
+	} else if len(u.Host) == 0 {
+		return nil, fmt.Errorf("URL has no host: %s", appURL)
+	}
+
+	host := u.Host
+	if i := strings.LastIndex(host, ":"); i > -1 && !strings.HasSuffix(host, "]") {
+		host = host[:i]
+	}
+	return &RelyingParty{
+		ID:     strings.Trim(host, "[]"),
+		Origin: u.Scheme + "://" + u.Host,
+	}, nil
+}
+
+// NewChallenge returns a random challenge encoded by Encoding.
+func NewChallenge() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return Encoding.EncodeToString(buf), nil
+}
+
+type clientData struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	Origin    string `json:"origin"`
+}
+
+func (rp *RelyingParty) verifyClientData(data []byte, typ, challenge string) error {
+	var cd clientData
+	if err := json.Unmarshal(data, &cd); err != nil {
+		return ErrInvalidClientData
+	} else if cd.Type != typ {
+		return ErrInvalidClientData
+	} else if len(challenge) == 0 || subtle.ConstantTimeCompare([]byte(cd.Challenge), []byte(challenge)) != 1 {
+		return ErrChallengeMismatch
+	} else if cd.Origin != rp.Origin {
+		return ErrOriginMismatch
+	}
+	return nil
+}
+
+type authenticatorData struct {
+	rpIDHash  []byte
+	flags     byte
+	signCount uint32
+
+	// Only available in registration.
+	credentialID []byte
+	publicKey    []byte
+}
+
+func parseAuthenticatorData(data []byte) (*authenticatorData, error) {
+	if len(data) < 37 {
+		return nil, errors.New("authenticator data is too short")
+	}
+	ad := &authenticatorData{
+		rpIDHash:  data[:32],
+		flags:     data[32],
+		signCount: binary.BigEndian.Uint32(data[33:37]),
+	}
+	if ad.flags&flagAttestedData == 0 {
+		return ad, nil
+	}
+
+	// AAGUID (16 bytes), length of credential ID (2 bytes), credential ID, public key.
+	data = data[37:]
+	if len(data) < 18 {
+		return nil, errors.New("attested credential data is too short")
+	}
+	idLen := int(binary.BigEndian.Uint16(data[16:18]))
+	data = data[18:]
+	if len(data) < idLen {
+		return nil, errors.New("credential ID is truncated")
+	}
+	ad.credentialID = data[:idLen]
+	data = data[idLen:]
+
+	_, rest, err := decodeCBOR(data)
+	if err != nil {
+		return nil, fmt.Errorf("decode public key: %v", err)
+	}
+	ad.publicKey = data[:len(data)-len(rest)]
+	return ad, nil
+}
+
+func (rp *RelyingParty) verifyAuthenticatorData(ad *authenticatorData) error {
+	rpIDHash := sha256.Sum256([]byte(rp.ID))
+	if !bytes.Equal(ad.rpIDHash, rpIDHash[:]) {
+		return ErrRelyingPartyMismatch
+	} else if ad.flags&flagUserPresent == 0 {
+		return ErrUserNotPresent
+	}
+	return nil
+}
+
+// parsePublicKey parses COSE_Key formatted public key.
+func parsePublicKey(data []byte) (alg int64, key crypto.PublicKey, err error) {
+	v, _, err := decodeCBOR(data)
+	if err != nil {
+		return 0, nil, err
+	}
+	m, ok := v.(map[interface{}]interface{})
+	if !ok {
+		return 0, nil, ErrUnsupportedKey
+	}
+	alg, _ = m[int64(3)].(int64)
+
+	switch alg {
+	case ALG_ES256:
+		crv, _ := m[int64(-1)].(int64)
+		x, _ := m[int64(-2)].([]byte)
+		y, _ := m[int64(-3)].([]byte)
+		if crv != 1 || len(x) != 32 || len(y) != 32 {
+			return 0, nil, ErrUnsupportedKey
+		}
+		pub := &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}
+		if !pub.Curve.IsOnCurve(pub.X, pub.Y) {
+			return 0, nil, ErrUnsupportedKey
+		}
+		return alg, pub, nil
+
+	case ALG_RS256:
+		n, _ := m[int64(-1)].([]byte)
+		e, _ := m[int64(-2)].([]byte)
+		if len(n) == 0 || len(e) == 0 || len(e) > 4 {
+			return 0, nil, ErrUnsupportedKey
+		}
+		exp := 0
+		for _, b := range e {
+			exp = exp<<8 | int(b)
+		}
+		return alg, &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: exp}, nil
+	}
+	return 0, nil, ErrUnsupportedKey
+}
+
+func verifySignature(publicKey, signed, signature []byte) error {
+	alg, key, err := parsePublicKey(publicKey)
+	if err != nil {
+		return err
+	}
+	hash := sha256.Sum256(signed)
+
+	switch alg {
+	case ALG_ES256:
+		var sig struct {
+			R, S *big.Int
+		}
+		if rest, err := asn1.Unmarshal(signature, &sig); err != nil || len(rest) > 0 {
+			return ErrInvalidSignature
+		}
+		if !ecdsa.Verify(key.(*ecdsa.PublicKey), hash[:], sig.R, sig.S) {
+			return ErrInvalidSignature
+		}
+	case ALG_RS256:
+		if rsa.VerifyPKCS1v15(key.(*rsa.PublicKey), crypto.SHA256, hash[:], signature) != nil {
+			return ErrInvalidSignature
+		}
+	}
+	return nil
+}
+
+// VerifyRegistration verifies response of navigator.credentials.create
+// to given challenge, and returns the new credential.
+func (rp *RelyingParty) VerifyRegistration(challenge string, clientDataJSON, attestationObject []byte) (*Credential, error) {
+	if err := rp.verifyClientData(clientDataJSON, "webauthn.create", challenge); err != nil {
+		return nil, err
+	}
+
+	v, _, err := decodeCBOR(attestationObject)
+	if err != nil {
+		return nil, fmt.Errorf("decode attestation object: %v", err)
+	}
+	obj, ok := v.(map[interface{}]interface{})
+	if !ok {
+		return nil, errors.New("invalid attestation object")
+	}
+	rawAuthData, _ := obj["authData"].([]byte)
+	ad, err := parseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return nil, err
+	} else if err = rp.verifyAuthenticatorData(ad); err != nil {
+		return nil, err
+	} else if len(ad.credentialID) == 0 {
+		return nil, errors.New("attested credential data is missing")
+	}
+
+	if _, _, err = parsePublicKey(ad.publicKey); err != nil {
+		return nil, err
+	}
+	return &Credential{
+		ID:        ad.credentialID,
+		PublicKey: ad.publicKey,
+		SignCount: ad.signCount,
+	}, nil
+}
+
+// VerifyAssertion verifies response of navigator.credentials.get to given challenge
+// signed by given credential, and returns new value of signature counter.
+func (rp *RelyingParty) VerifyAssertion(challenge string, cred *Credential, clientDataJSON, authData, signature []byte) (uint32, error) {
+	if err := rp.verifyClientData(clientDataJSON, "webauthn.get", challenge); err != nil {
+		return 0, err
+	}
+
+	ad, err := parseAuthenticatorData(authData)
+	if err != nil {
+		return 0, err
+	} else if err = rp.verifyAuthenticatorData(ad); err != nil {
+		return 0, err
+	}
+
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	signed := append(append([]byte{}, authData...), clientDataHash[:]...)
+	if err = verifySignature(cred.PublicKey, signed, signature); err != nil {
+		return 0, err
+	}
+
+	// Authenticators that do not implement counter always report zero.
+	if (ad.signCount != 0 || cred.SignCount != 0) && ad.signCount <= cred.SignCount {
+		return 0, ErrSignCountNotIncreased
+	}
+	return ad.signCount, nil
+}
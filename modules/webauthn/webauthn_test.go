@@ -0,0 +1,177 @@
+// Copyright 2015 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package webauthn
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/asn1"
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+)
+
+// cborHead encodes head of a CBOR data item, only used to build test data.
+func cborHead(major byte, n int) []byte {
+	switch {
+	case n < 24:
+		return []byte{major<<5 | byte(n)}
+	case n < 256:
+		return []byte{major<<5 | 24, byte(n)}
+	}
+	return []byte{major<<5 | 25, byte(n >> 8), byte(n)}
+}
+
+func cborInt(n int) []byte {
+	if n < 0 {
+		return cborHead(cborNegInt, -1-n)
+	}
+	return cborHead(cborUint, n)
+}
+
+func cborBytesItem(b []byte) []byte {
+	return append(cborHead(cborBytes, len(b)), b...)
+}
+
+func cborTextItem(s string) []byte {
+	return append(cborHead(cborText, len(s)), s...)
+}
+
+func coseES256Key(pub *ecdsa.PublicKey) []byte {
+	x, y := make([]byte, 32), make([]byte, 32)
+	xb, yb := pub.X.Bytes(), pub.Y.Bytes()
+	copy(x[32-len(xb):], xb)
+	copy(y[32-len(yb):], yb)
+
+	buf := cborHead(cborMap, 5)
+	buf = append(append(buf, cborInt(1)...), cborInt(2)...)
+	buf = append(append(buf, cborInt(3)...), cborInt(ALG_ES256)...)
+	buf = append(append(buf, cborInt(-1)...), cborInt(1)...)
+	buf = append(append(buf, cborInt(-2)...), cborBytesItem(x)...)
+	buf = append(append(buf, cborInt(-3)...), cborBytesItem(y)...)
+	return buf
+}
+
+func authData(rpID string, flags byte, signCount uint32, credID, publicKey []byte) []byte {
+	hash := sha256.Sum256([]byte(rpID))
+	buf := append([]byte{}, hash[:]...)
+	buf = append(buf, flags, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(buf[33:], signCount)
+	if credID != nil {
+		buf = append(buf, make([]byte, 16)...)
+		buf = append(buf, byte(len(credID)>>8), byte(len(credID)))
+		buf = append(buf, credID...)
+		buf = append(buf, publicKey...)
+	}
+	return buf
+}
+
+func clientDataJSON(typ, challenge, origin string) []byte {
+	data, _ := json.Marshal(clientData{typ, challenge, origin})
+	return data
+}
+
+func Test_decodeCBOR(t *testing.T) {
+	data := cborHead(cborMap, 2)
+	data = append(append(data, cborTextItem("fmt")...), cborTextItem("none")...)
+	data = append(append(data, cborInt(-257)...), cborHead(cborArray, 2)...)
+	data = append(append(data, 0xf5), 0xf6)
+
+	v, rest, err := decodeCBOR(data)
+	if err != nil {
+		t.Fatalf("decodeCBOR: %v", err)
+	} else if len(rest) != 0 {
+		t.Fatalf("expect no remaining bytes, got %d", len(rest))
+	}
+	m := v.(map[interface{}]interface{})
+	if m["fmt"] != "none" {
+		t.Errorf("expect fmt to be 'none', got %v", m["fmt"])
+	}
+	arr := m[int64(-257)].([]interface{})
+	if arr[0] != true || arr[1] != nil {
+		t.Errorf("unexpected array: %v", arr)
+	}
+
+	if _, _, err = decodeCBOR(cborHead(cborBytes, 10)); err == nil {
+		t.Error("expect error for truncated data")
+	}
+}
+
+func TestNewRelyingParty(t *testing.T) {
+	rp, err := NewRelyingParty("https://try.gogs.io:3000/sub/")
+	if err != nil {
+		t.Fatalf("NewRelyingParty: %v", err)
+	}
+	if rp.ID != "try.gogs.io" || rp.Origin != "https://try.gogs.io:3000" {
+		t.Errorf("unexpected relying party: %+v", rp)
+	}
+}
+
+func TestRegistrationAndAssertion(t *testing.T) {
+	rp := &RelyingParty{ID: "localhost", Origin: "http://localhost:3000"}
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	credID := []byte("credential-id")
+
+	// Registration.
+	challenge, _ := NewChallenge()
+	attObj := cborHead(cborMap, 3)
+	attObj = append(append(attObj, cborTextItem("fmt")...), cborTextItem("none")...)
+	attObj = append(append(attObj, cborTextItem("attStmt")...), cborHead(cborMap, 0)...)
+	attObj = append(attObj, cborTextItem("authData")...)
+	attObj = append(attObj, cborBytesItem(authData(rp.ID, flagUserPresent|flagAttestedData, 0, credID, coseES256Key(&priv.PublicKey)))...)
+
+	if _, err = rp.VerifyRegistration("other", clientDataJSON("webauthn.create", challenge, rp.Origin), attObj); err != ErrChallengeMismatch {
+		t.Errorf("expect ErrChallengeMismatch, got %v", err)
+	}
+	if _, err = rp.VerifyRegistration(challenge, clientDataJSON("webauthn.create", challenge, "http://evil.com"), attObj); err != ErrOriginMismatch {
+		t.Errorf("expect ErrOriginMismatch, got %v", err)
+	}
+	cred, err := rp.VerifyRegistration(challenge, clientDataJSON("webauthn.create", challenge, rp.Origin), attObj)
+	if err != nil {
+		t.Fatalf("VerifyRegistration: %v", err)
+	} else if string(cred.ID) != string(credID) {
+		t.Fatalf("unexpected credential ID: %s", cred.ID)
+	}
+
+	// Assertion.
+	sign := func(ad, cd []byte) []byte {
+		cdHash := sha256.Sum256(cd)
+		hash := sha256.Sum256(append(append([]byte{}, ad...), cdHash[:]...))
+		r, s, err := ecdsa.Sign(rand.Reader, priv, hash[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig, _ := asn1.Marshal(struct{ R, S interface{} }{r, s})
+		return sig
+	}
+
+	challenge, _ = NewChallenge()
+	ad := authData(rp.ID, flagUserPresent, 5, nil, nil)
+	cd := clientDataJSON("webauthn.get", challenge, rp.Origin)
+	count, err := rp.VerifyAssertion(challenge, cred, cd, ad, sign(ad, cd))
+	if err != nil {
+		t.Fatalf("VerifyAssertion: %v", err)
+	} else if count != 5 {
+		t.Errorf("expect sign count 5, got %d", count)
+	}
+
+	cred.SignCount = count
+	if _, err = rp.VerifyAssertion(challenge, cred, cd, ad, sign(ad, cd)); err != ErrSignCountNotIncreased {
+		t.Errorf("expect ErrSignCountNotIncreased, got %v", err)
+	}
+
+	ad = authData(rp.ID, flagUserPresent, 6, nil, nil)
+	if _, err = rp.VerifyAssertion(challenge, cred, cd, ad, sign(authData(rp.ID, flagUserPresent, 7, nil, nil), cd)); err != ErrInvalidSignature {
+		t.Errorf("expect ErrInvalidSignature, got %v", err)
+	}
+	if _, err = rp.VerifyAssertion(challenge, cred, cd, authData("evil.com", flagUserPresent, 6, nil, nil), nil); err != ErrRelyingPartyMismatch {
+		t.Errorf("expect ErrRelyingPartyMismatch, got %v", err)
+	}
+}
@@ -515,6 +515,88 @@ function initOrganization() {
     }
 }
 
+// Convert between ArrayBuffer and unpadded base64url used by WebAuthn exchanges.
+function bufferToBase64URL(buf) {
+    var bytes = new Uint8Array(buf);
+    var str = '';
+    for (var i = 0; i < bytes.length; i++) {
+        str += String.fromCharCode(bytes[i]);
+    }
+    return btoa(str).replace(/\+/g, '-').replace(/\//g, '_').replace(/=+$/, '');
+}
+
+function base64URLToBuffer(str) {
+    str = str.replace(/-/g, '+').replace(/_/g, '/');
+    while (str.length % 4 != 0) {
+        str += '=';
+    }
+    var raw = atob(str);
+    var bytes = new Uint8Array(raw.length);
+    for (var i = 0; i < raw.length; i++) {
+        bytes[i] = raw.charCodeAt(i);
+    }
+    return bytes.buffer;
+}
+
+function initSecurityKeys() {
+    var $unsupported = $('.security-key-unsupported');
+    if (!window.PublicKeyCredential) {
+        $unsupported.show();
+        return;
+    }
+
+    // Register a new security key.
+    var $form = $('#add-security-key-form');
+    if ($form.length > 0) {
+        $form.submit(function (e) {
+            if ($form.find('input[name=attestation_object]').val() != '') {
+                return;
+            }
+            e.preventDefault();
+
+            var opts = $form.data('options');
+            opts.challenge = base64URLToBuffer(opts.challenge);
+            opts.user.id = base64URLToBuffer(opts.user.id);
+            for (var i = 0; i < opts.excludeCredentials.length; i++) {
+                opts.excludeCredentials[i].id = base64URLToBuffer(opts.excludeCredentials[i].id);
+            }
+            navigator.credentials.create({publicKey: opts}).then(function (cred) {
+                $form.find('input[name=client_data]').val(bufferToBase64URL(cred.response.clientDataJSON));
+                $form.find('input[name=attestation_object]').val(bufferToBase64URL(cred.response.attestationObject));
+                $form.submit();
+            }).catch(function (err) {
+                $('.security-key-error').text(err.message).show();
+            });
+        });
+    }
+
+    // Sign in with security key.
+    var $signin = $('#security-key-signin-form');
+    if ($signin.length > 0) {
+        var opts = $signin.data('options');
+        opts.challenge = base64URLToBuffer(opts.challenge);
+        for (var i = 0; i < opts.allowCredentials.length; i++) {
+            opts.allowCredentials[i].id = base64URLToBuffer(opts.allowCredentials[i].id);
+        }
+        var getAssertion = function () {
+            navigator.credentials.get({publicKey: opts}).then(function (cred) {
+                $signin.find('input[name=credential_id]').val(bufferToBase64URL(cred.rawId));
+                $signin.find('input[name=client_data]').val(bufferToBase64URL(cred.response.clientDataJSON));
+                $signin.find('input[name=authenticator_data]').val(bufferToBase64URL(cred.response.authenticatorData));
+                $signin.find('input[name=signature]').val(bufferToBase64URL(cred.response.signature));
+                $signin.submit();
+            }).catch(function (err) {
+                $('.security-key-error').text(err.message).show();
+            });
+        };
+        $signin.find('.retry.button').click(function (e) {
+            e.preventDefault();
+            getAssertion();
+        });
+        getAssertion();
+    }
+}
+
 function initUser() {
     if ($('.user').length == 0) {
         return;
@@ -531,6 +613,41 @@ function initUser() {
             }
         });
     }
+
+    if ($('.user.security-key').length > 0) {
+        initSecurityKeys();
+    }
+}
+
+function initCodeComments() {
+    if ($('#code-comment-form-template').length == 0) {
+        return;
+    }
+
+    $('.add-code-comment').click(function () {
+        // Put form after thread of the line if there is one.
+        var $row = $(this).closest('tr');
+        if (!$row.hasClass('code-comments') && $row.next().hasClass('code-comments')) {
+            $row = $row.next();
+        }
+        if ($row.next().hasClass('code-comment-form')) {
+            $row.next().find('textarea').focus();
+            return false;
+        }
+
+        var $form = $('#code-comment-form-template').children('form').clone();
+        $form.find('input[name=tree_path]').val($(this).data('path'));
+        $form.find('input[name=line]').val($(this).data('line'));
+
+        var $formRow = $('<tr class="code-comment-form"><td colspan="3"></td></tr>');
+        $formRow.children('td').append($form);
+        $row.after($formRow);
+        $form.find('.cancel').click(function () {
+            $formRow.remove();
+        });
+        $form.find('textarea').focus();
+        return false;
+    });
 }
 
 function initWebhook() {
@@ -887,6 +1004,7 @@ $(document).ready(function () {
     initCommentForm();
     initInstall();
     initRepository();
+    initCodeComments();
     initWiki();
     initOrganization();
     initUser();
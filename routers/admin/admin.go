@@ -120,6 +120,9 @@ const (
 	GIT_GC_REPOS
 	SYNC_SSH_AUTHORIZED_KEY
 	SYNC_REPOSITORY_UPDATE_HOOK
+	REBUILD_DERIVED_DATA
+	TOGGLE_MAINTENANCE_MODE
+	CLEAN_ORPHANED_ATTACHMENTS
 )
 
 func Dashboard(ctx *middleware.Context) {
@@ -152,6 +155,26 @@ func Dashboard(ctx *middleware.Context) {
 		case SYNC_REPOSITORY_UPDATE_HOOK:
 			success = ctx.Tr("admin.dashboard.resync_all_update_hooks_success")
 			err = models.RewriteRepositoryUpdateHook()
+		case REBUILD_DERIVED_DATA:
+			success = ctx.Tr("admin.dashboard.rebuild_derived_data_success")
+			if err = ctx.Cache.Flush(); err == nil {
+				go models.RebuildDerivedData()
+			}
+		case TOGGLE_MAINTENANCE_MODE:
+			if setting.MaintenanceMode {
+				success = ctx.Tr("admin.dashboard.maintenance_mode_disabled")
+			} else {
+				success = ctx.Tr("admin.dashboard.maintenance_mode_enabled")
+			}
+			err = setting.SaveMaintenanceMode(!setting.MaintenanceMode)
+		case CLEAN_ORPHANED_ATTACHMENTS:
+			if models.IsDeletingOrphanedAttachments() {
+				ctx.Flash.Info(ctx.Tr("admin.dashboard.delete_orphaned_attachments_running"))
+				ctx.Redirect(setting.AppSubUrl + "/admin")
+				return
+			}
+			success = ctx.Tr("admin.dashboard.delete_orphaned_attachments_success")
+			err = models.DeleteOrphanedAttachments()
 		}
 
 		if err != nil {
@@ -164,6 +187,7 @@ func Dashboard(ctx *middleware.Context) {
 	}
 
 	ctx.Data["Stats"] = models.GetStatistic()
+	ctx.Data["RebuildStatus"] = models.GetRebuildStatus()
 	// FIXME: update periodically
 	updateSystemStatus()
 	ctx.Data["SysStatus"] = sysStatus
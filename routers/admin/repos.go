@@ -9,6 +9,7 @@ import (
 
 	"github.com/gogits/gogs/models"
 	"github.com/gogits/gogs/modules/base"
+	"github.com/gogits/gogs/modules/log"
 	"github.com/gogits/gogs/modules/middleware"
 	"github.com/gogits/gogs/modules/setting"
 )
@@ -39,3 +40,29 @@ func Repositories(ctx *middleware.Context) {
 	ctx.Data["Total"] = total
 	ctx.HTML(200, REPOS)
 }
+
+// GitGcRepo runs garbage collection on a single repository.
+func GitGcRepo(ctx *middleware.Context) {
+	repo, err := models.GetRepositoryByID(ctx.ParamsInt64(":id"))
+	if err != nil {
+		if models.IsErrRepoNotExist(err) {
+			ctx.Handle(404, "GetRepositoryByID", nil)
+		} else {
+			ctx.Handle(500, "GetRepositoryByID", err)
+		}
+		return
+	}
+
+	if err = repo.GitGC(); err != nil {
+		if models.IsErrRepoLocked(err) {
+			ctx.Flash.Error(ctx.Tr("admin.repos.git_gc_locked", repo.Name))
+		} else {
+			ctx.Flash.Error(err.Error())
+		}
+	} else {
+		log.Trace("Repository garbage collected: %d", repo.ID)
+		ctx.Flash.Success(ctx.Tr("admin.repos.git_gc_success", repo.Name,
+			base.FileSize(repo.SizeBeforeGc), base.FileSize(repo.SizeAfterGc)))
+	}
+	ctx.Redirect(setting.AppSubUrl + "/admin/repos?page=" + ctx.Query("page"))
+}
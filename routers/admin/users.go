@@ -30,14 +30,31 @@ func Users(ctx *middleware.Context) {
 	ctx.Data["PageIsAdmin"] = true
 	ctx.Data["PageIsAdminUsers"] = true
 
+	pendingCount := models.CountPendingApprovalUsers()
+	ctx.Data["PendingApprovalCount"] = pendingCount
+
+	isShowPending := ctx.Query("type") == "pending"
+	ctx.Data["IsShowPendingApproval"] = isShowPending
+
 	total := models.CountUsers()
+	if isShowPending {
+		total = pendingCount
+	}
 	page := ctx.QueryInt("page")
 	if page <= 1 {
 		page = 1
 	}
 	ctx.Data["Page"] = paginater.New(int(total), setting.AdminUserPagingNum, page, 5)
 
-	users, err := models.Users(page, setting.AdminUserPagingNum)
+	var (
+		users []*models.User
+		err   error
+	)
+	if isShowPending {
+		users, err = models.PendingApprovalUsers(page, setting.AdminUserPagingNum)
+	} else {
+		users, err = models.Users(page, setting.AdminUserPagingNum)
+	}
 	if err != nil {
 		ctx.Handle(500, "Users", err)
 		return
@@ -199,6 +216,27 @@ func EditUserPost(ctx *middleware.Context, form auth.AdminEditUserForm) {
 		}
 	}
 
+	// Administrators are not limited by cooldown of changing username.
+	if u.LowerName != strings.ToLower(form.UserName) {
+		if err := models.ChangeUserName(u, form.UserName); err != nil {
+			ctx.Data["Err_UserName"] = true
+			switch {
+			case models.IsErrUserAlreadyExist(err):
+				ctx.RenderWithErr(ctx.Tr("form.username_been_taken"), USER_EDIT, &form)
+			case models.IsErrNameReserved(err):
+				ctx.RenderWithErr(ctx.Tr("user.form.name_reserved", err.(models.ErrNameReserved).Name), USER_EDIT, &form)
+			case models.IsErrNamePatternNotAllowed(err):
+				ctx.RenderWithErr(ctx.Tr("user.form.name_pattern_not_allowed", err.(models.ErrNamePatternNotAllowed).Pattern), USER_EDIT, &form)
+			default:
+				ctx.Handle(500, "ChangeUserName", err)
+			}
+			return
+		}
+		log.Trace("User name changed by admin(%s): %s -> %s", ctx.User.Name, u.Name, form.UserName)
+	}
+	u.Name = form.UserName
+	u.LowerName = strings.ToLower(form.UserName)
+
 	if len(form.Password) > 0 {
 		u.Passwd = form.Password
 		u.Salt = models.GetUserSalt()
@@ -211,9 +249,15 @@ func EditUserPost(ctx *middleware.Context, form auth.AdminEditUserForm) {
 	u.Website = form.Website
 	u.Location = form.Location
 	u.IsActive = form.Active
+	// Activating account by administrator approves it as well.
+	if u.IsActive {
+		u.NeedApproval = false
+	}
 	u.IsAdmin = form.Admin
 	u.AllowGitHook = form.AllowGitHook
 	u.AllowImportLocal = form.AllowImportLocal
+	u.StorageQuota = form.StorageQuota
+	u.MaxRepoCreation = form.MaxRepoCreation
 
 	if err := models.UpdateUser(u); err != nil {
 		if models.IsErrEmailAlreadyUsed(err) {
@@ -230,6 +274,43 @@ func EditUserPost(ctx *middleware.Context, form auth.AdminEditUserForm) {
 	ctx.Redirect(setting.AppSubUrl + "/admin/users/" + ctx.Params(":userid"))
 }
 
+// POST /admin/users/:userid/approve
+func ApproveUser(ctx *middleware.Context) {
+	u, err := models.GetUserByID(ctx.ParamsInt64(":userid"))
+	if err != nil {
+		if models.IsErrUserNotExist(err) {
+			ctx.Handle(404, "GetUserByID", err)
+		} else {
+			ctx.Handle(500, "GetUserByID", err)
+		}
+		return
+	}
+
+	if !u.NeedApproval {
+		ctx.Flash.Error(ctx.Tr("admin.users.not_pending_approval"))
+		ctx.Redirect(setting.AppSubUrl + "/admin/users/" + ctx.Params(":userid"))
+		return
+	}
+
+	if err = models.ApproveUser(u); err != nil {
+		ctx.Handle(500, "ApproveUser", err)
+		return
+	}
+	log.Trace("Account approved by admin(%s): %s", ctx.User.Name, u.Name)
+
+	// E-mail address still needs to be confirmed by user after approval.
+	if setting.MailService != nil {
+		if setting.Service.RegisterEmailConfirm {
+			mailer.SendActivateAccountMail(ctx.Context, u)
+		} else {
+			mailer.SendRegisterNotifyMail(ctx.Context, u)
+		}
+	}
+
+	ctx.Flash.Success(ctx.Tr("admin.users.approve_success", u.Name))
+	ctx.Redirect(setting.AppSubUrl + "/admin/users?type=pending")
+}
+
 func DeleteUser(ctx *middleware.Context) {
 	u, err := models.GetUserByID(ctx.ParamsInt64(":userid"))
 	if err != nil {
@@ -0,0 +1,43 @@
+// Copyright 2015 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package v1
+
+import (
+	"github.com/gogits/gogs/models"
+	"github.com/gogits/gogs/modules/log"
+	"github.com/gogits/gogs/modules/middleware"
+)
+
+// GET /admin/rebuild
+func GetRebuildStatus(ctx *middleware.Context) {
+	ctx.JSON(200, models.GetRebuildStatus())
+}
+
+// POST /admin/rebuild
+func RebuildDerivedData(ctx *middleware.Context) {
+	if models.IsRebuildRunning() {
+		ctx.JSON(409, models.GetRebuildStatus())
+		return
+	}
+
+	if err := ctx.Cache.Flush(); err != nil {
+		ctx.APIError(500, "Flush", err)
+		return
+	}
+	go models.RebuildDerivedData()
+
+	log.Trace("Rebuild of derived data requested by admin: %s", ctx.User.Name)
+	ctx.JSON(202, models.GetRebuildStatus())
+}
+
+// GET /admin/stats
+func GetServerStats(ctx *middleware.Context) {
+	stats, err := models.GetServerStats()
+	if err != nil {
+		ctx.APIError(500, "GetServerStats", err)
+		return
+	}
+	ctx.JSON(200, stats)
+}
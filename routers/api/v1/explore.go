@@ -0,0 +1,77 @@
+// Copyright 2015 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package v1
+
+import (
+	api "github.com/gogits/go-gogs-client"
+
+	"github.com/gogits/gogs/models"
+	"github.com/gogits/gogs/modules/middleware"
+	"github.com/gogits/gogs/modules/setting"
+)
+
+// EXPLORE_MAX_PAGING_NUM is the maximum number of users or organizations can be requested per page.
+const EXPLORE_MAX_PAGING_NUM = 50
+
+// exploreUsers lists users or organizations of given type like explore page does,
+// results are sorted by most recently updated unless "sort=newest" is given.
+func exploreUsers(ctx *middleware.Context, tp models.UserType) {
+	if setting.Service.RequireSignInView && !ctx.IsSigned {
+		ctx.APIError(401, "", "Sign in is required to explore.")
+		return
+	}
+
+	page := ctx.QueryInt("page")
+	if page <= 0 {
+		page = 1
+	}
+	limit := ctx.QueryInt("limit")
+	if limit <= 0 {
+		limit = setting.ExplorePagingNum
+	} else if limit > EXPLORE_MAX_PAGING_NUM {
+		limit = EXPLORE_MAX_PAGING_NUM
+	}
+
+	opts := &models.ExploreUserOptions{
+		Type:     tp,
+		Page:     page,
+		PageSize: limit,
+	}
+	switch ctx.Query("sort") {
+	case "", "recentupdate":
+		opts.OrderBy = "updated DESC"
+	case "newest":
+		opts.OrderBy = "created DESC"
+	default:
+		ctx.APIError(422, "", "Sort must be one of 'recentupdate' or 'newest'.")
+		return
+	}
+
+	users, total, err := models.ExploreUsers(opts)
+	if err != nil {
+		ctx.APIError(500, "ExploreUsers", err)
+		return
+	}
+
+	results := make([]*api.User, len(users))
+	for i := range users {
+		results[i] = ToApiUser(users[i])
+		if !ctx.IsSigned {
+			results[i].Email = ""
+		}
+	}
+	setPaginationHeaders(ctx, page, limit, int(total))
+	ctx.JSON(200, &results)
+}
+
+// GET /explore/users
+func ExploreUsers(ctx *middleware.Context) {
+	exploreUsers(ctx, models.INDIVIDUAL)
+}
+
+// GET /explore/orgs
+func ExploreOrgs(ctx *middleware.Context) {
+	exploreUsers(ctx, models.ORGANIZATION)
+}
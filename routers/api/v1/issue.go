@@ -0,0 +1,532 @@
+// Copyright 2015 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package v1
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	api "github.com/gogits/go-gogs-client"
+
+	"github.com/gogits/gogs/models"
+	"github.com/gogits/gogs/modules/log"
+	"github.com/gogits/gogs/modules/middleware"
+)
+
+// RepositoryMeta represents basic information of the repository an object belongs to.
+type RepositoryMeta struct {
+	ID       int64  `json:"id"`
+	Name     string `json:"name"`
+	Owner    string `json:"owner"`
+	FullName string `json:"full_name"`
+}
+
+// Label represents an issue label in API format.
+type Label struct {
+	ID    int64  `json:"id"`
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+// Milestone represents a milestone in API format.
+type Milestone struct {
+	ID           int64     `json:"id"`
+	Title        string    `json:"title"`
+	Description  string    `json:"description"`
+	State        string    `json:"state"`
+	OpenIssues   int       `json:"open_issues"`
+	ClosedIssues int       `json:"closed_issues"`
+	Deadline     time.Time `json:"due_on"`
+}
+
+// Issue represents an issue in API format.
+type Issue struct {
+	ID         int64           `json:"id"`
+	Index      int64           `json:"number"`
+	Repository *RepositoryMeta `json:"repository"`
+	Poster     *api.User       `json:"user"`
+	Title      string          `json:"title"`
+	Body       string          `json:"body"`
+	Labels     []*Label        `json:"labels"`
+	Milestone  *Milestone      `json:"milestone"`
+	Assignee   *api.User       `json:"assignee"`
+	State      string          `json:"state"`
+	Locked     bool            `json:"locked"`
+	Pinned     bool            `json:"pinned"`
+	Comments   int             `json:"comments"`
+	Created    time.Time       `json:"created_at"`
+	Updated    time.Time       `json:"updated_at"`
+}
+
+func stateName(isClosed bool) string {
+	if isClosed {
+		return "closed"
+	}
+	return "open"
+}
+
+// ToApiRepositoryMeta converts repository to API format of basic information.
+func ToApiRepositoryMeta(repo *models.Repository) *RepositoryMeta {
+	return &RepositoryMeta{
+		ID:       repo.ID,
+		Name:     repo.Name,
+		Owner:    repo.Owner.Name,
+		FullName: repo.Owner.Name + "/" + repo.Name,
+	}
+}
+
+// ToApiLabel converts label to API format.
+func ToApiLabel(l *models.Label) *Label {
+	return &Label{
+		ID:    l.ID,
+		Name:  l.Name,
+		Color: l.Color,
+	}
+}
+
+// ToApiMilestone converts milestone to API format.
+func ToApiMilestone(m *models.Milestone) *Milestone {
+	return &Milestone{
+		ID:           m.ID,
+		Title:        m.Name,
+		Description:  m.Content,
+		State:        stateName(m.IsClosed),
+		OpenIssues:   m.NumOpenIssues,
+		ClosedIssues: m.NumClosedIssues,
+		Deadline:     m.Deadline,
+	}
+}
+
+// ToApiIssue converts issue to API format, repository of issue must be loaded.
+func ToApiIssue(issue *models.Issue) (*Issue, error) {
+	if err := issue.GetPoster(); err != nil {
+		return nil, err
+	} else if err = issue.GetLabels(); err != nil {
+		return nil, err
+	}
+
+	apiIssue := &Issue{
+		ID:         issue.ID,
+		Index:      issue.Index,
+		Repository: ToApiRepositoryMeta(issue.Repo),
+		Poster:     ToApiUser(issue.Poster),
+		Title:      issue.Name,
+		Body:       issue.Content,
+		Labels:     make([]*Label, len(issue.Labels)),
+		State:      stateName(issue.IsClosed),
+		Locked:     issue.IsLocked,
+		Pinned:     issue.IsPinned,
+		Comments:   issue.NumComments,
+		Created:    issue.Created,
+		Updated:    issue.Updated,
+	}
+	for i := range issue.Labels {
+		apiIssue.Labels[i] = ToApiLabel(issue.Labels[i])
+	}
+	if issue.Milestone != nil {
+		apiIssue.Milestone = ToApiMilestone(issue.Milestone)
+	}
+	if issue.Assignee != nil {
+		apiIssue.Assignee = ToApiUser(issue.Assignee)
+	}
+	return apiIssue, nil
+}
+
+// getUserIDByQuery returns ID of user whose name is given by query parameter,
+// or 0 if parameter is empty.
+func getUserIDByQuery(ctx *middleware.Context, name string) int64 {
+	userName := ctx.Query(name)
+	if len(userName) == 0 {
+		return 0
+	}
+
+	u, err := models.GetUserByName(userName)
+	if err != nil {
+		if models.IsErrUserNotExist(err) {
+			ctx.APIError(422, "", err)
+		} else {
+			ctx.APIError(500, "GetUserByName", err)
+		}
+		return 0
+	}
+	return u.Id
+}
+
+// getAssigneeByName returns user of given name who can be assigned to issues of repository,
+// it responds with 422 if user does not exist or cannot read the repository.
+func getAssigneeByName(ctx *middleware.Context, name string) *models.User {
+	assignee, err := models.GetUserByName(name)
+	if err != nil {
+		if models.IsErrUserNotExist(err) {
+			ctx.APIError(422, "", err)
+		} else {
+			ctx.APIError(500, "GetUserByName", err)
+		}
+		return nil
+	}
+
+	has, err := models.HasAccess(assignee, ctx.Repo.Repository, models.ACCESS_MODE_READ)
+	if err != nil {
+		ctx.APIError(500, "HasAccess", err)
+		return nil
+	} else if !has {
+		ctx.APIError(422, "", "Assignee does not have access to repository.")
+		return nil
+	}
+	return assignee
+}
+
+// GET /issues/search
+func SearchIssues(ctx *middleware.Context) {
+	opts := &models.IssuesOptions{
+		Page:            ctx.QueryInt("page"),
+		IsClosed:        ctx.Query("state") == "closed",
+		IsPull:          ctx.Query("type") == "pulls",
+		SortType:        ctx.Query("sort"),
+		Keyword:         strings.TrimSpace(ctx.Query("q")),
+		VisibleToUserID: ctx.User.Id,
+		OnlyEnabledUnit: true,
+	}
+	if opts.Page <= 0 {
+		opts.Page = 1
+	}
+	if labels := strings.TrimSpace(ctx.Query("labels")); len(labels) > 0 {
+		opts.LabelNames = strings.Split(labels, ",")
+	}
+
+	opts.AssigneeID = getUserIDByQuery(ctx, "assignee")
+	if ctx.Written() {
+		return
+	}
+	opts.PosterID = getUserIDByQuery(ctx, "author")
+	if ctx.Written() {
+		return
+	}
+
+	issues, err := models.Issues(opts)
+	if err != nil {
+		ctx.APIError(500, "Issues", err)
+		return
+	}
+
+	repos := make(map[int64]*models.Repository)
+	apiIssues := make([]*Issue, len(issues))
+	for i := range issues {
+		repo, ok := repos[issues[i].RepoID]
+		if !ok {
+			repo, err = models.GetRepositoryByID(issues[i].RepoID)
+			if err != nil {
+				ctx.APIError(500, "GetRepositoryByID", err)
+				return
+			} else if err = repo.GetOwner(); err != nil {
+				ctx.APIError(500, "GetOwner", err)
+				return
+			}
+			repos[repo.ID] = repo
+		}
+		issues[i].Repo = repo
+
+		if apiIssues[i], err = ToApiIssue(issues[i]); err != nil {
+			ctx.APIError(500, "ToApiIssue", err)
+			return
+		}
+	}
+
+	ctx.JSON(200, &apiIssues)
+}
+
+// EditIssueOption represents options for editing an issue,
+// fields left empty are not changed.
+type EditIssueOption struct {
+	Title     *string `json:"title"`
+	Body      *string `json:"body"`
+	Assignee  *string `json:"assignee"`
+	Milestone *int64  `json:"milestone"`
+	Labels    []int64 `json:"labels"`
+	State     *string `json:"state"`
+}
+
+// PATCH /repos/:username/:reponame/issues/:index
+func EditIssue(ctx *middleware.Context, form EditIssueOption) {
+	repo := ctx.Repo.Repository
+	issue, err := models.GetIssueByIndex(repo.ID, ctx.ParamsInt64(":index"))
+	if err != nil {
+		if models.IsErrIssueNotExist(err) {
+			ctx.Error(404)
+		} else {
+			ctx.APIError(500, "GetIssueByIndex", err)
+		}
+		return
+	}
+	issue.Repo = repo
+
+	// Poster is allowed to edit title, content and status of own issue,
+	// but changing metadata requires write access.
+	isPusher := ctx.Repo.IsPusher()
+	if !isPusher && (!issue.IsPoster(ctx.User.Id) ||
+		form.Assignee != nil || form.Milestone != nil || form.Labels != nil) {
+		ctx.APIError(403, "", "Given user does not have write access to repository.")
+		return
+	}
+
+	if form.Title != nil || form.Body != nil {
+		if form.Title != nil {
+			issue.Name = strings.TrimSpace(*form.Title)
+			if len(issue.Name) == 0 {
+				ctx.APIError(422, "", "Title cannot be empty.")
+				return
+			}
+		}
+		if form.Body != nil {
+			issue.Content = *form.Body
+		}
+		if err = models.UpdateIssue(issue); err != nil {
+			ctx.APIError(500, "UpdateIssue", err)
+			return
+		}
+	}
+
+	if form.Assignee != nil {
+		var assigneeID int64
+		if len(*form.Assignee) > 0 {
+			assignee := getAssigneeByName(ctx, *form.Assignee)
+			if ctx.Written() {
+				return
+			}
+			assigneeID = assignee.Id
+		}
+
+		if issue.AssigneeID != assigneeID {
+			issue.AssigneeID = assigneeID
+			if err = models.UpdateIssueUserByAssignee(ctx.User, issue); err != nil {
+				ctx.APIError(500, "UpdateIssueUserByAssignee", err)
+				return
+			}
+		}
+	}
+
+	if form.Milestone != nil && issue.MilestoneID != *form.Milestone {
+		if *form.Milestone > 0 {
+			if _, err = models.GetRepoMilestoneByID(repo.ID, *form.Milestone); err != nil {
+				if models.IsErrMilestoneNotExist(err) {
+					ctx.APIError(422, "", err)
+				} else {
+					ctx.APIError(500, "GetRepoMilestoneByID", err)
+				}
+				return
+			}
+		}
+
+		oldMid := issue.MilestoneID
+		issue.MilestoneID = *form.Milestone
+		if err = models.ChangeMilestoneAssign(ctx.User, oldMid, issue); err != nil {
+			ctx.APIError(500, "ChangeMilestoneAssign", err)
+			return
+		}
+	}
+
+	if form.Labels != nil {
+		labels := make([]*models.Label, 0, len(form.Labels))
+		for _, id := range form.Labels {
+			label, err := models.GetLabelByID(id)
+			if err != nil {
+				if models.IsErrLabelNotExist(err) {
+					ctx.APIError(422, "", err)
+				} else {
+					ctx.APIError(500, "GetLabelByID", err)
+				}
+				return
+			} else if label.RepoID != repo.ID {
+				ctx.APIError(422, "", models.ErrLabelNotExist{id})
+				return
+			}
+			labels = append(labels, label)
+		}
+
+		// Only labels actually added or removed are changed, so timeline of issue is not polluted.
+		if err = issue.GetLabels(); err != nil {
+			ctx.APIError(500, "GetLabels", err)
+			return
+		}
+		keep := make(map[int64]bool, len(labels))
+		for _, label := range labels {
+			keep[label.ID] = true
+		}
+		for _, label := range issue.Labels {
+			if keep[label.ID] {
+				continue
+			}
+			if err = issue.RemoveLabel(ctx.User, label); err != nil {
+				ctx.APIError(500, "RemoveLabel", err)
+				return
+			}
+		}
+		for _, label := range labels {
+			if issue.HasLabel(label.ID) {
+				continue
+			}
+			if err = issue.AddLabel(ctx.User, label); err != nil {
+				ctx.APIError(500, "AddLabel", err)
+				return
+			}
+		}
+	}
+
+	if form.State != nil {
+		if *form.State != "open" && *form.State != "closed" {
+			ctx.APIError(422, "", "State must be either 'open' or 'closed'.")
+			return
+		}
+
+		if msg, err := changeIssueStatus(ctx.User, issue, *form.State == "closed"); err != nil {
+			ctx.APIError(500, "changeIssueStatus", err)
+			return
+		} else if len(msg) > 0 {
+			ctx.APIError(422, "", msg)
+			return
+		}
+		log.Trace("Issue[%d] status changed to closed: %v", issue.ID, issue.IsClosed)
+	}
+
+	// Reload to reflect all changes.
+	issue, err = models.GetIssueByIndex(repo.ID, issue.Index)
+	if err != nil {
+		ctx.APIError(500, "GetIssueByIndex", err)
+		return
+	}
+	issue.Repo = repo
+
+	apiIssue, err := ToApiIssue(issue)
+	if err != nil {
+		ctx.APIError(500, "ToApiIssue", err)
+		return
+	}
+	ctx.JSON(200, apiIssue)
+}
+
+// changeIssueStatus closes or reopens issue with checks of pull request applied,
+// it returns a message describing the reason if the change is not allowed.
+func changeIssueStatus(doer *models.User, issue *models.Issue, isClosed bool) (string, error) {
+	if issue.IsClosed == isClosed {
+		return "", nil
+	}
+
+	if issue.IsPull {
+		if err := issue.GetPullRequest(); err != nil {
+			return "", fmt.Errorf("GetPullRequest: %v", err)
+		} else if issue.HasMerged {
+			return "Cannot change state of a merged pull request.", nil
+		}
+
+		// Duplication check should apply to reopen pull request.
+		if !isClosed {
+			pull := issue.PullRequest
+			pr, err := models.GetUnmergedPullRequest(pull.HeadRepoID, pull.BaseRepoID, pull.HeadBranch, pull.BaseBranch)
+			if err == nil {
+				return fmt.Sprintf("There is already an open pull request #%d for the same branches.", pr.Index), nil
+			} else if !models.IsErrPullRequestNotExist(err) {
+				return "", fmt.Errorf("GetUnmergedPullRequest: %v", err)
+			}
+
+			if err = issue.UpdatePatch(); err != nil {
+				return "", fmt.Errorf("UpdatePatch: %v", err)
+			}
+			issue.AddToTaskQueue()
+		}
+	}
+
+	if err := issue.ChangeStatus(doer, isClosed); err != nil {
+		return "", fmt.Errorf("ChangeStatus: %v", err)
+	}
+	return "", nil
+}
+
+// getIssueToLock returns issue given by URL that current user is allowed to lock or pin.
+func getIssueToLock(ctx *middleware.Context) *models.Issue {
+	if !ctx.Repo.IsAdmin() {
+		ctx.APIError(403, "", "Given user does not have admin access to repository.")
+		return nil
+	}
+
+	issue, err := models.GetIssueByIndex(ctx.Repo.Repository.ID, ctx.ParamsInt64(":index"))
+	if err != nil {
+		if models.IsErrIssueNotExist(err) {
+			ctx.Error(404)
+		} else {
+			ctx.APIError(500, "GetIssueByIndex", err)
+		}
+		return nil
+	} else if !ctx.Repo.Repository.IsUnitEnabled(issue.Unit()) {
+		ctx.Error(404)
+		return nil
+	}
+	return issue
+}
+
+// LockIssueOption represents options for locking conversation of an issue.
+type LockIssueOption struct {
+	Reason string `json:"reason" binding:"MaxSize(255)"`
+}
+
+// PUT /repos/:username/:reponame/issues/:index/lock
+func LockIssue(ctx *middleware.Context, form LockIssueOption) {
+	issue := getIssueToLock(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	if err := issue.ChangeLockStatus(ctx.User, ctx.Repo.Repository, true, strings.TrimSpace(form.Reason)); err != nil {
+		ctx.APIError(500, "ChangeLockStatus", err)
+		return
+	}
+	ctx.Status(204)
+}
+
+// DELETE /repos/:username/:reponame/issues/:index/lock
+func UnlockIssue(ctx *middleware.Context) {
+	issue := getIssueToLock(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	if err := issue.ChangeLockStatus(ctx.User, ctx.Repo.Repository, false, ""); err != nil {
+		ctx.APIError(500, "ChangeLockStatus", err)
+		return
+	}
+	ctx.Status(204)
+}
+
+// PUT /repos/:username/:reponame/issues/:index/pin
+func PinIssue(ctx *middleware.Context) {
+	issue := getIssueToLock(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	if err := issue.ChangePinStatus(true); err != nil {
+		if models.IsErrIssuePinnedLimit(err) {
+			ctx.APIError(422, "", fmt.Sprintf("Repository cannot have more than %d pinned issues.", err.(models.ErrIssuePinnedLimit).Limit))
+		} else {
+			ctx.APIError(500, "ChangePinStatus", err)
+		}
+		return
+	}
+	ctx.Status(204)
+}
+
+// DELETE /repos/:username/:reponame/issues/:index/pin
+func UnpinIssue(ctx *middleware.Context) {
+	issue := getIssueToLock(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	if err := issue.ChangePinStatus(false); err != nil {
+		ctx.APIError(500, "ChangePinStatus", err)
+		return
+	}
+	ctx.Status(204)
+}
@@ -0,0 +1,181 @@
+// Copyright 2015 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package v1
+
+import (
+	"fmt"
+
+	"github.com/gogits/gogs/models"
+	"github.com/gogits/gogs/modules/log"
+	"github.com/gogits/gogs/modules/middleware"
+	"github.com/gogits/gogs/modules/setting"
+)
+
+// BatchIssuesOption represents operations to be applied to a list of issues,
+// fields left empty are not changed.
+type BatchIssuesOption struct {
+	Issues       []int64 `json:"issues"`
+	AddLabels    []int64 `json:"add_labels"`
+	RemoveLabels []int64 `json:"remove_labels"`
+	Milestone    *int64  `json:"milestone"`
+	Assignee     *string `json:"assignee"`
+	State        *string `json:"state"`
+}
+
+// BatchIssueResult represents result of applying batch operations to an issue.
+type BatchIssueResult struct {
+	Index   int64  `json:"number"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
+
+// getBatchLabels returns labels of repository with given IDs.
+func getBatchLabels(ctx *middleware.Context, ids []int64) []*models.Label {
+	labels := make([]*models.Label, 0, len(ids))
+	for _, id := range ids {
+		label, err := models.GetLabelByID(id)
+		if err != nil {
+			if models.IsErrLabelNotExist(err) {
+				ctx.APIError(422, "", err)
+			} else {
+				ctx.APIError(500, "GetLabelByID", err)
+			}
+			return nil
+		} else if label.RepoID != ctx.Repo.Repository.ID {
+			ctx.APIError(422, "", models.ErrLabelNotExist{id})
+			return nil
+		}
+		labels = append(labels, label)
+	}
+	return labels
+}
+
+// batchUpdateIssue applies operations to a single issue. It returns a message
+// describing the reason if an operation is not allowed for the issue.
+func batchUpdateIssue(doer *models.User, issue *models.Issue, form BatchIssuesOption,
+	addLabels, removeLabels []*models.Label, assigneeID int64) (string, error) {
+	for _, label := range removeLabels {
+		if !issue.HasLabel(label.ID) {
+			continue
+		}
+		if err := issue.RemoveLabel(doer, label); err != nil {
+			return "", fmt.Errorf("RemoveLabel: %v", err)
+		}
+	}
+	for _, label := range addLabels {
+		if issue.HasLabel(label.ID) {
+			continue
+		}
+		if err := issue.AddLabel(doer, label); err != nil {
+			return "", fmt.Errorf("AddLabel: %v", err)
+		}
+	}
+
+	if form.Milestone != nil && issue.MilestoneID != *form.Milestone {
+		oldMid := issue.MilestoneID
+		issue.MilestoneID = *form.Milestone
+		if err := models.ChangeMilestoneAssign(doer, oldMid, issue); err != nil {
+			return "", fmt.Errorf("ChangeMilestoneAssign: %v", err)
+		}
+	}
+
+	if form.Assignee != nil && issue.AssigneeID != assigneeID {
+		issue.AssigneeID = assigneeID
+		if err := models.UpdateIssueUserByAssignee(doer, issue); err != nil {
+			return "", fmt.Errorf("UpdateIssueUserByAssignee: %v", err)
+		}
+	}
+
+	if form.State != nil {
+		return changeIssueStatus(doer, issue, *form.State == "closed")
+	}
+	return "", nil
+}
+
+// POST /repos/:username/:reponame/issues/batch
+//
+// Operations are validated once before being applied to each issue, every issue
+// is updated independently so failure of one issue does not affect the others.
+func BatchUpdateIssues(ctx *middleware.Context, form BatchIssuesOption) {
+	if !ctx.Repo.IsPusher() {
+		ctx.APIError(403, "", "Given user does not have write access to repository.")
+		return
+	}
+
+	if len(form.Issues) == 0 {
+		ctx.APIError(422, "", "List of issues cannot be empty.")
+		return
+	} else if len(form.Issues) > setting.API.MaxBatchSize {
+		ctx.APIError(422, "", fmt.Sprintf("Cannot update more than %d issues in a batch.", setting.API.MaxBatchSize))
+		return
+	}
+
+	repo := ctx.Repo.Repository
+	addLabels := getBatchLabels(ctx, form.AddLabels)
+	if ctx.Written() {
+		return
+	}
+	removeLabels := getBatchLabels(ctx, form.RemoveLabels)
+	if ctx.Written() {
+		return
+	}
+
+	if form.Milestone != nil && *form.Milestone > 0 {
+		if _, err := models.GetRepoMilestoneByID(repo.ID, *form.Milestone); err != nil {
+			if models.IsErrMilestoneNotExist(err) {
+				ctx.APIError(422, "", err)
+			} else {
+				ctx.APIError(500, "GetRepoMilestoneByID", err)
+			}
+			return
+		}
+	}
+
+	var assigneeID int64
+	if form.Assignee != nil && len(*form.Assignee) > 0 {
+		assignee := getAssigneeByName(ctx, *form.Assignee)
+		if ctx.Written() {
+			return
+		}
+		assigneeID = assignee.Id
+	}
+
+	if form.State != nil && *form.State != "open" && *form.State != "closed" {
+		ctx.APIError(422, "", "State must be either 'open' or 'closed'.")
+		return
+	}
+
+	results := make([]*BatchIssueResult, len(form.Issues))
+	for i, index := range form.Issues {
+		results[i] = &BatchIssueResult{Index: index}
+
+		issue, err := models.GetIssueByIndex(repo.ID, index)
+		if err != nil {
+			if !models.IsErrIssueNotExist(err) {
+				log.Error(4, "GetIssueByIndex[%d]: %v", index, err)
+			}
+			results[i].Error = "Issue does not exist."
+			continue
+		} else if !repo.IsUnitEnabled(issue.Unit()) {
+			results[i].Error = "Issue does not exist."
+			continue
+		}
+		issue.Repo = repo
+
+		msg, err := batchUpdateIssue(ctx.User, issue, form, addLabels, removeLabels, assigneeID)
+		if err != nil {
+			log.Error(4, "batchUpdateIssue[%d]: %v", issue.ID, err)
+			results[i].Error = "Internal server error."
+			continue
+		} else if len(msg) > 0 {
+			results[i].Error = msg
+			continue
+		}
+		results[i].Success = true
+	}
+	log.Trace("Issues batch updated in repository[%d] by user[%d]: %v", repo.ID, ctx.User.Id, form.Issues)
+
+	ctx.JSON(200, &results)
+}
@@ -0,0 +1,206 @@
+// Copyright 2015 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package v1
+
+import (
+	"time"
+
+	api "github.com/gogits/go-gogs-client"
+
+	"github.com/gogits/gogs/models"
+	"github.com/gogits/gogs/modules/base"
+	"github.com/gogits/gogs/modules/log"
+	"github.com/gogits/gogs/modules/middleware"
+	"github.com/gogits/gogs/modules/setting"
+)
+
+// Comment represents a comment of issue or pull request in API format.
+type Comment struct {
+	ID       int64     `json:"id"`
+	Poster   *api.User `json:"user"`
+	Body     string    `json:"body"`
+	BodyHTML string    `json:"body_html,omitempty"`
+	Path     string    `json:"path,omitempty"`
+	Line     int64     `json:"line,omitempty"`
+	Edited   bool      `json:"edited"`
+	Created  time.Time `json:"created_at"`
+	Updated  time.Time `json:"updated_at"`
+}
+
+// ToApiComment converts comment to API format,
+// body is rendered as HTML in context of given link if it is not empty.
+func ToApiComment(c *models.Comment, renderLink string) *Comment {
+	apiComment := &Comment{
+		ID:      c.ID,
+		Body:    c.Content,
+		Path:    c.TreePath,
+		Line:    c.Line,
+		Edited:  c.IsEdited,
+		Created: c.Created,
+		Updated: c.Updated,
+	}
+	// Poster may have been deleted.
+	if c.Poster != nil {
+		apiComment.Poster = ToApiUser(c.Poster)
+	}
+	if len(renderLink) > 0 {
+		apiComment.BodyHTML = string(base.RenderMarkdown([]byte(c.Content), renderLink))
+	}
+	return apiComment
+}
+
+// isUserComment returns true if comment is written by user rather than an event of issue.
+func isUserComment(c *models.Comment) bool {
+	return c.Type == models.COMMENT_TYPE_COMMENT || c.Type == models.COMMENT_TYPE_CODE
+}
+
+// getIssueToComment returns issue given by URL of repository.
+func getIssueToComment(ctx *middleware.Context) *models.Issue {
+	issue, err := models.GetIssueByIndex(ctx.Repo.Repository.ID, ctx.ParamsInt64(":index"))
+	if err != nil {
+		if models.IsErrIssueNotExist(err) {
+			ctx.Error(404)
+		} else {
+			ctx.APIError(500, "GetIssueByIndex", err)
+		}
+		return nil
+	} else if !ctx.Repo.Repository.IsUnitEnabled(issue.Unit()) {
+		ctx.Error(404)
+		return nil
+	}
+	issue.Repo = ctx.Repo.Repository
+	return issue
+}
+
+// getCommentToManage returns comment given by URL which current user is allowed to change,
+// that is the poster or who has admin access to repository.
+func getCommentToManage(ctx *middleware.Context) *models.Comment {
+	issue := getIssueToComment(ctx)
+	if ctx.Written() {
+		return nil
+	}
+
+	c, err := models.GetCommentByID(ctx.ParamsInt64(":id"))
+	if err != nil {
+		if models.IsErrCommentNotExist(err) {
+			ctx.Error(404)
+		} else {
+			ctx.APIError(500, "GetCommentByID", err)
+		}
+		return nil
+	} else if c.IssueID != issue.ID || !isUserComment(c) {
+		ctx.Error(404)
+		return nil
+	}
+
+	if c.PosterID != ctx.User.Id && !ctx.Repo.IsAdmin() {
+		ctx.APIError(403, "", "Given user is neither poster of comment nor admin of repository.")
+		return nil
+	}
+	return c
+}
+
+// commentRenderLink returns link for rendering comments when requested by "render=true".
+func commentRenderLink(ctx *middleware.Context) string {
+	if ctx.Query("render") != "true" {
+		return ""
+	}
+	return setting.AppSubUrl + "/" + ctx.Repo.Owner.Name + "/" + ctx.Repo.Repository.Name
+}
+
+// GET /repos/:username/:reponame/issues/:index/comments
+func ListIssueComments(ctx *middleware.Context) {
+	issue := getIssueToComment(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	comments, err := models.GetCommentsByIssueID(issue.ID)
+	if err != nil {
+		ctx.APIError(500, "GetCommentsByIssueID", err)
+		return
+	}
+
+	renderLink := commentRenderLink(ctx)
+	apiComments := make([]*Comment, 0, len(comments))
+	for i := range comments {
+		if isUserComment(comments[i]) {
+			apiComments = append(apiComments, ToApiComment(comments[i], renderLink))
+		}
+	}
+	ctx.JSON(200, &apiComments)
+}
+
+// CreateIssueCommentOption represents options for creating a comment.
+type CreateIssueCommentOption struct {
+	Body string `json:"body" binding:"Required"`
+}
+
+// POST /repos/:username/:reponame/issues/:index/comments
+func CreateIssueComment(ctx *middleware.Context, form CreateIssueCommentOption) {
+	issue := getIssueToComment(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	if !issue.CanComment(ctx.User, ctx.Repo.IsPusher()) {
+		ctx.APIError(403, "", "Conversation of issue is locked.")
+		return
+	}
+
+	c, err := models.CreateIssueComment(ctx.User, ctx.Repo.Repository, issue, form.Body, nil)
+	if err != nil {
+		ctx.APIError(500, "CreateIssueComment", err)
+		return
+	}
+	c.Poster = ctx.User
+	log.Trace("Comment created: %d/%d/%d", ctx.Repo.Repository.ID, issue.ID, c.ID)
+
+	ctx.JSON(201, ToApiComment(c, commentRenderLink(ctx)))
+}
+
+// EditIssueCommentOption represents options for editing a comment.
+type EditIssueCommentOption struct {
+	Body string `json:"body" binding:"Required"`
+}
+
+// PATCH /repos/:username/:reponame/issues/:index/comments/:id
+func EditIssueComment(ctx *middleware.Context, form EditIssueCommentOption) {
+	c := getCommentToManage(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	c.Content = form.Body
+	c.IsEdited = true
+	if err := models.UpdateComment(c); err != nil {
+		ctx.APIError(500, "UpdateComment", err)
+		return
+	}
+
+	// Reload to get time of update.
+	c, err := models.GetCommentByID(c.ID)
+	if err != nil {
+		ctx.APIError(500, "GetCommentByID", err)
+		return
+	}
+	ctx.JSON(200, ToApiComment(c, commentRenderLink(ctx)))
+}
+
+// DELETE /repos/:username/:reponame/issues/:index/comments/:id
+func DeleteIssueComment(ctx *middleware.Context) {
+	c := getCommentToManage(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	if err := models.DeleteComment(c); err != nil {
+		ctx.APIError(500, "DeleteComment", err)
+		return
+	}
+	log.Trace("Comment deleted: %d/%d", c.IssueID, c.ID)
+
+	ctx.Status(204)
+}
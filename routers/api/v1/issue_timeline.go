@@ -0,0 +1,171 @@
+// Copyright 2015 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package v1
+
+import (
+	"time"
+
+	api "github.com/gogits/go-gogs-client"
+
+	"github.com/gogits/gogs/models"
+	"github.com/gogits/gogs/modules/middleware"
+)
+
+const (
+	// TIMELINE_PAGING_NUM is the default number of timeline events returned per page.
+	TIMELINE_PAGING_NUM = 30
+	// TIMELINE_MAX_PAGING_NUM is the maximum number of timeline events can be requested per page.
+	TIMELINE_MAX_PAGING_NUM = 100
+)
+
+// TimelineEvent represents an event in timeline of issue or pull request in API format,
+// fields other than the common ones are only present for related types of event.
+type TimelineEvent struct {
+	ID        int64      `json:"id"`
+	Event     string     `json:"event"`
+	Actor     *api.User  `json:"actor"`
+	Created   time.Time  `json:"created_at"`
+	Body      string     `json:"body,omitempty"`
+	CommitID  string     `json:"commit_id,omitempty"`
+	Path      string     `json:"path,omitempty"`
+	Line      int64      `json:"line,omitempty"`
+	Label     *Label     `json:"label,omitempty"`
+	Milestone *Milestone `json:"milestone,omitempty"`
+	Assignee  *api.User  `json:"assignee,omitempty"`
+}
+
+// timelineEventName returns name of event that comment records.
+func timelineEventName(c *models.Comment) string {
+	switch c.Type {
+	case models.COMMENT_TYPE_COMMENT:
+		return "commented"
+	case models.COMMENT_TYPE_CODE:
+		return "line_commented"
+	case models.COMMENT_TYPE_REOPEN:
+		return "reopened"
+	case models.COMMENT_TYPE_CLOSE:
+		return "closed"
+	case models.COMMENT_TYPE_ISSUE_REF, models.COMMENT_TYPE_COMMIT_REF,
+		models.COMMENT_TYPE_COMMENT_REF, models.COMMENT_TYPE_PULL_REF:
+		return "referenced"
+	case models.COMMENT_TYPE_LOCK:
+		return "locked"
+	case models.COMMENT_TYPE_UNLOCK:
+		return "unlocked"
+	case models.COMMENT_TYPE_LABEL:
+		return "labeled"
+	case models.COMMENT_TYPE_UNLABEL:
+		return "unlabeled"
+	case models.COMMENT_TYPE_MILESTONE:
+		if c.MilestoneID == 0 {
+			return "demilestoned"
+		}
+		return "milestoned"
+	case models.COMMENT_TYPE_ASSIGNEE:
+		if c.AssigneeID == 0 {
+			return "unassigned"
+		}
+		return "assigned"
+	}
+	return "unknown"
+}
+
+// ToApiTimelineEvent converts comment of any type to an event in API format.
+// Labels, milestones and users that no longer exist are omitted.
+func ToApiTimelineEvent(c *models.Comment) (*TimelineEvent, error) {
+	event := &TimelineEvent{
+		ID:       c.ID,
+		Event:    timelineEventName(c),
+		Created:  c.Created,
+		Body:     c.Content,
+		CommitID: c.CommitSHA,
+		Path:     c.TreePath,
+		Line:     c.Line,
+	}
+	if c.Poster != nil {
+		event.Actor = ToApiUser(c.Poster)
+	}
+
+	switch c.Type {
+	case models.COMMENT_TYPE_LABEL, models.COMMENT_TYPE_UNLABEL:
+		label, err := models.GetLabelByID(c.LabelID)
+		if err == nil {
+			event.Label = ToApiLabel(label)
+		} else if !models.IsErrLabelNotExist(err) {
+			return nil, err
+		}
+
+	case models.COMMENT_TYPE_MILESTONE:
+		// Removed milestone is reported when milestone is cleared.
+		mid := c.MilestoneID
+		if mid == 0 {
+			mid = c.OldMilestoneID
+		}
+		if mid > 0 {
+			m, err := models.GetMilestoneByID(mid)
+			if err == nil {
+				event.Milestone = ToApiMilestone(m)
+			} else if !models.IsErrMilestoneNotExist(err) {
+				return nil, err
+			}
+		}
+
+	case models.COMMENT_TYPE_ASSIGNEE:
+		// Removed assignee is reported when assignee is cleared.
+		uid := c.AssigneeID
+		if uid == 0 {
+			uid = c.OldAssigneeID
+		}
+		if uid > 0 {
+			u, err := models.GetUserByID(uid)
+			if err == nil {
+				event.Assignee = ToApiUser(u)
+			} else if !models.IsErrUserNotExist(err) {
+				return nil, err
+			}
+		}
+	}
+	return event, nil
+}
+
+// GET /repos/:username/:reponame/issues/:index/timeline
+func ListIssueTimeline(ctx *middleware.Context) {
+	issue := getIssueToComment(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	page := ctx.QueryInt("page")
+	if page <= 0 {
+		page = 1
+	}
+	limit := ctx.QueryInt("limit")
+	if limit <= 0 {
+		limit = TIMELINE_PAGING_NUM
+	} else if limit > TIMELINE_MAX_PAGING_NUM {
+		limit = TIMELINE_MAX_PAGING_NUM
+	}
+
+	comments, err := models.GetTimelineComments(issue.ID, page, limit)
+	if err != nil {
+		ctx.APIError(500, "GetTimelineComments", err)
+		return
+	}
+	total, err := models.CountTimelineComments(issue.ID)
+	if err != nil {
+		ctx.APIError(500, "CountTimelineComments", err)
+		return
+	}
+
+	events := make([]*TimelineEvent, len(comments))
+	for i := range comments {
+		if events[i], err = ToApiTimelineEvent(comments[i]); err != nil {
+			ctx.APIError(500, "ToApiTimelineEvent", err)
+			return
+		}
+	}
+	setPaginationHeaders(ctx, page, limit, int(total))
+	ctx.JSON(200, &events)
+}
@@ -5,9 +5,13 @@
 package v1
 
 import (
+	"strings"
+
 	"github.com/gogits/gogs/modules/auth/apiv1"
 	"github.com/gogits/gogs/modules/base"
 	"github.com/gogits/gogs/modules/middleware"
+	"github.com/gogits/gogs/modules/setting"
+	"github.com/gogits/gogs/modules/webauthn"
 )
 
 // Render an arbitrary Markdown document.
@@ -39,3 +43,120 @@ func MarkdownRaw(ctx *middleware.Context) {
 	}
 	ctx.Write(base.RenderRawMarkdown(body, ""))
 }
+
+// ServerCapabilities represents features supported by this instance.
+type ServerCapabilities struct {
+	// LFS is always false because Git LFS server is not implemented yet.
+	LFS            bool `json:"lfs"`
+	TwoFactor      bool `json:"two_factor"`
+	OAuth          bool `json:"oauth"`
+	PullRequestAPI bool `json:"pull_request_api"`
+	ForkAPI        bool `json:"fork_api"`
+	IssueSearchAPI bool `json:"issue_search_api"`
+	Registration   bool `json:"registration"`
+	EmailConfirm   bool `json:"email_confirm"`
+	Mailer         bool `json:"mailer"`
+	Webhooks       bool `json:"webhooks"`
+}
+
+// ServerVersion represents version and capabilities of this instance.
+type ServerVersion struct {
+	Version      string              `json:"version"`
+	Capabilities *ServerCapabilities `json:"capabilities"`
+}
+
+// isSecurityKeyAvailable returns true if browsers are able to use security keys
+// as second factor, which requires a secure origin derived from application URL.
+func isSecurityKeyAvailable() bool {
+	rp, err := webauthn.NewRelyingParty(setting.AppUrl)
+	if err != nil {
+		return false
+	}
+	return strings.HasPrefix(rp.Origin, "https://") || rp.ID == "localhost"
+}
+
+// GET /version
+func Version(ctx *middleware.Context) {
+	ctx.JSON(200, &ServerVersion{
+		Version: setting.AppVer,
+		Capabilities: &ServerCapabilities{
+			TwoFactor:      isSecurityKeyAvailable(),
+			OAuth:          true,
+			PullRequestAPI: true,
+			ForkAPI:        true,
+			IssueSearchAPI: true,
+			Registration:   !setting.Service.DisableRegistration,
+			EmailConfirm:   setting.Service.RegisterEmailConfirm && setting.MailService != nil,
+			Mailer:         setting.MailService != nil,
+			Webhooks:       len(setting.Webhook.Types) > 0,
+		},
+	})
+}
+
+// RateLimitStatus represents API rate limit status of current client.
+type RateLimitStatus struct {
+	Enabled bool `json:"enabled"`
+	*middleware.RateLimit
+}
+
+// GET /rate_limit
+func GetRateLimit(ctx *middleware.Context) {
+	if !setting.API.RateLimitEnabled {
+		ctx.JSON(200, &RateLimitStatus{})
+		return
+	}
+	ctx.JSON(200, &RateLimitStatus{true, middleware.GetRateLimit(ctx)})
+}
+
+// CsrfToken represents CSRF token of current session and ways to send it.
+//
+// Web (non-API) endpoints require the token for POST, PUT, PATCH and DELETE requests.
+// The token is also set in cookie for pages rendered by server, but the cookie itself
+// is not accepted as proof: clients must echo the token back either in the header
+// (preferred for XHR) or in the form field.
+type CsrfToken struct {
+	Token     string `json:"token"`
+	Header    string `json:"header"`
+	FormField string `json:"form_field"`
+	Cookie    string `json:"cookie"`
+}
+
+// checkCsrfClient responds with error if client is not authenticated by session,
+// tokens and basic authentication are not subject to CSRF.
+func checkCsrfClient(ctx *middleware.Context) bool {
+	if ctx.Data["AccessToken"] != nil || ctx.IsBasicAuth {
+		ctx.APIError(403, "", "CSRF token is only used by clients authenticated by session.")
+		return false
+	}
+	return true
+}
+
+func toApiCsrfToken(ctx *middleware.Context, token string) *CsrfToken {
+	x := ctx.CSRF()
+	return &CsrfToken{
+		Token:     token,
+		Header:    x.GetHeaderName(),
+		FormField: x.GetFormName(),
+		Cookie:    x.GetCookieName(),
+	}
+}
+
+// GET /csrf
+func GetCsrfToken(ctx *middleware.Context) {
+	if !checkCsrfClient(ctx) {
+		return
+	}
+	ctx.Resp.Header().Set("Cache-Control", "no-store")
+	ctx.JSON(200, toApiCsrfToken(ctx, ctx.CSRF().GetToken()))
+}
+
+// POST /csrf
+//
+// A new token is issued and set in cookie, previous tokens still work until they expire.
+func RenewCsrfToken(ctx *middleware.Context) {
+	if !checkCsrfClient(ctx) {
+		return
+	}
+	ctx.Resp.Header().Set("Cache-Control", "no-store")
+	ctx.JSON(200, toApiCsrfToken(ctx, ctx.RenewCsrfToken()))
+}
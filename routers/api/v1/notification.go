@@ -0,0 +1,124 @@
+// Copyright 2015 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package v1
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gogits/gogs/models"
+	"github.com/gogits/gogs/modules/middleware"
+	"github.com/gogits/gogs/modules/setting"
+)
+
+const (
+	// NOTIFICATION_PAGING_NUM is the default number of notifications returned per page.
+	NOTIFICATION_PAGING_NUM = 30
+	// NOTIFICATION_MAX_PAGING_NUM is the maximum number of notifications can be requested per page.
+	NOTIFICATION_MAX_PAGING_NUM = 100
+)
+
+// NotificationSubject represents the issue or pull request that a notification is about.
+type NotificationSubject struct {
+	Type  string `json:"type"`
+	Index int64  `json:"number"`
+	Title string `json:"title"`
+	State string `json:"state"`
+	URL   string `json:"html_url"`
+}
+
+// Notification represents a notification of user in API format.
+type Notification struct {
+	ID         int64                `json:"id"`
+	Reason     string               `json:"reason"`
+	Unread     bool                 `json:"unread"`
+	Repository *RepositoryMeta      `json:"repository"`
+	Subject    *NotificationSubject `json:"subject"`
+	Updated    time.Time            `json:"updated_at"`
+}
+
+// ToApiNotification converts notification to API format,
+// repository and issue of notification must be loaded.
+func ToApiNotification(n *models.Notification) *Notification {
+	subjectType := "issue"
+	if n.Issue.IsPull {
+		subjectType = "pull"
+	}
+	return &Notification{
+		ID:         n.ID,
+		Reason:     n.Reason.String(),
+		Unread:     !n.IsRead,
+		Repository: ToApiRepositoryMeta(n.Repo),
+		Subject: &NotificationSubject{
+			Type:  subjectType,
+			Index: n.Issue.Index,
+			Title: n.Issue.Name,
+			State: stateName(n.Issue.IsClosed),
+			URL:   setting.AppUrl + strings.TrimPrefix(n.Link(), setting.AppSubUrl+"/"),
+		},
+		Updated: n.Updated,
+	}
+}
+
+// GET /notifications?unread=true&repo_id=...
+func ListNotifications(ctx *middleware.Context) {
+	page := ctx.QueryInt("page")
+	if page <= 0 {
+		page = 1
+	}
+	limit := ctx.QueryInt("limit")
+	if limit <= 0 {
+		limit = NOTIFICATION_PAGING_NUM
+	} else if limit > NOTIFICATION_MAX_PAGING_NUM {
+		limit = NOTIFICATION_MAX_PAGING_NUM
+	}
+
+	opts := &models.NotificationsOptions{
+		UID:        ctx.User.Id,
+		RepoID:     ctx.QueryInt64("repo_id"),
+		OnlyUnread: ctx.Query("unread") == "true",
+		Page:       page,
+		PageSize:   limit,
+	}
+	notifications, err := models.GetNotifications(opts)
+	if err != nil {
+		ctx.APIError(500, "GetNotifications", err)
+		return
+	}
+	total, err := models.CountNotifications(opts)
+	if err != nil {
+		ctx.APIError(500, "CountNotifications", err)
+		return
+	}
+
+	apiNotifications := make([]*Notification, len(notifications))
+	for i := range notifications {
+		apiNotifications[i] = ToApiNotification(notifications[i])
+	}
+	setPaginationHeaders(ctx, page, limit, int(total))
+	ctx.JSON(200, &apiNotifications)
+}
+
+// PATCH /notifications?repo_id=...
+func MarkNotificationsRead(ctx *middleware.Context) {
+	if err := models.MarkNotificationsRead(ctx.User.Id, ctx.QueryInt64("repo_id")); err != nil {
+		ctx.APIError(500, "MarkNotificationsRead", err)
+		return
+	}
+	ctx.Status(204)
+}
+
+// PATCH /notifications/:id
+func MarkNotificationRead(ctx *middleware.Context) {
+	if err := models.MarkNotificationRead(ctx.User.Id, ctx.ParamsInt64(":id")); err != nil {
+		if models.IsErrNotificationNotExist(err) {
+			ctx.Error(404)
+		} else {
+			ctx.APIError(500, "MarkNotificationRead", err)
+		}
+		return
+	}
+	ctx.Status(204)
+}
@@ -0,0 +1,175 @@
+// Copyright 2015 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package v1
+
+import (
+	"io/ioutil"
+
+	"github.com/gogits/gogs/models"
+	"github.com/gogits/gogs/modules/base"
+	"github.com/gogits/gogs/modules/log"
+	"github.com/gogits/gogs/modules/middleware"
+)
+
+// Organization represents an organization in API format.
+type Organization struct {
+	ID          int64  `json:"id"`
+	UserName    string `json:"username"`
+	FullName    string `json:"full_name"`
+	AvatarUrl   string `json:"avatar_url"`
+	Description string `json:"description"`
+	Website     string `json:"website"`
+	Location    string `json:"location"`
+}
+
+// UserOrganization represents an organization that a user is member of,
+// role is only visible to the user, site admins and members of the organization.
+type UserOrganization struct {
+	*Organization
+	Visibility string `json:"visibility"` // Visibility of membership, "public" or "private".
+	Role       string `json:"role,omitempty"`
+}
+
+// ToApiOrganization converts organization to API format.
+func ToApiOrganization(org *models.User) *Organization {
+	return &Organization{
+		ID:          org.Id,
+		UserName:    org.Name,
+		FullName:    org.FullName,
+		AvatarUrl:   org.AvatarLink(),
+		Description: org.Description,
+		Website:     org.Website,
+		Location:    org.Location,
+	}
+}
+
+// listUserOrgs responses organizations that given user is member of and visible to current user.
+func listUserOrgs(ctx *middleware.Context, u *models.User) {
+	ous, err := models.GetOrgUsersByUserId(u.Id)
+	if err != nil {
+		ctx.APIError(500, "GetOrgUsersByUserId", err)
+		return
+	}
+
+	isSelfOrAdmin := ctx.IsSigned && (ctx.User.Id == u.Id || ctx.User.IsAdmin)
+	apiOrgs := make([]*UserOrganization, 0, len(ous))
+	for _, ou := range ous {
+		isOrgMember := ctx.IsSigned && models.IsOrganizationMember(ou.OrgID, ctx.User.Id)
+		if !ou.IsPublic && !isSelfOrAdmin && !isOrgMember {
+			continue
+		}
+
+		org, err := models.GetUserByID(ou.OrgID)
+		if err != nil {
+			ctx.APIError(500, "GetUserByID", err)
+			return
+		}
+		apiOrg := &UserOrganization{
+			Organization: ToApiOrganization(org),
+			Visibility:   "private",
+		}
+		if ou.IsPublic {
+			apiOrg.Visibility = "public"
+		}
+		if isSelfOrAdmin || isOrgMember {
+			apiOrg.Role = "member"
+			if ou.IsOwner {
+				apiOrg.Role = "owner"
+			}
+		}
+		apiOrgs = append(apiOrgs, apiOrg)
+	}
+	ctx.JSON(200, &apiOrgs)
+}
+
+// GET /user/orgs
+func ListMyOrgs(ctx *middleware.Context) {
+	listUserOrgs(ctx, ctx.User)
+}
+
+// GET /users/:username/orgs
+func ListUserOrgs(ctx *middleware.Context) {
+	u, err := models.GetUserByName(ctx.Params(":username"))
+	if err != nil {
+		if models.IsErrUserNotExist(err) {
+			ctx.Error(404)
+		} else {
+			ctx.APIError(500, "GetUserByName", err)
+		}
+		return
+	}
+	listUserOrgs(ctx, u)
+}
+
+// getOrgToManage returns organization given by URL which current user is allowed to manage.
+func getOrgToManage(ctx *middleware.Context) *models.User {
+	org, err := models.GetOrgByName(ctx.Params(":org"))
+	if err != nil {
+		if err == models.ErrOrgNotExist {
+			ctx.Error(404)
+		} else {
+			ctx.APIError(500, "GetOrgByName", err)
+		}
+		return nil
+	}
+
+	if !org.IsOwnedBy(ctx.User.Id) && !ctx.User.IsAdmin {
+		ctx.APIError(403, "", "Given user is not owner of organization.")
+		return nil
+	}
+	return org
+}
+
+// POST /orgs/:org/avatar
+func UpdateOrgAvatar(ctx *middleware.Context) {
+	org := getOrgToManage(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	fr, _, err := ctx.Req.FormFile("avatar")
+	if err != nil {
+		ctx.APIError(422, "", "Field 'avatar' must be an uploaded image file.")
+		return
+	}
+	defer fr.Close()
+
+	data, err := ioutil.ReadAll(fr)
+	if err != nil {
+		ctx.APIError(500, "ReadAll", err)
+		return
+	}
+	if _, ok := base.IsImageFile(data); !ok {
+		ctx.APIError(422, "", "Uploaded file is not an image.")
+		return
+	} else if err = models.CheckAvatarImage(data); err != nil {
+		ctx.APIError(422, "", err)
+		return
+	}
+
+	if err = org.UploadAvatar(data); err != nil {
+		ctx.APIError(500, "UploadAvatar", err)
+		return
+	}
+	log.Trace("Organization avatar updated: %s", org.Name)
+
+	ctx.JSON(200, ToApiUser(org))
+}
+
+// DELETE /orgs/:org/avatar
+func DeleteOrgAvatar(ctx *middleware.Context) {
+	org := getOrgToManage(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	if err := org.DeleteAvatar(); err != nil {
+		ctx.APIError(500, "DeleteAvatar", err)
+		return
+	}
+	log.Trace("Organization avatar deleted: %s", org.Name)
+
+	ctx.Status(204)
+}
@@ -0,0 +1,177 @@
+// Copyright 2015 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package v1
+
+import (
+	api "github.com/gogits/go-gogs-client"
+
+	"github.com/gogits/gogs/models"
+	"github.com/gogits/gogs/modules/log"
+	"github.com/gogits/gogs/modules/middleware"
+)
+
+// OrgMember represents a member of organization in API format,
+// role is only visible to site admins and members of the organization.
+type OrgMember struct {
+	*api.User
+	Visibility string `json:"visibility"` // Visibility of membership, "public" or "private".
+	Role       string `json:"role,omitempty"`
+}
+
+// ToApiOrgMember converts member of organization to API format, with role if it is visible.
+func ToApiOrgMember(u *models.User, ou *models.OrgUser, canSeeRole bool) *OrgMember {
+	member := &OrgMember{
+		User:       ToApiUser(u),
+		Visibility: "private",
+	}
+	if ou.IsPublic {
+		member.Visibility = "public"
+	}
+	if canSeeRole {
+		member.Role = "member"
+		if ou.IsOwner {
+			member.Role = "owner"
+		}
+	}
+	return member
+}
+
+// getOrgToView returns organization given by URL, and whether current user
+// is allowed to see private members and their roles.
+func getOrgToView(ctx *middleware.Context) (*models.User, bool) {
+	org, err := models.GetOrgByName(ctx.Params(":org"))
+	if err != nil {
+		if err == models.ErrOrgNotExist {
+			ctx.Error(404)
+		} else {
+			ctx.APIError(500, "GetOrgByName", err)
+		}
+		return nil, false
+	}
+	return org, ctx.IsSigned && (ctx.User.IsAdmin || org.IsOrgMember(ctx.User.Id))
+}
+
+// GET /orgs/:org/members
+func ListOrgMembers(ctx *middleware.Context) {
+	org, canSeeAll := getOrgToView(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	ous, err := models.GetOrgUsersByOrgId(org.Id)
+	if err != nil {
+		ctx.APIError(500, "GetOrgUsersByOrgId", err)
+		return
+	}
+
+	members := make([]*OrgMember, 0, len(ous))
+	for _, ou := range ous {
+		if !ou.IsPublic && !canSeeAll {
+			continue
+		}
+
+		u, err := models.GetUserByID(ou.Uid)
+		if err != nil {
+			ctx.APIError(500, "GetUserByID", err)
+			return
+		}
+		members = append(members, ToApiOrgMember(u, ou, canSeeAll))
+	}
+	ctx.JSON(200, &members)
+}
+
+// GET /orgs/:org/members/:username
+func GetOrgMember(ctx *middleware.Context) {
+	org, canSeeAll := getOrgToView(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	u, err := models.GetUserByName(ctx.Params(":username"))
+	if err != nil {
+		if models.IsErrUserNotExist(err) {
+			ctx.Error(404)
+		} else {
+			ctx.APIError(500, "GetUserByName", err)
+		}
+		return
+	}
+
+	// Private membership is only visible to the member oneself besides members of organization.
+	ou, err := models.GetOrgUser(org.Id, u.Id)
+	if err != nil {
+		ctx.APIError(500, "GetOrgUser", err)
+		return
+	} else if ou == nil || (!ou.IsPublic && !canSeeAll && !(ctx.IsSigned && ctx.User.Id == u.Id)) {
+		ctx.Error(404)
+		return
+	}
+	ctx.JSON(200, ToApiOrgMember(u, ou, canSeeAll))
+}
+
+// getOrgMemberToManage returns organization and user given by URL,
+// and current user must be allowed to manage the organization.
+func getOrgMemberToManage(ctx *middleware.Context) (*models.User, *models.User) {
+	org := getOrgToManage(ctx)
+	if ctx.Written() {
+		return nil, nil
+	}
+
+	u, err := models.GetUserByName(ctx.Params(":username"))
+	if err != nil {
+		if models.IsErrUserNotExist(err) {
+			ctx.Error(404)
+		} else {
+			ctx.APIError(500, "GetUserByName", err)
+		}
+		return nil, nil
+	}
+	if u.IsOrganization() {
+		ctx.APIError(422, "", "Organization cannot be a member of organization.")
+		return nil, nil
+	}
+	return org, u
+}
+
+// PUT /orgs/:org/members/:username
+func AddOrgMember(ctx *middleware.Context) {
+	org, u := getOrgMemberToManage(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	if err := org.AddMember(u.Id); err != nil {
+		ctx.APIError(500, "AddMember", err)
+		return
+	}
+	log.Trace("Member added to organization(%s): %s", org.Name, u.Name)
+
+	ctx.Status(204)
+}
+
+// DELETE /orgs/:org/members/:username
+func RemoveOrgMember(ctx *middleware.Context) {
+	org, u := getOrgMemberToManage(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	if !org.IsOrgMember(u.Id) {
+		ctx.Error(404)
+		return
+	}
+
+	if err := org.RemoveMember(u.Id); err != nil {
+		if models.IsErrLastOrgOwner(err) {
+			ctx.APIError(422, "", err)
+		} else {
+			ctx.APIError(500, "RemoveMember", err)
+		}
+		return
+	}
+	log.Trace("Member removed from organization(%s): %s", org.Name, u.Name)
+
+	ctx.Status(204)
+}
@@ -0,0 +1,416 @@
+// Copyright 2015 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package v1
+
+import (
+	"strings"
+
+	api "github.com/gogits/go-gogs-client"
+
+	"github.com/gogits/gogs/models"
+	"github.com/gogits/gogs/modules/log"
+	"github.com/gogits/gogs/modules/middleware"
+)
+
+// Team represents a team of organization in API format.
+// Effective access of a user to a repository is the highest permission
+// among all teams the user belongs to and collaboration of repository.
+type Team struct {
+	ID          int64  `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Permission  string `json:"permission"`
+	NumRepos    int    `json:"repos_count"`
+	NumMembers  int    `json:"members_count"`
+}
+
+// teamPermissionName returns name of access mode used by teams.
+func teamPermissionName(mode models.AccessMode) string {
+	switch mode {
+	case models.ACCESS_MODE_READ:
+		return "read"
+	case models.ACCESS_MODE_WRITE:
+		return "write"
+	case models.ACCESS_MODE_ADMIN:
+		return "admin"
+	case models.ACCESS_MODE_OWNER:
+		return "owner"
+	}
+	return "none"
+}
+
+// parseTeamPermission returns access mode of given permission name,
+// owner permission is reserved for owner team thus not assignable.
+func parseTeamPermission(name string) (models.AccessMode, bool) {
+	switch strings.ToLower(name) {
+	case "read":
+		return models.ACCESS_MODE_READ, true
+	case "write":
+		return models.ACCESS_MODE_WRITE, true
+	case "admin":
+		return models.ACCESS_MODE_ADMIN, true
+	}
+	return models.ACCESS_MODE_NONE, false
+}
+
+// ToApiTeam converts team to API format.
+func ToApiTeam(t *models.Team) *Team {
+	return &Team{
+		ID:          t.ID,
+		Name:        t.Name,
+		Description: t.Description,
+		Permission:  teamPermissionName(t.Authorize),
+		NumRepos:    t.NumRepos,
+		NumMembers:  t.NumMembers,
+	}
+}
+
+// getTeamToManage returns team given by URL and its organization,
+// which current user is allowed to manage.
+func getTeamToManage(ctx *middleware.Context) (*models.User, *models.Team) {
+	t, err := models.GetTeamById(ctx.ParamsInt64(":teamid"))
+	if err != nil {
+		if err == models.ErrTeamNotExist {
+			ctx.Error(404)
+		} else {
+			ctx.APIError(500, "GetTeamById", err)
+		}
+		return nil, nil
+	}
+
+	org, err := models.GetUserByID(t.OrgID)
+	if err != nil {
+		ctx.APIError(500, "GetUserByID", err)
+		return nil, nil
+	}
+
+	if !org.IsOwnedBy(ctx.User.Id) && !ctx.User.IsAdmin {
+		// Do not reveal existence of team to non-members.
+		if !t.IsMember(ctx.User.Id) {
+			ctx.Error(404)
+		} else {
+			ctx.APIError(403, "", "Given user is not owner of organization.")
+		}
+		return nil, nil
+	}
+	return org, t
+}
+
+// GET /orgs/:org/teams
+func ListOrgTeams(ctx *middleware.Context) {
+	org := getOrgToManage(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	if err := org.GetTeams(); err != nil {
+		ctx.APIError(500, "GetTeams", err)
+		return
+	}
+
+	teams := make([]*Team, len(org.Teams))
+	for i := range org.Teams {
+		teams[i] = ToApiTeam(org.Teams[i])
+	}
+	ctx.JSON(200, &teams)
+}
+
+// CreateTeamOption represents options for creating a team,
+// permission is 'read' when not given.
+type CreateTeamOption struct {
+	Name        string `json:"name" binding:"Required;AlphaDashDot;MaxSize(30)"`
+	Description string `json:"description" binding:"MaxSize(255)"`
+	Permission  string `json:"permission"`
+}
+
+// POST /orgs/:org/teams
+func CreateTeam(ctx *middleware.Context, form CreateTeamOption) {
+	org := getOrgToManage(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	auth := models.ACCESS_MODE_READ
+	if len(form.Permission) > 0 {
+		var ok bool
+		if auth, ok = parseTeamPermission(form.Permission); !ok {
+			ctx.APIError(422, "", "Permission must be one of 'read', 'write' or 'admin'.")
+			return
+		}
+	}
+
+	t := &models.Team{
+		OrgID:       org.Id,
+		Name:        form.Name,
+		Description: form.Description,
+		Authorize:   auth,
+	}
+	if err := models.NewTeam(t); err != nil {
+		if err == models.ErrTeamAlreadyExist ||
+			models.IsErrNameReserved(err) ||
+			models.IsErrNamePatternNotAllowed(err) {
+			ctx.APIError(422, "", err)
+		} else {
+			ctx.APIError(500, "NewTeam", err)
+		}
+		return
+	}
+	log.Trace("Team created: %s/%s", org.Name, t.Name)
+
+	ctx.JSON(201, ToApiTeam(t))
+}
+
+// GET /teams/:teamid
+func GetTeam(ctx *middleware.Context) {
+	_, t := getTeamToManage(ctx)
+	if ctx.Written() {
+		return
+	}
+	ctx.JSON(200, ToApiTeam(t))
+}
+
+// EditTeamOption represents options for editing a team,
+// fields left empty are not changed.
+type EditTeamOption struct {
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+	Permission  *string `json:"permission"`
+}
+
+// PATCH /teams/:teamid
+func EditTeam(ctx *middleware.Context, form EditTeamOption) {
+	_, t := getTeamToManage(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	if t.IsOwnerTeam() && (form.Name != nil || form.Permission != nil) {
+		ctx.APIError(422, "", "Cannot change name or permission of owner team.")
+		return
+	}
+
+	if form.Name != nil {
+		t.Name = strings.TrimSpace(*form.Name)
+	}
+	if form.Description != nil {
+		t.Description = *form.Description
+	}
+
+	var authChanged bool
+	if form.Permission != nil {
+		auth, ok := parseTeamPermission(*form.Permission)
+		if !ok {
+			ctx.APIError(422, "", "Permission must be one of 'read', 'write' or 'admin'.")
+			return
+		}
+		if t.Authorize != auth {
+			authChanged = true
+			t.Authorize = auth
+		}
+	}
+
+	if err := models.UpdateTeam(t, authChanged); err != nil {
+		if models.IsErrNameReserved(err) || models.IsErrNamePatternNotAllowed(err) {
+			ctx.APIError(422, "", err)
+		} else {
+			ctx.APIError(500, "UpdateTeam", err)
+		}
+		return
+	}
+	log.Trace("Team updated[%d]: %s", t.ID, t.Name)
+
+	ctx.JSON(200, ToApiTeam(t))
+}
+
+// DELETE /teams/:teamid
+func DeleteTeam(ctx *middleware.Context) {
+	org, t := getTeamToManage(ctx)
+	if ctx.Written() {
+		return
+	}
+	if t.IsOwnerTeam() {
+		ctx.APIError(422, "", "Cannot delete owner team.")
+		return
+	}
+
+	if err := models.DeleteTeam(t); err != nil {
+		ctx.APIError(500, "DeleteTeam", err)
+		return
+	}
+	log.Trace("Team deleted: %s/%s", org.Name, t.Name)
+
+	ctx.Status(204)
+}
+
+// GET /teams/:teamid/members
+func ListTeamMembers(ctx *middleware.Context) {
+	_, t := getTeamToManage(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	if err := t.GetMembers(); err != nil {
+		ctx.APIError(500, "GetMembers", err)
+		return
+	}
+
+	members := make([]*api.User, len(t.Members))
+	for i := range t.Members {
+		members[i] = ToApiUser(t.Members[i])
+	}
+	ctx.JSON(200, &members)
+}
+
+// getTeamMember returns user given by URL.
+func getTeamMember(ctx *middleware.Context) *models.User {
+	u, err := models.GetUserByName(ctx.Params(":username"))
+	if err != nil {
+		if models.IsErrUserNotExist(err) {
+			ctx.Error(404)
+		} else {
+			ctx.APIError(500, "GetUserByName", err)
+		}
+		return nil
+	}
+	if u.IsOrganization() {
+		ctx.APIError(422, "", "Organization cannot be a team member.")
+		return nil
+	}
+	return u
+}
+
+// PUT /teams/:teamid/members/:username
+func AddTeamMember(ctx *middleware.Context) {
+	org, t := getTeamToManage(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	u := getTeamMember(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	if err := t.AddMember(u.Id); err != nil {
+		ctx.APIError(500, "AddMember", err)
+		return
+	}
+	log.Trace("Member added to team[%d] of %s: %s", t.ID, org.Name, u.Name)
+
+	ctx.Status(204)
+}
+
+// DELETE /teams/:teamid/members/:username
+func RemoveTeamMember(ctx *middleware.Context) {
+	org, t := getTeamToManage(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	u := getTeamMember(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	if err := t.RemoveMember(u.Id); err != nil {
+		if models.IsErrLastOrgOwner(err) {
+			ctx.APIError(422, "", err)
+		} else {
+			ctx.APIError(500, "RemoveMember", err)
+		}
+		return
+	}
+	log.Trace("Member removed from team[%d] of %s: %s", t.ID, org.Name, u.Name)
+
+	ctx.Status(204)
+}
+
+// GET /teams/:teamid/repos
+func ListTeamRepos(ctx *middleware.Context) {
+	org, t := getTeamToManage(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	if err := t.GetRepositories(); err != nil {
+		ctx.APIError(500, "GetRepositories", err)
+		return
+	}
+
+	permission := api.Permission{
+		Admin: t.Authorize >= models.ACCESS_MODE_ADMIN,
+		Push:  t.Authorize >= models.ACCESS_MODE_WRITE,
+		Pull:  true,
+	}
+	repos := make([]*Repository, len(t.Repos))
+	for i := range t.Repos {
+		repos[i] = ToApiRepository(org, t.Repos[i], permission)
+	}
+	ctx.JSON(200, &repos)
+}
+
+// getTeamRepo returns repository given by URL in organization of team.
+func getTeamRepo(ctx *middleware.Context, org *models.User) *models.Repository {
+	repo, err := models.GetRepositoryByName(org.Id, ctx.Params(":reponame"))
+	if err != nil {
+		if models.IsErrRepoNotExist(err) {
+			ctx.Error(404)
+		} else {
+			ctx.APIError(500, "GetRepositoryByName", err)
+		}
+		return nil
+	}
+	return repo
+}
+
+// PUT /teams/:teamid/repos/:reponame
+func AddTeamRepo(ctx *middleware.Context) {
+	org, t := getTeamToManage(ctx)
+	if ctx.Written() {
+		return
+	}
+	if t.IsOwnerTeam() {
+		ctx.APIError(422, "", "Owner team has access to all repositories.")
+		return
+	}
+
+	repo := getTeamRepo(ctx, org)
+	if ctx.Written() {
+		return
+	}
+
+	if err := t.AddRepository(repo); err != nil {
+		ctx.APIError(500, "AddRepository", err)
+		return
+	}
+	log.Trace("Repository added to team[%d]: %s/%s", t.ID, org.Name, repo.Name)
+
+	ctx.Status(204)
+}
+
+// DELETE /teams/:teamid/repos/:reponame
+func RemoveTeamRepo(ctx *middleware.Context) {
+	org, t := getTeamToManage(ctx)
+	if ctx.Written() {
+		return
+	}
+	if t.IsOwnerTeam() {
+		ctx.APIError(422, "", "Owner team has access to all repositories.")
+		return
+	}
+
+	repo := getTeamRepo(ctx, org)
+	if ctx.Written() {
+		return
+	}
+
+	if err := t.RemoveRepository(repo.ID); err != nil {
+		ctx.APIError(500, "RemoveRepository", err)
+		return
+	}
+	log.Trace("Repository removed from team[%d]: %s/%s", t.ID, org.Name, repo.Name)
+
+	ctx.Status(204)
+}
@@ -5,7 +5,10 @@
 package v1
 
 import (
+	"fmt"
 	"path"
+	"strings"
+	"time"
 
 	"github.com/Unknwon/com"
 
@@ -13,32 +16,158 @@ import (
 
 	"github.com/gogits/gogs/models"
 	"github.com/gogits/gogs/modules/auth"
+	"github.com/gogits/gogs/modules/base"
+	"github.com/gogits/gogs/modules/git"
 	"github.com/gogits/gogs/modules/log"
 	"github.com/gogits/gogs/modules/middleware"
 	"github.com/gogits/gogs/modules/setting"
 )
 
+// Repository represents a repository in API format,
+// with fields that are not yet part of the client library.
+type Repository struct {
+	*api.Repository
+	Archived bool        `json:"archived"`
+	Template bool        `json:"template"`
+	Size     int64       `json:"size"` // In bytes.
+	Mirror   *MirrorInfo `json:"mirror,omitempty"`
+	Units    *RepoUnits  `json:"units"`
+
+	RequiredApprovals int `json:"required_approvals"`
+	// AutoAssign is only present in single repository responses to repository admins.
+	AutoAssign *AutoAssign `json:"auto_assign,omitempty"`
+}
+
+// AutoAssign represents how new issues and pull requests without assignee are assigned,
+// users and teams are only used in round-robin mode.
+type AutoAssign struct {
+	Mode      string   `json:"mode"`
+	Assignees []string `json:"assignees"`
+	Teams     []string `json:"teams"`
+}
+
+// RepoUnits represents which units are enabled in a repository.
+type RepoUnits struct {
+	Issues   bool `json:"issues"`
+	Pulls    bool `json:"pull_requests"`
+	Wiki     bool `json:"wiki"`
+	Releases bool `json:"releases"`
+}
+
+// EditRepoUnitsOption represents options for changing units of a repository,
+// fields left empty are not changed.
+type EditRepoUnitsOption struct {
+	Issues   *bool `json:"issues"`
+	Pulls    *bool `json:"pull_requests"`
+	Wiki     *bool `json:"wiki"`
+	Releases *bool `json:"releases"`
+}
+
+// MirrorInfo represents synchronization status of a mirror repository.
+type MirrorInfo struct {
+	Interval   int       `json:"interval"` // In hours.
+	LastSync   time.Time `json:"last_sync"`
+	LastError  string    `json:"last_error"`
+	NextUpdate time.Time `json:"next_update"`
+}
+
 // ToApiRepository converts repository to API format.
-func ToApiRepository(owner *models.User, repo *models.Repository, permission api.Permission) *api.Repository {
+func ToApiRepository(owner *models.User, repo *models.Repository, permission api.Permission) *Repository {
 	cl := repo.CloneLink()
-	return &api.Repository{
-		Id:          repo.ID,
-		Owner:       *ToApiUser(owner),
-		FullName:    owner.Name + "/" + repo.Name,
-		Private:     repo.IsPrivate,
-		Fork:        repo.IsFork,
-		HtmlUrl:     setting.AppUrl + owner.Name + "/" + repo.Name,
-		CloneUrl:    cl.HTTPS,
-		SshUrl:      cl.SSH,
-		Permissions: permission,
+	apiRepo := &Repository{
+		Repository: &api.Repository{
+			Id:          repo.ID,
+			Owner:       *ToApiUser(owner),
+			FullName:    owner.Name + "/" + repo.Name,
+			Private:     repo.IsPrivate,
+			Fork:        repo.IsFork,
+			HtmlUrl:     setting.AppUrl + owner.Name + "/" + repo.Name,
+			CloneUrl:    cl.HTTPS,
+			SshUrl:      cl.SSH,
+			Permissions: permission,
+		},
+		Archived: repo.IsArchived,
+		Template: repo.IsTemplate,
+		Size:     repo.Size,
+		Units: &RepoUnits{
+			Issues:   repo.EnableIssues,
+			Pulls:    repo.EnablePulls,
+			Wiki:     repo.EnableWiki,
+			Releases: repo.EnableReleases,
+		},
+		RequiredApprovals: repo.RequiredApprovals,
+	}
+
+	if repo.IsMirror {
+		if repo.Mirror == nil {
+			if err := repo.GetMirror(); err != nil {
+				log.Error(4, "GetMirror[%d]: %v", repo.ID, err)
+			}
+		}
+		if repo.Mirror != nil {
+			apiRepo.Mirror = &MirrorInfo{
+				Interval:   repo.Mirror.Interval,
+				LastSync:   repo.Mirror.LastSync,
+				LastError:  repo.Mirror.LastError,
+				NextUpdate: repo.Mirror.NextUpdate,
+			}
+		}
+	}
+	return apiRepo
+}
+
+// toApiAutoAssign converts auto assignment of repository to API format,
+// users and teams that fail to load are omitted.
+func toApiAutoAssign(repo *models.Repository) *AutoAssign {
+	autoAssign := &AutoAssign{
+		Mode:      repo.AutoAssignMode.Name(),
+		Assignees: []string{},
+		Teams:     []string{},
+	}
+	if repo.AutoAssignMode != models.AUTO_ASSIGN_ROUND_ROBIN {
+		return autoAssign
+	}
+
+	users, err := repo.GetAutoAssignees()
+	if err != nil {
+		log.Error(4, "GetAutoAssignees[%d]: %v", repo.ID, err)
+	}
+	for _, u := range users {
+		autoAssign.Assignees = append(autoAssign.Assignees, u.Name)
+	}
+	teams, err := repo.GetAutoAssignTeams()
+	if err != nil {
+		log.Error(4, "GetAutoAssignTeams[%d]: %v", repo.ID, err)
+	}
+	for _, t := range teams {
+		autoAssign.Teams = append(autoAssign.Teams, t.Name)
 	}
+	return autoAssign
+}
+
+// checkRepoSort validates "sort" and "order" query parameters of repository listing,
+// sort is one of "updated" (default), "created", "name", "size" and "stars",
+// and order is either "asc" or "desc".
+func checkRepoSort(ctx *middleware.Context, sort, order string) bool {
+	if !models.IsValidRepoSort(sort, order) {
+		ctx.APIError(422, "", fmt.Sprintf("Invalid sort or order, sort must be one of %s and order must be asc or desc.",
+			strings.Join(models.RepoSortKeys(), ", ")))
+		return false
+	}
+	return true
 }
 
 func SearchRepos(ctx *middleware.Context) {
 	opt := models.SearchOption{
-		Keyword: path.Base(ctx.Query("q")),
-		Uid:     com.StrTo(ctx.Query("uid")).MustInt64(),
-		Limit:   com.StrTo(ctx.Query("limit")).MustInt(),
+		Keyword:  path.Base(ctx.Query("q")),
+		Uid:      com.StrTo(ctx.Query("uid")).MustInt64(),
+		Limit:    com.StrTo(ctx.Query("limit")).MustInt(),
+		Archived: ctx.Query("archived") == "true",
+		Sort:     ctx.Query("sort"),
+		Order:    ctx.Query("order"),
+	}
+	if !checkRepoSort(ctx, opt.Sort, opt.Order) {
+		return
 	}
 	if opt.Limit == 0 {
 		opt.Limit = 10
@@ -96,51 +225,91 @@ func SearchRepos(ctx *middleware.Context) {
 
 // https://github.com/gogits/go-gogs-client/wiki/Repositories#list-your-repositories
 func ListMyRepos(ctx *middleware.Context) {
-	ownRepos, err := models.GetRepositories(ctx.User.Id, true)
-	if err != nil {
-		ctx.APIError(500, "GetRepositories", err)
+	sort, order := ctx.Query("sort"), ctx.Query("order")
+	if !checkRepoSort(ctx, sort, order) {
 		return
 	}
-	numOwnRepos := len(ownRepos)
 
-	accessibleRepos, err := ctx.User.GetRepositoryAccesses()
+	userRepos, modes, err := ctx.User.GetOwnedAndAccessibleRepositories(sort, order)
 	if err != nil {
-		ctx.APIError(500, "GetRepositoryAccesses", err)
+		ctx.APIError(500, "GetOwnedAndAccessibleRepositories", err)
 		return
 	}
 
-	repos := make([]*api.Repository, numOwnRepos+len(accessibleRepos))
-	for i := range ownRepos {
-		repos[i] = ToApiRepository(ctx.User, ownRepos[i], api.Permission{true, true, true})
-	}
-	i := numOwnRepos
+	repos := make([]*Repository, len(userRepos))
+	for i, repo := range userRepos {
+		if repo.OwnerID == ctx.User.Id {
+			repos[i] = ToApiRepository(ctx.User, repo, api.Permission{true, true, true})
+			continue
+		}
 
-	for repo, access := range accessibleRepos {
+		if err = repo.GetOwner(); err != nil {
+			ctx.APIError(500, "GetOwner", err)
+			return
+		}
+		access := modes[repo.ID]
 		repos[i] = ToApiRepository(repo.Owner, repo, api.Permission{
 			Admin: access >= models.ACCESS_MODE_ADMIN,
 			Push:  access >= models.ACCESS_MODE_WRITE,
 			Pull:  true,
 		})
-		i++
 	}
 
 	ctx.JSON(200, &repos)
 }
 
-func createRepo(ctx *middleware.Context, owner *models.User, opt api.CreateRepoOption) {
-	repo, err := models.CreateRepository(owner, models.CreateRepoOptions{
-		Name:        opt.Name,
-		Description: opt.Description,
-		Gitignores:  opt.Gitignores,
-		License:     opt.License,
-		Readme:      opt.Readme,
-		IsPrivate:   opt.Private,
-		AutoInit:    opt.AutoInit,
-	})
+// CreateRepoOption represents options for creating a repository.
+type CreateRepoOption struct {
+	api.CreateRepoOption
+	DefaultBranch string `json:"default_branch" binding:"MaxSize(100)"`
+	// DefaultLabels indicates whether to create default labels of organization,
+	// it is true when not given.
+	DefaultLabels *bool `json:"default_labels"`
+	// Private, EnableIssues and EnableWiki fall back to defaults of organization
+	// when not given.
+	Private      *bool `json:"private"`
+	EnableIssues *bool `json:"enable_issues"`
+	EnableWiki   *bool `json:"enable_wiki"`
+}
+
+func createRepo(ctx *middleware.Context, owner *models.User, opt CreateRepoOption) {
+	opts := models.CreateRepoOptions{
+		Name:          opt.Name,
+		Description:   opt.Description,
+		Gitignores:    opt.Gitignores,
+		License:       opt.License,
+		Readme:        opt.Readme,
+		IsPrivate:     opt.Private != nil && *opt.Private,
+		AutoInit:      opt.AutoInit,
+		DefaultBranch: strings.TrimSpace(opt.DefaultBranch),
+		DisableIssues: opt.EnableIssues != nil && !*opt.EnableIssues,
+		DisableWiki:   opt.EnableWiki != nil && !*opt.EnableWiki,
+	}
+	// Templates are committed in the initial commit, which requires auto init.
+	if len(opts.Gitignores) > 0 || len(opts.License) > 0 || len(opts.Readme) > 0 {
+		opts.AutoInit = true
+	}
+	if opts.AutoInit && len(opts.Readme) == 0 {
+		opts.Readme = "Default"
+	}
+	if owner.IsOrganization() {
+		defaults, err := models.GetOrgRepoDefaults(owner.Id)
+		if err != nil {
+			ctx.APIError(500, "GetOrgRepoDefaults", err)
+			return
+		}
+		defaults.Apply(&opts, opt.Private, opt.EnableIssues, opt.EnableWiki, opt.DefaultLabels == nil || *opt.DefaultLabels)
+	}
+
+	repo, err := models.CreateRepository(owner, opts)
 	if err != nil {
 		if models.IsErrRepoAlreadyExist(err) ||
 			models.IsErrNameReserved(err) ||
-			models.IsErrNamePatternNotAllowed(err) {
+			models.IsErrNamePatternNotAllowed(err) ||
+			models.IsErrNameCharsNotAllowed(err) ||
+			models.IsErrReachLimitOfRepo(err) ||
+			models.IsErrInvalidDefaultBranch(err) ||
+			models.IsErrRepoInitFileNotExist(err) {
 			ctx.APIError(422, "", err)
 		} else {
 			if repo != nil {
@@ -152,12 +321,16 @@ func createRepo(ctx *middleware.Context, owner *models.User, opt api.CreateRepoO
 		}
 		return
 	}
+	if err = owner.GrantRepoCreatorAccess(repo, ctx.User); err != nil {
+		ctx.APIError(500, "GrantRepoCreatorAccess", err)
+		return
+	}
 
 	ctx.JSON(201, ToApiRepository(owner, repo, api.Permission{true, true, true}))
 }
 
 // https://github.com/gogits/go-gogs-client/wiki/Repositories#create
-func CreateRepo(ctx *middleware.Context, opt api.CreateRepoOption) {
+func CreateRepo(ctx *middleware.Context, opt CreateRepoOption) {
 	// Shouldn't reach this condition, but just in case.
 	if ctx.User.IsOrganization() {
 		ctx.APIError(422, "", "not allowed creating repository for organization")
@@ -166,7 +339,7 @@ func CreateRepo(ctx *middleware.Context, opt api.CreateRepoOption) {
 	createRepo(ctx, ctx.User, opt)
 }
 
-func CreateOrgRepo(ctx *middleware.Context, opt api.CreateRepoOption) {
+func CreateOrgRepo(ctx *middleware.Context, opt CreateRepoOption) {
 	org, err := models.GetOrgByName(ctx.Params(":org"))
 	if err != nil {
 		if models.IsErrUserNotExist(err) {
@@ -177,8 +350,8 @@ func CreateOrgRepo(ctx *middleware.Context, opt api.CreateRepoOption) {
 		return
 	}
 
-	if !org.IsOwnedBy(ctx.User.Id) {
-		ctx.APIError(403, "", "Given user is not owner of organization.")
+	if !org.CanCreateOrgRepo(ctx.User.Id) {
+		ctx.APIError(403, "", "Given user is not allowed to create repository in organization.")
 		return
 	}
 	createRepo(ctx, org, opt)
@@ -207,9 +380,9 @@ func MigrateRepo(ctx *middleware.Context, form auth.MigrateRepoForm) {
 	}
 
 	if ctxUser.IsOrganization() && !ctx.User.IsAdmin {
-		// Check ownership of organization.
-		if !ctxUser.IsOwnedBy(ctx.User.Id) {
-			ctx.APIError(403, "", "Given user is not owner of organization.")
+		// Check permission of creating repository in organization.
+		if !ctxUser.CanCreateOrgRepo(ctx.User.Id) {
+			ctx.APIError(403, "", "Given user is not allowed to create repository in organization.")
 			return
 		}
 	}
@@ -247,7 +420,15 @@ func MigrateRepo(ctx *middleware.Context, form auth.MigrateRepoForm) {
 				log.Error(4, "DeleteRepository: %v", errDelete)
 			}
 		}
-		ctx.APIError(500, "MigrateRepository", err)
+		if models.IsErrReachLimitOfRepo(err) {
+			ctx.APIError(422, "", err)
+		} else {
+			ctx.APIError(500, "MigrateRepository", err)
+		}
+		return
+	}
+	if err = ctxUser.GrantRepoCreatorAccess(repo, ctx.User); err != nil {
+		ctx.APIError(500, "GrantRepoCreatorAccess", err)
 		return
 	}
 
@@ -279,13 +460,45 @@ func parseOwnerAndRepo(ctx *middleware.Context) (*models.User, *models.Repositor
 	return owner, repo
 }
 
+// GET /repos/:username/:reponame
+// Response has an ETag that changes whenever repository or its owner is updated,
+// request with matching If-None-Match header gets 304 without content.
 func GetRepo(ctx *middleware.Context) {
 	owner, repo := parseOwnerAndRepo(ctx)
 	if ctx.Written() {
 		return
 	}
 
-	ctx.JSON(200, ToApiRepository(owner, repo, api.Permission{true, true, true}))
+	var lastSync int64
+	if repo.IsMirror {
+		if err := repo.GetMirror(); err != nil {
+			ctx.APIError(500, "GetMirror", err)
+			return
+		}
+		lastSync = repo.Mirror.LastSync.UnixNano()
+	}
+
+	// Auto assignment reveals users and teams, so it is only shown to admins.
+	isAdmin := false
+	if ctx.IsSigned {
+		mode, err := models.AccessLevel(ctx.User, repo)
+		if err != nil {
+			ctx.APIError(500, "AccessLevel", err)
+			return
+		}
+		isAdmin = mode >= models.ACCESS_MODE_ADMIN || ctx.User.IsAdmin
+	}
+
+	if ctx.CheckETag(base.EncodeMD5(fmt.Sprintf("%d-%d-%d-%d-%d-%v-%d-%v",
+		repo.ID, repo.Updated.UnixNano(), repo.Size, owner.Id, owner.Updated.UnixNano(), repo.IsArchived, lastSync, isAdmin))) {
+		return
+	}
+
+	apiRepo := ToApiRepository(owner, repo, api.Permission{true, true, true})
+	if isAdmin {
+		apiRepo.AutoAssign = toApiAutoAssign(repo)
+	}
+	ctx.JSON(200, apiRepo)
 }
 
 func DeleteRepo(ctx *middleware.Context) {
@@ -307,3 +520,169 @@ func DeleteRepo(ctx *middleware.Context) {
 	log.Trace("Repository deleted: %s/%s", owner.Name, repo.Name)
 	ctx.Status(204)
 }
+
+// EditRepoOption represents options for editing a repository,
+// fields left empty are not changed.
+type EditRepoOption struct {
+	Description   *string `json:"description" binding:"MaxSize(255)"`
+	Website       *string `json:"website" binding:"MaxSize(100)"`
+	DefaultBranch *string `json:"default_branch"`
+	Private       *bool   `json:"private"`
+	Template      *bool   `json:"template"`
+
+	Units *EditRepoUnitsOption `json:"units"`
+
+	RequiredApprovals *int `json:"required_approvals"`
+	// AutoAssign replaces auto assignment as a whole.
+	AutoAssign *AutoAssign `json:"auto_assign"`
+}
+
+// PATCH /repos/:username/:reponame
+func EditRepo(ctx *middleware.Context, form EditRepoOption) {
+	owner, repo := parseOwnerAndRepo(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	mode, err := models.AccessLevel(ctx.User, repo)
+	if err != nil {
+		ctx.APIError(500, "AccessLevel", err)
+		return
+	} else if mode < models.ACCESS_MODE_ADMIN && !ctx.User.IsAdmin {
+		ctx.APIError(403, "", "Given user does not have admin access to repository.")
+		return
+	}
+
+	if form.Description != nil {
+		repo.Description = *form.Description
+	}
+	if form.Website != nil {
+		repo.Website = *form.Website
+	}
+
+	if form.DefaultBranch != nil && repo.DefaultBranch != *form.DefaultBranch {
+		gitRepo, err := git.OpenRepository(repo.RepoPath())
+		if err != nil {
+			ctx.APIError(500, "OpenRepository", err)
+			return
+		} else if !gitRepo.IsBranchExist(*form.DefaultBranch) {
+			ctx.APIError(422, "", fmt.Sprintf("Branch '%s' does not exist.", *form.DefaultBranch))
+			return
+		}
+
+		if err = gitRepo.SetDefaultBranch(*form.DefaultBranch); err != nil && !git.IsErrUnsupportedVersion(err) {
+			ctx.APIError(500, "SetDefaultBranch", err)
+			return
+		}
+		repo.DefaultBranch = *form.DefaultBranch
+	}
+
+	visibilityChanged := false
+	if form.Private != nil && repo.IsPrivate != *form.Private {
+		// Visibility of forked repository is forced sync with base repository.
+		if repo.IsFork {
+			ctx.APIError(422, "", "Cannot change visibility of a forked repository.")
+			return
+		} else if !*form.Private && setting.Repository.ForcePrivate && !ctx.User.IsAdmin {
+			ctx.APIError(422, "", "Repositories are forced to be private on this instance.")
+			return
+		}
+		repo.IsPrivate = *form.Private
+		visibilityChanged = true
+	}
+	if form.Template != nil {
+		repo.IsTemplate = *form.Template
+	}
+	if form.Units != nil {
+		if form.Units.Issues != nil {
+			repo.EnableIssues = *form.Units.Issues
+		}
+		if form.Units.Pulls != nil {
+			repo.EnablePulls = *form.Units.Pulls
+		}
+		if form.Units.Wiki != nil {
+			repo.EnableWiki = *form.Units.Wiki
+		}
+		if form.Units.Releases != nil {
+			repo.EnableReleases = *form.Units.Releases
+		}
+	}
+
+	if form.RequiredApprovals != nil {
+		if *form.RequiredApprovals < 0 {
+			ctx.APIError(422, "", "Number of required approvals cannot be negative.")
+			return
+		}
+		repo.RequiredApprovals = *form.RequiredApprovals
+	}
+
+	if form.AutoAssign != nil {
+		mode, ok := models.ToAutoAssignMode(form.AutoAssign.Mode)
+		if !ok {
+			ctx.APIError(422, "", "Mode of auto assignment must be one of none, author and round_robin.")
+			return
+		}
+		if err = repo.SetAutoAssignment(mode, form.AutoAssign.Assignees, form.AutoAssign.Teams); err != nil {
+			if models.IsErrInvalidAutoAssignee(err) {
+				if name := err.(models.ErrInvalidAutoAssignee).Name; len(name) > 0 {
+					ctx.APIError(422, "", "User or team does not exist or does not have write access to repository: "+name)
+				} else {
+					ctx.APIError(422, "", "At least one user or team is required for round-robin assignment.")
+				}
+			} else {
+				ctx.APIError(500, "SetAutoAssignment", err)
+			}
+			return
+		}
+	}
+
+	if err = models.UpdateRepository(repo, visibilityChanged); err != nil {
+		ctx.APIError(500, "UpdateRepository", err)
+		return
+	}
+	log.Trace("Repository updated: %s/%s", owner.Name, repo.Name)
+
+	apiRepo := ToApiRepository(owner, repo, api.Permission{true, true, true})
+	apiRepo.AutoAssign = toApiAutoAssign(repo)
+	ctx.JSON(200, apiRepo)
+}
+
+// POST /repos/:username/:reponame/mirror-sync
+func MirrorSync(ctx *middleware.Context) {
+	repo := ctx.Repo.Repository
+	if !ctx.Repo.IsPusher() {
+		ctx.APIError(403, "", "Given user does not have write access to repository.")
+		return
+	} else if !repo.IsMirror {
+		ctx.APIError(422, "", "Repository is not a mirror.")
+		return
+	}
+
+	if err := repo.GetMirror(); err != nil {
+		ctx.APIError(500, "GetMirror", err)
+		return
+	} else if err = repo.Mirror.ScheduleSync(); err != nil {
+		ctx.APIError(500, "ScheduleSync", err)
+		return
+	}
+	ctx.Status(202)
+}
+
+// POST /repos/:username/:reponame/cache/flush
+func FlushRepoCache(ctx *middleware.Context) {
+	repo := ctx.Repo.Repository
+	if err := ctx.FlushRepoCache(repo.ID); err != nil {
+		ctx.APIError(500, "FlushRepoCache", err)
+		return
+	}
+	archives, err := repo.DeleteArchives()
+	if err != nil {
+		ctx.APIError(500, "DeleteArchives", err)
+		return
+	}
+	log.Trace("Repository cache flushed[%d]: %d archives", repo.ID, archives)
+
+	ctx.JSON(200, map[string]int{
+		"deleted_archives": archives,
+	})
+}
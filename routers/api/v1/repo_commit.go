@@ -0,0 +1,418 @@
+// Copyright 2015 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package v1
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gogits/gogs/models"
+	"github.com/gogits/gogs/modules/base"
+	"github.com/gogits/gogs/modules/git"
+	"github.com/gogits/gogs/modules/middleware"
+	"github.com/gogits/gogs/modules/setting"
+)
+
+const (
+	// COMMIT_PAGING_NUM is the default number of commits returned per page.
+	COMMIT_PAGING_NUM = 30
+	// COMMIT_MAX_PAGING_NUM is the maximum number of commits can be requested per page.
+	COMMIT_MAX_PAGING_NUM = 100
+)
+
+// CommitUser represents author or committer of a commit.
+type CommitUser struct {
+	Name  string    `json:"name"`
+	Email string    `json:"email"`
+	Date  time.Time `json:"date"`
+}
+
+// CommitFile represents a file changed by a commit.
+type CommitFile struct {
+	Filename         string `json:"filename"`
+	PreviousFilename string `json:"previous_filename,omitempty"`
+	Status           string `json:"status"`
+	Additions        int    `json:"additions"`
+	Deletions        int    `json:"deletions"`
+	Binary           bool   `json:"binary"`
+}
+
+// CommitStats represents number of changed lines of a commit.
+type CommitStats struct {
+	Additions int `json:"additions"`
+	Deletions int `json:"deletions"`
+	Total     int `json:"total"`
+}
+
+// Commit represents a Git commit in API format,
+// files and stats are only present when commit is retrieved individually.
+type Commit struct {
+	SHA       string        `json:"sha"`
+	URL       string        `json:"html_url"`
+	Author    *CommitUser   `json:"author"`
+	Committer *CommitUser   `json:"committer"`
+	Message   string        `json:"message"`
+	Parents   []string      `json:"parents"`
+	Stats     *CommitStats  `json:"stats,omitempty"`
+	Files     []*CommitFile `json:"files,omitempty"`
+	// Truncated indicates list of files is incomplete because the diff is too large.
+	Truncated bool   `json:"truncated,omitempty"`
+	Patch     string `json:"patch,omitempty"`
+	// PatchTooLarge indicates patch is omitted because it exceeds size limit.
+	PatchTooLarge bool `json:"patch_too_large,omitempty"`
+}
+
+func toApiCommitUser(sig *git.Signature) *CommitUser {
+	return &CommitUser{
+		Name:  sig.Name,
+		Email: sig.Email,
+		Date:  sig.When,
+	}
+}
+
+// ToApiCommit converts Git commit of repository to API format.
+func ToApiCommit(repo *models.Repository, c *git.Commit) *Commit {
+	parents := make([]string, c.ParentCount())
+	for i := range parents {
+		id, _ := c.ParentId(i)
+		parents[i] = id.String()
+	}
+
+	return &Commit{
+		SHA:       c.ID.String(),
+		URL:       setting.AppUrl + repo.MustOwner().Name + "/" + repo.Name + "/commit/" + c.ID.String(),
+		Author:    toApiCommitUser(c.Author),
+		Committer: toApiCommitUser(c.Committer),
+		Message:   c.Message(),
+		Parents:   parents,
+	}
+}
+
+func diffFileStatus(f *models.DiffFile) string {
+	switch f.Type {
+	case models.DIFF_FILE_ADD:
+		return "added"
+	case models.DIFF_FILE_DEL:
+		return "deleted"
+	case models.DIFF_FILE_RENAME:
+		return "renamed"
+	}
+	return "modified"
+}
+
+// GET /repos/:username/:reponame/commits/:sha
+func GetRepoCommit(ctx *middleware.Context) {
+	gitRepo, err := git.OpenRepository(ctx.Repo.Repository.RepoPath())
+	if err != nil {
+		ctx.APIError(500, "OpenRepository", err)
+		return
+	}
+
+	sha := ctx.Params(":sha")
+	id, _, err := gitRepo.ResolveRef(sha)
+	if err != nil {
+		if err == git.ErrNotExist {
+			ctx.Error(404)
+		} else if git.IsErrAmbiguousObject(err) {
+			ctx.JSON(409, map[string]interface{}{
+				"message":    "Short object ID is ambiguous: " + sha,
+				"url":        base.DOC_URL,
+				"candidates": err.(git.ErrAmbiguousObject).Candidates,
+			})
+		} else {
+			ctx.APIError(500, "ResolveRef", err)
+		}
+		return
+	}
+
+	// Object that does not point to a commit is treated as not existing.
+	commitID, err := gitRepo.PeelToCommit(id)
+	if err != nil {
+		ctx.Error(404)
+		return
+	}
+	commit, err := gitRepo.GetCommit(commitID)
+	if err != nil {
+		ctx.APIError(500, "GetCommit", err)
+		return
+	}
+
+	diff, err := models.GetDiffCommit(ctx.Repo.Repository.RepoPath(), commitID, models.DefaultDiffLimits())
+	if err != nil {
+		ctx.APIError(500, "GetDiffCommit", err)
+		return
+	}
+
+	apiCommit := ToApiCommit(ctx.Repo.Repository, commit)
+	apiCommit.Stats = &CommitStats{
+		Additions: diff.TotalAddition,
+		Deletions: diff.TotalDeletion,
+		Total:     diff.TotalAddition + diff.TotalDeletion,
+	}
+	apiCommit.Files = make([]*CommitFile, len(diff.Files))
+	for i, f := range diff.Files {
+		apiCommit.Files[i] = &CommitFile{
+			Filename:  f.Name,
+			Status:    diffFileStatus(f),
+			Additions: f.Addition,
+			Deletions: f.Deletion,
+			Binary:    f.IsBin,
+		}
+		if f.IsRenamed {
+			apiCommit.Files[i].PreviousFilename = f.OldName
+		}
+	}
+	apiCommit.Truncated = diff.IsIncomplete
+
+	if ctx.Query("diff") == "true" {
+		patch, err := gitRepo.GetCommitPatch(commitID, setting.Git.MaxGitDiffBytes)
+		if err != nil {
+			if !git.IsErrPatchTooLarge(err) {
+				ctx.APIError(500, "GetCommitPatch", err)
+				return
+			}
+			apiCommit.PatchTooLarge = true
+		} else {
+			apiCommit.Patch = string(patch)
+		}
+	}
+
+	ctx.JSON(200, apiCommit)
+}
+
+// setPaginationHeaders sets total number of items and links to neighbouring pages in response headers.
+func setPaginationHeaders(ctx *middleware.Context, page, limit, total int) {
+	ctx.Resp.Header().Set("X-Total-Count", fmt.Sprintf("%d", total))
+
+	query := ctx.Req.URL.Query()
+	link := func(page int, rel string) string {
+		query.Set("page", fmt.Sprintf("%d", page))
+		query.Set("limit", fmt.Sprintf("%d", limit))
+		return fmt.Sprintf("<%s%s?%s>; rel=\"%s\"", strings.TrimSuffix(setting.AppUrl, "/"), ctx.Req.URL.Path, query.Encode(), rel)
+	}
+
+	lastPage := (total + limit - 1) / limit
+	links := make([]string, 0, 4)
+	if page < lastPage {
+		links = append(links, link(page+1, "next"), link(lastPage, "last"))
+	}
+	if page > 1 {
+		links = append(links, link(1, "first"), link(page-1, "prev"))
+	}
+	if len(links) > 0 {
+		ctx.Resp.Header().Set("Link", strings.Join(links, ", "))
+	}
+}
+
+// GET /repos/:username/:reponame/commits
+func ListRepoCommits(ctx *middleware.Context) {
+	apiCommits := make([]*Commit, 0, COMMIT_PAGING_NUM)
+	if ctx.Repo.Repository.IsBare {
+		ctx.JSON(200, &apiCommits)
+		return
+	}
+
+	gitRepo, err := git.OpenRepository(ctx.Repo.Repository.RepoPath())
+	if err != nil {
+		ctx.APIError(500, "OpenRepository", err)
+		return
+	}
+
+	ref := ctx.Query("sha")
+	if len(ref) == 0 {
+		ref = ctx.Repo.Repository.DefaultBranch
+	}
+	head, err := getCommitByRef(gitRepo, ref)
+	if err != nil {
+		if err == git.ErrNotExist {
+			ctx.APIError(404, "", "Reference does not exist: "+ref)
+		} else {
+			ctx.APIError(500, "getCommitByRef", err)
+		}
+		return
+	}
+	headID := head.ID.String()
+
+	page := ctx.QueryInt("page")
+	if page <= 0 {
+		page = 1
+	}
+	limit := ctx.QueryInt("limit")
+	if limit <= 0 {
+		limit = COMMIT_PAGING_NUM
+	} else if limit > COMMIT_MAX_PAGING_NUM {
+		limit = COMMIT_MAX_PAGING_NUM
+	}
+
+	treePath := strings.Trim(ctx.Query("path"), "/")
+	var total int
+	if len(treePath) > 0 {
+		total, err = gitRepo.FileCommitsCount(headID, treePath)
+	} else {
+		total, err = gitRepo.CommitsCount(headID)
+	}
+	if err != nil {
+		ctx.APIError(500, "CommitsCount", err)
+		return
+	}
+
+	// Pages after the last one are all empty, clamping keeps the offset from overflowing.
+	if maxPage := total/limit + 1; page > maxPage {
+		page = maxPage
+	}
+	commits, err := gitRepo.CommitsByRangeAndPath(headID, treePath, (page-1)*limit, limit)
+	if err != nil {
+		ctx.APIError(500, "CommitsByRangeAndPath", err)
+		return
+	}
+	for e := commits.Front(); e != nil; e = e.Next() {
+		apiCommits = append(apiCommits, ToApiCommit(ctx.Repo.Repository, e.Value.(*git.Commit)))
+	}
+
+	setPaginationHeaders(ctx, page, limit, total)
+	ctx.JSON(200, &apiCommits)
+}
+
+// ResolvedRef represents result of resolving a branch, tag or abbreviated object ID.
+type ResolvedRef struct {
+	Ref  string `json:"ref"`
+	SHA  string `json:"sha"`
+	Type string `json:"type"`
+	// CommitSHA is the commit that object points to, it is absent for trees and blobs.
+	CommitSHA string `json:"commit_sha,omitempty"`
+}
+
+// GET /repos/:username/:reponame/git/resolve/*
+func ResolveRepoRef(ctx *middleware.Context) {
+	if ctx.Repo.Repository.IsBare {
+		ctx.Error(404)
+		return
+	}
+
+	gitRepo, err := git.OpenRepository(ctx.Repo.Repository.RepoPath())
+	if err != nil {
+		ctx.APIError(500, "OpenRepository", err)
+		return
+	}
+
+	ref := ctx.Params("*")
+	id, typ, err := gitRepo.ResolveRef(ref)
+	if err != nil {
+		if err == git.ErrNotExist {
+			ctx.APIError(404, "", "Reference does not exist: "+ref)
+		} else if git.IsErrAmbiguousObject(err) {
+			ctx.JSON(409, map[string]interface{}{
+				"message":    "Short object ID is ambiguous: " + ref,
+				"url":        base.DOC_URL,
+				"candidates": err.(git.ErrAmbiguousObject).Candidates,
+			})
+		} else {
+			ctx.APIError(500, "ResolveRef", err)
+		}
+		return
+	}
+
+	resolved := &ResolvedRef{
+		Ref:  ref,
+		SHA:  id,
+		Type: string(typ),
+	}
+	switch typ {
+	case git.COMMIT:
+		resolved.CommitSHA = id
+	case git.TAG:
+		// Tag may point to object other than commit.
+		if commitID, err := gitRepo.PeelToCommit(id); err == nil {
+			resolved.CommitSHA = commitID
+		}
+	}
+	ctx.JSON(200, resolved)
+}
+
+const (
+	// REF_PAGING_NUM is the default number of references returned per page.
+	REF_PAGING_NUM = 100
+	// REF_MAX_PAGING_NUM is the maximum number of references can be requested per page.
+	REF_MAX_PAGING_NUM = 1000
+)
+
+// Reference represents a Git reference and the object it points to.
+type Reference struct {
+	Ref  string `json:"ref"`
+	SHA  string `json:"sha"`
+	Type string `json:"type"`
+}
+
+// GET /repos/:username/:reponame/git/refs
+// GET /repos/:username/:reponame/git/refs/*
+func ListRepoRefs(ctx *middleware.Context) {
+	apiRefs := make([]*Reference, 0, REF_PAGING_NUM)
+	if ctx.Repo.Repository.IsBare {
+		ctx.JSON(200, &apiRefs)
+		return
+	}
+
+	gitRepo, err := git.OpenRepository(ctx.Repo.Repository.RepoPath())
+	if err != nil {
+		ctx.APIError(500, "OpenRepository", err)
+		return
+	}
+
+	var patterns []string
+	if filter := strings.Trim(ctx.Params("*"), "/"); len(filter) > 0 {
+		patterns = append(patterns, "refs/"+filter)
+	}
+	refs, err := gitRepo.GetRefs(patterns...)
+	if err != nil {
+		ctx.APIError(500, "GetRefs", err)
+		return
+	}
+
+	// Notes are only listed when explicitly asked for.
+	if len(patterns) == 0 && ctx.Query("notes") != "true" {
+		filtered := refs[:0]
+		for _, ref := range refs {
+			if !strings.HasPrefix(ref.Name, "refs/notes/") {
+				filtered = append(filtered, ref)
+			}
+		}
+		refs = filtered
+	}
+
+	page := ctx.QueryInt("page")
+	if page <= 0 {
+		page = 1
+	}
+	limit := ctx.QueryInt("limit")
+	if limit <= 0 {
+		limit = REF_PAGING_NUM
+	} else if limit > REF_MAX_PAGING_NUM {
+		limit = REF_MAX_PAGING_NUM
+	}
+
+	// Pages after the last one are all empty, clamping keeps the offset from overflowing.
+	if maxPage := len(refs)/limit + 1; page > maxPage {
+		page = maxPage
+	}
+	start := (page - 1) * limit
+	if start > len(refs) {
+		start = len(refs)
+	}
+	end := start + limit
+	if end > len(refs) {
+		end = len(refs)
+	}
+	for _, ref := range refs[start:end] {
+		apiRefs = append(apiRefs, &Reference{
+			Ref:  ref.Name,
+			SHA:  ref.ObjectID,
+			Type: string(ref.Type),
+		})
+	}
+
+	setPaginationHeaders(ctx, page, limit, len(refs))
+	ctx.JSON(200, &apiRefs)
+}
@@ -5,9 +5,20 @@
 package v1
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+
+	"github.com/Unknwon/com"
+
 	"github.com/gogits/gogs/models"
+	"github.com/gogits/gogs/modules/base"
 	"github.com/gogits/gogs/modules/git"
+	"github.com/gogits/gogs/modules/log"
 	"github.com/gogits/gogs/modules/middleware"
+	"github.com/gogits/gogs/modules/setting"
 	"github.com/gogits/gogs/routers/repo"
 )
 
@@ -42,3 +53,224 @@ func GetRepoArchive(ctx *middleware.Context) {
 
 	repo.Download(ctx)
 }
+
+// Readme represents README file of repository in API format.
+type Readme struct {
+	Name    string `json:"name"`
+	Ref     string `json:"ref"`
+	Size    int64  `json:"size"`
+	Content string `json:"content"`
+	HTML    string `json:"html"`
+}
+
+// getCommitByRef returns commit of given branch, tag or commit ID.
+func getCommitByRef(gitRepo *git.Repository, ref string) (*git.Commit, error) {
+	switch {
+	case gitRepo.IsBranchExist(ref):
+		return gitRepo.GetCommitOfBranch(ref)
+	case gitRepo.IsTagExist(ref):
+		return gitRepo.GetCommitOfTag(ref)
+	case len(ref) == 40:
+		// Invalid or missing commit ID is treated as not existing.
+		if commit, err := gitRepo.GetCommit(ref); err == nil {
+			return commit, nil
+		}
+	}
+	return nil, git.ErrNotExist
+}
+
+// GET /repos/:username/:reponame/readme
+func GetRepoReadme(ctx *middleware.Context) {
+	if ctx.Repo.Repository.IsBare {
+		ctx.Error(404)
+		return
+	}
+
+	gitRepo, err := git.OpenRepository(ctx.Repo.Repository.RepoPath())
+	if err != nil {
+		ctx.APIError(500, "OpenRepository", err)
+		return
+	}
+
+	ref := ctx.Query("ref")
+	if len(ref) == 0 {
+		ref = ctx.Repo.Repository.DefaultBranch
+	}
+	commit, err := getCommitByRef(gitRepo, ref)
+	if err != nil {
+		if err == git.ErrNotExist {
+			ctx.APIError(404, "", "Reference does not exist: "+ref)
+		} else {
+			ctx.APIError(500, "getCommitByRef", err)
+		}
+		return
+	}
+
+	entries, err := commit.ListEntries("")
+	if err != nil {
+		ctx.APIError(500, "ListEntries", err)
+		return
+	}
+	var readme *git.Blob
+	for _, entry := range entries {
+		if !entry.IsDir() && base.IsReadmeFile(entry.Name()) {
+			readme = entry.Blob()
+			break
+		}
+	}
+	if readme == nil {
+		ctx.Error(404)
+		return
+	}
+
+	dataRc, err := readme.Data()
+	if err != nil {
+		ctx.APIError(500, "Data", err)
+		return
+	}
+	buf, err := ioutil.ReadAll(dataRc)
+	if err != nil {
+		ctx.APIError(500, "ReadAll", err)
+		return
+	}
+
+	apiReadme := &Readme{
+		Name:    readme.Name(),
+		Ref:     ref,
+		Size:    readme.Size(),
+		Content: string(buf),
+	}
+	if _, isTextFile := base.IsTextFile(buf); isTextFile {
+		if base.IsMarkdownFile(readme.Name()) {
+			urlPrefix := setting.AppUrl + ctx.Repo.Owner.Name + "/" + ctx.Repo.Repository.Name + "/src/" + ref
+			apiReadme.HTML = string(base.RenderMarkdown(buf, urlPrefix))
+		} else {
+			escaped := []byte(template.HTMLEscapeString(string(buf)))
+			apiReadme.HTML = string(bytes.Replace(escaped, []byte("\n"), []byte(`<br>`), -1))
+		}
+	}
+	ctx.JSON(200, apiReadme)
+}
+
+// BlameLine represents authorship of a line in API format.
+type BlameLine struct {
+	Line         int    `json:"line"`
+	OriginalLine int    `json:"original_line"`
+	Content      string `json:"content"`
+}
+
+// BlameHunk represents consecutive lines introduced by the same commit in API format.
+type BlameHunk struct {
+	SHA      string       `json:"sha"`
+	Author   *CommitUser  `json:"author"`
+	Summary  string       `json:"summary"`
+	Filename string       `json:"filename"`
+	Lines    []*BlameLine `json:"lines"`
+}
+
+// Blame represents blame result of a file in API format.
+type Blame struct {
+	Path  string       `json:"path"`
+	SHA   string       `json:"sha"`
+	Hunks []*BlameHunk `json:"hunks"`
+}
+
+func toApiBlame(treePath, commitID string, hunks []*git.BlameHunk) *Blame {
+	blame := &Blame{
+		Path:  treePath,
+		SHA:   commitID,
+		Hunks: make([]*BlameHunk, len(hunks)),
+	}
+	for i, h := range hunks {
+		blame.Hunks[i] = &BlameHunk{
+			SHA: h.CommitID,
+			Author: &CommitUser{
+				Name:  h.Author.Name,
+				Email: h.Author.Email,
+				Date:  h.Author.When,
+			},
+			Summary:  h.Summary,
+			Filename: h.Filename,
+			Lines:    make([]*BlameLine, len(h.Lines)),
+		}
+		for j, l := range h.Lines {
+			blame.Hunks[i].Lines[j] = &BlameLine{
+				Line:         l.Line,
+				OriginalLine: l.OriginalLine,
+				Content:      l.Content,
+			}
+		}
+	}
+	return blame
+}
+
+// GET /repos/:username/:reponame/blame/*
+func GetRepoBlame(ctx *middleware.Context) {
+	if ctx.Repo.Repository.IsBare {
+		ctx.Error(404)
+		return
+	}
+
+	gitRepo, err := git.OpenRepository(ctx.Repo.Repository.RepoPath())
+	if err != nil {
+		ctx.APIError(500, "OpenRepository", err)
+		return
+	}
+
+	ref := ctx.Query("ref")
+	if len(ref) == 0 {
+		ref = ctx.Repo.Repository.DefaultBranch
+	}
+	commit, err := getCommitByRef(gitRepo, ref)
+	if err != nil {
+		if err == git.ErrNotExist {
+			ctx.APIError(404, "", "Reference does not exist: "+ref)
+		} else {
+			ctx.APIError(500, "getCommitByRef", err)
+		}
+		return
+	}
+
+	treePath := ctx.Params("*")
+	entry, err := commit.GetTreeEntryByPath(treePath)
+	if err != nil {
+		if err == git.ErrNotExist {
+			ctx.Error(404)
+		} else {
+			ctx.APIError(500, "GetTreeEntryByPath", err)
+		}
+		return
+	} else if entry.IsDir() || entry.IsSubModule() {
+		ctx.APIError(422, "", "Path is not a file: "+treePath)
+		return
+	} else if setting.Git.MaxBlameFileSize > 0 && entry.Size() > setting.Git.MaxBlameFileSize {
+		ctx.APIError(422, "", fmt.Sprintf("File is too large to blame, maximum size is %d bytes.", setting.Git.MaxBlameFileSize))
+		return
+	}
+
+	// Blame of a file at a commit never changes, so result is cached by commit ID.
+	commitID := commit.ID.String()
+	cacheKey := "Blame_" + commitID + "_" + base.EncodeMD5(treePath)
+	if data := com.ToStr(ctx.GetRepoCache(ctx.Repo.Repository.ID, cacheKey)); len(data) > 0 {
+		blame := new(Blame)
+		if err = json.Unmarshal([]byte(data), blame); err == nil {
+			ctx.JSON(200, blame)
+			return
+		}
+		log.Warn("Unmarshal cached blame[%s]: %v", cacheKey, err)
+	}
+
+	hunks, err := gitRepo.Blame(commitID, treePath)
+	if err != nil {
+		ctx.APIError(500, "Blame", err)
+		return
+	}
+
+	blame := toApiBlame(treePath, commitID, hunks)
+	if data, err := json.Marshal(blame); err != nil {
+		log.Error(4, "Marshal blame: %v", err)
+	} else if err = ctx.PutRepoCache(ctx.Repo.Repository.ID, cacheKey, string(data), 86400); err != nil {
+		log.Error(4, "Cache blame: %v", err)
+	}
+	ctx.JSON(200, blame)
+}
@@ -0,0 +1,70 @@
+// Copyright 2015 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package v1
+
+import (
+	api "github.com/gogits/go-gogs-client"
+
+	"github.com/gogits/gogs/models"
+	"github.com/gogits/gogs/modules/log"
+	"github.com/gogits/gogs/modules/middleware"
+)
+
+// CreateForkOption represents options for forking a repository,
+// fork is created under current user when organization is empty.
+type CreateForkOption struct {
+	Organization string `json:"organization"`
+}
+
+// POST /repos/:username/:reponame/forks
+func CreateFork(ctx *middleware.Context, form CreateForkOption) {
+	forkRepo := ctx.Repo.Repository
+	if !forkRepo.CanBeForked() {
+		ctx.APIError(422, "", "Repository cannot be forked.")
+		return
+	}
+
+	ctxUser := ctx.User
+	if len(form.Organization) > 0 {
+		org, err := models.GetOrgByName(form.Organization)
+		if err != nil {
+			if models.IsErrUserNotExist(err) {
+				ctx.APIError(422, "", err)
+			} else {
+				ctx.APIError(500, "GetOrgByName", err)
+			}
+			return
+		}
+
+		// Check ownership of organization.
+		if !org.IsOwnedBy(ctx.User.Id) {
+			ctx.APIError(403, "", "Given user is not owner of organization.")
+			return
+		}
+		ctxUser = org
+	}
+
+	if repo, has := models.HasForkedRepo(ctxUser.Id, forkRepo.ID); has {
+		ctx.APIError(409, "", "Repository has already been forked as "+ctxUser.Name+"/"+repo.Name+".")
+		return
+	}
+
+	repo, err := models.ForkRepository(ctxUser, forkRepo, forkRepo.Name, forkRepo.Description)
+	if err != nil {
+		if models.IsErrRepoAlreadyExist(err) ||
+			models.IsErrNameReserved(err) ||
+			models.IsErrNamePatternNotAllowed(err) ||
+			models.IsErrNameCharsNotAllowed(err) ||
+			models.IsErrReachLimitOfRepo(err) {
+			ctx.APIError(422, "", err)
+		} else {
+			ctx.APIError(500, "ForkRepository", err)
+		}
+		return
+	}
+
+	log.Trace("Repository forked[%d]: %s/%s", forkRepo.ID, ctxUser.Name, repo.Name)
+	ctx.JSON(201, ToApiRepository(ctxUser, repo, api.Permission{true, true, true}))
+}
@@ -86,8 +86,12 @@ func CreateRepoHook(ctx *middleware.Context, form api.CreateHookOption) {
 		HookEvent: &models.HookEvent{
 			ChooseEvents: true,
 			HookEvents: models.HookEvents{
-				Create: com.IsSliceContainsStr(form.Events, string(models.HOOK_EVENT_CREATE)),
-				Push:   com.IsSliceContainsStr(form.Events, string(models.HOOK_EVENT_PUSH)),
+				Create:      com.IsSliceContainsStr(form.Events, string(models.HOOK_EVENT_CREATE)),
+				Push:        com.IsSliceContainsStr(form.Events, string(models.HOOK_EVENT_PUSH)),
+				PushTag:     com.IsSliceContainsStr(form.Events, string(models.HOOK_EVENT_PUSH_TAG)),
+				PullRequest: com.IsSliceContainsStr(form.Events, string(models.HOOK_EVENT_PULL_REQUEST)),
+				Release:     com.IsSliceContainsStr(form.Events, string(models.HOOK_EVENT_RELEASE)),
+				Wiki:        com.IsSliceContainsStr(form.Events, string(models.HOOK_EVENT_WIKI)),
 			},
 		},
 		IsActive:     form.Active,
@@ -173,6 +177,10 @@ func EditRepoHook(ctx *middleware.Context, form api.EditHookOption) {
 	w.ChooseEvents = true
 	w.Create = com.IsSliceContainsStr(form.Events, string(models.HOOK_EVENT_CREATE))
 	w.Push = com.IsSliceContainsStr(form.Events, string(models.HOOK_EVENT_PUSH))
+	w.PushTag = com.IsSliceContainsStr(form.Events, string(models.HOOK_EVENT_PUSH_TAG))
+	w.PullRequest = com.IsSliceContainsStr(form.Events, string(models.HOOK_EVENT_PULL_REQUEST))
+	w.Release = com.IsSliceContainsStr(form.Events, string(models.HOOK_EVENT_RELEASE))
+	w.Wiki = com.IsSliceContainsStr(form.Events, string(models.HOOK_EVENT_WIKI))
 	if err = w.UpdateEvent(); err != nil {
 		ctx.APIError(500, "UpdateEvent", err)
 		return
@@ -189,3 +197,67 @@ func EditRepoHook(ctx *middleware.Context, form api.EditHookOption) {
 
 	ctx.JSON(200, ToApiHook(ctx.Repo.RepoLink, w))
 }
+
+// getRepoHook returns webhook of repository given by URL,
+// current user must have admin access to the repository.
+func getRepoHook(ctx *middleware.Context) *models.Webhook {
+	if !ctx.Repo.IsAdmin() {
+		ctx.APIError(403, "", "Given user does not have admin access to repository.")
+		return nil
+	}
+
+	w, err := models.GetWebhookByID(ctx.ParamsInt64(":id"))
+	if err != nil {
+		if models.IsErrWebhookNotExist(err) {
+			ctx.Error(404)
+		} else {
+			ctx.APIError(500, "GetWebhookByID", err)
+		}
+		return nil
+	} else if w.RepoID != ctx.Repo.Repository.ID {
+		ctx.Error(404)
+		return nil
+	}
+	return w
+}
+
+// GET /repos/:username/:reponame/hooks/:id/headers
+func GetRepoHookHeaders(ctx *middleware.Context) {
+	w := getRepoHook(ctx)
+	if ctx.Written() {
+		return
+	}
+	ctx.JSON(200, w.MaskedHeaders())
+}
+
+// PUT /repos/:username/:reponame/hooks/:id/headers
+func SetRepoHookHeaders(ctx *middleware.Context) {
+	w := getRepoHook(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	body, err := ctx.Req.Body().Bytes()
+	if err != nil {
+		ctx.APIError(500, "Body", err)
+		return
+	}
+	headers := make(map[string]string)
+	if err = json.Unmarshal(body, &headers); err != nil {
+		ctx.APIError(422, "", "Headers must be a JSON object of string values.")
+		return
+	}
+
+	if err = w.SetHeaders(headers); err != nil {
+		if models.IsErrInvalidHookHeader(err) {
+			ctx.APIError(422, "", err)
+		} else {
+			ctx.APIError(500, "SetHeaders", err)
+		}
+		return
+	} else if err = models.UpdateWebhook(w); err != nil {
+		ctx.APIError(500, "UpdateWebhook", err)
+		return
+	}
+	ctx.JSON(200, w.MaskedHeaders())
+}
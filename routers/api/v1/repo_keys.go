@@ -16,6 +16,14 @@ import (
 	"github.com/gogits/gogs/modules/setting"
 )
 
+// CreateDeployKeyOption represents options for creating a deploy key,
+// key is read-only unless read_only is explicitly set to false.
+type CreateDeployKeyOption struct {
+	Title    string `json:"title" binding:"Required"`
+	Key      string `json:"key" binding:"Required"`
+	ReadOnly *bool  `json:"read_only"`
+}
+
 func ToApiDeployKey(apiLink string, key *models.DeployKey) *api.DeployKey {
 	return &api.DeployKey{
 		ID:       key.ID,
@@ -23,7 +31,7 @@ func ToApiDeployKey(apiLink string, key *models.DeployKey) *api.DeployKey {
 		URL:      apiLink + com.ToStr(key.ID),
 		Title:    key.Name,
 		Created:  key.Created,
-		ReadOnly: true, // All deploy keys are read-only.
+		ReadOnly: key.IsReadOnly(),
 	}
 }
 
@@ -62,6 +70,9 @@ func GetRepoDeployKey(ctx *middleware.Context) {
 			ctx.Handle(500, "GetDeployKeyByID", err)
 		}
 		return
+	} else if key.RepoID != ctx.Repo.Repository.ID {
+		ctx.Error(404)
+		return
 	}
 
 	if err = key.GetContent(); err != nil {
@@ -74,7 +85,7 @@ func GetRepoDeployKey(ctx *middleware.Context) {
 }
 
 // https://github.com/gogits/go-gogs-client/wiki/Repositories---Deploy-Keys#add-a-new-deploy-key
-func CreateRepoDeployKey(ctx *middleware.Context, form api.CreateDeployKeyOption) {
+func CreateRepoDeployKey(ctx *middleware.Context, form CreateDeployKeyOption) {
 	content, err := models.CheckPublicKeyString(form.Key)
 	if err != nil {
 		if models.IsErrKeyUnableVerify(err) {
@@ -85,7 +96,8 @@ func CreateRepoDeployKey(ctx *middleware.Context, form api.CreateDeployKeyOption
 		return
 	}
 
-	key, err := models.AddDeployKey(ctx.Repo.Repository.ID, form.Title, content)
+	readOnly := form.ReadOnly == nil || *form.ReadOnly
+	key, err := models.AddDeployKey(ctx.Repo.Repository.ID, form.Title, content, readOnly)
 	if err != nil {
 		ctx.Data["HasError"] = true
 		switch {
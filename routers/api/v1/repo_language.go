@@ -0,0 +1,111 @@
+// Copyright 2015 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package v1
+
+import (
+	"encoding/json"
+	"io/ioutil"
+
+	"github.com/Unknwon/com"
+
+	"github.com/gogits/gogs/modules/git"
+	"github.com/gogits/gogs/modules/linguist"
+	"github.com/gogits/gogs/modules/log"
+	"github.com/gogits/gogs/modules/middleware"
+)
+
+// getRepoLanguages returns number of bytes of each language in tree of given commit,
+// vendored and generated files marked by ".gitattributes" are not counted.
+func getRepoLanguages(gitRepo *git.Repository, commit *git.Commit) (map[string]int64, error) {
+	entries, err := commit.ListEntries("")
+	if err != nil {
+		return nil, err
+	}
+
+	var attrsData []byte
+	for _, entry := range entries {
+		if entry.IsDir() || entry.Name() != ".gitattributes" {
+			continue
+		}
+		r, err := entry.Blob().Data()
+		if err != nil {
+			return nil, err
+		}
+		if attrsData, err = ioutil.ReadAll(r); err != nil {
+			return nil, err
+		}
+		break
+	}
+	attrs := linguist.ParseAttributes(string(attrsData))
+
+	files, err := gitRepo.ListTreeFiles(commit.ID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	languages := make(map[string]int64)
+	for _, f := range files {
+		if f.Mode == git.ModeSymlink || attrs.IsExcluded(f.Path) {
+			continue
+		}
+		if lang := linguist.DetectLanguage(f.Path); len(lang) > 0 {
+			languages[lang] += f.Size
+		}
+	}
+	return languages, nil
+}
+
+// GET /repos/:username/:reponame/languages
+func GetRepoLanguages(ctx *middleware.Context) {
+	if ctx.Repo.Repository.IsBare {
+		ctx.JSON(200, map[string]int64{})
+		return
+	}
+
+	gitRepo, err := git.OpenRepository(ctx.Repo.Repository.RepoPath())
+	if err != nil {
+		ctx.APIError(500, "OpenRepository", err)
+		return
+	}
+
+	ref := ctx.Query("ref")
+	if len(ref) == 0 {
+		ref = ctx.Repo.Repository.DefaultBranch
+	}
+	commit, err := getCommitByRef(gitRepo, ref)
+	if err != nil {
+		if err == git.ErrNotExist {
+			ctx.APIError(404, "", "Reference does not exist: "+ref)
+		} else {
+			ctx.APIError(500, "getCommitByRef", err)
+		}
+		return
+	}
+
+	// Result is cached by commit ID, so a push that moves the branch gets
+	// a different key and languages are computed again.
+	cacheKey := "Languages_" + commit.ID.String()
+	if data := com.ToStr(ctx.GetRepoCache(ctx.Repo.Repository.ID, cacheKey)); len(data) > 0 {
+		languages := make(map[string]int64)
+		if err = json.Unmarshal([]byte(data), &languages); err == nil {
+			ctx.JSON(200, languages)
+			return
+		}
+		log.Warn("Unmarshal cached languages[%s]: %v", cacheKey, err)
+	}
+
+	languages, err := getRepoLanguages(gitRepo, commit)
+	if err != nil {
+		ctx.APIError(500, "getRepoLanguages", err)
+		return
+	}
+
+	if data, err := json.Marshal(languages); err != nil {
+		log.Error(4, "Marshal languages: %v", err)
+	} else if err = ctx.PutRepoCache(ctx.Repo.Repository.ID, cacheKey, string(data), 86400); err != nil {
+		log.Error(4, "Cache languages: %v", err)
+	}
+	ctx.JSON(200, languages)
+}
@@ -0,0 +1,579 @@
+// Copyright 2015 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package v1
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	api "github.com/gogits/go-gogs-client"
+
+	"github.com/gogits/gogs/models"
+	"github.com/gogits/gogs/modules/git"
+	"github.com/gogits/gogs/modules/log"
+	"github.com/gogits/gogs/modules/middleware"
+	"github.com/gogits/gogs/modules/setting"
+)
+
+// PullRequestBranch represents head or base branch of a pull request.
+type PullRequestBranch struct {
+	Label string          `json:"label"`
+	Ref   string          `json:"ref"`
+	Sha   string          `json:"sha"`
+	Repo  *RepositoryMeta `json:"repo"`
+}
+
+// PullRequest represents a pull request in API format.
+type PullRequest struct {
+	*Issue
+	Head           *PullRequestBranch `json:"head"`
+	Base           *PullRequestBranch `json:"base"`
+	MergeBase      string             `json:"merge_base"`
+	Mergeable      bool               `json:"mergeable"`
+	MergeableState string             `json:"mergeable_state"`
+	HasMerged      bool               `json:"merged"`
+	MergeCommitID  string             `json:"merge_commit_sha"`
+	Merged         *time.Time         `json:"merged_at"`
+	Merger         *api.User          `json:"merged_by"`
+	// MergeBlockedReason explains why reviews do not allow pull request to be merged.
+	MergeBlockedReason string `json:"merge_blocked_reason,omitempty"`
+}
+
+func mergeableStateName(status models.PullRequestStatus) string {
+	switch status {
+	case models.PULL_REQUEST_STATUS_CONFLICT:
+		return "conflict"
+	case models.PULL_REQUEST_STATUS_CHECKING:
+		return "checking"
+	case models.PULL_REQUEST_STATUS_MERGEABLE:
+		return "mergeable"
+	}
+	return ""
+}
+
+// branchCommitID returns latest commit ID of branch in given repository,
+// or an empty string if it no longer exists.
+func branchCommitID(repoPath, branch string) string {
+	gitRepo, err := git.OpenRepository(repoPath)
+	if err != nil {
+		return ""
+	}
+	commitID, err := gitRepo.GetCommitIdOfBranch(branch)
+	if err != nil {
+		return ""
+	}
+	return commitID
+}
+
+// ToApiPullRequest converts pull request to API format,
+// both repository and pull request of issue must be loaded.
+func ToApiPullRequest(issue *models.Issue) (*PullRequest, error) {
+	var reviewErr error
+	if !issue.PullRequest.HasMerged && !issue.IsClosed {
+		issue.PullRequest.BaseRepo = issue.Repo
+		reviewErr = issue.PullRequest.CheckReviews()
+	}
+	return toApiPullRequest(issue, reviewErr)
+}
+
+// toApiPullRequest converts pull request to API format with given result of checking reviews.
+func toApiPullRequest(issue *models.Issue, reviewErr error) (*PullRequest, error) {
+	apiIssue, err := ToApiIssue(issue)
+	if err != nil {
+		return nil, err
+	}
+
+	pr := issue.PullRequest
+	pr.BaseRepo = issue.Repo
+	if err = pr.GetHeadRepo(); err != nil {
+		return nil, fmt.Errorf("GetHeadRepo: %v", err)
+	}
+
+	apiPR := &PullRequest{
+		Issue: apiIssue,
+		Head: &PullRequestBranch{
+			Label: pr.HeadUserName + ":" + pr.HeadBranch,
+			Ref:   pr.HeadBranch,
+		},
+		Base: &PullRequestBranch{
+			Label: issue.Repo.Owner.Name + ":" + pr.BaseBranch,
+			Ref:   pr.BaseBranch,
+			Sha:   branchCommitID(issue.Repo.RepoPath(), pr.BaseBranch),
+			Repo:  ToApiRepositoryMeta(issue.Repo),
+		},
+		MergeBase:      pr.MergeBase,
+		Mergeable:      !pr.HasMerged && pr.CanAutoMerge(),
+		MergeableState: mergeableStateName(pr.Status),
+		HasMerged:      pr.HasMerged,
+		MergeCommitID:  pr.MergeCommitID,
+	}
+
+	// Head repository could have been deleted.
+	if pr.HeadRepo != nil {
+		if err = pr.HeadRepo.GetOwner(); err != nil {
+			return nil, fmt.Errorf("GetOwner: %v", err)
+		}
+		apiPR.Head.Sha = branchCommitID(pr.HeadRepo.RepoPath(), pr.HeadBranch)
+		apiPR.Head.Repo = ToApiRepositoryMeta(pr.HeadRepo)
+	}
+
+	if pr.HasMerged {
+		if err = pr.GetMerger(); err != nil {
+			return nil, err
+		}
+		apiPR.Merged = &pr.Merged
+		apiPR.Merger = ToApiUser(pr.Merger)
+	} else if !issue.IsClosed {
+		if apiPR.MergeBlockedReason, err = reviewBlockedReason(reviewErr); err != nil {
+			return nil, fmt.Errorf("CheckReviews: %v", err)
+		} else if len(apiPR.MergeBlockedReason) > 0 {
+			apiPR.Mergeable = false
+		}
+	}
+	return apiPR, nil
+}
+
+// getPullRequestByIndex returns pull request of current repository by index in URL,
+// with repository and pull request loaded.
+func getPullRequestByIndex(ctx *middleware.Context) *models.Issue {
+	issue, err := models.GetIssueByIndex(ctx.Repo.Repository.ID, ctx.ParamsInt64(":index"))
+	if err != nil {
+		if models.IsErrIssueNotExist(err) {
+			ctx.Error(404)
+		} else {
+			ctx.APIError(500, "GetIssueByIndex", err)
+		}
+		return nil
+	} else if !issue.IsPull {
+		ctx.Error(404)
+		return nil
+	}
+	issue.Repo = ctx.Repo.Repository
+
+	if err = issue.GetPullRequest(); err != nil {
+		ctx.APIError(500, "GetPullRequest", err)
+		return nil
+	}
+	return issue
+}
+
+// GET /repos/:username/:reponame/pulls
+func ListPullRequests(ctx *middleware.Context) {
+	page := ctx.QueryInt("page")
+	if page <= 0 {
+		page = 1
+	}
+
+	issues, err := models.Issues(&models.IssuesOptions{
+		RepoID:   ctx.Repo.Repository.ID,
+		Page:     page,
+		IsClosed: ctx.Query("state") == "closed",
+		IsPull:   true,
+		SortType: ctx.Query("sort"),
+	})
+	if err != nil {
+		ctx.APIError(500, "Issues", err)
+		return
+	}
+
+	prs := make([]*models.PullRequest, 0, len(issues))
+	for i := range issues {
+		issues[i].Repo = ctx.Repo.Repository
+		if err = issues[i].GetPullRequest(); err != nil {
+			ctx.APIError(500, "GetPullRequest", err)
+			return
+		}
+		if !issues[i].PullRequest.HasMerged && !issues[i].IsClosed {
+			prs = append(prs, issues[i].PullRequest)
+		}
+	}
+
+	// Reviews of all pull requests are checked at once.
+	reviewErrs, err := models.CheckReviewsOfPullRequests(ctx.Repo.Repository, prs)
+	if err != nil {
+		ctx.APIError(500, "CheckReviewsOfPullRequests", err)
+		return
+	}
+
+	apiPRs := make([]*PullRequest, len(issues))
+	for i := range issues {
+		if apiPRs[i], err = toApiPullRequest(issues[i], reviewErrs[issues[i].PullRequest.ID]); err != nil {
+			ctx.APIError(500, "ToApiPullRequest", err)
+			return
+		}
+	}
+
+	ctx.JSON(200, &apiPRs)
+}
+
+// GET /repos/:username/:reponame/pulls/:index
+func GetPullRequest(ctx *middleware.Context) {
+	issue := getPullRequestByIndex(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	apiPR, err := ToApiPullRequest(issue)
+	if err != nil {
+		ctx.APIError(500, "ToApiPullRequest", err)
+		return
+	}
+	ctx.JSON(200, apiPR)
+}
+
+// GET /repos/:username/:reponame/pulls/:index.diff
+// GET /repos/:username/:reponame/pulls/:index.patch
+func GetPullRequestPatch(ctx *middleware.Context) {
+	issue := getPullRequestByIndex(ctx)
+	if ctx.Written() {
+		return
+	}
+	pr := issue.PullRequest
+
+	var (
+		gitRepo       *git.Repository
+		startCommitID string
+		endCommitID   string
+		err           error
+	)
+	if pr.HasMerged {
+		gitRepo, err = git.OpenRepository(ctx.Repo.Repository.RepoPath())
+		if err != nil {
+			ctx.APIError(500, "OpenRepository", err)
+			return
+		}
+		startCommitID = pr.MergeBase
+		endCommitID = pr.MergedCommitID
+	} else {
+		if err = pr.GetHeadRepo(); err != nil {
+			ctx.APIError(500, "GetHeadRepo", err)
+			return
+		} else if pr.HeadRepo == nil {
+			ctx.APIError(404, "", "Head repository has been deleted.")
+			return
+		}
+
+		gitRepo, err = git.OpenRepository(pr.HeadRepo.RepoPath())
+		if err != nil {
+			ctx.APIError(500, "OpenRepository", err)
+			return
+		}
+		endCommitID, err = gitRepo.GetCommitIdOfBranch(pr.HeadBranch)
+		if err != nil {
+			ctx.APIError(404, "", "Head branch has been deleted.")
+			return
+		}
+
+		var prInfo *git.PullRequestInfo
+		prInfo, err = gitRepo.GetPullRequestInfo(ctx.Repo.Repository.RepoPath(), pr.BaseBranch, pr.HeadBranch)
+		if err != nil {
+			ctx.APIError(500, "GetPullRequestInfo", err)
+			return
+		}
+		startCommitID = prInfo.MergeBase
+	}
+
+	var (
+		patch       []byte
+		contentType string
+	)
+	if ctx.Params(":ext") == "patch" {
+		patch, err = gitRepo.GetFormatPatch(startCommitID, endCommitID, setting.Git.MaxGitDiffBytes)
+		contentType = "text/x-patch; charset=utf-8"
+	} else {
+		patch, err = gitRepo.GetLimitedPatch(startCommitID, endCommitID, setting.Git.MaxGitDiffBytes)
+		contentType = "text/x-diff; charset=utf-8"
+	}
+	if err != nil {
+		if git.IsErrPatchTooLarge(err) {
+			ctx.APIError(413, "", fmt.Sprintf("Diff exceeds maximum size of %d bytes.", setting.Git.MaxGitDiffBytes))
+		} else {
+			ctx.APIError(500, "GetPatch", err)
+		}
+		return
+	}
+
+	ctx.Resp.Header().Set("Content-Type", contentType)
+	ctx.Resp.WriteHeader(200)
+	ctx.Resp.Write(patch)
+}
+
+// CreatePullRequestOption represents options for creating a pull request.
+type CreatePullRequestOption struct {
+	// Head is the branch to be merged, in format of "branch" or "username:branch"
+	// when it is from fork of another user.
+	Head  string `json:"head" binding:"Required"`
+	Base  string `json:"base" binding:"Required"`
+	Title string `json:"title" binding:"Required;MaxSize(255)"`
+	Body  string `json:"body"`
+}
+
+// POST /repos/:username/:reponame/pulls
+func CreatePullRequest(ctx *middleware.Context, form CreatePullRequestOption) {
+	repo := ctx.Repo.Repository
+	var (
+		headUser   = ctx.User
+		headBranch = form.Head
+		err        error
+	)
+	if idx := strings.Index(form.Head, ":"); idx > -1 {
+		headUser, err = models.GetUserByName(form.Head[:idx])
+		if err != nil {
+			if models.IsErrUserNotExist(err) {
+				ctx.APIError(422, "", err)
+			} else {
+				ctx.APIError(500, "GetUserByName", err)
+			}
+			return
+		}
+		headBranch = form.Head[idx+1:]
+	}
+
+	baseGitRepo, err := git.OpenRepository(repo.RepoPath())
+	if err != nil {
+		ctx.APIError(500, "OpenRepository", err)
+		return
+	} else if !baseGitRepo.IsBranchExist(form.Base) {
+		ctx.APIError(422, "", fmt.Sprintf("Base branch '%s' does not exist.", form.Base))
+		return
+	}
+
+	// Head repository must be a fork of this repository that current user can administrate.
+	headRepo, has := models.HasForkedRepo(headUser.Id, repo.ID)
+	if !has || (!ctx.User.IsAdminOfRepo(headRepo) && !ctx.User.IsAdmin) {
+		ctx.APIError(422, "", fmt.Sprintf("User '%s' does not have a fork of this repository that you can access.", headUser.Name))
+		return
+	}
+
+	headGitRepo, err := git.OpenRepository(models.RepoPath(headUser.Name, headRepo.Name))
+	if err != nil {
+		ctx.APIError(500, "OpenRepository", err)
+		return
+	} else if !headGitRepo.IsBranchExist(headBranch) {
+		ctx.APIError(422, "", fmt.Sprintf("Head branch '%s' does not exist.", headBranch))
+		return
+	}
+
+	pr, err := models.GetUnmergedPullRequest(headRepo.ID, repo.ID, headBranch, form.Base)
+	if err == nil {
+		ctx.APIError(409, "", fmt.Sprintf("There is already an open pull request #%d for the same branches.", pr.Index))
+		return
+	} else if !models.IsErrPullRequestNotExist(err) {
+		ctx.APIError(500, "GetUnmergedPullRequest", err)
+		return
+	}
+
+	prInfo, err := headGitRepo.GetPullRequestInfo(repo.RepoPath(), form.Base, headBranch)
+	if err != nil {
+		ctx.APIError(500, "GetPullRequestInfo", err)
+		return
+	}
+	patch, err := headGitRepo.GetPatch(prInfo.MergeBase, headBranch)
+	if err != nil {
+		ctx.APIError(500, "GetPatch", err)
+		return
+	}
+
+	assigneeID, err := repo.AutoAssignee(ctx.User)
+	if err != nil {
+		ctx.APIError(500, "AutoAssignee", err)
+		return
+	}
+
+	pull := &models.Issue{
+		RepoID:     repo.ID,
+		Index:      repo.NextIssueIndex(),
+		Name:       form.Title,
+		PosterID:   ctx.User.Id,
+		Poster:     ctx.User,
+		AssigneeID: assigneeID,
+		IsPull:     true,
+		Content:    form.Body,
+	}
+	if err = models.NewPullRequest(repo, pull, nil, nil, &models.PullRequest{
+		HeadRepoID:   headRepo.ID,
+		BaseRepoID:   repo.ID,
+		HeadUserName: headUser.Name,
+		HeadBranch:   headBranch,
+		BaseBranch:   form.Base,
+		MergeBase:    prInfo.MergeBase,
+		Type:         models.PULL_REQUEST_GOGS,
+	}, patch); err != nil {
+		ctx.APIError(500, "NewPullRequest", err)
+		return
+	}
+	log.Trace("Pull request created: %d/%d", repo.ID, pull.ID)
+
+	pull, err = models.GetIssueByIndex(repo.ID, pull.Index)
+	if err != nil {
+		ctx.APIError(500, "GetIssueByIndex", err)
+		return
+	}
+	pull.Repo = repo
+	if err = pull.GetPullRequest(); err != nil {
+		ctx.APIError(500, "GetPullRequest", err)
+		return
+	}
+
+	apiPR, err := ToApiPullRequest(pull)
+	if err != nil {
+		ctx.APIError(500, "ToApiPullRequest", err)
+		return
+	}
+	ctx.JSON(201, apiPR)
+}
+
+// MergePullRequestOption represents options for merging a pull request.
+type MergePullRequestOption struct {
+	Style   string `json:"merge_style"`
+	Title   string `json:"commit_title"`
+	Message string `json:"commit_message"`
+}
+
+// POST /repos/:username/:reponame/pulls/:index/merge
+func MergePullRequest(ctx *middleware.Context, form MergePullRequestOption) {
+	repo := ctx.Repo.Repository
+	if !ctx.Repo.IsAdmin() {
+		ctx.APIError(403, "", "Given user does not have admin access to repository.")
+		return
+	}
+
+	if len(form.Style) == 0 {
+		form.Style = string(models.MERGE_STYLE_MERGE)
+	} else if !models.IsValidMergeStyle(form.Style) {
+		ctx.APIError(422, "", fmt.Sprintf("Unsupported merge style '%s'.", form.Style))
+		return
+	}
+
+	issue := getPullRequestByIndex(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	pr := issue.PullRequest
+	if pr.HasMerged {
+		ctx.APIError(409, "", "Pull request has already been merged.")
+		return
+	} else if issue.IsClosed || !pr.CanAutoMerge() {
+		ctx.APIError(405, "", "Pull request is not mergeable.")
+		return
+	}
+
+	if reason, err := mergeBlockedReason(pr); err != nil {
+		ctx.APIError(500, "mergeBlockedReason", err)
+		return
+	} else if len(reason) > 0 {
+		ctx.APIError(405, "", reason)
+		return
+	}
+
+	if err := pr.GetHeadRepo(); err != nil {
+		ctx.APIError(500, "GetHeadRepo", err)
+		return
+	} else if pr.HeadRepo == nil {
+		ctx.APIError(405, "", "Head repository of pull request no longer exists.")
+		return
+	}
+
+	// Message is kept with default title when only message is given.
+	message := strings.TrimSpace(form.Title)
+	if body := strings.TrimSpace(form.Message); len(body) > 0 {
+		if len(message) == 0 {
+			message = pr.DefaultMergeMessage()
+		}
+		message += "\n\n" + body
+	}
+
+	baseGitRepo, err := git.OpenRepository(repo.RepoPath())
+	if err != nil {
+		ctx.APIError(500, "OpenRepository", err)
+		return
+	}
+
+	pr.Issue = issue
+	mergeCommitID, err := pr.Merge(ctx.User, baseGitRepo, models.MergeStyle(form.Style), message)
+	if err != nil {
+		ctx.APIError(500, "Merge", err)
+		return
+	}
+	log.Trace("Pull request merged: %d", pr.ID)
+
+	ctx.JSON(200, map[string]interface{}{
+		"sha":     mergeCommitID,
+		"merged":  true,
+		"message": "Pull request successfully merged.",
+	})
+}
+
+// MergeBase represents result of checking whether head can be merged into base cleanly.
+type MergeBase struct {
+	Base            string   `json:"base"`
+	Head            string   `json:"head"`
+	MergeBase       string   `json:"merge_base"`
+	Mergeable       bool     `json:"mergeable"`
+	ConflictedFiles []string `json:"conflicted_files"`
+}
+
+// GET /repos/:username/:reponame/merge-base?base=...&head=...
+func GetMergeBase(ctx *middleware.Context) {
+	if ctx.Repo.Repository.IsBare {
+		ctx.Error(404)
+		return
+	}
+
+	baseRef, headRef := ctx.Query("base"), ctx.Query("head")
+	if len(baseRef) == 0 || len(headRef) == 0 {
+		ctx.APIError(422, "", "Both base and head are required.")
+		return
+	}
+
+	gitRepo, err := git.OpenRepository(ctx.Repo.Repository.RepoPath())
+	if err != nil {
+		ctx.APIError(500, "OpenRepository", err)
+		return
+	}
+
+	commitIDs := make([]string, 2)
+	for i, ref := range []string{baseRef, headRef} {
+		commit, err := getCommitByRef(gitRepo, ref)
+		if err != nil {
+			if err == git.ErrNotExist {
+				ctx.APIError(404, "", "Reference does not exist: "+ref)
+			} else {
+				ctx.APIError(500, "getCommitByRef", err)
+			}
+			return
+		}
+		commitIDs[i] = commit.ID.String()
+	}
+
+	mergeBase, err := gitRepo.GetMergeBase(commitIDs[0], commitIDs[1])
+	if err != nil {
+		if err == git.ErrNoMergeBase {
+			ctx.APIError(422, "", "Base and head do not have a common ancestor.")
+		} else {
+			ctx.APIError(500, "GetMergeBase", err)
+		}
+		return
+	}
+
+	conflicts, err := gitRepo.GetMergeConflicts(mergeBase, commitIDs[0], commitIDs[1])
+	if err != nil {
+		ctx.APIError(500, "GetMergeConflicts", err)
+		return
+	}
+	if conflicts == nil {
+		conflicts = []string{}
+	}
+
+	ctx.JSON(200, &MergeBase{
+		Base:            commitIDs[0],
+		Head:            commitIDs[1],
+		MergeBase:       mergeBase,
+		Mergeable:       len(conflicts) == 0,
+		ConflictedFiles: conflicts,
+	})
+}
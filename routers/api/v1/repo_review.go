@@ -0,0 +1,229 @@
+// Copyright 2015 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package v1
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	api "github.com/gogits/go-gogs-client"
+
+	"github.com/gogits/gogs/models"
+	"github.com/gogits/gogs/modules/log"
+	"github.com/gogits/gogs/modules/middleware"
+)
+
+// DefaultReviewers represents users and teams whose reviews are requested for new pull requests.
+type DefaultReviewers struct {
+	Reviewers []*api.User `json:"reviewers"`
+	Teams     []*Team     `json:"teams"`
+}
+
+// EditDefaultReviewersOption represents options for updating default reviewers,
+// users and teams are given by name and replace existing ones.
+type EditDefaultReviewersOption struct {
+	Reviewers []string `json:"reviewers"`
+	Teams     []string `json:"teams"`
+}
+
+func responseDefaultReviewers(ctx *middleware.Context, repo *models.Repository) {
+	users, err := repo.GetDefaultReviewers()
+	if err != nil {
+		ctx.APIError(500, "GetDefaultReviewers", err)
+		return
+	}
+	teams, err := repo.GetDefaultReviewTeams()
+	if err != nil {
+		ctx.APIError(500, "GetDefaultReviewTeams", err)
+		return
+	}
+
+	reviewers := &DefaultReviewers{
+		Reviewers: make([]*api.User, len(users)),
+		Teams:     make([]*Team, len(teams)),
+	}
+	for i := range users {
+		reviewers.Reviewers[i] = ToApiUser(users[i])
+	}
+	for i := range teams {
+		reviewers.Teams[i] = ToApiTeam(teams[i])
+	}
+	ctx.JSON(200, reviewers)
+}
+
+// GET /repos/:username/:reponame/default-reviewers
+func GetDefaultReviewers(ctx *middleware.Context) {
+	responseDefaultReviewers(ctx, ctx.Repo.Repository)
+}
+
+// PUT /repos/:username/:reponame/default-reviewers
+func EditDefaultReviewers(ctx *middleware.Context, form EditDefaultReviewersOption) {
+	if !ctx.Repo.IsAdmin() {
+		ctx.APIError(403, "", "Given user does not have admin access to repository.")
+		return
+	}
+
+	users := make([]*models.User, 0, len(form.Reviewers))
+	for _, name := range form.Reviewers {
+		u, err := models.GetUserByName(name)
+		if err != nil {
+			if models.IsErrUserNotExist(err) {
+				ctx.APIError(422, "", "User does not exist: "+name)
+			} else {
+				ctx.APIError(500, "GetUserByName", err)
+			}
+			return
+		}
+		users = append(users, u)
+	}
+
+	owner := ctx.Repo.Owner
+	teams := make([]*models.Team, 0, len(form.Teams))
+	for _, name := range form.Teams {
+		if !owner.IsOrganization() {
+			ctx.APIError(422, "", "Teams can only review pull requests of organization repositories.")
+			return
+		}
+		t, err := owner.GetTeam(name)
+		if err != nil {
+			if err == models.ErrTeamNotExist {
+				ctx.APIError(422, "", "Team does not exist: "+name)
+			} else {
+				ctx.APIError(500, "GetTeam", err)
+			}
+			return
+		}
+		teams = append(teams, t)
+	}
+
+	repo := ctx.Repo.Repository
+	if err := repo.UpdateDefaultReviewers(users, teams); err != nil {
+		if models.IsErrInvalidReviewer(err) {
+			ctx.APIError(422, "", "User or team does not have access to repository: "+err.(models.ErrInvalidReviewer).Name)
+		} else {
+			ctx.APIError(500, "UpdateDefaultReviewers", err)
+		}
+		return
+	}
+	log.Trace("Default reviewers updated[%d]: %s", repo.ID, ctx.User.Name)
+
+	responseDefaultReviewers(ctx, repo)
+}
+
+// Review represents the latest review of a user on a pull request.
+type Review struct {
+	ID       int64     `json:"id"`
+	Reviewer *api.User `json:"user"`
+	State    string    `json:"state"`
+	Body     string    `json:"body"`
+	CommitID string    `json:"commit_id"`
+	Created  time.Time `json:"created_at"`
+	Updated  time.Time `json:"updated_at"`
+}
+
+var reviewStateNames = map[models.ReviewState]string{
+	models.REVIEW_STATE_COMMENT:           "commented",
+	models.REVIEW_STATE_APPROVED:          "approved",
+	models.REVIEW_STATE_CHANGES_REQUESTED: "changes_requested",
+}
+
+// ToApiReview converts review to API format, reviewer must be loaded.
+func ToApiReview(r *models.Review) *Review {
+	return &Review{
+		ID:       r.ID,
+		Reviewer: ToApiUser(r.Reviewer),
+		State:    reviewStateNames[r.State],
+		Body:     r.Content,
+		CommitID: r.CommitID,
+		Created:  r.Created,
+		Updated:  r.Updated,
+	}
+}
+
+// mergeBlockedReason returns the reason why reviews of pull request do not allow merging,
+// or an empty string if they do.
+func mergeBlockedReason(pr *models.PullRequest) (string, error) {
+	return reviewBlockedReason(pr.CheckReviews())
+}
+
+// reviewBlockedReason converts result of checking reviews to the reason why merging is blocked.
+func reviewBlockedReason(err error) (string, error) {
+	switch {
+	case err == nil:
+		return "", nil
+	case models.IsErrPullRequestNotApproved(err):
+		e := err.(models.ErrPullRequestNotApproved)
+		return fmt.Sprintf("Pull request requires %d approving reviews, but has %d.", e.Required, e.Approvals), nil
+	case models.IsErrPullRequestChangesRequested(err):
+		return fmt.Sprintf("Reviewer '%s' requested changes.", err.(models.ErrPullRequestChangesRequested).Reviewer), nil
+	}
+	return "", err
+}
+
+// GET /repos/:username/:reponame/pulls/:index/reviews
+func ListPullReviews(ctx *middleware.Context) {
+	issue := getPullRequestByIndex(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	reviews, err := models.GetReviews(issue.PullRequest.ID)
+	if err != nil {
+		ctx.APIError(500, "GetReviews", err)
+		return
+	}
+
+	apiReviews := make([]*Review, len(reviews))
+	for i := range reviews {
+		apiReviews[i] = ToApiReview(reviews[i])
+	}
+	ctx.JSON(200, &apiReviews)
+}
+
+// CreateReviewOption represents options for submitting a review,
+// event is one of "approve", "request_changes" and "comment".
+type CreateReviewOption struct {
+	Event string `json:"event" binding:"Required"`
+	Body  string `json:"body"`
+}
+
+// POST /repos/:username/:reponame/pulls/:index/reviews
+func CreatePullReview(ctx *middleware.Context, form CreateReviewOption) {
+	var state models.ReviewState
+	switch strings.ToLower(form.Event) {
+	case "approve":
+		state = models.REVIEW_STATE_APPROVED
+	case "request_changes":
+		state = models.REVIEW_STATE_CHANGES_REQUESTED
+	case "comment":
+		state = models.REVIEW_STATE_COMMENT
+	default:
+		ctx.APIError(422, "", fmt.Sprintf("Unsupported review event '%s'.", form.Event))
+		return
+	}
+
+	issue := getPullRequestByIndex(ctx)
+	if ctx.Written() {
+		return
+	}
+	if issue.PosterID == ctx.User.Id {
+		ctx.APIError(422, "", "Cannot review your own pull request.")
+		return
+	} else if issue.IsClosed || issue.PullRequest.HasMerged {
+		ctx.APIError(422, "", "Pull request is already closed.")
+		return
+	}
+
+	r, err := models.SubmitReview(issue.PullRequest, ctx.User, state, strings.TrimSpace(form.Body))
+	if err != nil {
+		ctx.APIError(500, "SubmitReview", err)
+		return
+	}
+	log.Trace("Pull request reviewed[%d]: %s", issue.PullRequest.ID, ctx.User.Name)
+
+	r.Reviewer = ctx.User
+	ctx.JSON(201, ToApiReview(r))
+}
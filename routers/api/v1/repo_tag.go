@@ -0,0 +1,98 @@
+// Copyright 2015 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package v1
+
+import (
+	"time"
+
+	"github.com/gogits/gogs/modules/git"
+	"github.com/gogits/gogs/modules/middleware"
+)
+
+// TAG_PAGING_NUM is the default number of tags returned per page.
+const TAG_PAGING_NUM = 30
+
+// Tagger represents who created an annotated tag.
+type Tagger struct {
+	Name  string    `json:"name"`
+	Email string    `json:"email"`
+	Date  time.Time `json:"date"`
+}
+
+// Tag represents a Git tag in API format,
+// message and tagger are only present for annotated tags.
+type Tag struct {
+	Name    string  `json:"name"`
+	ID      string  `json:"id"`
+	Commit  string  `json:"commit_sha"`
+	Message string  `json:"message,omitempty"`
+	Tagger  *Tagger `json:"tagger,omitempty"`
+}
+
+// ToApiTag converts Git tag to API format.
+func ToApiTag(t *git.Tag) *Tag {
+	apiTag := &Tag{
+		Name:    t.Name,
+		ID:      t.ID.String(),
+		Commit:  t.Object.String(),
+		Message: t.TagMessage,
+	}
+	if t.Tagger != nil {
+		apiTag.Tagger = &Tagger{
+			Name:  t.Tagger.Name,
+			Email: t.Tagger.Email,
+			Date:  t.Tagger.When,
+		}
+	}
+	return apiTag
+}
+
+// GET /repos/:username/:reponame/tags
+func ListRepoTags(ctx *middleware.Context) {
+	apiTags := make([]*Tag, 0, TAG_PAGING_NUM)
+	if ctx.Repo.Repository.IsBare {
+		ctx.JSON(200, &apiTags)
+		return
+	}
+
+	gitRepo, err := git.OpenRepository(ctx.Repo.Repository.RepoPath())
+	if err != nil {
+		ctx.APIError(500, "OpenRepository", err)
+		return
+	}
+	tagNames, err := gitRepo.GetTagsByCreatorDate()
+	if err != nil {
+		ctx.APIError(500, "GetTagsByCreatorDate", err)
+		return
+	}
+
+	page := ctx.QueryInt("page")
+	if page <= 0 {
+		page = 1
+	}
+	limit := ctx.QueryInt("limit")
+	if limit <= 0 {
+		limit = TAG_PAGING_NUM
+	}
+	start := (page - 1) * limit
+	if start >= len(tagNames) {
+		ctx.JSON(200, &apiTags)
+		return
+	}
+	end := start + limit
+	if end > len(tagNames) {
+		end = len(tagNames)
+	}
+
+	for _, name := range tagNames[start:end] {
+		tag, err := gitRepo.GetTag(name)
+		if err != nil {
+			ctx.APIError(500, "GetTag", err)
+			return
+		}
+		apiTags = append(apiTags, ToApiTag(tag))
+	}
+	ctx.JSON(200, &apiTags)
+}
@@ -0,0 +1,75 @@
+// Copyright 2015 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package v1
+
+import (
+	api "github.com/gogits/go-gogs-client"
+
+	"github.com/gogits/gogs/models"
+	"github.com/gogits/gogs/modules/log"
+	"github.com/gogits/gogs/modules/middleware"
+	"github.com/gogits/gogs/modules/setting"
+)
+
+// GenerateRepoOption represents options for generating a repository from template,
+// repository is created under current user when organization is empty.
+type GenerateRepoOption struct {
+	Organization   string `json:"organization"`
+	Name           string `json:"name" binding:"Required;AlphaDashDot;MaxSize(100)"`
+	Description    string `json:"description" binding:"MaxSize(255)"`
+	Private        bool   `json:"private"`
+	FlattenHistory bool   `json:"flatten_history"`
+}
+
+// POST /repos/:username/:reponame/generate
+func GenerateRepo(ctx *middleware.Context, form GenerateRepoOption) {
+	tmpl := ctx.Repo.Repository
+	if !tmpl.IsTemplate {
+		ctx.APIError(422, "", "Repository is not a template.")
+		return
+	}
+
+	ctxUser := ctx.User
+	if len(form.Organization) > 0 {
+		org, err := models.GetOrgByName(form.Organization)
+		if err != nil {
+			if models.IsErrUserNotExist(err) {
+				ctx.APIError(422, "", err)
+			} else {
+				ctx.APIError(500, "GetOrgByName", err)
+			}
+			return
+		}
+
+		// Check ownership of organization.
+		if !org.IsOwnedBy(ctx.User.Id) {
+			ctx.APIError(403, "", "Given user is not owner of organization.")
+			return
+		}
+		ctxUser = org
+	}
+
+	repo, err := models.GenerateRepository(ctxUser, tmpl, models.GenerateRepoOptions{
+		Name:           form.Name,
+		Description:    form.Description,
+		IsPrivate:      form.Private || setting.Repository.ForcePrivate,
+		FlattenHistory: form.FlattenHistory,
+	})
+	if err != nil {
+		if models.IsErrRepoAlreadyExist(err) ||
+			models.IsErrNameReserved(err) ||
+			models.IsErrNamePatternNotAllowed(err) ||
+			models.IsErrNameCharsNotAllowed(err) ||
+			models.IsErrReachLimitOfRepo(err) {
+			ctx.APIError(422, "", err)
+		} else {
+			ctx.APIError(500, "GenerateRepository", err)
+		}
+		return
+	}
+
+	log.Trace("Repository generated[%d]: %s/%s", tmpl.ID, ctxUser.Name, repo.Name)
+	ctx.JSON(201, ToApiRepository(ctxUser, repo, api.Permission{true, true, true}))
+}
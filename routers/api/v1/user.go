@@ -5,11 +5,15 @@
 package v1
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/Unknwon/com"
 
 	api "github.com/gogits/go-gogs-client"
 
 	"github.com/gogits/gogs/models"
+	"github.com/gogits/gogs/modules/base"
 	"github.com/gogits/gogs/modules/middleware"
 )
 
@@ -62,6 +66,8 @@ func SearchUsers(ctx *middleware.Context) {
 }
 
 // GET /users/:username
+// Response has an ETag that changes whenever user is updated,
+// request with matching If-None-Match header gets 304 without content.
 func GetUserInfo(ctx *middleware.Context) {
 	u, err := models.GetUserByName(ctx.Params(":username"))
 	if err != nil {
@@ -77,5 +83,47 @@ func GetUserInfo(ctx *middleware.Context) {
 	if !ctx.IsSigned {
 		u.Email = ""
 	}
+
+	if ctx.CheckETag(base.EncodeMD5(fmt.Sprintf("%d-%d-%v", u.Id, u.Updated.UnixNano(), ctx.IsSigned))) {
+		return
+	}
 	ctx.JSON(200, &api.User{u.Id, u.Name, u.FullName, u.Email, u.AvatarLink()})
 }
+
+// AuthenticatedUser represents profile of user who owns the access token,
+// which includes private information and settings not shown to others.
+type AuthenticatedUser struct {
+	*api.User
+	Location         string    `json:"location"`
+	Website          string    `json:"website"`
+	IsAdmin          bool      `json:"is_admin"`
+	AllowGitHook     bool      `json:"allow_git_hook"`
+	AllowImportLocal bool      `json:"allow_import_local"`
+	PrivateByDefault bool      `json:"private_by_default"`
+	NumRepos         int       `json:"repos_count"`
+	NumFollowers     int       `json:"followers_count"`
+	NumFollowing     int       `json:"following_count"`
+	NumStars         int       `json:"starred_count"`
+	Created          time.Time `json:"created_at"`
+	Updated          time.Time `json:"updated_at"`
+}
+
+// GET /user
+func GetAuthenticatedUser(ctx *middleware.Context) {
+	u := ctx.User
+	ctx.JSON(200, &AuthenticatedUser{
+		User:             ToApiUser(u),
+		Location:         u.Location,
+		Website:          u.Website,
+		IsAdmin:          u.IsAdmin,
+		AllowGitHook:     u.CanEditGitHook(),
+		AllowImportLocal: u.CanImportLocal(),
+		PrivateByDefault: u.LastRepoVisibility,
+		NumRepos:         u.NumRepos,
+		NumFollowers:     u.NumFollowers,
+		NumFollowing:     u.NumFollowings,
+		NumStars:         u.NumStars,
+		Created:          u.Created,
+		Updated:          u.Updated,
+	})
+}
@@ -5,12 +5,36 @@
 package v1
 
 import (
-	api "github.com/gogits/go-gogs-client"
+	"time"
 
 	"github.com/gogits/gogs/models"
 	"github.com/gogits/gogs/modules/middleware"
 )
 
+// AccessToken represents a personal access token in API format,
+// SHA1 is only present when token is just created.
+type AccessToken struct {
+	ID         int64     `json:"id"`
+	Name       string    `json:"name"`
+	Sha1       string    `json:"sha1,omitempty"`
+	Scopes     []string  `json:"scopes"`
+	Created    time.Time `json:"created_at"`
+	LastUsed   time.Time `json:"last_used_at"`
+	LastUsedIP string    `json:"last_used_ip"`
+}
+
+// ToApiAccessToken converts access token to API format without secret.
+func ToApiAccessToken(t *models.AccessToken) *AccessToken {
+	return &AccessToken{
+		ID:         t.ID,
+		Name:       t.Name,
+		Scopes:     t.ScopeList(),
+		Created:    t.Created,
+		LastUsed:   t.LastUsed,
+		LastUsedIP: t.LastUsedIP,
+	}
+}
+
 // GET /users/:username/tokens
 func ListAccessTokens(ctx *middleware.Context) {
 	tokens, err := models.ListAccessTokens(ctx.User.Id)
@@ -19,15 +43,16 @@ func ListAccessTokens(ctx *middleware.Context) {
 		return
 	}
 
-	apiTokens := make([]*api.AccessToken, len(tokens))
+	apiTokens := make([]*AccessToken, len(tokens))
 	for i := range tokens {
-		apiTokens[i] = &api.AccessToken{tokens[i].Name, tokens[i].Sha1}
+		apiTokens[i] = ToApiAccessToken(tokens[i])
 	}
 	ctx.JSON(200, &apiTokens)
 }
 
 type CreateAccessTokenForm struct {
-	Name string `json:"name" binding:"Required"`
+	Name   string   `json:"name" binding:"Required"`
+	Scopes []string `json:"scopes"`
 }
 
 // POST /users/:username/tokens
@@ -36,9 +61,16 @@ func CreateAccessToken(ctx *middleware.Context, form CreateAccessTokenForm) {
 		UID:  ctx.User.Id,
 		Name: form.Name,
 	}
+	if err := t.SetScopes(form.Scopes); err != nil {
+		ctx.APIError(422, "", err)
+		return
+	}
 	if err := models.NewAccessToken(t); err != nil {
 		ctx.APIError(500, "NewAccessToken", err)
 		return
 	}
-	ctx.JSON(201, &api.AccessToken{t.Name, t.Sha1})
+
+	apiToken := ToApiAccessToken(t)
+	apiToken.Sha1 = t.Sha1
+	ctx.JSON(201, apiToken)
 }
@@ -0,0 +1,168 @@
+// Copyright 2015 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package v1
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Unknwon/com"
+
+	"github.com/gogits/gogs/models"
+	"github.com/gogits/gogs/modules/log"
+	"github.com/gogits/gogs/modules/mailer"
+	"github.com/gogits/gogs/modules/middleware"
+	"github.com/gogits/gogs/modules/setting"
+)
+
+// UserExport represents an export job of user data in API format.
+type UserExport struct {
+	ID         int64     `json:"id"`
+	Status     string    `json:"status"`
+	Error      string    `json:"error,omitempty"`
+	Created    time.Time `json:"created_at"`
+	Expires    time.Time `json:"expires_at"`
+	ArchiveURL string    `json:"archive_url,omitempty"`
+}
+
+// ToApiUserExport converts export job to API format.
+func ToApiUserExport(u *models.User, e *models.UserExport) *UserExport {
+	apiExport := &UserExport{
+		ID:      e.ID,
+		Status:  e.Status.Name(),
+		Error:   e.Error,
+		Created: e.Created,
+		Expires: e.Expires,
+	}
+	if e.Status == models.USER_EXPORT_READY {
+		apiExport.ArchiveURL = fmt.Sprintf("%sapi/v1/users/%s/exports/%d/archive", setting.AppUrl, u.Name, e.ID)
+	}
+	return apiExport
+}
+
+// getExportUser returns user given by URL, only the user and site admins
+// are allowed to access exports of the user.
+func getExportUser(ctx *middleware.Context) *models.User {
+	u, err := models.GetUserByName(ctx.Params(":username"))
+	if err != nil {
+		if models.IsErrUserNotExist(err) {
+			ctx.Error(404)
+		} else {
+			ctx.APIError(500, "GetUserByName", err)
+		}
+		return nil
+	}
+
+	if u.Id != ctx.User.Id && !ctx.User.IsAdmin {
+		ctx.APIError(403, "", "Given user is not allowed to export data of this user.")
+		return nil
+	}
+	return u
+}
+
+func getUserExport(ctx *middleware.Context, u *models.User) *models.UserExport {
+	e, err := models.GetUserExportByID(u.Id, ctx.ParamsInt64(":id"))
+	if err != nil {
+		if models.IsErrUserExportNotExist(err) {
+			ctx.Error(404)
+		} else {
+			ctx.APIError(500, "GetUserExportByID", err)
+		}
+		return nil
+	}
+	return e
+}
+
+// POST /users/:username/exports
+func CreateUserExport(ctx *middleware.Context) {
+	u := getExportUser(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	e, err := models.NewUserExport(u, ctx.User)
+	if err != nil {
+		ctx.APIError(500, "NewUserExport", err)
+		return
+	}
+
+	doer := ctx.User
+	var msg *mailer.Message
+	if setting.MailService != nil {
+		if msg, err = mailer.NewUserExportMail(ctx.Render, doer, e); err != nil {
+			ctx.APIError(500, "NewUserExportMail", err)
+			return
+		}
+	}
+
+	// Archive can be large, assemble it in background and notify requester when ready.
+	// Export job is reloaded so it is not shared with the response.
+	go func(uid, id int64) {
+		e, err := models.GetUserExportByID(uid, id)
+		if err != nil {
+			log.Error(4, "GetUserExportByID[%d]: %v", id, err)
+			return
+		}
+		if err = models.ExportUserData(e); err != nil {
+			log.Error(4, "ExportUserData[%d]: %v", e.ID, err)
+			return
+		} else if e.Status == models.USER_EXPORT_READY && msg != nil {
+			mailer.SendAsync(msg)
+		}
+	}(u.Id, e.ID)
+
+	log.Trace("User data export requested[%d]: %s by %s", e.ID, u.Name, doer.Name)
+	ctx.JSON(202, ToApiUserExport(u, e))
+}
+
+// GET /users/:username/exports/:id
+func GetUserExport(ctx *middleware.Context) {
+	u := getExportUser(ctx)
+	if ctx.Written() {
+		return
+	}
+	e := getUserExport(ctx, u)
+	if ctx.Written() {
+		return
+	}
+	ctx.JSON(200, ToApiUserExport(u, e))
+}
+
+// GET /users/:username/exports/:id/archive
+func GetUserExportArchive(ctx *middleware.Context) {
+	u := getExportUser(ctx)
+	if ctx.Written() {
+		return
+	}
+	e := getUserExport(ctx, u)
+	if ctx.Written() {
+		return
+	}
+
+	if e.Status != models.USER_EXPORT_READY || e.IsExpired() || !com.IsFile(e.LocalPath()) {
+		ctx.Error(404)
+		return
+	}
+	ctx.ServeFile(e.LocalPath(), fmt.Sprintf("%s-export-%d.zip", u.Name, e.ID))
+}
+
+// DELETE /users/:username/exports/:id
+func DeleteUserExport(ctx *middleware.Context) {
+	u := getExportUser(ctx)
+	if ctx.Written() {
+		return
+	}
+	e := getUserExport(ctx, u)
+	if ctx.Written() {
+		return
+	}
+
+	if err := models.DeleteUserExport(e); err != nil {
+		ctx.APIError(500, "DeleteUserExport", err)
+		return
+	}
+	log.Trace("User data export deleted[%d]: %s", e.ID, u.Name)
+	ctx.Status(204)
+}
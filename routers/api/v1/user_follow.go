@@ -0,0 +1,120 @@
+// Copyright 2015 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package v1
+
+import (
+	api "github.com/gogits/go-gogs-client"
+
+	"github.com/gogits/gogs/models"
+	"github.com/gogits/gogs/modules/log"
+	"github.com/gogits/gogs/modules/middleware"
+)
+
+// getUserByParams returns user given by URL, it responds 404 if user does not exist.
+func getUserByParams(ctx *middleware.Context) *models.User {
+	u, err := models.GetUserByName(ctx.Params(":username"))
+	if err != nil {
+		if models.IsErrUserNotExist(err) {
+			ctx.Error(404)
+		} else {
+			ctx.APIError(500, "GetUserByName", err)
+		}
+		return nil
+	}
+	return u
+}
+
+func responseApiUsers(ctx *middleware.Context, users []*models.User) {
+	apiUsers := make([]*api.User, len(users))
+	for i := range users {
+		apiUsers[i] = ToApiUser(users[i])
+	}
+	ctx.JSON(200, &apiUsers)
+}
+
+// GET /users/:username/followers
+func ListFollowers(ctx *middleware.Context) {
+	u := getUserByParams(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	page := ctx.QueryInt("page")
+	if page <= 0 {
+		page = 1
+	}
+	users, err := u.GetFollowers(page)
+	if err != nil {
+		ctx.APIError(500, "GetFollowers", err)
+		return
+	}
+	responseApiUsers(ctx, users)
+}
+
+// GET /users/:username/following
+func ListFollowing(ctx *middleware.Context) {
+	u := getUserByParams(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	page := ctx.QueryInt("page")
+	if page <= 0 {
+		page = 1
+	}
+	users, err := u.GetFollowing(page)
+	if err != nil {
+		ctx.APIError(500, "GetFollowing", err)
+		return
+	}
+	responseApiUsers(ctx, users)
+}
+
+// GET /user/following/:username
+func CheckMyFollowing(ctx *middleware.Context) {
+	u := getUserByParams(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	if models.IsFollowing(ctx.User.Id, u.Id) {
+		ctx.Status(204)
+	} else {
+		ctx.Error(404)
+	}
+}
+
+// PUT /user/following/:username
+func Follow(ctx *middleware.Context) {
+	u := getUserByParams(ctx)
+	if ctx.Written() {
+		return
+	} else if u.Id == ctx.User.Id {
+		ctx.APIError(422, "", "Cannot follow yourself.")
+		return
+	}
+
+	if err := models.FollowUser(ctx.User.Id, u.Id); err != nil {
+		ctx.APIError(500, "FollowUser", err)
+		return
+	}
+	log.Trace("User followed[%s]: %s", u.Name, ctx.User.Name)
+	ctx.Status(204)
+}
+
+// DELETE /user/following/:username
+func Unfollow(ctx *middleware.Context) {
+	u := getUserByParams(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	if err := models.UnFollowUser(ctx.User.Id, u.Id); err != nil {
+		ctx.APIError(500, "UnFollowUser", err)
+		return
+	}
+	log.Trace("User unfollowed[%s]: %s", u.Name, ctx.User.Name)
+	ctx.Status(204)
+}
@@ -0,0 +1,65 @@
+// Copyright 2015 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package v1
+
+import (
+	"time"
+
+	"github.com/gogits/gogs/models"
+	"github.com/gogits/gogs/modules/log"
+	"github.com/gogits/gogs/modules/middleware"
+)
+
+// OAuth2Authorization represents an OAuth2 application authorized by user in API format.
+type OAuth2Authorization struct {
+	ID              int64     `json:"id"`
+	ApplicationName string    `json:"application_name"`
+	ClientID        string    `json:"client_id"`
+	Scopes          string    `json:"scopes"`
+	Created         time.Time `json:"created_at"`
+	Updated         time.Time `json:"updated_at"`
+}
+
+// ToApiOAuth2Authorization converts OAuth2 grant to API format.
+func ToApiOAuth2Authorization(g *models.OAuth2Grant) *OAuth2Authorization {
+	return &OAuth2Authorization{
+		ID:              g.ID,
+		ApplicationName: g.App.Name,
+		ClientID:        g.App.ClientID,
+		Scopes:          g.Scopes,
+		Created:         g.Created,
+		Updated:         g.Updated,
+	}
+}
+
+// GET /user/oauth2/authorizations
+func ListMyOAuth2Authorizations(ctx *middleware.Context) {
+	grants, err := models.ListOAuth2Grants(ctx.User.Id)
+	if err != nil {
+		ctx.APIError(500, "ListOAuth2Grants", err)
+		return
+	}
+
+	apiGrants := make([]*OAuth2Authorization, len(grants))
+	for i := range grants {
+		apiGrants[i] = ToApiOAuth2Authorization(grants[i])
+	}
+	ctx.JSON(200, &apiGrants)
+}
+
+// DELETE /user/oauth2/authorizations/:id
+func RevokeMyOAuth2Authorization(ctx *middleware.Context) {
+	if err := models.RevokeOAuth2Grant(ctx.User.Id, ctx.ParamsInt64(":id")); err != nil {
+		if models.IsErrOAuth2GrantNotExist(err) {
+			ctx.Error(404)
+		} else {
+			ctx.APIError(500, "RevokeOAuth2Grant", err)
+		}
+		return
+	}
+	log.Trace("OAuth2 authorization revoked[%d]: %s", ctx.ParamsInt64(":id"), ctx.User.Name)
+
+	ctx.Status(204)
+}
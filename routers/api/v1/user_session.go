@@ -0,0 +1,78 @@
+// Copyright 2015 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package v1
+
+import (
+	"time"
+
+	"github.com/gogits/gogs/models"
+	"github.com/gogits/gogs/modules/log"
+	"github.com/gogits/gogs/modules/middleware"
+)
+
+// UserSession represents a signed in session of user in API format.
+type UserSession struct {
+	ID        int64     `json:"id"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent"`
+	Current   bool      `json:"current"`
+	Created   time.Time `json:"created_at"`
+	LastSeen  time.Time `json:"last_seen_at"`
+}
+
+// ToApiUserSession converts session to API format.
+func ToApiUserSession(s *models.UserSession, currentSID string) *UserSession {
+	return &UserSession{
+		ID:        s.ID,
+		IP:        s.IP,
+		UserAgent: s.UserAgent,
+		Current:   s.SID == currentSID,
+		Created:   s.Created,
+		LastSeen:  s.LastSeen,
+	}
+}
+
+// GET /user/sessions
+func ListMySessions(ctx *middleware.Context) {
+	sessions, err := models.ListUserSessions(ctx.User.Id)
+	if err != nil {
+		ctx.APIError(500, "ListUserSessions", err)
+		return
+	}
+
+	apiSessions := make([]*UserSession, len(sessions))
+	for i := range sessions {
+		apiSessions[i] = ToApiUserSession(sessions[i], ctx.Session.ID())
+	}
+	ctx.JSON(200, &apiSessions)
+}
+
+// DELETE /user/sessions/:id
+func RevokeMySession(ctx *middleware.Context) {
+	if err := models.RevokeUserSession(ctx.User.Id, ctx.ParamsInt64(":id")); err != nil {
+		if models.IsErrUserSessionNotExist(err) {
+			ctx.Error(404)
+		} else {
+			ctx.APIError(500, "RevokeUserSession", err)
+		}
+		return
+	}
+	log.Trace("Session revoked[%d]: %s", ctx.ParamsInt64(":id"), ctx.User.Name)
+
+	ctx.Status(204)
+}
+
+// DELETE /user/sessions
+// It revokes all sessions except the one of current request,
+// which means all sessions when request is authenticated by access token.
+func RevokeMyOtherSessions(ctx *middleware.Context) {
+	if err := models.RevokeOtherUserSessions(ctx.User.Id, ctx.Session.ID()); err != nil {
+		ctx.APIError(500, "RevokeOtherUserSessions", err)
+		return
+	}
+	log.Trace("Other sessions revoked: %s", ctx.User.Name)
+
+	ctx.Status(204)
+}
@@ -23,6 +23,7 @@ import (
 	"github.com/gogits/gogs/models/cron"
 	"github.com/gogits/gogs/modules/auth"
 	"github.com/gogits/gogs/modules/base"
+	"github.com/gogits/gogs/modules/highlight"
 	"github.com/gogits/gogs/modules/log"
 	"github.com/gogits/gogs/modules/mailer"
 	"github.com/gogits/gogs/modules/middleware"
@@ -57,6 +58,7 @@ func GlobalInit() {
 	log.Trace("Custom path: %s", setting.CustomPath)
 	log.Trace("Log path: %s", setting.LogRootPath)
 	models.LoadConfigs()
+	highlight.NewContext()
 	NewServices()
 
 	if setting.InstallLock {
@@ -82,8 +84,8 @@ func GlobalInit() {
 	checkRunMode()
 
 	if setting.StartSSHServer {
-		ssh.Listen(setting.SSHPort)
-		log.Info("SSH server started on :%v", setting.SSHPort)
+		ssh.Listen(setting.SSHListenPort)
+		log.Info("SSH server started on :%v", setting.SSHListenPort)
 	}
 }
 
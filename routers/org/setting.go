@@ -12,6 +12,7 @@ import (
 	"github.com/gogits/gogs/models"
 	"github.com/gogits/gogs/modules/auth"
 	"github.com/gogits/gogs/modules/base"
+	"github.com/gogits/gogs/modules/git"
 	"github.com/gogits/gogs/modules/log"
 	"github.com/gogits/gogs/modules/middleware"
 	"github.com/gogits/gogs/modules/setting"
@@ -22,6 +23,9 @@ const (
 	SETTINGS_OPTIONS base.TplName = "org/settings/options"
 	SETTINGS_DELETE  base.TplName = "org/settings/delete"
 	SETTINGS_HOOKS   base.TplName = "org/settings/hooks"
+
+	SETTINGS_REPO_DEFAULTS   base.TplName = "org/settings/repo_defaults"
+	SETTINGS_MEMBER_DEFAULTS base.TplName = "org/settings/member_defaults"
 )
 
 func Settings(ctx *middleware.Context) {
@@ -90,6 +94,106 @@ func SettingsAvatar(ctx *middleware.Context, form auth.UploadAvatarForm) {
 	ctx.Redirect(ctx.Org.OrgLink + "/settings")
 }
 
+func RepoDefaults(ctx *middleware.Context) {
+	ctx.Data["Title"] = ctx.Tr("org.settings")
+	ctx.Data["PageIsSettingsRepoDefaults"] = true
+
+	defaults, err := models.GetOrgRepoDefaults(ctx.Org.Organization.Id)
+	if err != nil {
+		ctx.Handle(500, "GetOrgRepoDefaults", err)
+		return
+	}
+	ctx.Data["default_branch"] = defaults.DefaultBranch
+	ctx.Data["private"] = defaults.IsPrivate
+	ctx.Data["enable_issues"] = defaults.EnableIssues
+	ctx.Data["enable_wiki"] = defaults.EnableWiki
+	ctx.Data["labels"] = defaults.Labels
+	ctx.Data["DefaultBranch"] = setting.Repository.DefaultBranch
+	ctx.HTML(200, SETTINGS_REPO_DEFAULTS)
+}
+
+func RepoDefaultsPost(ctx *middleware.Context, form auth.OrgRepoDefaultsForm) {
+	ctx.Data["Title"] = ctx.Tr("org.settings")
+	ctx.Data["PageIsSettingsRepoDefaults"] = true
+	ctx.Data["DefaultBranch"] = setting.Repository.DefaultBranch
+
+	if ctx.HasError() {
+		ctx.HTML(200, SETTINGS_REPO_DEFAULTS)
+		return
+	}
+
+	defaults, err := models.GetOrgRepoDefaults(ctx.Org.Organization.Id)
+	if err != nil {
+		ctx.Handle(500, "GetOrgRepoDefaults", err)
+		return
+	}
+
+	defaults.DefaultBranch = strings.TrimSpace(form.DefaultBranch)
+	if len(defaults.DefaultBranch) > 0 && !git.IsValidBranchName(defaults.DefaultBranch) {
+		ctx.Data["Err_DefaultBranch"] = true
+		ctx.RenderWithErr(ctx.Tr("repo.form.invalid_default_branch", defaults.DefaultBranch), SETTINGS_REPO_DEFAULTS, &form)
+		return
+	}
+	defaults.IsPrivate = form.Private
+	defaults.EnableIssues = form.EnableIssues
+	defaults.EnableWiki = form.EnableWiki
+	defaults.Labels = strings.TrimSpace(form.Labels)
+
+	if err = models.UpdateOrgRepoDefaults(defaults); err != nil {
+		if models.IsErrInvalidDefaultLabel(err) {
+			ctx.Data["Err_Labels"] = true
+			ctx.RenderWithErr(ctx.Tr("org.settings.repo_defaults_invalid_label", err.(models.ErrInvalidDefaultLabel).Line), SETTINGS_REPO_DEFAULTS, &form)
+		} else {
+			ctx.Handle(500, "UpdateOrgRepoDefaults", err)
+		}
+		return
+	}
+	log.Trace("Organization repository defaults updated: %s", ctx.Org.Organization.Name)
+
+	ctx.Flash.Success(ctx.Tr("org.settings.update_repo_defaults_success"))
+	ctx.Redirect(ctx.Org.OrgLink + "/settings/repo_defaults")
+}
+
+func MemberDefaults(ctx *middleware.Context) {
+	ctx.Data["Title"] = ctx.Tr("org.settings")
+	ctx.Data["PageIsSettingsMemberDefaults"] = true
+
+	if err := ctx.Org.Organization.GetTeams(); err != nil {
+		ctx.Handle(500, "GetTeams", err)
+		return
+	}
+	ctx.Data["Teams"] = ctx.Org.Organization.Teams
+	ctx.HTML(200, SETTINGS_MEMBER_DEFAULTS)
+}
+
+func MemberDefaultsPost(ctx *middleware.Context, form auth.OrgMemberDefaultsForm) {
+	org := ctx.Org.Organization
+
+	if form.DefaultTeamID > 0 {
+		t, err := models.GetTeamById(form.DefaultTeamID)
+		if err != nil && err != models.ErrTeamNotExist {
+			ctx.Handle(500, "GetTeamById", err)
+			return
+		} else if err != nil || t.OrgID != org.Id || t.IsOwnerTeam() {
+			ctx.Flash.Error(ctx.Tr("org.settings.member_defaults_invalid_team"))
+			ctx.Redirect(ctx.Org.OrgLink + "/settings/member_defaults")
+			return
+		}
+	}
+
+	org.DefaultMemberPublic = form.DefaultMemberPublic
+	org.DefaultTeamID = form.DefaultTeamID
+	org.MembersCanCreateRepo = form.MembersCanCreateRepo
+	if err := models.UpdateUser(org); err != nil {
+		ctx.Handle(500, "UpdateUser", err)
+		return
+	}
+	log.Trace("Organization member defaults updated: %s", org.Name)
+
+	ctx.Flash.Success(ctx.Tr("org.settings.update_member_defaults_success"))
+	ctx.Redirect(ctx.Org.OrgLink + "/settings/member_defaults")
+}
+
 func SettingsDelete(ctx *middleware.Context) {
 	ctx.Data["Title"] = ctx.Tr("org.settings")
 	ctx.Data["PageIsSettingsDelete"] = true
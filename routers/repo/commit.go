@@ -8,6 +8,7 @@ import (
 	"container/list"
 	"path"
 
+	"github.com/Unknwon/com"
 	"github.com/Unknwon/paginater"
 
 	"github.com/gogits/gogs/models"
@@ -142,16 +143,48 @@ func FileHistory(ctx *middleware.Context) {
 	ctx.HTML(200, COMMITS)
 }
 
+// DIFF_WHITESPACE_COOKIE is the name of cookie that remembers how user wants whitespace changes to be shown.
+const DIFF_WHITESPACE_COOKIE = "diff_whitespace"
+
+// getDiffWhitespace returns how whitespace changes should be treated in diff,
+// preference given by query parameters "w" or "ignore-eol" is remembered in cookie.
+func getDiffWhitespace(ctx *middleware.Context) models.DiffWhitespace {
+	query := ctx.Req.URL.Query()
+	_, hasW := query["w"]
+	_, hasEOL := query["ignore-eol"]
+
+	var whitespace models.DiffWhitespace
+	if hasW || hasEOL {
+		switch {
+		case ctx.Query("w") == "1":
+			whitespace = models.DIFF_WHITESPACE_IGNORE_ALL
+		case ctx.Query("ignore-eol") == "1":
+			whitespace = models.DIFF_WHITESPACE_IGNORE_EOL
+		}
+		ctx.SetCookie(DIFF_WHITESPACE_COOKIE, com.ToStr(int(whitespace)), 0, setting.AppSubUrl)
+	} else {
+		whitespace = models.DiffWhitespace(com.StrTo(ctx.GetCookie(DIFF_WHITESPACE_COOKIE)).MustInt())
+		if whitespace < models.DIFF_WHITESPACE_SHOW || whitespace > models.DIFF_WHITESPACE_IGNORE_EOL {
+			whitespace = models.DIFF_WHITESPACE_SHOW
+		}
+	}
+
+	ctx.Data["DiffIgnoreAllWhitespace"] = whitespace == models.DIFF_WHITESPACE_IGNORE_ALL
+	ctx.Data["DiffIgnoreEOLWhitespace"] = whitespace == models.DIFF_WHITESPACE_IGNORE_EOL
+	return whitespace
+}
+
 func Diff(ctx *middleware.Context) {
 	ctx.Data["PageIsDiff"] = true
+	ctx.Data["RequireHighlightJS"] = true
 
 	userName := ctx.Repo.Owner.Name
 	repoName := ctx.Repo.Repository.Name
 	commitID := ctx.Repo.CommitID
 
 	commit := ctx.Repo.Commit
-	diff, err := models.GetDiffCommit(models.RepoPath(userName, repoName),
-		commitID, setting.Git.MaxGitDiffLines)
+	diff, err := models.GetDiffCommitWithWhitespace(models.RepoPath(userName, repoName),
+		commitID, models.DefaultDiffLimits(), getDiffWhitespace(ctx))
 	if err != nil {
 		ctx.Handle(404, "GetDiffCommit", err)
 		return
@@ -187,6 +220,7 @@ func Diff(ctx *middleware.Context) {
 func CompareDiff(ctx *middleware.Context) {
 	ctx.Data["IsRepoToolbarCommits"] = true
 	ctx.Data["IsDiffCompare"] = true
+	ctx.Data["RequireHighlightJS"] = true
 	userName := ctx.Repo.Owner.Name
 	repoName := ctx.Repo.Repository.Name
 	beforeCommitID := ctx.Params(":before")
@@ -198,8 +232,8 @@ func CompareDiff(ctx *middleware.Context) {
 		return
 	}
 
-	diff, err := models.GetDiffRange(models.RepoPath(userName, repoName), beforeCommitID,
-		afterCommitID, setting.Git.MaxGitDiffLines)
+	diff, err := models.GetDiffRangeWithWhitespace(models.RepoPath(userName, repoName), beforeCommitID,
+		afterCommitID, models.DefaultDiffLimits(), getDiffWhitespace(ctx))
 	if err != nil {
 		ctx.Handle(404, "GetDiffRange", err)
 		return
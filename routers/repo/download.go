@@ -6,13 +6,18 @@ package repo
 
 import (
 	"io"
+	"net/http"
+	"os"
 	"path"
+	"time"
 
 	"github.com/gogits/gogs/modules/base"
 	"github.com/gogits/gogs/modules/git"
 	"github.com/gogits/gogs/modules/middleware"
 )
 
+// ServeData writes content of reader to response, partial content is served
+// for range requests when reader is seekable, e.g. a local file.
 func ServeData(ctx *middleware.Context, name string, reader io.Reader) error {
 	buf := make([]byte, 1024)
 	n, _ := reader.Read(buf)
@@ -21,13 +26,26 @@ func ServeData(ctx *middleware.Context, name string, reader io.Reader) error {
 	}
 
 	_, isTextFile := base.IsTextFile(buf)
-	if ! isTextFile {
+	if !isTextFile {
 		_, isImageFile := base.IsImageFile(buf)
 		if !isImageFile {
-			ctx.Resp.Header().Set("Content-Disposition", "attachment; filename="+path.Base(ctx.Repo.TreeName))
+			ctx.Resp.Header().Set("Content-Disposition", "attachment; filename="+path.Base(name))
 			ctx.Resp.Header().Set("Content-Transfer-Encoding", "binary")
 		}
 	}
+
+	if rs, ok := reader.(io.ReadSeeker); ok {
+		if _, err := rs.Seek(0, os.SEEK_SET); err != nil {
+			return err
+		}
+		// Content type is detected from content because name could be quoted.
+		if len(ctx.Resp.Header().Get("Content-Type")) == 0 {
+			ctx.Resp.Header().Set("Content-Type", http.DetectContentType(buf))
+		}
+		http.ServeContent(ctx.Resp, ctx.Req.Request, name, time.Time{}, rs)
+		return nil
+	}
+
 	ctx.Resp.Write(buf)
 	_, err := io.Copy(ctx.Resp, reader)
 	return err
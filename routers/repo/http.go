@@ -21,8 +21,11 @@ import (
 	"strings"
 	"time"
 
+	"github.com/Unknwon/com"
+
 	"github.com/gogits/gogs/models"
 	"github.com/gogits/gogs/modules/base"
+	"github.com/gogits/gogs/modules/git"
 	"github.com/gogits/gogs/modules/log"
 	"github.com/gogits/gogs/modules/middleware"
 	"github.com/gogits/gogs/modules/setting"
@@ -56,15 +59,23 @@ func HTTP(ctx *middleware.Context) {
 		reponame = reponame[:len(reponame)-5]
 	}
 
-	repoUser, err := models.GetUserByName(username)
+	// Former name of owner is resolved directly instead of redirecting,
+	// because not all Git clients follow redirects.
+	repoUser, err := models.GetUserByNameOrRedirect(username)
 	if err != nil {
 		if models.IsErrUserNotExist(err) {
-			ctx.Handle(404, "GetUserByName", nil)
+			ctx.Handle(404, "GetUserByNameOrRedirect", nil)
 		} else {
-			ctx.Handle(500, "GetUserByName", err)
+			ctx.Handle(500, "GetUserByNameOrRedirect", err)
 		}
 		return
 	}
+	// Request path is used to locate repository, which is stored under current name of owner.
+	if repoUser.LowerName != strings.ToLower(username) {
+		ctx.Req.URL.Path = strings.Replace(strings.ToLower(ctx.Req.URL.Path),
+			"/"+strings.ToLower(username)+"/", "/"+repoUser.LowerName+"/", 1)
+		username = repoUser.LowerName
+	}
 
 	repo, err := models.GetRepositoryByName(repoUser.Id, reponame)
 	if err != nil {
@@ -125,9 +136,19 @@ func HTTP(ctx *middleware.Context) {
 				}
 				return
 			}
-			token.Updated = time.Now()
-			if err = models.UpdateAccessToekn(token); err != nil {
-				ctx.Handle(500, "UpdateAccessToekn", err)
+
+			// Token with limited scopes must be allowed to perform the operation.
+			scope := models.ACCESS_TOKEN_SCOPE_REPO_WRITE
+			if isPull {
+				scope = models.ACCESS_TOKEN_SCOPE_REPO_READ
+			}
+			if !token.HasScope(scope) {
+				ctx.HandleText(403, "access token does not have required scope: "+scope)
+				return
+			}
+
+			if err = token.UpdateLastUsed(ctx.RemoteIP); err != nil {
+				log.Error(4, "UpdateLastUsed: %v", err)
 			}
 			authUser, err = models.GetUserByID(token.UID)
 			if err != nil {
@@ -135,6 +156,18 @@ func HTTP(ctx *middleware.Context) {
 				return
 			}
 			authUsername = authUser.Name
+		} else if has, err := models.HasSecurityKeys(authUser.Id); err != nil {
+			ctx.Handle(500, "HasSecurityKeys", err)
+			return
+		} else if has {
+			// Password alone cannot pass second factor.
+			ctx.HandleText(401, "access token is required for users with security keys")
+			return
+		}
+
+		if authUser.NeedApproval {
+			ctx.HandleText(403, "account is waiting for approval")
+			return
 		}
 
 		if !isPublicPull {
@@ -164,6 +197,28 @@ func HTTP(ctx *middleware.Context) {
 				ctx.HandleText(401, "mirror repository is read-only")
 				return
 			}
+
+			if !isPull && setting.MaintenanceMode && !authUser.IsAdmin {
+				ctx.HandleText(503, "site is in maintenance mode and read-only")
+				return
+			}
+
+			if !isPull && repo.IsArchived {
+				ctx.HandleText(403, "archived repository is read-only")
+				return
+			}
+
+			if !isPull && !isWiki {
+				exceeded, err := repoUser.IsStorageQuotaExceeded()
+				if err != nil {
+					ctx.Handle(500, "IsStorageQuotaExceeded", err)
+					return
+				} else if exceeded {
+					ctx.HandleText(403, fmt.Sprintf("storage quota of %s (%s) has been reached",
+						repoUser.Name, base.FileSize(repoUser.StorageQuotaSize())))
+					return
+				}
+			}
 		}
 	}
 
@@ -212,23 +267,55 @@ func HTTP(ctx *middleware.Context) {
 		}
 	}
 
+	var maxPushSize int64
+	if !isPull {
+		maxPushSize = repo.MaxPushBodySize()
+	}
+
+	// Let update hook know who is pushing to enforce protected tags.
+	var env []string
+	if !isPull && !isWiki {
+		env = append(env, models.ENV_REPO_ID+"="+com.ToStr(repo.ID))
+		if authUser != nil {
+			env = append(env, models.ENV_AUTH_USER_ID+"="+com.ToStr(authUser.Id))
+		}
+	}
+
 	HTTPBackend(&Config{
-		RepoRootPath: setting.RepoRootPath,
-		GitBinPath:   "git",
-		UploadPack:   true,
-		ReceivePack:  true,
-		OnSucceed:    callback,
+		RepoRootPath:    setting.RepoRootPath,
+		GitBinPath:      git.BinPath(),
+		UploadPack:      true,
+		ReceivePack:     true,
+		MaxFetchObjects: setting.Git.MaxFetchObjects,
+		MaxPushSize:     maxPushSize,
+		ProtocolV2:      !setting.Git.DisableProtocolV2,
+		Env:             env,
+		OnSucceed:       callback,
 	})(ctx.Resp, ctx.Req.Request)
 
 	runtime.GC()
 }
 
+// MAX_UPLOAD_PACK_REQUEST_SIZE is the maximum size of request body of upload-pack after decompression,
+// which only contains negotiation and is read into memory.
+const MAX_UPLOAD_PACK_REQUEST_SIZE = 10 << 20
+
 type Config struct {
 	RepoRootPath string
 	GitBinPath   string
 	UploadPack   bool
 	ReceivePack  bool
-	OnSucceed    func(rpc string, input []byte)
+	// MaxFetchObjects limits number of objects a single fetch can pull,
+	// 0 means no limit.
+	MaxFetchObjects int64
+	// MaxPushSize limits size of request body of a single receive-pack request in bytes,
+	// 0 means no limit.
+	MaxPushSize int64
+	// ProtocolV2 indicates whether clients are allowed to use Git protocol version 2.
+	ProtocolV2 bool
+	// Env is additional environment variables passed to Git commands serving RPC.
+	Env       []string
+	OnSucceed func(rpc string, input []byte)
 }
 
 type handler struct {
@@ -331,6 +418,13 @@ func serviceRpc(rpc string, hr handler) {
 
 	w.Header().Set("Content-Type", fmt.Sprintf("application/x-git-%s-result", rpc))
 
+	// Reject oversized push as early as possible when client tells the size.
+	checkPushSize := rpc == "receive-pack" && hr.Config.MaxPushSize > 0
+	if checkPushSize && r.ContentLength > hr.Config.MaxPushSize {
+		renderPushTooLarge(hr)
+		return
+	}
+
 	var (
 		reqBody = r.Body
 		input   []byte
@@ -348,8 +442,17 @@ func serviceRpc(rpc string, hr handler) {
 		}
 	}
 
-	if hr.Config.OnSucceed != nil {
-		input, err = ioutil.ReadAll(reqBody)
+	// Upload-pack request is always read to tell which round of fetch it is.
+	checkFetchLimit := rpc == "upload-pack" && hr.Config.MaxFetchObjects > 0
+	if hr.Config.OnSucceed != nil || rpc == "upload-pack" || checkPushSize {
+		// Read one more byte than the limit to tell if it is exceeded.
+		var body io.Reader = reqBody
+		if checkPushSize {
+			body = io.LimitReader(reqBody, hr.Config.MaxPushSize+1)
+		} else if rpc == "upload-pack" {
+			body = io.LimitReader(reqBody, MAX_UPLOAD_PACK_REQUEST_SIZE+1)
+		}
+		input, err = ioutil.ReadAll(body)
 		if err != nil {
 			log.GitLogger.Error(2, "fail to read request body: %v", err)
 			w.WriteHeader(http.StatusInternalServerError)
@@ -360,10 +463,55 @@ func serviceRpc(rpc string, hr handler) {
 		br = reqBody
 	}
 
+	if checkPushSize && int64(len(input)) > hr.Config.MaxPushSize {
+		renderPushTooLarge(hr)
+		return
+	}
+
+	if rpc == "upload-pack" && len(input) > MAX_UPLOAD_PACK_REQUEST_SIZE {
+		log.GitLogger.Warn("fetch from '%s' rejected: request body exceeds the limit of %d bytes", dir, MAX_UPLOAD_PACK_REQUEST_SIZE)
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	// Request is parsed once for both fetch limit and statistics. Malformed request
+	// is left to Git to respond unless objects have to be counted, which only
+	// happens when fetch is limited.
+	var uploadPackReq *git.UploadPackRequest
+	if rpc == "upload-pack" {
+		uploadPackReq, err = git.ParseUploadPackRequest(input)
+		if err != nil && checkFetchLimit {
+			log.GitLogger.Error(2, "fail to parse upload-pack request: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+	if checkFetchLimit && !isFetchWithinLimit(hr, uploadPackReq) {
+		return
+	}
+
+	// Stateless fetch takes several requests, so it is only counted by the round
+	// that sends the pack: the one with done, or a protocol version 2 fetch
+	// whose response contains the pack because server has found it ready.
+	var (
+		stdout       io.Writer = w
+		isFinalRound bool
+		detector     *packfileDetector
+	)
+	if req := uploadPackReq; req != nil && len(req.Wants) > 0 && (len(req.Command) == 0 || req.Command == "fetch") {
+		if req.Done {
+			isFinalRound = true
+		} else if req.Command == "fetch" {
+			detector = &packfileDetector{Writer: w}
+			stdout = detector
+		}
+	}
+
 	args := []string{rpc, "--stateless-rpc", dir}
 	cmd := exec.Command(hr.Config.GitBinPath, args...)
 	cmd.Dir = dir
-	cmd.Stdout = w
+	cmd.Env = append(gitProtocolEnv(hr), hr.Config.Env...)
+	cmd.Stdout = stdout
 	cmd.Stdin = br
 
 	if err := cmd.Run(); err != nil {
@@ -372,11 +520,97 @@ func serviceRpc(rpc string, hr handler) {
 		return
 	}
 
+	if rpc == "receive-pack" {
+		models.IncreaseGitPushes()
+	} else if isFinalRound || (detector != nil && detector.Found) {
+		models.IncreaseGitClones()
+	}
+
 	if hr.Config.OnSucceed != nil {
 		hr.Config.OnSucceed(rpc, input)
 	}
 }
 
+// isFetchWithinLimit checks if objects requested by an upload-pack request
+// exceed the configured limit, and responds with an error to client if so.
+// Every round of negotiation is checked because server may send pack
+// before client says done.
+func isFetchWithinLimit(hr handler, req *git.UploadPackRequest) bool {
+	if len(req.Wants) == 0 {
+		return true
+	}
+
+	gitRepo, err := git.OpenRepository(hr.Dir)
+	if err != nil {
+		log.GitLogger.Error(2, "fail to open repository(%s): %v", hr.Dir, err)
+		hr.w.WriteHeader(http.StatusInternalServerError)
+		return false
+	}
+
+	var count int64
+	switch {
+	case req.Depth == 1 && len(req.DeepenSince) == 0 && len(req.DeepenNot) == 0:
+		count, err = gitRepo.CountTipObjects(req.Wants)
+	case req.IsShallow() && len(req.Shallows) > 0:
+		// Shallow client lacks history behind what it has, so deepening
+		// is counted as if client had nothing.
+		count, err = gitRepo.CountFetchObjects(req.Wants, nil)
+	default:
+		// Objects within other depths cannot be counted cheaply,
+		// so the whole history is counted.
+		count, err = gitRepo.CountFetchObjects(req.Wants, req.Haves)
+	}
+	if err != nil {
+		log.GitLogger.Error(2, "fail to count fetch objects(%s): %v", hr.Dir, err)
+		hr.w.WriteHeader(http.StatusInternalServerError)
+		return false
+	}
+
+	if count > hr.Config.MaxFetchObjects {
+		hr.w.WriteHeader(http.StatusOK)
+		hr.w.Write(packetWrite(fmt.Sprintf("ERR fetch of %d objects exceeds the limit of %d objects, please try a shallow clone with --depth=1\n",
+			count, hr.Config.MaxFetchObjects)))
+		return false
+	}
+	return true
+}
+
+// packfileSection is the section header of protocol version 2 fetch response that precedes the pack.
+var packfileSection = packetWrite("packfile\n")
+
+// packfileDetector passes response of protocol version 2 fetch through to its writer
+// and detects whether it contains the packfile section.
+type packfileDetector struct {
+	io.Writer
+	Found bool
+	tail  []byte
+}
+
+func (d *packfileDetector) Write(p []byte) (int, error) {
+	// Section header may be split across writes, so end of previous write is kept.
+	// Pack data comes after the header, so scanning stops once it is found.
+	if !d.Found {
+		buf := append(d.tail, p...)
+		if bytes.Contains(buf, packfileSection) {
+			d.Found = true
+			d.tail = nil
+		} else if len(buf) >= len(packfileSection) {
+			d.tail = append([]byte(nil), buf[len(buf)-len(packfileSection)+1:]...)
+		} else {
+			d.tail = buf
+		}
+	}
+	return d.Writer.Write(p)
+}
+
+// renderPushTooLarge responds to client that the push exceeds the size limit.
+func renderPushTooLarge(hr handler) {
+	log.GitLogger.Warn("push to '%s' rejected: request body exceeds the limit of %d bytes", hr.Dir, hr.Config.MaxPushSize)
+	hr.w.WriteHeader(http.StatusOK)
+	hr.w.Write(packetWrite(fmt.Sprintf("ERR push exceeds the maximum size of %s, please push in smaller parts\n",
+		base.FileSize(hr.Config.MaxPushSize))))
+}
+
 func getInfoRefs(hr handler) {
 	w, r, dir := hr.w, hr.r, hr.Dir
 	serviceName := getServiceType(r)
@@ -384,7 +618,7 @@ func getInfoRefs(hr handler) {
 
 	if access {
 		args := []string{serviceName, "--stateless-rpc", "--advertise-refs", "."}
-		refs := gitCommand(hr.Config.GitBinPath, dir, args...)
+		refs := gitCommandWithEnv(hr.Config.GitBinPath, dir, gitProtocolEnv(hr), args...)
 
 		hdrNocache(w)
 		w.Header().Set("Content-Type", fmt.Sprintf("application/x-git-%s-advertisement", serviceName))
@@ -399,6 +633,18 @@ func getInfoRefs(hr handler) {
 	}
 }
 
+// gitProtocolEnv returns environment variables for Git commands serving the request,
+// protocol parameters sent by client are only forwarded when version 2 is allowed.
+// Older Git versions ignore these parameters and fall back to version 0.
+func gitProtocolEnv(hr handler) []string {
+	env := git.Env()
+	params := hr.r.Header.Get("Git-Protocol")
+	if hr.Config.ProtocolV2 && git.IsProtocolV2(params) {
+		env = append(env, git.ENV_GIT_PROTOCOL+"="+params)
+	}
+	return env
+}
+
 func getInfoPacks(hr handler) {
 	hdrCacheForever(hr.w)
 	sendFile("text/plain; charset=utf-8", hr)
@@ -497,8 +743,13 @@ func updateServerInfo(gitBinPath, dir string) []byte {
 }
 
 func gitCommand(gitBinPath, dir string, args ...string) []byte {
+	return gitCommandWithEnv(gitBinPath, dir, git.Env(), args...)
+}
+
+func gitCommandWithEnv(gitBinPath, dir string, env []string, args ...string) []byte {
 	command := exec.Command(gitBinPath, args...)
 	command.Dir = dir
+	command.Env = env
 	out, err := command.Output()
 
 	if err != nil {
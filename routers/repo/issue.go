@@ -334,7 +334,7 @@ func checkMentions(ctx *middleware.Context, issue *models.Issue) {
 			mentions[i] = strings.TrimSpace(mentions[i])[1:]
 		}
 
-		if err := models.UpdateMentions(mentions, issue.ID); err != nil {
+		if err := models.UpdateMentions(ctx.User, mentions, issue.ID); err != nil {
 			ctx.Handle(500, "UpdateMentions", err)
 			return
 		}
@@ -344,7 +344,7 @@ func checkMentions(ctx *middleware.Context, issue *models.Issue) {
 
 	// Mail watchers and mentions.
 	if setting.Service.EnableNotifyMail {
-		tos, err := mailer.SendIssueNotifyMail(ctx.User, ctx.Repo.Owner, repo, issue)
+		tos, err := mailer.SendIssueNotifyMail(ctx.Render, ctx.User, ctx.Repo.Owner, repo, issue)
 		if err != nil {
 			ctx.Handle(500, "SendIssueNotifyMail", err)
 			return
@@ -353,7 +353,7 @@ func checkMentions(ctx *middleware.Context, issue *models.Issue) {
 		tos = append(tos, ctx.User.LowerName)
 		newTos := make([]string, 0, len(mentions))
 		for _, m := range mentions {
-			if com.IsSliceContainsStr(tos, m) {
+			if com.IsSliceContainsStr(tos, strings.ToLower(m)) {
 				continue
 			}
 
@@ -391,6 +391,14 @@ func NewIssuePost(ctx *middleware.Context, form auth.CreateIssueForm) {
 		return
 	}
 
+	if assigneeID == 0 {
+		var err error
+		if assigneeID, err = repo.AutoAssignee(ctx.User); err != nil {
+			ctx.Handle(500, "AutoAssignee", err)
+			return
+		}
+	}
+
 	issue := &models.Issue{
 		RepoID:      ctx.Repo.Repository.ID,
 		Index:       repo.NextIssueIndex(),
@@ -486,6 +494,11 @@ func ViewIssue(ctx *middleware.Context) {
 		return
 	}
 
+	if !ctx.Repo.Repository.IsUnitEnabled(issue.Unit()) {
+		ctx.Handle(404, "IsUnitEnabled", nil)
+		return
+	}
+
 	if issue.IsPull {
 		if err = issue.GetPullRequest(); err != nil {
 			ctx.Handle(500, "GetPullRequest", err)
@@ -569,7 +582,7 @@ func ViewIssue(ctx *middleware.Context) {
 	)
 	// Render comments.
 	for _, comment = range issue.Comments {
-		if comment.Type == models.COMMENT_TYPE_COMMENT {
+		if comment.Type == models.COMMENT_TYPE_COMMENT || comment.Type == models.COMMENT_TYPE_CODE {
 			comment.RenderedContent = string(base.RenderMarkdown([]byte(comment.Content), ctx.Repo.RepoLink))
 
 			// Check tag.
@@ -594,6 +607,7 @@ func ViewIssue(ctx *middleware.Context) {
 
 	ctx.Data["Issue"] = issue
 	ctx.Data["IsIssueOwner"] = ctx.Repo.IsAdmin() || (ctx.IsSigned && issue.IsPoster(ctx.User.Id))
+	ctx.Data["CanComment"] = issue.CanComment(ctx.User, ctx.Repo.IsPusher())
 	ctx.Data["SignInLink"] = setting.AppSubUrl + "/user/login"
 	ctx.HTML(200, ISSUE_VIEW)
 }
@@ -607,6 +621,9 @@ func getActionIssue(ctx *middleware.Context) *models.Issue {
 			ctx.Handle(500, "GetIssueByIndex", err)
 		}
 		return nil
+	} else if !ctx.Repo.Repository.IsUnitEnabled(issue.Unit()) {
+		ctx.Error(404, "IsUnitEnabled")
+		return nil
 	}
 	return issue
 }
@@ -667,7 +684,7 @@ func UpdateIssueLabel(ctx *middleware.Context) {
 	}
 
 	if ctx.Query("action") == "clear" {
-		if err := issue.ClearLabels(); err != nil {
+		if err := issue.ClearLabels(ctx.User); err != nil {
 			ctx.Handle(500, "ClearLabels", err)
 			return
 		}
@@ -684,12 +701,12 @@ func UpdateIssueLabel(ctx *middleware.Context) {
 		}
 
 		if isAttach && !issue.HasLabel(label.ID) {
-			if err = issue.AddLabel(label); err != nil {
+			if err = issue.AddLabel(ctx.User, label); err != nil {
 				ctx.Handle(500, "AddLabel", err)
 				return
 			}
 		} else if !isAttach && issue.HasLabel(label.ID) {
-			if err = issue.RemoveLabel(label); err != nil {
+			if err = issue.RemoveLabel(ctx.User, label); err != nil {
 				ctx.Handle(500, "RemoveLabel", err)
 				return
 			}
@@ -718,7 +735,7 @@ func UpdateIssueMilestone(ctx *middleware.Context) {
 
 	// Not check for invalid milestone id and give responsibility to owners.
 	issue.MilestoneID = mid
-	if err := models.ChangeMilestoneAssign(oldMid, issue); err != nil {
+	if err := models.ChangeMilestoneAssign(ctx.User, oldMid, issue); err != nil {
 		ctx.Handle(500, "ChangeMilestoneAssign", err)
 		return
 	}
@@ -744,7 +761,7 @@ func UpdateIssueAssignee(ctx *middleware.Context) {
 
 	// Not check for invalid assignee id and give responsibility to owners.
 	issue.AssigneeID = aid
-	if err := models.UpdateIssueUserByAssignee(issue); err != nil {
+	if err := models.UpdateIssueUserByAssignee(ctx.User, issue); err != nil {
 		ctx.Handle(500, "UpdateIssueUserByAssignee: %v", err)
 		return
 	}
@@ -763,6 +780,9 @@ func NewComment(ctx *middleware.Context, form auth.CreateCommentForm) {
 			ctx.Handle(500, "GetIssueByIndex", err)
 		}
 		return
+	} else if !ctx.Repo.Repository.IsUnitEnabled(issue.Unit()) {
+		ctx.Handle(404, "IsUnitEnabled", nil)
+		return
 	}
 	if issue.IsPull {
 		if err = issue.GetPullRequest(); err != nil {
@@ -842,6 +862,11 @@ func NewComment(ctx *middleware.Context, form auth.CreateCommentForm) {
 		return
 	}
 
+	if !issue.CanComment(ctx.User, ctx.Repo.IsPusher()) {
+		ctx.Flash.Error(ctx.Tr("repo.issues.locked_comment_denied"))
+		return
+	}
+
 	comment, err = models.CreateIssueComment(ctx.User, ctx.Repo.Repository, issue, form.Content, attachments)
 	if err != nil {
 		ctx.Handle(500, "CreateIssueComment", err)
@@ -861,6 +886,77 @@ func NewComment(ctx *middleware.Context, form auth.CreateCommentForm) {
 	log.Trace("Comment created: %d/%d/%d", ctx.Repo.Repository.ID, issue.ID, comment.ID)
 }
 
+func LockIssue(ctx *middleware.Context, form auth.LockIssueForm) {
+	issue := getActionIssue(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	if ctx.HasError() {
+		ctx.Flash.Error(ctx.Data["ErrorMsg"].(string))
+		ctx.Redirect(fmt.Sprintf("%s/issues/%d", ctx.Repo.RepoLink, issue.Index))
+		return
+	}
+
+	if err := issue.ChangeLockStatus(ctx.User, ctx.Repo.Repository, true, strings.TrimSpace(form.Reason)); err != nil {
+		ctx.Handle(500, "ChangeLockStatus", err)
+		return
+	}
+	log.Trace("Issue locked: %d/%d", ctx.Repo.Repository.ID, issue.ID)
+
+	ctx.Redirect(fmt.Sprintf("%s/issues/%d", ctx.Repo.RepoLink, issue.Index))
+}
+
+func UnlockIssue(ctx *middleware.Context) {
+	issue := getActionIssue(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	if err := issue.ChangeLockStatus(ctx.User, ctx.Repo.Repository, false, ""); err != nil {
+		ctx.Handle(500, "ChangeLockStatus", err)
+		return
+	}
+	log.Trace("Issue unlocked: %d/%d", ctx.Repo.Repository.ID, issue.ID)
+
+	ctx.Redirect(fmt.Sprintf("%s/issues/%d", ctx.Repo.RepoLink, issue.Index))
+}
+
+func PinIssue(ctx *middleware.Context) {
+	issue := getActionIssue(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	if err := issue.ChangePinStatus(true); err != nil {
+		if models.IsErrIssuePinnedLimit(err) {
+			ctx.Flash.Error(ctx.Tr("repo.issues.pin_limit", err.(models.ErrIssuePinnedLimit).Limit))
+		} else {
+			ctx.Handle(500, "ChangePinStatus", err)
+			return
+		}
+	} else {
+		log.Trace("Issue pinned: %d/%d", ctx.Repo.Repository.ID, issue.ID)
+	}
+
+	ctx.Redirect(fmt.Sprintf("%s/issues/%d", ctx.Repo.RepoLink, issue.Index))
+}
+
+func UnpinIssue(ctx *middleware.Context) {
+	issue := getActionIssue(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	if err := issue.ChangePinStatus(false); err != nil {
+		ctx.Handle(500, "ChangePinStatus", err)
+		return
+	}
+	log.Trace("Issue unpinned: %d/%d", ctx.Repo.Repository.ID, issue.ID)
+
+	ctx.Redirect(fmt.Sprintf("%s/issues/%d", ctx.Repo.RepoLink, issue.Index))
+}
+
 func UpdateCommentContent(ctx *middleware.Context) {
 	comment, err := models.GetCommentByID(ctx.ParamsInt64(":id"))
 	if err != nil {
@@ -875,7 +971,7 @@ func UpdateCommentContent(ctx *middleware.Context) {
 	if !ctx.IsSigned || (ctx.User.Id != comment.PosterID && !ctx.Repo.IsAdmin()) {
 		ctx.Error(403)
 		return
-	} else if comment.Type != models.COMMENT_TYPE_COMMENT {
+	} else if comment.Type != models.COMMENT_TYPE_COMMENT && comment.Type != models.COMMENT_TYPE_CODE {
 		ctx.Error(204)
 		return
 	}
@@ -887,6 +983,7 @@ func UpdateCommentContent(ctx *middleware.Context) {
 		})
 		return
 	}
+	comment.IsEdited = true
 	if err := models.UpdateComment(comment); err != nil {
 		ctx.Handle(500, "UpdateComment", err)
 		return
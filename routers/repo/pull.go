@@ -7,6 +7,7 @@ package repo
 import (
 	"container/list"
 	"errors"
+	"fmt"
 	"path"
 	"strings"
 
@@ -118,6 +119,10 @@ func ForkPost(ctx *middleware.Context, form auth.CreateRepoForm) {
 			ctx.RenderWithErr(ctx.Tr("repo.form.name_reserved", err.(models.ErrNameReserved).Name), FORK, &form)
 		case models.IsErrNamePatternNotAllowed(err):
 			ctx.RenderWithErr(ctx.Tr("repo.form.name_pattern_not_allowed", err.(models.ErrNamePatternNotAllowed).Pattern), FORK, &form)
+		case models.IsErrNameCharsNotAllowed(err):
+			ctx.RenderWithErr(ctx.Tr("repo.form.name_chars_not_allowed", err.(models.ErrNameCharsNotAllowed).Name), FORK, &form)
+		case models.IsErrReachLimitOfRepo(err):
+			ctx.RenderWithErr(ctx.Tr("repo.form.reach_limit_of_creation", err.(models.ErrReachLimitOfRepo).Limit), FORK, &form)
 		default:
 			ctx.Handle(500, "ForkPost", err)
 		}
@@ -233,6 +238,17 @@ func PrepareViewPullInfo(ctx *middleware.Context, pull *models.Issue) *git.PullR
 	}
 	ctx.Data["NumCommits"] = prInfo.Commits.Len()
 	ctx.Data["NumFiles"] = prInfo.NumFiles
+
+	reviews, err := models.GetReviews(pull.PullRequest.ID)
+	if err != nil {
+		ctx.Handle(500, "GetReviews", err)
+		return nil
+	}
+	ctx.Data["Reviews"] = reviews
+	ctx.Data["MergeBlockedReason"] = mergeBlockedReason(ctx, pull.PullRequest)
+	if ctx.Written() {
+		return nil
+	}
 	return prInfo
 }
 
@@ -288,6 +304,7 @@ func ViewPullCommits(ctx *middleware.Context) {
 
 func ViewPullFiles(ctx *middleware.Context) {
 	ctx.Data["PageIsPullFiles"] = true
+	ctx.Data["RequireHighlightJS"] = true
 
 	pull := checkPullInfo(ctx)
 	if ctx.Written() {
@@ -340,8 +357,8 @@ func ViewPullFiles(ctx *middleware.Context) {
 		gitRepo = headGitRepo
 	}
 
-	diff, err := models.GetDiffRange(diffRepoPath,
-		startCommitID, endCommitID, setting.Git.MaxGitDiffLines)
+	diff, err := models.GetDiffRangeWithWhitespace(diffRepoPath,
+		startCommitID, endCommitID, models.DefaultDiffLimits(), getDiffWhitespace(ctx))
 	if err != nil {
 		ctx.Handle(500, "GetDiffRange", err)
 		return
@@ -349,6 +366,23 @@ func ViewPullFiles(ctx *middleware.Context) {
 	ctx.Data["Diff"] = diff
 	ctx.Data["DiffNotAvailable"] = diff.NumFiles() == 0
 
+	codeComments, err := models.GetCodeComments(pull.ID)
+	if err != nil {
+		ctx.Handle(500, "GetCodeComments", err)
+		return
+	}
+	for _, lines := range codeComments {
+		for _, comments := range lines {
+			for _, c := range comments {
+				c.RenderedContent = string(base.RenderMarkdown([]byte(c.Content), ctx.Repo.RepoLink))
+			}
+		}
+	}
+	ctx.Data["CodeComments"] = codeComments
+	ctx.Data["CanCodeComment"] = ctx.IsSigned
+	ctx.Data["CodeCommentLink"] = fmt.Sprintf("%s/pulls/%d/files/comment", ctx.Repo.RepoLink, pull.Index)
+	ctx.Data["AfterCommitID"] = endCommitID
+
 	commit, err := gitRepo.GetCommit(endCommitID)
 	if err != nil {
 		ctx.Handle(500, "GetCommit", err)
@@ -366,6 +400,90 @@ func ViewPullFiles(ctx *middleware.Context) {
 	ctx.HTML(200, PULL_FILES)
 }
 
+func NewCodeComment(ctx *middleware.Context, form auth.CodeCommentForm) {
+	issue := checkPullInfo(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	filesLink := fmt.Sprintf("%s/pulls/%d/files", ctx.Repo.RepoLink, issue.Index)
+	if ctx.HasError() {
+		ctx.Flash.Error(ctx.Data["ErrorMsg"].(string))
+		ctx.Redirect(filesLink)
+		return
+	} else if form.Line == 0 {
+		ctx.Flash.Error(ctx.Tr("repo.pulls.code_comment_invalid_line"))
+		ctx.Redirect(filesLink)
+		return
+	} else if !issue.CanComment(ctx.User, ctx.Repo.IsPusher()) {
+		ctx.Flash.Error(ctx.Tr("repo.issues.locked_comment_denied"))
+		ctx.Redirect(filesLink)
+		return
+	}
+
+	comment, err := models.CreateCodeComment(ctx.User, ctx.Repo.Repository, issue, form.TreePath, form.Line, form.CommitSHA, form.Content)
+	if err != nil {
+		ctx.Handle(500, "CreateCodeComment", err)
+		return
+	}
+
+	checkMentions(ctx, &models.Issue{
+		ID:      issue.ID,
+		Index:   issue.Index,
+		Name:    issue.Name,
+		Content: form.Content,
+	})
+	if ctx.Written() {
+		return
+	}
+
+	log.Trace("Code comment created: %d/%d/%d", ctx.Repo.Repository.ID, issue.ID, comment.ID)
+	ctx.Redirect(filesLink + "#" + comment.HashTag())
+}
+
+// mergeBlockedReason returns translated reason why reviews do not allow pull request
+// to be merged, or an empty string if they do.
+func mergeBlockedReason(ctx *middleware.Context, pr *models.PullRequest) string {
+	err := pr.CheckReviews()
+	switch {
+	case err == nil:
+		return ""
+	case models.IsErrPullRequestNotApproved(err):
+		e := err.(models.ErrPullRequestNotApproved)
+		return ctx.Tr("repo.pulls.not_approved", e.Required, e.Approvals)
+	case models.IsErrPullRequestChangesRequested(err):
+		return ctx.Tr("repo.pulls.changes_requested", err.(models.ErrPullRequestChangesRequested).Reviewer)
+	}
+	ctx.Handle(500, "CheckReviews", err)
+	return ""
+}
+
+func SubmitReview(ctx *middleware.Context, form auth.SubmitReviewForm) {
+	issue := checkPullInfo(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	link := fmt.Sprintf("%s/pulls/%d", ctx.Repo.RepoLink, issue.Index)
+	if ctx.HasError() {
+		ctx.Flash.Error(ctx.Data["ErrorMsg"].(string))
+		ctx.Redirect(link)
+		return
+	} else if issue.PosterID == ctx.User.Id || issue.IsClosed {
+		ctx.Error(403)
+		return
+	}
+
+	if _, err := models.SubmitReview(issue.PullRequest, ctx.User, models.ReviewState(form.State), strings.TrimSpace(form.Content)); err != nil {
+		ctx.Handle(500, "SubmitReview", err)
+		return
+	}
+	log.Trace("Pull request reviewed[%d]: %s", issue.PullRequest.ID, ctx.User.Name)
+
+	ctx.Flash.Success(ctx.Tr("repo.pulls.review_submitted"))
+	ctx.Redirect(link)
+}
+
 func MergePullRequest(ctx *middleware.Context) {
 	issue := checkPullInfo(ctx)
 	if ctx.Written() {
@@ -391,9 +509,17 @@ func MergePullRequest(ctx *middleware.Context) {
 		return
 	}
 
+	if reason := mergeBlockedReason(ctx, pr); ctx.Written() {
+		return
+	} else if len(reason) > 0 {
+		ctx.Flash.Error(reason)
+		ctx.Redirect(ctx.Repo.RepoLink + "/pulls/" + com.ToStr(pr.Index))
+		return
+	}
+
 	pr.Issue = issue
 	pr.Issue.Repo = ctx.Repo.Repository
-	if err = pr.Merge(ctx.User, ctx.Repo.GitRepo); err != nil {
+	if _, err = pr.Merge(ctx.User, ctx.Repo.GitRepo, models.MERGE_STYLE_MERGE, ""); err != nil {
 		ctx.Handle(500, "Merge", err)
 		return
 	}
@@ -504,8 +630,8 @@ func PrepareCompareDiff(
 		return true
 	}
 
-	diff, err := models.GetDiffRange(models.RepoPath(headUser.Name, headRepo.Name),
-		prInfo.MergeBase, headCommitID, setting.Git.MaxGitDiffLines)
+	diff, err := models.GetDiffRangeWithWhitespace(models.RepoPath(headUser.Name, headRepo.Name),
+		prInfo.MergeBase, headCommitID, models.DefaultDiffLimits(), getDiffWhitespace(ctx))
 	if err != nil {
 		ctx.Handle(500, "GetDiffRange", err)
 		return false
@@ -537,6 +663,7 @@ func CompareAndPullRequest(ctx *middleware.Context) {
 	ctx.Data["Title"] = ctx.Tr("repo.pulls.compare_changes")
 	ctx.Data["PageIsComparePull"] = true
 	ctx.Data["IsDiffCompare"] = true
+	ctx.Data["RequireHighlightJS"] = true
 	renderAttachmentSettings(ctx)
 
 	headUser, headRepo, headGitRepo, prInfo, baseBranch, headBranch := ParseCompareInfo(ctx)
@@ -577,6 +704,7 @@ func CompareAndPullRequestPost(ctx *middleware.Context, form auth.CreateIssueFor
 	ctx.Data["Title"] = ctx.Tr("repo.pulls.compare_changes")
 	ctx.Data["PageIsComparePull"] = true
 	ctx.Data["IsDiffCompare"] = true
+	ctx.Data["RequireHighlightJS"] = true
 	renderAttachmentSettings(ctx)
 
 	var (
@@ -609,6 +737,13 @@ func CompareAndPullRequestPost(ctx *middleware.Context, form auth.CreateIssueFor
 		return
 	}
 
+	if assigneeID == 0 {
+		if assigneeID, err = repo.AutoAssignee(ctx.User); err != nil {
+			ctx.Handle(500, "AutoAssignee", err)
+			return
+		}
+	}
+
 	pull := &models.Issue{
 		RepoID:      repo.ID,
 		Index:       repo.NextIssueIndex(),
@@ -633,6 +768,11 @@ func CompareAndPullRequestPost(ctx *middleware.Context, form auth.CreateIssueFor
 		return
 	}
 
+	checkMentions(ctx, pull)
+	if ctx.Written() {
+		return
+	}
+
 	log.Trace("Pull request created: %d/%d", repo.ID, pull.ID)
 	ctx.Redirect(ctx.Repo.RepoLink + "/pulls/" + com.ToStr(pull.Index))
 }
@@ -194,6 +194,10 @@ func NewReleasePost(ctx *middleware.Context, form auth.NewReleaseForm) {
 	}
 	log.Trace("Release created: %s/%s:%s", ctx.User.LowerName, ctx.Repo.Repository.Name, form.TagName)
 
+	if err = rel.PrepareWebhooks(ctx.User, models.HOOK_ACTION_PUBLISHED); err != nil {
+		log.Error(4, "PrepareWebhooks: %v", err)
+	}
+
 	ctx.Redirect(ctx.Repo.RepoLink + "/releases")
 }
 
@@ -248,6 +252,7 @@ func EditReleasePost(ctx *middleware.Context, form auth.EditReleaseForm) {
 		return
 	}
 
+	wasDraft := rel.IsDraft
 	rel.Title = form.Title
 	rel.Note = form.Content
 	rel.IsDraft = len(form.Draft) > 0
@@ -256,14 +261,32 @@ func EditReleasePost(ctx *middleware.Context, form auth.EditReleaseForm) {
 		ctx.Handle(500, "UpdateRelease", err)
 		return
 	}
+
+	action := models.HOOK_ACTION_EDITED
+	if wasDraft {
+		action = models.HOOK_ACTION_PUBLISHED
+	}
+	if err = rel.PrepareWebhooks(ctx.User, action); err != nil {
+		log.Error(4, "PrepareWebhooks: %v", err)
+	}
 	ctx.Redirect(ctx.Repo.RepoLink + "/releases")
 }
 
 func DeleteRelease(ctx *middleware.Context) {
-	if err := models.DeleteReleaseByID(ctx.QueryInt64("id")); err != nil {
+	rel, err := models.GetReleaseByID(ctx.QueryInt64("id"))
+	if err != nil || rel.RepoID != ctx.Repo.Repository.ID {
+		if err != nil && !models.IsErrReleaseNotExist(err) {
+			ctx.Handle(500, "GetReleaseByID", err)
+			return
+		}
+		ctx.Flash.Error(ctx.Tr("repo.release.not_exist"))
+	} else if err = models.DeleteReleaseByID(rel.ID); err != nil {
 		ctx.Flash.Error("DeleteReleaseByID: " + err.Error())
 	} else {
 		ctx.Flash.Success(ctx.Tr("repo.release.deletion_success"))
+		if err = rel.PrepareWebhooks(ctx.User, models.HOOK_ACTION_DELETED); err != nil {
+			log.Error(4, "PrepareWebhooks: %v", err)
+		}
 	}
 
 	ctx.JSON(200, map[string]interface{}{
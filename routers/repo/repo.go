@@ -6,9 +6,11 @@ package repo
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path"
 	"strings"
+	"time"
 
 	"github.com/Unknwon/com"
 
@@ -27,9 +29,9 @@ const (
 )
 
 func checkContextUser(ctx *middleware.Context, uid int64) *models.User {
-	orgs, err := models.GetOwnedOrgsByUserIDDesc(ctx.User.Id, "updated")
+	orgs, err := models.GetOrgsCanCreateRepoByUserID(ctx.User.Id)
 	if err != nil {
-		ctx.Handle(500, "GetOwnedOrgsByUserIDDesc", err)
+		ctx.Handle(500, "GetOrgsCanCreateRepoByUserID", err)
 		return nil
 	}
 	ctx.Data["Orgs"] = orgs
@@ -49,8 +51,8 @@ func checkContextUser(ctx *middleware.Context, uid int64) *models.User {
 		return nil
 	}
 
-	// Check ownership of organization.
-	if !org.IsOrganization() || !org.IsOwnedBy(ctx.User.Id) {
+	// Check permission of creating repository in organization.
+	if !org.IsOrganization() || !org.CanCreateOrgRepo(ctx.User.Id) {
 		ctx.Error(403)
 		return nil
 	}
@@ -67,6 +69,11 @@ func Create(ctx *middleware.Context) {
 	ctx.Data["readme"] = "Default"
 	ctx.Data["private"] = ctx.User.LastRepoVisibility
 	ctx.Data["IsForcedPrivate"] = setting.Repository.ForcePrivate
+	ctx.Data["DefaultBranch"] = setting.Repository.DefaultBranch
+
+	ctx.Data["default_labels"] = true
+	ctx.Data["enable_issues"] = true
+	ctx.Data["enable_wiki"] = true
 
 	ctxUser := checkContextUser(ctx, ctx.QueryInt64("org"))
 	if ctx.Written() {
@@ -74,6 +81,21 @@ func Create(ctx *middleware.Context) {
 	}
 	ctx.Data["ContextUser"] = ctxUser
 
+	// Prefill default settings of organization, which can still be changed by creator.
+	if ctxUser.IsOrganization() {
+		defaults, err := models.GetOrgRepoDefaults(ctxUser.Id)
+		if err != nil {
+			ctx.Handle(500, "GetOrgRepoDefaults", err)
+			return
+		}
+		ctx.Data["private"] = defaults.IsPrivate
+		ctx.Data["enable_issues"] = defaults.EnableIssues
+		ctx.Data["enable_wiki"] = defaults.EnableWiki
+		if len(defaults.DefaultBranch) > 0 {
+			ctx.Data["DefaultBranch"] = defaults.DefaultBranch
+		}
+	}
+
 	ctx.HTML(200, CREATE)
 }
 
@@ -88,6 +110,16 @@ func handleCreateError(ctx *middleware.Context, err error, name string, tpl base
 	case models.IsErrNamePatternNotAllowed(err):
 		ctx.Data["Err_RepoName"] = true
 		ctx.RenderWithErr(ctx.Tr("repo.form.name_pattern_not_allowed", err.(models.ErrNamePatternNotAllowed).Pattern), tpl, form)
+	case models.IsErrNameCharsNotAllowed(err):
+		ctx.Data["Err_RepoName"] = true
+		ctx.RenderWithErr(ctx.Tr("repo.form.name_chars_not_allowed", err.(models.ErrNameCharsNotAllowed).Name), tpl, form)
+	case models.IsErrReachLimitOfRepo(err):
+		ctx.RenderWithErr(ctx.Tr("repo.form.reach_limit_of_creation", err.(models.ErrReachLimitOfRepo).Limit), tpl, form)
+	case models.IsErrInvalidDefaultBranch(err):
+		ctx.Data["Err_DefaultBranch"] = true
+		ctx.RenderWithErr(ctx.Tr("repo.form.invalid_default_branch", err.(models.ErrInvalidDefaultBranch).Name), tpl, form)
+	case models.IsErrRepoInitFileNotExist(err):
+		ctx.RenderWithErr(ctx.Tr("repo.form.init_file_not_exist", err.(models.ErrRepoInitFileNotExist).Name), tpl, form)
 	default:
 		ctx.Handle(500, name, err)
 	}
@@ -99,6 +131,7 @@ func CreatePost(ctx *middleware.Context, form auth.CreateRepoForm) {
 	ctx.Data["Gitignores"] = models.Gitignores
 	ctx.Data["Licenses"] = models.Licenses
 	ctx.Data["Readmes"] = models.Readmes
+	ctx.Data["DefaultBranch"] = setting.Repository.DefaultBranch
 
 	ctxUser := checkContextUser(ctx, form.Uid)
 	if ctx.Written() {
@@ -111,15 +144,32 @@ func CreatePost(ctx *middleware.Context, form auth.CreateRepoForm) {
 		return
 	}
 
-	repo, err := models.CreateRepository(ctxUser, models.CreateRepoOptions{
-		Name:        form.RepoName,
-		Description: form.Description,
-		Gitignores:  form.Gitignores,
-		License:     form.License,
-		Readme:      form.Readme,
-		IsPrivate:   form.Private || setting.Repository.ForcePrivate,
-		AutoInit:    form.AutoInit,
-	})
+	opts := models.CreateRepoOptions{
+		Name:          form.RepoName,
+		Description:   form.Description,
+		Gitignores:    form.Gitignores,
+		License:       form.License,
+		Readme:        form.Readme,
+		IsPrivate:     form.Private || setting.Repository.ForcePrivate,
+		AutoInit:      form.AutoInit,
+		DefaultBranch: strings.TrimSpace(form.DefaultBranch),
+		DisableIssues: !form.EnableIssues,
+		DisableWiki:   !form.EnableWiki,
+	}
+	if ctxUser.IsOrganization() {
+		defaults, err := models.GetOrgRepoDefaults(ctxUser.Id)
+		if err != nil {
+			ctx.Handle(500, "GetOrgRepoDefaults", err)
+			return
+		}
+		// Visibility and units are always given by form, which is prefilled with defaults.
+		defaults.Apply(&opts, &opts.IsPrivate, &form.EnableIssues, &form.EnableWiki, form.DefaultLabels)
+	}
+
+	repo, err := models.CreateRepository(ctxUser, opts)
+	if err == nil {
+		err = ctxUser.GrantRepoCreatorAccess(repo, ctx.User)
+	}
 	if err == nil {
 		log.Trace("Repository created[%d]: %s/%s", repo.ID, ctxUser.Name, repo.Name)
 		ctx.Redirect(setting.AppSubUrl + "/" + ctxUser.Name + "/" + repo.Name)
@@ -191,6 +241,9 @@ func MigratePost(ctx *middleware.Context, form auth.MigrateRepoForm) {
 		IsMirror:    form.Mirror,
 		RemoteAddr:  remoteAddr,
 	})
+	if err == nil {
+		err = ctxUser.GrantRepoCreatorAccess(repo, ctx.User)
+	}
 	if err == nil {
 		log.Trace("Repository migrated[%d]: %s/%s", repo.ID, ctxUser.Name, form.RepoName)
 		ctx.Redirect(setting.AppSubUrl + "/" + ctxUser.Name + "/" + form.RepoName)
@@ -273,12 +326,23 @@ func Download(ctx *middleware.Context) {
 		ext = ".tar.gz"
 		archivePath = path.Join(ctx.Repo.GitRepo.Path, "archives/targz")
 		archiveType = git.TARGZ
+	case strings.HasSuffix(uri, ".tar.bz2"):
+		ext = ".tar.bz2"
+		archivePath = path.Join(ctx.Repo.GitRepo.Path, "archives/tarbz2")
+		archiveType = git.TARBZ2
 	default:
 		ctx.Error(404)
 		return
 	}
 	refName = strings.TrimSuffix(uri, ext)
 
+	// Prefix is the top-level directory name inside archive.
+	prefix := strings.Trim(ctx.Query("prefix"), "/")
+	if strings.Contains(prefix, "..") || strings.ContainsAny(prefix, "\\\x00\r\n") {
+		ctx.Error(400, "Invalid prefix")
+		return
+	}
+
 	if !com.IsDir(archivePath) {
 		if err := os.MkdirAll(archivePath, os.ModePerm); err != nil {
 			ctx.Handle(500, "Download -> os.MkdirAll(archivePath)", err)
@@ -315,13 +379,43 @@ func Download(ctx *middleware.Context) {
 		return
 	}
 
-	archivePath = path.Join(archivePath, base.ShortSha(commit.ID.String())+ext)
-	if !com.IsFile(archivePath) {
-		if err := commit.CreateArchive(archivePath, archiveType); err != nil {
-			ctx.Handle(500, "Download -> CreateArchive "+archivePath, err)
-			return
+	// Archives are cached by commit ID, and by prefix when it is given.
+	fileName := ctx.Repo.Repository.Name + "-" + base.ShortSha(commit.ID.String()) + ext
+	cacheName := commit.ID.String()
+	if len(prefix) > 0 {
+		cacheName += "-" + base.EncodeMD5(prefix)
+	}
+	archivePath = path.Join(archivePath, cacheName+ext)
+	if com.IsFile(archivePath) {
+		ctx.ServeFile(archivePath, fileName)
+		return
+	}
+
+	// Stream archive to client and save it to cache at the same time,
+	// cache file is only put in place when archive is complete.
+	tmpPath := fmt.Sprintf("%s.%d.tmp", archivePath, time.Now().UnixNano())
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		ctx.Handle(500, "Download -> os.Create", err)
+		return
+	}
+
+	ctx.Resp.Header().Set("Content-Type", "application/octet-stream")
+	ctx.Resp.Header().Set("Content-Disposition", "attachment; filename="+fileName)
+	err = commit.WriteArchive(io.MultiWriter(f, ctx.Resp), archiveType, prefix)
+	f.Close()
+	if err != nil {
+		os.Remove(tmpPath)
+		if ctx.Written() {
+			log.Error(4, "Download -> WriteArchive %s: %v", archivePath, err)
+		} else {
+			ctx.Handle(500, "Download -> WriteArchive "+archivePath, err)
 		}
+		return
 	}
 
-	ctx.ServeFile(archivePath, ctx.Repo.Repository.Name+"-"+base.ShortSha(commit.ID.String())+ext)
+	if err = os.Rename(tmpPath, archivePath); err != nil {
+		log.Error(4, "Download -> Rename %s: %v", archivePath, err)
+		os.Remove(tmpPath)
+	}
 }
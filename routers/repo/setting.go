@@ -32,11 +32,52 @@ const (
 	GITHOOKS         base.TplName = "repo/settings/githooks"
 	GITHOOK_EDIT     base.TplName = "repo/settings/githook_edit"
 	DEPLOY_KEYS      base.TplName = "repo/settings/deploy_keys"
+	PROTECTED_TAGS   base.TplName = "repo/settings/protected_tags"
 )
 
+// splitNames returns non-empty names in comma-separated list.
+func splitNames(list string) []string {
+	names := make([]string, 0, 5)
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); len(name) > 0 {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// renderAutoAssignees sets names of users and teams assigned in turn in round-robin mode.
+func renderAutoAssignees(ctx *middleware.Context) {
+	users, err := ctx.Repo.Repository.GetAutoAssignees()
+	if err != nil {
+		ctx.Handle(500, "GetAutoAssignees", err)
+		return
+	}
+	teams, err := ctx.Repo.Repository.GetAutoAssignTeams()
+	if err != nil {
+		ctx.Handle(500, "GetAutoAssignTeams", err)
+		return
+	}
+
+	names := make([]string, len(users))
+	for i := range users {
+		names[i] = users[i].Name
+	}
+	ctx.Data["AutoAssignees"] = strings.Join(names, ", ")
+	names = make([]string, len(teams))
+	for i := range teams {
+		names[i] = teams[i].Name
+	}
+	ctx.Data["AutoAssignTeams"] = strings.Join(names, ", ")
+}
+
 func Settings(ctx *middleware.Context) {
 	ctx.Data["Title"] = ctx.Tr("repo.settings")
 	ctx.Data["PageIsSettingsOptions"] = true
+	renderAutoAssignees(ctx)
+	if ctx.Written() {
+		return
+	}
 	ctx.HTML(200, SETTINGS_OPTIONS)
 }
 
@@ -48,11 +89,28 @@ func SettingsPost(ctx *middleware.Context, form auth.RepoSettingForm) {
 
 	switch ctx.Query("action") {
 	case "update":
+		ctx.Data["AutoAssignees"] = form.AutoAssignees
+		ctx.Data["AutoAssignTeams"] = form.AutoAssignTeams
 		if ctx.HasError() {
 			ctx.HTML(200, SETTINGS_OPTIONS)
 			return
 		}
 
+		autoAssignMode, _ := models.ToAutoAssignMode(form.AutoAssign)
+		if err := repo.SetAutoAssignment(autoAssignMode, splitNames(form.AutoAssignees), splitNames(form.AutoAssignTeams)); err != nil {
+			if models.IsErrInvalidAutoAssignee(err) {
+				ctx.Data["Err_AutoAssignees"] = true
+				if name := err.(models.ErrInvalidAutoAssignee).Name; len(name) > 0 {
+					ctx.RenderWithErr(ctx.Tr("repo.settings.auto_assign_invalid", name), SETTINGS_OPTIONS, &form)
+				} else {
+					ctx.RenderWithErr(ctx.Tr("repo.settings.auto_assign_empty"), SETTINGS_OPTIONS, &form)
+				}
+			} else {
+				ctx.Handle(500, "SetAutoAssignment", err)
+			}
+			return
+		}
+
 		isNameChanged := false
 		oldRepoName := repo.Name
 		newRepoName := form.RepoName
@@ -68,6 +126,8 @@ func SettingsPost(ctx *middleware.Context, form auth.RepoSettingForm) {
 					ctx.RenderWithErr(ctx.Tr("repo.form.name_reserved", err.(models.ErrNameReserved).Name), SETTINGS_OPTIONS, &form)
 				case models.IsErrNamePatternNotAllowed(err):
 					ctx.RenderWithErr(ctx.Tr("repo.form.name_pattern_not_allowed", err.(models.ErrNamePatternNotAllowed).Pattern), SETTINGS_OPTIONS, &form)
+				case models.IsErrNameCharsNotAllowed(err):
+					ctx.RenderWithErr(ctx.Tr("repo.form.name_chars_not_allowed", err.(models.ErrNameCharsNotAllowed).Name), SETTINGS_OPTIONS, &form)
 				default:
 					ctx.Handle(500, "ChangeRepositoryName", err)
 				}
@@ -100,6 +160,20 @@ func SettingsPost(ctx *middleware.Context, form auth.RepoSettingForm) {
 
 		visibilityChanged := repo.IsPrivate != form.Private
 		repo.IsPrivate = form.Private
+		repo.IsTemplate = form.Template
+		repo.EnableIssues = form.EnableIssues
+		repo.EnablePulls = form.EnablePulls
+		repo.EnableWiki = form.EnableWiki
+		repo.EnableReleases = form.EnableReleases
+		repo.RequiredApprovals = form.RequiredApprovals
+		repo.SignedCommitMode, _ = models.ToSignedCommitMode(form.SignedCommits)
+		// Only site administrators are allowed to override push size limit.
+		if ctx.User.IsAdmin {
+			if form.MaxPushSize < -1 {
+				form.MaxPushSize = -1
+			}
+			repo.MaxPushSize = form.MaxPushSize
+		}
 		if err := models.UpdateRepository(repo, visibilityChanged); err != nil {
 			ctx.Handle(500, "UpdateRepository", err)
 			return
@@ -158,6 +232,32 @@ func SettingsPost(ctx *middleware.Context, form auth.RepoSettingForm) {
 		log.Trace("Repository transfered: %s/%s -> %s", ctx.Repo.Owner.Name, repo.Name, newOwner)
 		ctx.Flash.Success(ctx.Tr("repo.settings.transfer_succeed"))
 		ctx.Redirect(setting.AppSubUrl + "/" + newOwner + "/" + repo.Name)
+	case "archive", "unarchive":
+		repo.IsArchived = ctx.Query("action") == "archive"
+		if err := models.UpdateRepository(repo, false); err != nil {
+			ctx.Handle(500, "UpdateRepository", err)
+			return
+		}
+		log.Trace("Repository archive state changed[%v]: %s/%s", repo.IsArchived, ctx.Repo.Owner.Name, repo.Name)
+
+		if repo.IsArchived {
+			ctx.Flash.Success(ctx.Tr("repo.settings.archive_success"))
+		} else {
+			ctx.Flash.Success(ctx.Tr("repo.settings.unarchive_success"))
+		}
+		ctx.Redirect(ctx.Repo.RepoLink + "/settings")
+	case "mirror-sync":
+		if !repo.IsMirror {
+			ctx.Error(404)
+			return
+		}
+
+		if err := ctx.Repo.Mirror.ScheduleSync(); err != nil {
+			ctx.Handle(500, "ScheduleSync", err)
+			return
+		}
+		ctx.Flash.Info(ctx.Tr("repo.settings.mirror_sync_in_progress"))
+		ctx.Redirect(ctx.Repo.RepoLink + "/settings")
 	case "delete":
 		if repo.Name != form.RepoName {
 			ctx.RenderWithErr(ctx.Tr("form.enterred_invalid_repo_name"), SETTINGS_OPTIONS, nil)
@@ -333,8 +433,12 @@ func ParseHookEvent(form auth.WebhookForm) *models.HookEvent {
 		SendEverything: form.SendEverything(),
 		ChooseEvents:   form.ChooseEvents(),
 		HookEvents: models.HookEvents{
-			Create: form.Create,
-			Push:   form.Push,
+			Create:      form.Create,
+			Push:        form.Push,
+			PushTag:     form.PushTag,
+			PullRequest: form.PullRequest,
+			Release:     form.Release,
+			Wiki:        form.Wiki,
 		},
 	}
 }
@@ -359,7 +463,7 @@ func WebHooksNewPost(ctx *middleware.Context, form auth.NewWebhookForm) {
 	}
 
 	contentType := models.JSON
-	if models.HookContentType(form.ContentType) == models.FORM {
+	if models.ToHookContentType(form.ContentType) == models.FORM {
 		contentType = models.FORM
 	}
 
@@ -373,6 +477,16 @@ func WebHooksNewPost(ctx *middleware.Context, form auth.NewWebhookForm) {
 		HookTaskType: models.GOGS,
 		OrgID:        orCtx.OrgID,
 	}
+	w.PayloadTemplate = form.PayloadTemplate
+	if _, err := models.ParsePayloadTemplate(w.PayloadTemplate); err != nil {
+		ctx.Data["Webhook"] = w
+		ctx.Data["Err_PayloadTemplate"] = true
+		ctx.RenderWithErr(ctx.Tr("repo.settings.payload_template_invalid", err), orCtx.NewTemplate, &form)
+		return
+	}
+	if !setWebhookHeaders(ctx, orCtx, w, form) {
+		return
+	}
 	if err := w.UpdateEvent(); err != nil {
 		ctx.Handle(500, "UpdateEvent", err)
 		return
@@ -385,6 +499,27 @@ func WebHooksNewPost(ctx *middleware.Context, form auth.NewWebhookForm) {
 	ctx.Redirect(orCtx.Link + "/settings/hooks")
 }
 
+// setWebhookHeaders sets custom headers of webhook from form,
+// and renders the form with error if they are invalid.
+func setWebhookHeaders(ctx *middleware.Context, orCtx *OrgRepoCtx, w *models.Webhook, form auth.NewWebhookForm) bool {
+	headers, err := models.ParseHookHeaders(form.Headers)
+	if err == nil {
+		err = w.SetHeaders(headers)
+	}
+	if err != nil {
+		if !models.IsErrInvalidHookHeader(err) {
+			ctx.Handle(500, "SetHeaders", err)
+			return false
+		}
+		ctx.Data["Webhook"] = w
+		ctx.Data["HookHeaders"] = form.Headers
+		ctx.Data["Err_Headers"] = true
+		ctx.RenderWithErr(ctx.Tr("repo.settings.webhook_headers_invalid", err), orCtx.NewTemplate, &form)
+		return false
+	}
+	return true
+}
+
 func SlackHooksNewPost(ctx *middleware.Context, form auth.NewSlackHookForm) {
 	ctx.Data["Title"] = ctx.Tr("repo.settings")
 	ctx.Data["PageIsSettingsHooks"] = true
@@ -461,6 +596,7 @@ func checkWebhook(ctx *middleware.Context) (*OrgRepoCtx, *models.Webhook) {
 		ctx.Data["HookType"] = "slack"
 	default:
 		ctx.Data["HookType"] = "gogs"
+		ctx.Data["HookHeaders"] = w.MaskedHeadersText()
 	}
 
 	ctx.Data["History"], err = w.History(1)
@@ -501,15 +637,24 @@ func WebHooksEditPost(ctx *middleware.Context, form auth.NewWebhookForm) {
 	}
 
 	contentType := models.JSON
-	if models.HookContentType(form.ContentType) == models.FORM {
+	if models.ToHookContentType(form.ContentType) == models.FORM {
 		contentType = models.FORM
 	}
 
 	w.URL = form.PayloadURL
 	w.ContentType = contentType
 	w.Secret = form.Secret
+	w.PayloadTemplate = form.PayloadTemplate
 	w.HookEvent = ParseHookEvent(form.WebhookForm)
 	w.IsActive = form.Active
+	if _, err := models.ParsePayloadTemplate(w.PayloadTemplate); err != nil {
+		ctx.Data["Err_PayloadTemplate"] = true
+		ctx.RenderWithErr(ctx.Tr("repo.settings.payload_template_invalid", err), orCtx.NewTemplate, &form)
+		return
+	}
+	if !setWebhookHeaders(ctx, orCtx, w, form) {
+		return
+	}
 	if err := w.UpdateEvent(); err != nil {
 		ctx.Handle(500, "UpdateEvent", err)
 		return
@@ -565,6 +710,38 @@ func SlackHooksEditPost(ctx *middleware.Context, form auth.NewSlackHookForm) {
 	ctx.Redirect(fmt.Sprintf("%s/settings/hooks/%d", orCtx.Link, w.ID))
 }
 
+func TestWebhook(ctx *middleware.Context) {
+	w, err := models.GetWebhookByID(ctx.ParamsInt64(":id"))
+	if err != nil {
+		if models.IsErrWebhookNotExist(err) {
+			ctx.Handle(404, "GetWebhookByID", nil)
+		} else {
+			ctx.Handle(500, "GetWebhookByID", err)
+		}
+		return
+	} else if w.RepoID != ctx.Repo.Repository.ID {
+		ctx.Handle(404, "GetWebhookByID", nil)
+		return
+	}
+
+	t, err := w.TestDelivery(ctx.User, ctx.Repo.Repository)
+	if err != nil {
+		ctx.Handle(500, "TestDelivery", err)
+		return
+	}
+
+	body := t.ResponseInfo.Body
+	if len(body) > 200 {
+		body = body[:200] + "..."
+	}
+	if t.IsSucceed {
+		ctx.Flash.Success(ctx.Tr("repo.settings.webhook.test_delivery_success", t.ResponseInfo.Status, body))
+	} else {
+		ctx.Flash.Error(ctx.Tr("repo.settings.webhook.test_delivery_failed", t.ResponseInfo.Status, body))
+	}
+	ctx.Redirect(fmt.Sprintf("%s/settings/hooks/%d", ctx.Repo.RepoLink, w.ID))
+}
+
 func DeleteWebhook(ctx *middleware.Context) {
 	if err := models.DeleteWebhook(ctx.QueryInt64("id")); err != nil {
 		ctx.Flash.Error("DeleteWebhook: " + err.Error())
@@ -645,7 +822,7 @@ func GitHooksEditPost(ctx *middleware.Context) {
 		return
 	}
 	hook.Content = ctx.Query("content")
-	if err = hook.Update(); err != nil {
+	if err = hook.Update(models.CustomHookWrapper(name)); err != nil {
 		ctx.Handle(500, "hook.Update", err)
 		return
 	}
@@ -666,7 +843,7 @@ func DeployKeys(ctx *middleware.Context) {
 	ctx.HTML(200, DEPLOY_KEYS)
 }
 
-func DeployKeysPost(ctx *middleware.Context, form auth.AddSSHKeyForm) {
+func DeployKeysPost(ctx *middleware.Context, form auth.AddDeployKeyForm) {
 	ctx.Data["Title"] = ctx.Tr("repo.settings.deploy_keys")
 	ctx.Data["PageIsSettingsKeys"] = true
 
@@ -695,7 +872,7 @@ func DeployKeysPost(ctx *middleware.Context, form auth.AddSSHKeyForm) {
 		}
 	}
 
-	key, err := models.AddDeployKey(ctx.Repo.Repository.ID, form.Title, content)
+	key, err := models.AddDeployKey(ctx.Repo.Repository.ID, form.Title, content, !form.IsWritable)
 	if err != nil {
 		ctx.Data["HasError"] = true
 		switch {
@@ -727,3 +904,72 @@ func DeleteDeployKey(ctx *middleware.Context) {
 		"redirect": ctx.Repo.RepoLink + "/settings/keys",
 	})
 }
+
+func ProtectedTags(ctx *middleware.Context) {
+	ctx.Data["Title"] = ctx.Tr("repo.settings.protected_tags")
+	ctx.Data["PageIsSettingsProtectedTags"] = true
+
+	tags, err := models.GetProtectedTags(ctx.Repo.Repository.ID)
+	if err != nil {
+		ctx.Handle(500, "GetProtectedTags", err)
+		return
+	}
+	ctx.Data["ProtectedTags"] = tags
+
+	ctx.HTML(200, PROTECTED_TAGS)
+}
+
+func ProtectedTagsPost(ctx *middleware.Context, form auth.ProtectedTagForm) {
+	ctx.Data["Title"] = ctx.Tr("repo.settings.protected_tags")
+	ctx.Data["PageIsSettingsProtectedTags"] = true
+
+	tags, err := models.GetProtectedTags(ctx.Repo.Repository.ID)
+	if err != nil {
+		ctx.Handle(500, "GetProtectedTags", err)
+		return
+	}
+	ctx.Data["ProtectedTags"] = tags
+
+	if ctx.HasError() {
+		ctx.HTML(200, PROTECTED_TAGS)
+		return
+	}
+
+	overrideMode := models.ACCESS_MODE_NONE
+	switch form.OverrideMode {
+	case "admin":
+		overrideMode = models.ACCESS_MODE_ADMIN
+	case "owner":
+		overrideMode = models.ACCESS_MODE_OWNER
+	}
+
+	if _, err = models.NewProtectedTag(ctx.Repo.Repository.ID, form.Pattern, overrideMode); err != nil {
+		ctx.Data["HasError"] = true
+		ctx.Data["Err_Pattern"] = true
+		switch {
+		case models.IsErrInvalidTagPattern(err):
+			ctx.RenderWithErr(ctx.Tr("repo.settings.protected_tag_invalid_pattern"), PROTECTED_TAGS, &form)
+		case models.IsErrProtectedTagAlreadyExist(err):
+			ctx.RenderWithErr(ctx.Tr("repo.settings.protected_tag_already_exists"), PROTECTED_TAGS, &form)
+		default:
+			ctx.Handle(500, "NewProtectedTag", err)
+		}
+		return
+	}
+
+	log.Trace("Protected tag added: %d/%s", ctx.Repo.Repository.ID, form.Pattern)
+	ctx.Flash.Success(ctx.Tr("repo.settings.add_protected_tag_success", form.Pattern))
+	ctx.Redirect(ctx.Repo.RepoLink + "/settings/tags")
+}
+
+func DeleteProtectedTag(ctx *middleware.Context) {
+	if err := models.DeleteProtectedTag(ctx.Repo.Repository.ID, ctx.QueryInt64("id")); err != nil {
+		ctx.Flash.Error("DeleteProtectedTag: " + err.Error())
+	} else {
+		ctx.Flash.Success(ctx.Tr("repo.settings.protected_tag_deletion_success"))
+	}
+
+	ctx.JSON(200, map[string]interface{}{
+		"redirect": ctx.Repo.RepoLink + "/settings/tags",
+	})
+}
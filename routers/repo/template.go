@@ -0,0 +1,100 @@
+// Copyright 2015 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package repo
+
+import (
+	"github.com/gogits/gogs/models"
+	"github.com/gogits/gogs/modules/auth"
+	"github.com/gogits/gogs/modules/base"
+	"github.com/gogits/gogs/modules/log"
+	"github.com/gogits/gogs/modules/middleware"
+	"github.com/gogits/gogs/modules/setting"
+)
+
+const (
+	GENERATE base.TplName = "repo/generate"
+)
+
+// getTemplateRepository returns template repository that current user has read access to.
+func getTemplateRepository(ctx *middleware.Context) *models.Repository {
+	tmpl, err := models.GetRepositoryByID(ctx.ParamsInt64(":repoid"))
+	if err != nil {
+		if models.IsErrRepoNotExist(err) {
+			ctx.Handle(404, "GetRepositoryByID", nil)
+		} else {
+			ctx.Handle(500, "GetRepositoryByID", err)
+		}
+		return nil
+	}
+
+	has, err := models.HasAccess(ctx.User, tmpl, models.ACCESS_MODE_READ)
+	if err != nil {
+		ctx.Handle(500, "HasAccess", err)
+		return nil
+	} else if !has || !tmpl.IsTemplate {
+		ctx.Handle(404, "getTemplateRepository", nil)
+		return nil
+	}
+
+	if err = tmpl.GetOwner(); err != nil {
+		ctx.Handle(500, "GetOwner", err)
+		return nil
+	}
+	ctx.Data["TemplateFrom"] = tmpl.Owner.Name + "/" + tmpl.Name
+	ctx.Data["IsForcedPrivate"] = setting.Repository.ForcePrivate
+	return tmpl
+}
+
+func Generate(ctx *middleware.Context) {
+	ctx.Data["Title"] = ctx.Tr("new_from_template")
+
+	getTemplateRepository(ctx)
+	if ctx.Written() {
+		return
+	}
+	ctx.Data["private"] = ctx.User.LastRepoVisibility
+
+	ctxUser := checkContextUser(ctx, ctx.QueryInt64("org"))
+	if ctx.Written() {
+		return
+	}
+	ctx.Data["ContextUser"] = ctxUser
+
+	ctx.HTML(200, GENERATE)
+}
+
+func GeneratePost(ctx *middleware.Context, form auth.GenerateRepoForm) {
+	ctx.Data["Title"] = ctx.Tr("new_from_template")
+
+	tmpl := getTemplateRepository(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	ctxUser := checkContextUser(ctx, form.Uid)
+	if ctx.Written() {
+		return
+	}
+	ctx.Data["ContextUser"] = ctxUser
+
+	if ctx.HasError() {
+		ctx.HTML(200, GENERATE)
+		return
+	}
+
+	repo, err := models.GenerateRepository(ctxUser, tmpl, models.GenerateRepoOptions{
+		Name:           form.RepoName,
+		Description:    form.Description,
+		IsPrivate:      form.Private || setting.Repository.ForcePrivate,
+		FlattenHistory: form.FlattenHistory,
+	})
+	if err != nil {
+		handleCreateError(ctx, err, "GeneratePost", GENERATE, &form)
+		return
+	}
+
+	log.Trace("Repository generated[%d]: %s/%s -> %s/%s", tmpl.ID, tmpl.Owner.Name, tmpl.Name, ctxUser.Name, repo.Name)
+	ctx.Redirect(setting.AppSubUrl + "/" + ctxUser.Name + "/" + repo.Name)
+}
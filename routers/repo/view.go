@@ -16,8 +16,10 @@ import (
 	"github.com/gogits/gogs/models"
 	"github.com/gogits/gogs/modules/base"
 	"github.com/gogits/gogs/modules/git"
+	"github.com/gogits/gogs/modules/highlight"
 	"github.com/gogits/gogs/modules/log"
 	"github.com/gogits/gogs/modules/middleware"
+	"github.com/gogits/gogs/modules/setting"
 	"github.com/gogits/gogs/modules/template"
 )
 
@@ -75,36 +77,40 @@ func Home(ctx *middleware.Context) {
 	if entry != nil && !entry.IsDir() {
 		blob := entry.Blob()
 
-		if dataRc, err := blob.Data(); err != nil {
-			ctx.Handle(404, "blob.Data", err)
+		// Only beginning of content is read to detect type of file,
+		// so large files are never read into memory unless displayed.
+		if buf, err := blob.DataHead(1024); err != nil {
+			ctx.Handle(404, "blob.DataHead", err)
 			return
 		} else {
 			ctx.Data["FileSize"] = blob.Size()
 			ctx.Data["IsFile"] = true
 			ctx.Data["FileName"] = blob.Name()
-			ext := path.Ext(blob.Name())
-			if len(ext) > 0 {
-				ext = ext[1:]
-			}
-			ctx.Data["FileExt"] = ext
+			ctx.Data["HighlightClass"] = highlight.FileNameToHighlightClass(blob.Name())
 			ctx.Data["FileLink"] = rawLink + "/" + treename
 
-			buf := make([]byte, 1024)
-			n, _ := dataRc.Read(buf)
-			if n > 0 {
-				buf = buf[:n]
-			}
-
 			_, isTextFile := base.IsTextFile(buf)
 			_, isImageFile := base.IsImageFile(buf)
-			ctx.Data["IsFileText"] = isTextFile
+			isTooLarge := blob.Size() > setting.MaxDisplayFileSize
+			ctx.Data["IsFileText"] = isTextFile && !isTooLarge
 
 			switch {
 			case isImageFile:
 				ctx.Data["IsImageFile"] = true
-			case isTextFile:
-				d, _ := ioutil.ReadAll(dataRc)
-				buf = append(buf, d...)
+			case isTextFile && isTooLarge:
+				ctx.Data["IsFileTooLarge"] = true
+			case !isTextFile:
+				ctx.Data["IsBinaryFile"] = true
+			default:
+				dataRc, err := blob.Data()
+				if err != nil {
+					ctx.Handle(404, "blob.Data", err)
+					return
+				}
+				buf, _ = ioutil.ReadAll(dataRc)
+				if blob.Size() > setting.MaxHighlightFileSize {
+					ctx.Data["HighlightClass"] = "nohighlight"
+				}
 				readmeExist := base.IsMarkdownFile(blob.Name()) || base.IsReadmeFile(blob.Name())
 				ctx.Data["ReadmeExist"] = readmeExist
 				if readmeExist {
@@ -177,6 +183,11 @@ func Home(ctx *middleware.Context) {
 			}
 		}
 
+		// README that is too large to display is treated as absent.
+		if readmeFile != nil && readmeFile.Size() > setting.MaxDisplayFileSize {
+			readmeFile = nil
+		}
+
 		if readmeFile != nil {
 			ctx.Data["ReadmeInList"] = true
 			ctx.Data["ReadmeExist"] = true
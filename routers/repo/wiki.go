@@ -14,6 +14,7 @@ import (
 	"github.com/gogits/gogs/models"
 	"github.com/gogits/gogs/modules/auth"
 	"github.com/gogits/gogs/modules/base"
+	"github.com/gogits/gogs/modules/log"
 	"github.com/gogits/gogs/modules/middleware"
 )
 
@@ -195,7 +196,8 @@ func NewWikiPost(ctx *middleware.Context, form auth.NewWikiForm) {
 		return
 	}
 
-	if err := ctx.Repo.Repository.AddWikiPage(ctx.User, form.Title, form.Content, form.Message); err != nil {
+	commitID, message, err := ctx.Repo.Repository.AddWikiPage(ctx.User, form.Title, form.Content, form.Message)
+	if err != nil {
 		if models.IsErrWikiAlreadyExist(err) {
 			ctx.Data["Err_Title"] = true
 			ctx.RenderWithErr(ctx.Tr("repo.wiki.page_already_exists"), WIKI_NEW, &form)
@@ -205,6 +207,10 @@ func NewWikiPost(ctx *middleware.Context, form auth.NewWikiForm) {
 		return
 	}
 
+	if err = ctx.Repo.Repository.PrepareWikiWebhooks(ctx.User, models.HOOK_WIKI_CREATED, form.Title, message, commitID); err != nil {
+		log.Error(4, "PrepareWikiWebhooks: %v", err)
+	}
+
 	ctx.Redirect(ctx.Repo.RepoLink + "/wiki/" + models.ToWikiPageURL(form.Title))
 }
 
@@ -236,10 +242,39 @@ func EditWikiPost(ctx *middleware.Context, form auth.NewWikiForm) {
 		return
 	}
 
-	if err := ctx.Repo.Repository.EditWikiPage(ctx.User, form.OldTitle, form.Title, form.Content, form.Message); err != nil {
+	commitID, message, err := ctx.Repo.Repository.EditWikiPage(ctx.User, form.OldTitle, form.Title, form.Content, form.Message)
+	if err != nil {
 		ctx.Handle(500, "EditWikiPage", err)
 		return
 	}
 
+	if err = ctx.Repo.Repository.PrepareWikiWebhooks(ctx.User, models.HOOK_WIKI_EDITED, form.Title, message, commitID); err != nil {
+		log.Error(4, "PrepareWikiWebhooks: %v", err)
+	}
+
 	ctx.Redirect(ctx.Repo.RepoLink + "/wiki/" + models.ToWikiPageURL(form.Title))
 }
+
+func DeleteWikiPagePost(ctx *middleware.Context) {
+	pageName := models.ToWikiPageName(ctx.Params(":page"))
+	if len(pageName) == 0 {
+		pageName = "Home"
+	}
+
+	commitID, message, err := ctx.Repo.Repository.DeleteWikiPage(ctx.User, pageName)
+	if err != nil {
+		if models.IsErrWikiNotExist(err) {
+			ctx.Handle(404, "DeleteWikiPage", nil)
+		} else {
+			ctx.Handle(500, "DeleteWikiPage", err)
+		}
+		return
+	}
+
+	if err = ctx.Repo.Repository.PrepareWikiWebhooks(ctx.User, models.HOOK_WIKI_DELETED, pageName, message, commitID); err != nil {
+		log.Error(4, "PrepareWikiWebhooks: %v", err)
+	}
+
+	ctx.Flash.Success(ctx.Tr("repo.wiki.delete_page_success", pageName))
+	ctx.Redirect(ctx.Repo.RepoLink + "/wiki/_pages")
+}
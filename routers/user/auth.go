@@ -5,8 +5,11 @@
 package user
 
 import (
+	"fmt"
 	"net/url"
+	"strings"
 
+	"github.com/Unknwon/com"
 	"github.com/go-macaron/captcha"
 
 	"github.com/gogits/gogs/models"
@@ -49,6 +52,74 @@ func SignIn(ctx *middleware.Context) {
 	ctx.HTML(200, SIGNIN)
 }
 
+// loginFailures returns number of recorded failed sign in attempts of given key.
+func loginFailures(ctx *middleware.Context, key string) int {
+	v := ctx.Cache.Get("LoginFailures_" + key)
+	if v == nil {
+		return 0
+	}
+	return com.StrTo(com.ToStr(v)).MustInt()
+}
+
+// loginAccountName returns name that per-account lockout is recorded by for given sign in name.
+// Username and e-mail of the same account resolve to the same name, so they share the limit
+// of failed attempts as well. Normalized input is returned when no account matches.
+func loginAccountName(name string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+
+	var (
+		u   *models.User
+		err error
+	)
+	if strings.Contains(name, "@") {
+		u, err = models.GetUserByEmail(name)
+	} else {
+		u, err = models.GetUserByName(name)
+	}
+	if err != nil {
+		if models.IsErrUserNotExist(err) {
+			return name, nil
+		}
+		return "", err
+	}
+	return u.LowerName, nil
+}
+
+// isLoginLocked returns true if sign in is locked for given account name or client IP.
+// Names do not have to exist, so the lockout does not tell whether an account exists.
+func isLoginLocked(ctx *middleware.Context, name string) bool {
+	return ctx.Cache.IsExist("LoginLocked_user_"+name) ||
+		(setting.Service.LoginMaxFailedAttemptsPerIP > 0 && ctx.Cache.IsExist("LoginLocked_ip_"+ctx.RemoteIP))
+}
+
+// recordLoginFailure increases failed attempts of given key,
+// and locks it when reaches the limit.
+func recordLoginFailure(ctx *middleware.Context, key string, limit int, desc string) {
+	if limit <= 0 {
+		return
+	}
+
+	timeout := int64(setting.Service.LoginLockoutMinutes * 60)
+	count := loginFailures(ctx, key) + 1
+	if count < limit {
+		if err := ctx.Cache.Put("LoginFailures_"+key, count, timeout); err != nil {
+			log.Error(4, "Set cache(LoginFailures) fail: %v", err)
+		}
+		return
+	}
+
+	ctx.Cache.Delete("LoginFailures_" + key)
+	if err := ctx.Cache.Put("LoginLocked_"+key, count, timeout); err != nil {
+		log.Error(4, "Set cache(LoginLocked) fail: %v", err)
+		return
+	}
+	log.Warn("%s: locked for %d minutes after %d failed attempts", desc, setting.Service.LoginLockoutMinutes, count)
+	if err := models.CreateUserNotice(fmt.Sprintf("%s has been locked for %d minutes after %d failed sign in attempts.",
+		desc, setting.Service.LoginLockoutMinutes, count)); err != nil {
+		log.Error(4, "CreateUserNotice: %v", err)
+	}
+}
+
 func SignInPost(ctx *middleware.Context, form auth.SignInForm) {
 	ctx.Data["Title"] = ctx.Tr("sign_in")
 
@@ -57,23 +128,78 @@ func SignInPost(ctx *middleware.Context, form auth.SignInForm) {
 		return
 	}
 
+	name, err := loginAccountName(form.UserName)
+	if err != nil {
+		ctx.Handle(500, "loginAccountName", err)
+		return
+	}
+	if isLoginLocked(ctx, name) {
+		ctx.RenderWithErr(ctx.Tr("auth.login_locked", setting.Service.LoginLockoutMinutes), SIGNIN, &form)
+		return
+	}
+
 	u, err := models.UserSignIn(form.UserName, form.Password)
 	if err != nil {
 		if models.IsErrUserNotExist(err) {
+			recordLoginFailure(ctx, "user_"+name, setting.Service.LoginMaxFailedAttempts, "Account '"+name+"'")
+			recordLoginFailure(ctx, "ip_"+ctx.RemoteIP, setting.Service.LoginMaxFailedAttemptsPerIP, "Sign in from IP '"+ctx.RemoteIP+"'")
 			ctx.RenderWithErr(ctx.Tr("form.username_password_incorrect"), SIGNIN, &form)
 		} else {
 			ctx.Handle(500, "UserSignIn", err)
 		}
 		return
 	}
+	ctx.Cache.Delete("LoginFailures_user_" + name)
+
+	if u.NeedApproval {
+		ctx.RenderWithErr(ctx.Tr("auth.account_pending_approval"), SIGNIN, &form)
+		return
+	}
+
+	if !u.IsActive && setting.Service.RequireEmailConfirmSignIn {
+		// User has proved identity, resend confirmation e-mail if not limited.
+		if !ctx.Cache.IsExist("MailResendLimit_" + u.LowerName) {
+			mailer.SendActivateAccountMail(ctx.Context, u)
+			if err = ctx.Cache.Put("MailResendLimit_"+u.LowerName, u.LowerName, 180); err != nil {
+				log.Error(4, "Set cache(MailResendLimit) fail: %v", err)
+			}
+		}
+		ctx.RenderWithErr(ctx.Tr("auth.email_not_confirmed", u.Email, setting.Service.ActiveCodeLives/60), SIGNIN, &form)
+		return
+	}
+
+	// User with security keys has to complete sign in with one of them.
+	hasKeys, err := models.HasSecurityKeys(u.Id)
+	if err != nil {
+		ctx.Handle(500, "HasSecurityKeys", err)
+		return
+	} else if hasKeys {
+		ctx.Session.Set("securityKeyUid", u.Id)
+		ctx.Session.Set("securityKeyRemember", form.Remember)
+		ctx.Redirect(setting.AppSubUrl + "/user/login/security_key")
+		return
+	}
+
+	handleSignIn(ctx, u, form.Remember)
+}
 
-	if form.Remember {
+// handleSignIn signs in given user who has been fully authenticated,
+// and redirects to the requested page.
+func handleSignIn(ctx *middleware.Context, u *models.User, remember bool) {
+	if remember {
 		days := 86400 * setting.LogInRememberDays
 		ctx.SetCookie(setting.CookieUserName, u.Name, days, setting.AppSubUrl)
-		ctx.SetSuperSecureCookie(base.EncodeMD5(u.Rands+u.Passwd),
+		ctx.SetSuperSecureCookie(u.RememberCookieSecret(),
 			setting.CookieRememberName, u.Name, days, setting.AppSubUrl)
 	}
 
+	// Start tracking of session from scratch at next request.
+	if err := models.DeleteUserSessionBySID(ctx.Session.ID()); err != nil {
+		ctx.Handle(500, "DeleteUserSessionBySID", err)
+		return
+	}
+	ctx.Session.Delete("sessionTracked")
+
 	ctx.Session.Set("uid", u.Id)
 	ctx.Session.Set("uname", u.Name)
 	if redirectTo, _ := url.QueryUnescape(ctx.GetCookie("redirect_to")); len(redirectTo) > 0 {
@@ -86,6 +212,10 @@ func SignInPost(ctx *middleware.Context, form auth.SignInForm) {
 }
 
 func SignOut(ctx *middleware.Context) {
+	if err := models.DeleteUserSessionBySID(ctx.Session.ID()); err != nil {
+		log.Error(4, "DeleteUserSessionBySID: %v", err)
+	}
+	ctx.Session.Delete("sessionTracked")
 	ctx.Session.Delete("uid")
 	ctx.Session.Delete("uname")
 	ctx.Session.Delete("socialId")
@@ -137,11 +267,19 @@ func SignUpPost(ctx *middleware.Context, cpt *captcha.Captcha, form auth.Registe
 		return
 	}
 
+	if !isSignUpEmailAllowed(ctx, form.UserName, form.Email) {
+		ctx.Data["Err_Email"] = true
+		ctx.RenderWithErr(ctx.Tr("form.email_domain_not_allowed"), SIGNUP, &form)
+		return
+	}
+
+	needApproval := setting.Service.RegisterManualApproval && models.CountUsers() > 0
 	u := &models.User{
-		Name:     form.UserName,
-		Email:    form.Email,
-		Passwd:   form.Password,
-		IsActive: !setting.Service.RegisterEmailConfirm,
+		Name:         form.UserName,
+		Email:        form.Email,
+		Passwd:       form.Password,
+		IsActive:     !setting.Service.RegisterEmailConfirm && !needApproval,
+		NeedApproval: needApproval,
 	}
 	if err := models.CreateUser(u); err != nil {
 		switch {
@@ -174,6 +312,16 @@ func SignUpPost(ctx *middleware.Context, cpt *captcha.Captcha, form auth.Registe
 		}
 	}
 
+	// E-mail is confirmed after account is approved by administrator.
+	if u.NeedApproval {
+		if setting.MailService != nil {
+			mailer.SendApprovalRequestMail(ctx.Context, u)
+		}
+		ctx.Data["IsPendingApproval"] = true
+		ctx.HTML(200, ACTIVATE)
+		return
+	}
+
 	// Send confirmation e-mail, no need for social account.
 	if setting.Service.RegisterEmailConfirm && u.Id > 1 {
 		mailer.SendActivateAccountMail(ctx.Context, u)
@@ -191,9 +339,54 @@ func SignUpPost(ctx *middleware.Context, cpt *captcha.Captcha, form auth.Registe
 	ctx.Redirect(setting.AppSubUrl + "/user/login")
 }
 
+// emailDomainRejectReason checks domain of e-mail address against anti-spam settings,
+// and returns the reason if it is not allowed, or an empty string if it is.
+func emailDomainRejectReason(email string) string {
+	domain := base.EmailDomain(email)
+	switch {
+	case len(setting.Service.EmailDomainWhitelist) > 0 && !base.IsEmailDomainMatch(domain, setting.Service.EmailDomainWhitelist):
+		return "domain is not in whitelist"
+	case base.IsEmailDomainMatch(domain, setting.Service.EmailDomainBlacklist):
+		return "domain is in blacklist"
+	case setting.Service.BlockDisposableEmail && base.IsDisposableEmailDomain(domain):
+		return "domain is a disposable e-mail service"
+	}
+	return ""
+}
+
+// isSignUpEmailAllowed checks domain of e-mail address against anti-spam settings,
+// and logs the rejected attempt of sign up.
+func isSignUpEmailAllowed(ctx *middleware.Context, name, email string) bool {
+	reason := emailDomainRejectReason(email)
+	if len(reason) == 0 {
+		return true
+	}
+
+	log.Warn("Sign up rejected [name: %s, email: %s, ip: %s]: %s", name, email, ctx.RemoteIP, reason)
+	return false
+}
+
+// isEmailChangeAllowed works like isSignUpEmailAllowed for e-mail addresses
+// added or changed by signed in user.
+func isEmailChangeAllowed(ctx *middleware.Context, email string) bool {
+	reason := emailDomainRejectReason(email)
+	if len(reason) == 0 {
+		return true
+	}
+
+	log.Warn("E-mail change rejected [name: %s, email: %s, ip: %s]: %s", ctx.User.Name, email, ctx.RemoteIP, reason)
+	return false
+}
+
 func Activate(ctx *middleware.Context) {
 	code := ctx.Query("code")
 	if len(code) == 0 {
+		// Users who are required to confirm e-mail before sign in
+		// get activation e-mail resent by signing in.
+		if !ctx.IsSigned {
+			ctx.Redirect(setting.AppSubUrl + "/user/login")
+			return
+		}
 		ctx.Data["IsActivatePage"] = true
 		if ctx.User.IsActive {
 			ctx.Error(404)
@@ -0,0 +1,79 @@
+// Copyright 2015 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package user
+
+import (
+	"github.com/gogits/gogs/models"
+	"github.com/gogits/gogs/modules/auth"
+	"github.com/gogits/gogs/modules/base"
+	"github.com/gogits/gogs/modules/log"
+	"github.com/gogits/gogs/modules/middleware"
+	"github.com/gogits/gogs/modules/setting"
+)
+
+const (
+	SETTINGS_GPG_KEYS base.TplName = "user/settings/gpg_keys"
+)
+
+func SettingsGPGKeys(ctx *middleware.Context) {
+	ctx.Data["Title"] = ctx.Tr("settings")
+	ctx.Data["PageIsSettingsGPGKeys"] = true
+
+	keys, err := models.ListGPGKeys(ctx.User.Id)
+	if err != nil {
+		ctx.Handle(500, "ListGPGKeys", err)
+		return
+	}
+	ctx.Data["GPGKeys"] = keys
+	ctx.HTML(200, SETTINGS_GPG_KEYS)
+}
+
+func SettingsGPGKeysPost(ctx *middleware.Context, form auth.AddGPGKeyForm) {
+	ctx.Data["Title"] = ctx.Tr("settings")
+	ctx.Data["PageIsSettingsGPGKeys"] = true
+
+	keys, err := models.ListGPGKeys(ctx.User.Id)
+	if err != nil {
+		ctx.Handle(500, "ListGPGKeys", err)
+		return
+	}
+	ctx.Data["GPGKeys"] = keys
+
+	if ctx.HasError() {
+		ctx.HTML(200, SETTINGS_GPG_KEYS)
+		return
+	}
+
+	key, err := models.AddGPGKey(ctx.User.Id, form.Content)
+	if err != nil {
+		ctx.Data["HasError"] = true
+		ctx.Data["Err_Content"] = true
+		switch {
+		case models.IsErrGPGKeyAlreadyExist(err):
+			ctx.RenderWithErr(ctx.Tr("settings.gpg_key_been_used"), SETTINGS_GPG_KEYS, &form)
+		case models.IsErrGPGKeyInvalid(err):
+			ctx.RenderWithErr(ctx.Tr("settings.gpg_key_invalid", err.(models.ErrGPGKeyInvalid).Reason), SETTINGS_GPG_KEYS, &form)
+		default:
+			ctx.Handle(500, "AddGPGKey", err)
+		}
+		return
+	}
+
+	log.Trace("GPG key added: %s", ctx.User.Name)
+	ctx.Flash.Success(ctx.Tr("settings.add_gpg_key_success", key.KeyID))
+	ctx.Redirect(setting.AppSubUrl + "/user/settings/gpg_keys")
+}
+
+func DeleteGPGKey(ctx *middleware.Context) {
+	if err := models.DeleteGPGKey(ctx.User.Id, ctx.QueryInt64("id")); err != nil {
+		ctx.Flash.Error("DeleteGPGKey: " + err.Error())
+	} else {
+		ctx.Flash.Success(ctx.Tr("settings.gpg_key_deletion_success"))
+	}
+
+	ctx.JSON(200, map[string]interface{}{
+		"redirect": setting.AppSubUrl + "/user/settings/gpg_keys",
+	})
+}
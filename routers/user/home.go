@@ -361,7 +361,7 @@ func Profile(ctx *middleware.Context) {
 	u, err := models.GetUserByName(uname)
 	if err != nil {
 		if models.IsErrUserNotExist(err) {
-			ctx.Handle(404, "GetUserByName", err)
+			ctx.RedirectToUser(uname)
 		} else {
 			ctx.Handle(500, "GetUserByName", err)
 		}
@@ -0,0 +1,72 @@
+// Copyright 2015 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package user
+
+import (
+	"fmt"
+
+	"github.com/Unknwon/paginater"
+
+	"github.com/gogits/gogs/models"
+	"github.com/gogits/gogs/modules/log"
+	"github.com/gogits/gogs/modules/middleware"
+	"github.com/gogits/gogs/modules/setting"
+)
+
+// GET /user/notifications?state=unread|all&repo=...
+func Notifications(ctx *middleware.Context) {
+	ctx.Data["Title"] = ctx.Tr("notifications")
+	ctx.Data["PageIsNotifications"] = true
+
+	isShowAll := ctx.Query("state") == "all"
+	repoID := ctx.QueryInt64("repo")
+	ctx.Data["IsShowAll"] = isShowAll
+	ctx.Data["RepoID"] = repoID
+
+	page := ctx.QueryInt("page")
+	if page <= 1 {
+		page = 1
+	}
+
+	opts := &models.NotificationsOptions{
+		UID:        ctx.User.Id,
+		RepoID:     repoID,
+		OnlyUnread: !isShowAll,
+		Page:       page,
+		PageSize:   setting.IssuePagingNum,
+	}
+	notifications, err := models.GetNotifications(opts)
+	if err != nil {
+		ctx.Handle(500, "GetNotifications", err)
+		return
+	}
+	ctx.Data["Notifications"] = notifications
+
+	total, err := models.CountNotifications(opts)
+	if err != nil {
+		ctx.Handle(500, "CountNotifications", err)
+		return
+	}
+	ctx.Data["Page"] = paginater.New(int(total), setting.IssuePagingNum, page, 5)
+
+	ctx.HTML(200, NOTIFICATION)
+}
+
+// POST /user/notifications/read?repo=...
+func MarkNotificationsRead(ctx *middleware.Context) {
+	repoID := ctx.QueryInt64("repo")
+	if err := models.MarkNotificationsRead(ctx.User.Id, repoID); err != nil {
+		ctx.Handle(500, "MarkNotificationsRead", err)
+		return
+	}
+	log.Trace("Notifications marked as read[%d]: %s", repoID, ctx.User.Name)
+
+	ctx.Flash.Success(ctx.Tr("notification.mark_all_read_success"))
+	if repoID > 0 {
+		ctx.Redirect(fmt.Sprintf("%s/user/notifications?repo=%d", setting.AppSubUrl, repoID))
+		return
+	}
+	ctx.Redirect(setting.AppSubUrl + "/user/notifications")
+}
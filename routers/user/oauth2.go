@@ -0,0 +1,258 @@
+// Copyright 2015 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package user
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/gogits/gogs/models"
+	"github.com/gogits/gogs/modules/auth"
+	"github.com/gogits/gogs/modules/base"
+	"github.com/gogits/gogs/modules/log"
+	"github.com/gogits/gogs/modules/middleware"
+	"github.com/gogits/gogs/modules/setting"
+)
+
+const (
+	OAUTH2_AUTHORIZE base.TplName = "user/auth/oauth2_authorize"
+)
+
+// loadApplications loads access tokens, OAuth2 applications and authorizations of current user.
+func loadApplications(ctx *middleware.Context) {
+	tokens, err := models.ListAccessTokens(ctx.User.Id)
+	if err != nil {
+		ctx.Handle(500, "ListAccessTokens", err)
+		return
+	}
+	ctx.Data["Tokens"] = tokens
+
+	apps, err := models.ListOAuth2Applications(ctx.User.Id)
+	if err != nil {
+		ctx.Handle(500, "ListOAuth2Applications", err)
+		return
+	}
+	ctx.Data["OAuth2Applications"] = apps
+
+	grants, err := models.ListOAuth2Grants(ctx.User.Id)
+	if err != nil {
+		ctx.Handle(500, "ListOAuth2Grants", err)
+		return
+	}
+	ctx.Data["OAuth2Grants"] = grants
+}
+
+func SettingsOAuth2ApplicationsPost(ctx *middleware.Context, form auth.NewOAuth2ApplicationForm) {
+	ctx.Data["Title"] = ctx.Tr("settings")
+	ctx.Data["PageIsSettingsApplications"] = true
+	ctx.Data["TokenScopes"] = models.AccessTokenScopes
+
+	if ctx.HasError() {
+		loadApplications(ctx)
+		if ctx.Written() {
+			return
+		}
+		ctx.HTML(200, SETTINGS_APPLICATIONS)
+		return
+	}
+
+	app := &models.OAuth2Application{
+		UID:  ctx.User.Id,
+		Name: form.AppName,
+	}
+	if err := app.SetRedirectURIs(form.RedirectURIs); err != nil {
+		ctx.Flash.Error(ctx.Tr("settings.oauth2_invalid_redirect_uri", err.(models.ErrOAuth2InvalidRedirectURI).URI))
+		ctx.Redirect(setting.AppSubUrl + "/user/settings/applications")
+		return
+	}
+	secret, err := models.NewOAuth2Application(app)
+	if err != nil {
+		ctx.Handle(500, "NewOAuth2Application", err)
+		return
+	}
+	log.Trace("OAuth2 application registered[%d]: %s", app.ID, ctx.User.Name)
+
+	ctx.Flash.Success(ctx.Tr("settings.oauth2_application_created"))
+	ctx.Flash.Info(ctx.Tr("settings.oauth2_client_credentials", app.ClientID, secret))
+	ctx.Redirect(setting.AppSubUrl + "/user/settings/applications")
+}
+
+func SettingsDeleteOAuth2Application(ctx *middleware.Context) {
+	if err := models.DeleteOAuth2Application(ctx.User.Id, ctx.QueryInt64("id")); err != nil {
+		ctx.Flash.Error("DeleteOAuth2Application: " + err.Error())
+	} else {
+		ctx.Flash.Success(ctx.Tr("settings.oauth2_application_deleted"))
+	}
+
+	ctx.JSON(200, map[string]interface{}{
+		"redirect": setting.AppSubUrl + "/user/settings/applications",
+	})
+}
+
+func SettingsRevokeOAuth2Grant(ctx *middleware.Context) {
+	if err := models.RevokeOAuth2Grant(ctx.User.Id, ctx.QueryInt64("id")); err != nil {
+		ctx.Flash.Error("RevokeOAuth2Grant: " + err.Error())
+	} else {
+		ctx.Flash.Success(ctx.Tr("settings.oauth2_grant_revoked"))
+	}
+
+	ctx.JSON(200, map[string]interface{}{
+		"redirect": setting.AppSubUrl + "/user/settings/applications",
+	})
+}
+
+// parseOAuth2Scopes parses scopes separated by space or comma.
+func parseOAuth2Scopes(scope string) []string {
+	return strings.FieldsFunc(scope, func(r rune) bool {
+		return r == ' ' || r == ','
+	})
+}
+
+// checkOAuth2Request validates client ID and redirect URI of authorization request.
+// Errors are rendered to user instead of redirected, because redirect URI cannot be trusted.
+func checkOAuth2Request(ctx *middleware.Context) (*models.OAuth2Application, string) {
+	if ctx.Query("response_type") != "code" {
+		ctx.Handle(400, "OAuth2Authorize", nil)
+		return nil, ""
+	}
+
+	app, err := models.GetOAuth2ApplicationByClientID(ctx.Query("client_id"))
+	if err != nil {
+		if models.IsErrOAuth2ApplicationNotExist(err) {
+			ctx.Handle(404, "GetOAuth2ApplicationByClientID", nil)
+		} else {
+			ctx.Handle(500, "GetOAuth2ApplicationByClientID", err)
+		}
+		return nil, ""
+	}
+
+	// Redirect URI can be omitted only when exactly one is registered.
+	redirectURI := ctx.Query("redirect_uri")
+	if len(redirectURI) == 0 {
+		if uris := app.RedirectURIList(); len(uris) == 1 {
+			redirectURI = uris[0]
+		}
+	}
+	if !app.IsValidRedirectURI(redirectURI) {
+		log.Warn("OAuth2 redirect URI mismatch[%s]: %s", app.ClientID, redirectURI)
+		ctx.Handle(400, "OAuth2Authorize", nil)
+		return nil, ""
+	}
+
+	if err = new(models.AccessToken).SetScopes(parseOAuth2Scopes(ctx.Query("scope"))); err != nil {
+		ctx.Handle(400, "OAuth2Authorize", nil)
+		return nil, ""
+	}
+	return app, redirectURI
+}
+
+// oauth2Redirect redirects user back to application with given parameters.
+func oauth2Redirect(ctx *middleware.Context, redirectURI string, params map[string]string) {
+	u, err := url.Parse(redirectURI)
+	if err != nil {
+		ctx.Handle(500, "Parse", err)
+		return
+	}
+
+	q := u.Query()
+	for k, v := range params {
+		q.Set(k, v)
+	}
+	if state := ctx.Query("state"); len(state) > 0 {
+		q.Set("state", state)
+	}
+	u.RawQuery = q.Encode()
+	ctx.Redirect(u.String())
+}
+
+// GET /login/oauth/authorize
+func OAuth2Authorize(ctx *middleware.Context) {
+	app, redirectURI := checkOAuth2Request(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	ctx.Data["Title"] = ctx.Tr("auth.oauth2_authorize", app.Name)
+	ctx.Data["Application"] = app
+	ctx.Data["RedirectURI"] = redirectURI
+	ctx.Data["Scopes"] = parseOAuth2Scopes(ctx.Query("scope"))
+	ctx.Data["RequestQuery"] = ctx.Req.URL.RawQuery
+	ctx.HTML(200, OAUTH2_AUTHORIZE)
+}
+
+// POST /login/oauth/authorize
+func OAuth2AuthorizePost(ctx *middleware.Context) {
+	app, redirectURI := checkOAuth2Request(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	if ctx.Query("granted") != "true" {
+		oauth2Redirect(ctx, redirectURI, map[string]string{"error": "access_denied"})
+		return
+	}
+
+	code, err := models.NewOAuth2Code(ctx.User.Id, app, redirectURI, parseOAuth2Scopes(ctx.Query("scope")))
+	if err != nil {
+		ctx.Handle(500, "NewOAuth2Code", err)
+		return
+	}
+	log.Trace("OAuth2 application authorized[%s]: %s", app.ClientID, ctx.User.Name)
+
+	oauth2Redirect(ctx, redirectURI, map[string]string{"code": code})
+}
+
+// oauth2Error responds error of token request in format defined by RFC 6749.
+func oauth2Error(ctx *middleware.Context, status int, code string) {
+	ctx.JSON(status, map[string]string{
+		"error": code,
+	})
+}
+
+// POST /login/oauth/access_token
+func OAuth2AccessToken(ctx *middleware.Context) {
+	if ctx.Query("grant_type") != "authorization_code" {
+		oauth2Error(ctx, 400, "unsupported_grant_type")
+		return
+	}
+
+	app, err := models.GetOAuth2ApplicationByClientID(ctx.Query("client_id"))
+	if err != nil {
+		if models.IsErrOAuth2ApplicationNotExist(err) {
+			oauth2Error(ctx, 401, "invalid_client")
+		} else {
+			log.Error(4, "GetOAuth2ApplicationByClientID: %v", err)
+			oauth2Error(ctx, 500, "server_error")
+		}
+		return
+	} else if !app.ValidateClientSecret(ctx.Query("client_secret")) {
+		oauth2Error(ctx, 401, "invalid_client")
+		return
+	}
+
+	redirectURI := ctx.Query("redirect_uri")
+	if len(redirectURI) == 0 {
+		if uris := app.RedirectURIList(); len(uris) == 1 {
+			redirectURI = uris[0]
+		}
+	}
+	t, err := models.ExchangeOAuth2Code(app, ctx.Query("code"), redirectURI)
+	if err != nil {
+		if models.IsErrOAuth2InvalidCode(err) {
+			oauth2Error(ctx, 400, "invalid_grant")
+		} else {
+			log.Error(4, "ExchangeOAuth2Code: %v", err)
+			oauth2Error(ctx, 500, "server_error")
+		}
+		return
+	}
+
+	ctx.Resp.Header().Set("Cache-Control", "no-store")
+	ctx.JSON(200, map[string]string{
+		"access_token": t.Sha1,
+		"token_type":   "bearer",
+		"scope":        t.Scopes,
+	})
+}
@@ -0,0 +1,307 @@
+// Copyright 2015 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package user
+
+import (
+	"encoding/json"
+
+	"github.com/Unknwon/com"
+
+	"github.com/gogits/gogs/models"
+	"github.com/gogits/gogs/modules/auth"
+	"github.com/gogits/gogs/modules/base"
+	"github.com/gogits/gogs/modules/log"
+	"github.com/gogits/gogs/modules/middleware"
+	"github.com/gogits/gogs/modules/setting"
+	"github.com/gogits/gogs/modules/webauthn"
+)
+
+const (
+	SETTINGS_SECURITY_KEYS base.TplName = "user/settings/security_keys"
+	SIGNIN_SECURITY_KEY    base.TplName = "user/auth/security_key"
+)
+
+// WEBAUTHN_TIMEOUT is the time in milliseconds that browser waits for user to use security key.
+const WEBAUTHN_TIMEOUT = 60000
+
+type publicKeyCredentialDescriptor struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+// prepareWebAuthnChallenge generates a new challenge for current session.
+func prepareWebAuthnChallenge(ctx *middleware.Context) (*webauthn.RelyingParty, string) {
+	rp, err := webauthn.NewRelyingParty(setting.AppUrl)
+	if err != nil {
+		ctx.Handle(500, "NewRelyingParty", err)
+		return nil, ""
+	}
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		ctx.Handle(500, "NewChallenge", err)
+		return nil, ""
+	}
+	ctx.Session.Set("securityKeyChallenge", challenge)
+	return rp, challenge
+}
+
+// popWebAuthnChallenge returns challenge of current session and makes sure it cannot be reused.
+func popWebAuthnChallenge(ctx *middleware.Context) string {
+	challenge, _ := ctx.Session.Get("securityKeyChallenge").(string)
+	ctx.Session.Delete("securityKeyChallenge")
+	return challenge
+}
+
+func decodeWebAuthnValues(values ...string) ([][]byte, error) {
+	data := make([][]byte, len(values))
+	for i := range values {
+		var err error
+		if data[i], err = webauthn.Encoding.DecodeString(values[i]); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+func renderSecurityKeys(ctx *middleware.Context, keys []*models.SecurityKey) {
+	rp, challenge := prepareWebAuthnChallenge(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	excludes := make([]publicKeyCredentialDescriptor, len(keys))
+	for i := range keys {
+		excludes[i] = publicKeyCredentialDescriptor{"public-key", keys[i].CredentialID}
+	}
+	opts, err := json.Marshal(map[string]interface{}{
+		"challenge": challenge,
+		"rp": map[string]string{
+			"id":   rp.ID,
+			"name": setting.AppName,
+		},
+		"user": map[string]string{
+			"id":          webauthn.Encoding.EncodeToString([]byte(com.ToStr(ctx.User.Id))),
+			"name":        ctx.User.Name,
+			"displayName": ctx.User.DisplayName(),
+		},
+		"pubKeyCredParams": []map[string]interface{}{
+			{"type": "public-key", "alg": webauthn.ALG_ES256},
+			{"type": "public-key", "alg": webauthn.ALG_RS256},
+		},
+		"excludeCredentials": excludes,
+		"attestation":        "none",
+		"timeout":            WEBAUTHN_TIMEOUT,
+	})
+	if err != nil {
+		ctx.Handle(500, "Marshal", err)
+		return
+	}
+	ctx.Data["SecurityKeys"] = keys
+	ctx.Data["CreateOptions"] = string(opts)
+	ctx.HTML(200, SETTINGS_SECURITY_KEYS)
+}
+
+func SettingsSecurityKeys(ctx *middleware.Context) {
+	ctx.Data["Title"] = ctx.Tr("settings")
+	ctx.Data["PageIsSettingsSecurityKeys"] = true
+
+	keys, err := models.ListSecurityKeys(ctx.User.Id)
+	if err != nil {
+		ctx.Handle(500, "ListSecurityKeys", err)
+		return
+	}
+	renderSecurityKeys(ctx, keys)
+}
+
+func SettingsSecurityKeysPost(ctx *middleware.Context, form auth.AddSecurityKeyForm) {
+	ctx.Data["Title"] = ctx.Tr("settings")
+	ctx.Data["PageIsSettingsSecurityKeys"] = true
+
+	challenge := popWebAuthnChallenge(ctx)
+	if ctx.HasError() {
+		keys, err := models.ListSecurityKeys(ctx.User.Id)
+		if err != nil {
+			ctx.Handle(500, "ListSecurityKeys", err)
+			return
+		}
+		renderSecurityKeys(ctx, keys)
+		return
+	}
+
+	rp, err := webauthn.NewRelyingParty(setting.AppUrl)
+	if err != nil {
+		ctx.Handle(500, "NewRelyingParty", err)
+		return
+	}
+
+	data, err := decodeWebAuthnValues(form.ClientData, form.AttestationObject)
+	if err != nil {
+		ctx.Flash.Error(ctx.Tr("settings.security_key_invalid", err))
+		ctx.Redirect(setting.AppSubUrl + "/user/settings/security_keys")
+		return
+	}
+	cred, err := rp.VerifyRegistration(challenge, data[0], data[1])
+	if err != nil {
+		ctx.Flash.Error(ctx.Tr("settings.security_key_invalid", err))
+		ctx.Redirect(setting.AppSubUrl + "/user/settings/security_keys")
+		return
+	}
+
+	if _, err = models.AddSecurityKey(ctx.User.Id, form.Name, cred); err != nil {
+		if models.IsErrSecurityKeyAlreadyExist(err) {
+			ctx.Flash.Error(ctx.Tr("settings.security_key_name_used"))
+			ctx.Redirect(setting.AppSubUrl + "/user/settings/security_keys")
+		} else {
+			ctx.Handle(500, "AddSecurityKey", err)
+		}
+		return
+	}
+
+	log.Trace("Security key added: %s", ctx.User.Name)
+	ctx.Flash.Success(ctx.Tr("settings.add_security_key_success", form.Name))
+	ctx.Redirect(setting.AppSubUrl + "/user/settings/security_keys")
+}
+
+func DeleteSecurityKey(ctx *middleware.Context) {
+	if err := models.DeleteSecurityKey(ctx.User.Id, ctx.QueryInt64("id")); err != nil {
+		ctx.Flash.Error("DeleteSecurityKey: " + err.Error())
+	} else {
+		ctx.Flash.Success(ctx.Tr("settings.security_key_deletion_success"))
+	}
+
+	ctx.JSON(200, map[string]interface{}{
+		"redirect": setting.AppSubUrl + "/user/settings/security_keys",
+	})
+}
+
+// getSecurityKeySignInUser returns user who has passed password check
+// and needs to complete sign in with security key.
+func getSecurityKeySignInUser(ctx *middleware.Context) *models.User {
+	uid, ok := ctx.Session.Get("securityKeyUid").(int64)
+	if !ok {
+		ctx.Redirect(setting.AppSubUrl + "/user/login")
+		return nil
+	}
+
+	u, err := models.GetUserByID(uid)
+	if err != nil {
+		ctx.Handle(500, "GetUserByID", err)
+		return nil
+	}
+	return u
+}
+
+func renderSecurityKeySignIn(ctx *middleware.Context, u *models.User) {
+	rp, challenge := prepareWebAuthnChallenge(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	keys, err := models.ListSecurityKeys(u.Id)
+	if err != nil {
+		ctx.Handle(500, "ListSecurityKeys", err)
+		return
+	}
+	allows := make([]publicKeyCredentialDescriptor, len(keys))
+	for i := range keys {
+		allows[i] = publicKeyCredentialDescriptor{"public-key", keys[i].CredentialID}
+	}
+	opts, err := json.Marshal(map[string]interface{}{
+		"challenge":        challenge,
+		"rpId":             rp.ID,
+		"allowCredentials": allows,
+		"userVerification": "discouraged",
+		"timeout":          WEBAUTHN_TIMEOUT,
+	})
+	if err != nil {
+		ctx.Handle(500, "Marshal", err)
+		return
+	}
+	ctx.Data["RequestOptions"] = string(opts)
+	ctx.HTML(200, SIGNIN_SECURITY_KEY)
+}
+
+func SignInSecurityKey(ctx *middleware.Context) {
+	ctx.Data["Title"] = ctx.Tr("sign_in")
+
+	u := getSecurityKeySignInUser(ctx)
+	if ctx.Written() {
+		return
+	}
+	renderSecurityKeySignIn(ctx, u)
+}
+
+func SignInSecurityKeyPost(ctx *middleware.Context, form auth.SecurityKeySignInForm) {
+	ctx.Data["Title"] = ctx.Tr("sign_in")
+
+	u := getSecurityKeySignInUser(ctx)
+	if ctx.Written() {
+		return
+	}
+	challenge := popWebAuthnChallenge(ctx)
+
+	if isLoginLocked(ctx, u.LowerName) {
+		ctx.Session.Delete("securityKeyUid")
+		ctx.Flash.Error(ctx.Tr("auth.login_locked", setting.Service.LoginLockoutMinutes))
+		ctx.Redirect(setting.AppSubUrl + "/user/login")
+		return
+	}
+
+	// Failures are counted the same way as incorrect passwords.
+	fail := func(err error) {
+		log.Trace("Security key sign in failed[%s]: %v", u.Name, err)
+		recordLoginFailure(ctx, "user_"+u.LowerName, setting.Service.LoginMaxFailedAttempts, "Account '"+u.LowerName+"'")
+		recordLoginFailure(ctx, "ip_"+ctx.RemoteIP, setting.Service.LoginMaxFailedAttemptsPerIP, "Sign in from IP '"+ctx.RemoteIP+"'")
+		ctx.Flash.ErrorMsg = ctx.Tr("auth.security_key_failed")
+		ctx.Data["Flash"] = ctx.Flash
+		renderSecurityKeySignIn(ctx, u)
+	}
+
+	if ctx.HasError() {
+		renderSecurityKeySignIn(ctx, u)
+		return
+	}
+
+	key, err := models.GetSecurityKeyByCredentialID(u.Id, form.CredentialID)
+	if err != nil {
+		if models.IsErrSecurityKeyNotExist(err) {
+			fail(err)
+		} else {
+			ctx.Handle(500, "GetSecurityKeyByCredentialID", err)
+		}
+		return
+	}
+	cred, err := key.Credential()
+	if err != nil {
+		ctx.Handle(500, "Credential", err)
+		return
+	}
+
+	rp, err := webauthn.NewRelyingParty(setting.AppUrl)
+	if err != nil {
+		ctx.Handle(500, "NewRelyingParty", err)
+		return
+	}
+	data, err := decodeWebAuthnValues(form.ClientData, form.AuthenticatorData, form.Signature)
+	if err != nil {
+		fail(err)
+		return
+	}
+	signCount, err := rp.VerifyAssertion(challenge, cred, data[0], data[1], data[2])
+	if err != nil {
+		fail(err)
+		return
+	}
+
+	if err = models.UpdateSecurityKeyUsage(key, signCount); err != nil {
+		log.Error(4, "UpdateSecurityKeyUsage: %v", err)
+	}
+	ctx.Cache.Delete("LoginFailures_user_" + u.LowerName)
+
+	remember, _ := ctx.Session.Get("securityKeyRemember").(bool)
+	ctx.Session.Delete("securityKeyUid")
+	ctx.Session.Delete("securityKeyRemember")
+	handleSignIn(ctx, u, remember)
+}
@@ -26,6 +26,8 @@ const (
 	SETTINGS_PASSWORD     base.TplName = "user/settings/password"
 	SETTINGS_EMAILS       base.TplName = "user/settings/email"
 	SETTINGS_SSH_KEYS     base.TplName = "user/settings/sshkeys"
+	SETTINGS_SESSIONS     base.TplName = "user/settings/sessions"
+	SETTINGS_NOTIFICATION base.TplName = "user/settings/notification"
 	SETTINGS_SOCIAL       base.TplName = "user/settings/social"
 	SETTINGS_APPLICATIONS base.TplName = "user/settings/applications"
 	SETTINGS_DELETE       base.TplName = "user/settings/delete"
@@ -50,6 +52,16 @@ func SettingsPost(ctx *middleware.Context, form auth.UpdateProfileForm) {
 
 	// Check if user name has been changed.
 	if ctx.User.LowerName != strings.ToLower(form.Name) {
+		if !setting.Service.EnableUsernameChange {
+			ctx.Flash.Error(ctx.Tr("settings.username_change_disabled"))
+			ctx.Redirect(setting.AppSubUrl + "/user/settings")
+			return
+		} else if !ctx.User.CanChangeName() {
+			ctx.Flash.Error(ctx.Tr("settings.username_change_cooldown", ctx.User.NextNameChangeTime().Format("2006-01-02 15:04")))
+			ctx.Redirect(setting.AppSubUrl + "/user/settings")
+			return
+		}
+
 		if err := models.ChangeUserName(ctx.User, form.Name); err != nil {
 			switch {
 			case models.IsErrUserAlreadyExist(err):
@@ -71,6 +83,12 @@ func SettingsPost(ctx *middleware.Context, form auth.UpdateProfileForm) {
 		}
 		log.Trace("User name changed: %s -> %s", ctx.User.Name, form.Name)
 	}
+	if !strings.EqualFold(ctx.User.Email, form.Email) && !isEmailChangeAllowed(ctx, form.Email) {
+		ctx.Flash.Error(ctx.Tr("form.email_domain_not_allowed"))
+		ctx.Redirect(setting.AppSubUrl + "/user/settings")
+		return
+	}
+
 	// In case it's just a case change.
 	ctx.User.Name = form.Name
 	ctx.User.LowerName = strings.ToLower(form.Name)
@@ -106,6 +124,8 @@ func UpdateAvatarSetting(ctx *middleware.Context, form auth.UploadAvatarForm, ct
 		}
 		if _, ok := base.IsImageFile(data); !ok {
 			return errors.New(ctx.Tr("settings.uploaded_avatar_not_a_image"))
+		} else if err = models.CheckAvatarImage(data); err != nil {
+			return err
 		}
 		if err = ctxUser.UploadAvatar(data); err != nil {
 			return fmt.Errorf("UploadAvatar: %v", err)
@@ -168,6 +188,32 @@ func SettingsPasswordPost(ctx *middleware.Context, form auth.ChangePasswordForm)
 	ctx.Redirect(setting.AppSubUrl + "/user/settings/password")
 }
 
+func SettingsNotification(ctx *middleware.Context) {
+	ctx.Data["Title"] = ctx.Tr("settings")
+	ctx.Data["PageIsSettingsNotification"] = true
+	ctx.HTML(200, SETTINGS_NOTIFICATION)
+}
+
+func SettingsNotificationPost(ctx *middleware.Context, form auth.UpdateNotificationForm) {
+	ctx.Data["Title"] = ctx.Tr("settings")
+	ctx.Data["PageIsSettingsNotification"] = true
+
+	if ctx.HasError() {
+		ctx.HTML(200, SETTINGS_NOTIFICATION)
+		return
+	}
+
+	ctx.User.WatchNotifyMode = models.WatchNotifyMode(form.WatchNotifyMode)
+	if err := models.UpdateUser(ctx.User); err != nil {
+		ctx.Handle(500, "UpdateUser", err)
+		return
+	}
+	log.Trace("User notification settings updated: %s", ctx.User.Name)
+
+	ctx.Flash.Success(ctx.Tr("settings.update_notification_success"))
+	ctx.Redirect(setting.AppSubUrl + "/user/settings/notifications")
+}
+
 func SettingsEmails(ctx *middleware.Context) {
 	ctx.Data["Title"] = ctx.Tr("settings")
 	ctx.Data["PageIsSettingsEmails"] = true
@@ -211,6 +257,11 @@ func SettingsEmailPost(ctx *middleware.Context, form auth.AddEmailForm) {
 		return
 	}
 
+	if !isEmailChangeAllowed(ctx, strings.TrimSpace(form.Email)) {
+		ctx.RenderWithErr(ctx.Tr("form.email_domain_not_allowed"), SETTINGS_EMAILS, &form)
+		return
+	}
+
 	e := &models.EmailAddress{
 		UID:         ctx.User.Id,
 		Email:       strings.TrimSpace(form.Email),
@@ -264,6 +315,7 @@ func SettingsSSHKeys(ctx *middleware.Context) {
 		return
 	}
 	ctx.Data["Keys"] = keys
+	ctx.Data["SSHKeyImportSources"] = setting.Service.SSHKeyImportSources
 
 	ctx.HTML(200, SETTINGS_SSH_KEYS)
 }
@@ -314,6 +366,53 @@ func SettingsSSHKeysPost(ctx *middleware.Context, form auth.AddSSHKeyForm) {
 	ctx.Redirect(setting.AppSubUrl + "/user/settings/ssh")
 }
 
+// POST /user/settings/ssh/import
+func SettingsSSHKeysImportPost(ctx *middleware.Context, form auth.ImportSSHKeysForm) {
+	if ctx.HasError() {
+		ctx.Flash.Error(ctx.Data["ErrorMsg"].(string))
+		ctx.Redirect(setting.AppSubUrl + "/user/settings/ssh")
+		return
+	} else if !models.IsValidKeyImportSource(form.Source) {
+		ctx.Error(404)
+		return
+	}
+
+	contents, err := models.FetchRemotePublicKeys(form.Source, form.UserName)
+	if err != nil {
+		log.Warn("FetchRemotePublicKeys[%s/%s]: %v", form.Source, form.UserName, err)
+		ctx.Flash.Error(ctx.Tr("settings.ssh_key_import_failed", form.Source, form.UserName))
+		ctx.Redirect(setting.AppSubUrl + "/user/settings/ssh")
+		return
+	} else if len(contents) == 0 {
+		ctx.Flash.Info(ctx.Tr("settings.ssh_key_import_empty", form.Source, form.UserName))
+		ctx.Redirect(setting.AppSubUrl + "/user/settings/ssh")
+		return
+	}
+
+	added, skipped, err := models.ImportPublicKeys(ctx.User.Id, form.Source, form.UserName, contents)
+	if err != nil {
+		ctx.Handle(500, "ImportPublicKeys", err)
+		return
+	}
+	log.Trace("SSH keys imported[%s/%s]: %s added %d, skipped %d", form.Source, form.UserName, ctx.User.Name, len(added), len(skipped))
+
+	names := make([]string, len(added))
+	for i := range added {
+		names[i] = added[i].Name
+	}
+	reasons := make([]string, len(skipped))
+	for i := range skipped {
+		reasons[i] = skipped[i].Name + ": " + skipped[i].Reason
+	}
+	if len(added) > 0 {
+		ctx.Flash.Success(ctx.Tr("settings.ssh_key_import_success", len(added), strings.Join(names, ", ")))
+	}
+	if len(skipped) > 0 {
+		ctx.Flash.Info(ctx.Tr("settings.ssh_key_import_skipped", len(skipped), strings.Join(reasons, "; ")))
+	}
+	ctx.Redirect(setting.AppSubUrl + "/user/settings/ssh")
+}
+
 func DeleteSSHKey(ctx *middleware.Context) {
 	if err := models.DeletePublicKey(ctx.QueryInt64("id")); err != nil {
 		ctx.Flash.Error("DeletePublicKey: " + err.Error())
@@ -326,16 +425,73 @@ func DeleteSSHKey(ctx *middleware.Context) {
 	})
 }
 
+func SettingsSessions(ctx *middleware.Context) {
+	ctx.Data["Title"] = ctx.Tr("settings")
+	ctx.Data["PageIsSettingsSessions"] = true
+
+	sessions, err := models.ListUserSessions(ctx.User.Id)
+	if err != nil {
+		ctx.Handle(500, "ListUserSessions", err)
+		return
+	}
+	ctx.Data["Sessions"] = sessions
+	ctx.Data["CurrentSID"] = ctx.Session.ID()
+	ctx.HTML(200, SETTINGS_SESSIONS)
+}
+
+// refreshRememberCookie issues remember cookie again for current session
+// when it has been invalidated by revoking other sessions.
+func refreshRememberCookie(ctx *middleware.Context) error {
+	if len(ctx.GetCookie(setting.CookieRememberName)) == 0 {
+		return nil
+	}
+
+	u, err := models.GetUserByID(ctx.User.Id)
+	if err != nil {
+		return err
+	}
+	ctx.SetSuperSecureCookie(u.RememberCookieSecret(),
+		setting.CookieRememberName, u.Name, 86400*setting.LogInRememberDays, setting.AppSubUrl)
+	return nil
+}
+
+func RevokeSession(ctx *middleware.Context) {
+	if err := models.RevokeUserSession(ctx.User.Id, ctx.QueryInt64("id")); err != nil {
+		ctx.Flash.Error("RevokeUserSession: " + err.Error())
+	} else if err = refreshRememberCookie(ctx); err != nil {
+		ctx.Flash.Error("refreshRememberCookie: " + err.Error())
+	} else {
+		ctx.Flash.Success(ctx.Tr("settings.session_revocation_success"))
+	}
+
+	ctx.JSON(200, map[string]interface{}{
+		"redirect": setting.AppSubUrl + "/user/settings/sessions",
+	})
+}
+
+func RevokeOtherSessions(ctx *middleware.Context) {
+	if err := models.RevokeOtherUserSessions(ctx.User.Id, ctx.Session.ID()); err != nil {
+		ctx.Handle(500, "RevokeOtherUserSessions", err)
+		return
+	} else if err = refreshRememberCookie(ctx); err != nil {
+		ctx.Handle(500, "refreshRememberCookie", err)
+		return
+	}
+
+	log.Trace("Other sessions revoked: %s", ctx.User.Name)
+	ctx.Flash.Success(ctx.Tr("settings.other_sessions_revocation_success"))
+	ctx.Redirect(setting.AppSubUrl + "/user/settings/sessions")
+}
+
 func SettingsApplications(ctx *middleware.Context) {
 	ctx.Data["Title"] = ctx.Tr("settings")
 	ctx.Data["PageIsSettingsApplications"] = true
+	ctx.Data["TokenScopes"] = models.AccessTokenScopes
 
-	tokens, err := models.ListAccessTokens(ctx.User.Id)
-	if err != nil {
-		ctx.Handle(500, "ListAccessTokens", err)
+	loadApplications(ctx)
+	if ctx.Written() {
 		return
 	}
-	ctx.Data["Tokens"] = tokens
 
 	ctx.HTML(200, SETTINGS_APPLICATIONS)
 }
@@ -343,14 +499,13 @@ func SettingsApplications(ctx *middleware.Context) {
 func SettingsApplicationsPost(ctx *middleware.Context, form auth.NewAccessTokenForm) {
 	ctx.Data["Title"] = ctx.Tr("settings")
 	ctx.Data["PageIsSettingsApplications"] = true
+	ctx.Data["TokenScopes"] = models.AccessTokenScopes
 
 	if ctx.HasError() {
-		tokens, err := models.ListAccessTokens(ctx.User.Id)
-		if err != nil {
-			ctx.Handle(500, "ListAccessTokens", err)
+		loadApplications(ctx)
+		if ctx.Written() {
 			return
 		}
-		ctx.Data["Tokens"] = tokens
 		ctx.HTML(200, SETTINGS_APPLICATIONS)
 		return
 	}
@@ -359,6 +514,11 @@ func SettingsApplicationsPost(ctx *middleware.Context, form auth.NewAccessTokenF
 		UID:  ctx.User.Id,
 		Name: form.Name,
 	}
+	if err := t.SetScopes(form.Scopes); err != nil {
+		ctx.Flash.Error(ctx.Tr("settings.invalid_token_scope", err.(models.ErrAccessTokenInvalidScope).Scope))
+		ctx.Redirect(setting.AppSubUrl + "/user/settings/applications")
+		return
+	}
 	if err := models.NewAccessToken(t); err != nil {
 		ctx.Handle(500, "NewAccessToken", err)
 		return